@@ -0,0 +1,50 @@
+// Package api holds the OpenAPI specification of the GOYAV API, embedded in the binaries so that the server can
+// serve it along with the endpoints it describes.
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Spec is the OpenAPI 3 specification of the API, in YAML.
+//
+//go:embed openapi.yml
+var Spec []byte
+
+// JSON returns the specification in JSON, the format expected by most of the client generators.
+func JSON() ([]byte, error) {
+	var doc any
+	if err := yaml.Unmarshal(Spec, &doc); err != nil {
+		return nil, fmt.Errorf("invalid OpenAPI specification: %w", err)
+	}
+	return json.Marshal(jsonValue(doc))
+}
+
+// jsonValue returns v with the keys of its mappings converted to strings, as JSON requires, since YAML decodes
+// the mappings with keys of other types, such as the response codes, to map[any]any.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, e := range v {
+			v[k] = jsonValue(e)
+		}
+		return v
+	case map[any]any:
+		m := make(map[string]any, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = jsonValue(e)
+		}
+		return m
+	case []any:
+		for i, e := range v {
+			v[i] = jsonValue(e)
+		}
+		return v
+	default:
+		return v
+	}
+}
@@ -0,0 +1,25 @@
+package api
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestJSON(t *testing.T) {
+	b, err := JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var spec struct {
+		OpenAPI string                    `json:"openapi"`
+		Paths   map[string]map[string]any `json:"paths"`
+	}
+	if err = json.Unmarshal(b, &spec); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, "3.0.0", spec.OpenAPI)
+	assert.Contains(t, spec.Paths["/documents"], "post")
+	assert.Contains(t, spec.Paths["/openapi.json"], "get")
+}
@@ -0,0 +1,268 @@
+// Command goyav-cli uploads files to a GoyAV server, waits for their verdicts and reports them. It exits with
+// status 1 when a file is infected, so that a CI pipeline can fail on infected build artifacts.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"goyav/pkg/client"
+	"goyav/pkg/helper"
+	"io"
+	"io/fs"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"text/tabwriter"
+	"time"
+)
+
+// Exit statuses of goyav-cli.
+const (
+	exitClean    = 0 // every file is clean
+	exitInfected = 1 // a file at least is infected
+	exitFailure  = 2 // no file is infected but a file at least could not be analyzed, or the command is invalid
+)
+
+// config holds the flags of goyav-cli.
+type config struct {
+	url          string
+	apiKey       string
+	priority     string
+	engine       string
+	format       string
+	concurrency  int
+	wait         bool
+	pollInterval time.Duration
+	waitTimeout  time.Duration
+}
+
+// file is a file to upload, tag is its path relative to the directory given on the command line, if any.
+type file struct {
+	index int
+	path  string
+	tag   string
+}
+
+// result is the outcome of the upload of a file.
+type result struct {
+	File     string           `json:"file"`
+	ID       string           `json:"id,omitempty"`
+	Status   string           `json:"status,omitempty"`
+	Threat   string           `json:"threat,omitempty"`
+	Error    string           `json:"error,omitempty"`
+	Document *client.Document `json:"document,omitempty"`
+}
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr))
+}
+
+// run implements "goyav-cli [flags] <file|dir>...": it uploads the files, and every regular file found under the
+// directories, then writes their verdicts to stdout and returns the exit status.
+func run(args []string, stdout, stderr io.Writer) int {
+	var cfg config
+	fset := flag.NewFlagSet("goyav-cli", flag.ContinueOnError)
+	fset.SetOutput(stderr)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: goyav-cli [flags] <file|dir>...")
+		fset.PrintDefaults()
+	}
+	fset.StringVar(&cfg.url, "url", helper.GetEnvWithDefault("GOYAV_URL", "http://localhost:80"), "base URL of the GoyAV server (env GOYAV_URL)")
+	fset.StringVar(&cfg.apiKey, "api-key", os.Getenv("GOYAV_API_KEY"), "API key sent in the X-API-Key header (env GOYAV_API_KEY)")
+	fset.StringVar(&cfg.priority, "priority", client.PriorityInteractive, "priority of the analyses, interactive or batch")
+	fset.StringVar(&cfg.engine, "engine", "", "comma-separated names of the engines analyzing the files, all of them by default")
+	fset.StringVar(&cfg.format, "format", "table", "output format, table or json")
+	fset.IntVar(&cfg.concurrency, "concurrency", 4, "number of concurrent uploads")
+	fset.BoolVar(&cfg.wait, "wait", true, "wait for the verdict of each uploaded file")
+	fset.DurationVar(&cfg.pollInterval, "poll-interval", 2*time.Second, "interval between two checks of a pending verdict")
+	fset.DurationVar(&cfg.waitTimeout, "wait-timeout", 5*time.Minute, "maximum time to wait for the verdict of a file")
+	if err := fset.Parse(args); err != nil {
+		return exitFailure
+	}
+	if err := validate(cfg, fset.NArg()); err != nil {
+		fmt.Fprintln(stderr, "goyav-cli:", err)
+		fset.Usage()
+		return exitFailure
+	}
+
+	c, err := client.New(cfg.url, client.WithAPIKey(cfg.apiKey), client.WithPriority(cfg.priority), client.WithEngine(cfg.engine))
+	if err != nil {
+		fmt.Fprintln(stderr, "goyav-cli:", err)
+		return exitFailure
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	files, err := collect(fset.Args())
+	if err != nil {
+		fmt.Fprintln(stderr, "goyav-cli:", err)
+		return exitFailure
+	}
+
+	results := make([]result, len(files))
+	jobs := make(chan file)
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for f := range jobs {
+				results[f.index] = scan(ctx, c, cfg, f)
+			}
+		}()
+	}
+	for _, f := range files {
+		jobs <- f
+	}
+	close(jobs)
+	wg.Wait()
+
+	if cfg.format == "json" {
+		err = writeJSON(stdout, results)
+	} else {
+		err = writeTable(stdout, results)
+	}
+	if err != nil {
+		fmt.Fprintln(stderr, "goyav-cli: failed to write the results:", err)
+		return exitFailure
+	}
+	return exitStatus(results, cfg.wait)
+}
+
+// validate checks the flags and that n files or directories are given.
+func validate(cfg config, n int) error {
+	switch {
+	case n == 0:
+		return errors.New("expected at least one file or directory")
+	case cfg.concurrency < 1:
+		return fmt.Errorf("invalid concurrency %d", cfg.concurrency)
+	case cfg.priority != client.PriorityBatch && cfg.priority != client.PriorityInteractive:
+		return fmt.Errorf("invalid priority %q", cfg.priority)
+	case cfg.format != "table" && cfg.format != "json":
+		return fmt.Errorf("invalid format %q", cfg.format)
+	}
+	return nil
+}
+
+// collect returns the files to upload: the regular files of paths, and those found under its directories.
+func collect(paths []string) ([]file, error) {
+	var files []file
+	for _, root := range paths {
+		info, err := os.Stat(root)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, file{index: len(files), path: root, tag: fileTag(filepath.Dir(root), root)})
+			continue
+		}
+		err = filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if d.Type().IsRegular() {
+				files = append(files, file{index: len(files), path: path, tag: fileTag(root, path)})
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return files, nil
+}
+
+// fileTag returns the tag of an uploaded file: its path relative to dir, or its base name if the relative path
+// is longer than helper.TagMaxLength.
+func fileTag(dir, path string) string {
+	tag, err := filepath.Rel(dir, path)
+	if err != nil || len(tag) > helper.TagMaxLength {
+		tag = filepath.Base(path)
+	}
+	if len(tag) > helper.TagMaxLength {
+		tag = tag[len(tag)-helper.TagMaxLength:]
+	}
+	return filepath.ToSlash(tag)
+}
+
+// scan uploads f then waits for its verdict if cfg.wait is set.
+func scan(ctx context.Context, c *client.Client, cfg config, f file) result {
+	res := result{File: f.path}
+
+	r, err := os.Open(f.path)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.ID, _, err = c.Upload(ctx, filepath.Base(f.path), f.tag, r)
+	r.Close()
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+
+	res.Status = client.StatusPending
+	if !cfg.wait {
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.waitTimeout)
+	defer cancel()
+	doc, err := c.Await(ctx, res.ID, cfg.pollInterval)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.Status, res.Threat, res.Document = doc.Status, doc.Threat, doc
+	return res
+}
+
+// exitStatus returns exitInfected if a file is infected, otherwise exitFailure if a file could not be analyzed,
+// or is still pending while the verdicts are awaited, and exitClean otherwise.
+func exitStatus(results []result, wait bool) int {
+	status := exitClean
+	for _, res := range results {
+		switch {
+		case res.Status == client.StatusInfected:
+			return exitInfected
+		case res.Error != "",
+			res.Status == client.StatusTimeout,
+			res.Status == client.StatusError,
+			res.Status == client.StatusTooLarge,
+			res.Status == client.StatusPending && wait:
+			status = exitFailure
+		}
+	}
+	return status
+}
+
+// writeTable writes results as an aligned table, one line per file.
+func writeTable(w io.Writer, results []result) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "FILE\tID\tSTATUS\tTHREAT\tERROR")
+	for _, res := range results {
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", res.File, dash(res.ID), dash(res.Status), dash(res.Threat), dash(res.Error))
+	}
+	return tw.Flush()
+}
+
+// writeJSON writes results as an indented JSON array.
+func writeJSON(w io.Writer, results []result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(results)
+}
+
+// dash returns s, or "-" if s is empty, so that the columns of the table stay aligned.
+func dash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
@@ -0,0 +1,322 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"errors"
+	"flag"
+	"fmt"
+	"goyav/pkg/client"
+	"goyav/pkg/helper"
+	"io"
+	"io/fs"
+	"log/slog"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"sync"
+	"syscall"
+	"time"
+)
+
+// importHeader is the header of the CSV report written by the import subcommand.
+var importHeader = []string{"file", "id", "verdict", "error"}
+
+// importJob is a file to import. ID is set when a previous run already uploaded the file.
+type importJob struct {
+	path string
+	ID   string
+}
+
+// importResult is a line of the CSV report.
+type importResult struct {
+	path    string
+	ID      string
+	verdict string
+	err     error
+}
+
+// importConfig holds the flags of the import subcommand.
+type importConfig struct {
+	dir          string
+	url          string
+	apiKey       string
+	priority     string
+	engine       string
+	report       string
+	concurrency  int
+	rate         float64
+	wait         bool
+	pollInterval time.Duration
+	waitTimeout  time.Duration
+}
+
+// runImport implements "goyav import <dir>": it uploads every regular file found under dir to a
+// GoyAV server and records file, ID and verdict of each upload in a CSV report. Files already
+// reported with a final verdict are skipped, so an interrupted import resumes where it stopped.
+func runImport(args []string) error {
+	var cfg importConfig
+	fset := flag.NewFlagSet("import", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: goyav import [flags] <dir>")
+		fset.PrintDefaults()
+	}
+	fset.StringVar(&cfg.url, "url", helper.GetEnvWithDefault("GOYAV_URL", "http://localhost:80"), "base URL of the GoyAV server (env GOYAV_URL)")
+	fset.StringVar(&cfg.apiKey, "api-key", os.Getenv("GOYAV_API_KEY"), "API key sent in the X-API-Key header (env GOYAV_API_KEY)")
+	fset.StringVar(&cfg.priority, "priority", client.PriorityBatch, "priority of the analyses, batch or interactive")
+	fset.StringVar(&cfg.engine, "engine", "", "comma-separated names of the engines analyzing the files, all of them by default")
+	fset.StringVar(&cfg.report, "report", "goyav-import.csv", "path of the CSV report, read back to resume an interrupted import")
+	fset.IntVar(&cfg.concurrency, "concurrency", 4, "number of concurrent uploads")
+	fset.Float64Var(&cfg.rate, "rate", 0, "maximum number of uploads per second, 0 means unlimited")
+	fset.BoolVar(&cfg.wait, "wait", true, "wait for the verdict of each uploaded file")
+	fset.DurationVar(&cfg.pollInterval, "poll-interval", 2*time.Second, "interval between two checks of a pending verdict")
+	fset.DurationVar(&cfg.waitTimeout, "wait-timeout", 5*time.Minute, "maximum time to wait for the verdict of a file")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+	if fset.NArg() != 1 {
+		fset.Usage()
+		return errors.New("import: expected exactly one directory")
+	}
+	cfg.dir = fset.Arg(0)
+	if cfg.concurrency < 1 {
+		return fmt.Errorf("import: invalid concurrency %d", cfg.concurrency)
+	}
+	if cfg.rate < 0 {
+		return fmt.Errorf("import: invalid rate %v", cfg.rate)
+	}
+	if cfg.priority != client.PriorityBatch && cfg.priority != client.PriorityInteractive {
+		return fmt.Errorf("import: invalid priority %q", cfg.priority)
+	}
+
+	c, err := client.New(cfg.url, client.WithAPIKey(cfg.apiKey), client.WithPriority(cfg.priority), client.WithEngine(cfg.engine))
+	if err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+
+	done, err := readImportReport(cfg.report, cfg.wait)
+	if err != nil {
+		return fmt.Errorf("import: failed to read the report: %w", err)
+	}
+
+	report, err := openImportReport(cfg.report)
+	if err != nil {
+		return fmt.Errorf("import: failed to open the report: %w", err)
+	}
+	defer report.Close()
+	w := csv.NewWriter(report)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	jobs := make(chan importJob)
+	results := make(chan importResult)
+
+	var wg sync.WaitGroup
+	for i := 0; i < cfg.concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range jobs {
+				results <- importFile(ctx, c, cfg, job)
+			}
+		}()
+	}
+
+	walkErr := make(chan error, 1)
+	go func() {
+		defer close(jobs)
+		walkErr <- walkImportDir(ctx, cfg, done, jobs)
+	}()
+
+	go func() {
+		wg.Wait()
+		close(results)
+	}()
+
+	var imported, failed int
+	for res := range results {
+		line := []string{res.path, res.ID, res.verdict, ""}
+		if res.err != nil {
+			line[3] = res.err.Error()
+			failed++
+			slog.Warn("import: failed to import file", "file", res.path, "error", res.err.Error())
+		} else {
+			imported++
+		}
+		// flushing each line keeps the report usable to resume an interrupted import
+		w.Write(line)
+		w.Flush()
+	}
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("import: failed to write the report: %w", err)
+	}
+
+	var skipped int
+	for _, ID := range done {
+		if ID == "" {
+			skipped++
+		}
+	}
+	slog.Info("import: done", "imported", imported, "failed", failed, "skipped", skipped, "report", cfg.report)
+	if err := <-walkErr; err != nil {
+		return fmt.Errorf("import: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("import: %d file(s) failed, run the import again to retry them", failed)
+	}
+	return nil
+}
+
+// walkImportDir sends the files found under cfg.dir to jobs, at cfg.rate files per second at most.
+// done holds the IDs of the files imported by a previous run: files with an empty ID are skipped
+// and files with an ID are only checked for their verdict.
+func walkImportDir(ctx context.Context, cfg importConfig, done map[string]string, jobs chan<- importJob) error {
+	var tick <-chan time.Time
+	if cfg.rate > 0 {
+		ticker := time.NewTicker(time.Duration(float64(time.Second) / cfg.rate))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+
+	// the report must not be imported if it is written in the imported directory
+	report, _ := filepath.Abs(cfg.report)
+
+	return filepath.WalkDir(cfg.dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		if abs, _ := filepath.Abs(path); abs == report {
+			return nil
+		}
+		job := importJob{path: path}
+		if ID, ok := done[path]; ok {
+			if ID == "" {
+				return nil
+			}
+			job.ID = ID
+		} else if tick != nil {
+			select {
+			case <-tick:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+		select {
+		case jobs <- job:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	})
+}
+
+// importFile uploads a file, unless the job already has an ID, then waits for its verdict if cfg.wait is set.
+func importFile(ctx context.Context, c *client.Client, cfg importConfig, job importJob) importResult {
+	res := importResult{path: job.path, ID: job.ID}
+
+	if res.ID == "" {
+		f, err := os.Open(job.path)
+		if err != nil {
+			res.err = err
+			return res
+		}
+		res.ID, _, err = c.Upload(ctx, filepath.Base(job.path), importTag(cfg.dir, job.path), f)
+		f.Close()
+		if err != nil {
+			res.err = err
+			return res
+		}
+	}
+
+	res.verdict = client.StatusPending
+	if !cfg.wait {
+		return res
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, cfg.waitTimeout)
+	defer cancel()
+	doc, err := c.Await(ctx, res.ID, cfg.pollInterval)
+	if err != nil {
+		res.err = err
+		return res
+	}
+	res.verdict = doc.Status
+	return res
+}
+
+// importTag returns the tag of an imported file: its path relative to the imported directory,
+// or its base name if the relative path is longer than helper.TagMaxLength.
+func importTag(dir, path string) string {
+	tag, err := filepath.Rel(dir, path)
+	if err != nil || len(tag) > helper.TagMaxLength {
+		tag = filepath.Base(path)
+	}
+	if len(tag) > helper.TagMaxLength {
+		tag = tag[len(tag)-helper.TagMaxLength:]
+	}
+	return filepath.ToSlash(tag)
+}
+
+// readImportReport reads the report of a previous run, if any. It returns the files to skip,
+// mapped to an empty ID, and the files uploaded but still pending, mapped to their ID.
+// Pending files are skipped as well when the verdicts are not awaited.
+func readImportReport(name string, wait bool) (map[string]string, error) {
+	done := make(map[string]string)
+	f, err := os.Open(name)
+	if errors.Is(err, fs.ErrNotExist) {
+		return done, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	r := csv.NewReader(f)
+	r.FieldsPerRecord = len(importHeader)
+	for {
+		line, err := r.Read()
+		if err == io.EOF {
+			return done, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		path, ID, verdict, failure := line[0], line[1], line[2], line[3]
+		switch {
+		case path == importHeader[0] && ID == importHeader[1]:
+		case ID == "":
+			// the upload failed, it is retried
+		case failure == "" && (verdict != client.StatusPending || !wait):
+			done[path] = ""
+		default:
+			done[path] = ID
+		}
+	}
+}
+
+// openImportReport opens the report for appending, writing its header if it is new.
+func openImportReport(name string) (*os.File, error) {
+	f, err := os.OpenFile(name, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	if info.Size() == 0 {
+		w := csv.NewWriter(f)
+		w.Write(importHeader)
+		w.Flush()
+		if err := w.Error(); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	return f, nil
+}
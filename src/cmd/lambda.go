@@ -0,0 +1,52 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/adapter/lambda"
+	"goyav/internal/app"
+	"log/slog"
+	"os"
+)
+
+// runLambda implements "goyav lambda", which is also run when GoyAV is started by the Lambda runtime:
+// it handles the S3 events the function is invoked with, analyzing the created objects and tagging
+// them with their verdicts.
+func runLambda() error {
+	api := os.Getenv("AWS_LAMBDA_RUNTIME_API")
+	if api == "" {
+		return errors.New("lambda: AWS_LAMBDA_RUNTIME_API is not set, the lambda mode must be run by the Lambda runtime")
+	}
+	ctx := context.Background()
+	rt := lambda.NewRuntime(api)
+
+	h, err := buildLambdaHandler()
+	if err != nil {
+		// the error is reported to Lambda as well, so that it shows in the invocation
+		if rerr := rt.InitError(ctx, err); rerr != nil {
+			slog.Error("GoyAV failed to report its initialization error", "error", rerr.Error())
+		}
+		return err
+	}
+
+	slog.Info("Starting GoyAV in lambda mode")
+	return rt.Run(ctx, h.Handle)
+}
+
+// buildLambdaHandler assembles the document service and the handler of the S3 events.
+func buildLambdaHandler() (*lambda.Handler, error) {
+	cfg, err := app.LoadConfig()
+	if err != nil {
+		return nil, fmt.Errorf("lambda: setup failed: %w", err)
+	}
+	goyav, err := app.BuildService(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("lambda: failed to initiate the service: %w", err)
+	}
+	h, err := app.ProvideLambdaHandler(cfg.Lambda, cfg.Server.MaxUploadSize, goyav.Service)
+	if err != nil {
+		return nil, fmt.Errorf("lambda: failed to create the S3 client: %w", err)
+	}
+	return h, nil
+}
@@ -1,57 +1,104 @@
 package main
 
 import (
-	"fmt"
-	"goyav/internal/adapter/web"
-	"goyav/internal/core/port"
-	"goyav/internal/service"
+	"context"
+	"errors"
+	"goyav/internal/app"
 	"log/slog"
+	"net"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 )
 
 func main() {
 
-	var (
-		byteRepo          port.BinaryRepository
-		docRepo           port.DocumentRepository
-		analyzer          port.AntivirusAnalyzer
-		host              string
-		port              int64
-		maxUploadSize     uint64
-		uploadTimeout     uint64
-		version           string
-		information       string
-		resultTTL         time.Duration
-		semaphoreCapacity uint64
-		err               error
-	)
+	if len(os.Args) > 1 && os.Args[1] == "import" {
+		if err := runImport(os.Args[2:]); err != nil {
+			slog.Error("GoyAV import failed", "error", err.Error())
+			os.Exit(1)
+		}
+		return
+	}
 
-	// Setup application configurations
-	if err = setup(&host, &port, &maxUploadSize, &uploadTimeout, &version, &information, &resultTTL, &semaphoreCapacity, &byteRepo, &docRepo, &analyzer); err != nil {
-		slog.Error("GoyAV failed to setup", "error", err.Error())
+	flushLogs, err := setLogger()
+	if err != nil {
+		slog.Error("GoyAV failed to setup the logs", "error", err.Error())
 		os.Exit(1)
 	}
+	defer flushLogs()
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		if err := runMigrate(os.Args[2:]); err != nil {
+			slog.Error("GoyAV migration failed", "error", err.Error())
+			flushLogs()
+			os.Exit(1)
+		}
+		return
+	}
+
+	// The Lambda runtime starts the function without arguments
+	if (len(os.Args) > 1 && os.Args[1] == "lambda") || os.Getenv("AWS_LAMBDA_RUNTIME_API") != "" {
+		if err := runLambda(); err != nil {
+			slog.Error("GoyAV lambda failed", "error", err.Error())
+			flushLogs()
+			os.Exit(1)
+		}
+		return
+	}
 
-	service, err := service.New(byteRepo, docRepo, analyzer, version, information, resultTTL, semaphoreCapacity)
+	// Setup application configurations
+	cfg, err := app.LoadConfig()
 	if err != nil {
-		slog.Error("GoyAV failed to initiate the serive", "error", err.Error())
+		slog.Error("GoyAV failed to setup", "error", err.Error())
+		flushLogs()
 		os.Exit(1)
 	}
 
-	// Setting up HTTP server
-	mux := web.NewDocumentMux(service, maxUploadSize)
-	server := http.Server{
-		ReadTimeout: time.Duration(uploadTimeout) * time.Second,
-		Addr:        fmt.Sprintf("%v:%v", host, port),
-		Handler:     mux,
+	// Assemble the repositories, the analyzer, the service and the HTTP server
+	goyav, err := app.Build(cfg)
+	if err != nil {
+		slog.Error("GoyAV failed to initiate the service", "error", err.Error())
+		flushLogs()
+		os.Exit(1)
 	}
 
 	// Starting HTTP server
-	slog.Info("Starting GoyAV")
-	if err = server.ListenAndServe(); err != nil {
+	slog.Info("Starting GoyAV", "network", goyav.Listener.Addr().Network(), "address", goyav.Listener.Addr().String())
+	err = serve(goyav.Server, goyav.Listener)
+	if cerr := goyav.Close(); cerr != nil {
+		slog.Error("GoyAV failed to release its resources", "error", cerr.Error())
+	}
+	if err != nil {
 		slog.Error("GoyAV failed to start", "error", err.Error())
+		flushLogs()
 		os.Exit(1)
 	}
 }
+
+// shutdownTimeout is the time given to the requests in flight to complete once GoyAV is asked to stop.
+const shutdownTimeout = 30 * time.Second
+
+// serve serves the requests accepted on ln with server until SIGINT or SIGTERM, then stops accepting new ones and
+// lets those in flight complete, so that a restart through a systemd socket unit, which keeps accepting the
+// connections meanwhile, loses none of them.
+func serve(server *http.Server, ln net.Listener) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	stopped := make(chan error, 1)
+	go func() {
+		<-ctx.Done()
+		slog.Info("Stopping GoyAV", "timeout", shutdownTimeout.String())
+		sctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		stopped <- server.Shutdown(sctx)
+	}()
+
+	if err := server.Serve(ln); !errors.Is(err, http.ErrServerClosed) {
+		return err
+	}
+	return <-stopped
+}
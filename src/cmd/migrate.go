@@ -0,0 +1,48 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/app"
+	"log/slog"
+)
+
+// runMigrate implements "goyav migrate": it applies the pending migrations of the database configured by the
+// GOYAV_POSTGRES_* variables and partitions its documents table if required, or only checks that none is pending with -check, so that the migrations can be
+// run as a deployment step rather than at startup.
+func runMigrate(args []string) error {
+	fset := flag.NewFlagSet("migrate", flag.ContinueOnError)
+	fset.Usage = func() {
+		fmt.Fprintln(fset.Output(), "usage: goyav migrate [flags]")
+		fset.PrintDefaults()
+	}
+	check := fset.Bool("check", false, "only check that the schema of the database is up to date")
+	if err := fset.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, err := app.LoadPostgresConfig()
+	if err != nil {
+		return fmt.Errorf("migrate: setup failed: %w", err)
+	}
+	db, err := app.ProvideDB(*cfg)
+	if err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	defer db.Close()
+
+	ctx := context.Background()
+	if *check {
+		if err = docrepo.CheckSchemaVersion(ctx, db); err != nil {
+			return fmt.Errorf("migrate: %w", err)
+		}
+		slog.Info("database schema up to date")
+		return nil
+	}
+	if err = app.MigrateDB(ctx, *cfg, db); err != nil {
+		return fmt.Errorf("migrate: %w", err)
+	}
+	return nil
+}
@@ -1,273 +1,155 @@
 package main
 
 import (
-	"database/sql"
 	"errors"
 	"fmt"
-	"goyav/internal/adapter/antivirus"
-	"goyav/internal/adapter/storage/binaryrepo"
-	"goyav/internal/adapter/storage/docrepo"
-	"goyav/internal/core/port"
-	"goyav/internal/service"
+	"goyav/internal/adapter/otlp"
 	"goyav/pkg/helper"
+	"io"
 	"log/slog"
+	"math"
+	"net/url"
 	"os"
 	"strconv"
+	"strings"
 	"time"
-
-	"github.com/minio/minio-go/v7"
-	"github.com/minio/minio-go/v7/pkg/credentials"
-)
-
-const (
-	// Default upload size limit in bytes : 1 Mib
-	DefaultMaxUploadSize    uint64        = 1 << 20
-	DefaultUploadTimeout    uint64        = 10
-	DefaultResultTimeToLive time.Duration = time.Hour
 )
 
-// setup initializes the GoyAV application with necessary configurations.
-// It configures the host, port, max upload size, version, and information for the application,
-// along with initializing byte repository, document repository and antivirus analyzer
-func setup(host *string, port *int64, maxUploadSize *uint64, uploadTimeout *uint64, ver *string, info *string, resTTL *time.Duration, semaphoreCapacity *uint64, b *port.BinaryRepository, d *port.DocumentRepository, a *port.AntivirusAnalyzer) error {
-	var err error
-
-	setLogger()
-
-	// Configure host (default: localhost) and port (default : 80)
-	*host = helper.GetEnvWithDefault("GOYAV_HOST", "localhost")
-	*port, err = strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_PORT", "80"), 10, 64)
-	if err != nil {
-		return errors.New("GOYAV_PORT must be a valid port number")
-	}
-	slog.Info("server configuration", "host", *host, "port", *port)
-
-	// Configure version
-	*ver, err = helper.GetEnvWithError("GOYAV_VERSION")
-	if err != nil {
-		return errors.New("GOYAV_VERSION must be set")
-	}
-	slog.Info("application version set", "version", *ver)
-
-	// Configure information (default: GoyAV)
-	*info = helper.GetEnvWithDefault("GOYAV_INFORMATION", "GoyAV")
-	slog.Info("application information set", "information", *info)
-
-	// Configure maximum upload size (default: 1 MiB)
-	*maxUploadSize, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_MAX_UPLOAD_SIZE", ""), 10, 64)
-	if err != nil || *maxUploadSize == 0 {
-		*maxUploadSize = DefaultMaxUploadSize
-		slog.Warn("setting maximum upload size set to default", "default (bytes)", *maxUploadSize)
-	}
-	slog.Info("maximum upload size set", "size (bytes)", *maxUploadSize)
-
-	// Configure upload timeout in seconds (default: 10 seconds)
-	*uploadTimeout, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_UPLOAD_TIMEOUT", ""), 10, 64)
-	if err != nil || *uploadTimeout <= 0 {
-		*uploadTimeout = DefaultUploadTimeout
-		slog.Warn("setting upload timeout to default", "default (seconds)", DefaultUploadTimeout)
-	}
-	slog.Info("upload timeout set", "timeout (seconds)", uploadTimeout)
-
-	// Configure result time to live (default: 1 hour)
-	*resTTL, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_RESULT_TTL", "1h"))
-	if err != nil {
-		*resTTL = DefaultResultTimeToLive
-		slog.Warn("setting result time to live to default", "default", resTTL.String())
-	}
-	slog.Info("result time to live set", "duration", (*resTTL).String())
-	slog.Info("document repository auto-purge set", "auto-purge ?", *resTTL > 0)
-
-	// Configure semaphore capacity (default: 128 goroutines)
-	*semaphoreCapacity, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_SEMAPHORE_CAPACITY", "128"), 10, 64)
-	if err != nil {
-		*semaphoreCapacity = service.DefaultSemaphoreCapacity
-		slog.Warn("setting semaphore capacity to default", "default", "128 goroutines")
-	}
-	slog.Info("semaphore capacity set", "capacity (goroutines)", semaphoreCapacity)
-
-	// Initialize byte repository
-	if err = setupMinioByteRepository(b); err != nil {
-		return fmt.Errorf("error while creating binary repository: %w", err)
-	}
-
-	// Initialize document repository
-	if err = setupPostgresDocumentRepository(d); err != nil {
-		return fmt.Errorf("error while creating document repository: %w", err)
-	}
-
-	// Initialize antivirus analyzer
-	if err = setupClamAVAnalyzer(a); err != nil {
-		return fmt.Errorf("error while creating antivirus analyzer: %w", err)
-	}
-
-	return nil
+// logComponents are the packages of the components whose level is set by GOYAV_LOG_LEVELS, along with their
+// sub-packages.
+var logComponents = map[string]string{
+	"web":      "goyav/internal/adapter/web",
+	"service":  "goyav/internal/service",
+	"clamav":   "goyav/internal/adapter/antivirus",
+	"minio":    "goyav/internal/adapter/storage/binaryrepo",
+	"postgres": "goyav/internal/adapter/storage/docrepo",
 }
 
-// setupMinioByteRepository configures a s3 binary repository for storing binary data of files.
-func setupMinioByteRepository(b *port.BinaryRepository) error {
-	var err error
+// setLogger sets the default logger, writing JSON lines to stdout, or to the rotating log file GOYAV_LOG_FILE if it is
+// set, and to stdout as well if GOYAV_LOG_STDOUT is true, and exporting the records with OTLP if
+// GOYAV_OTLP_LOGS_ENDPOINT is set. The components of GOYAV_LOG_LEVELS log at their own level. It returns the function
+// exporting the last records, to be called once GoyAV stops.
+func setLogger() (func(), error) {
+	var level slog.Level = slog.LevelInfo
 
-	// Retrieve the s3 endpoint endpoint : host and port without protocol
-	endpoint, err := helper.GetEnvWithError("GOYAV_S3_ENDPOINT_URL")
-	if err != nil {
-		return err
+	isDubugMode, _ := strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_DEBUG_MODE", "false"))
+	if isDubugMode {
+		level = slog.LevelDebug
 	}
-	slog.Info("configuring s3 bucket", "endpoint URL", endpoint)
 
-	// Retrieve s3 access key ID with error check
-	accessKeyID, err := helper.GetEnvWithError("GOYAV_S3_ACCESS_KEY")
+	levels, err := parseLogLevels(helper.GetEnvWithDefault("GOYAV_LOG_LEVELS", ""))
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("GOYAV_LOG_LEVELS is not valid: %w", err)
 	}
-	slog.Info("configuring s3 bucket", "access key ID", accessKeyID)
 
-	// Retrieve s3 secret key with error check
-	secretKey, err := helper.GetEnvWithError("GOYAV_S3_SECRET_KEY")
+	out, err := logOutput()
 	if err != nil {
-		return err
+		return nil, err
 	}
-	slog.Debug("configuring s3 bucket", "secret key", secretKey)
-
-	// Retrieve s3 bucket name configuration
-	bucketName := helper.GetEnvWithDefault("GOYAV_S3_BUCKET_NAME", "goyav")
-	slog.Info("configuring s3 bucket", "bucket name", bucketName)
-
-	// Parse and validate s3 SSL usage
-	useSSL, err := strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_S3_USE_SSL", "false"))
+	exporter, err := logExporter()
 	if err != nil {
-		return errors.New("GOYAV_S3_USE_SSL must be true or false")
+		return nil, err
 	}
-	slog.Info("configuring s3 bucket", "use ssl ?", useSSL)
 
-	// Create s3 client
-	cli, err := minio.New(endpoint, &minio.Options{
-		Creds:  credentials.NewStaticV4(accessKeyID, secretKey, ""),
-		Secure: useSSL,
-	})
-	if err != nil {
-		return err
+	// The levels of the components are enforced once the JSON handler lets through the lowest.
+	var handler slog.Handler = slog.NewJSONHandler(out, &slog.HandlerOptions{Level: slog.Level(math.MinInt)})
+	flush := func() {}
+	if exporter != nil {
+		handler = helper.NewTeeHandler(handler, exporter.Handler())
+		flush = func() { exporter.Close() }
 	}
 
-	*b, err = binaryrepo.NewMinio(cli, bucketName)
-	if err != nil {
-		return err
-	}
-
-	slog.Info("minio repository setup complete")
-	return nil
+	// The log lines of a request carry its request ID.
+	slog.SetDefault(slog.New(helper.NewRequestIDHandler(helper.NewPackageLevelHandler(handler, level, levels))))
+	return flush, nil
 }
 
-// setupPostgresDocumentRepository configures a Postgres document repository.
-func setupPostgresDocumentRepository(d *port.DocumentRepository) error {
-	var err error
+// logExporter returns the exporter of the logs configured by GOYAV_OTLP_LOGS_ENDPOINT, GOYAV_OTLP_HEADERS,
+// GOYAV_OTLP_SERVICE_NAME and GOYAV_OTLP_TIMEOUT, or nil if the logs are not exported.
+func logExporter() (*otlp.LogExporter, error) {
+	endpoint := helper.GetEnvWithDefault("GOYAV_OTLP_LOGS_ENDPOINT", "")
+	if endpoint == "" {
+		return nil, nil
+	}
+	if u, err := url.Parse(endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, errors.New("GOYAV_OTLP_LOGS_ENDPOINT must be an http or https URL")
+	}
+	headers := make(map[string]string)
+	if v := helper.GetEnvWithDefault("GOYAV_OTLP_HEADERS", ""); v != "" {
+		for _, pair := range strings.Split(v, ",") {
+			k, v, found := strings.Cut(pair, "=")
+			if k = strings.TrimSpace(k); !found || k == "" {
+				return nil, errors.New(`GOYAV_OTLP_HEADERS must be a comma-separated list of "name=value" pairs`)
+			}
+			headers[k] = strings.TrimSpace(v)
+		}
+	}
+	timeout, err := time.ParseDuration(helper.GetEnvWithDefault("GOYAV_OTLP_TIMEOUT", otlp.DefaultTimeout.String()))
+	if err != nil || timeout <= 0 {
+		return nil, errors.New("GOYAV_OTLP_TIMEOUT must be a strictly positive duration")
+	}
+	return otlp.NewLogExporter(endpoint, headers, helper.GetEnvWithDefault("GOYAV_OTLP_SERVICE_NAME", "goyav"), timeout), nil
+}
 
-	// Retrieve PostgreSQL hst configuration
-	hst := helper.GetEnvWithDefault("GOYAV_POSTGRES_HOST", "127.0.0.1")
-	slog.Info("configuring postgres", "host", hst)
+// parseLogLevels parses the value of GOYAV_LOG_LEVELS, e.g. "clamav:debug,web:warn", into the levels of the packages
+// of the components, see logComponents.
+func parseLogLevels(v string) (map[string]slog.Level, error) {
+	levels := make(map[string]slog.Level)
+	if v == "" {
+		return levels, nil
+	}
+	for _, pair := range strings.Split(v, ",") {
+		component, name, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, errors.New(`expected a comma-separated list of "component:level" pairs`)
+		}
+		pkg, ok := logComponents[strings.ToLower(component)]
+		if !ok {
+			return nil, fmt.Errorf("unknown component %q, expected web, service, clamav, minio or postgres", component)
+		}
+		var level slog.Level
+		if err := level.UnmarshalText([]byte(name)); err != nil {
+			return nil, fmt.Errorf("component %q: unknown level %q, expected debug, info, warn or error", component, name)
+		}
+		if _, exists := levels[pkg]; exists {
+			return nil, fmt.Errorf("duplicated level for component %q", component)
+		}
+		levels[pkg] = level
+	}
+	return levels, nil
+}
 
-	// Parse and validate PostgreSQL port
-	prt, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_POSTGRES_PORT", "5432"), 10, 64)
-	if err != nil {
-		return errors.New("GOYAV_POSTGRES_PORT must be a valid port number")
+// logOutput returns the output of the logs, configured by GOYAV_LOG_FILE, GOYAV_LOG_MAX_SIZE, GOYAV_LOG_MAX_AGE,
+// GOYAV_LOG_MAX_BACKUPS and GOYAV_LOG_STDOUT.
+func logOutput() (io.Writer, error) {
+	path := helper.GetEnvWithDefault("GOYAV_LOG_FILE", "")
+	if path == "" {
+		return os.Stdout, nil
 	}
-	slog.Info("configuring postgres", "port", prt)
 
-	// Retrieve PostgreSQL user
-	user, err := helper.GetEnvWithError("GOYAV_POSTGRES_USER")
-	if err != nil {
-		return fmt.Errorf("GOYAV_POSTGRES_USER must be a valid user name: %w", err)
+	maxSize, err := strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_LOG_MAX_SIZE", "104857600"), 10, 64)
+	if err != nil || maxSize < 0 {
+		return nil, errors.New("GOYAV_LOG_MAX_SIZE must be a positive number of bytes")
 	}
-	slog.Info("configuring postgres", "user", user)
-
-	// Retrieve PostgreSQL user passwd
-	passwd, err := helper.GetEnvWithError("GOYAV_POSTGRES_USER_PASSWORD")
-	if err != nil {
-		return fmt.Errorf("GOYAV_POSTGRES_USER_PASSWORD is not valid: %w", err)
+	maxAge, err := time.ParseDuration(helper.GetEnvWithDefault("GOYAV_LOG_MAX_AGE", "24h"))
+	if err != nil || maxAge < 0 {
+		return nil, errors.New("GOYAV_LOG_MAX_AGE must be a positive duration")
 	}
-	slog.Debug("configuring postgres", "password", passwd)
-
-	// Retrieve PostgreSQL database name
-	dbname, err := helper.GetEnvWithError("GOYAV_POSTGRES_DB")
-	if err != nil {
-		return fmt.Errorf("GOYAV_POSTGRES_DB is not valid : %w", err)
+	maxBackups, err := strconv.Atoi(helper.GetEnvWithDefault("GOYAV_LOG_MAX_BACKUPS", "7"))
+	if err != nil || maxBackups < 0 {
+		return nil, errors.New("GOYAV_LOG_MAX_BACKUPS must be a positive number")
 	}
-	slog.Info("configuring postgres", "database name", dbname)
-
-	// Retrieve PostgreSQL schema
-	schema, err := helper.GetEnvWithError("GOYAV_POSTGRES_SCHEMA")
+	stdout, err := strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_LOG_STDOUT", "false"))
 	if err != nil {
-		return fmt.Errorf("GOYAV_POSTGRES_SCHEMA is not valid: %w", err)
+		return nil, errors.New("GOYAV_LOG_STDOUT must be true or false")
 	}
-	slog.Info("configuring postgres", "postgres schema name", schema)
-
-	// Retrieve PostgreSQL SSL usage
-	ssl := helper.GetEnvWithDefault("GOYAV_POSTGRES_SSL_MODE", "require")
-	slog.Info("configuring postgres", "postgres ssl mode", ssl)
-
-	connInfo := fmt.Sprintf("host=%v port=%v dbname=%v search_path=%v sslmode=%v user=%v password=%v", hst, prt, dbname, schema, ssl, user, passwd)
-	db, err := sql.Open("postgres", connInfo)
 
+	file, err := helper.OpenRotatingFile(path, maxSize, maxAge, maxBackups)
 	if err != nil {
-		return err
+		return nil, fmt.Errorf("GOYAV_LOG_FILE is not valid: %w", err)
 	}
-
-	// Initialize the PostgreSQL document repository
-	*d, err = docrepo.NewPotgres(db)
-	if err != nil {
-		return err
+	if stdout {
+		return io.MultiWriter(file, os.Stdout), nil
 	}
-
-	slog.Info("postgres repository setup complete")
-	return nil
-}
-
-// setupClamAVAnalyzer configures a ClamAV antivirus analyzer.
-func setupClamAVAnalyzer(a *port.AntivirusAnalyzer) error {
-	var err error
-
-	// Retrieve ClamAV host configuration
-	clamdHost := helper.GetEnvWithDefault("GOYAV_CLAMAV_HOST", "127.0.0.1")
-	slog.Info("configuring clamav", "host", clamdHost)
-
-	// Parse and validate ClamAV port
-	clamdPort, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_PORT", "3310"), 10, 64)
-	if err != nil {
-		return errors.New("GOYAV_CLAMAV_PORT must be a valid port number")
-	}
-	slog.Info("configuring clamav", "port", clamdPort)
-
-	// Parse and validate ClamAV timeout
-	clamdTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_TIMEOUT", "30"), 10, 64)
-	if err != nil {
-		return errors.New("GOYAV_CLAMAV_TIMEOUT must be a strictly positive number")
-	}
-	slog.Info("configuring clamav", "timeout", clamdTimeout)
-
-	// Initialize the ClamAV analyzer
-	*a, err = antivirus.NewClamav(clamdHost, clamdPort, clamdTimeout)
-	if err != nil {
-		return err
-	}
-
-	slog.Info("clamav analyzer setup complete")
-	return nil
-}
-
-func setLogger() {
-	var level slog.Level = slog.LevelInfo
-
-	isDubugMode, _ := strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_DEBUG_MODE", "false"))
-	if isDubugMode {
-		level = slog.LevelDebug
-	}
-
-	slog.SetDefault(
-		slog.New(slog.NewJSONHandler(
-			os.Stdout,
-			&slog.HandlerOptions{
-				Level: level,
-			}),
-		),
-	)
+	return file, nil
 }
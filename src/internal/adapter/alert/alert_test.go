@@ -0,0 +1,58 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testAlert = &domain.InfectedRateAlert{
+	At:        time.Date(2024, 3, 18, 6, 0, 0, 0, time.UTC),
+	Window:    15 * time.Minute,
+	Threshold: 0.1,
+	Rate:      0.42,
+	Infected:  21,
+	Analyzed:  50,
+}
+
+func TestWebhookNotifier(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m webhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "GoyAV alert: infected rate 42.0% above 10.0% over 15m0s: 21 infected out of 50", m.Message)
+		assert.Equal(t, "infected_rate", m.Alert.Name)
+		assert.Equal(t, float64(900), m.Alert.Window)
+		assert.Equal(t, int64(21), m.Alert.Infected)
+	}))
+	defer srv.Close()
+	assert.NoError(t, NewWebhook(srv.URL, time.Second, 0).Notify(context.Background(), testAlert))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.ErrorIs(t, NewWebhook(failing.URL, time.Second, 0).Notify(context.Background(), testAlert), port.ErrAlertDeliveryFailed)
+}
+
+func TestSlackNotifier(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m slackMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.Text == "" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Contains(t, m.Text, "infected rate 42.0% above 10.0%")
+	}))
+	defer srv.Close()
+	assert.NoError(t, NewSlack(srv.URL, time.Second).Notify(context.Background(), testAlert))
+}
@@ -0,0 +1,22 @@
+package alert
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"log/slog"
+)
+
+// LogNotifier implements port.AlertNotifier by logging the alerts as errors, to be picked up by the log collector.
+type LogNotifier struct{}
+
+// NewLog creates a notifier logging the alerts.
+func NewLog() *LogNotifier {
+	return &LogNotifier{}
+}
+
+// Notify logs alert.
+func (LogNotifier) Notify(ctx context.Context, alert *domain.InfectedRateAlert) error {
+	slog.ErrorContext(ctx, "alert - infected rate above threshold", "rate", alert.Rate, "threshold", alert.Threshold,
+		"window", alert.Window.String(), "infected", alert.Infected, "analyzed", alert.Analyzed)
+	return nil
+}
@@ -0,0 +1,34 @@
+package alert
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"sync"
+)
+
+// MockNotifier is a mock implementation of port.AlertNotifier recording the alerts instead of delivering them.
+type MockNotifier struct {
+	mu     sync.Mutex
+	alerts []*domain.InfectedRateAlert
+}
+
+// NewMock creates a new instance of MockNotifier.
+func NewMock() *MockNotifier {
+	return &MockNotifier{}
+}
+
+// Notify records a copy of alert as delivered.
+func (m *MockNotifier) Notify(_ context.Context, alert *domain.InfectedRateAlert) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	a := *alert
+	m.alerts = append(m.alerts, &a)
+	return nil
+}
+
+// Alerts returns the alerts delivered, in order.
+func (m *MockNotifier) Alerts() []*domain.InfectedRateAlert {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*domain.InfectedRateAlert(nil), m.alerts...)
+}
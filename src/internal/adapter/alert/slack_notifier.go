@@ -0,0 +1,29 @@
+package alert
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"net/http"
+	"time"
+)
+
+// SlackNotifier implements port.AlertNotifier by posting the alerts to a Slack incoming webhook URL.
+type SlackNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewSlack creates a notifier posting the alerts to the Slack incoming webhook url with requests bounded by timeout.
+func NewSlack(url string, timeout time.Duration) *SlackNotifier {
+	return &SlackNotifier{url: url, client: &http.Client{Timeout: timeout}}
+}
+
+// slackMessage is the body posted to the Slack incoming webhook URL.
+type slackMessage struct {
+	Text string `json:"text"`
+}
+
+// Notify posts alert to the Slack incoming webhook URL.
+func (s *SlackNotifier) Notify(ctx context.Context, alert *domain.InfectedRateAlert) error {
+	return post(ctx, s.client, s.url, &slackMessage{Text: ":rotating_light: *GoyAV alert*: " + alert.String()})
+}
@@ -0,0 +1,94 @@
+// Package alert implements the notification of the alerts raised by the service.
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the default timeout of the delivery of an alert.
+const DefaultTimeout = 10 * time.Second
+
+// WebhookNotifier implements port.AlertNotifier by posting the alerts as JSON to a webhook URL. Unlike the callback
+// URLs, the webhook URL is configured by the operator and may target a private address.
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a notifier posting the alerts to url with requests bounded by timeout, their bodies of at least
+// compression bytes being compressed if the webhook accepts it, see helper.CompressingTransport.
+func NewWebhook(url string, timeout time.Duration, compression int) *WebhookNotifier {
+	return &WebhookNotifier{url: url, client: &http.Client{Timeout: timeout, Transport: helper.CompressingTransport(nil, compression)}}
+}
+
+// webhookMessage is the body posted to the webhook URL.
+type webhookMessage struct {
+	Message string       `json:"message"`
+	Alert   webhookAlert `json:"alert"`
+}
+
+// webhookAlert is the alert posted to the webhook URL, its window given in seconds.
+type webhookAlert struct {
+	Name      string    `json:"name"`
+	At        time.Time `json:"at"`
+	Window    float64   `json:"window_seconds"`
+	Threshold float64   `json:"threshold"`
+	Rate      float64   `json:"rate"`
+	Infected  int64     `json:"infected"`
+	Analyzed  int64     `json:"analyzed"`
+}
+
+// Notify posts alert to the webhook URL, which must answer with a 2xx status code.
+func (w *WebhookNotifier) Notify(ctx context.Context, alert *domain.InfectedRateAlert) error {
+	return post(ctx, w.client, w.url, NewWebhookMessage(alert))
+}
+
+// NewWebhookMessage returns the body of alert posted as JSON to the webhook URLs.
+func NewWebhookMessage(alert *domain.InfectedRateAlert) any {
+	return &webhookMessage{
+		Message: "GoyAV alert: " + alert.String(),
+		Alert: webhookAlert{
+			Name:      "infected_rate",
+			At:        alert.At,
+			Window:    alert.Window.Seconds(),
+			Threshold: alert.Threshold,
+			Rate:      alert.Rate,
+			Infected:  alert.Infected,
+			Analyzed:  alert.Analyzed,
+		},
+	}
+}
+
+// post posts message as JSON to url, which must answer with a 2xx status code.
+func post(ctx context.Context, client *http.Client, url string, message any) error {
+	body, err := json.Marshal(message)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrAlertDeliveryFailed, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrAlertDeliveryFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrAlertDeliveryFailed, err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: unexpected status code %d", port.ErrAlertDeliveryFailed, resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,103 @@
+package anonymizer
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/core/port"
+)
+
+// PseudonymPrefix is the prefix of the pseudonyms, telling them apart from the values they replace.
+const PseudonymPrefix = "p_"
+
+// KeyMinLength is the minimum length in bytes of the key deriving the pseudonyms.
+const KeyMinLength = 32
+
+// SealKeyLength is the length in bytes of the AES-256 key sealing the original values.
+const SealKeyLength = 32
+
+// HMACAnonymizer derives the pseudonyms of values with HMAC-SHA256 and, if it is given a seal key,
+// keeps their original values encrypted with AES-256-GCM.
+type HMACAnonymizer struct {
+	key  []byte
+	aead cipher.AEAD // aead seals the original values, which are not kept when it is nil.
+}
+
+var ErrHMACAnonymizer = errors.New("HMACAnonymizer")
+
+// NewHMAC creates an HMACAnonymizer deriving the pseudonyms with key. The original values are kept encrypted
+// with sealKey when it is not empty, and not kept at all otherwise.
+func NewHMAC(key, sealKey []byte) (*HMACAnonymizer, error) {
+	if len(key) < KeyMinLength {
+		return nil, fmt.Errorf("%w: the key must be at least %d bytes long", ErrHMACAnonymizer, KeyMinLength)
+	}
+	a := &HMACAnonymizer{key: key}
+	if len(sealKey) == 0 {
+		return a, nil
+	}
+	if len(sealKey) != SealKeyLength {
+		return nil, fmt.Errorf("%w: the seal key must be %d bytes long", ErrHMACAnonymizer, SealKeyLength)
+	}
+	block, err := aes.NewCipher(sealKey)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHMACAnonymizer, err)
+	}
+	if a.aead, err = cipher.NewGCM(block); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrHMACAnonymizer, err)
+	}
+	return a, nil
+}
+
+// Pseudonym returns the pseudonym of a value, the empty value is kept as is.
+func (a *HMACAnonymizer) Pseudonym(value string) string {
+	if value == "" {
+		return ""
+	}
+	mac := hmac.New(sha256.New, a.key)
+	mac.Write([]byte(value))
+	return PseudonymPrefix + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Seal encrypts the original values of a document, it returns an empty string when they are not kept.
+func (a *HMACAnonymizer) Seal(values map[string]string) (string, error) {
+	if a.aead == nil || len(values) == 0 {
+		return "", nil
+	}
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w: %v", ErrHMACAnonymizer, port.ErrSealFailed, err)
+	}
+	nonce := make([]byte, a.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("%w: %w: %v", ErrHMACAnonymizer, port.ErrSealFailed, err)
+	}
+	return base64.StdEncoding.EncodeToString(a.aead.Seal(nonce, nonce, plaintext, nil)), nil
+}
+
+// Open decrypts the original values sealed by Seal.
+func (a *HMACAnonymizer) Open(sealed string) (map[string]string, error) {
+	if a.aead == nil {
+		return nil, fmt.Errorf("%w: %w: no seal key", ErrHMACAnonymizer, port.ErrOpenFailed)
+	}
+	data, err := base64.StdEncoding.DecodeString(sealed)
+	if err != nil || len(data) < a.aead.NonceSize() {
+		return nil, fmt.Errorf("%w: %w: malformed sealed values", ErrHMACAnonymizer, port.ErrOpenFailed)
+	}
+	nonce, ciphertext := data[:a.aead.NonceSize()], data[a.aead.NonceSize():]
+	plaintext, err := a.aead.Open(nil, nonce, ciphertext, nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrHMACAnonymizer, port.ErrOpenFailed, err)
+	}
+	var values map[string]string
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrHMACAnonymizer, port.ErrOpenFailed, err)
+	}
+	return values, nil
+}
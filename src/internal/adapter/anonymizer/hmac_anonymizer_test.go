@@ -0,0 +1,71 @@
+package anonymizer
+
+import (
+	"bytes"
+	"goyav/internal/core/port"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewHMAC(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), KeyMinLength)
+
+	_, err := NewHMAC(key[:KeyMinLength-1], nil)
+	assert.Error(t, err, "a short key must be rejected")
+
+	_, err = NewHMAC(key, []byte("short"))
+	assert.Error(t, err, "a seal key of the wrong length must be rejected")
+
+	_, err = NewHMAC(key, bytes.Repeat([]byte("s"), SealKeyLength))
+	assert.NoError(t, err)
+}
+
+func TestPseudonym(t *testing.T) {
+	a, err := NewHMAC(bytes.Repeat([]byte("k"), KeyMinLength), nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	other, _ := NewHMAC(bytes.Repeat([]byte("o"), KeyMinLength), nil)
+
+	p := a.Pseudonym("invoice.pdf")
+	assert.True(t, strings.HasPrefix(p, PseudonymPrefix))
+	assert.NotContains(t, p, "invoice")
+	assert.Equal(t, p, a.Pseudonym("invoice.pdf"), "a value must always have the same pseudonym")
+	assert.NotEqual(t, p, a.Pseudonym("invoice.doc"))
+	assert.NotEqual(t, p, other.Pseudonym("invoice.pdf"), "pseudonyms must depend on the key")
+	assert.Empty(t, a.Pseudonym(""))
+}
+
+func TestSealAndOpen(t *testing.T) {
+	key := bytes.Repeat([]byte("k"), KeyMinLength)
+	values := map[string]string{"tag": "invoice.pdf"}
+
+	t.Run("WithSealKey", func(t *testing.T) {
+		a, err := NewHMAC(key, bytes.Repeat([]byte("s"), SealKeyLength))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sealed, err := a.Seal(values)
+		assert.NoError(t, err)
+		assert.NotContains(t, sealed, "invoice")
+
+		opened, err := a.Open(sealed)
+		assert.NoError(t, err)
+		assert.Equal(t, values, opened)
+
+		_, err = a.Open(sealed[:len(sealed)-4])
+		assert.ErrorIs(t, err, port.ErrOpenFailed, "tampered values must not be opened")
+	})
+
+	t.Run("WithoutSealKey", func(t *testing.T) {
+		a, err := NewHMAC(key, nil)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		sealed, err := a.Seal(values)
+		assert.NoError(t, err)
+		assert.Empty(t, sealed, "original values must not be kept")
+	})
+}
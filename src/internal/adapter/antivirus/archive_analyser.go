@@ -0,0 +1,265 @@
+package antivirus
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"os"
+	"path"
+	"strings"
+)
+
+// Formats of the archives extracted by an ArchiveAnalyser.
+const (
+	formatZip  = "zip"
+	formatTar  = "tar"
+	formatGzip = "gzip"
+)
+
+var (
+	zipMagic      = []byte("PK\x03\x04")
+	zipEmptyMagic = []byte("PK\x05\x06")
+	tarMagic      = []byte("ustar")
+)
+
+// tarMagicOffset is the offset of the magic of the header of a tar entry.
+const tarMagicOffset = 257
+
+// ArchiveAnalyser is a port.ArchiveAnalyzer extracting zip, tar and gzip archives, nested ones included, and
+// analyzing their files one by one with an antivirus analyzer.
+type ArchiveAnalyser struct {
+	analyzer port.AntivirusAnalyzer
+	limits   domain.ArchiveLimits
+}
+
+// NewArchive creates an ArchiveAnalyser analyzing the files of the archives with a, within limits.
+// The nested archives deeper than limits.MaxDepth are analyzed as a whole.
+func NewArchive(a port.AntivirusAnalyzer, limits domain.ArchiveLimits) *ArchiveAnalyser {
+	return &ArchiveAnalyser{analyzer: a, limits: limits}
+}
+
+// archiveFormat returns the format of the archive starting with head, or an empty string if it is not one.
+func archiveFormat(head []byte) string {
+	switch {
+	case bytes.HasPrefix(head, zipMagic), bytes.HasPrefix(head, zipEmptyMagic):
+		return formatZip
+	case bytes.HasPrefix(head, gzipMagic):
+		return formatGzip
+	case len(head) >= tarMagicOffset+len(tarMagic) && bytes.Equal(head[tarMagicOffset:tarMagicOffset+len(tarMagic)], tarMagic):
+		return formatTar
+	default:
+		return ""
+	}
+}
+
+// AnalyzeArchive extracts the zip, tar or gzip archive of size bytes read from r, analyzes each of its files and
+// reports the verdict on each of them. The archives it holds are extracted in turn, up to the maximum depth.
+// It returns port.ErrNotAnArchive if the data is not an archive of one of these formats.
+func (a *ArchiveAnalyser) AnalyzeArchive(ctx context.Context, r io.ReaderAt, size int64) (*domain.ArchiveReport, error) {
+	head := make([]byte, tarMagicOffset+len(tarMagic))
+	n, err := r.ReadAt(head, 0)
+	if err != nil && err != io.EOF {
+		return nil, fmt.Errorf("%w: %v", port.ErrInvalidArchive, err)
+	}
+	format := archiveFormat(head[:n])
+	if format == "" {
+		return nil, port.ErrNotAnArchive
+	}
+
+	report := &domain.ArchiveReport{Status: domain.StatusClean.String()}
+	x := &extraction{ArchiveAnalyser: a, report: report}
+	if err := x.extract(ctx, format, r, size, "", 1); err != nil {
+		return nil, err
+	}
+	report.Files = len(report.Entries)
+	return report, nil
+}
+
+// extraction is the extraction of an archive, it records the verdicts of its files and counts them.
+type extraction struct {
+	*ArchiveAnalyser
+	report *domain.ArchiveReport
+	total  unpackedTotal
+}
+
+// extract extracts the archive of the given format and size read from r, at the given depth. The paths of its
+// entries are prefixed by prefix, the path of the archive if it is nested.
+func (x *extraction) extract(ctx context.Context, format string, r io.ReaderAt, size int64, prefix string, depth int) error {
+	switch format {
+	case formatZip:
+		zr, err := zip.NewReader(r, size)
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", port.ErrInvalidArchive, prefixOrRoot(prefix), err)
+		}
+		for _, f := range zr.File {
+			if f.FileInfo().IsDir() || !f.Mode().IsRegular() {
+				continue
+			}
+			if err := x.extractZipEntry(ctx, f, prefix, depth); err != nil {
+				return err
+			}
+		}
+		return nil
+	case formatTar:
+		return x.extractTar(ctx, tar.NewReader(io.NewSectionReader(r, 0, size)), prefix, depth)
+	default:
+		return x.extractGzip(ctx, io.NewSectionReader(r, 0, size), prefix, depth)
+	}
+}
+
+// extractZipEntry analyzes the file f of a zip archive.
+func (x *extraction) extractZipEntry(ctx context.Context, f *zip.File, prefix string, depth int) error {
+	rc, err := f.Open()
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", port.ErrInvalidArchive, path.Join(prefix, f.Name), err)
+	}
+	defer rc.Close()
+	return x.analyzeEntry(ctx, rc, path.Join(prefix, f.Name), int64(f.UncompressedSize64), depth)
+}
+
+// extractTar analyzes the regular files of the tar archive read from tr.
+func (x *extraction) extractTar(ctx context.Context, tr *tar.Reader, prefix string, depth int) error {
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %s: %v", port.ErrInvalidArchive, prefixOrRoot(prefix), err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		if err := x.analyzeEntry(ctx, io.LimitReader(tr, hdr.Size), path.Join(prefix, hdr.Name), hdr.Size, depth); err != nil {
+			return err
+		}
+	}
+}
+
+// extractGzip analyzes the data compressed with gzip read from r: the files of the tar archive it holds, if so,
+// or the decompressed data otherwise, named after the name stored in its header.
+func (x *extraction) extractGzip(ctx context.Context, r io.Reader, prefix string, depth int) error {
+	zr, err := gzip.NewReader(r)
+	if err != nil {
+		return fmt.Errorf("%w: %s: %v", port.ErrInvalidArchive, prefixOrRoot(prefix), err)
+	}
+	defer zr.Close()
+
+	br := bufio.NewReader(zr)
+	head, _ := br.Peek(tarMagicOffset + len(tarMagic))
+	if archiveFormat(head) == formatTar {
+		return x.extractTar(ctx, tar.NewReader(br), prefix, depth)
+	}
+
+	// The size of the decompressed data is only known once it is read.
+	name := path.Join(prefix, gzipEntryName(zr.Name, prefix))
+	f, n, err := x.spool(br, name)
+	if err != nil {
+		return err
+	}
+	defer removeSpool(f)
+	return x.analyzeEntry(ctx, io.NewSectionReader(f, 0, n), name, n, depth)
+}
+
+// analyzeEntry analyzes the file of an archive at the given depth, of size bytes read from r. It is extracted
+// in turn if it is an archive and the maximum depth is not reached.
+func (x *extraction) analyzeEntry(ctx context.Context, r io.Reader, name string, size int64, depth int) error {
+	x.total.files++
+	x.total.size += size
+	switch {
+	case x.limits.MaxFiles > 0 && x.total.files > x.limits.MaxFiles:
+		return fmt.Errorf("%w: more than %d files", port.ErrArchiveLimitExceeded, x.limits.MaxFiles)
+	case x.limits.MaxFileSize > 0 && size > x.limits.MaxFileSize:
+		return fmt.Errorf("%w: %s is larger than %d bytes", port.ErrArchiveLimitExceeded, name, x.limits.MaxFileSize)
+	case x.limits.MaxUnpackedSize > 0 && x.total.size > x.limits.MaxUnpackedSize:
+		return fmt.Errorf("%w: more than %d unpacked bytes", port.ErrArchiveLimitExceeded, x.limits.MaxUnpackedSize)
+	}
+
+	br := bufio.NewReader(r)
+	head, _ := br.Peek(tarMagicOffset + len(tarMagic))
+	if format := archiveFormat(head); format != "" && (x.limits.MaxDepth == 0 || depth < x.limits.MaxDepth) {
+		// The nested archive is not an entry itself, its files are.
+		x.total.files--
+		x.total.size -= size
+		f, n, err := x.spool(br, name)
+		if err != nil {
+			return err
+		}
+		defer removeSpool(f)
+		return x.extract(ctx, format, f, n, name, depth+1)
+	}
+
+	status, threat, err := analyzeThreat(ctx, x.analyzer, br)
+	if err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	if status == domain.StatusInfected {
+		x.report.Status = status.String()
+	}
+	x.report.Entries = append(x.report.Entries, domain.ArchiveEntry{Path: name, Status: status.String(), Threat: threat})
+	return nil
+}
+
+// analyzeThreat analyzes data with a, along with the name of the threat detected when a is a port.ThreatIdentifier.
+func analyzeThreat(ctx context.Context, a port.AntivirusAnalyzer, data io.Reader) (domain.AnalysisStatus, string, error) {
+	if t, ok := a.(port.ThreatIdentifier); ok {
+		return t.AnalyzeThreat(ctx, data)
+	}
+	status, err := a.Analyze(ctx, data)
+	return status, "", err
+}
+
+// spool copies the file name of an archive read from r to a temporary file, within the maximum file size,
+// and returns it along with its size. The file must be removed with removeSpool.
+func (x *extraction) spool(r io.Reader, name string) (*os.File, int64, error) {
+	f, err := os.CreateTemp("", "goyav-archive-*")
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create a temporary file: %w", err)
+	}
+	if x.limits.MaxFileSize > 0 {
+		r = io.LimitReader(r, x.limits.MaxFileSize+1)
+	}
+	n, err := io.Copy(f, r)
+	switch {
+	case err != nil:
+		removeSpool(f)
+		return nil, 0, fmt.Errorf("%w: %s: %v", port.ErrInvalidArchive, name, err)
+	case x.limits.MaxFileSize > 0 && n > x.limits.MaxFileSize:
+		removeSpool(f)
+		return nil, 0, fmt.Errorf("%w: %s is larger than %d bytes", port.ErrArchiveLimitExceeded, name, x.limits.MaxFileSize)
+	}
+	return f, n, nil
+}
+
+// removeSpool closes and removes a temporary file created by spool.
+func removeSpool(f *os.File) {
+	f.Close()
+	os.Remove(f.Name())
+}
+
+// gzipEntryName returns the name of the data compressed with gzip: the name stored in its header if any,
+// otherwise the name of the nested archive holding it without its .gz extension, or "data".
+func gzipEntryName(stored, prefix string) string {
+	if name := path.Base(stored); stored != "" && name != "." && name != "/" {
+		return name
+	}
+	if name := strings.TrimSuffix(path.Base(prefix), ".gz"); prefix != "" && name != "" {
+		return name
+	}
+	return "data"
+}
+
+// prefixOrRoot returns the path of a nested archive, or "." for the analyzed archive itself.
+func prefixOrRoot(prefix string) string {
+	if prefix == "" {
+		return "."
+	}
+	return prefix
+}
@@ -0,0 +1,103 @@
+package antivirus
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"context"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// zipEntry is an entry of a zip archive built by a test.
+type zipEntry struct {
+	name string
+	data []byte
+}
+
+func buildZip(t *testing.T, entries []zipEntry) []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, e := range entries {
+		w, err := zw.Create(e.name)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		w.Write(e.data)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func TestAnalyzeArchive(t *testing.T) {
+	nested := buildTar(t, []tarEntry{
+		{name: "bin/eicar.com", data: port.EICAR},
+		{name: "bin/", typeflag: tar.TypeDir},
+	}, true)
+	archive := buildZip(t, []zipEntry{
+		{name: "docs/readme.txt", data: []byte("hello")},
+		{name: "docs/", data: nil},
+		{name: "nested.tar.gz", data: nested},
+	})
+
+	t.Run("Entries", func(t *testing.T) {
+		a := NewArchive(NewMock(), domain.ArchiveLimits{MaxDepth: 3})
+		report, err := a.AnalyzeArchive(context.Background(), bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, &domain.ArchiveReport{
+			Status: "infected",
+			Files:  2,
+			Entries: []domain.ArchiveEntry{
+				{Path: "docs/readme.txt", Status: "clean"},
+				{Path: "nested.tar.gz/bin/eicar.com", Status: "infected", Threat: EICARSignature},
+			},
+		}, report)
+	})
+
+	t.Run("MaxDepth", func(t *testing.T) {
+		// the nested archive is analyzed as a whole
+		a := NewArchive(NewMock(), domain.ArchiveLimits{MaxDepth: 1})
+		report, err := a.AnalyzeArchive(context.Background(), bytes.NewReader(archive), int64(len(archive)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, []domain.ArchiveEntry{
+			{Path: "docs/readme.txt", Status: "clean"},
+			{Path: "nested.tar.gz", Status: "clean"},
+		}, report.Entries)
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		for name, limits := range map[string]domain.ArchiveLimits{
+			"MaxFiles":        {MaxFiles: 1},
+			"MaxFileSize":     {MaxFileSize: 4},
+			"MaxUnpackedSize": {MaxUnpackedSize: int64(len(port.EICAR))},
+		} {
+			t.Run(name, func(t *testing.T) {
+				a := NewArchive(NewMock(), limits)
+				_, err := a.AnalyzeArchive(context.Background(), bytes.NewReader(archive), int64(len(archive)))
+				assert.ErrorIs(t, err, port.ErrArchiveLimitExceeded)
+			})
+		}
+	})
+
+	t.Run("NotAnArchive", func(t *testing.T) {
+		a := NewArchive(NewMock(), domain.ArchiveLimits{})
+		_, err := a.AnalyzeArchive(context.Background(), bytes.NewReader(port.EICAR), int64(len(port.EICAR)))
+		assert.ErrorIs(t, err, port.ErrNotAnArchive)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		a := NewArchive(NewMock(), domain.ArchiveLimits{})
+		truncated := archive[:len(archive)/2]
+		_, err := a.AnalyzeArchive(context.Background(), bytes.NewReader(truncated), int64(len(truncated)))
+		assert.ErrorIs(t, err, port.ErrInvalidArchive)
+	})
+}
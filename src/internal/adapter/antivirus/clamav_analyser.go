@@ -6,7 +6,12 @@ import (
 	"fmt"
 	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
+	"goyav/pkg/helper"
 	"io"
+	"math"
+	"net"
+	"strconv"
+	"strings"
 	"time"
 
 	"github.com/lyimmi/go-clamd"
@@ -16,39 +21,102 @@ import (
 type ClamavAnalyser struct {
 	Analyser *clamd.Clamd  // Analyser is the ClamAV scanner instance.
 	Timeout  time.Duration // Timeout is the timeout value in seconds for operations.
+
+	address   string // address is the host and port of clamd, to which the data is streamed.
+	chunkSize int    // chunkSize is the size of the chunks of data streamed to clamd, see WithChunkSize.
+
+	// chunks are the buffers of the chunks, preceded by their length, reused from one analysis to the next.
+	chunks *helper.BufferPool
+
+	// timeoutPerMB and maxTimeout scale the timeout of an analysis with the size of the data, see WithSizeTimeout.
+	timeoutPerMB time.Duration
+	maxTimeout   time.Duration
+
+	// ops logs the failed and slow analyses with the context of their request, see WithSlowAnalyses.
+	ops helper.OperationLog
 }
 
 var ErrClamavAntiVirusAnalyser = errors.New("ClamavAntiVirusAnalyser")
 
-// NewClamav creates a new instance of ClamavAntiVirusAnalyser.
-func NewClamav(host string, port uint64, timeout uint64) (*ClamavAnalyser, error) {
+// clamavOperations logs the analyses of ClamavAnalyser, the data exceeding the StreamMaxLength of clamd being expected.
+var clamavOperations = helper.OperationLog{Component: "clamav", Ignore: []error{port.ErrAntivirusSizeLimitExceeded}}
+
+// NewClamav creates a new instance of ClamavAntiVirusAnalyser. Optional behaviours are enabled with opts.
+func NewClamav(host string, port uint64, timeout uint64, opts ...ClamavOption) (*ClamavAnalyser, error) {
 	if timeout == 0 {
 		return nil, fmt.Errorf("%w: timeout value must be a strictly positive number. given value=%v", ErrClamavAntiVirusAnalyser, timeout)
 	}
 
-	return &ClamavAnalyser{
+	a := &ClamavAnalyser{
 		Analyser: clamd.NewClamd(
 			clamd.WithTCP(host, int(port)),
 		),
-		Timeout: time.Duration(timeout) * time.Second,
-	}, nil
+		Timeout:   time.Duration(timeout) * time.Second,
+		address:   net.JoinHostPort(host, strconv.FormatUint(port, 10)),
+		chunkSize: DefaultChunkSize,
+		ops:       clamavOperations,
+	}
+	for _, opt := range opts {
+		opt(a)
+	}
+	a.chunks = helper.NewBufferPool(4 + a.chunkSize)
+	return a, nil
+}
+
+// WithSlowAnalyses makes the analyser log the analyses lasting at least threshold, along with the failed ones,
+// with the request ID of the request whose document they analyse.
+func WithSlowAnalyses(threshold time.Duration) ClamavOption {
+	return func(a *ClamavAnalyser) {
+		a.ops.Slow = max(0, threshold)
+	}
 }
 
 // Analyze performs antivirus analysis on the provided binary data.
 func (a *ClamavAnalyser) Analyze(ctx context.Context, data io.Reader) (domain.AnalysisStatus, error) {
-	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	status, _, err := a.AnalyzeThreat(ctx, data)
+	return status, err
+}
+
+// AnalyzeThreat performs antivirus analysis on the provided binary data, and returns the name of the signature
+// matched by infected data. Data larger than the StreamMaxLength of clamd fails with port.ErrAntivirusSizeLimitExceeded.
+func (a *ClamavAnalyser) AnalyzeThreat(ctx context.Context, data io.Reader) (_ domain.AnalysisStatus, _ string, err error) {
+	defer a.ops.Observe(ctx, "analysis", time.Now(), &err)
+	ctx, cancel := context.WithTimeout(ctx, a.timeout(math.MaxInt64))
 	defer cancel()
-	clean, err := a.Analyser.ScanStream(ctx, data)
-	if err != nil {
-		if errors.Is(err, clamd.ErrEICARFound) {
-			return domain.StatusInfected, nil
-		}
-		return domain.StatusPending, fmt.Errorf("%w: %w: %v", ErrClamavAntiVirusAnalyser, port.ErrAntivirusAnalysisFailed, err)
+	reply, err := a.scanStream(ctx, data)
+	switch {
+	case errors.Is(err, port.ErrAntivirusSizeLimitExceeded):
+		return domain.StatusPending, "", fmt.Errorf("%w: %w", ErrClamavAntiVirusAnalyser, err)
+	case err != nil:
+		return domain.StatusPending, "", fmt.Errorf("%w: %w: %v", ErrClamavAntiVirusAnalyser, port.ErrAntivirusAnalysisFailed, err)
+	case strings.HasSuffix(reply, sizeLimitReply):
+		return domain.StatusPending, "", fmt.Errorf("%w: %w: %v", ErrClamavAntiVirusAnalyser, port.ErrAntivirusSizeLimitExceeded, reply)
+	case strings.HasSuffix(reply, " OK"):
+		return domain.StatusClean, "", nil
+	}
+	if threat, ok := foundSignature(reply); ok {
+		return domain.StatusInfected, threat, nil
 	}
-	if !clean {
-		return domain.StatusInfected, nil
+	return domain.StatusPending, "", fmt.Errorf("%w: %w: %v", ErrClamavAntiVirusAnalyser, port.ErrAntivirusAnalysisFailed, reply)
+}
+
+// foundSignature returns the name of the signature from a response of clamd reporting a threat,
+// such as "stream: Win.Test.EICAR_HDB-1 FOUND", and whether there is one.
+func foundSignature(response string) (string, bool) {
+	for _, line := range strings.Split(response, "\n") {
+		line = strings.TrimRight(strings.TrimSpace(line), "\x00")
+		name, found := strings.CutSuffix(line, " FOUND")
+		if !found {
+			continue
+		}
+		if i := strings.LastIndex(name, ":"); i >= 0 {
+			name = name[i+1:]
+		}
+		if name = strings.TrimSpace(name); name != "" {
+			return name, true
+		}
 	}
-	return domain.StatusClean, nil
+	return "", false
 }
 
 // TimeoutValue returns the timeout value.
@@ -69,3 +137,18 @@ func (a *ClamavAnalyser) Ping() error {
 	}
 	return nil
 }
+
+// Load queries clamd's STATS command and reports its thread and queue usage.
+func (a *ClamavAnalyser) Load(ctx context.Context) (port.AnalyzerLoad, error) {
+	ctx, cancel := context.WithTimeout(ctx, a.Timeout)
+	defer cancel()
+	stats, err := a.Analyser.Stats(ctx)
+	if err != nil {
+		return port.AnalyzerLoad{}, fmt.Errorf("%w: %w: %v", ErrClamavAntiVirusAnalyser, port.ErrAntivirusAnalyserUnavailable, err)
+	}
+	return port.AnalyzerLoad{
+		BusyWorkers: stats.Threads.Live - stats.Threads.Idle,
+		MaxWorkers:  stats.Threads.Max,
+		QueuedJobs:  stats.Queue.Items,
+	}, nil
+}
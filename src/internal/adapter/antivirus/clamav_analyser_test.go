@@ -1,14 +1,20 @@
 package antivirus
 
 import (
+	"bufio"
 	"bytes"
 	"context"
+	"encoding/binary"
 	"fmt"
 	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
 	"goyav/pkg/helper"
+	"io"
 	"log"
+	"math"
+	"net"
 	"os"
+	"strconv"
 	"testing"
 	"time"
 
@@ -78,9 +84,10 @@ func TestClamavAnalyser_Analyze(t *testing.T) {
 	t.Run("InfectedData", func(t *testing.T) {
 		data := bytes.NewReader(port.EICAR)
 		ctx := context.Background()
-		status, err := analyser.Analyze(ctx, data)
+		status, threat, err := analyser.AnalyzeThreat(ctx, data)
 		assert.NoError(t, err)
 		assert.Equal(t, domain.StatusInfected, status)
+		assert.Equal(t, EICARSignature, threat)
 	})
 
 	t.Run("ContextCanceled", func(t *testing.T) {
@@ -92,6 +99,97 @@ func TestClamavAnalyser_Analyze(t *testing.T) {
 	})
 }
 
+func TestFoundSignature(t *testing.T) {
+	tests := []struct {
+		response string
+		threat   string
+		found    bool
+	}{
+		{"Win.Test.EICAR_HDB-1 FOUND\nstream: Win.Test.EICAR_HDB-1 FOUND", "Win.Test.EICAR_HDB-1", true},
+		{"unknown error\nstream: Win.Ransomware.Lockbit-9951420-0 FOUND\x00", "Win.Ransomware.Lockbit-9951420-0", true},
+		{"unknown error\nstream: OK", "", false},
+		{"unknown error\nINSTREAM size limit exceeded. ERROR", "", false},
+		{"stream: FOUND", "", false},
+	}
+	for _, tt := range tests {
+		threat, found := foundSignature(tt.response)
+		assert.Equal(t, tt.found, found, tt.response)
+		assert.Equal(t, tt.threat, threat, tt.response)
+	}
+}
+
+// fakeClamd accepts one INSTREAM command, records the length of each chunk and replies with reply.
+func fakeClamd(t *testing.T, reply string) (host string, port uint64, chunks chan []int) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	t.Cleanup(func() { l.Close() })
+	chunks = make(chan []int, 1)
+	go func() {
+		conn, err := l.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		r := bufio.NewReader(conn)
+		if _, err := r.ReadString(0); err != nil {
+			return
+		}
+		var lengths []int
+		for {
+			var n uint32
+			if err := binary.Read(r, binary.BigEndian, &n); err != nil || n == 0 {
+				break
+			}
+			lengths = append(lengths, int(n))
+			io.CopyN(io.Discard, r, int64(n))
+		}
+		chunks <- lengths
+		conn.Write([]byte(reply + "\x00"))
+	}()
+	host, p, _ := net.SplitHostPort(l.Addr().String())
+	port, _ = strconv.ParseUint(p, 10, 64)
+	return host, port, chunks
+}
+
+func TestClamavAnalyser_Stream(t *testing.T) {
+	t.Run("ChunkSize", func(t *testing.T) {
+		host, p, chunks := fakeClamd(t, "stream: OK")
+		analyser, err := NewClamav(host, p, timeout, WithChunkSize(4))
+		assert.NoError(t, err)
+
+		status, err := analyser.Analyze(ctx, bytes.NewReader([]byte("0123456789")))
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusClean, status)
+		assert.Equal(t, []int{4, 4, 2}, <-chunks)
+	})
+
+	t.Run("SizeLimitExceeded", func(t *testing.T) {
+		host, p, _ := fakeClamd(t, "INSTREAM size limit exceeded. ERROR")
+		analyser, err := NewClamav(host, p, timeout)
+		assert.NoError(t, err)
+
+		_, err = analyser.Analyze(ctx, bytes.NewReader(port.EICAR))
+		assert.ErrorIs(t, err, port.ErrAntivirusSizeLimitExceeded)
+	})
+}
+
+func TestClamavAnalyser_SizeTimeout(t *testing.T) {
+	analyser, err := NewClamav(clamavHost, clamavPort, timeout, WithSizeTimeout(time.Second, time.Minute))
+	assert.NoError(t, err)
+
+	assert.Equal(t, 10*time.Second, analyser.timeout(0))
+	assert.Equal(t, 15*time.Second, analyser.timeout(5<<20))
+	assert.Equal(t, 10*time.Second+500*time.Millisecond, analyser.timeout(512<<10))
+	assert.Equal(t, time.Minute, analyser.timeout(1<<30), "the timeout must be capped")
+	assert.Equal(t, time.Minute, analyser.timeout(math.MaxInt64), "the timeout must be capped")
+
+	analyser, err = NewClamav(clamavHost, clamavPort, timeout)
+	assert.NoError(t, err)
+	assert.Equal(t, 10*time.Second, analyser.timeout(1<<30), "the timeout must not depend on the size by default")
+}
+
 func TestClamavAnalyser_Ping(t *testing.T) {
 	analyser, err := NewClamav(clamavHost, clamavPort, timeout)
 	assert.NoError(t, err)
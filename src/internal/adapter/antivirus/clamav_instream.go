@@ -0,0 +1,111 @@
+package antivirus
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"goyav/internal/core/port"
+	"io"
+	"net"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultChunkSize is the default size of the chunks of data sent to clamd with the INSTREAM command.
+	DefaultChunkSize = 64 << 10
+
+	// sizeLimitReply ends the reply of clamd to an INSTREAM command sending more than its StreamMaxLength.
+	sizeLimitReply = "size limit exceeded. ERROR"
+)
+
+// ClamavOption configures an optional behaviour of a ClamavAnalyser.
+type ClamavOption func(*ClamavAnalyser)
+
+// WithChunkSize sets the size of the chunks of data sent to clamd, DefaultChunkSize when it is zero. Larger chunks
+// take fewer writes, but a chunk must not exceed the StreamMaxLength of clamd.
+func WithChunkSize(size uint64) ClamavOption {
+	return func(a *ClamavAnalyser) {
+		if size > 0 {
+			a.chunkSize = int(size)
+		}
+	}
+}
+
+// scanStream sends data to clamd with the INSTREAM command, in chunks of chunkSize bytes, and returns its reply,
+// such as "stream: OK". clamd closes the connection once it received more than its StreamMaxLength, so that its
+// reply is read even when the data cannot be sent any longer; if the reply was lost with the connection, its closing
+// is taken for the size limit, as clamd does not close it otherwise while the data is sent. The deadline of the
+// connection follows the timeout of the data sent so far, see WithSizeTimeout.
+func (a *ClamavAnalyser) scanStream(ctx context.Context, data io.Reader) (string, error) {
+	start := time.Now()
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "tcp", a.address)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	dl := &streamDeadline{conn: conn, start: start}
+	dl.limit, _ = ctx.Deadline()
+	dl.extend(a.timeout(0))
+	stop := context.AfterFunc(ctx, dl.cancel)
+	defer stop()
+
+	if _, err = conn.Write([]byte("zINSTREAM\x00")); err != nil {
+		return "", err
+	}
+	var sent int64
+	buf := a.chunks.Get()
+	sendErr := sendChunks(conn, data, *buf, func(n int) {
+		sent += int64(n)
+		dl.extend(a.timeout(sent))
+	})
+	a.chunks.Put(buf)
+	if errors.Is(sendErr, errReadData) {
+		return "", sendErr
+	}
+
+	reply, err := bufio.NewReader(conn).ReadString(0)
+	if reply = strings.TrimSpace(strings.TrimRight(reply, "\x00")); reply != "" {
+		return reply, nil
+	}
+	if errors.Is(sendErr, syscall.EPIPE) || errors.Is(sendErr, syscall.ECONNRESET) {
+		return "", fmt.Errorf("%w: %v", port.ErrAntivirusSizeLimitExceeded, sendErr)
+	}
+	if sendErr != nil {
+		return "", sendErr
+	}
+	if err == nil {
+		err = errors.New("empty reply")
+	}
+	return "", err
+}
+
+// errReadData is returned by sendChunks when the data cannot be read.
+var errReadData = errors.New("failed to read the data")
+
+// sendChunks writes data to w in chunks of the size of buf less 4 bytes, each preceded by its length, then the zero
+// length ending the stream. sent is called with the length of each chunk written.
+func sendChunks(w io.Writer, data io.Reader, buf []byte, sent func(int)) error {
+	for {
+		n, err := io.ReadFull(data, buf[4:])
+		if n > 0 {
+			binary.BigEndian.PutUint32(buf, uint32(n))
+			if _, werr := w.Write(buf[:4+n]); werr != nil {
+				return werr
+			}
+			sent(n)
+		}
+		if errors.Is(err, io.EOF) || errors.Is(err, io.ErrUnexpectedEOF) {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("%w: %v", errReadData, err)
+		}
+	}
+	_, err := w.Write([]byte{0, 0, 0, 0})
+	return err
+}
@@ -0,0 +1,62 @@
+package antivirus
+
+import (
+	"net"
+	"sync"
+	"time"
+)
+
+// WithSizeTimeout scales the timeout of an analysis with the size of the data: Timeout, then perMB for each MiB of
+// data, capped at max, so that a large file is given the time to be scanned without giving a tiny one as long. Since
+// the size of the data is only known once it is read, the deadline is pushed back as it is streamed to clamd. The
+// timeout is Timeout whatever the size when perMB is zero, and max is raised to Timeout when it is lower.
+func WithSizeTimeout(perMB, max time.Duration) ClamavOption {
+	return func(a *ClamavAnalyser) {
+		a.timeoutPerMB = perMB
+		a.maxTimeout = max
+	}
+}
+
+// timeout returns the timeout of the analysis of size bytes.
+func (a *ClamavAnalyser) timeout(size int64) time.Duration {
+	if a.timeoutPerMB <= 0 {
+		return a.Timeout
+	}
+	extra := float64(a.timeoutPerMB) * float64(size) / (1 << 20)
+	if extra >= float64(a.maxTimeout-a.Timeout) {
+		return max(a.maxTimeout, a.Timeout)
+	}
+	return a.Timeout + time.Duration(extra)
+}
+
+// streamDeadline sets the deadline of the connection of an analysis started at start, pushed back as the data is
+// streamed, but never beyond the deadline of its context, until the context is done.
+type streamDeadline struct {
+	mux      sync.Mutex
+	conn     net.Conn
+	start    time.Time
+	limit    time.Time // limit is the deadline of the context, if any.
+	canceled bool
+}
+
+// extend sets the deadline of the connection to timeout after the start of the analysis.
+func (d *streamDeadline) extend(timeout time.Duration) {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	if d.canceled {
+		return
+	}
+	t := d.start.Add(timeout)
+	if !d.limit.IsZero() && d.limit.Before(t) {
+		t = d.limit
+	}
+	d.conn.SetDeadline(t)
+}
+
+// cancel interrupts the reads and writes of the connection for good.
+func (d *streamDeadline) cancel() {
+	d.mux.Lock()
+	defer d.mux.Unlock()
+	d.canceled = true
+	d.conn.SetDeadline(time.Now())
+}
@@ -0,0 +1,359 @@
+package antivirus
+
+import (
+	"archive/tar"
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/klauspost/compress/zstd"
+)
+
+// maxManifestSize is the maximum size of the JSON files of an image tarball read in memory: the manifests,
+// indexes and configurations. Larger blobs are only read as layers.
+const maxManifestSize = 1 << 20
+
+var (
+	gzipMagic = []byte{0x1f, 0x8b}
+	zstdMagic = []byte{0x28, 0xb5, 0x2f, 0xfd}
+)
+
+// ImageAnalyser is a port.ImageAnalyzer unpacking the layers of container images and analyzing their files
+// one by one with an antivirus analyzer.
+type ImageAnalyser struct {
+	analyzer port.AntivirusAnalyzer
+	limits   domain.ImageLimits
+
+	// checkLinks makes the links escaping the root of the filesystem and the Windows shortcuts reported.
+	checkLinks bool
+}
+
+// NewImage creates an ImageAnalyser analyzing the files of the images with a, within limits. When checkLinks
+// is set, the symbolic and hard links whose target escapes the root of the filesystem of a layer, and the
+// Windows shortcuts, are reported along with the infected files.
+func NewImage(a port.AntivirusAnalyzer, limits domain.ImageLimits, checkLinks bool) *ImageAnalyser {
+	return &ImageAnalyser{analyzer: a, limits: limits, checkLinks: checkLinks}
+}
+
+// ociDescriptor is a reference to a blob of an OCI image layout.
+type ociDescriptor struct {
+	MediaType string `json:"mediaType"`
+	Digest    string `json:"digest"`
+}
+
+// ociManifest is an OCI image index or image manifest.
+type ociManifest struct {
+	MediaType string          `json:"mediaType"`
+	Manifests []ociDescriptor `json:"manifests"` // Manifests is set for an image index.
+	Layers    []ociDescriptor `json:"layers"`    // Layers is set for an image manifest.
+}
+
+// dockerManifest is an entry of the manifest.json file written by docker save.
+type dockerManifest struct {
+	Layers []string `json:"Layers"`
+}
+
+// imageLayer is a layer of an image to analyze.
+type imageLayer struct {
+	digest string
+	path   string // path is the path of the layer in the image tarball.
+}
+
+// AnalyzeImage unpacks the layers of the image tarball of size bytes read from r, possibly compressed, in the OCI
+// image layout or in the format of docker save, analyzes each of their regular files and reports the findings of
+// each layer. The tarball is read twice: once to resolve the layers from the manifests, then to unpack them.
+func (a *ImageAnalyser) AnalyzeImage(ctx context.Context, r io.ReaderAt, size int64) (*domain.ImageReport, error) {
+	layers, err := a.resolveLayers(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make(map[string]*domain.LayerReport, len(layers))
+	for _, l := range layers {
+		reports[l.path] = &domain.LayerReport{Digest: l.digest, Status: domain.StatusClean.String()}
+	}
+
+	tr, closeTar, err := newTarReader(io.NewSectionReader(r, 0, size))
+	if err != nil {
+		return nil, err
+	}
+	defer closeTar()
+
+	var total unpackedTotal
+	analyzed := make(map[string]bool, len(layers))
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", port.ErrInvalidImage, err)
+		}
+		name := path.Clean(hdr.Name)
+		report, ok := reports[name]
+		if !ok || analyzed[name] {
+			continue
+		}
+		analyzed[name] = true
+		if err := a.analyzeLayer(ctx, tr, report, &total); err != nil {
+			return nil, err
+		}
+	}
+
+	image := &domain.ImageReport{Status: domain.StatusClean.String(), Files: total.files}
+	for _, l := range layers {
+		if !analyzed[l.path] {
+			return nil, fmt.Errorf("%w: missing layer %s", port.ErrInvalidImage, l.digest)
+		}
+		report := reports[l.path]
+		if report.Status == domain.StatusInfected.String() {
+			image.Status = report.Status
+		}
+		image.Layers = append(image.Layers, *report)
+	}
+	return image, nil
+}
+
+// resolveLayers reads the manifests of an image tarball and returns the layers of its images, without duplicates.
+func (a *ImageAnalyser) resolveLayers(r io.Reader) ([]imageLayer, error) {
+	tr, closeTar, err := newTarReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer closeTar()
+	files := make(map[string][]byte)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", port.ErrInvalidImage, err)
+		}
+		if hdr.Typeflag != tar.TypeReg || hdr.Size > maxManifestSize {
+			continue
+		}
+		b, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %v", port.ErrInvalidImage, err)
+		}
+		files[path.Clean(hdr.Name)] = b
+	}
+
+	var layers []imageLayer
+	switch {
+	case files["index.json"] != nil:
+		if layers, err = ociLayers(files, "index.json", nil); err != nil {
+			return nil, err
+		}
+	case files["manifest.json"] != nil:
+		var manifests []dockerManifest
+		if err := json.Unmarshal(files["manifest.json"], &manifests); err != nil {
+			return nil, fmt.Errorf("%w: manifest.json: %v", port.ErrInvalidImage, err)
+		}
+		for _, m := range manifests {
+			for _, p := range m.Layers {
+				layers = append(layers, imageLayer{digest: layerDigest(p), path: path.Clean(p)})
+			}
+		}
+	default:
+		return nil, fmt.Errorf("%w: neither an OCI image layout nor a docker save archive", port.ErrInvalidImage)
+	}
+
+	seen := make(map[string]bool, len(layers))
+	unique := layers[:0]
+	for _, l := range layers {
+		if !seen[l.path] {
+			seen[l.path] = true
+			unique = append(unique, l)
+		}
+	}
+	if len(unique) == 0 {
+		return nil, fmt.Errorf("%w: no layer", port.ErrInvalidImage)
+	}
+	if a.limits.MaxLayers > 0 && len(unique) > a.limits.MaxLayers {
+		return nil, fmt.Errorf("%w: %d layers, the maximum is %d", port.ErrImageLimitExceeded, len(unique), a.limits.MaxLayers)
+	}
+	return unique, nil
+}
+
+// ociLayers returns the layers of the images referenced by the OCI index or manifest name, following nested indexes.
+// visited holds the manifests already followed.
+func ociLayers(files map[string][]byte, name string, visited map[string]bool) ([]imageLayer, error) {
+	if visited == nil {
+		visited = make(map[string]bool)
+	}
+	if visited[name] {
+		return nil, nil
+	}
+	visited[name] = true
+
+	b, ok := files[name]
+	if !ok {
+		return nil, fmt.Errorf("%w: missing manifest %s", port.ErrInvalidImage, name)
+	}
+	var m ociManifest
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, fmt.Errorf("%w: %s: %v", port.ErrInvalidImage, name, err)
+	}
+
+	var layers []imageLayer
+	for _, l := range m.Layers {
+		// the layers of artifacts such as attestations are not filesystems
+		if l.MediaType != "" && !strings.Contains(l.MediaType, ".tar") {
+			continue
+		}
+		p, err := blobPath(l.Digest)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, imageLayer{digest: l.Digest, path: p})
+	}
+	for _, desc := range m.Manifests {
+		p, err := blobPath(desc.Digest)
+		if err != nil {
+			return nil, err
+		}
+		nested, err := ociLayers(files, p, visited)
+		if err != nil {
+			return nil, err
+		}
+		layers = append(layers, nested...)
+	}
+	return layers, nil
+}
+
+// blobPath returns the path of the blob of an OCI image layout having a digest, e.g. sha256:abc.
+func blobPath(digest string) (string, error) {
+	alg, hex, ok := strings.Cut(digest, ":")
+	if !ok || alg == "" || hex == "" || strings.ContainsAny(digest, "/.") {
+		return "", fmt.Errorf("%w: invalid digest %q", port.ErrInvalidImage, digest)
+	}
+	return path.Join("blobs", alg, hex), nil
+}
+
+// layerDigest returns the digest of a layer of a docker save archive, given by its path when it is a blob.
+func layerDigest(p string) string {
+	if dir, hex := path.Split(path.Clean(p)); strings.HasPrefix(dir, "blobs/") {
+		return path.Base(dir) + ":" + hex
+	}
+	return p
+}
+
+// unpackedTotal counts the files unpacked from all the layers of an image.
+type unpackedTotal struct {
+	files int
+	size  int64
+}
+
+// analyzeLayer analyzes the files of the layer read from r, recording them in report and total.
+func (a *ImageAnalyser) analyzeLayer(ctx context.Context, r io.Reader, report *domain.LayerReport, total *unpackedTotal) error {
+	tr, closeTar, err := newTarReader(r)
+	if err != nil {
+		return err
+	}
+	defer closeTar()
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("%w: layer %s: %v", port.ErrInvalidImage, report.Digest, err)
+		}
+
+		name := path.Clean("/" + hdr.Name)
+		if escapes(hdr.Name) {
+			report.Findings = append(report.Findings, domain.ImageFinding{Path: hdr.Name, Kind: domain.FindingUnsafePath})
+			continue
+		}
+
+		switch hdr.Typeflag {
+		case tar.TypeSymlink, tar.TypeLink:
+			if !a.checkLinks {
+				continue
+			}
+			target := hdr.Linkname
+			if hdr.Typeflag == tar.TypeSymlink && !path.IsAbs(target) {
+				target = path.Join(path.Dir(strings.TrimPrefix(name, "/")), target)
+			}
+			if escapes(target) {
+				report.Findings = append(report.Findings, domain.ImageFinding{Path: name, Kind: domain.FindingEscapingLink, Detail: hdr.Linkname})
+			}
+			continue
+		case tar.TypeReg:
+		default:
+			continue
+		}
+
+		total.files++
+		total.size += hdr.Size
+		switch {
+		case a.limits.MaxFiles > 0 && total.files > a.limits.MaxFiles:
+			return fmt.Errorf("%w: more than %d files", port.ErrImageLimitExceeded, a.limits.MaxFiles)
+		case a.limits.MaxFileSize > 0 && hdr.Size > a.limits.MaxFileSize:
+			return fmt.Errorf("%w: %s is larger than %d bytes", port.ErrImageLimitExceeded, name, a.limits.MaxFileSize)
+		case a.limits.MaxUnpackedSize > 0 && total.size > a.limits.MaxUnpackedSize:
+			return fmt.Errorf("%w: more than %d unpacked bytes", port.ErrImageLimitExceeded, a.limits.MaxUnpackedSize)
+		}
+
+		report.Files++
+		if a.checkLinks && isShortcut(name) {
+			report.Findings = append(report.Findings, domain.ImageFinding{Path: name, Kind: domain.FindingShortcut})
+		}
+		status, threat, err := analyzeThreat(ctx, a.analyzer, io.LimitReader(tr, hdr.Size))
+		if err != nil {
+			return fmt.Errorf("%s: %w", name, err)
+		}
+		if status == domain.StatusInfected {
+			report.Status = status.String()
+			report.Findings = append(report.Findings, domain.ImageFinding{Path: name, Kind: domain.FindingInfected, Detail: threat})
+		}
+	}
+}
+
+// escapes reports whether a path of a layer, relative to its root unless it is absolute, escapes the root.
+func escapes(p string) bool {
+	if path.IsAbs(p) {
+		return false
+	}
+	p = path.Clean(p)
+	return p == ".." || strings.HasPrefix(p, "../")
+}
+
+// isShortcut reports whether a file is a Windows shortcut.
+func isShortcut(name string) bool {
+	ext := strings.ToLower(path.Ext(name))
+	return ext == ".lnk" || ext == ".url"
+}
+
+// newTarReader returns a tar reader of r, decompressing it if it is compressed with gzip or zstd,
+// along with a function releasing the decompressor.
+func newTarReader(r io.Reader) (*tar.Reader, func(), error) {
+	br := bufio.NewReader(r)
+	magic, _ := br.Peek(len(zstdMagic))
+	switch {
+	case bytes.HasPrefix(magic, gzipMagic):
+		zr, err := gzip.NewReader(br)
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", port.ErrInvalidImage, err)
+		}
+		return tar.NewReader(zr), func() { zr.Close() }, nil
+	case bytes.HasPrefix(magic, zstdMagic):
+		zr, err := zstd.NewReader(br, zstd.WithDecoderConcurrency(1))
+		if err != nil {
+			return nil, nil, fmt.Errorf("%w: %v", port.ErrInvalidImage, err)
+		}
+		return tar.NewReader(zr), zr.Close, nil
+	default:
+		return tar.NewReader(br), func() {}, nil
+	}
+}
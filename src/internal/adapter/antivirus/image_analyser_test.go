@@ -0,0 +1,128 @@
+package antivirus
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// tarEntry is an entry of a tarball built by a test.
+type tarEntry struct {
+	name     string
+	data     []byte
+	linkname string
+	typeflag byte
+}
+
+func buildTar(t *testing.T, entries []tarEntry, compress bool) []byte {
+	var buf bytes.Buffer
+	var tw *tar.Writer
+	var zw *gzip.Writer
+	if compress {
+		zw = gzip.NewWriter(&buf)
+		tw = tar.NewWriter(zw)
+	} else {
+		tw = tar.NewWriter(&buf)
+	}
+	for _, e := range entries {
+		if e.typeflag == 0 {
+			e.typeflag = tar.TypeReg
+		}
+		hdr := &tar.Header{Name: e.name, Typeflag: e.typeflag, Linkname: e.linkname, Size: int64(len(e.data)), Mode: 0o644}
+		if err := tw.WriteHeader(hdr); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		tw.Write(e.data)
+	}
+	if err := tw.Close(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if zw != nil {
+		zw.Close()
+	}
+	return buf.Bytes()
+}
+
+// ociImage builds an OCI image layout tarball made of layers.
+func ociImage(t *testing.T, layers ...[]byte) ([]byte, []string) {
+	var (
+		entries     []tarEntry
+		descriptors []map[string]string
+		digests     []string
+	)
+	addBlob := func(b []byte) string {
+		sum := sha256.Sum256(b)
+		h := hex.EncodeToString(sum[:])
+		entries = append(entries, tarEntry{name: "blobs/sha256/" + h, data: b})
+		return "sha256:" + h
+	}
+	for _, l := range layers {
+		d := addBlob(l)
+		digests = append(digests, d)
+		descriptors = append(descriptors, map[string]string{"mediaType": "application/vnd.oci.image.layer.v1.tar+gzip", "digest": d})
+	}
+	manifest, _ := json.Marshal(map[string]any{"schemaVersion": 2, "layers": descriptors})
+	index, _ := json.Marshal(map[string]any{"schemaVersion": 2, "manifests": []map[string]string{{"digest": addBlob(manifest)}}})
+	entries = append(entries,
+		tarEntry{name: "oci-layout", data: []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		tarEntry{name: "index.json", data: index},
+	)
+	return buildTar(t, entries, false), digests
+}
+
+func TestAnalyzeImage(t *testing.T) {
+	base := buildTar(t, []tarEntry{
+		{name: "etc/", typeflag: tar.TypeDir},
+		{name: "etc/passwd", data: []byte("root:x:0:0")},
+		{name: "bin/sh", typeflag: tar.TypeSymlink, linkname: "/bin/busybox"},
+	}, true)
+	top := buildTar(t, []tarEntry{
+		{name: "app/eicar.com", data: port.EICAR},
+		{name: "app/escape", typeflag: tar.TypeSymlink, linkname: "../../../etc/shadow"},
+		{name: "app/readme.lnk", data: []byte("shortcut")},
+		{name: "../outside", data: []byte("x")},
+	}, false)
+	image, digests := ociImage(t, base, top)
+	a := NewImage(NewMock(), domain.ImageLimits{}, true)
+
+	t.Run("Findings", func(t *testing.T) {
+		report, err := a.AnalyzeImage(context.Background(), bytes.NewReader(image), int64(len(image)))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, "infected", report.Status)
+		assert.Equal(t, 3, report.Files)
+		if assert.Len(t, report.Layers, 2) {
+			assert.Equal(t, domain.LayerReport{Digest: digests[0], Status: "clean", Files: 1}, report.Layers[0])
+			assert.Equal(t, digests[1], report.Layers[1].Digest)
+			assert.Equal(t, "infected", report.Layers[1].Status)
+			assert.ElementsMatch(t, []domain.ImageFinding{
+				{Path: "/app/eicar.com", Kind: domain.FindingInfected, Detail: EICARSignature},
+				{Path: "/app/escape", Kind: domain.FindingEscapingLink, Detail: "../../../etc/shadow"},
+				{Path: "/app/readme.lnk", Kind: domain.FindingShortcut},
+				{Path: "../outside", Kind: domain.FindingUnsafePath},
+			}, report.Layers[1].Findings)
+		}
+	})
+
+	t.Run("Limits", func(t *testing.T) {
+		limited := NewImage(NewMock(), domain.ImageLimits{MaxLayers: 1}, true)
+		_, err := limited.AnalyzeImage(context.Background(), bytes.NewReader(image), int64(len(image)))
+		assert.ErrorIs(t, err, port.ErrImageLimitExceeded)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		data := buildTar(t, []tarEntry{{name: "readme.txt", data: []byte("not an image")}}, false)
+		_, err := a.AnalyzeImage(context.Background(), bytes.NewReader(data), int64(len(data)))
+		assert.ErrorIs(t, err, port.ErrInvalidImage)
+	})
+}
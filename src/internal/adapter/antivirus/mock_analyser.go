@@ -8,6 +8,7 @@ import (
 	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
 	"io"
+	"sync"
 	"time"
 )
 
@@ -16,6 +17,11 @@ import (
 type MockAntivirusAnalyzer struct {
 	isOnline bool          // Indicates whether the mock analyzer is "online" or "offline"
 	Timeout  time.Duration // Timeout in seconds
+
+	load    port.AnalyzerLoad // load is the simulated load reported by Load
+	loadMux sync.Mutex
+
+	maxSize int64 // maxSize is the size of the largest data analyzed, unlimited when it is zero
 }
 
 var ErrMockAntivirusAnalyzer = errors.New("MockAntivirusAnalyzer")
@@ -28,26 +34,36 @@ func NewMock() *MockAntivirusAnalyzer {
 	}
 }
 
+// EICARSignature is the name of the threat reported by the mock analyzer for the EICAR test file, as ClamAV names it.
+const EICARSignature = "Win.Test.EICAR_HDB-1"
+
 // Analyze performs a mock antivirus analysis on the byte content of a document.
 func (m *MockAntivirusAnalyzer) Analyze(ctx context.Context, r io.Reader) (domain.AnalysisStatus, error) {
+	status, _, err := m.AnalyzeThreat(ctx, r)
+	return status, err
+}
+
+// AnalyzeThreat performs a mock antivirus analysis on the byte content of a document, and names EICARSignature
+// the threat found in infected content.
+func (m *MockAntivirusAnalyzer) AnalyzeThreat(ctx context.Context, r io.Reader) (domain.AnalysisStatus, string, error) {
 	if err := m.checkContextAndAvailability(ctx); err != nil {
-		return domain.StatusPending, err
+		return domain.StatusPending, "", err
 	}
 
 	// Simulate analysis duration
 	time.Sleep(time.Second)
 
-	var status domain.AnalysisStatus
 	b, err := io.ReadAll(r)
 	if err != nil {
-		return domain.StatusPending, fmt.Errorf("%w: %w: %v", ErrMockAntivirusAnalyzer, port.ErrAntivirusAnalysisFailed, err)
+		return domain.StatusPending, "", fmt.Errorf("%w: %w: %v", ErrMockAntivirusAnalyzer, port.ErrAntivirusAnalysisFailed, err)
+	}
+	if m.maxSize > 0 && int64(len(b)) > m.maxSize {
+		return domain.StatusPending, "", fmt.Errorf("%w: %w", ErrMockAntivirusAnalyzer, port.ErrAntivirusSizeLimitExceeded)
 	}
 	if bytes.Contains(b, port.EICAR) {
-		status = domain.StatusInfected
-	} else {
-		status = domain.StatusClean
+		return domain.StatusInfected, EICARSignature, nil
 	}
-	return status, nil
+	return domain.StatusClean, "", nil
 }
 
 // Ping simulates a connectivity check to the antivirus service.
@@ -63,6 +79,28 @@ func (m *MockAntivirusAnalyzer) TimeoutValue() uint64 {
 	return uint64(m.Timeout.Seconds())
 }
 
+// Load returns the simulated load of the analyzer.
+func (m *MockAntivirusAnalyzer) Load(ctx context.Context) (port.AnalyzerLoad, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return port.AnalyzerLoad{}, err
+	}
+	m.loadMux.Lock()
+	defer m.loadMux.Unlock()
+	return m.load, nil
+}
+
+// SetLoad sets the load reported by the mock analyzer.
+func (m *MockAntivirusAnalyzer) SetLoad(l port.AnalyzerLoad) {
+	m.loadMux.Lock()
+	m.load = l
+	m.loadMux.Unlock()
+}
+
+// SetMaxSize sets the size of the largest data the mock analyzer accepts to analyze, unlimited when it is zero.
+func (m *MockAntivirusAnalyzer) SetMaxSize(size int64) {
+	m.maxSize = size
+}
+
 // Online switches on or off the status of a mock analyzer instance.
 func (m *MockAntivirusAnalyzer) IsOnline(b bool) {
 	m.isOnline = b
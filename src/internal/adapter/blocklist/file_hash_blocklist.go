@@ -0,0 +1,158 @@
+// Package blocklist implements the hash blocklists of the service.
+package blocklist
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/pkg/helper"
+	"io"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultThreat is the default name of the threat of the blocklisted hashes listed without one.
+const DefaultThreat = "GoyAV.Blocklist.Hash"
+
+// FileHashBlocklist implements port.HashBlocklist with a text file listing a hash per line, in hexadecimal, followed
+// by the name of its threat, if any. The blank lines and those starting with '#' are ignored. The file is read again
+// once it changed, checked at most once per reload interval, so that a hash added to it is blocked without restarting
+// the service; a file which cannot be read or parsed any longer leaves the hashes read last in force.
+type FileHashBlocklist struct {
+	path           string
+	algo           helper.HashAlgorithm
+	threat         string
+	reloadInterval time.Duration
+
+	mu        sync.RWMutex
+	hashes    map[string]string // hashes maps each blocklisted hash to the name of its threat.
+	modTime   time.Time         // modTime and size are those of the file when it was read last.
+	size      int64
+	checkedAt time.Time
+
+	// hits counts the lookups of blocklisted hashes, and reloadErrors the failed reads of the file once it changed.
+	hits, reloadErrors atomic.Uint64
+}
+
+// NewFile creates a hash blocklist of the hashes of algo listed by the file at path, named threat, DefaultThreat if it
+// is empty, unless the file names their threat. The file is not read again when reloadInterval is zero.
+func NewFile(path string, algo helper.HashAlgorithm, threat string, reloadInterval time.Duration) (*FileHashBlocklist, error) {
+	if threat == "" {
+		threat = DefaultThreat
+	}
+	b := &FileHashBlocklist{path: path, algo: algo, threat: threat, reloadInterval: reloadInterval, checkedAt: time.Now()}
+	if err := b.load(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Lookup returns the name of the threat of the content with the given hash, and whether it is blocklisted.
+func (b *FileHashBlocklist) Lookup(_ context.Context, hash string) (string, bool, error) {
+	b.reloadIfChanged()
+	b.mu.RLock()
+	threat, ok := b.hashes[strings.ToLower(hash)]
+	b.mu.RUnlock()
+	if ok {
+		b.hits.Add(1)
+	}
+	return threat, ok, nil
+}
+
+// Len returns the number of blocklisted hashes.
+func (b *FileHashBlocklist) Len() int {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return len(b.hashes)
+}
+
+// Metrics reports the number of blocklisted hashes, the number of uploads they blocked and the number of failed reads
+// of the file, implementing port.MetricsReporter.
+func (b *FileHashBlocklist) Metrics() []domain.Metric {
+	return []domain.Metric{
+		{Name: "goyav_hash_blocklist_entries", Help: "Number of hashes in the hash blocklist.", Kind: domain.MetricGauge, Value: float64(b.Len())},
+		{Name: "goyav_hash_blocklist_hits_total", Help: "Total number of uploads of a blocklisted hash.", Kind: domain.MetricCounter, Value: float64(b.hits.Load())},
+		{Name: "goyav_hash_blocklist_reload_errors_total", Help: "Total number of failed reads of the changed hash blocklist.", Kind: domain.MetricCounter, Value: float64(b.reloadErrors.Load())},
+	}
+}
+
+// reloadIfChanged reads the file again if it changed since it was read last, once the reload interval elapsed since
+// the last check.
+func (b *FileHashBlocklist) reloadIfChanged() {
+	if b.reloadInterval <= 0 {
+		return
+	}
+	b.mu.Lock()
+	if time.Since(b.checkedAt) < b.reloadInterval {
+		b.mu.Unlock()
+		return
+	}
+	b.checkedAt = time.Now()
+	modTime, size := b.modTime, b.size
+	b.mu.Unlock()
+
+	info, err := os.Stat(b.path)
+	if err == nil && info.ModTime().Equal(modTime) && info.Size() == size {
+		return
+	}
+	if err == nil {
+		err = b.load()
+	}
+	if err != nil {
+		b.reloadErrors.Add(1)
+		slog.Error("blocklist - failed to reload the hash blocklist, the previous hashes stay in force", "error", err, "path", b.path)
+		return
+	}
+	slog.Info("blocklist - hash blocklist reloaded", "path", b.path, "hashes", b.Len())
+}
+
+// load reads the file and replaces the blocklisted hashes with those it lists.
+func (b *FileHashBlocklist) load() error {
+	f, err := os.Open(b.path)
+	if err != nil {
+		return fmt.Errorf("failed to open the hash blocklist: %w", err)
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return fmt.Errorf("failed to read the hash blocklist: %w", err)
+	}
+	hashes, err := b.parse(f)
+	if err != nil {
+		return fmt.Errorf("invalid hash blocklist %s: %w", b.path, err)
+	}
+	b.mu.Lock()
+	b.hashes, b.modTime, b.size = hashes, info.ModTime(), info.Size()
+	b.mu.Unlock()
+	return nil
+}
+
+// parse returns the hashes listed by r, mapped to the name of their threat.
+func (b *FileHashBlocklist) parse(r io.Reader) (map[string]string, error) {
+	hashes := make(map[string]string)
+	sc := bufio.NewScanner(r)
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		hash, threat, _ := strings.Cut(strings.Join(strings.Fields(line), " "), " ")
+		hash = strings.ToLower(hash)
+		if !b.algo.IsValid(hash) {
+			return nil, fmt.Errorf("line %d: %q is not a %s hash", n, hash, b.algo)
+		}
+		if threat == "" {
+			threat = b.threat
+		}
+		hashes[hash] = threat
+	}
+	if err := sc.Err(); err != nil {
+		return nil, err
+	}
+	return hashes, nil
+}
@@ -0,0 +1,66 @@
+package blocklist
+
+import (
+	"context"
+	"goyav/pkg/helper"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	eicarHash = "275a021bbfb6489e54d471899f7db9d1663fc695ec2fe2a2c4538aabf651fd0f"
+	otherHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+func TestFileHashBlocklist(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "blocklist.txt")
+	write := func(content string) {
+		if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+	write("# known malware\n\n" + eicarHash + "\tWin.Test.EICAR_HDB-1\n")
+
+	b, err := NewFile(path, helper.HashSHA256, "", 10*time.Millisecond)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	threat, blocked, err := b.Lookup(ctx, eicarHash)
+	assert.NoError(t, err)
+	assert.True(t, blocked)
+	assert.Equal(t, "Win.Test.EICAR_HDB-1", threat)
+	_, blocked, _ = b.Lookup(ctx, otherHash)
+	assert.False(t, blocked)
+
+	t.Run("Reload", func(t *testing.T) {
+		write(eicarHash + "\n" + otherHash + " Custom.Threat\n")
+		time.Sleep(20 * time.Millisecond)
+		threat, blocked, _ := b.Lookup(ctx, eicarHash)
+		assert.True(t, blocked)
+		assert.Equal(t, DefaultThreat, threat, "the default threat should name the hashes listed without one")
+		threat, blocked, _ = b.Lookup(ctx, otherHash)
+		assert.True(t, blocked)
+		assert.Equal(t, "Custom.Threat", threat)
+		assert.Equal(t, 2, b.Len())
+	})
+
+	t.Run("InvalidReload", func(t *testing.T) {
+		write("not a hash\n")
+		time.Sleep(20 * time.Millisecond)
+		_, blocked, _ := b.Lookup(ctx, otherHash)
+		assert.True(t, blocked, "the previous hashes should stay in force")
+		assert.Equal(t, float64(1), b.Metrics()[2].Value)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		_, err := NewFile(path, helper.HashSHA256, "", 0)
+		assert.Error(t, err)
+		_, err = NewFile(filepath.Join(t.TempDir(), "missing.txt"), helper.HashSHA256, "", 0)
+		assert.Error(t, err)
+	})
+}
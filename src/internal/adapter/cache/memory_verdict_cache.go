@@ -0,0 +1,110 @@
+// Package cache implements the caches of the service.
+package cache
+
+import (
+	"container/list"
+	"context"
+	"goyav/internal/core/domain"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries is the default number of verdicts kept by a MemoryVerdictCache.
+const DefaultMaxEntries = 100_000
+
+// MemoryVerdictCache implements port.VerdictCache in memory, each replica of the service keeping a cache of its own.
+// The verdicts expire after the TTL of the cache, and the least recently used ones are evicted once it is full.
+type MemoryVerdictCache struct {
+	ttl        time.Duration
+	maxEntries int
+
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List // lru orders the entries from the most to the least recently used.
+
+	// hits and misses count the lookups which found a verdict and those which did not.
+	hits, misses uint64
+}
+
+// memoryEntry is an entry of a MemoryVerdictCache.
+type memoryEntry struct {
+	key       string
+	verdict   domain.CachedVerdict
+	expiresAt time.Time
+}
+
+// NewMemory creates an in-memory verdict cache keeping at most maxEntries verdicts, DefaultMaxEntries if it is not
+// strictly positive, for ttl each.
+func NewMemory(ttl time.Duration, maxEntries int) *MemoryVerdictCache {
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &MemoryVerdictCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		entries:    make(map[string]*list.Element),
+		lru:        list.New(),
+	}
+}
+
+// Get returns the verdict cached under key, and whether there is one which has not expired.
+func (c *MemoryVerdictCache) Get(_ context.Context, key string) (domain.CachedVerdict, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	el, ok := c.entries[key]
+	if !ok {
+		c.misses++
+		return domain.CachedVerdict{}, false, nil
+	}
+	e := el.Value.(*memoryEntry)
+	if time.Now().After(e.expiresAt) {
+		c.remove(el)
+		c.misses++
+		return domain.CachedVerdict{}, false, nil
+	}
+	c.lru.MoveToFront(el)
+	c.hits++
+	return e.verdict, true, nil
+}
+
+// Set caches v under key for the TTL of the cache, evicting the least recently used verdict if the cache is full.
+func (c *MemoryVerdictCache) Set(_ context.Context, key string, v domain.CachedVerdict) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	expiresAt := time.Now().Add(c.ttl)
+	if el, ok := c.entries[key]; ok {
+		e := el.Value.(*memoryEntry)
+		e.verdict, e.expiresAt = v, expiresAt
+		c.lru.MoveToFront(el)
+		return nil
+	}
+	for c.lru.Len() >= c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+	c.entries[key] = c.lru.PushFront(&memoryEntry{key: key, verdict: v, expiresAt: expiresAt})
+	return nil
+}
+
+// Len returns the number of verdicts in the cache, expired ones included until they are looked up or evicted.
+func (c *MemoryVerdictCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Metrics reports the number of verdicts in the cache and the numbers of lookups which found a verdict or not,
+// implementing port.MetricsReporter.
+func (c *MemoryVerdictCache) Metrics() []domain.Metric {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return []domain.Metric{
+		{Name: "goyav_verdict_cache_entries", Help: "Number of verdicts in the verdict cache.", Kind: domain.MetricGauge, Value: float64(c.lru.Len())},
+		{Name: "goyav_verdict_cache_hits_total", Help: "Total number of uploads given a cached verdict.", Kind: domain.MetricCounter, Value: float64(c.hits)},
+		{Name: "goyav_verdict_cache_misses_total", Help: "Total number of uploads without a cached verdict.", Kind: domain.MetricCounter, Value: float64(c.misses)},
+	}
+}
+
+func (c *MemoryVerdictCache) remove(el *list.Element) {
+	c.lru.Remove(el)
+	delete(c.entries, el.Value.(*memoryEntry).key)
+}
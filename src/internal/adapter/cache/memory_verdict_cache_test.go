@@ -0,0 +1,50 @@
+package cache
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMemoryVerdictCache(t *testing.T) {
+	ctx := context.Background()
+	clean := domain.CachedVerdict{Status: domain.StatusClean, AnalyzedAt: time.Now()}
+	infected := domain.CachedVerdict{Status: domain.StatusInfected, Threat: "Win.Test.EICAR_HDB-1", AnalyzedAt: time.Now()}
+
+	t.Run("Eviction", func(t *testing.T) {
+		c := NewMemory(time.Hour, 2)
+		assert.NoError(t, c.Set(ctx, "a", clean))
+		assert.NoError(t, c.Set(ctx, "b", infected))
+
+		// a is used more recently than b, which is evicted
+		v, ok, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.Equal(t, clean, v)
+		assert.NoError(t, c.Set(ctx, "c", infected))
+		assert.Equal(t, 2, c.Len())
+		_, ok, _ = c.Get(ctx, "b")
+		assert.False(t, ok)
+
+		// replacing a verdict does not evict another one
+		assert.NoError(t, c.Set(ctx, "c", clean))
+		v, ok, _ = c.Get(ctx, "c")
+		assert.True(t, ok)
+		assert.Equal(t, clean, v)
+		assert.Equal(t, 2, c.Len())
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		c := NewMemory(10*time.Millisecond, 0)
+		assert.NoError(t, c.Set(ctx, "a", clean))
+		time.Sleep(20 * time.Millisecond)
+		_, ok, err := c.Get(ctx, "a")
+		assert.NoError(t, err)
+		assert.False(t, ok)
+		assert.Zero(t, c.Len())
+		assert.Equal(t, float64(1), c.Metrics()[2].Value, "an expired verdict is a miss")
+	})
+}
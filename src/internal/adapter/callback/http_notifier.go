@@ -0,0 +1,203 @@
+package callback
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	// DefaultAttempts is the default number of attempts to notify a callback URL.
+	DefaultAttempts = 5
+
+	// DefaultTimeout is the default timeout of a request to a callback URL.
+	DefaultTimeout = 10 * time.Second
+
+	// defaultRetryDelay is the delay before the second attempt, doubled for each of the next ones.
+	defaultRetryDelay = time.Second
+)
+
+// nonPublicPrefixes are the special-purpose address blocks which are global unicast addresses
+// but must not be reached by callbacks, in addition to the private ones.
+var nonPublicPrefixes = []netip.Prefix{
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("100.64.0.0/10"),
+	netip.MustParsePrefix("192.0.0.0/24"),
+	netip.MustParsePrefix("198.18.0.0/15"),
+	netip.MustParsePrefix("240.0.0.0/4"),
+	netip.MustParsePrefix("64:ff9b::/96"),
+	netip.MustParsePrefix("2001:db8::/32"),
+}
+
+// HTTPNotifier implements port.CallbackNotifier by posting the analyzed documents as JSON to the callback URLs.
+// Unless allowed, the callback URLs may only target public addresses: the check is made on the addresses connected
+// to, once resolved, so that a host name resolving to a private address is refused as well, and redirects are not
+// followed.
+type HTTPNotifier struct {
+	client       *http.Client
+	attempts     int
+	retryDelay   time.Duration
+	allowPrivate bool
+}
+
+// NewHTTP creates a notifier posting to the callback URLs with requests bounded by timeout, made at most attempts
+// times. allowPrivate lets the callback URLs target loopback and private addresses, e.g. in a development environment.
+// The bodies of at least compression bytes are compressed if the callback host accepts it, see
+// helper.CompressingTransport.
+func NewHTTP(timeout time.Duration, attempts int, allowPrivate bool, compression int) *HTTPNotifier {
+	n := &HTTPNotifier{
+		attempts:     max(attempts, 1),
+		retryDelay:   defaultRetryDelay,
+		allowPrivate: allowPrivate,
+	}
+	dialer := &net.Dialer{Timeout: timeout, Control: n.checkAddress}
+	n.client = &http.Client{
+		Timeout: timeout,
+		Transport: helper.CompressingTransport(&http.Transport{
+			// a proxy would be connected to rather than the callback host, whose address could not be checked
+			Proxy:               nil,
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: timeout,
+			MaxIdleConnsPerHost: 2,
+			IdleConnTimeout:     time.Minute,
+		}, compression),
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	return n
+}
+
+// Validate checks that callbackURL is an absolute http or https URL without credentials, whose host is not an address
+// or a name which may not be notified. The addresses the host name resolves to are checked when notified.
+func (n *HTTPNotifier) Validate(callbackURL string) error {
+	if len(callbackURL) > domain.CallbackURLMaxLength {
+		return fmt.Errorf("%w: longer than %d bytes", port.ErrInvalidCallbackURL, domain.CallbackURLMaxLength)
+	}
+	u, err := url.Parse(callbackURL)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrInvalidCallbackURL, err)
+	}
+	switch {
+	case u.Scheme != "http" && u.Scheme != "https":
+		return fmt.Errorf("%w: the scheme must be http or https", port.ErrInvalidCallbackURL)
+	case u.Hostname() == "":
+		return fmt.Errorf("%w: missing host", port.ErrInvalidCallbackURL)
+	case u.User != nil:
+		return fmt.Errorf("%w: credentials are not allowed", port.ErrInvalidCallbackURL)
+	}
+	if n.allowPrivate {
+		return nil
+	}
+	host := strings.ToLower(strings.TrimSuffix(u.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return fmt.Errorf("%w: %s is not a public host", port.ErrInvalidCallbackURL, host)
+	}
+	if ip, err := netip.ParseAddr(host); err == nil && !isPublic(ip) {
+		return fmt.Errorf("%w: %s is not a public address", port.ErrInvalidCallbackURL, ip)
+	}
+	return nil
+}
+
+// callbackMessage is the body posted to the callback URLs, shaped as the response of GET /documents/{id}.
+type callbackMessage struct {
+	ID       string              `json:"id"`
+	Message  string              `json:"message"`
+	Document *domain.DocumentDTO `json:"document"`
+}
+
+// Notify posts doc to callbackURL until it answers with a 2xx status code. The requests failing with a network error,
+// a 408, a 429 or a 5xx status code are retried with an exponential backoff, the others are not.
+func (n *HTTPNotifier) Notify(ctx context.Context, callbackURL string, doc *domain.Document) error {
+	body, err := json.Marshal(&callbackMessage{ID: doc.ID, Message: "analysis completed", Document: domain.NewDocumentDTO(doc)})
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrCallbackFailed, err)
+	}
+
+	delay := n.retryDelay
+	for attempt := 1; ; attempt++ {
+		retry, err := n.post(ctx, callbackURL, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= n.attempts {
+			return fmt.Errorf("%w: %w: attempts=%d", port.ErrCallbackFailed, err, attempt)
+		}
+		select {
+		case <-time.After(delay):
+			delay *= 2
+		case <-ctx.Done():
+			return fmt.Errorf("%w: %w: %v", port.ErrCallbackFailed, err, ctx.Err())
+		}
+	}
+}
+
+// post sends a request with body to callbackURL, and reports whether it may be retried if it failed.
+func (n *HTTPNotifier) post(ctx context.Context, callbackURL string, body []byte) (bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, callbackURL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, port.ErrInvalidCallbackURL), err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode == http.StatusRequestTimeout, resp.StatusCode == http.StatusTooManyRequests, resp.StatusCode >= 500:
+		return true, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	default:
+		return false, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+}
+
+// checkAddress is the control function of the dialer, refusing the connections to the addresses which may not be
+// notified.
+func (n *HTTPNotifier) checkAddress(network, address string, _ syscall.RawConn) error {
+	if n.allowPrivate {
+		return nil
+	}
+	ap, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrInvalidCallbackURL, err)
+	}
+	if !isPublic(ap.Addr()) {
+		return fmt.Errorf("%w: %s is not a public address", port.ErrInvalidCallbackURL, ap.Addr())
+	}
+	return nil
+}
+
+// isPublic reports whether ip is a public unicast address: neither loopback, link-local, multicast, private nor
+// reserved.
+func isPublic(ip netip.Addr) bool {
+	ip = ip.Unmap()
+	if !ip.IsGlobalUnicast() || ip.IsPrivate() {
+		return false
+	}
+	for _, p := range nonPublicPrefixes {
+		if p.Contains(ip) {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,46 @@
+package callback
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"strings"
+	"sync"
+)
+
+// MockNotifier is a mock implementation of port.CallbackNotifier recording the notifications instead of sending them.
+// It only accepts the http and https URLs.
+type MockNotifier struct {
+	mu            sync.Mutex
+	notifications map[string][]*domain.Document
+}
+
+// NewMock creates a new instance of MockNotifier.
+func NewMock() *MockNotifier {
+	return &MockNotifier{notifications: make(map[string][]*domain.Document)}
+}
+
+// Validate accepts the callback URLs starting with http:// or https://.
+func (m *MockNotifier) Validate(callbackURL string) error {
+	if !strings.HasPrefix(callbackURL, "http://") && !strings.HasPrefix(callbackURL, "https://") {
+		return fmt.Errorf("%w: the scheme must be http or https", port.ErrInvalidCallbackURL)
+	}
+	return nil
+}
+
+// Notify records a copy of doc as notified to callbackURL.
+func (m *MockNotifier) Notify(_ context.Context, callbackURL string, doc *domain.Document) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	d := *doc
+	m.notifications[callbackURL] = append(m.notifications[callbackURL], &d)
+	return nil
+}
+
+// Notifications returns the documents notified to callbackURL, in order.
+func (m *MockNotifier) Notifications(callbackURL string) []*domain.Document {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*domain.Document(nil), m.notifications[callbackURL]...)
+}
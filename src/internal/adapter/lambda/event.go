@@ -0,0 +1,54 @@
+package lambda
+
+import (
+	"net/url"
+	"strings"
+)
+
+// S3Event is the notification of changes to the objects of S3 buckets a function is invoked with.
+type S3Event struct {
+	Records []S3EventRecord `json:"Records"`
+}
+
+// S3EventRecord is the notification of a change to an object.
+type S3EventRecord struct {
+	EventName string `json:"eventName"`
+	AWSRegion string `json:"awsRegion"`
+	S3        struct {
+		Bucket struct {
+			Name string `json:"name"`
+		} `json:"bucket"`
+		Object struct {
+			Key       string `json:"key"` // Key is URL-encoded.
+			Size      int64  `json:"size"`
+			VersionID string `json:"versionId"`
+		} `json:"object"`
+	} `json:"s3"`
+}
+
+// Object is an object of an S3 bucket.
+type Object struct {
+	Bucket    string
+	Key       string
+	VersionID string // VersionID is empty when the bucket is not versioned.
+	Size      int64
+}
+
+// IsCreation reports whether the record notifies the creation of an object.
+func (r S3EventRecord) IsCreation() bool {
+	return strings.HasPrefix(r.EventName, "ObjectCreated:")
+}
+
+// Object returns the object of the record, with its decoded key.
+func (r S3EventRecord) Object() (Object, error) {
+	key, err := url.QueryUnescape(r.S3.Object.Key)
+	if err != nil {
+		return Object{}, err
+	}
+	return Object{
+		Bucket:    r.S3.Bucket.Name,
+		Key:       key,
+		VersionID: r.S3.Object.VersionID,
+		Size:      r.S3.Object.Size,
+	}, nil
+}
@@ -0,0 +1,218 @@
+package lambda
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"log/slog"
+	"path"
+	"time"
+)
+
+const (
+	// DefaultPollInterval is the default interval between two checks of a pending verdict.
+	DefaultPollInterval = time.Second
+
+	// verdictMargin is the time kept before the deadline of an invocation to record the verdicts and respond.
+	verdictMargin = 2 * time.Second
+)
+
+var (
+	// ErrInvalidEvent is returned when the payload of an invocation is not an S3 event.
+	ErrInvalidEvent = errors.New("invalid S3 event")
+
+	// ErrIncomplete is returned when the verdict of some objects of an event could not be recorded,
+	// so that the invocation is retried by Lambda.
+	ErrIncomplete = errors.New("verdicts not recorded")
+)
+
+// ObjectStore reads the objects notified by S3 events and records their verdicts.
+type ObjectStore interface {
+	// Get returns the data of an object.
+	Get(ctx context.Context, obj Object) (io.ReadCloser, error)
+
+	// PutVerdict records the verdict of the document of an object, without losing the other tags of the object.
+	PutVerdict(ctx context.Context, obj Object, doc *domain.Document) error
+}
+
+// Result is the outcome of the analysis of an object.
+type Result struct {
+	Bucket string `json:"bucket"`
+	Key    string `json:"key"`
+	ID     string `json:"id,omitempty"`
+	Status string `json:"status,omitempty"`
+	Error  string `json:"error,omitempty"`
+
+	// retry reports whether the invocation must be retried to record the verdict.
+	retry bool
+}
+
+// Report is the response of an invocation.
+type Report struct {
+	Results []Result `json:"results"`
+}
+
+// Handler analyzes the objects created in S3 buckets with a document service, and records their verdicts.
+type Handler struct {
+	service       port.DocumentService
+	objects       ObjectStore
+	maxObjectSize uint64
+
+	// pollInterval is the interval between two checks of a pending verdict.
+	pollInterval time.Duration
+
+	// tenant owns the documents of the objects, the default tenant when it is empty.
+	tenant string
+}
+
+// Option configures optional behaviours of a Handler.
+type Option func(*Handler)
+
+// WithPollInterval sets the interval between two checks of a pending verdict.
+func WithPollInterval(d time.Duration) Option {
+	return func(h *Handler) {
+		if d > 0 {
+			h.pollInterval = d
+		}
+	}
+}
+
+// WithTenant makes the documents of the objects owned by tenant rather than by the default tenant.
+func WithTenant(tenant string) Option {
+	return func(h *Handler) {
+		h.tenant = tenant
+	}
+}
+
+// NewHandler creates a Handler uploading the objects of at most maxObjectSize bytes read from objects to s.
+func NewHandler(s port.DocumentService, objects ObjectStore, maxObjectSize uint64, opts ...Option) *Handler {
+	h := &Handler{
+		service:       s,
+		objects:       objects,
+		maxObjectSize: maxObjectSize,
+		pollInterval:  DefaultPollInterval,
+	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	return h
+}
+
+// Handle handles an S3 event: each created object is uploaded to the document service, then its verdict is awaited
+// and recorded. The verdicts still pending when the invocation is about to time out, or that failed to be recorded,
+// make Handle return ErrIncomplete so that Lambda retries the event; uploading an object again is harmless.
+func (h *Handler) Handle(ctx context.Context, payload []byte) (any, error) {
+	var event S3Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidEvent, err)
+	}
+	if h.tenant != "" {
+		ctx = domain.ContextWithTenant(ctx, h.tenant)
+	}
+
+	// the verdicts are awaited until shortly before the deadline of the invocation
+	wctx := ctx
+	if deadline, ok := ctx.Deadline(); ok {
+		var cancel context.CancelFunc
+		wctx, cancel = context.WithDeadline(ctx, deadline.Add(-verdictMargin))
+		defer cancel()
+	}
+
+	report := &Report{Results: make([]Result, 0, len(event.Records))}
+	var incomplete int
+	for _, record := range event.Records {
+		if !record.IsCreation() {
+			continue
+		}
+		res := h.handleRecord(ctx, wctx, record)
+		if res.retry {
+			incomplete++
+		}
+		slog.InfoContext(ctx, "lambda - object handled", "bucket", res.Bucket, "key", res.Key, "ID", res.ID, "status", res.Status, "error", res.Error)
+		report.Results = append(report.Results, res)
+	}
+
+	if incomplete > 0 {
+		return nil, fmt.Errorf("%w: %d of %d objects", ErrIncomplete, incomplete, len(report.Results))
+	}
+	return report, nil
+}
+
+// handleRecord uploads the object of a record, waits for its verdict with wctx and records it with ctx.
+func (h *Handler) handleRecord(ctx, wctx context.Context, record S3EventRecord) Result {
+	res := Result{Bucket: record.S3.Bucket.Name, Key: record.S3.Object.Key}
+	obj, err := record.Object()
+	if err != nil {
+		res.Error = fmt.Sprintf("invalid object key: %v", err)
+		return res
+	}
+	res.Key = obj.Key
+	if obj.Size <= 0 || uint64(obj.Size) > h.maxObjectSize {
+		res.Error = fmt.Sprintf("the object size must be between 1 and %d bytes", h.maxObjectSize)
+		return res
+	}
+
+	// any other failure is retried
+	res.retry = true
+
+	r, err := h.objects.Get(ctx, obj)
+	if err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.ID, err = h.service.Upload(domain.ContextWithFileName(ctx, path.Base(obj.Key)), r, obj.Size, objectTag(obj.Key))
+	r.Close()
+	if err != nil && !errors.Is(err, port.ErrDocumentAlreadyExists) {
+		// an object of a rejected media type will never be accepted
+		res.retry = !errors.Is(err, port.ErrServiceUnsupportedMediaType)
+		res.Error = err.Error()
+		return res
+	}
+
+	doc, err := h.awaitVerdict(wctx, res.ID)
+	if err != nil {
+		res.Status = domain.StatusPending.String()
+		res.Error = err.Error()
+		return res
+	}
+	res.Status = doc.Status.String()
+
+	if err = h.objects.PutVerdict(ctx, obj, doc); err != nil {
+		res.Error = err.Error()
+		return res
+	}
+	res.retry = false
+	return res
+}
+
+// awaitVerdict polls the document ID until it is analyzed or ctx is done.
+func (h *Handler) awaitVerdict(ctx context.Context, ID string) (*domain.Document, error) {
+	for {
+		doc, err := h.service.GetDocument(ctx, ID)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Status != domain.StatusPending {
+			return doc, nil
+		}
+		select {
+		case <-time.After(h.pollInterval):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no verdict yet: %w", ctx.Err())
+		}
+	}
+}
+
+// objectTag returns the tag of the document of an object: its key, or the end of its key
+// if it is longer than helper.TagMaxLength.
+func objectTag(key string) string {
+	if len(key) > helper.TagMaxLength {
+		return key[len(key)-helper.TagMaxLength:]
+	}
+	return key
+}
@@ -0,0 +1,159 @@
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/internal/service"
+	"goyav/pkg/helper"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// memoryObjectStore is an in-memory ObjectStore.
+type memoryObjectStore struct {
+	mux      sync.Mutex
+	data     map[string][]byte
+	verdicts map[string]*domain.Document
+}
+
+func newMemoryObjectStore(data map[string][]byte) *memoryObjectStore {
+	return &memoryObjectStore{data: data, verdicts: make(map[string]*domain.Document)}
+}
+
+func (m *memoryObjectStore) Get(ctx context.Context, obj Object) (io.ReadCloser, error) {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	b, ok := m.data[obj.Bucket+"/"+obj.Key]
+	if !ok {
+		return nil, errors.New("no such object")
+	}
+	return io.NopCloser(bytes.NewReader(b)), nil
+}
+
+func (m *memoryObjectStore) PutVerdict(ctx context.Context, obj Object, doc *domain.Document) error {
+	m.mux.Lock()
+	defer m.mux.Unlock()
+	m.verdicts[obj.Bucket+"/"+obj.Key] = doc
+	return nil
+}
+
+// s3Event returns the payload of an S3 event notifying the creation of objects of a bucket.
+func s3Event(t *testing.T, bucket string, objects map[string]int64) []byte {
+	var event S3Event
+	for key, size := range objects {
+		var r S3EventRecord
+		r.EventName = "ObjectCreated:Put"
+		r.S3.Bucket.Name = bucket
+		r.S3.Object.Key = key
+		r.S3.Object.Size = size
+		event.Records = append(event.Records, r)
+	}
+	b, err := json.Marshal(event)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	return b
+}
+
+func TestHandle(t *testing.T) {
+	svc, err := service.New(binaryrepo.NewMock(), docrepo.NewMock(), antivirus.NewMock(), "1.0", "information", 0, 128)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	clean, infected := []byte("clean data"), port.EICAR
+	objects := newMemoryObjectStore(map[string][]byte{
+		"uploads/clean+file.txt": clean,
+		"uploads/eicar.com":      infected,
+	})
+	h := NewHandler(svc, objects, 1<<10, WithPollInterval(100*time.Millisecond))
+
+	t.Run("Verdicts", func(t *testing.T) {
+		payload := s3Event(t, "uploads", map[string]int64{
+			"clean%2Bfile.txt": int64(len(clean)),
+			"eicar.com":        int64(len(infected)),
+		})
+		res, err := h.Handle(context.Background(), payload)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Len(t, res.(*Report).Results, 2)
+		assert.Equal(t, domain.StatusClean, objects.verdicts["uploads/clean+file.txt"].Status)
+		assert.Equal(t, domain.StatusInfected, objects.verdicts["uploads/eicar.com"].Status)
+	})
+
+	t.Run("TooLarge", func(t *testing.T) {
+		res, err := h.Handle(context.Background(), s3Event(t, "uploads", map[string]int64{"large.bin": 1 << 20}))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.NotEmpty(t, res.(*Report).Results[0].Error)
+		assert.NotContains(t, objects.verdicts, "uploads/large.bin")
+	})
+
+	t.Run("Incomplete", func(t *testing.T) {
+		_, err := h.Handle(context.Background(), s3Event(t, "uploads", map[string]int64{"missing.txt": 10}))
+		assert.ErrorIs(t, err, ErrIncomplete)
+	})
+
+	t.Run("InvalidEvent", func(t *testing.T) {
+		_, err := h.Handle(context.Background(), []byte("not an event"))
+		assert.ErrorIs(t, err, ErrInvalidEvent)
+	})
+}
+
+func TestRuntimeRun(t *testing.T) {
+	var (
+		mux       sync.Mutex
+		delivered bool
+		responses = make(map[string]string)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/2018-06-01/runtime/invocation/next":
+			mux.Lock()
+			defer mux.Unlock()
+			if delivered {
+				// no more invocations: make Run return
+				w.WriteHeader(http.StatusInternalServerError)
+				return
+			}
+			delivered = true
+			w.Header().Set("Lambda-Runtime-Aws-Request-Id", "req-1")
+			w.Header().Set("Lambda-Runtime-Deadline-Ms", strconv.FormatInt(time.Now().Add(time.Minute).UnixMilli(), 10))
+			w.Write([]byte(`"ping"`))
+		case "/2018-06-01/runtime/invocation/req-1/response":
+			b, _ := io.ReadAll(r.Body)
+			mux.Lock()
+			responses["req-1"] = string(b)
+			mux.Unlock()
+			w.WriteHeader(http.StatusAccepted)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	rt := NewRuntime(srv.Listener.Addr().String())
+	err := rt.Run(context.Background(), func(ctx context.Context, payload []byte) (any, error) {
+		assert.Equal(t, "req-1", helper.RequestIDFromContext(ctx))
+		_, hasDeadline := ctx.Deadline()
+		assert.True(t, hasDeadline)
+		return map[string]string{"payload": string(payload)}, nil
+	})
+	assert.ErrorIs(t, err, ErrRuntimeAPI)
+	assert.JSONEq(t, `{"payload":"\"ping\""}`, responses["req-1"])
+}
@@ -0,0 +1,48 @@
+package lambda
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/adapter/storage/objecttag"
+	"goyav/internal/core/domain"
+	"io"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// Tags recording the verdict of an object.
+const (
+	TagStatus     = "goyav-status"
+	TagDocumentID = "goyav-id"
+	TagAnalyzedAt = "goyav-analyzed-at"
+)
+
+// MinioObjectStore is an ObjectStore reading the objects with a minio client and recording their verdicts as object tags.
+type MinioObjectStore struct {
+	client *minio.Client
+}
+
+// NewMinioObjectStore creates a MinioObjectStore using cli.
+func NewMinioObjectStore(cli *minio.Client) *MinioObjectStore {
+	return &MinioObjectStore{client: cli}
+}
+
+// Get returns the data of an object.
+func (m *MinioObjectStore) Get(ctx context.Context, obj Object) (io.ReadCloser, error) {
+	r, err := m.client.GetObject(ctx, obj.Bucket, obj.Key, minio.GetObjectOptions{VersionID: obj.VersionID})
+	if err != nil {
+		return nil, fmt.Errorf("failed to get object %s/%s: %v", obj.Bucket, obj.Key, err)
+	}
+	return r, nil
+}
+
+// PutVerdict records the verdict of the document of an object in the goyav-* tags and the scan tags of the object,
+// see objecttag.Verdict, along with the tags it already has.
+func (m *MinioObjectStore) PutVerdict(ctx context.Context, obj Object, doc *domain.Document) error {
+	values := objecttag.Verdict(doc)
+	values[TagStatus] = doc.Status.String()
+	values[TagDocumentID] = doc.ID
+	values[TagAnalyzedAt] = doc.AnalyzedAt.UTC().Format(time.RFC3339)
+	return objecttag.Put(ctx, m.client, obj.Bucket, obj.Key, obj.VersionID, values)
+}
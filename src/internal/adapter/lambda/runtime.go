@@ -0,0 +1,138 @@
+// Package lambda runs GoyAV as an AWS Lambda function: the objects put in S3 buckets are analyzed by the
+// document service as they are notified, and their verdicts are recorded next to them as object tags.
+package lambda
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/pkg/helper"
+	"io"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// runtimeAPIVersion is the version of the Lambda runtime API.
+const runtimeAPIVersion = "2018-06-01"
+
+// ErrRuntimeAPI is returned when a call to the Lambda runtime API fails.
+var ErrRuntimeAPI = errors.New("lambda runtime API error")
+
+// HandlerFunc handles the payload of an invocation, its result is sent back as JSON.
+type HandlerFunc func(ctx context.Context, payload []byte) (any, error)
+
+// invocation is an event received from the Lambda runtime API.
+type invocation struct {
+	requestID string
+	deadline  time.Time
+	payload   []byte
+}
+
+// invocationError is the error reported to the Lambda runtime API when an invocation fails.
+type invocationError struct {
+	Message string `json:"errorMessage"`
+	Type    string `json:"errorType"`
+}
+
+// Runtime is a client of the Lambda runtime API, which custom runtimes poll for invocations.
+type Runtime struct {
+	baseURL string
+	client  *http.Client
+}
+
+// NewRuntime creates a client of the Lambda runtime API served at api, the host and port given
+// by the AWS_LAMBDA_RUNTIME_API environment variable.
+func NewRuntime(api string) *Runtime {
+	return &Runtime{
+		baseURL: "http://" + api + "/" + runtimeAPIVersion + "/runtime",
+		// no timeout: waiting for the next invocation blocks until there is one
+		client: &http.Client{},
+	}
+}
+
+// Run handles the invocations of the function with h until ctx is done or the runtime API fails.
+// The context of an invocation carries its deadline and its request ID.
+func (rt *Runtime) Run(ctx context.Context, h HandlerFunc) error {
+	for {
+		inv, err := rt.next(ctx)
+		if err != nil {
+			return err
+		}
+
+		ictx, cancel := context.WithDeadline(helper.ContextWithRequestID(ctx, inv.requestID), inv.deadline)
+		res, err := h(ictx, inv.payload)
+		cancel()
+
+		if err != nil {
+			slog.ErrorContext(ictx, "lambda - invocation failed", "error", err)
+			err = rt.post(ctx, "/invocation/"+inv.requestID+"/error", invocationError{Message: err.Error(), Type: "GoyAV.Error"})
+		} else {
+			err = rt.post(ctx, "/invocation/"+inv.requestID+"/response", res)
+		}
+		if err != nil {
+			return err
+		}
+	}
+}
+
+// InitError reports to the runtime API that the function failed to initialize.
+func (rt *Runtime) InitError(ctx context.Context, err error) error {
+	return rt.post(ctx, "/init/error", invocationError{Message: err.Error(), Type: "GoyAV.InitError"})
+}
+
+// next waits for the next invocation of the function.
+func (rt *Runtime) next(ctx context.Context) (*invocation, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, rt.baseURL+"/invocation/next", nil)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRuntimeAPI, err)
+	}
+	resp, err := rt.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrRuntimeAPI, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("%w: next invocation: unexpected status %v", ErrRuntimeAPI, resp.Status)
+	}
+
+	inv := &invocation{requestID: resp.Header.Get("Lambda-Runtime-Aws-Request-Id")}
+	if inv.requestID == "" {
+		return nil, fmt.Errorf("%w: next invocation: missing request ID", ErrRuntimeAPI)
+	}
+	ms, err := strconv.ParseInt(resp.Header.Get("Lambda-Runtime-Deadline-Ms"), 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: next invocation: invalid deadline: %v", ErrRuntimeAPI, err)
+	}
+	inv.deadline = time.UnixMilli(ms)
+	if inv.payload, err = io.ReadAll(resp.Body); err != nil {
+		return nil, fmt.Errorf("%w: next invocation: %v", ErrRuntimeAPI, err)
+	}
+	return inv, nil
+}
+
+// post sends v as JSON to a path of the runtime API.
+func (rt *Runtime) post(ctx context.Context, path string, v any) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRuntimeAPI, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, rt.baseURL+path, bytes.NewReader(b))
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRuntimeAPI, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := rt.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrRuntimeAPI, err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode != http.StatusAccepted {
+		return fmt.Errorf("%w: %s: unexpected status %v", ErrRuntimeAPI, path, resp.Status)
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/port"
+	"sync"
+)
+
+// MockConcurrencyLimiter is a mock implementation of port.ConcurrencyLimiter giving its slots in memory, as a
+// RedisConcurrencyLimiter shared by the replicas of a single process would.
+type MockConcurrencyLimiter struct {
+	slots chan struct{}
+
+	mu  sync.Mutex
+	err error
+}
+
+// NewMock creates a new instance of MockConcurrencyLimiter giving at most capacity slots at once.
+func NewMock(capacity int) *MockConcurrencyLimiter {
+	return &MockConcurrencyLimiter{slots: make(chan struct{}, capacity)}
+}
+
+// Acquire blocks until a slot is available or ctx is done, and returns the function giving it back. It fails with
+// port.ErrConcurrencyLimiterFailed while the limiter is set unavailable.
+func (m *MockConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	m.mu.Lock()
+	err := m.err
+	m.mu.Unlock()
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", port.ErrConcurrencyLimiterFailed, err)
+	}
+	select {
+	case m.slots <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	var once sync.Once
+	return func() { once.Do(func() { <-m.slots }) }, nil
+}
+
+// SetUnavailable makes Acquire fail with err, or succeed again when err is nil.
+func (m *MockConcurrencyLimiter) SetUnavailable(err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.err = err
+}
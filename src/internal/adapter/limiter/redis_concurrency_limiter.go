@@ -0,0 +1,137 @@
+// Package limiter implements the concurrency limiters shared by the replicas of the service.
+package limiter
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/port"
+	"log/slog"
+	"math/rand/v2"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// DefaultKey is the default key of the sorted set of the slots of a RedisConcurrencyLimiter.
+	DefaultKey = "goyav:analyses"
+
+	// DefaultLease is the default time a slot of a RedisConcurrencyLimiter is held for without being renewed.
+	DefaultLease = 30 * time.Second
+
+	// pollInterval is the mean interval between two attempts to acquire a slot while all of them are taken.
+	pollInterval = 100 * time.Millisecond
+)
+
+// acquireScript removes the expired slots of the sorted set KEYS[1], then adds the slot ARGV[3], leased for ARGV[2]
+// milliseconds, unless ARGV[1] slots are taken already. The slots are scored by the time their lease expires, on
+// the clock of Redis, so that the clocks of the replicas do not matter.
+var acquireScript = redis.NewScript(`
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZREMRANGEBYSCORE', KEYS[1], '-inf', now)
+if redis.call('ZCARD', KEYS[1]) >= tonumber(ARGV[1]) then
+	return 0
+end
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[2]), ARGV[3])
+redis.call('PEXPIRE', KEYS[1], ARGV[2])
+return 1
+`)
+
+// renewScript extends the lease of the slot ARGV[2] of the sorted set KEYS[1] by ARGV[1] milliseconds, unless it
+// expired already.
+var renewScript = redis.NewScript(`
+if not redis.call('ZSCORE', KEYS[1], ARGV[2]) then
+	return 0
+end
+local t = redis.call('TIME')
+local now = tonumber(t[1]) * 1000 + math.floor(tonumber(t[2]) / 1000)
+redis.call('ZADD', KEYS[1], now + tonumber(ARGV[1]), ARGV[2])
+redis.call('PEXPIRE', KEYS[1], ARGV[1])
+return 1
+`)
+
+// RedisConcurrencyLimiter implements port.ConcurrencyLimiter with a sorted set in Redis holding the slots taken by
+// all the replicas sharing its key. A slot is leased, and its lease renewed while it is held, so that the slots of a
+// replica which stops without giving them back are freed once their lease expires. The slots are given to the first
+// replica attempting to acquire one once it is freed, not in the order the replicas are waiting for them.
+type RedisConcurrencyLimiter struct {
+	client   redis.UniversalClient
+	key      string
+	capacity int
+	lease    time.Duration
+}
+
+// NewRedis creates a concurrency limiter giving at most capacity slots at once, held in the sorted set key of client,
+// DefaultKey if it is empty, for lease at a time, DefaultLease if it is not strictly positive.
+func NewRedis(client redis.UniversalClient, key string, capacity int, lease time.Duration) *RedisConcurrencyLimiter {
+	if key == "" {
+		key = DefaultKey
+	}
+	if lease <= 0 {
+		lease = DefaultLease
+	}
+	return &RedisConcurrencyLimiter{client: client, key: key, capacity: capacity, lease: lease}
+}
+
+// Acquire blocks until a slot is available or ctx is done, and returns the function giving it back. The lease of
+// the slot is renewed until it is given back.
+func (l *RedisConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	token := uuid.NewString()
+	for {
+		ok, err := acquireScript.Run(ctx, l.client, []string{l.key}, l.capacity, l.lease.Milliseconds(), token).Bool()
+		switch {
+		case ctx.Err() != nil:
+			return nil, ctx.Err()
+		case err != nil:
+			return nil, fmt.Errorf("%w: %v", port.ErrConcurrencyLimiterFailed, err)
+		case ok:
+			return l.hold(token), nil
+		}
+		select {
+		case <-time.After(pollInterval/2 + rand.N(pollInterval)):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// hold renews the lease of the slot token until the function it returns gives it back.
+func (l *RedisConcurrencyLimiter) hold(token string) func() {
+	done := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(l.lease / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+			}
+			ctx, cancel := context.WithTimeout(context.Background(), l.lease/3)
+			held, err := renewScript.Run(ctx, l.client, []string{l.key}, l.lease.Milliseconds(), token).Bool()
+			cancel()
+			switch {
+			case err != nil:
+				slog.Warn("limiter - failed to renew the lease of a slot", "error", err, "key", l.key)
+			case !held:
+				slog.Warn("limiter - the lease of a slot expired before it was given back", "key", l.key)
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			close(done)
+			ctx, cancel := context.WithTimeout(context.Background(), l.lease/3)
+			defer cancel()
+			if err := l.client.ZRem(ctx, l.key, token).Err(); err != nil {
+				slog.Warn("limiter - failed to give back a slot, left to expire", "error", err, "key", l.key)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package limiter
+
+import (
+	"context"
+	"goyav/internal/core/port"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRedisConcurrencyLimiter(t *testing.T) {
+	mr := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+	defer client.Close()
+
+	// The limiters of two replicas share the same slots
+	a := NewRedis(client, "", 2, time.Minute)
+	b := NewRedis(client, "", 2, time.Minute)
+
+	t.Run("Capacity", func(t *testing.T) {
+		release1, err := a.Acquire(context.Background())
+		assert.NoError(t, err)
+		release2, err := b.Acquire(context.Background())
+		assert.NoError(t, err)
+		members, _ := mr.ZMembers(DefaultKey)
+		assert.Len(t, members, 2)
+
+		// all the slots are taken
+		ctx, cancel := context.WithTimeout(context.Background(), 300*time.Millisecond)
+		defer cancel()
+		_, err = a.Acquire(ctx)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+		// a slot given back by a replica is taken by the other one
+		acquired := make(chan error)
+		go func() {
+			release, err := b.Acquire(context.Background())
+			if err == nil {
+				release()
+			}
+			acquired <- err
+		}()
+		release1()
+		release1() // giving back a slot twice has no effect
+		select {
+		case err := <-acquired:
+			assert.NoError(t, err)
+		case <-time.After(time.Second):
+			t.Fatal("the slot given back was not acquired")
+		}
+
+		release2()
+		members, _ = mr.ZMembers(DefaultKey)
+		assert.Empty(t, members)
+	})
+
+	t.Run("Expiry", func(t *testing.T) {
+		// the slots of a replica which stopped without giving them back are freed once their lease expires
+		for _, token := range []string{"stopped-1", "stopped-2"} {
+			_, err := mr.ZAdd(DefaultKey, float64(time.Now().Add(-time.Second).UnixMilli()), token)
+			assert.NoError(t, err)
+		}
+		release, err := a.Acquire(context.Background())
+		assert.NoError(t, err)
+		members, _ := mr.ZMembers(DefaultKey)
+		assert.Len(t, members, 1)
+		release()
+	})
+
+	t.Run("Unavailable", func(t *testing.T) {
+		mr.Close()
+		_, err := a.Acquire(context.Background())
+		assert.ErrorIs(t, err, port.ErrConcurrencyLimiterFailed)
+	})
+}
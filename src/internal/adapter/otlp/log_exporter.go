@@ -0,0 +1,213 @@
+// Package otlp implements the export of the logs to an observability backend with the OpenTelemetry protocol, OTLP,
+// over HTTP with the JSON encoding.
+package otlp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default timeout of the export of a batch of log records.
+	DefaultTimeout = 10 * time.Second
+
+	// exportInterval is the interval between two exports of the log records queued.
+	exportInterval = 5 * time.Second
+
+	// batchSize is the number of log records queued which triggers an export without waiting for the interval.
+	batchSize = 512
+
+	// maxQueued is the number of log records queued above which the new ones are dropped, when the backend cannot keep
+	// up or cannot be reached.
+	maxQueued = 16 * batchSize
+)
+
+// LogExporter exports the log records of its handlers to the OTLP/HTTP logs endpoint of a collector, e.g.
+// http://otel-collector:4318/v1/logs, in batches. It must be closed to export the last records.
+type LogExporter struct {
+	url      string
+	headers  map[string]string
+	client   *http.Client
+	resource resource
+
+	mu      sync.Mutex
+	queue   []logRecord
+	dropped int
+
+	wake chan struct{}
+	stop chan struct{}
+	done chan struct{}
+}
+
+// NewLogExporter creates an exporter posting the log records to url with the extra headers, e.g. to authenticate to
+// the backend, with requests bounded by timeout. The records are attributed to the service named serviceName.
+func NewLogExporter(url string, headers map[string]string, serviceName string, timeout time.Duration) *LogExporter {
+	attrs := []keyValue{{Key: "service.name", Value: anyValue{StringValue: &serviceName}}}
+	if host, err := os.Hostname(); err == nil {
+		attrs = append(attrs, keyValue{Key: "host.name", Value: anyValue{StringValue: &host}})
+	}
+	e := &LogExporter{
+		url:      url,
+		headers:  headers,
+		client:   &http.Client{Timeout: timeout},
+		resource: resource{Attributes: attrs},
+		wake:     make(chan struct{}, 1),
+		stop:     make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// enqueue queues r for the next export, unless too many records are queued already.
+func (e *LogExporter) enqueue(r logRecord) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if len(e.queue) >= maxQueued {
+		e.dropped++
+		return
+	}
+	e.queue = append(e.queue, r)
+	if len(e.queue) == batchSize {
+		select {
+		case e.wake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// run exports the records queued every exportInterval, or as soon as a batch is full, until the exporter is closed.
+func (e *LogExporter) run() {
+	defer close(e.done)
+	ticker := time.NewTicker(exportInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+		case <-e.wake:
+		case <-e.stop:
+			e.flush()
+			return
+		}
+		e.flush()
+	}
+}
+
+// flush exports the records queued by batches. The records of a batch which cannot be exported are dropped, and the
+// failure is reported on stderr rather than logged, which would queue more records.
+func (e *LogExporter) flush() {
+	e.mu.Lock()
+	records, dropped := e.queue, e.dropped
+	e.queue, e.dropped = nil, 0
+	e.mu.Unlock()
+
+	if dropped > 0 {
+		fmt.Fprintf(os.Stderr, "otlp - %d log records dropped, too many records queued\n", dropped)
+	}
+	for len(records) > 0 {
+		n := min(len(records), batchSize)
+		if err := e.export(records[:n]); err != nil {
+			fmt.Fprintf(os.Stderr, "otlp - failed to export %d log records: %v\n", n, err)
+		}
+		records = records[n:]
+	}
+}
+
+// export posts records to the logs endpoint, which must answer with a 2xx status code.
+func (e *LogExporter) export(records []logRecord) error {
+	body, err := json.Marshal(&exportRequest{ResourceLogs: []resourceLogs{{
+		Resource:  e.resource,
+		ScopeLogs: []scopeLogs{{Scope: scope{Name: "goyav"}, LogRecords: records}},
+	}}})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(context.Background(), http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Close exports the records queued and stops the exporter. The records logged afterwards are dropped.
+func (e *LogExporter) Close() error {
+	select {
+	case <-e.stop:
+	default:
+		close(e.stop)
+	}
+	<-e.done
+	return nil
+}
+
+// exportRequest is the body of an OTLP export of logs, see ExportLogsServiceRequest.
+type exportRequest struct {
+	ResourceLogs []resourceLogs `json:"resourceLogs"`
+}
+
+type resourceLogs struct {
+	Resource  resource    `json:"resource"`
+	ScopeLogs []scopeLogs `json:"scopeLogs"`
+}
+
+type resource struct {
+	Attributes []keyValue `json:"attributes"`
+}
+
+type scopeLogs struct {
+	Scope      scope       `json:"scope"`
+	LogRecords []logRecord `json:"logRecords"`
+}
+
+type scope struct {
+	Name string `json:"name"`
+}
+
+// logRecord is an OTLP log record. The 64-bit integers are encoded as strings, and the trace and span IDs in
+// hexadecimal, as required by the JSON encoding of OTLP.
+type logRecord struct {
+	TimeUnixNano         string     `json:"timeUnixNano"`
+	ObservedTimeUnixNano string     `json:"observedTimeUnixNano"`
+	SeverityNumber       int        `json:"severityNumber"`
+	SeverityText         string     `json:"severityText"`
+	Body                 anyValue   `json:"body"`
+	Attributes           []keyValue `json:"attributes,omitempty"`
+	TraceID              string     `json:"traceId,omitempty"`
+	SpanID               string     `json:"spanId,omitempty"`
+	Flags                uint32     `json:"flags,omitempty"`
+}
+
+type keyValue struct {
+	Key   string   `json:"key"`
+	Value anyValue `json:"value"`
+}
+
+// anyValue is an OTLP attribute value, exactly one of its fields is set.
+type anyValue struct {
+	StringValue *string  `json:"stringValue,omitempty"`
+	BoolValue   *bool    `json:"boolValue,omitempty"`
+	IntValue    *string  `json:"intValue,omitempty"`
+	DoubleValue *float64 `json:"doubleValue,omitempty"`
+}
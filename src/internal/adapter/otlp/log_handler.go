@@ -0,0 +1,132 @@
+package otlp
+
+import (
+	"context"
+	"fmt"
+	"goyav/pkg/helper"
+	"log/slog"
+	"strconv"
+	"time"
+)
+
+// logHandler is a slog.Handler queuing the records for their export by a LogExporter. It exports all the records it is
+// given, their levels being enforced by the handlers wrapping it.
+type logHandler struct {
+	exporter *LogExporter
+	attrs    []keyValue // attrs are the attributes of the handler, added to each record.
+	prefix   string     // prefix is the dot-separated path of the groups of the handler, prefixing the next attributes.
+}
+
+// Handler returns a slog.Handler queuing the records for their export. The trace context carried by the context of
+// a record, see helper.ContextWithTraceContext, correlates the record to its trace.
+func (e *LogExporter) Handler() slog.Handler {
+	return &logHandler{exporter: e}
+}
+
+// Enabled reports true, the levels being enforced by the handlers wrapping h.
+func (h *logHandler) Enabled(context.Context, slog.Level) bool {
+	return true
+}
+
+// Handle queues r for its export.
+func (h *logHandler) Handle(ctx context.Context, r slog.Record) error {
+	t := r.Time
+	if t.IsZero() {
+		t = time.Now()
+	}
+	msg := r.Message
+	rec := logRecord{
+		TimeUnixNano:         strconv.FormatInt(t.UnixNano(), 10),
+		ObservedTimeUnixNano: strconv.FormatInt(time.Now().UnixNano(), 10),
+		SeverityNumber:       severityNumber(r.Level),
+		SeverityText:         r.Level.String(),
+		Body:                 anyValue{StringValue: &msg},
+		Attributes:           append(make([]keyValue, 0, len(h.attrs)+r.NumAttrs()), h.attrs...),
+	}
+	r.Attrs(func(a slog.Attr) bool {
+		rec.Attributes = appendAttr(rec.Attributes, h.prefix, a)
+		return true
+	})
+	if tc, ok := helper.TraceContextFromContext(ctx); ok {
+		rec.TraceID, rec.SpanID = tc.TraceID, tc.SpanID
+		if tc.Sampled {
+			rec.Flags = 1
+		}
+	}
+	h.exporter.enqueue(rec)
+	return nil
+}
+
+// WithAttrs returns a copy of h adding attrs to each record.
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = append([]keyValue(nil), h.attrs...)
+	for _, a := range attrs {
+		c.attrs = appendAttr(c.attrs, h.prefix, a)
+	}
+	return &c
+}
+
+// WithGroup returns a copy of h prefixing the next attributes with the group name.
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	if name == "" {
+		return h
+	}
+	c := *h
+	c.prefix = h.prefix + name + "."
+	return &c
+}
+
+// severityNumber returns the OTLP severity number of level: DEBUG is 5, INFO 9, WARN 13 and ERROR 17.
+func severityNumber(level slog.Level) int {
+	return min(max(int(level)+9, 1), 24)
+}
+
+// appendAttr appends a to attrs, its key prefixed with prefix, flattening the groups into dot-separated keys.
+func appendAttr(attrs []keyValue, prefix string, a slog.Attr) []keyValue {
+	a.Value = a.Value.Resolve()
+	if a.Equal(slog.Attr{}) {
+		return attrs
+	}
+	if a.Value.Kind() == slog.KindGroup {
+		p := prefix
+		if a.Key != "" {
+			p += a.Key + "."
+		}
+		for _, g := range a.Value.Group() {
+			attrs = appendAttr(attrs, p, g)
+		}
+		return attrs
+	}
+	return append(attrs, keyValue{Key: prefix + a.Key, Value: toAnyValue(a.Value)})
+}
+
+// toAnyValue converts v to an OTLP attribute value, as a string unless it is a boolean or a number.
+func toAnyValue(v slog.Value) anyValue {
+	var s string
+	switch v.Kind() {
+	case slog.KindBool:
+		b := v.Bool()
+		return anyValue{BoolValue: &b}
+	case slog.KindInt64:
+		s = strconv.FormatInt(v.Int64(), 10)
+		return anyValue{IntValue: &s}
+	case slog.KindUint64:
+		s = strconv.FormatUint(v.Uint64(), 10)
+		return anyValue{IntValue: &s}
+	case slog.KindFloat64:
+		f := v.Float64()
+		return anyValue{DoubleValue: &f}
+	case slog.KindTime:
+		s = v.Time().Format(time.RFC3339Nano)
+	case slog.KindAny:
+		if err, ok := v.Any().(error); ok {
+			s = err.Error()
+		} else {
+			s = fmt.Sprint(v.Any())
+		}
+	default:
+		s = v.String()
+	}
+	return anyValue{StringValue: &s}
+}
@@ -0,0 +1,75 @@
+package otlp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goyav/pkg/helper"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogExporter(t *testing.T) {
+	var (
+		mu       sync.Mutex
+		requests []exportRequest
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "Bearer secret", r.Header.Get("Authorization"))
+		var req exportRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		mu.Lock()
+		requests = append(requests, req)
+		mu.Unlock()
+	}))
+	defer srv.Close()
+
+	exporter := NewLogExporter(srv.URL, map[string]string{"Authorization": "Bearer secret"}, "goyav", time.Second)
+	logger := slog.New(exporter.Handler()).With("component", "test").WithGroup("upload")
+
+	tc := helper.TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true}
+	ctx := helper.ContextWithTraceContext(context.Background(), tc)
+	logger.WarnContext(ctx, "upload rejected", "size", 42, "ratio", 0.5, "error", errors.New("too large"), slog.Group("doc", "tag", "invoice"))
+	logger.Debug("without trace")
+	assert.NoError(t, exporter.Close())
+
+	if !assert.Len(t, requests, 1) {
+		return
+	}
+	rl := requests[0].ResourceLogs[0]
+	assert.Equal(t, "service.name", rl.Resource.Attributes[0].Key)
+	assert.Equal(t, "goyav", *rl.Resource.Attributes[0].Value.StringValue)
+	records := rl.ScopeLogs[0].LogRecords
+	if !assert.Len(t, records, 2) {
+		return
+	}
+
+	r := records[0]
+	assert.Equal(t, "upload rejected", *r.Body.StringValue)
+	assert.Equal(t, 13, r.SeverityNumber)
+	assert.Equal(t, "WARN", r.SeverityText)
+	assert.Equal(t, tc.TraceID, r.TraceID)
+	assert.Equal(t, tc.SpanID, r.SpanID)
+	assert.Equal(t, uint32(1), r.Flags)
+	attrs := make(map[string]anyValue)
+	for _, kv := range r.Attributes {
+		attrs[kv.Key] = kv.Value
+	}
+	assert.Equal(t, "test", *attrs["component"].StringValue)
+	assert.Equal(t, "42", *attrs["upload.size"].IntValue)
+	assert.Equal(t, 0.5, *attrs["upload.ratio"].DoubleValue)
+	assert.Equal(t, "too large", *attrs["upload.error"].StringValue)
+	assert.Equal(t, "invoice", *attrs["upload.doc.tag"].StringValue)
+
+	assert.Equal(t, 5, records[1].SeverityNumber)
+	assert.Empty(t, records[1].TraceID)
+}
@@ -0,0 +1,60 @@
+package report
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"mime"
+	"net"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// EmailSender implements port.ReportSender by mailing the reports as plain text through an SMTP server, which is
+// authenticated with PLAIN when a username is given. The connection is upgraded with STARTTLS when the server offers it.
+type EmailSender struct {
+	addr string
+	auth smtp.Auth
+	from string
+	to   []string
+
+	// sendMail sends the message, smtp.SendMail unless replaced by the tests.
+	sendMail func(addr string, a smtp.Auth, from string, to []string, msg []byte) error
+}
+
+// NewEmail creates a sender mailing the reports from the address from to the addresses to, through the SMTP server
+// listening on addr, as host:port.
+func NewEmail(addr, username, password, from string, to []string) *EmailSender {
+	e := &EmailSender{addr: addr, from: from, to: to, sendMail: smtp.SendMail}
+	if username != "" {
+		host, _, _ := net.SplitHostPort(addr)
+		e.auth = smtp.PlainAuth("", username, password, host)
+	}
+	return e
+}
+
+// Send mails report to the recipients. smtp.SendMail does not take a context, the delivery is only bounded by the
+// timeouts of the SMTP server.
+func (e *EmailSender) Send(_ context.Context, report *domain.SummaryReport) error {
+	if err := e.sendMail(e.addr, e.auth, e.from, e.to, e.message(report, time.Now())); err != nil {
+		return fmt.Errorf("%w: %v", port.ErrReportDeliveryFailed, err)
+	}
+	return nil
+}
+
+// message returns the email of report, dated now.
+func (e *EmailSender) message(report *domain.SummaryReport, now time.Time) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "From: %s\r\n", e.from)
+	fmt.Fprintf(&b, "To: %s\r\n", strings.Join(e.to, ", "))
+	fmt.Fprintf(&b, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", Subject(report)))
+	fmt.Fprintf(&b, "Date: %s\r\n", now.Format(time.RFC1123Z))
+	b.WriteString("MIME-Version: 1.0\r\n")
+	b.WriteString("Content-Type: text/plain; charset=utf-8\r\n")
+	b.WriteString("\r\n")
+	b.WriteString(strings.ReplaceAll(Text(report), "\n", "\r\n"))
+	return b.Bytes()
+}
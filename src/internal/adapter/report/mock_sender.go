@@ -0,0 +1,34 @@
+package report
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"sync"
+)
+
+// MockSender is a mock implementation of port.ReportSender recording the reports instead of delivering them.
+type MockSender struct {
+	mu      sync.Mutex
+	reports []*domain.SummaryReport
+}
+
+// NewMock creates a new instance of MockSender.
+func NewMock() *MockSender {
+	return &MockSender{}
+}
+
+// Send records a copy of report as delivered.
+func (m *MockSender) Send(_ context.Context, report *domain.SummaryReport) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	r := *report
+	m.reports = append(m.reports, &r)
+	return nil
+}
+
+// Reports returns the reports delivered, in order.
+func (m *MockSender) Reports() []*domain.SummaryReport {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return append([]*domain.SummaryReport(nil), m.reports...)
+}
@@ -0,0 +1,77 @@
+package report
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"net/http"
+	"net/http/httptest"
+	"net/smtp"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var testReport = &domain.SummaryReport{
+	Period:         domain.ReportDaily,
+	From:           time.Date(2024, 3, 17, 6, 0, 0, 0, time.UTC),
+	To:             time.Date(2024, 3, 18, 6, 0, 0, 0, time.UTC),
+	Total:          10,
+	Infected:       2,
+	Clean:          6,
+	Failed:         1,
+	Pending:        1,
+	InfectedRate:   0.25,
+	TopThreats:     []domain.ThreatCount{{Name: "Win.Test.EICAR_HDB-1", Count: 2}},
+	BusiestTenants: []domain.TenantCount{{Tenant: "bu-a", Uploads: 7}, {Tenant: "", Uploads: 3}},
+}
+
+func TestWebhookSender(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var m webhookMessage
+		if err := json.NewDecoder(r.Body).Decode(&m); err != nil || m.Report == nil {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		assert.Equal(t, "GoyAV daily report of 2024-03-18", m.Message)
+		assert.Equal(t, testReport.TopThreats, m.Report.TopThreats)
+		assert.Equal(t, 0.25, m.Report.InfectedRate)
+	}))
+	defer srv.Close()
+	assert.NoError(t, NewWebhook(srv.URL, time.Second, 0).Send(context.Background(), testReport))
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer failing.Close()
+	assert.ErrorIs(t, NewWebhook(failing.URL, time.Second, 0).Send(context.Background(), testReport), port.ErrReportDeliveryFailed)
+}
+
+func TestEmailSender(t *testing.T) {
+	var (
+		sentTo  []string
+		message string
+	)
+	e := NewEmail("smtp.example.com:587", "goyav", "secret", "goyav@example.com", []string{"secops@example.com", "ops@example.com"})
+	e.sendMail = func(addr string, a smtp.Auth, from string, to []string, msg []byte) error {
+		assert.Equal(t, "smtp.example.com:587", addr)
+		assert.NotNil(t, a)
+		sentTo, message = to, string(msg)
+		return nil
+	}
+	assert.NoError(t, e.Send(context.Background(), testReport))
+	assert.Equal(t, []string{"secops@example.com", "ops@example.com"}, sentTo)
+	assert.Contains(t, message, "To: secops@example.com, ops@example.com\r\n")
+	assert.Contains(t, message, "Subject: GoyAV daily report of 2024-03-18\r\n")
+	assert.Contains(t, message, "Infected rate: 25.00%\r\n")
+	assert.Contains(t, message, "Win.Test.EICAR_HDB-1")
+	assert.Contains(t, message, "(default)")
+	assert.False(t, strings.Contains(strings.ReplaceAll(message, "\r\n", ""), "\n"), "the lines must end with CRLF")
+
+	e.sendMail = func(string, smtp.Auth, string, []string, []byte) error { return errors.New("connection refused") }
+	assert.ErrorIs(t, e.Send(context.Background(), testReport), port.ErrReportDeliveryFailed)
+}
@@ -0,0 +1,50 @@
+package report
+
+import (
+	"fmt"
+	"goyav/internal/core/domain"
+	"strings"
+)
+
+// Subject returns the title of report, e.g. "GoyAV daily report of 2024-03-18".
+func Subject(report *domain.SummaryReport) string {
+	period := report.Period
+	if period == "" {
+		period = "summary"
+	}
+	return fmt.Sprintf("GoyAV %s report of %s", period, report.To.UTC().Format("2006-01-02"))
+}
+
+// Text renders report as plain text.
+func Text(report *domain.SummaryReport) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s\n\n", Subject(report))
+	fmt.Fprintf(&b, "Documents uploaded from %s to %s\n\n", report.From.UTC().Format("2006-01-02 15:04 MST"), report.To.UTC().Format("2006-01-02 15:04 MST"))
+	fmt.Fprintf(&b, "Total:         %d\n", report.Total)
+	fmt.Fprintf(&b, "Clean:         %d\n", report.Clean)
+	fmt.Fprintf(&b, "Infected:      %d\n", report.Infected)
+	fmt.Fprintf(&b, "Failed:        %d\n", report.Failed)
+	fmt.Fprintf(&b, "Pending:       %d\n", report.Pending)
+	fmt.Fprintf(&b, "Infected rate: %.2f%%\n", report.InfectedRate*100)
+
+	b.WriteString("\nTop threats\n")
+	if len(report.TopThreats) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, t := range report.TopThreats {
+		fmt.Fprintf(&b, "  %-40s %d\n", t.Name, t.Count)
+	}
+
+	b.WriteString("\nBusiest tenants\n")
+	if len(report.BusiestTenants) == 0 {
+		b.WriteString("  none\n")
+	}
+	for _, t := range report.BusiestTenants {
+		tenant := t.Tenant
+		if tenant == "" {
+			tenant = "(default)"
+		}
+		fmt.Fprintf(&b, "  %-40s %d\n", tenant, t.Uploads)
+	}
+	return b.String()
+}
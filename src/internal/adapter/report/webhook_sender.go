@@ -0,0 +1,67 @@
+// Package report implements the delivery of the summary reports of the service.
+package report
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout is the default timeout of the delivery of a report.
+const DefaultTimeout = 30 * time.Second
+
+// WebhookSender implements port.ReportSender by posting the reports as JSON to a webhook URL. Unlike the callback URLs,
+// the webhook URL is configured by the operator and may target a private address.
+type WebhookSender struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhook creates a sender posting the reports to url with requests bounded by timeout, their bodies of at least
+// compression bytes being compressed if the webhook accepts it, see helper.CompressingTransport.
+func NewWebhook(url string, timeout time.Duration, compression int) *WebhookSender {
+	return &WebhookSender{url: url, client: &http.Client{Timeout: timeout, Transport: helper.CompressingTransport(nil, compression)}}
+}
+
+// webhookMessage is the body posted to the webhook URL.
+type webhookMessage struct {
+	Message string                `json:"message"`
+	Report  *domain.SummaryReport `json:"report"`
+}
+
+// Send posts report to the webhook URL, which must answer with a 2xx status code.
+func (w *WebhookSender) Send(ctx context.Context, report *domain.SummaryReport) error {
+	body, err := json.Marshal(NewWebhookMessage(report))
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrReportDeliveryFailed, err)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrReportDeliveryFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+
+	resp, err := w.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrReportDeliveryFailed, err)
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%w: unexpected status code %d", port.ErrReportDeliveryFailed, resp.StatusCode)
+	}
+	return nil
+}
+
+// NewWebhookMessage returns the body of report posted as JSON to the webhook URLs.
+func NewWebhookMessage(report *domain.SummaryReport) any {
+	return &webhookMessage{Message: Subject(report), Report: report}
+}
@@ -0,0 +1,106 @@
+// Package reputation implements the external reputation sources of the service.
+package reputation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultTimeout is the default timeout of a lookup.
+	DefaultTimeout = 5 * time.Second
+
+	// DefaultMalwareBazaarURL is the URL of the API of MalwareBazaar.
+	DefaultMalwareBazaarURL = "https://mb-api.abuse.ch/api/v1/"
+
+	// maxResponseSize bounds the size of the responses read from a reputation source.
+	maxResponseSize = 1 << 20
+)
+
+// MalwareBazaar implements port.ReputationSource with the API of MalwareBazaar, which only lists malware: the content
+// it knows is infected, and the content it does not know is left to the analyzer.
+type MalwareBazaar struct {
+	url    string
+	apiKey string
+	client *http.Client
+}
+
+// NewMalwareBazaar creates a reputation source querying the MalwareBazaar API at url, DefaultMalwareBazaarURL if it is
+// empty, with apiKey, with requests bounded by timeout.
+func NewMalwareBazaar(url, apiKey string, timeout time.Duration) *MalwareBazaar {
+	if url == "" {
+		url = DefaultMalwareBazaarURL
+	}
+	return &MalwareBazaar{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}}
+}
+
+// malwareBazaarResponse is the body of the responses to the get_info queries.
+type malwareBazaarResponse struct {
+	QueryStatus string `json:"query_status"`
+	Data        []struct {
+		Signature string `json:"signature"`
+	} `json:"data"`
+}
+
+// Lookup returns the reputation of the content with the given SHA-256 hash, infected if MalwareBazaar knows it.
+func (m *MalwareBazaar) Lookup(ctx context.Context, sha256 string) (domain.Reputation, error) {
+	rep := domain.Reputation{Status: domain.StatusPending, Source: "malwarebazaar"}
+	form := url.Values{"query": {"get_info"}, "hash": {sha256}}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, m.url, strings.NewReader(form.Encode()))
+	if err != nil {
+		return rep, fmt.Errorf("%w: %v", port.ErrReputationLookupFailed, err)
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Auth-Key", m.apiKey)
+	req.Header.Set("User-Agent", "GoyAV")
+
+	var body malwareBazaarResponse
+	if err = doJSON(m.client, req, &body); err != nil {
+		return rep, err
+	}
+	switch body.QueryStatus {
+	case "hash_not_found":
+		return rep, nil
+	case "ok":
+	default:
+		return rep, fmt.Errorf("%w: query status %q", port.ErrReputationLookupFailed, body.QueryStatus)
+	}
+	rep.Status, rep.Threat = domain.StatusInfected, "MalwareBazaar.Malware"
+	if len(body.Data) > 0 && body.Data[0].Signature != "" {
+		rep.Threat = "MalwareBazaar." + body.Data[0].Signature
+	}
+	return rep, nil
+}
+
+// errNotFound is returned by doJSON when the content looked up is not known to the source.
+var errNotFound = errors.New("content not found")
+
+// doJSON sends req with client and decodes the JSON body of its 200 response into v.
+func doJSON(client *http.Client, req *http.Request, v any) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("%w: %v", port.ErrReputationLookupFailed, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+		return fmt.Errorf("%w: %w", port.ErrReputationLookupFailed, errNotFound)
+	}
+	if resp.StatusCode != http.StatusOK {
+		io.Copy(io.Discard, io.LimitReader(resp.Body, maxResponseSize))
+		return fmt.Errorf("%w: unexpected status code %d", port.ErrReputationLookupFailed, resp.StatusCode)
+	}
+	if err = json.NewDecoder(io.LimitReader(resp.Body, maxResponseSize)).Decode(v); err != nil {
+		return fmt.Errorf("%w: invalid response: %v", port.ErrReputationLookupFailed, err)
+	}
+	return nil
+}
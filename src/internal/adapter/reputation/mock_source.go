@@ -0,0 +1,58 @@
+package reputation
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"sync"
+)
+
+// MockSource is a mock implementation of port.ReputationSource giving the reputations set with SetReputation.
+type MockSource struct {
+	mu          sync.Mutex
+	reputations map[string]domain.Reputation
+	online      bool
+	lookups     int
+}
+
+// NewMock creates a new instance of MockSource, online and knowing no content.
+func NewMock() *MockSource {
+	return &MockSource{reputations: make(map[string]domain.Reputation), online: true}
+}
+
+// SetReputation sets the reputation of the content with the given SHA-256 hash.
+func (m *MockSource) SetReputation(sha256 string, rep domain.Reputation) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	rep.Source = "mock"
+	m.reputations[sha256] = rep
+}
+
+// IsOnline sets whether the lookups succeed.
+func (m *MockSource) IsOnline(online bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.online = online
+}
+
+// Lookups returns the number of lookups made.
+func (m *MockSource) Lookups() int {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.lookups
+}
+
+// Lookup returns the reputation set for the content with the given SHA-256 hash, pending if there is none.
+func (m *MockSource) Lookup(_ context.Context, sha256 string) (domain.Reputation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.lookups++
+	if !m.online {
+		return domain.Reputation{}, port.ErrReputationLookupFailed
+	}
+	rep, ok := m.reputations[sha256]
+	if !ok {
+		return domain.Reputation{Status: domain.StatusPending, Source: "mock"}, nil
+	}
+	return rep, nil
+}
@@ -0,0 +1,96 @@
+package reputation
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	knownHash   = "275a021bbfb6489e54d471899f7db9d1663fc695ec2fe2a2c4538aabf651fd0f"
+	unknownHash = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+	cleanHash   = "9f86d081884c7d659a2feaa0c55ad015a3bf4f1b2b0b822cd15d6c15b0f00a08"
+)
+
+func TestMalwareBazaar(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Auth-Key") != "key" {
+			w.Write([]byte(`{"query_status": "unknown_auth_key"}`))
+			return
+		}
+		assert.Equal(t, "get_info", r.FormValue("query"))
+		switch r.FormValue("hash") {
+		case knownHash:
+			w.Write([]byte(`{"query_status": "ok", "data": [{"sha256_hash": "` + knownHash + `", "signature": "AgentTesla"}]}`))
+		default:
+			w.Write([]byte(`{"query_status": "hash_not_found"}`))
+		}
+	}))
+	defer srv.Close()
+
+	mb := NewMalwareBazaar(srv.URL, "key", time.Second)
+	rep, err := mb.Lookup(ctx, knownHash)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Reputation{Status: domain.StatusInfected, Threat: "MalwareBazaar.AgentTesla", Source: "malwarebazaar"}, rep)
+
+	rep, err = mb.Lookup(ctx, unknownHash)
+	assert.NoError(t, err)
+	assert.False(t, rep.IsConfident(), "unknown content should be analyzed")
+
+	_, err = NewMalwareBazaar(srv.URL, "wrong", time.Second).Lookup(ctx, knownHash)
+	assert.ErrorIs(t, err, port.ErrReputationLookupFailed)
+}
+
+func TestVirusTotal(t *testing.T) {
+	ctx := context.Background()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("x-apikey") != "key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch strings.TrimPrefix(r.URL.Path, "/files/") {
+		case knownHash:
+			w.Write([]byte(`{"data": {"attributes": {"last_analysis_stats": {"malicious": 12, "undetected": 50},
+				"popular_threat_classification": {"suggested_threat_label": "trojan.agenttesla"}}}}`))
+		case cleanHash:
+			w.Write([]byte(`{"data": {"attributes": {"last_analysis_stats": {"malicious": 0, "suspicious": 0, "undetected": 60, "harmless": 10}}}}`))
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	vt := NewVirusTotal(srv.URL+"/files/", "key", time.Second, 0, 0)
+	rep, err := vt.Lookup(ctx, knownHash)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.Reputation{Status: domain.StatusInfected, Threat: "VirusTotal.trojan.agenttesla", Source: "virustotal"}, rep)
+
+	rep, err = vt.Lookup(ctx, cleanHash)
+	assert.NoError(t, err)
+	assert.False(t, rep.IsConfident(), "the content should never be clean by default")
+
+	rep, err = vt.Lookup(ctx, unknownHash)
+	assert.NoError(t, err)
+	assert.False(t, rep.IsConfident(), "unknown content should be analyzed")
+
+	t.Run("Thresholds", func(t *testing.T) {
+		vt := NewVirusTotal(srv.URL+"/files/", "key", time.Second, 20, 50)
+		rep, err := vt.Lookup(ctx, knownHash)
+		assert.NoError(t, err)
+		assert.False(t, rep.IsConfident(), "12 detections are below the threshold")
+		rep, err = vt.Lookup(ctx, cleanHash)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusClean, rep.Status)
+	})
+
+	_, err = NewVirusTotal(srv.URL+"/files/", "wrong", time.Second, 0, 0).Lookup(ctx, knownHash)
+	assert.ErrorIs(t, err, port.ErrReputationLookupFailed)
+}
@@ -0,0 +1,93 @@
+package reputation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const (
+	// DefaultVirusTotalURL is the URL of the files of the API of VirusTotal.
+	DefaultVirusTotalURL = "https://www.virustotal.com/api/v3/files/"
+
+	// DefaultMinMalicious is the default number of engines of VirusTotal detecting content for it to be infected.
+	DefaultMinMalicious = 5
+)
+
+// VirusTotal implements port.ReputationSource with the API of VirusTotal, from the results of the last analysis of the
+// content by its engines: the content is infected once minMalicious engines detect it, and clean once minClean engines
+// analyzed it without any detecting it, nor finding it suspicious. Any other content is left to the analyzer.
+type VirusTotal struct {
+	url          string
+	apiKey       string
+	client       *http.Client
+	minMalicious int
+	minClean     int
+}
+
+// NewVirusTotal creates a reputation source querying the VirusTotal API at url, DefaultVirusTotalURL if it is empty,
+// with apiKey, with requests bounded by timeout. The content is infected once minMalicious engines detect it,
+// DefaultMinMalicious if it is not strictly positive, and clean once minClean engines analyzed it without result, the
+// content is never clean when minClean is zero.
+func NewVirusTotal(url, apiKey string, timeout time.Duration, minMalicious, minClean int) *VirusTotal {
+	if url == "" {
+		url = DefaultVirusTotalURL
+	}
+	if minMalicious <= 0 {
+		minMalicious = DefaultMinMalicious
+	}
+	return &VirusTotal{url: url, apiKey: apiKey, client: &http.Client{Timeout: timeout}, minMalicious: minMalicious, minClean: minClean}
+}
+
+// virusTotalResponse is the body of the responses to the file lookups.
+type virusTotalResponse struct {
+	Data struct {
+		Attributes struct {
+			LastAnalysisStats struct {
+				Malicious  int `json:"malicious"`
+				Suspicious int `json:"suspicious"`
+				Undetected int `json:"undetected"`
+				Harmless   int `json:"harmless"`
+			} `json:"last_analysis_stats"`
+			PopularThreatClassification struct {
+				SuggestedThreatLabel string `json:"suggested_threat_label"`
+			} `json:"popular_threat_classification"`
+		} `json:"attributes"`
+	} `json:"data"`
+}
+
+// Lookup returns the reputation of the content with the given SHA-256 hash, from the last analysis of VirusTotal.
+func (v *VirusTotal) Lookup(ctx context.Context, sha256 string) (domain.Reputation, error) {
+	rep := domain.Reputation{Status: domain.StatusPending, Source: "virustotal"}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, v.url+url.PathEscape(sha256), nil)
+	if err != nil {
+		return rep, fmt.Errorf("%w: %v", port.ErrReputationLookupFailed, err)
+	}
+	req.Header.Set("x-apikey", v.apiKey)
+	req.Header.Set("Accept", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+
+	var body virusTotalResponse
+	if err = doJSON(v.client, req, &body); err != nil {
+		if errors.Is(err, errNotFound) {
+			return rep, nil
+		}
+		return rep, err
+	}
+	stats := body.Data.Attributes.LastAnalysisStats
+	switch {
+	case stats.Malicious >= v.minMalicious:
+		rep.Status, rep.Threat = domain.StatusInfected, "VirusTotal.Malicious"
+		if label := body.Data.Attributes.PopularThreatClassification.SuggestedThreatLabel; label != "" {
+			rep.Threat = "VirusTotal." + label
+		}
+	case v.minClean > 0 && stats.Malicious == 0 && stats.Suspicious == 0 && stats.Undetected+stats.Harmless >= v.minClean:
+		rep.Status = domain.StatusClean
+	}
+	return rep, nil
+}
@@ -4,10 +4,13 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
 	"goyav/pkg/helper"
 	"io"
 	"log"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
@@ -211,6 +214,115 @@ func TestGet(t *testing.T) {
 	})
 }
 
+func TestQuarantine(t *testing.T) {
+	bucketName := "quarantine-bucket"
+	repo, err := NewMinio(client, bucketName, WithLifecycleExpiry(36*time.Hour), WithQuarantine(time.Hour, true))
+	if err != nil {
+		t.Fatalf("Failed to create MinioBinaryRepository: %v", err)
+	}
+
+	// the lifecycle expires the objects after whole days
+	cfg, err := client.GetBucketLifecycle(ctx, bucketName)
+	if err != nil {
+		t.Fatalf("Failed to get the lifecycle of the bucket: %v", err)
+	}
+	assert.Len(t, cfg.Rules, 1)
+	assert.Equal(t, 2, int(cfg.Rules[0].Expiration.Days))
+
+	// the quarantined objects cannot be deleted
+	testID := "infected-file"
+	testData := []byte("infected data")
+	if err := repo.Save(ctx, bytes.NewReader(testData), int64(len(testData)), testID); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+	assert.NoError(t, repo.Quarantine(ctx, testID))
+	info, err := client.StatObject(ctx, bucketName, testID, minio.StatObjectOptions{})
+	if err != nil {
+		t.Fatalf("Failed to stat the quarantined object: %v", err)
+	}
+	err = client.RemoveObject(ctx, bucketName, testID, minio.RemoveObjectOptions{VersionID: info.VersionID})
+	assert.Error(t, err, "a quarantined object must not be deleted")
+
+	// object locking cannot be enabled on an existing bucket
+	if err := client.MakeBucket(ctx, "unlocked-bucket", minio.MakeBucketOptions{}); err != nil {
+		t.Fatalf("Failed to create bucket: %v", err)
+	}
+	_, err = NewMinio(client, "unlocked-bucket", WithQuarantine(0, true))
+	assert.Error(t, err)
+}
+
+func TestServerSideEncryption(t *testing.T) {
+	// the key ID is required in SSE-KMS mode
+	_, err := NewMinio(client, "sse-bucket", WithServerSideEncryption(SSEKMS, ""))
+	assert.Error(t, err)
+
+	// unknown modes are rejected
+	_, err = NewMinio(client, "sse-bucket", WithServerSideEncryption("SSE-C", ""))
+	assert.Error(t, err)
+
+	_, err = NewMinio(client, "sse-bucket", WithServerSideEncryption(SSENone, ""))
+	assert.NoError(t, err)
+}
+
+func TestKeySharding(t *testing.T) {
+	repo, err := NewMinio(client, "sharded-bucket", WithKeySharding(2))
+	if err != nil {
+		t.Fatalf("Failed to create MinioBinaryRepository: %v", err)
+	}
+	tenantCtx := domain.ContextWithTenant(ctx, "finance")
+	testData := []byte("Hello, MinIO!")
+	if err = repo.Save(tenantCtx, bytes.NewReader(testData), int64(len(testData)), "test-file"); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// the object is stored under two levels of prefixes of the hash of its ID
+	key := repo.key(tenantCtx, "test-file")
+	assert.Regexp(t, `^finance/[0-9a-f]{2}/[0-9a-f]{2}/test-file$`, key)
+	_, err = client.StatObject(ctx, "sharded-bucket", key, minio.StatObjectOptions{})
+	assert.NoError(t, err)
+
+	r, err := repo.Get(tenantCtx, "test-file")
+	if assert.NoError(t, err) {
+		r.Close()
+	}
+	var found []port.BinaryInfo
+	err = repo.Walk(ctx, func(info port.BinaryInfo) error {
+		found = append(found, info)
+		return nil
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, found, 1) {
+		assert.Equal(t, "finance", found[0].Tenant)
+		assert.Equal(t, "test-file", found[0].ID)
+	}
+	assert.NoError(t, repo.Delete(tenantCtx, "test-file"))
+}
+
+func TestMultipartUpload(t *testing.T) {
+	repo, err := NewMinio(client, "multipart-bucket", WithMultipartUpload(MinPartSize, MinPartSize, 2))
+	if err != nil {
+		t.Fatalf("Failed to create MinioBinaryRepository: %v", err)
+	}
+	testData := bytes.Repeat([]byte("0123456789abcdef"), 3*MinPartSize/16)
+	if err = repo.Save(ctx, bytes.NewReader(testData), int64(len(testData)), "large-file"); err != nil {
+		t.Fatalf("Failed to save data: %v", err)
+	}
+
+	// the object is uploaded in three parts, whose number ends its ETag
+	info, err := client.StatObject(ctx, "multipart-bucket", repo.key(ctx, "large-file"), minio.StatObjectOptions{})
+	if assert.NoError(t, err) {
+		assert.True(t, strings.HasSuffix(info.ETag, "-3"), info.ETag)
+	}
+	r, err := repo.Get(ctx, "large-file")
+	if assert.NoError(t, err) {
+		b, err := io.ReadAll(r)
+		r.Close()
+		assert.NoError(t, err)
+		assert.Equal(t, testData, b)
+	}
+	assert.NoError(t, repo.Delete(ctx, "large-file"))
+}
+
 func TestPing(t *testing.T) {
 	bucketName := "test-bucket"
 
@@ -6,23 +6,61 @@ import (
 	"fmt"
 	"io"
 	"log/slog"
+	"mime"
+	"net/url"
+	"strings"
 	"time"
 
+	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
+	"goyav/pkg/helper"
 
 	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/encrypt"
 )
 
 // MinioBinaryRepository provides a storage backend using Minio.
 type MinioBinaryRepository struct {
 	client     *minio.Client
 	bucketName string
+
+	// expiry is the age of the objects expired by the lifecycle of the bucket, which is left as is when it is zero.
+	expiry time.Duration
+
+	// quarantineRetention and quarantineLegalHold protect the quarantined objects, see WithQuarantine.
+	quarantineRetention time.Duration
+	quarantineLegalHold bool
+
+	// sse encrypts the saved objects, built from sseMode and sseKeyID, see WithServerSideEncryption.
+	sseMode  string
+	sseKeyID string
+	sse      encrypt.ServerSide
+
+	// shardLevels is the number of levels of key prefixes the objects are spread across, see WithKeySharding.
+	shardLevels int
+
+	// multipartThreshold, partSize and partParallelism configure the multipart uploads, see WithMultipartUpload.
+	multipartThreshold uint64
+	partSize           uint64
+	partParallelism    uint
+
+	// ops logs the failed and slow operations with the context of their request, see WithSlowOperations.
+	ops helper.OperationLog
+}
+
+// WithSlowOperations makes the repository log the saves, gets, deletions, quarantines and taggings of objects lasting
+// at least threshold, along with the failed ones, with the request ID of the request they serve.
+func WithSlowOperations(threshold time.Duration) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.ops.Slow = max(0, threshold)
+	}
 }
 
 var ErrMinioBinaryRepository = errors.New("MinioBinaryRepository")
 
-// NewMinio creates a new instance of MinioByteRepository.
-func NewMinio(client *minio.Client, bucketName string) (*MinioBinaryRepository, error) {
+// NewMinio creates a new instance of MinioByteRepository, creating its bucket if it does not exist.
+// Optional behaviours are enabled with opts.
+func NewMinio(client *minio.Client, bucketName string, opts ...MinioOption) (*MinioBinaryRepository, error) {
 
 	if client == nil {
 		return nil, fmt.Errorf("%w: client is nil", ErrMinioBinaryRepository)
@@ -32,6 +70,20 @@ func NewMinio(client *minio.Client, bucketName string) (*MinioBinaryRepository,
 		return nil, fmt.Errorf("%w: bucket name is empty", ErrMinioBinaryRepository)
 	}
 
+	m := &MinioBinaryRepository{
+		client:     client,
+		bucketName: bucketName,
+		ops:        helper.OperationLog{Component: "minio", Ignore: []error{port.ErrBinaryNotFound}},
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	sse, err := newServerSide(m.sseMode, m.sseKeyID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrMinioBinaryRepository, err)
+	}
+	m.sse = sse
+
 	bucketExists, err := client.BucketExists(context.Background(), bucketName)
 	if err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrMinioBinaryRepository, err)
@@ -39,21 +91,29 @@ func NewMinio(client *minio.Client, bucketName string) (*MinioBinaryRepository,
 
 	// if the named bucket doesn't exist, create it.
 	if !bucketExists {
-		if err = client.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{}); err != nil {
+		if err = client.MakeBucket(context.Background(), bucketName, minio.MakeBucketOptions{ObjectLocking: m.objectLocking()}); err != nil {
 			return nil, fmt.Errorf("%w: %v", ErrMinioBinaryRepository, err)
 		}
 		slog.Debug("a new bucket is created")
+	} else if m.objectLocking() {
+		if err = m.checkObjectLocking(context.Background()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMinioBinaryRepository, err)
+		}
 	}
 
-	return &MinioBinaryRepository{
-		client:     client,
-		bucketName: bucketName,
-	}, nil
+	if m.expiry > 0 {
+		if err = m.setLifecycle(context.Background()); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrMinioBinaryRepository, err)
+		}
+	}
+
+	return m, nil
 }
 
 // Save saves an object into the Minio bucket
-func (m *MinioBinaryRepository) Save(ctx context.Context, data io.Reader, size int64, ID string) error {
-	_, err := m.client.PutObject(ctx, m.bucketName, ID, io.LimitReader(data, size), size, minio.PutObjectOptions{})
+func (m *MinioBinaryRepository) Save(ctx context.Context, data io.Reader, size int64, ID string) (err error) {
+	defer m.ops.Observe(ctx, "save", time.Now(), &err, "ID", ID, "size", size)
+	_, err = m.client.PutObject(ctx, m.bucketName, m.key(ctx, ID), io.LimitReader(data, size), size, m.putOptions(size))
 	if err != nil {
 		return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrSaveDataFailed, err)
 	}
@@ -62,11 +122,15 @@ func (m *MinioBinaryRepository) Save(ctx context.Context, data io.Reader, size i
 }
 
 // Delete removes an object from the Minio bucket identified by ID. Returns an error if the object is not found.
-func (m MinioBinaryRepository) Delete(ctx context.Context, ID string) error {
+func (m MinioBinaryRepository) Delete(ctx context.Context, ID string) (err error) {
+	defer m.ops.Observe(ctx, "delete", time.Now(), &err, "ID", ID)
 	if err := m.exists(ctx, ID); err != nil {
+		if errors.Is(err, port.ErrBinaryNotFound) {
+			return fmt.Errorf("%w: %w: %w", ErrMinioBinaryRepository, port.ErrDeleteDataFailed, err)
+		}
 		return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrDeleteDataFailed, err)
 	}
-	err := m.client.RemoveObject(ctx, m.bucketName, ID, minio.RemoveObjectOptions{ForceDelete: true})
+	err = m.client.RemoveObject(ctx, m.bucketName, m.key(ctx, ID), minio.RemoveObjectOptions{ForceDelete: true})
 	if err != nil {
 		return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrDeleteDataFailed, err)
 	}
@@ -74,17 +138,58 @@ func (m MinioBinaryRepository) Delete(ctx context.Context, ID string) error {
 }
 
 // Get returns an object from the Minio bucket identified by ID. Returns error if the object does not exist.
-func (m MinioBinaryRepository) Get(ctx context.Context, ID string) (io.ReadCloser, error) {
+func (m MinioBinaryRepository) Get(ctx context.Context, ID string) (_ io.ReadCloser, err error) {
+	defer m.ops.Observe(ctx, "get", time.Now(), &err, "ID", ID)
 	if err := m.exists(ctx, ID); err != nil {
+		if errors.Is(err, port.ErrBinaryNotFound) {
+			return nil, fmt.Errorf("%w: %w: %w", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
+		}
 		return nil, fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
 	}
-	o, err := m.client.GetObject(ctx, m.bucketName, ID, minio.GetObjectOptions{})
+	o, err := m.client.GetObject(ctx, m.bucketName, m.key(ctx, ID), minio.GetObjectOptions{})
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
 	}
 	return o, nil
 }
 
+// PresignUpload returns a presigned URL the staging object of the document identified by ID can be uploaded to
+// with a PUT request until it expires, see PromoteUpload. Its host is the endpoint of the Minio client.
+func (m MinioBinaryRepository) PresignUpload(ctx context.Context, ID string, expiry time.Duration) (string, error) {
+	u, err := m.client.PresignedPutObject(ctx, m.bucketName, m.stagingKey(ctx, ID), expiry)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrPresignFailed, err)
+	}
+	return u.String(), nil
+}
+
+// BinaryVersion returns the ETag of the object of the document identified by ID.
+func (m MinioBinaryRepository) BinaryVersion(ctx context.Context, ID string) (_ string, err error) {
+	defer m.ops.Observe(ctx, "binary version", time.Now(), &err, "ID", ID)
+	info, err := m.client.StatObject(ctx, m.bucketName, m.key(ctx, ID), minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return "", fmt.Errorf("%w: %w: %w: ID = %q", ErrMinioBinaryRepository, port.ErrGetDataFailed, port.ErrBinaryNotFound, ID)
+		}
+		return "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
+	}
+	return info.ETag, nil
+}
+
+// PresignDownload returns a presigned URL the object of the document identified by ID can be downloaded from
+// with a GET request until it expires, as an attachment named fileName if not empty.
+func (m MinioBinaryRepository) PresignDownload(ctx context.Context, ID, fileName string, expiry time.Duration) (string, error) {
+	params := make(url.Values)
+	if fileName != "" {
+		params.Set("response-content-disposition", mime.FormatMediaType("attachment", map[string]string{"filename": fileName}))
+	}
+	u, err := m.client.PresignedGetObject(ctx, m.bucketName, m.key(ctx, ID), expiry, params)
+	if err != nil {
+		return "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrPresignFailed, err)
+	}
+	return u.String(), nil
+}
+
 // Ping checks Minio service availability with a 5-second timeout.
 func (m MinioBinaryRepository) Ping() error {
 	timeout := 5 * time.Second
@@ -102,10 +207,50 @@ func (m MinioBinaryRepository) Ping() error {
 	return nil
 }
 
-// exists checks if an object with the given ID exists in the repository.
+// Walk calls fn for every object of the bucket, whatever its tenant, but the uploads not promoted yet.
+func (m MinioBinaryRepository) Walk(ctx context.Context, fn func(port.BinaryInfo) error) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	for o := range m.client.ListObjects(ctx, m.bucketName, minio.ListObjectsOptions{Recursive: true}) {
+		if o.Err != nil {
+			return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrWalkDataFailed, o.Err)
+		}
+		if isStagingKey(o.Key) {
+			continue
+		}
+		tenant, ID := m.splitKey(o.Key)
+		if err := fn(port.BinaryInfo{Tenant: tenant, ID: ID, Size: o.Size, ModifiedAt: o.LastModified}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// exists checks if an object with the given ID exists in the repository, returning port.ErrBinaryNotFound if it does not.
 func (m MinioBinaryRepository) exists(ctx context.Context, ID string) error {
-	if _, err := m.client.StatObject(ctx, m.bucketName, ID, minio.StatObjectOptions{}); err != nil {
+	if _, err := m.client.StatObject(ctx, m.bucketName, m.key(ctx, ID), minio.StatObjectOptions{}); err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return fmt.Errorf("%w: ID = %q", port.ErrBinaryNotFound, ID)
+		}
 		return fmt.Errorf("error while searching for ID = %q: %w", ID, err)
 	}
 	return nil
 }
+
+// objectKey returns the key of the object holding the binary data of a document: the document's ID,
+// prefixed by the tenant carried by ctx if any.
+func objectKey(ctx context.Context, ID string) string {
+	if tenant := domain.TenantFromContext(ctx); tenant != domain.DefaultTenant {
+		return tenant + "/" + ID
+	}
+	return ID
+}
+
+// splitObjectKey returns the tenant and the document's ID of an object key made by objectKey.
+func splitObjectKey(key string) (tenant, ID string) {
+	if tenant, ID, found := strings.Cut(key, "/"); found {
+		return tenant, ID
+	}
+	return domain.DefaultTenant, key
+}
@@ -0,0 +1,40 @@
+package binaryrepo
+
+import (
+	"fmt"
+
+	"github.com/minio/minio-go/v7/pkg/encrypt"
+)
+
+// Server-side encryption modes of the objects saved by a MinioBinaryRepository.
+const (
+	SSENone = ""        // the objects are stored as the bucket's default encryption, if any, says
+	SSES3   = "SSE-S3"  // the objects are encrypted with keys managed by the object store
+	SSEKMS  = "SSE-KMS" // the objects are encrypted with a key of the key management service
+)
+
+// WithServerSideEncryption makes the object store encrypt the saved objects with the given mode, SSES3 or SSEKMS.
+// The key ID names the key of the key management service used in SSEKMS mode, and is ignored otherwise.
+func WithServerSideEncryption(mode, keyID string) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.sseMode = mode
+		m.sseKeyID = keyID
+	}
+}
+
+// newServerSide returns the server-side encryption of the given mode, nil for SSENone.
+func newServerSide(mode, keyID string) (encrypt.ServerSide, error) {
+	switch mode {
+	case SSENone:
+		return nil, nil
+	case SSES3:
+		return encrypt.NewSSE(), nil
+	case SSEKMS:
+		if keyID == "" {
+			return nil, fmt.Errorf("a key ID is required in %s mode", SSEKMS)
+		}
+		return encrypt.NewSSEKMS(keyID, nil)
+	default:
+		return nil, fmt.Errorf("unknown server-side encryption mode %q", mode)
+	}
+}
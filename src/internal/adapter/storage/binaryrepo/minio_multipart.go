@@ -0,0 +1,44 @@
+package binaryrepo
+
+import (
+	"github.com/minio/minio-go/v7"
+)
+
+const (
+	// MinPartSize and MaxPartSize bound the size of the parts of a multipart upload, as S3 does.
+	MinPartSize = 5 << 20
+	MaxPartSize = 5 << 30
+
+	// maxPartsCount is the maximum number of parts of a multipart upload.
+	maxPartsCount = 10000
+)
+
+// WithMultipartUpload saves the objects of threshold bytes or more with a multipart upload of parts of partSize
+// bytes, uploading parallelism parts at once, and the smaller objects with a single PUT, so that a large file does not
+// funnel through a single slow request. Each upload of more than one part at once holds parallelism parts in memory.
+// partSize is kept between MinPartSize and MaxPartSize, and raised for the objects too large to fit in the maximum
+// number of parts, and threshold is capped at MaxPartSize, the size of the largest single PUT. The objects are saved
+// the way the Minio client chooses when threshold is zero.
+func WithMultipartUpload(threshold, partSize uint64, parallelism uint) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.multipartThreshold = min(threshold, MaxPartSize)
+		m.partSize = min(max(partSize, MinPartSize), MaxPartSize)
+		m.partParallelism = max(parallelism, 1)
+	}
+}
+
+// putOptions returns the options of the upload of an object of size bytes.
+func (m MinioBinaryRepository) putOptions(size int64) minio.PutObjectOptions {
+	opts := minio.PutObjectOptions{ServerSideEncryption: m.sse}
+	if m.multipartThreshold == 0 {
+		return opts
+	}
+	if size < int64(m.multipartThreshold) {
+		opts.DisableMultipart = true
+		return opts
+	}
+	opts.PartSize = max(m.partSize, (uint64(size)+maxPartsCount-1)/maxPartsCount)
+	opts.NumThreads = m.partParallelism
+	opts.ConcurrentStreamParts = m.partParallelism > 1
+	return opts
+}
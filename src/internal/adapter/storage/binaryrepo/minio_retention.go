@@ -0,0 +1,97 @@
+package binaryrepo
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"goyav/internal/core/port"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/lifecycle"
+)
+
+// lifecycleRuleID is the ID of the lifecycle rule of the bucket set by WithLifecycleExpiry.
+const lifecycleRuleID = "goyav-expiry"
+
+// MinioOption configures optional behaviours of a MinioBinaryRepository.
+type MinioOption func(*MinioBinaryRepository)
+
+// WithLifecycleExpiry makes the bucket expire the objects older than ttl, rounded up to whole days, as a safety net
+// for the objects the service fails to delete. The noncurrent versions of a versioned bucket expire after a day.
+// It has no effect when ttl is not strictly positive.
+func WithLifecycleExpiry(ttl time.Duration) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.expiry = ttl
+	}
+}
+
+// WithQuarantine protects the quarantined objects from deletion, with a retention in governance mode for the given
+// duration, unless it is zero, and with a legal hold if legalHold is set. Either one requires object locking, which
+// is enabled on the bucket if it is created by NewMinio.
+func WithQuarantine(retention time.Duration, legalHold bool) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.quarantineRetention = retention
+		m.quarantineLegalHold = legalHold
+	}
+}
+
+// objectLocking reports whether the bucket must have object locking enabled.
+func (m *MinioBinaryRepository) objectLocking() bool {
+	return m.quarantineRetention > 0 || m.quarantineLegalHold
+}
+
+// lifecycleDays returns the number of whole days covering ttl, at least one.
+func lifecycleDays(ttl time.Duration) int {
+	const day = 24 * time.Hour
+	return max(1, int((ttl+day-1)/day))
+}
+
+// setLifecycle sets the lifecycle rule expiring the objects of the bucket, see WithLifecycleExpiry.
+func (m *MinioBinaryRepository) setLifecycle(ctx context.Context) error {
+	days := lifecycleDays(m.expiry)
+	cfg := lifecycle.NewConfiguration()
+	cfg.Rules = []lifecycle.Rule{{
+		ID:                          lifecycleRuleID,
+		Status:                      "Enabled",
+		Expiration:                  lifecycle.Expiration{Days: lifecycle.ExpirationDays(days)},
+		NoncurrentVersionExpiration: lifecycle.NoncurrentVersionExpiration{NoncurrentDays: 1},
+	}}
+	if err := m.client.SetBucketLifecycle(ctx, m.bucketName, cfg); err != nil {
+		return fmt.Errorf("failed to set the lifecycle of the bucket: %v", err)
+	}
+	slog.Info("bucket lifecycle set", "bucket", m.bucketName, "expiry (days)", days)
+	return nil
+}
+
+// checkObjectLocking checks that object locking is enabled on the bucket, it cannot be enabled once the bucket exists.
+func (m *MinioBinaryRepository) checkObjectLocking(ctx context.Context) error {
+	enabled, _, _, _, err := m.client.GetObjectLockConfig(ctx, m.bucketName)
+	if err != nil || enabled != "Enabled" {
+		return fmt.Errorf("object locking must be enabled on the bucket %q to quarantine objects: %v", m.bucketName, err)
+	}
+	return nil
+}
+
+// Quarantine protects the object of the document identified by ID from deletion, with a retention or a legal hold
+// as configured by WithQuarantine. It does nothing when neither is configured.
+func (m MinioBinaryRepository) Quarantine(ctx context.Context, ID string) (err error) {
+	defer m.ops.Observe(ctx, "quarantine", time.Now(), &err, "ID", ID)
+	key := m.key(ctx, ID)
+	if m.quarantineRetention > 0 {
+		mode := minio.Governance
+		until := time.Now().Add(m.quarantineRetention)
+		err := m.client.PutObjectRetention(ctx, m.bucketName, key, minio.PutObjectRetentionOptions{Mode: &mode, RetainUntilDate: &until})
+		if err != nil {
+			return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrQuarantineFailed, err)
+		}
+	}
+	if m.quarantineLegalHold {
+		status := minio.LegalHoldEnabled
+		if err := m.client.PutObjectLegalHold(ctx, m.bucketName, key, minio.PutObjectLegalHoldOptions{Status: &status}); err != nil {
+			return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrQuarantineFailed, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package binaryrepo
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+
+	"goyav/internal/core/domain"
+)
+
+// MaxShardLevels is the maximum number of levels of key prefixes of WithKeySharding, 256^3 prefixes.
+const MaxShardLevels = 3
+
+// WithKeySharding spreads the objects across key prefixes derived from the SHA-256 hash of the document's ID, one
+// level of two hexadecimal digits, 256 prefixes, per level: with one level, the object of the document ID of a
+// tenant is stored as "tenant/3f/ID", so that the listings and deletions of a prefix stay small whatever the number
+// of objects. The hash of the ID, rather than the ID, spreads the time-ordered IDs evenly. The objects stored with
+// another number of levels are not found, they must be moved first. levels is capped at MaxShardLevels and the
+// objects are not sharded when it is zero.
+func WithKeySharding(levels int) MinioOption {
+	return func(m *MinioBinaryRepository) {
+		m.shardLevels = min(max(levels, 0), MaxShardLevels)
+	}
+}
+
+// key returns the key of the object holding the binary data of a document: the key made by objectKey, with the
+// shard prefix of the document's ID inserted before it.
+func (m MinioBinaryRepository) key(ctx context.Context, ID string) string {
+	if m.shardLevels == 0 {
+		return objectKey(ctx, ID)
+	}
+	sum := sha256.Sum256([]byte(ID))
+	digits := hex.EncodeToString(sum[:m.shardLevels])
+	var b strings.Builder
+	if tenant := domain.TenantFromContext(ctx); tenant != domain.DefaultTenant {
+		b.WriteString(tenant + "/")
+	}
+	for i := 0; i < len(digits); i += 2 {
+		b.WriteString(digits[i:i+2] + "/")
+	}
+	b.WriteString(ID)
+	return b.String()
+}
+
+// splitKey returns the tenant and the document's ID of an object key made by key.
+func (m MinioBinaryRepository) splitKey(key string) (tenant, ID string) {
+	parts := strings.Split(key, "/")
+	ID = parts[len(parts)-1]
+	if len(parts) > m.shardLevels+1 {
+		return parts[0], ID
+	}
+	return domain.DefaultTenant, ID
+}
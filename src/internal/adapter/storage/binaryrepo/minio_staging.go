@@ -0,0 +1,81 @@
+package binaryrepo
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"strings"
+	"time"
+
+	"goyav/internal/core/port"
+
+	"github.com/minio/minio-go/v7"
+)
+
+// stagingPrefix prefixes the keys of the objects uploaded to a presigned URL until they are promoted, apart from
+// the objects of the documents. It cannot be mistaken for a tenant, whose names have no dot.
+const stagingPrefix = ".uploads/"
+
+// stagingKey returns the key of the object uploaded to the presigned URL of the document identified by ID.
+func (m MinioBinaryRepository) stagingKey(ctx context.Context, ID string) string {
+	return stagingPrefix + m.key(ctx, ID)
+}
+
+// isStagingKey reports whether key is the key of an object uploaded to a presigned URL and not promoted yet.
+func isStagingKey(key string) bool {
+	return strings.HasPrefix(key, stagingPrefix)
+}
+
+// GetUpload returns the object uploaded to the presigned URL of the document identified by ID, along with its ETag.
+// The object is read in this version only, a later upload failing the read.
+func (m MinioBinaryRepository) GetUpload(ctx context.Context, ID string) (_ io.ReadCloser, _ string, err error) {
+	defer m.ops.Observe(ctx, "get upload", time.Now(), &err, "ID", ID)
+	key := m.stagingKey(ctx, ID)
+	info, err := m.client.StatObject(ctx, m.bucketName, key, minio.StatObjectOptions{})
+	if err != nil {
+		if minio.ToErrorResponse(err).Code == "NoSuchKey" {
+			return nil, "", fmt.Errorf("%w: %w: %w: ID = %q", ErrMinioBinaryRepository, port.ErrGetDataFailed, port.ErrBinaryNotFound, ID)
+		}
+		return nil, "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
+	}
+	var opts minio.GetObjectOptions
+	if err = opts.SetMatchETag(info.ETag); err != nil {
+		return nil, "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
+	}
+	o, err := m.client.GetObject(ctx, m.bucketName, key, opts)
+	if err != nil {
+		return nil, "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrGetDataFailed, err)
+	}
+	return o, info.ETag, nil
+}
+
+// PromoteUpload copies the object uploaded to the presigned URL of the document identified by ID, provided that its
+// ETag is still version, to the object of the document, then removes it. It returns the ETag of the copy.
+func (m MinioBinaryRepository) PromoteUpload(ctx context.Context, ID, version string) (_ string, err error) {
+	defer m.ops.Observe(ctx, "promote upload", time.Now(), &err, "ID", ID)
+	src := minio.CopySrcOptions{Bucket: m.bucketName, Object: m.stagingKey(ctx, ID), MatchETag: version}
+	dst := minio.CopyDestOptions{Bucket: m.bucketName, Object: m.key(ctx, ID), Encryption: m.sse}
+	info, err := m.client.CopyObject(ctx, dst, src)
+	if err != nil {
+		switch minio.ToErrorResponse(err).Code {
+		case "PreconditionFailed", "NoSuchKey":
+			return "", fmt.Errorf("%w: %w: %w: ID = %q", ErrMinioBinaryRepository, port.ErrPromoteUploadFailed, port.ErrUploadChanged, ID)
+		}
+		return "", fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrPromoteUploadFailed, err)
+	}
+	// The copy is done, a leftover upload is only removed by the lifecycle of the bucket, if any.
+	if err := m.client.RemoveObject(ctx, m.bucketName, src.Object, minio.RemoveObjectOptions{}); err != nil {
+		slog.WarnContext(ctx, "failed to remove a promoted upload", "error", err, "ID", ID)
+	}
+	return info.ETag, nil
+}
+
+// DiscardUpload removes the object uploaded to the presigned URL of the document identified by ID, if any.
+func (m MinioBinaryRepository) DiscardUpload(ctx context.Context, ID string) (err error) {
+	defer m.ops.Observe(ctx, "discard upload", time.Now(), &err, "ID", ID)
+	if err = m.client.RemoveObject(ctx, m.bucketName, m.stagingKey(ctx, ID), minio.RemoveObjectOptions{}); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrPromoteUploadFailed, err)
+	}
+	return nil
+}
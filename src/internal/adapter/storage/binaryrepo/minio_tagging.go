@@ -0,0 +1,20 @@
+package binaryrepo
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"goyav/internal/adapter/storage/objecttag"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+)
+
+// TagVerdict records the verdict of doc in the scan tags of its object, see objecttag.Verdict.
+func (m MinioBinaryRepository) TagVerdict(ctx context.Context, doc *domain.Document) (err error) {
+	defer m.ops.Observe(ctx, "tag", time.Now(), &err, "ID", doc.ID)
+	if err := objecttag.Put(ctx, m.client, m.bucketName, m.key(ctx, doc.ID), "", objecttag.Verdict(doc)); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrMinioBinaryRepository, port.ErrTagVerdictFailed, err)
+	}
+	return nil
+}
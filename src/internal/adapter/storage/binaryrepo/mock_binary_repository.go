@@ -5,9 +5,14 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"goyav/internal/adapter/storage/objecttag"
+	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
 	"goyav/pkg/helper"
 	"io"
+	"strconv"
+	"sync"
+	"time"
 )
 
 // MockBinaryRepository is a mock implementation of the ByteRepository interface.
@@ -15,13 +20,35 @@ import (
 type MockBinaryRepository struct {
 	// simulatedStorage simulates a storage system using a map.
 	simulatedStorage map[string][]byte
-	isOnline         bool
+	// modifiedAt holds the time each entry of simulatedStorage was saved.
+	modifiedAt map[string]time.Time
+	// versions holds the version of each entry of simulatedStorage, see BinaryVersion.
+	versions map[string]string
+	// writeCount numbers the writes to simulatedStorage, the version of their data.
+	writeCount int
+	// quarantined holds the entries of simulatedStorage which cannot be deleted.
+	quarantined map[string]bool
+	// tags holds the tags of the entries of simulatedStorage, see TagVerdict.
+	tags map[string]map[string]string
+	// uploads holds the data uploaded to the presigned URLs and not promoted yet, see Upload.
+	uploads map[string]mockUpload
+	// uploadCount numbers the uploads, the version of their data.
+	uploadCount int
+	// walkCount counts the calls to Walk.
+	walkCount  int
+	storageMux sync.Mutex
+	isOnline   bool
 }
 
 // NewMock creates a new instance of MockByteRepository.
 func NewMock() *MockBinaryRepository {
 	return &MockBinaryRepository{
 		simulatedStorage: make(map[string][]byte),
+		modifiedAt:       make(map[string]time.Time),
+		versions:         make(map[string]string),
+		quarantined:      make(map[string]bool),
+		tags:             make(map[string]map[string]string),
+		uploads:          make(map[string]mockUpload),
 		isOnline:         true,
 	}
 }
@@ -45,22 +72,42 @@ func (m *MockBinaryRepository) Save(ctx context.Context, data io.Reader, size in
 		return fmt.Errorf("%w: %w: reading data failed: %v", ErrMockBinaryRepository, port.ErrSaveDataFailed, err)
 	}
 	// Simulate successful save operation.
-	m.simulatedStorage[documentID] = b
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	m.store(objectKey(ctx, documentID), b)
 	return nil
 }
 
+// store writes data to the simulated storage entry key, in a new version. The caller holds storageMux.
+func (m *MockBinaryRepository) store(key string, data []byte) string {
+	m.writeCount++
+	m.simulatedStorage[key] = data
+	m.modifiedAt[key] = time.Now()
+	m.versions[key] = strconv.Itoa(m.writeCount)
+	return m.versions[key]
+}
+
 // Delete simulates the deletion of document's byte data.
 // It returns ErrDeleteFailed error with additional context if the operation fails.
 func (m *MockBinaryRepository) Delete(ctx context.Context, documentID string) error {
 	if err := m.checkContextAndAvailability(ctx); err != nil {
 		return err
 	}
-	if _, exists := m.simulatedStorage[documentID]; !exists {
-		return fmt.Errorf("%w: %w : id not found : id=%q", ErrMockBinaryRepository, port.ErrDeleteDataFailed, documentID)
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	key := objectKey(ctx, documentID)
+	if _, exists := m.simulatedStorage[key]; !exists {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockBinaryRepository, port.ErrDeleteDataFailed, port.ErrBinaryNotFound, documentID)
+	}
+	if m.quarantined[key] {
+		return fmt.Errorf("%w: %w: quarantined: id=%q", ErrMockBinaryRepository, port.ErrDeleteDataFailed, documentID)
 	}
 
 	// Simulate successful delete operation.
-	delete(m.simulatedStorage, documentID)
+	delete(m.simulatedStorage, key)
+	delete(m.modifiedAt, key)
+	delete(m.versions, key)
+	delete(m.tags, key)
 	return nil
 }
 
@@ -68,9 +115,11 @@ func (m *MockBinaryRepository) Get(ctx context.Context, ID string) (io.ReadClose
 	if err := m.checkContextAndAvailability(ctx); err != nil {
 		return nil, err
 	}
-	b, exists := m.simulatedStorage[ID]
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	b, exists := m.simulatedStorage[objectKey(ctx, ID)]
 	if !exists {
-		return nil, fmt.Errorf("%w: %w : id not found", ErrMockBinaryRepository, port.ErrGetDataFailed)
+		return nil, fmt.Errorf("%w: %w: %w", ErrMockBinaryRepository, port.ErrGetDataFailed, port.ErrBinaryNotFound)
 	}
 	return io.NopCloser(bytes.NewBuffer(b)), nil
 }
@@ -86,6 +135,168 @@ func (m *MockBinaryRepository) Ping() error {
 	return nil
 }
 
+// Walk calls fn for every entry of the simulated storage, whatever its tenant.
+func (m *MockBinaryRepository) Walk(ctx context.Context, fn func(port.BinaryInfo) error) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	// fn is called on a snapshot, so that it can use the repository.
+	m.storageMux.Lock()
+	m.walkCount++
+	infos := make([]port.BinaryInfo, 0, len(m.simulatedStorage))
+	for key, b := range m.simulatedStorage {
+		tenant, ID := splitObjectKey(key)
+		infos = append(infos, port.BinaryInfo{Tenant: tenant, ID: ID, Size: int64(len(b)), ModifiedAt: m.modifiedAt[key]})
+	}
+	m.storageMux.Unlock()
+
+	for _, info := range infos {
+		if err := fn(info); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Walks returns the number of times the simulated storage was walked, see Walk.
+func (m *MockBinaryRepository) Walks() int {
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	return m.walkCount
+}
+
+// mockUpload is data uploaded to a presigned URL, in its version.
+type mockUpload struct {
+	data    []byte
+	version string
+}
+
+// PresignUpload returns a fake URL naming the simulated staging entry of the document, the data
+// is uploaded with Upload.
+func (m *MockBinaryRepository) PresignUpload(ctx context.Context, ID string, expiry time.Duration) (string, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return "", err
+	}
+	if !helper.IsValidID(ID) {
+		return "", fmt.Errorf("%w: %w: invalide id: %q", ErrMockBinaryRepository, port.ErrPresignFailed, ID)
+	}
+	return fmt.Sprintf("mock://storage/.uploads/%s?expires=%d", objectKey(ctx, ID), time.Now().Add(expiry).Unix()), nil
+}
+
+// Upload simulates the upload of data to the presigned URL of the document identified by ID, replacing the data
+// uploaded before, if any.
+func (m *MockBinaryRepository) Upload(ctx context.Context, ID string, data []byte) {
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	m.uploadCount++
+	m.uploads[objectKey(ctx, ID)] = mockUpload{data: bytes.Clone(data), version: strconv.Itoa(m.uploadCount)}
+}
+
+// GetUpload returns the data uploaded to the presigned URL of the document identified by ID, along with its version.
+func (m *MockBinaryRepository) GetUpload(ctx context.Context, ID string) (io.ReadCloser, string, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, "", err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	u, exists := m.uploads[objectKey(ctx, ID)]
+	if !exists {
+		return nil, "", fmt.Errorf("%w: %w: %w", ErrMockBinaryRepository, port.ErrGetDataFailed, port.ErrBinaryNotFound)
+	}
+	return io.NopCloser(bytes.NewReader(u.data)), u.version, nil
+}
+
+// PromoteUpload moves the data uploaded to the presigned URL of the document identified by ID, in the given version,
+// to its simulated storage entry, and returns the version of the entry.
+func (m *MockBinaryRepository) PromoteUpload(ctx context.Context, ID, version string) (string, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return "", err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	key := objectKey(ctx, ID)
+	u, exists := m.uploads[key]
+	if !exists || u.version != version {
+		return "", fmt.Errorf("%w: %w: %w: id=%q", ErrMockBinaryRepository, port.ErrPromoteUploadFailed, port.ErrUploadChanged, ID)
+	}
+	delete(m.uploads, key)
+	return m.store(key, u.data), nil
+}
+
+// DiscardUpload removes the data uploaded to the presigned URL of the document identified by ID, if any.
+func (m *MockBinaryRepository) DiscardUpload(ctx context.Context, ID string) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	delete(m.uploads, objectKey(ctx, ID))
+	return nil
+}
+
+// BinaryVersion returns the version of the simulated storage entry of the document, which changes whenever the entry
+// is written.
+func (m *MockBinaryRepository) BinaryVersion(ctx context.Context, ID string) (string, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return "", err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	version, exists := m.versions[objectKey(ctx, ID)]
+	if !exists {
+		return "", fmt.Errorf("%w: %w: %w: id=%q", ErrMockBinaryRepository, port.ErrGetDataFailed, port.ErrBinaryNotFound, ID)
+	}
+	return version, nil
+}
+
+// PresignDownload returns a fake URL naming the simulated storage entry of the document.
+func (m *MockBinaryRepository) PresignDownload(ctx context.Context, ID, fileName string, expiry time.Duration) (string, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return "", err
+	}
+	if !helper.IsValidID(ID) {
+		return "", fmt.Errorf("%w: %w: invalide id: %q", ErrMockBinaryRepository, port.ErrPresignFailed, ID)
+	}
+	return fmt.Sprintf("mock://storage/%s?expires=%d", objectKey(ctx, ID), time.Now().Add(expiry).Unix()), nil
+}
+
+// Quarantine simulates a legal hold on the document's byte data, which cannot be deleted anymore.
+func (m *MockBinaryRepository) Quarantine(ctx context.Context, documentID string) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	key := objectKey(ctx, documentID)
+	if _, exists := m.simulatedStorage[key]; !exists {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockBinaryRepository, port.ErrQuarantineFailed, port.ErrBinaryNotFound, documentID)
+	}
+	m.quarantined[key] = true
+	return nil
+}
+
+// TagVerdict simulates the tagging of document's byte data with its verdict, see objecttag.Verdict.
+func (m *MockBinaryRepository) TagVerdict(ctx context.Context, doc *domain.Document) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	key := objectKey(ctx, doc.ID)
+	if _, exists := m.simulatedStorage[key]; !exists {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockBinaryRepository, port.ErrTagVerdictFailed, port.ErrBinaryNotFound, doc.ID)
+	}
+	m.tags[key] = objecttag.Verdict(doc)
+	return nil
+}
+
+// Tags returns the tags of document's byte data, nil if it has none.
+func (m *MockBinaryRepository) Tags(ctx context.Context, documentID string) map[string]string {
+	m.storageMux.Lock()
+	defer m.storageMux.Unlock()
+	return m.tags[objectKey(ctx, documentID)]
+}
+
 // Online switches on or off the status of a mock binary repository instance.
 func (m *MockBinaryRepository) IsOnline(b bool) {
 	m.isOnline = b
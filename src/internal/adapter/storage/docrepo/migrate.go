@@ -0,0 +1,167 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"log/slog"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+const (
+	createSchemaVersionQuery = `CREATE TABLE IF NOT EXISTS schema_version (
+    version INTEGER PRIMARY KEY,
+    name VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT now()
+)`
+	schemaVersionQuery = "SELECT COALESCE(MAX(version), 0) FROM schema_version"
+
+	// schemaLock is the name of the advisory lock serializing the changes of the schema, see lockSchema.
+	schemaLock = "schema"
+)
+
+var (
+	// ErrMigrationFailed is returned when the migrations of the database fail, none of them is applied then.
+	ErrMigrationFailed = errors.New("database migration failed")
+
+	// ErrSchemaOutdated is returned when the database lacks migrations, which are applied by "goyav migrate".
+	ErrSchemaOutdated = errors.New("database schema is outdated")
+
+	// ErrSchemaTooRecent is returned when the database has migrations unknown to this release.
+	ErrSchemaTooRecent = errors.New("database schema is more recent than the application")
+)
+
+// Migration is a versioned change of the schema of the database, read from the migrations directory
+// where it is named after its version and its name, as in 0001_documents.sql.
+type Migration struct {
+	Version int
+	Name    string
+	Query   string
+}
+
+// Migrations returns the migrations of the database, ordered by version.
+func Migrations() ([]Migration, error) {
+	entries, err := migrationFiles.ReadDir("migrations")
+	if err != nil {
+		return nil, err
+	}
+	migrations := make([]Migration, 0, len(entries))
+	for _, e := range entries {
+		version, name, ok := strings.Cut(strings.TrimSuffix(e.Name(), ".sql"), "_")
+		v, err := strconv.Atoi(version)
+		if !ok || err != nil || v <= 0 {
+			return nil, fmt.Errorf("invalid migration file name %q", e.Name())
+		}
+		query, err := migrationFiles.ReadFile(path.Join("migrations", e.Name()))
+		if err != nil {
+			return nil, err
+		}
+		migrations = append(migrations, Migration{Version: v, Name: name, Query: string(query)})
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	for i := 1; i < len(migrations); i++ {
+		if migrations[i].Version == migrations[i-1].Version {
+			return nil, fmt.Errorf("duplicate migration version %d", migrations[i].Version)
+		}
+	}
+	return migrations, nil
+}
+
+// LatestSchemaVersion returns the version of the schema expected by this release, the version of its last migration.
+func LatestSchemaVersion() (int, error) {
+	migrations, err := Migrations()
+	if err != nil || len(migrations) == 0 {
+		return 0, err
+	}
+	return migrations[len(migrations)-1].Version, nil
+}
+
+// SchemaVersion returns the version of the schema of the database, the version of its last applied migration.
+// It fails if no migration was ever applied.
+func SchemaVersion(ctx context.Context, db *sql.DB) (int, error) {
+	var version int
+	if err := db.QueryRowContext(ctx, schemaVersionQuery).Scan(&version); err != nil {
+		return 0, err
+	}
+	return version, nil
+}
+
+// Migrate applies the migrations of the database more recent than its schema version, in a single transaction,
+// and returns the versions of the schema before and after. The replicas migrating the database at once apply the
+// migrations one after the other, the first one applying them all, see lockSchema.
+func Migrate(ctx context.Context, db *sql.DB) (from, to int, err error) {
+	migrations, err := Migrations()
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, 0, fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+	defer tx.Rollback()
+	if err = lockSchema(ctx, tx); err != nil {
+		return 0, 0, fmt.Errorf("%w: failed to lock the schema: %v", ErrMigrationFailed, err)
+	}
+	if _, err = tx.ExecContext(ctx, createSchemaVersionQuery); err != nil {
+		return 0, 0, fmt.Errorf("%w: failed to create the schema_version table: %v", ErrMigrationFailed, err)
+	}
+	if err = tx.QueryRowContext(ctx, schemaVersionQuery).Scan(&from); err != nil {
+		return 0, 0, fmt.Errorf("%w: failed to read the schema version: %v", ErrMigrationFailed, err)
+	}
+
+	to = from
+	for _, m := range migrations {
+		if m.Version <= from {
+			continue
+		}
+		if _, err = tx.ExecContext(ctx, m.Query); err != nil {
+			return from, from, fmt.Errorf("%w: migration %d %s: %v", ErrMigrationFailed, m.Version, m.Name, err)
+		}
+		if _, err = tx.ExecContext(ctx, "INSERT INTO schema_version (version, name) VALUES ($1, $2)", m.Version, m.Name); err != nil {
+			return from, from, fmt.Errorf("%w: migration %d %s: %v", ErrMigrationFailed, m.Version, m.Name, err)
+		}
+		to = m.Version
+	}
+	if err = tx.Commit(); err != nil {
+		return from, from, fmt.Errorf("%w: %v", ErrMigrationFailed, err)
+	}
+	if to != from {
+		slog.Info("database migrated", "from version", from, "to version", to)
+	}
+	return from, to, nil
+}
+
+// lockSchema takes the advisory lock serializing the changes of the schema of the database until the end of tx, so
+// that the replicas starting at once change it one after the other, each one seeing the changes of the previous
+// ones, rather than failing on the objects they created meanwhile.
+func lockSchema(ctx context.Context, tx *sql.Tx) error {
+	_, err := tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", advisoryLockClass, schemaLock)
+	return err
+}
+
+// CheckSchemaVersion checks that the schema of the database is the one expected by this release.
+func CheckSchemaVersion(ctx context.Context, db *sql.DB) error {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		return err
+	}
+	version, err := SchemaVersion(ctx, db)
+	switch {
+	case err != nil:
+		return fmt.Errorf("%w: failed to read the schema version, the database may never have been migrated: %v", ErrSchemaOutdated, err)
+	case version < latest:
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaOutdated, version, latest)
+	case version > latest:
+		return fmt.Errorf("%w: version %d, expected %d", ErrSchemaTooRecent, version, latest)
+	}
+	return nil
+}
@@ -0,0 +1,130 @@
+package docrepo
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMigrations(t *testing.T) {
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, m := range migrations {
+		assert.Equal(t, i+1, m.Version, "the versions must follow each other")
+		assert.NotEmpty(t, m.Name)
+		assert.NotEmpty(t, m.Query)
+	}
+	assert.Equal(t, "documents", migrations[0].Name)
+}
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	migrations, err := Migrations()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	latest := migrations[len(migrations)-1].Version
+
+	t.Run("FromScratch", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(advisoryLockClass, schemaLock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+		for _, m := range migrations {
+			mock.ExpectExec(".+").WillReturnResult(sqlmock.NewResult(0, 0))
+			mock.ExpectExec("INSERT INTO schema_version").WithArgs(m.Version, m.Name).WillReturnResult(sqlmock.NewResult(1, 1))
+		}
+		mock.ExpectCommit()
+
+		from, to, err := Migrate(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, 0, from)
+		assert.Equal(t, latest, to)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("UpToDate", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(advisoryLockClass, schemaLock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(latest))
+		mock.ExpectCommit()
+
+		from, to, err := Migrate(ctx, db)
+		assert.NoError(t, err)
+		assert.Equal(t, latest, from)
+		assert.Equal(t, latest, to)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+
+	t.Run("Failure", func(t *testing.T) {
+		db, mock, err := sqlmock.New()
+		if err != nil {
+			t.Fatalf("error creating sqlmock: %v", err)
+		}
+		defer db.Close()
+
+		mock.ExpectBegin()
+		mock.ExpectExec("SELECT pg_advisory_xact_lock").WithArgs(advisoryLockClass, schemaLock).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("CREATE TABLE IF NOT EXISTS schema_version").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(0))
+		mock.ExpectExec(".+").WillReturnError(fmt.Errorf("syntax error"))
+		mock.ExpectRollback()
+
+		from, to, err := Migrate(ctx, db)
+		assert.ErrorIs(t, err, ErrMigrationFailed)
+		assert.Equal(t, 0, from)
+		assert.Equal(t, 0, to)
+		assert.NoError(t, mock.ExpectationsWereMet())
+	})
+}
+
+func TestCheckSchemaVersion(t *testing.T) {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		name    string
+		version int
+		err     error
+	}{
+		{name: "UpToDate", version: latest},
+		{name: "Outdated", version: latest - 1, err: ErrSchemaOutdated},
+		{name: "TooRecent", version: latest + 1, err: ErrSchemaTooRecent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, mock, err := sqlmock.New()
+			if err != nil {
+				t.Fatalf("error creating sqlmock: %v", err)
+			}
+			defer db.Close()
+
+			mock.ExpectQuery("SELECT COALESCE").WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(tt.version))
+			err = CheckSchemaVersion(context.Background(), db)
+			if tt.err == nil {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, tt.err)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+-- Schema of the documents table as created on every boot before the versioned migrations, idempotent so that it
+-- applies to the databases set up by the previous releases as well.
+CREATE TABLE IF NOT EXISTS documents (
+    id SERIAL PRIMARY KEY,
+    document_id VARCHAR(255) NOT NULL UNIQUE,
+    hash VARCHAR(255) NOT NULL,
+    tag VARCHAR(255) NOT NULL,
+    status INTEGER NOT NULL,
+    analyzed_at TIMESTAMP WITHOUT TIME ZONE,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
+
+-- Columns added after the first release, before the versioned migrations
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS tenant VARCHAR(64) NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS source VARCHAR(16) NOT NULL DEFAULT 'upload';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS origin TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS sealed TEXT NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS hash_algo VARCHAR(16) NOT NULL DEFAULT 'SHA-256';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS file_name VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS file_size BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS content_type VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE documents ADD COLUMN IF NOT EXISTS archive_report TEXT NOT NULL DEFAULT '';
+
+-- Indexes
+CREATE INDEX IF NOT EXISTS idx_document_id ON documents(document_id);
+CREATE INDEX IF NOT EXISTS idx_hash ON documents(hash);
+CREATE INDEX IF NOT EXISTS idx_status ON documents(status);
+CREATE INDEX IF NOT EXISTS idx_analyzed_at ON documents(analyzed_at);
+CREATE INDEX IF NOT EXISTS idx_tenant_hash ON documents(tenant, hash);
+
+-- Check Constraints, named after the number of statuses so that the constraint of a previous release is replaced
+DO $$
+BEGIN
+    IF NOT EXISTS (
+        SELECT 1 FROM pg_constraint 
+        WHERE conname = 'chk_status_5' AND conrelid = 'documents'::regclass
+    ) THEN
+        ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_status;
+        ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_status_4;
+        ALTER TABLE documents ADD CONSTRAINT chk_status_5 CHECK (status IN (0, 1, 2, 3, 4));
+    END IF;
+END
+$$;
@@ -0,0 +1,6 @@
+CREATE TABLE IF NOT EXISTS tenant_quotas (
+    tenant VARCHAR(64) PRIMARY KEY,
+    day DATE NOT NULL,
+    uploads BIGINT NOT NULL DEFAULT 0,
+    stored_bytes BIGINT NOT NULL DEFAULT 0
+);
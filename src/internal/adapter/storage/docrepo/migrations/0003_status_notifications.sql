@@ -0,0 +1,17 @@
+-- Notifies the changes of the status of the documents on the goyav_document_status channel, so that every replica
+-- can push them to its clients. The payload is a JSON object with the document_id, tenant, status and analyzed_at
+-- columns of the document.
+CREATE OR REPLACE FUNCTION notify_document_status() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('goyav_document_status', json_build_object(
+        'document_id', NEW.document_id,
+        'tenant', NEW.tenant,
+        'status', NEW.status,
+        'analyzed_at', NEW.analyzed_at
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER documents_status_notify AFTER UPDATE OF status ON documents
+    FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status) EXECUTE FUNCTION notify_document_status();
@@ -0,0 +1,5 @@
+-- Soft deletion of the documents: a deleted document keeps its row, with the date of its deletion, until the purge
+-- removes it, so that it can be restored meanwhile.
+ALTER TABLE documents ADD COLUMN deleted_at TIMESTAMP WITHOUT TIME ZONE;
+
+CREATE INDEX idx_documents_deleted_at ON documents(deleted_at) WHERE deleted_at IS NOT NULL;
@@ -0,0 +1,16 @@
+-- Name of the threat found in an infected document, as reported by the antivirus, empty unless known.
+ALTER TABLE documents ADD COLUMN threat_name VARCHAR(255) NOT NULL DEFAULT '';
+
+-- The notifications of the status changes carry the name of the threat along with the verdict.
+CREATE OR REPLACE FUNCTION notify_document_status() RETURNS trigger AS $$
+BEGIN
+    PERFORM pg_notify('goyav_document_status', json_build_object(
+        'document_id', NEW.document_id,
+        'tenant', NEW.tenant,
+        'status', NEW.status,
+        'analyzed_at', NEW.analyzed_at,
+        'threat_name', NEW.threat_name
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,6 @@
+-- Key/value labels set on the documents at upload, as a JSON object of strings.
+ALTER TABLE documents ADD COLUMN labels JSONB NOT NULL DEFAULT '{}';
+
+-- The documents are listed by containment of labels, the most recent first.
+CREATE INDEX idx_documents_labels ON documents USING GIN (labels jsonb_path_ops);
+CREATE INDEX idx_documents_tenant_created_at ON documents(tenant, created_at);
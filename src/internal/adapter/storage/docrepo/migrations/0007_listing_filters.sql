@@ -0,0 +1,5 @@
+-- The documents are listed by status, creation date, analysis date and tag prefix. LIKE 'prefix%' can only use
+-- an index of the tags with text_pattern_ops, whatever the collation of the database.
+CREATE INDEX idx_documents_tenant_status_created_at ON documents(tenant, status, created_at);
+CREATE INDEX idx_documents_tenant_analyzed_at ON documents(tenant, analyzed_at);
+CREATE INDEX idx_documents_tenant_tag ON documents(tenant, tag text_pattern_ops);
@@ -0,0 +1,3 @@
+-- The documents larger than the antivirus accepts to analyze get the too large status, 5.
+ALTER TABLE documents DROP CONSTRAINT IF EXISTS chk_status_5;
+ALTER TABLE documents ADD CONSTRAINT chk_status_6 CHECK (status IN (0, 1, 2, 3, 4, 5));
@@ -0,0 +1,2 @@
+-- Names of the engines selected to analyze the documents, comma-separated, empty unless known.
+ALTER TABLE documents ADD COLUMN engines TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,52 @@
+-- Append-only log of the changes of the documents, each event holding the state of its document right after the
+-- change. The events are written by a trigger of the documents table, in the transaction of the change itself, so
+-- that no change goes unrecorded whichever statement makes it. The rows removed by the purge are not recorded.
+CREATE TABLE document_events (
+    id BIGSERIAL PRIMARY KEY,
+    document_id VARCHAR(255) NOT NULL,
+    tenant VARCHAR(64) NOT NULL,
+    type VARCHAR(32) NOT NULL,
+    status INTEGER NOT NULL,
+    threat_name TEXT NOT NULL DEFAULT '',
+    hash VARCHAR(255) NOT NULL DEFAULT '',
+    occurred_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX idx_document_events_tenant_document_id ON document_events(tenant, document_id, id);
+CREATE INDEX idx_document_events_occurred_at ON document_events(occurred_at);
+
+CREATE OR REPLACE FUNCTION record_document_event() RETURNS trigger AS $$
+DECLARE
+    kind TEXT;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        kind := 'created';
+    ELSIF NEW IS NOT DISTINCT FROM OLD THEN
+        RETURN NULL;
+    ELSIF NEW.deleted_at IS DISTINCT FROM OLD.deleted_at THEN
+        kind := CASE WHEN NEW.deleted_at IS NULL THEN 'restored' ELSE 'deleted' END;
+    ELSIF NEW.status IS DISTINCT FROM OLD.status OR NEW.threat_name IS DISTINCT FROM OLD.threat_name THEN
+        kind := 'status_changed';
+    ELSIF NEW.hash IS DISTINCT FROM OLD.hash THEN
+        kind := 'content_uploaded';
+    ELSE
+        kind := 'updated';
+    END IF;
+    INSERT INTO document_events (document_id, tenant, type, status, threat_name, hash)
+        VALUES (NEW.document_id, NEW.tenant, kind, NEW.status, NEW.threat_name, NEW.hash);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER documents_record_event AFTER INSERT OR UPDATE ON documents
+    FOR EACH ROW EXECUTE FUNCTION record_document_event();
+
+-- The events are never updated, only purged along with the documents.
+CREATE OR REPLACE FUNCTION reject_document_event_update() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'document_events is append-only';
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER document_events_append_only BEFORE UPDATE ON document_events
+    FOR EACH ROW EXECUTE FUNCTION reject_document_event_update();
@@ -0,0 +1,28 @@
+-- Transactional outbox of the notifications of the callback URLs. A notification is recorded along with its upload,
+-- then made due by a trigger of the documents table in the transaction recording the final status of its document,
+-- so that a verdict recorded is never left unnotified, whichever replica stops. The due notifications are claimed by
+-- the replicas with FOR UPDATE SKIP LOCKED and removed once delivered.
+CREATE TABLE callback_outbox (
+    id BIGSERIAL PRIMARY KEY,
+    document_id VARCHAR(255) NOT NULL,
+    tenant VARCHAR(64) NOT NULL,
+    callback_url TEXT NOT NULL,
+    due BOOLEAN NOT NULL DEFAULT false,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    next_attempt_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'UTC'),
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL DEFAULT (now() AT TIME ZONE 'UTC')
+);
+
+CREATE INDEX idx_callback_outbox_tenant_document_id ON callback_outbox(tenant, document_id) WHERE NOT due;
+CREATE INDEX idx_callback_outbox_next_attempt_at ON callback_outbox(next_attempt_at) WHERE due;
+
+CREATE OR REPLACE FUNCTION release_document_callbacks() RETURNS trigger AS $$
+BEGIN
+    UPDATE callback_outbox SET due = true, next_attempt_at = now() AT TIME ZONE 'UTC'
+        WHERE tenant = NEW.tenant AND document_id = NEW.document_id AND NOT due;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE TRIGGER documents_release_callbacks AFTER UPDATE OF status ON documents
+    FOR EACH ROW WHEN (OLD.status = 0 AND NEW.status <> 0) EXECUTE FUNCTION release_document_callbacks();
@@ -0,0 +1,11 @@
+-- Subscriptions of URLs to the events of the service, managed through the admin API and shared by all the replicas.
+-- The secret signs the deliveries, it is kept as is since the signatures are computed from it.
+CREATE TABLE webhooks (
+    webhook_id VARCHAR(64) PRIMARY KEY,
+    url TEXT NOT NULL,
+    events TEXT[] NOT NULL,
+    secret TEXT NOT NULL DEFAULT '',
+    active BOOLEAN NOT NULL DEFAULT true,
+    created_at TIMESTAMP WITHOUT TIME ZONE NOT NULL,
+    updated_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
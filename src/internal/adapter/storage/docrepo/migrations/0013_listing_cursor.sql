@@ -0,0 +1,3 @@
+-- The listings are paginated with a cursor, the creation date and the ID of the last document of a page, in
+-- descending order. The index serves the next pages without sorting the documents created at the same date.
+CREATE INDEX idx_documents_tenant_created_at_id ON documents(tenant, created_at DESC, document_id DESC);
@@ -0,0 +1,14 @@
+-- The documents tables partitioned before their keys were declared lost their primary key and the uniqueness of
+-- their document IDs, which are restored including the partition key. The key of the partition of the documents
+-- created before the partitioning gives way to the one of the partitioned table.
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'documents'::regclass)
+        AND NOT EXISTS (SELECT 1 FROM pg_constraint WHERE conrelid = 'documents'::regclass AND contype = 'p') THEN
+        IF to_regclass('documents_unpartitioned') IS NOT NULL THEN
+            ALTER TABLE documents_unpartitioned DROP CONSTRAINT IF EXISTS documents_pkey;
+        END IF;
+        ALTER TABLE documents ADD PRIMARY KEY (id, created_at);
+        ALTER TABLE documents ADD CONSTRAINT documents_document_id_created_at_key UNIQUE (document_id, created_at);
+    END IF;
+END $$;
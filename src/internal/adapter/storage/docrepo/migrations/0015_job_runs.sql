@@ -0,0 +1,6 @@
+-- Start of the last run of the periodic jobs, such as the purge. The advisory lock of a job only keeps the replicas
+-- from running it at once: its last run, recorded under the lock, keeps them from running it one after the other.
+CREATE TABLE job_runs (
+    job VARCHAR(64) PRIMARY KEY,
+    last_run_at TIMESTAMP WITHOUT TIME ZONE NOT NULL
+);
@@ -0,0 +1,3 @@
+-- Version of the binary data of the documents, the ETag of its object, recorded when the data is saved or promoted so
+-- that a download can check the data unchanged without reading it. Empty unless known.
+ALTER TABLE documents ADD COLUMN binary_version VARCHAR(255) NOT NULL DEFAULT '';
@@ -1,6 +1,7 @@
 package docrepo
 
 import (
+	"cmp"
 	"context"
 	"errors"
 	"fmt"
@@ -8,6 +9,7 @@ import (
 	"goyav/internal/core/port"
 	"goyav/pkg/helper"
 	"maps"
+	"slices"
 	"sync"
 	"time"
 )
@@ -15,8 +17,17 @@ import (
 // MockDocumentRepository is a mock implementation of the DocumentRepository interface.
 // It uses an in-memory map to simulate document storage.
 type MockDocumentRepository struct {
-	documents   map[string]*domain.Document
-	documentMux sync.Mutex
+	documents    map[string]*domain.Document
+	events       []domain.DocumentEvent // events is the log of the changes of the documents, see record.
+	lastSeq      int64
+	callbacks    []*mockCallback // callbacks is the callback outbox, see AddCallback.
+	lastCallback int64
+	webhooks     []*domain.Webhook // webhooks are the webhook subscriptions, the oldest first.
+	documentMux  sync.Mutex
+
+	jobs    map[string]bool      // jobs holds the jobs running, see RunExclusive.
+	jobRuns map[string]time.Time // jobRuns holds the start of the last run of the jobs.
+	jobMux  sync.Mutex
 
 	isOnline  bool
 	onlineMux sync.Mutex
@@ -32,6 +43,30 @@ func NewMock() *MockDocumentRepository {
 	}
 }
 
+// RunExclusive runs fn unless the job named job is running already or was run less than every ago, and reports
+// whether fn was run.
+func (m *MockDocumentRepository) RunExclusive(ctx context.Context, job string, every time.Duration, fn func(ctx context.Context) error) (bool, error) {
+	m.jobMux.Lock()
+	if last, ok := m.jobRuns[job]; m.jobs[job] || (ok && time.Since(last) < every) {
+		m.jobMux.Unlock()
+		return false, nil
+	}
+	if m.jobs == nil {
+		m.jobs = make(map[string]bool)
+		m.jobRuns = make(map[string]time.Time)
+	}
+	m.jobs[job] = true
+	m.jobRuns[job] = time.Now()
+	m.jobMux.Unlock()
+
+	defer func() {
+		m.jobMux.Lock()
+		delete(m.jobs, job)
+		m.jobMux.Unlock()
+	}()
+	return true, fn(ctx)
+}
+
 // Get retrieves a document by its ID. Returns an error if the document does not exist or if a prob
 func (m *MockDocumentRepository) Get(ctx context.Context, id string) (*domain.Document, error) {
 	if err := m.checkContextAndAvailability(ctx); err != nil {
@@ -39,7 +74,7 @@ func (m *MockDocumentRepository) Get(ctx context.Context, id string) (*domain.Do
 	}
 	m.documentMux.Lock()
 	defer m.documentMux.Unlock()
-	if doc, exists := m.documents[id]; exists {
+	if doc, exists := m.documents[id]; exists && doc.Tenant == domain.TenantFromContext(ctx) {
 		return doc, nil
 	}
 	return nil, fmt.Errorf("%w: %w: id=%q", ErrMockDocumentRepository, port.ErrDocumentNotFound, id)
@@ -50,13 +85,13 @@ func (m *MockDocumentRepository) Save(ctx context.Context, d *domain.Document) e
 	if err := m.checkContextAndAvailability(ctx); err != nil {
 		return err
 	}
-	doc, _ := m.Get(ctx, d.ID)
-	if doc != nil {
-		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockDocumentRepository, port.ErrSaveDocumentFailed, port.ErrDocumentAlreadyExists, doc.ID)
-	}
 	m.documentMux.Lock()
 	defer m.documentMux.Unlock()
+	if _, exists := m.documents[d.ID]; exists {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockDocumentRepository, port.ErrSaveDocumentFailed, port.ErrDocumentAlreadyExists, d.ID)
+	}
 	m.documents[d.ID] = d
+	m.record(d, domain.EventCreated)
 	return nil
 }
 
@@ -70,8 +105,9 @@ func (m *MockDocumentRepository) GetByHash(ctx context.Context, h string) (*doma
 	}
 	m.documentMux.Lock()
 	defer m.documentMux.Unlock()
+	tenant := domain.TenantFromContext(ctx)
 	for _, doc := range m.documents {
-		if doc.Hash == h {
+		if doc.Hash == h && doc.Tenant == tenant && !doc.IsDeleted() {
 			return doc, nil
 		}
 	}
@@ -93,8 +129,40 @@ func (m *MockDocumentRepository) Delete(ctx context.Context, id string) error {
 	return nil
 }
 
-// UpdateStatus updates the analysis status and the analysis date of a document.
-func (m *MockDocumentRepository) UpdateStatus(ctx context.Context, id string, status domain.AnalysisStatus, analyzedAt time.Time) error {
+// SoftDelete marks a document as deleted at the given date.
+func (m *MockDocumentRepository) SoftDelete(ctx context.Context, id string, deletedAt time.Time) error {
+	doc, err := m.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrSoftDeleteDocumentFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	if doc.IsDeleted() {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockDocumentRepository, port.ErrSoftDeleteDocumentFailed, port.ErrDocumentNotFound, id)
+	}
+	doc.DeletedAt = deletedAt
+	m.record(doc, domain.EventDeleted)
+	return nil
+}
+
+// Restore clears the soft deletion of a document.
+func (m *MockDocumentRepository) Restore(ctx context.Context, id string) error {
+	doc, err := m.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrRestoreDocumentFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	if !doc.IsDeleted() {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrMockDocumentRepository, port.ErrRestoreDocumentFailed, port.ErrDocumentNotFound, id)
+	}
+	doc.DeletedAt = time.Time{}
+	m.record(doc, domain.EventRestored)
+	return nil
+}
+
+// UpdateStatus updates the analysis status, the threat name and the analysis date of a document.
+func (m *MockDocumentRepository) UpdateStatus(ctx context.Context, id string, status domain.AnalysisStatus, threat string, analyzedAt time.Time) error {
 	if err := m.checkContextAndAvailability(ctx); err != nil {
 		return err
 	}
@@ -104,8 +172,69 @@ func (m *MockDocumentRepository) UpdateStatus(ctx context.Context, id string, st
 	}
 	m.documentMux.Lock()
 	defer m.documentMux.Unlock()
+	changed := doc.Status != status || doc.Threat != threat
+	if doc.Status == domain.StatusPending && status != domain.StatusPending {
+		m.releaseCallbacks(doc)
+	}
 	doc.Status = status
+	doc.Threat = threat
 	doc.AnalyzedAt = analyzedAt
+	if changed {
+		m.record(doc, domain.EventStatusChanged)
+	}
+	return nil
+}
+
+// SaveArchiveReport records the archive report of a document.
+func (m *MockDocumentRepository) SaveArchiveReport(ctx context.Context, id string, report *domain.ArchiveReport) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	doc, err := m.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrSaveArchiveReportFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	doc.Archive = report
+	m.record(doc, domain.EventUpdated)
+	return nil
+}
+
+// UpdateContent records the hash, its algorithm, the size and the content type of a document which has no hash yet.
+func (m *MockDocumentRepository) UpdateContent(ctx context.Context, d *domain.Document) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	doc, err := m.Get(ctx, d.ID)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrUpdateContentFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	if doc.Hash != "" {
+		return fmt.Errorf("%w: %w: %w: id=%v", ErrMockDocumentRepository, port.ErrUpdateContentFailed, port.ErrDocumentContentRecorded, d.ID)
+	}
+	doc.Hash = d.Hash
+	doc.HashAlgo = d.HashAlgo
+	doc.Size = d.Size
+	doc.ContentType = d.ContentType
+	m.record(doc, domain.EventContentUploaded)
+	return nil
+}
+
+// UpdateBinaryVersion records the version of the binary data of a document.
+func (m *MockDocumentRepository) UpdateBinaryVersion(ctx context.Context, id, version string) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	doc, err := m.Get(ctx, id)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrUpdateBinaryVersionFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	doc.BinaryVersion = version
 	return nil
 }
 
@@ -119,19 +248,368 @@ func (m *MockDocumentRepository) Ping() error {
 }
 
 // Purge removes documents from the repository that have a known antiviral analysis result
-// and were created before the specified date.
-func (m *MockDocumentRepository) Purge(date time.Time) error {
+// and were created before the specified date, as well as the documents soft-deleted before that date, restricted to
+// the given statuses if any. It returns the number of documents removed.
+func (m *MockDocumentRepository) Purge(date time.Time, statuses ...domain.AnalysisStatus) (int64, error) {
 	if !m.isOnline {
-		return fmt.Errorf("%w: document repository is offline", ErrMockDocumentRepository)
+		return 0, fmt.Errorf("%w: document repository is offline", ErrMockDocumentRepository)
 	}
 	m.documentMux.Lock()
 	defer m.documentMux.Unlock()
+	n := len(m.documents)
 	maps.DeleteFunc(m.documents, func(k string, v *domain.Document) bool {
-		return v.CreatedAt.Before(date) && v.Status != domain.StatusPending
+		return (v.CreatedAt.Before(date) && v.Status != domain.StatusPending || v.IsDeleted() && v.DeletedAt.Before(date)) &&
+			(len(statuses) == 0 || slices.Contains(statuses, v.Status))
+	})
+	m.events = slices.DeleteFunc(m.events, func(e domain.DocumentEvent) bool {
+		_, exists := m.documents[e.DocumentID]
+		return !exists && e.OccurredAt.Before(date)
+	})
+	m.callbacks = slices.DeleteFunc(m.callbacks, func(c *mockCallback) bool {
+		_, exists := m.documents[c.DocumentID]
+		return !exists && c.createdAt.Before(date)
+	})
+	return int64(n - len(m.documents)), nil
+}
+
+// mockCallback is a notification of the callback outbox of MockDocumentRepository.
+type mockCallback struct {
+	domain.CallbackDelivery
+	due           bool
+	nextAttemptAt time.Time
+	createdAt     time.Time
+}
+
+// AddCallback records that callbackURL is to be notified of the document of the tenant carried by ctx identified by
+// ID, due at once if it has a final status, or else made due along with its final status.
+func (m *MockDocumentRepository) AddCallback(ctx context.Context, ID, callbackURL string) error {
+	doc, err := m.Get(ctx, ID)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %w", ErrMockDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	m.lastCallback++
+	now := time.Now()
+	m.callbacks = append(m.callbacks, &mockCallback{
+		CallbackDelivery: domain.CallbackDelivery{ID: m.lastCallback, DocumentID: ID, Tenant: doc.Tenant, CallbackURL: callbackURL},
+		due:              doc.Status != domain.StatusPending,
+		nextAttemptAt:    now,
+		createdAt:        now,
 	})
 	return nil
 }
 
+// ClaimCallbacks returns up to limit due notifications of all the tenants, the oldest first, and hides them from the
+// next claims for lease times two to the power of their previous attempts.
+func (m *MockDocumentRepository) ClaimCallbacks(ctx context.Context, limit int, lease time.Duration) ([]domain.CallbackDelivery, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	now := time.Now()
+	var deliveries []domain.CallbackDelivery
+	for _, c := range m.callbacks {
+		if len(deliveries) == limit {
+			break
+		}
+		if !c.due || c.nextAttemptAt.After(now) {
+			continue
+		}
+		c.nextAttemptAt = now.Add(lease << min(c.Attempts, 10))
+		c.Attempts++
+		deliveries = append(deliveries, c.CallbackDelivery)
+	}
+	return deliveries, nil
+}
+
+// RemoveCallback removes a notification once delivered, or given up.
+func (m *MockDocumentRepository) RemoveCallback(ctx context.Context, ID int64) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	m.callbacks = slices.DeleteFunc(m.callbacks, func(c *mockCallback) bool { return c.ID == ID })
+	return nil
+}
+
+// releaseCallbacks makes the notifications of doc due, as the trigger of the documents table does when it gets a
+// final status. m.documentMux must be held.
+func (m *MockDocumentRepository) releaseCallbacks(doc *domain.Document) {
+	for _, c := range m.callbacks {
+		if c.DocumentID == doc.ID && c.Tenant == doc.Tenant && !c.due {
+			c.due, c.nextAttemptAt = true, time.Now()
+		}
+	}
+}
+
+// Events retrieves the events of a document, the oldest first.
+func (m *MockDocumentRepository) Events(ctx context.Context, id string) ([]domain.DocumentEvent, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	tenant := domain.TenantFromContext(ctx)
+	var events []domain.DocumentEvent
+	for _, e := range m.events {
+		if e.DocumentID == id && e.Tenant == tenant {
+			events = append(events, e)
+		}
+	}
+	return events, nil
+}
+
+// SaveWebhook records the new webhook w.
+func (m *MockDocumentRepository) SaveWebhook(ctx context.Context, w *domain.Webhook) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	if slices.ContainsFunc(m.webhooks, func(o *domain.Webhook) bool { return o.ID == w.ID }) {
+		return fmt.Errorf("%w: %w: webhook %q exists already", ErrMockDocumentRepository, port.ErrWebhookRepositoryFailed, w.ID)
+	}
+	c := *w
+	m.webhooks = append(m.webhooks, &c)
+	return nil
+}
+
+// GetWebhook retrieves the webhook identified by ID.
+func (m *MockDocumentRepository) GetWebhook(ctx context.Context, ID string) (*domain.Webhook, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	i := m.webhookIndex(ID)
+	if i < 0 {
+		return nil, fmt.Errorf("%w: %w: ID %v", ErrMockDocumentRepository, port.ErrWebhookNotFound, ID)
+	}
+	c := *m.webhooks[i]
+	return &c, nil
+}
+
+// ListWebhooks retrieves all the webhooks, the oldest first.
+func (m *MockDocumentRepository) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	webhooks := make([]*domain.Webhook, len(m.webhooks))
+	for i, w := range m.webhooks {
+		c := *w
+		webhooks[i] = &c
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the webhook identified by the ID of w.
+func (m *MockDocumentRepository) UpdateWebhook(ctx context.Context, w *domain.Webhook) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	i := m.webhookIndex(w.ID)
+	if i < 0 {
+		return fmt.Errorf("%w: %w: ID %v", ErrMockDocumentRepository, port.ErrWebhookNotFound, w.ID)
+	}
+	c := *w
+	c.CreatedAt = m.webhooks[i].CreatedAt
+	m.webhooks[i] = &c
+	return nil
+}
+
+// DeleteWebhook removes the webhook identified by ID.
+func (m *MockDocumentRepository) DeleteWebhook(ctx context.Context, ID string) error {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	i := m.webhookIndex(ID)
+	if i < 0 {
+		return fmt.Errorf("%w: %w: ID %v", ErrMockDocumentRepository, port.ErrWebhookNotFound, ID)
+	}
+	m.webhooks = slices.Delete(m.webhooks, i, i+1)
+	return nil
+}
+
+// webhookIndex returns the index of the webhook identified by ID in m.webhooks, -1 if there is none. m.documentMux
+// must be held.
+func (m *MockDocumentRepository) webhookIndex(ID string) int {
+	return slices.IndexFunc(m.webhooks, func(w *domain.Webhook) bool { return w.ID == ID })
+}
+
+// record appends an event of the given type to the log of the changes of doc, as the trigger of the documents table
+// does. m.documentMux must be held.
+func (m *MockDocumentRepository) record(doc *domain.Document, t domain.DocumentEventType) {
+	m.lastSeq++
+	m.events = append(m.events, domain.DocumentEvent{
+		Seq:        m.lastSeq,
+		DocumentID: doc.ID,
+		Tenant:     doc.Tenant,
+		Type:       t,
+		Status:     doc.Status,
+		Threat:     doc.Threat,
+		Hash:       doc.Hash,
+		OccurredAt: time.Now(),
+	})
+}
+
+// List retrieves the documents of the tenant carried by ctx matching filter and following its cursor, the most recent
+// first, leaving out the soft-deleted ones.
+func (m *MockDocumentRepository) List(ctx context.Context, filter domain.DocumentFilter) ([]*domain.Document, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	tenant := domain.TenantFromContext(ctx)
+	var docs []*domain.Document
+	for _, doc := range m.documents {
+		if doc.Tenant == tenant && !doc.IsDeleted() && filter.Matches(doc) && filter.After.Precedes(doc) {
+			docs = append(docs, doc)
+		}
+	}
+	slices.SortFunc(docs, func(a, b *domain.Document) int {
+		if c := b.CreatedAt.Compare(a.CreatedAt); c != 0 {
+			return c
+		}
+		return cmp.Compare(b.ID, a.ID)
+	})
+	if filter.Limit > 0 && len(docs) > filter.Limit {
+		docs = docs[:filter.Limit]
+	}
+	return docs, nil
+}
+
+// Iterate calls fn with each document of the tenant carried by ctx matching filter, the oldest first, leaving out the
+// soft-deleted ones and ignoring the limit and the cursor of filter. fn is called once the documents are collected,
+// so that it may use the repository.
+func (m *MockDocumentRepository) Iterate(ctx context.Context, filter domain.DocumentFilter, fn func(*domain.Document) error) error {
+	filter.Limit, filter.After = 0, domain.DocumentCursor{}
+	docs, err := m.List(ctx, filter)
+	if err != nil {
+		return err
+	}
+	for i := len(docs) - 1; i >= 0; i-- {
+		if err := fn(docs[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FindByStatus retrieves the documents of all the tenants having the given analysis status.
+func (m *MockDocumentRepository) FindByStatus(ctx context.Context, status domain.AnalysisStatus) ([]*domain.Document, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	var docs []*domain.Document
+	for _, doc := range m.documents {
+		if doc.Status == status {
+			docs = append(docs, doc)
+		}
+	}
+	return docs, nil
+}
+
+// Stats returns aggregate statistics on the documents of the tenant carried by ctx.
+func (m *MockDocumentRepository) Stats(ctx context.Context, since time.Time) (*domain.DocumentStats, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	var (
+		stats    domain.DocumentStats
+		latency  time.Duration
+		analyzed int64
+		tenant   = domain.TenantFromContext(ctx)
+	)
+	for _, doc := range m.documents {
+		if doc.Tenant != tenant || doc.IsDeleted() {
+			continue
+		}
+		switch doc.Status {
+		case domain.StatusPending:
+			stats.Pending++
+		case domain.StatusInfected:
+			stats.Infected++
+		case domain.StatusClean:
+			stats.Clean++
+		case domain.StatusTimeout, domain.StatusError, domain.StatusTooLarge:
+			stats.Failed++
+		}
+		if !doc.CreatedAt.Before(since) {
+			stats.UploadedSince++
+		}
+		if doc.Status.IsVerdict() && !doc.AnalyzedAt.Before(doc.CreatedAt) {
+			latency += doc.AnalyzedAt.Sub(doc.CreatedAt)
+			analyzed++
+		}
+	}
+	if analyzed > 0 {
+		stats.AverageScanLatency = latency / time.Duration(analyzed)
+	}
+	return &stats, nil
+}
+
+// Summarize counts the documents of all the tenants created between from, included, and to, excluded, by status,
+// along with the top most frequent threats and the top tenants uploading the most.
+func (m *MockDocumentRepository) Summarize(ctx context.Context, from, to time.Time, top int) (*domain.SummaryReport, error) {
+	if err := m.checkContextAndAvailability(ctx); err != nil {
+		return nil, err
+	}
+	m.documentMux.Lock()
+	defer m.documentMux.Unlock()
+	var (
+		report  = &domain.SummaryReport{From: from, To: to}
+		threats = make(map[string]int64)
+		tenants = make(map[string]int64)
+	)
+	for _, doc := range m.documents {
+		if doc.CreatedAt.Before(from) || !doc.CreatedAt.Before(to) {
+			continue
+		}
+		report.Total++
+		switch {
+		case doc.Status == domain.StatusPending:
+			report.Pending++
+		case doc.Status == domain.StatusInfected:
+			report.Infected++
+			threats[doc.Threat]++
+		case doc.Status == domain.StatusClean:
+			report.Clean++
+		case doc.Status.IsFailure():
+			report.Failed++
+		}
+		tenants[doc.Tenant]++
+	}
+	for name, n := range threats {
+		report.TopThreats = append(report.TopThreats, domain.ThreatCount{Name: name, Count: n})
+	}
+	slices.SortFunc(report.TopThreats, func(a, b domain.ThreatCount) int {
+		return cmp.Or(cmp.Compare(b.Count, a.Count), cmp.Compare(a.Name, b.Name))
+	})
+	for tenant, n := range tenants {
+		report.BusiestTenants = append(report.BusiestTenants, domain.TenantCount{Tenant: tenant, Uploads: n})
+	}
+	slices.SortFunc(report.BusiestTenants, func(a, b domain.TenantCount) int {
+		return cmp.Or(cmp.Compare(b.Uploads, a.Uploads), cmp.Compare(a.Tenant, b.Tenant))
+	})
+	report.TopThreats = report.TopThreats[:min(top, len(report.TopThreats))]
+	report.BusiestTenants = report.BusiestTenants[:min(top, len(report.BusiestTenants))]
+	return report, nil
+}
+
 // Online switches on or off the status of a mock document repository instance.
 func (m *MockDocumentRepository) IsOnline(b bool) {
 	m.onlineMux.Lock()
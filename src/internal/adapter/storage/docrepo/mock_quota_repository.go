@@ -0,0 +1,85 @@
+package docrepo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"sync"
+	"time"
+)
+
+// MockQuotaRepository is a mock implementation of the QuotaRepository interface.
+// It uses an in-memory map to simulate the tenant_quotas table.
+type MockQuotaRepository struct {
+	usages   map[string]*domain.QuotaUsage
+	usageMux sync.Mutex
+}
+
+var ErrMockQuotaRepository = errors.New("MockQuotaRepository")
+
+// NewMockQuota creates a new instance of MockQuotaRepository.
+func NewMockQuota() *MockQuotaRepository {
+	return &MockQuotaRepository{
+		usages: make(map[string]*domain.QuotaUsage),
+	}
+}
+
+// Reserve records an upload of size bytes during the given day, provided that it fits in quota.
+func (m *MockQuotaRepository) Reserve(ctx context.Context, day time.Time, size int64, quota domain.Quota) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrMockQuotaRepository, port.ErrQuotaReserveFailed, err)
+	}
+	m.usageMux.Lock()
+	defer m.usageMux.Unlock()
+
+	tenant := domain.TenantFromContext(ctx)
+	day = domain.QuotaDay(day)
+	u, exists := m.usages[tenant]
+	if !exists {
+		u = &domain.QuotaUsage{Day: day}
+		m.usages[tenant] = u
+	}
+	uploads := u.Uploads
+	if !u.Day.Equal(day) {
+		uploads = 0
+	}
+	if quota.MaxUploadsPerDay > 0 && uploads >= quota.MaxUploadsPerDay {
+		return fmt.Errorf("%w: %w: tenant=%q", ErrMockQuotaRepository, port.ErrQuotaExceeded, tenant)
+	}
+	if quota.MaxStoredBytes > 0 && u.StoredBytes+size > quota.MaxStoredBytes {
+		return fmt.Errorf("%w: %w: tenant=%q", ErrMockQuotaRepository, port.ErrQuotaExceeded, tenant)
+	}
+	u.Day, u.Uploads, u.StoredBytes = day, uploads+1, u.StoredBytes+size
+	return nil
+}
+
+// Release gives back size bytes of stored data.
+func (m *MockQuotaRepository) Release(ctx context.Context, size int64) error {
+	m.usageMux.Lock()
+	defer m.usageMux.Unlock()
+	if u, exists := m.usages[domain.TenantFromContext(ctx)]; exists {
+		u.StoredBytes = max(u.StoredBytes-size, 0)
+	}
+	return nil
+}
+
+// Usage returns the usage of the quota during the given day.
+func (m *MockQuotaRepository) Usage(ctx context.Context, day time.Time) (*domain.QuotaUsage, error) {
+	m.usageMux.Lock()
+	defer m.usageMux.Unlock()
+	usage := &domain.QuotaUsage{Day: domain.QuotaDay(day)}
+	if u, exists := m.usages[domain.TenantFromContext(ctx)]; exists {
+		usage.StoredBytes = u.StoredBytes
+		if u.Day.Equal(usage.Day) {
+			usage.Uploads = u.Uploads
+		}
+	}
+	return usage, nil
+}
+
+// Ping checks the availability of the repository.
+func (m *MockQuotaRepository) Ping() error {
+	return nil
+}
@@ -0,0 +1,335 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"regexp"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// PartitionInterval is the period of time covered by each partition of the documents table, partitioned by
+// creation date.
+type PartitionInterval string
+
+const (
+	PartitionNone    PartitionInterval = ""
+	PartitionDaily   PartitionInterval = "daily"
+	PartitionMonthly PartitionInterval = "monthly"
+)
+
+// legacyPartition is the name of the partition holding the documents created before the table was partitioned.
+const legacyPartition = "documents_unpartitioned"
+
+// ErrPartitioningFailed is returned when the documents table cannot be partitioned, or its partitions maintained.
+var ErrPartitioningFailed = errors.New("documents table partitioning failed")
+
+// ParsePartitionInterval returns the partition interval named s, see PartitionInterval.
+func ParsePartitionInterval(s string) (PartitionInterval, error) {
+	switch i := PartitionInterval(s); i {
+	case PartitionNone, PartitionDaily, PartitionMonthly:
+		return i, nil
+	default:
+		return PartitionNone, fmt.Errorf("invalid partition interval %q", s)
+	}
+}
+
+// start returns the start of the partition holding the documents created at t.
+func (i PartitionInterval) start(t time.Time) time.Time {
+	y, m, d := wallClock(t).Date()
+	if i == PartitionMonthly {
+		d = 1
+	}
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
+
+// next returns the start of the partition following the one starting at start.
+func (i PartitionInterval) next(start time.Time) time.Time {
+	if i == PartitionMonthly {
+		return start.AddDate(0, 1, 0)
+	}
+	return start.AddDate(0, 0, 1)
+}
+
+// name returns the name of the partition starting at start, such as documents_p20240131 or documents_p202401.
+func (i PartitionInterval) name(start time.Time) string {
+	if i == PartitionMonthly {
+		return "documents_p" + start.Format("200601")
+	}
+	return "documents_p" + start.Format("20060102")
+}
+
+// wallClock returns the date and time of t in its location as a UTC time, as they are stored in the timestamp
+// without time zone columns.
+func wallClock(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), time.UTC)
+}
+
+// boundLayout is the layout of the bounds of the partitions.
+const boundLayout = "2006-01-02 15:04:05"
+
+// partitionUpperBound matches the upper bound of a partition in its definition, see pg_get_expr.
+var partitionUpperBound = regexp.MustCompile(`TO \('([^']+)'\)`)
+
+// partition is a partition of the documents table, holding the documents created before upper.
+type partition struct {
+	name  string
+	upper time.Time
+}
+
+// querier is implemented by *sql.DB and *sql.Tx.
+type querier interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// isPartitioned reports whether the documents table is partitioned.
+func isPartitioned(ctx context.Context, q querier) (bool, error) {
+	var partitioned bool
+	err := q.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM pg_partitioned_table WHERE partrelid = 'documents'::regclass)").Scan(&partitioned)
+	return partitioned, err
+}
+
+// listPartitions returns the partitions of the documents table.
+func listPartitions(ctx context.Context, q querier) ([]partition, error) {
+	rows, err := q.QueryContext(ctx, `SELECT c.relname, pg_get_expr(c.relpartbound, c.oid) FROM pg_inherits i
+JOIN pg_class c ON c.oid = i.inhrelid WHERE i.inhparent = 'documents'::regclass`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var partitions []partition
+	for rows.Next() {
+		var p partition
+		var bound string
+		if err = rows.Scan(&p.name, &bound); err != nil {
+			return nil, err
+		}
+		m := partitionUpperBound.FindStringSubmatch(bound)
+		if m == nil {
+			return nil, fmt.Errorf("unexpected bounds of partition %s: %s", p.name, bound)
+		}
+		if p.upper, err = time.Parse(boundLayout, m[1]); err != nil {
+			return nil, fmt.Errorf("unexpected bounds of partition %s: %s", p.name, bound)
+		}
+		partitions = append(partitions, p)
+	}
+	return partitions, rows.Err()
+}
+
+// Partition converts the documents table into a table partitioned by creation date with the given interval, unless it
+// is partitioned already. The existing documents are kept in a partition of their own, documents_unpartitioned,
+// covering the dates up to the end of the current interval, which is dropped once all of them are purged.
+// The conversion holds an exclusive lock on the table while the existing documents are checked against their bounds,
+// and the lock of the schema, see lockSchema.
+func Partition(ctx context.Context, db *sql.DB, interval PartitionInterval) error {
+	if interval == PartitionNone {
+		return nil
+	}
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	defer tx.Rollback()
+
+	if err = lockSchema(ctx, tx); err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE documents IN ACCESS EXCLUSIVE MODE"); err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	partitioned, err := isPartitioned(ctx, tx)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	if partitioned {
+		return nil
+	}
+
+	var sequence string
+	if err = tx.QueryRowContext(ctx, "SELECT pg_get_serial_sequence('documents', 'id')").Scan(&sequence); err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+
+	upper := interval.next(interval.start(time.Now())).Format(boundLayout)
+	statements := []string{
+		"ALTER TABLE documents RENAME TO " + legacyPartition,
+		"CREATE TABLE documents (LIKE " + legacyPartition + " INCLUDING DEFAULTS INCLUDING CONSTRAINTS) PARTITION BY RANGE (created_at)",
+		// the keys of a partitioned table include its partition key, the key of the existing documents gives way to
+		// the one of the partitioned table, see documentLockPrefix
+		"ALTER TABLE " + legacyPartition + " DROP CONSTRAINT IF EXISTS documents_pkey",
+		"ALTER TABLE documents ADD PRIMARY KEY (id, created_at)",
+		"ALTER TABLE documents ADD CONSTRAINT documents_document_id_created_at_key UNIQUE (document_id, created_at)",
+		// the sequence of the IDs must outlive the partition of the existing documents
+		"ALTER SEQUENCE " + sequence + " OWNED BY documents.id",
+		"CREATE INDEX idx_documents_document_id ON documents(document_id)",
+		"CREATE INDEX idx_documents_hash ON documents(hash)",
+		"CREATE INDEX idx_documents_status ON documents(status)",
+		"CREATE INDEX idx_documents_analyzed_at ON documents(analyzed_at)",
+		"CREATE INDEX idx_documents_tenant_hash ON documents(tenant, hash)",
+		// the index of migration 0004 keeps its name on the partitioned table
+		"ALTER INDEX idx_documents_deleted_at RENAME TO idx_documents_unpartitioned_deleted_at",
+		"CREATE INDEX idx_documents_deleted_at ON documents(deleted_at) WHERE deleted_at IS NOT NULL",
+		// and so do the indexes of migration 0006
+		"ALTER INDEX idx_documents_labels RENAME TO idx_documents_unpartitioned_labels",
+		"CREATE INDEX idx_documents_labels ON documents USING GIN (labels jsonb_path_ops)",
+		"ALTER INDEX idx_documents_tenant_created_at RENAME TO idx_documents_unpartitioned_tenant_created_at",
+		"CREATE INDEX idx_documents_tenant_created_at ON documents(tenant, created_at)",
+		// and those of migration 0007
+		"ALTER INDEX idx_documents_tenant_status_created_at RENAME TO idx_documents_unpartitioned_tenant_status_created_at",
+		"CREATE INDEX idx_documents_tenant_status_created_at ON documents(tenant, status, created_at)",
+		"ALTER INDEX idx_documents_tenant_analyzed_at RENAME TO idx_documents_unpartitioned_tenant_analyzed_at",
+		"CREATE INDEX idx_documents_tenant_analyzed_at ON documents(tenant, analyzed_at)",
+		"ALTER INDEX idx_documents_tenant_tag RENAME TO idx_documents_unpartitioned_tenant_tag",
+		"CREATE INDEX idx_documents_tenant_tag ON documents(tenant, tag text_pattern_ops)",
+		// and the index of migration 0013
+		"ALTER INDEX idx_documents_tenant_created_at_id RENAME TO idx_documents_unpartitioned_tenant_created_at_id",
+		"CREATE INDEX idx_documents_tenant_created_at_id ON documents(tenant, created_at DESC, document_id DESC)",
+		// the trigger of migration 0003 is moved to the partitioned table, which clones it into its partitions
+		"DROP TRIGGER IF EXISTS documents_status_notify ON " + legacyPartition,
+		"CREATE TRIGGER documents_status_notify AFTER UPDATE OF status ON documents FOR EACH ROW WHEN (OLD.status IS DISTINCT FROM NEW.status) EXECUTE FUNCTION notify_document_status()",
+		// and so is the trigger of migration 0010
+		"DROP TRIGGER IF EXISTS documents_record_event ON " + legacyPartition,
+		"CREATE TRIGGER documents_record_event AFTER INSERT OR UPDATE ON documents FOR EACH ROW EXECUTE FUNCTION record_document_event()",
+		// and the trigger of migration 0011
+		"DROP TRIGGER IF EXISTS documents_release_callbacks ON " + legacyPartition,
+		"CREATE TRIGGER documents_release_callbacks AFTER UPDATE OF status ON documents FOR EACH ROW WHEN (OLD.status = 0 AND NEW.status <> 0) EXECUTE FUNCTION release_document_callbacks()",
+		"ALTER TABLE documents ATTACH PARTITION " + legacyPartition + " FOR VALUES FROM (MINVALUE) TO ('" + upper + "')",
+	}
+	for _, s := range statements {
+		if _, err = tx.ExecContext(ctx, s); err != nil {
+			return fmt.Errorf("%w: %s: %v", ErrPartitioningFailed, s, err)
+		}
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	slog.Info("documents table partitioned", "interval", interval, "existing documents until", upper)
+	return nil
+}
+
+// ensurePartitions creates the partitions of the documents created from now on, up to partitionsAhead intervals
+// ahead, which are not covered by a partition yet.
+func (r PostgresDocumentRepository) ensurePartitions(ctx context.Context, now time.Time) error {
+	partitions, err := listPartitions(ctx, r.db)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrPartitioningFailed, err)
+	}
+	var covered time.Time
+	for _, p := range partitions {
+		if p.upper.After(covered) {
+			covered = p.upper
+		}
+	}
+	start := r.partitions.start(now)
+	for k := 0; k <= r.partitionsAhead; k++ {
+		if !start.Before(covered) {
+			if err = r.createPartition(ctx, start); err != nil {
+				return err
+			}
+		}
+		start = r.partitions.next(start)
+	}
+	return nil
+}
+
+// createPartition creates the partition starting at start, unless it exists already. It holds the lock of the schema,
+// see lockSchema, as the replicas would otherwise fail to create the same partition at once.
+func (r PostgresDocumentRepository) createPartition(ctx context.Context, start time.Time) error {
+	name := r.partitions.name(start)
+	q := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s PARTITION OF documents FOR VALUES FROM ('%s') TO ('%s')",
+		pq.QuoteIdentifier(name), start.Format(boundLayout), r.partitions.next(start).Format(boundLayout))
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: failed to create partition %s: %v", ErrPartitioningFailed, name, err)
+	}
+	defer tx.Rollback()
+	if err = lockSchema(ctx, tx); err != nil {
+		return fmt.Errorf("%w: failed to create partition %s: %v", ErrPartitioningFailed, name, err)
+	}
+	if _, err = tx.ExecContext(ctx, q); err != nil {
+		return fmt.Errorf("%w: failed to create partition %s: %v", ErrPartitioningFailed, name, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: failed to create partition %s: %v", ErrPartitioningFailed, name, err)
+	}
+	slog.Debug("documents partition created", "partition", name)
+	return nil
+}
+
+// documentLockPrefix prefixes the name of the advisory lock of a document ID. The unique constraints of a partitioned
+// table must include its partition key, so (document_id, created_at) is unique but the same ID could be saved twice
+// at different dates: the documents are thus saved holding the lock of their ID once no partition holds it, see
+// PostgresDocumentRepository.Save.
+const documentLockPrefix = "document/"
+
+// isMissingPartition reports whether err is returned for a row no partition of the documents table covers.
+func isMissingPartition(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23514" && pqErr.Constraint == ""
+}
+
+// dropPartitions drops the partitions of the documents created before date which would be left empty by a purge of
+// the given statuses, all but pending if none is given, and returns the number of documents they held.
+func (r PostgresDocumentRepository) dropPartitions(ctx context.Context, date time.Time, statuses []int64) (int64, error) {
+	partitions, err := listPartitions(ctx, r.db)
+	if err != nil {
+		return 0, err
+	}
+	var dropped int64
+	for _, p := range partitions {
+		if p.upper.After(wallClock(date)) {
+			continue
+		}
+		n, err := r.dropPartition(ctx, p.name, statuses)
+		if err != nil {
+			return dropped, err
+		}
+		dropped += n
+	}
+	return dropped, nil
+}
+
+// dropPartition drops the partition name if it holds none of the documents a purge of the given statuses would keep,
+// and returns the number of documents it held.
+func (r PostgresDocumentRepository) dropPartition(ctx context.Context, name string, statuses []int64) (int64, error) {
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	table := pq.QuoteIdentifier(name)
+	if _, err = tx.ExecContext(ctx, "LOCK TABLE "+table+" IN ACCESS EXCLUSIVE MODE"); err != nil {
+		return 0, err
+	}
+	var kept bool
+	q := "SELECT EXISTS (SELECT 1 FROM " + table + " WHERE status = $1"
+	args := []any{domain.StatusPending}
+	if len(statuses) > 0 {
+		q += " OR NOT (status = ANY($2))"
+		args = append(args, pq.Array(statuses))
+	}
+	if err = tx.QueryRowContext(ctx, q+")", args...).Scan(&kept); err != nil || kept {
+		return 0, err
+	}
+	var n int64
+	if err = tx.QueryRowContext(ctx, "SELECT count(*) FROM "+table).Scan(&n); err != nil {
+		return 0, err
+	}
+	if _, err = tx.ExecContext(ctx, "DROP TABLE "+table); err != nil {
+		return 0, err
+	}
+	if err = tx.Commit(); err != nil {
+		return 0, err
+	}
+	slog.Info("documents partition dropped", "partition", name, "documents", n)
+	return n, nil
+}
@@ -0,0 +1,155 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPartitionInterval(t *testing.T) {
+	at := time.Date(2024, time.January, 31, 18, 30, 0, 0, time.FixedZone("CET", 3600))
+
+	start := PartitionDaily.start(at)
+	assert.Equal(t, time.Date(2024, time.January, 31, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), PartitionDaily.next(start))
+	assert.Equal(t, "documents_p20240131", PartitionDaily.name(start))
+
+	start = PartitionMonthly.start(at)
+	assert.Equal(t, time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC), start)
+	assert.Equal(t, time.Date(2024, time.February, 1, 0, 0, 0, 0, time.UTC), PartitionMonthly.next(start))
+	assert.Equal(t, "documents_p202401", PartitionMonthly.name(start))
+
+	_, err := ParsePartitionInterval("weekly")
+	assert.Error(t, err)
+}
+
+func TestPartitionPartitioned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	expectCheck := func() *sqlmock.ExpectedQuery {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).WithArgs(advisoryLockClass, schemaLock).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("LOCK TABLE documents IN ACCESS EXCLUSIVE MODE").WillReturnResult(sqlmock.NewResult(0, 0))
+		return mock.ExpectQuery("SELECT EXISTS \\(SELECT 1 FROM pg_partitioned_table")
+	}
+
+	// a partitioned table is left as is
+	expectCheck().WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+	assert.NoError(t, Partition(context.Background(), db, PartitionDaily))
+
+	// the failure to check it is reported as a partitioning failure
+	expectCheck().WillReturnError(sql.ErrConnDone)
+	mock.ExpectRollback()
+	assert.ErrorIs(t, Partition(context.Background(), db, PartitionDaily), ErrPartitioningFailed)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestSavePartitioned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db, partitions: PartitionDaily}
+	doc := domain.NewDocument("RNiGEv6oqPNt6C4SeKuwLw", "hash", "tag")
+	doc.CreatedAt = time.Date(2024, time.January, 31, 12, 0, 0, 0, time.UTC)
+
+	// the document is inserted holding the lock of its ID once no partition holds it
+	expectLookup := func(exists bool) {
+		mock.ExpectBegin()
+		mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).WithArgs(advisoryLockClass, documentLockPrefix+doc.ID).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM documents WHERE document_id = \$1\)`).WithArgs(doc.ID).
+			WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(exists))
+	}
+
+	// the missing partition is created on demand
+	expectLookup(false)
+	mock.ExpectExec("INSERT INTO documents").WillReturnError(&pq.Error{Code: "23514", Message: `no partition of relation "documents" found for row`})
+	mock.ExpectRollback()
+	mock.ExpectBegin()
+	mock.ExpectExec(`SELECT pg_advisory_xact_lock\(\$1, hashtext\(\$2\)\)`).WithArgs(advisoryLockClass, schemaLock).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec(`CREATE TABLE IF NOT EXISTS "documents_p20240131" PARTITION OF documents FOR VALUES FROM \('2024-01-31 00:00:00'\) TO \('2024-02-01 00:00:00'\)`).
+		WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	expectLookup(false)
+	mock.ExpectExec("INSERT INTO documents").WillReturnResult(sqlmock.NewResult(1, 1))
+	mock.ExpectCommit()
+
+	assert.NoError(t, repo.Save(context.Background(), doc))
+
+	// the same ID cannot be saved again at another date, in another partition
+	expectLookup(true)
+	mock.ExpectRollback()
+
+	doc.CreatedAt = doc.CreatedAt.AddDate(0, 0, 1)
+	assert.ErrorIs(t, repo.Save(context.Background(), doc), port.ErrDocumentAlreadyExists)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestPurgePartitioned(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db, partitions: PartitionDaily, partitionsAhead: 1}
+	now := time.Now()
+	purgeTime := now.Add(-48 * time.Hour)
+	upper := func(t time.Time) string {
+		return "FOR VALUES FROM ('2024-01-01 00:00:00') TO ('" + PartitionDaily.next(PartitionDaily.start(t)).Format(boundLayout) + "')"
+	}
+	partitions := func() *sqlmock.Rows {
+		return sqlmock.NewRows([]string{"relname", "bound"}).
+			AddRow("documents_old", upper(now.Add(-96*time.Hour))).
+			AddRow("documents_pending", upper(now.Add(-72*time.Hour))).
+			AddRow("documents_current", upper(now))
+	}
+
+	// the partitions older than the purge date are dropped unless they hold pending documents
+	mock.ExpectQuery("SELECT c.relname").WillReturnRows(partitions())
+	mock.ExpectBegin()
+	mock.ExpectExec(`LOCK TABLE "documents_old"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS \(SELECT 1 FROM "documents_old" WHERE status = \$1\)`).
+		WithArgs(domain.StatusPending).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(false))
+	mock.ExpectQuery(`SELECT count\(\*\) FROM "documents_old"`).WillReturnRows(sqlmock.NewRows([]string{"count"}).AddRow(3))
+	mock.ExpectExec(`DROP TABLE "documents_old"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectBegin()
+	mock.ExpectExec(`LOCK TABLE "documents_pending"`).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectQuery(`SELECT EXISTS`).WillReturnRows(sqlmock.NewRows([]string{"exists"}).AddRow(true))
+	mock.ExpectRollback()
+
+	// the partition of the next interval is created
+	mock.ExpectQuery("SELECT c.relname").WillReturnRows(partitions())
+	mock.ExpectBegin()
+	mock.ExpectExec("SELECT pg_advisory_xact_lock").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectCommit()
+	mock.ExpectExec("DELETE FROM documents WHERE created_at < \\$1 AND status != \\$2").
+		WithArgs(purgeTime, domain.StatusPending).WillReturnResult(sqlmock.NewResult(0, 2))
+	mock.ExpectExec("DELETE FROM documents WHERE deleted_at < \\$1").WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM document_events WHERE occurred_at < \\$1").WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+	mock.ExpectExec("DELETE FROM callback_outbox WHERE created_at < \\$1").WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+
+	n, err := repo.Purge(purgeTime)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), n)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
@@ -0,0 +1,89 @@
+package docrepo
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"slices"
+	"time"
+)
+
+// orphanCallbacks is the condition of the notifications of the documents purged before $1.
+const orphanCallbacks = "created_at < $1 AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.document_id = callback_outbox.document_id AND d.tenant = callback_outbox.tenant)"
+
+// claimCallbacksQuery claims the due notifications, $1 at most, pushing their next attempt back by $2 seconds times
+// two to the power of their previous attempts, up to 2^10. The notifications claimed by another replica are skipped.
+const claimCallbacksQuery = `UPDATE callback_outbox SET attempts = attempts + 1,
+    next_attempt_at = (now() AT TIME ZONE 'UTC') + make_interval(secs => $2 * power(2, LEAST(attempts, 10)))
+WHERE id IN (
+    SELECT id FROM callback_outbox WHERE due AND next_attempt_at <= (now() AT TIME ZONE 'UTC')
+    ORDER BY id LIMIT $1 FOR UPDATE SKIP LOCKED
+)
+RETURNING id, document_id, tenant, callback_url, attempts`
+
+// AddCallback records that callbackURL is to be notified of the document of the tenant carried by ctx identified by
+// ID. The document is locked while the notification is recorded, so that its status cannot change meanwhile: the
+// notification is due at once if the document has a final status, or else made due by the trigger of migration 0011
+// along with its final status.
+func (r PostgresDocumentRepository) AddCallback(ctx context.Context, ID, callbackURL string) error {
+	tenant := domain.TenantFromContext(ctx)
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	defer tx.Rollback()
+
+	var status domain.AnalysisStatus
+	q := "SELECT status FROM documents WHERE document_id = $1 AND tenant = $2 FOR SHARE"
+	if err = tx.QueryRowContext(ctx, q, ID, tenant).Scan(&status); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("%w: %w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, port.ErrDocumentNotFound, ID)
+		}
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	q = "INSERT INTO callback_outbox (document_id, tenant, callback_url, due) VALUES ($1, $2, $3, $4)"
+	if _, err = tx.ExecContext(ctx, q, ID, tenant, callbackURL, status != domain.StatusPending); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	return nil
+}
+
+// ClaimCallbacks returns up to limit due notifications of all the tenants, the oldest first, and hides them from the
+// next claims for lease times two to the power of their previous attempts.
+func (r PostgresDocumentRepository) ClaimCallbacks(ctx context.Context, limit int, lease time.Duration) ([]domain.CallbackDelivery, error) {
+	rows, err := r.db.QueryContext(ctx, claimCallbacksQuery, limit, lease.Seconds())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	defer rows.Close()
+
+	var deliveries []domain.CallbackDelivery
+	for rows.Next() {
+		var d domain.CallbackDelivery
+		if err = rows.Scan(&d.ID, &d.DocumentID, &d.Tenant, &d.CallbackURL, &d.Attempts); err != nil {
+			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+		}
+		deliveries = append(deliveries, d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	// RETURNING does not keep the order of the subquery
+	slices.SortFunc(deliveries, func(a, b domain.CallbackDelivery) int { return cmp.Compare(a.ID, b.ID) })
+	return deliveries, nil
+}
+
+// RemoveCallback removes a notification once delivered, or given up.
+func (r PostgresDocumentRepository) RemoveCallback(ctx context.Context, ID int64) error {
+	if _, err := r.db.ExecContext(ctx, "DELETE FROM callback_outbox WHERE id = $1", ID); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrCallbackOutboxFailed, err)
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+package docrepo
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+)
+
+// eventColumns lists the columns of the document_events table mapped to domain.DocumentEvent, in the order used by
+// Events.
+const eventColumns = "id, document_id, tenant, type, status, threat_name, hash, occurred_at"
+
+// orphanEvents is the condition of the events of the documents purged before $1.
+const orphanEvents = "occurred_at < $1 AND NOT EXISTS (SELECT 1 FROM documents d WHERE d.document_id = document_events.document_id AND d.tenant = document_events.tenant)"
+
+// Events retrieves the events of the document of the tenant carried by ctx identified by ID, the oldest first. The
+// events are written by the trigger of migration 0010 along with the changes of the documents.
+func (r PostgresDocumentRepository) Events(ctx context.Context, ID string) ([]domain.DocumentEvent, error) {
+	q := "SELECT " + eventColumns + " FROM document_events WHERE tenant = $1 AND document_id = $2 ORDER BY id"
+	rows, err := r.db.QueryContext(ctx, q, domain.TenantFromContext(ctx), ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentEventsFailed, err)
+	}
+	defer rows.Close()
+
+	var events []domain.DocumentEvent
+	for rows.Next() {
+		var (
+			e         domain.DocumentEvent
+			eventType string
+		)
+		if err = rows.Scan(&e.Seq, &e.DocumentID, &e.Tenant, &eventType, &e.Status, &e.Threat, &e.Hash, &e.OccurredAt); err != nil {
+			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentEventsFailed, err)
+		}
+		e.Type = domain.DocumentEventType(eventType)
+		events = append(events, e)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentEventsFailed, err)
+	}
+	return events, nil
+}
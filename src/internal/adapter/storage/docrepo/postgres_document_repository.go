@@ -3,59 +3,250 @@ package docrepo
 import (
 	"context"
 	"database/sql"
-	_ "embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
+	"goyav/pkg/helper"
 	"log/slog"
+	"strings"
 	"time"
 
-	_ "github.com/lib/pq"
+	"github.com/lib/pq"
 )
 
 type PostgresDocumentRepository struct {
 	db *sql.DB
+
+	// partitions is the interval of the partitions of the documents table, PartitionNone if it is not partitioned,
+	// and partitionsAhead the number of partitions created ahead of the current one.
+	partitions      PartitionInterval
+	partitionsAhead int
+
+	// purgeBatchSize is the maximum number of documents deleted at once by Purge, unbounded if zero, and
+	// purgeBatchPause the pause between two batches.
+	purgeBatchSize  int
+	purgeBatchPause time.Duration
+
+	// ops logs the failed and slow operations with the context of their request, see WithSlowOperations.
+	ops helper.OperationLog
+}
+
+const (
+	// DefaultPurgeBatchSize is the default maximum number of documents deleted at once by Purge.
+	DefaultPurgeBatchSize = 5000
+
+	// DefaultPurgeBatchPause is the default pause between two batches of deletions of Purge.
+	DefaultPurgeBatchPause = 100 * time.Millisecond
+
+	// exportBatchSize is the number of documents fetched at once by Iterate.
+	exportBatchSize = 1000
+)
+
+// PostgresOption configures optional behaviours of a PostgresDocumentRepository.
+type PostgresOption func(*PostgresDocumentRepository)
+
+// WithPartitions makes the repository maintain the partitions of the documents table, partitioned with the given
+// interval by Partition: the partitions of the current interval and of the ahead next ones are created in advance,
+// and the partitions whose documents are all purged are dropped rather than emptied.
+func WithPartitions(interval PartitionInterval, ahead int) PostgresOption {
+	return func(r *PostgresDocumentRepository) {
+		r.partitions = interval
+		r.partitionsAhead = max(0, ahead)
+	}
+}
+
+// WithPurgeBatches makes Purge delete at most size documents at once, pausing between two batches, so that
+// the purge of large tables neither holds long locks nor bursts the write-ahead log. A size of zero deletes all
+// the documents at once.
+func WithPurgeBatches(size int, pause time.Duration) PostgresOption {
+	return func(r *PostgresDocumentRepository) {
+		r.purgeBatchSize = max(0, size)
+		r.purgeBatchPause = max(0, pause)
+	}
+}
+
+// WithSlowOperations makes the repository log the queries on the documents of a request lasting at least threshold,
+// along with the failed ones, with the request ID of the request they serve.
+func WithSlowOperations(threshold time.Duration) PostgresOption {
+	return func(r *PostgresDocumentRepository) {
+		r.ops.Slow = max(0, threshold)
+	}
+}
+
+// documentColumns lists the columns of the documents table mapped to domain.Document, in the order used by scanDocument.
+const documentColumns = "document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version"
+
+// rowScanner is implemented by *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// scanDocument reads a document from a row selecting documentColumns.
+func scanDocument(row rowScanner) (*domain.Document, error) {
+	var (
+		archive   string
+		deletedAt sql.NullTime
+		labels    []byte
+		engines   string
+	)
+	doc := new(domain.Document)
+	err := row.Scan(
+		&doc.ID,
+		&doc.Hash,
+		&doc.Tag,
+		&doc.Status,
+		&doc.AnalyzedAt,
+		&doc.CreatedAt,
+		&doc.Tenant,
+		&doc.Source,
+		&doc.Origin,
+		&doc.Sealed,
+		&doc.HashAlgo,
+		&doc.FileName,
+		&doc.Size,
+		&doc.ContentType,
+		&archive,
+		&deletedAt,
+		&doc.Threat,
+		&labels,
+		&engines,
+		&doc.BinaryVersion)
+	if err != nil {
+		return nil, err
+	}
+	doc.DeletedAt = deletedAt.Time
+	if archive != "" {
+		doc.Archive = new(domain.ArchiveReport)
+		if err = json.Unmarshal([]byte(archive), doc.Archive); err != nil {
+			return nil, fmt.Errorf("invalid archive report: %w", err)
+		}
+	}
+	if len(labels) > 0 {
+		if err = json.Unmarshal(labels, &doc.Labels); err != nil {
+			return nil, fmt.Errorf("invalid labels: %w", err)
+		}
+		if len(doc.Labels) == 0 {
+			doc.Labels = nil
+		}
+	}
+	if engines != "" {
+		doc.Engines = strings.Split(engines, ",")
+	}
+	return doc, nil
 }
 
 var ErrPostgresDocumentRepository = errors.New("PostgresDocumentRepository")
 
-func NewPotgres(db *sql.DB) (*PostgresDocumentRepository, error) {
+func NewPotgres(db *sql.DB, opts ...PostgresOption) (*PostgresDocumentRepository, error) {
 	if db == nil {
 		return nil, fmt.Errorf("%w : required sql.DB, got nil", ErrPostgresDocumentRepository)
 	}
 	if err := db.Ping(); err != nil {
 		return nil, fmt.Errorf("%w: %v", ErrPostgresDocumentRepository, err)
 	}
-	if err := SetupDocumentTable(db); err != nil {
-		return nil, fmt.Errorf("%w: failed to create document table: %v", ErrPostgresDocumentRepository, err)
+	ctx := context.Background()
+	if err := CheckSchemaVersion(ctx, db); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPostgresDocumentRepository, err)
+	}
+	r := &PostgresDocumentRepository{db: db, purgeBatchSize: DefaultPurgeBatchSize, purgeBatchPause: DefaultPurgeBatchPause,
+		ops: helper.OperationLog{Component: "postgres", Ignore: []error{port.ErrDocumentNotFound, port.ErrDocumentAlreadyExists}}}
+	for _, opt := range opts {
+		opt(r)
+	}
+	if r.partitions != PartitionNone {
+		partitioned, err := isPartitioned(ctx, db)
+		switch {
+		case err != nil:
+			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, ErrPartitioningFailed, err)
+		case !partitioned:
+			return nil, fmt.Errorf("%w: %w: the documents table is not partitioned, see goyav migrate", ErrPostgresDocumentRepository, ErrPartitioningFailed)
+		}
+		if err = r.ensurePartitions(ctx, time.Now()); err != nil {
+			return nil, fmt.Errorf("%w: %w", ErrPostgresDocumentRepository, err)
+		}
 	}
 	slog.Info("document repository created")
-	return &PostgresDocumentRepository{db: db}, nil
+	return r, nil
 }
 
 // Save adds a new document to the repository and returns an error if the document already exists or
 // if there is an issue during the save operation.
-func (r PostgresDocumentRepository) Save(ctx context.Context, doc *domain.Document) error {
-	q := "INSERT INTO documents (document_id, hash, tag, status, analyzed_at, created_at) VALUES ($1, $2, $3, $4, $5, $6)"
-	_, err := r.db.ExecContext(ctx, q, doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt)
+func (r PostgresDocumentRepository) Save(ctx context.Context, doc *domain.Document) (err error) {
+	defer r.ops.Observe(ctx, "save", time.Now(), &err, "ID", doc.ID)
+	source := doc.Source
+	if source == "" {
+		source = domain.SourceUpload
+	}
+	hashAlgo := doc.HashAlgo
+	if hashAlgo == "" {
+		hashAlgo = domain.DefaultHashAlgo
+	}
+	labels, err := marshalLabels(doc.Labels)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v: document=%#v", ErrPostgresDocumentRepository, port.ErrSaveDocumentFailed, err, doc)
+	}
+	// a new document is not deleted
+	q := "INSERT INTO documents (" + documentColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, NULL, $16, $17, $18, $19)"
+	args := []any{doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt, doc.Tenant, source, doc.Origin, doc.Sealed, hashAlgo,
+		doc.FileName, doc.Size, doc.ContentType, "", doc.Threat, labels, strings.Join(doc.Engines, ","), doc.BinaryVersion}
+	err = r.insert(ctx, doc.ID, q, args)
+	if err != nil && r.partitions != PartitionNone && isMissingPartition(err) {
+		// the partitions created in advance do not cover the creation date of the document
+		if err = r.createPartition(ctx, r.partitions.start(doc.CreatedAt)); err == nil {
+			err = r.insert(ctx, doc.ID, q, args)
+		}
+	}
+	if errors.Is(err, port.ErrDocumentAlreadyExists) || isUniqueViolation(err) {
+		return fmt.Errorf("%w: %w: %w: id=%q", ErrPostgresDocumentRepository, port.ErrSaveDocumentFailed, port.ErrDocumentAlreadyExists, doc.ID)
+	}
 	if err != nil {
 		return fmt.Errorf("%w: %w: %v: document=%#v", ErrPostgresDocumentRepository, port.ErrSaveDocumentFailed, err, doc)
 	}
 	return nil
 }
 
+// insert runs the query q inserting the document identified by ID. The ID of a document is unique only within each
+// partition of a partitioned table, so the document is inserted holding the advisory lock of its ID once no partition
+// holds it, see documentLockPrefix.
+func (r PostgresDocumentRepository) insert(ctx context.Context, ID, q string, args []any) error {
+	if r.partitions == PartitionNone {
+		_, err := r.db.ExecContext(ctx, q, args...)
+		return err
+	}
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+	if _, err = tx.ExecContext(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", advisoryLockClass, documentLockPrefix+ID); err != nil {
+		return err
+	}
+	var exists bool
+	if err = tx.QueryRowContext(ctx, "SELECT EXISTS (SELECT 1 FROM documents WHERE document_id = $1)", ID).Scan(&exists); err != nil {
+		return err
+	}
+	if exists {
+		return port.ErrDocumentAlreadyExists
+	}
+	if _, err = tx.ExecContext(ctx, q, args...); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// isUniqueViolation reports whether err is returned for a row breaking a unique constraint of the documents table.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
 // Get retrieves a document by its ID and returns an error if not found or if there is an issue with the ID.
-func (r PostgresDocumentRepository) Get(ctx context.Context, ID string) (*domain.Document, error) {
-	q := "SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE document_id = $1"
-	doc := new(domain.Document)
-	err := r.db.QueryRowContext(ctx, q, ID).Scan(
-		&doc.ID,
-		&doc.Hash,
-		&doc.Tag,
-		&doc.Status,
-		&doc.AnalyzedAt,
-		&doc.CreatedAt)
+func (r PostgresDocumentRepository) Get(ctx context.Context, ID string) (_ *domain.Document, err error) {
+	defer r.ops.Observe(ctx, "get", time.Now(), &err, "ID", ID)
+	q := "SELECT " + documentColumns + " FROM documents WHERE document_id = $1 AND tenant = $2"
+	doc, err := scanDocument(r.db.QueryRowContext(ctx, q, ID, domain.TenantFromContext(ctx)))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentNotFound, err)
@@ -67,16 +258,11 @@ func (r PostgresDocumentRepository) Get(ctx context.Context, ID string) (*domain
 }
 
 // GetByHash retrieves a document by its hash and returns an error if not found or if there is an issue with the hash.
-func (r PostgresDocumentRepository) GetByHash(ctx context.Context, hash string) (*domain.Document, error) {
-	q := "SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE hash = $1"
-	doc := new(domain.Document)
-	err := r.db.QueryRowContext(ctx, q, hash).Scan(
-		&doc.ID,
-		&doc.Hash,
-		&doc.Tag,
-		&doc.Status,
-		&doc.AnalyzedAt,
-		&doc.CreatedAt)
+// The soft-deleted documents are ignored.
+func (r PostgresDocumentRepository) GetByHash(ctx context.Context, hash string) (_ *domain.Document, err error) {
+	defer r.ops.Observe(ctx, "get by hash", time.Now(), &err, "hash", hash)
+	q := "SELECT " + documentColumns + " FROM documents WHERE hash = $1 AND tenant = $2 AND deleted_at IS NULL"
+	doc, err := scanDocument(r.db.QueryRowContext(ctx, q, hash, domain.TenantFromContext(ctx)))
 	if err != nil {
 		if errors.Is(err, sql.ErrNoRows) {
 			return nil, fmt.Errorf("%w.GetByHash: %w", ErrPostgresDocumentRepository, port.ErrDocumentNotFound)
@@ -88,9 +274,10 @@ func (r PostgresDocumentRepository) GetByHash(ctx context.Context, hash string)
 }
 
 // Delete removes a document from the repository by its ID and returns an error if not found or during deletion.
-func (r PostgresDocumentRepository) Delete(ctx context.Context, ID string) error {
-	q := "DELETE FROM documents WHERE document_id = $1"
-	res, err := r.db.ExecContext(ctx, q, ID)
+func (r PostgresDocumentRepository) Delete(ctx context.Context, ID string) (err error) {
+	defer r.ops.Observe(ctx, "delete", time.Now(), &err, "ID", ID)
+	q := "DELETE FROM documents WHERE document_id = $1 AND tenant = $2"
+	res, err := r.db.ExecContext(ctx, q, ID, domain.TenantFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDeleteDocumentFailed, err)
 	}
@@ -101,17 +288,56 @@ func (r PostgresDocumentRepository) Delete(ctx context.Context, ID string) error
 	}
 
 	if n == 0 {
-		return fmt.Errorf("%w: %w: no document found with ID %v", ErrPostgresDocumentRepository, port.ErrDeleteDocumentFailed, ID)
+		return fmt.Errorf("%w: %w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrDeleteDocumentFailed, port.ErrDocumentNotFound, ID)
 	}
 
 	return nil
 }
 
-// UpdateStatus updates a document's analysis status and date, returning an error for nonexistent documents,
-// invalid status, or update issues.
-func (r PostgresDocumentRepository) UpdateStatus(ctx context.Context, ID string, status domain.AnalysisStatus, analyzedAt time.Time) error {
-	q := "UPDATE documents SET status = $1, analyzed_at = $2 WHERE document_id = $3"
-	res, err := r.db.ExecContext(ctx, q, status, time.Now(), ID)
+// SoftDelete marks a document as deleted at the given date, it is removed by Purge once the recovery window
+// is over. It returns an error if the document does not exist or is deleted already.
+func (r PostgresDocumentRepository) SoftDelete(ctx context.Context, ID string, deletedAt time.Time) (err error) {
+	defer r.ops.Observe(ctx, "soft delete", time.Now(), &err, "ID", ID)
+	q := "UPDATE documents SET deleted_at = $1 WHERE document_id = $2 AND tenant = $3 AND deleted_at IS NULL"
+	res, err := r.db.ExecContext(ctx, q, deletedAt, ID, domain.TenantFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrSoftDeleteDocumentFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrSoftDeleteDocumentFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrSoftDeleteDocumentFailed, port.ErrDocumentNotFound, ID)
+	}
+	return nil
+}
+
+// Restore clears the soft deletion of a document. It returns an error if the document does not exist or is
+// not deleted.
+func (r PostgresDocumentRepository) Restore(ctx context.Context, ID string) (err error) {
+	defer r.ops.Observe(ctx, "restore", time.Now(), &err, "ID", ID)
+	q := "UPDATE documents SET deleted_at = NULL WHERE document_id = $1 AND tenant = $2 AND deleted_at IS NOT NULL"
+	res, err := r.db.ExecContext(ctx, q, ID, domain.TenantFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrRestoreDocumentFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrRestoreDocumentFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrRestoreDocumentFailed, port.ErrDocumentNotFound, ID)
+	}
+	return nil
+}
+
+// UpdateStatus updates a document's analysis status, threat name and date, returning an error for nonexistent
+// documents, invalid status, or update issues.
+func (r PostgresDocumentRepository) UpdateStatus(ctx context.Context, ID string, status domain.AnalysisStatus, threat string, analyzedAt time.Time) (err error) {
+	defer r.ops.Observe(ctx, "update status", time.Now(), &err, "ID", ID, "status", status)
+	q := "UPDATE documents SET status = $1, threat_name = $2, analyzed_at = $3 WHERE document_id = $4 AND tenant = $5"
+	res, err := r.db.ExecContext(ctx, q, status, threat, analyzedAt, ID, domain.TenantFromContext(ctx))
 	if err != nil {
 		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrUpdateStatusFailed, err)
 	}
@@ -128,6 +354,64 @@ func (r PostgresDocumentRepository) UpdateStatus(ctx context.Context, ID string,
 	return nil
 }
 
+// SaveArchiveReport records the verdict on each file of the archive of a document, as JSON.
+func (r PostgresDocumentRepository) SaveArchiveReport(ctx context.Context, ID string, report *domain.ArchiveReport) (err error) {
+	defer r.ops.Observe(ctx, "save archive report", time.Now(), &err, "ID", ID)
+	b, err := json.Marshal(report)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrSaveArchiveReportFailed, err)
+	}
+	q := "UPDATE documents SET archive_report = $1 WHERE document_id = $2 AND tenant = $3"
+	res, err := r.db.ExecContext(ctx, q, string(b), ID, domain.TenantFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrSaveArchiveReportFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrSaveArchiveReportFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: no document found with ID %v", ErrPostgresDocumentRepository, port.ErrSaveArchiveReportFailed, ID)
+	}
+	return nil
+}
+
+// UpdateContent records the hash, its algorithm, the size and the content type of a document which has no hash yet.
+func (r PostgresDocumentRepository) UpdateContent(ctx context.Context, doc *domain.Document) (err error) {
+	defer r.ops.Observe(ctx, "update content", time.Now(), &err, "ID", doc.ID)
+	q := "UPDATE documents SET hash = $1, hash_algo = $2, file_size = $3, content_type = $4 WHERE document_id = $5 AND tenant = $6 AND hash = ''"
+	res, err := r.db.ExecContext(ctx, q, doc.Hash, doc.HashAlgo, doc.Size, doc.ContentType, doc.ID, domain.TenantFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrUpdateContentFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrUpdateContentFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: %w: id=%v", ErrPostgresDocumentRepository, port.ErrUpdateContentFailed, port.ErrDocumentContentRecorded, doc.ID)
+	}
+	return nil
+}
+
+// UpdateBinaryVersion records the version of the binary data of a document.
+func (r PostgresDocumentRepository) UpdateBinaryVersion(ctx context.Context, ID, version string) (err error) {
+	defer r.ops.Observe(ctx, "update binary version", time.Now(), &err, "ID", ID)
+	q := "UPDATE documents SET binary_version = $1 WHERE document_id = $2 AND tenant = $3"
+	res, err := r.db.ExecContext(ctx, q, version, ID, domain.TenantFromContext(ctx))
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrUpdateBinaryVersionFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrUpdateBinaryVersionFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: no document found with ID %v", ErrPostgresDocumentRepository, port.ErrUpdateBinaryVersionFailed, ID)
+	}
+	return nil
+}
+
 // Ping checks the repository's availability or health status.
 func (r PostgresDocumentRepository) Ping() error {
 	if err := r.db.Ping(); err != nil {
@@ -137,22 +421,341 @@ func (r PostgresDocumentRepository) Ping() error {
 }
 
 // Purge removes documents from the repository that were created before the specified date
-// and have a status different from pending status (value = 0).
-func (r PostgresDocumentRepository) Purge(date time.Time) error {
-	q := "DELETE FROM documents WHERE created_at < $1 AND status != $2"
-	if _, err := r.db.Exec(q, date, domain.StatusPending); err != nil {
-		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentRepositoryPurgeFailed, err)
+// and have a status different from pending status (value = 0), restricted to the given statuses if any.
+// It returns the number of documents removed. When the documents table is partitioned, the partitions left empty
+// by the purge are dropped, and the partitions of the next intervals created. The remaining documents are deleted
+// by batches, see WithPurgeBatches, along with the documents soft-deleted before date, pending ones included,
+// restricted to the given statuses as well, then the events and the notifications of the documents no longer existing
+// which occurred before date.
+func (r PostgresDocumentRepository) Purge(date time.Time, statuses ...domain.AnalysisStatus) (int64, error) {
+	values := make([]int64, len(statuses))
+	for i, status := range statuses {
+		values[i] = int64(status)
+	}
+
+	var dropped int64
+	if r.partitions != PartitionNone {
+		ctx := context.Background()
+		var err error
+		if dropped, err = r.dropPartitions(ctx, date, values); err != nil {
+			return dropped, fmt.Errorf("%w: %w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentRepositoryPurgeFailed, ErrPartitioningFailed, err)
+		}
+		if err = r.ensurePartitions(ctx, time.Now()); err != nil {
+			return dropped, fmt.Errorf("%w: %w: %w", ErrPostgresDocumentRepository, port.ErrDocumentRepositoryPurgeFailed, err)
+		}
+	}
+
+	cond := "created_at < $1 AND status != $2"
+	args := []any{date, domain.StatusPending}
+	if len(statuses) > 0 {
+		cond += " AND status = ANY($3)"
+		args = append(args, pq.Array(values))
+	}
+	n, err := r.purgeRows("documents", cond, args...)
+	if err != nil {
+		return dropped + n, err
+	}
+	cond, args = "deleted_at < $1", []any{date}
+	if len(statuses) > 0 {
+		cond += " AND status = ANY($2)"
+		args = append(args, pq.Array(values))
+	}
+	deleted, err := r.purgeRows("documents", cond, args...)
+	if err != nil {
+		return dropped + n + deleted, err
+	}
+	// the events and the pending notifications of the purged documents go with them
+	if _, err = r.purgeRows("document_events", orphanEvents, date); err != nil {
+		return dropped + n + deleted, err
+	}
+	_, err = r.purgeRows("callback_outbox", orphanCallbacks, date)
+	return dropped + n + deleted, err
+}
+
+// purgeRows deletes the rows of table matching the condition cond, by batches of purgeBatchSize rows separated by
+// purgeBatchPause, so that no deletion holds its locks for long, or all at once if purgeBatchSize is zero. It returns
+// the number of rows deleted, by the batches committed before a failure as well.
+func (r PostgresDocumentRepository) purgeRows(table, cond string, args ...any) (int64, error) {
+	q := "DELETE FROM " + table + " WHERE " + cond
+	if r.purgeBatchSize > 0 {
+		q = fmt.Sprintf("DELETE FROM %s WHERE id IN (SELECT id FROM %s WHERE %s LIMIT %d)", table, table, cond, r.purgeBatchSize)
+	}
+
+	var total int64
+	for {
+		res, err := r.db.Exec(q, args...)
+		if err != nil {
+			return total, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentRepositoryPurgeFailed, err)
+		}
+		n, err := res.RowsAffected()
+		if err != nil {
+			return total, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentRepositoryPurgeFailed, err)
+		}
+		total += n
+		if r.purgeBatchSize <= 0 || n < int64(r.purgeBatchSize) {
+			return total, nil
+		}
+		time.Sleep(r.purgeBatchPause)
+	}
+}
+
+// List retrieves the documents of the tenant carried by ctx matching filter, the most recent first, leaving out the
+// soft-deleted ones. The labels are matched by containment, which the GIN index of the labels column serves, and the
+// tag prefix with LIKE, served by the index of the tags with text_pattern_ops. The documents created at the same time
+// are sorted by descending ID, so that the page following the cursor of filter starts with a row comparison rather
+// than an offset, which reads the index of the creation dates from the cursor on.
+func (r PostgresDocumentRepository) List(ctx context.Context, filter domain.DocumentFilter) (_ []*domain.Document, err error) {
+	defer r.ops.Observe(ctx, "list", time.Now(), &err)
+	where, args, err := filterConditions(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	if !filter.After.IsZero() {
+		args = append(args, filter.After.CreatedAt, filter.After.ID)
+		where += fmt.Sprintf(" AND (created_at, document_id) < ($%d, $%d)", len(args)-1, len(args))
+	}
+	q := "SELECT " + documentColumns + " FROM documents WHERE " + where + " ORDER BY created_at DESC, document_id DESC"
+	if filter.Limit > 0 {
+		q += fmt.Sprintf(" LIMIT %d", filter.Limit)
+	}
+	return r.findDocuments(ctx, q, args...)
+}
+
+// Iterate calls fn with each document of the tenant carried by ctx matching filter, the oldest first, leaving out the
+// soft-deleted ones and ignoring the limit and the cursor of filter. The documents are fetched by batches of
+// exportBatchSize rows from a cursor declared in a read-only transaction, so that they are not all held in memory and
+// are read from a single snapshot. The iteration stops at the first error returned by fn, which is returned as is.
+func (r PostgresDocumentRepository) Iterate(ctx context.Context, filter domain.DocumentFilter, fn func(*domain.Document) error) error {
+	where, args, err := filterConditions(ctx, filter)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	tx, err := r.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	// the transaction is read-only, rolling it back releases the cursor as committing it would
+	defer tx.Rollback()
+
+	q := "DECLARE export_cursor NO SCROLL CURSOR FOR SELECT " + documentColumns + " FROM documents WHERE " + where + " ORDER BY created_at"
+	if _, err = tx.ExecContext(ctx, q, args...); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	fetch := fmt.Sprintf("FETCH FORWARD %d FROM export_cursor", exportBatchSize)
+	for {
+		n, err := fetchDocuments(ctx, tx, fetch, fn)
+		if err != nil {
+			return err
+		}
+		if n < exportBatchSize {
+			return nil
+		}
 	}
-	return nil
 }
 
-//go:embed document_table.sql
-var createTableQuery string
+// fetchDocuments calls fn with each document fetched by the query q from a cursor of tx, and returns the number of
+// documents fetched.
+func fetchDocuments(ctx context.Context, tx *sql.Tx, q string, fn func(*domain.Document) error) (int, error) {
+	rows, err := tx.QueryContext(ctx, q)
+	if err != nil {
+		return 0, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	defer rows.Close()
 
-// SetupDocumentTable sets up the 'documents' table in the database.
-// It executes SQL commands to create the table, its indices, and constraints,
-// ensuring that the table is properly initialized for use.
-func SetupDocumentTable(db *sql.DB) error {
-	_, err := db.Exec(createTableQuery)
-	return err
+	n := 0
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return n, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+		}
+		n++
+		if err = fn(doc); err != nil {
+			return n, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return n, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	return n, nil
+}
+
+// filterConditions returns the conditions selecting the documents of the tenant carried by ctx matching filter, which
+// are not soft-deleted, joined by AND, along with their arguments.
+func filterConditions(ctx context.Context, filter domain.DocumentFilter) (string, []any, error) {
+	var (
+		conds = []string{"tenant = $1", "deleted_at IS NULL"}
+		args  = []any{domain.TenantFromContext(ctx)}
+	)
+	where := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+
+	if len(filter.Labels) > 0 {
+		labels, err := marshalLabels(filter.Labels)
+		if err != nil {
+			return "", nil, err
+		}
+		where("labels @> $%d::jsonb", labels)
+	}
+	if len(filter.Statuses) > 0 {
+		statuses := make([]int64, len(filter.Statuses))
+		for i, status := range filter.Statuses {
+			statuses[i] = int64(status)
+		}
+		where("status = ANY($%d)", pq.Array(statuses))
+	}
+	if !filter.CreatedAfter.IsZero() {
+		where("created_at >= $%d", filter.CreatedAfter)
+	}
+	if !filter.CreatedBefore.IsZero() {
+		where("created_at < $%d", filter.CreatedBefore)
+	}
+	if !filter.AnalyzedAfter.IsZero() || !filter.AnalyzedBefore.IsZero() {
+		where("status != $%d", domain.StatusPending)
+	}
+	if !filter.AnalyzedAfter.IsZero() {
+		where("analyzed_at >= $%d", filter.AnalyzedAfter)
+	}
+	if !filter.AnalyzedBefore.IsZero() {
+		where("analyzed_at < $%d", filter.AnalyzedBefore)
+	}
+	if filter.TagPrefix != "" {
+		where("tag LIKE $%d", likePrefix(filter.TagPrefix))
+	}
+	return strings.Join(conds, " AND "), args, nil
+}
+
+// likePrefix returns the LIKE pattern of the strings starting with prefix, whose wildcards are escaped.
+func likePrefix(prefix string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(prefix) + "%"
+}
+
+// FindByStatus retrieves the documents of all the tenants having the given analysis status.
+func (r PostgresDocumentRepository) FindByStatus(ctx context.Context, status domain.AnalysisStatus) ([]*domain.Document, error) {
+	q := "SELECT " + documentColumns + " FROM documents WHERE status = $1"
+	return r.findDocuments(ctx, q, status)
+}
+
+// findDocuments retrieves the documents selected by the query q, selecting documentColumns.
+func (r PostgresDocumentRepository) findDocuments(ctx context.Context, q string, args ...any) ([]*domain.Document, error) {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	defer rows.Close()
+
+	var docs []*domain.Document
+	for rows.Next() {
+		doc, err := scanDocument(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+		}
+		docs = append(docs, doc)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrFindDocumentsFailed, err)
+	}
+	return docs, nil
+}
+
+// marshalLabels returns the JSON object of labels, stored in the labels column, an empty object if there are none.
+func marshalLabels(labels domain.Labels) (string, error) {
+	if len(labels) == 0 {
+		return "{}", nil
+	}
+	b, err := json.Marshal(labels)
+	return string(b), err
+}
+
+// statsQuery aggregates the documents of a tenant in a single scan, leaving out the soft-deleted ones. The scan latency
+// is averaged over the documents with a verdict, leaving out the duplicates which reuse the analysis date of an earlier
+// document.
+const statsQuery = `SELECT
+    COUNT(*) FILTER (WHERE status = $2),
+    COUNT(*) FILTER (WHERE status = $3),
+    COUNT(*) FILTER (WHERE status = $4),
+    COUNT(*) FILTER (WHERE status IN ($6, $7, $8)),
+    COUNT(*) FILTER (WHERE created_at >= $5),
+    COALESCE(AVG(EXTRACT(EPOCH FROM analyzed_at - created_at)) FILTER (WHERE status IN ($3, $4) AND analyzed_at >= created_at), 0)
+FROM documents WHERE tenant = $1 AND deleted_at IS NULL`
+
+// Stats returns aggregate statistics on the documents, counting the documents uploaded since the given date.
+func (r PostgresDocumentRepository) Stats(ctx context.Context, since time.Time) (_ *domain.DocumentStats, err error) {
+	defer r.ops.Observe(ctx, "stats", time.Now(), &err)
+	var (
+		stats   domain.DocumentStats
+		latency float64
+	)
+	err = r.db.QueryRowContext(ctx, statsQuery, domain.TenantFromContext(ctx), domain.StatusPending, domain.StatusInfected, domain.StatusClean, since,
+		domain.StatusTimeout, domain.StatusError, domain.StatusTooLarge).
+		Scan(&stats.Pending, &stats.Infected, &stats.Clean, &stats.Failed, &stats.UploadedSince, &latency)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentStatsFailed, err)
+	}
+	stats.AverageScanLatency = time.Duration(latency * float64(time.Second))
+	return &stats, nil
+}
+
+// summaryQuery counts the documents of all the tenants created in a period by status.
+const summaryQuery = `SELECT
+    COUNT(*),
+    COUNT(*) FILTER (WHERE status = $3),
+    COUNT(*) FILTER (WHERE status = $4),
+    COUNT(*) FILTER (WHERE status = $5),
+    COUNT(*) FILTER (WHERE status IN ($6, $7, $8))
+FROM documents WHERE created_at >= $1 AND created_at < $2`
+
+// Summarize counts the documents of all the tenants created between from, included, and to, excluded, by status,
+// along with the top most frequent threats and the top tenants uploading the most, the ties broken by name.
+func (r PostgresDocumentRepository) Summarize(ctx context.Context, from, to time.Time, top int) (*domain.SummaryReport, error) {
+	report := &domain.SummaryReport{From: from, To: to}
+	err := r.db.QueryRowContext(ctx, summaryQuery, from, to, domain.StatusPending, domain.StatusInfected, domain.StatusClean,
+		domain.StatusTimeout, domain.StatusError, domain.StatusTooLarge).
+		Scan(&report.Total, &report.Pending, &report.Infected, &report.Clean, &report.Failed)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentSummaryFailed, err)
+	}
+
+	q := "SELECT threat_name, COUNT(*) FROM documents WHERE created_at >= $1 AND created_at < $2 AND status = $3 " +
+		"GROUP BY threat_name ORDER BY 2 DESC, 1 LIMIT $4"
+	err = r.scanCounts(ctx, q, func(name string, n int64) {
+		report.TopThreats = append(report.TopThreats, domain.ThreatCount{Name: name, Count: n})
+	}, from, to, domain.StatusInfected, top)
+	if err != nil {
+		return nil, err
+	}
+
+	q = "SELECT tenant, COUNT(*) FROM documents WHERE created_at >= $1 AND created_at < $2 GROUP BY tenant ORDER BY 2 DESC, 1 LIMIT $3"
+	err = r.scanCounts(ctx, q, func(tenant string, n int64) {
+		report.BusiestTenants = append(report.BusiestTenants, domain.TenantCount{Tenant: tenant, Uploads: n})
+	}, from, to, top)
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// scanCounts calls add with each name and count selected by the query q.
+func (r PostgresDocumentRepository) scanCounts(ctx context.Context, q string, add func(string, int64), args ...any) error {
+	rows, err := r.db.QueryContext(ctx, q, args...)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentSummaryFailed, err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var (
+			name string
+			n    int64
+		)
+		if err = rows.Scan(&name, &n); err != nil {
+			return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentSummaryFailed, err)
+		}
+		add(name, n)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrDocumentSummaryFailed, err)
+	}
+	return nil
 }
@@ -6,14 +6,21 @@ import (
 	"errors"
 	"fmt"
 	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
 	"testing"
 	"time"
 
 	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/lib/pq"
 	"github.com/stretchr/testify/assert"
 )
 
 func TestNewPotgresDocumentRepository(t *testing.T) {
+	latest, err := LatestSchemaVersion()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
 	t.Run("SuccessfulCreation", func(t *testing.T) {
 		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
 		if err != nil {
@@ -22,7 +29,8 @@ func TestNewPotgresDocumentRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPing().WillReturnError(nil)
-		mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM schema_version").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(latest))
 
 		repo, err := NewPotgres(db)
 		assert.NoError(t, err)
@@ -59,7 +67,7 @@ func TestNewPotgresDocumentRepository(t *testing.T) {
 		}
 	})
 
-	t.Run("DocumentTableSetupFailure", func(t *testing.T) {
+	t.Run("SchemaOutdated", func(t *testing.T) {
 		db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
 		if err != nil {
 			t.Fatalf("error creating sqlmock: %v", err)
@@ -67,10 +75,11 @@ func TestNewPotgresDocumentRepository(t *testing.T) {
 		defer db.Close()
 
 		mock.ExpectPing().WillReturnError(nil)
-		mock.ExpectExec("CREATE TABLE IF NOT EXISTS").WillReturnError(fmt.Errorf("error setting up document table"))
+		mock.ExpectQuery("SELECT COALESCE\\(MAX\\(version\\), 0\\) FROM schema_version").
+			WillReturnRows(sqlmock.NewRows([]string{"version"}).AddRow(latest - 1))
 
 		repo, err := NewPotgres(db)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, ErrSchemaOutdated)
 		assert.Nil(t, repo)
 
 		if err := mock.ExpectationsWereMet(); err != nil {
@@ -95,11 +104,12 @@ func TestSave(t *testing.T) {
 		Status:     1,
 		AnalyzedAt: time.Now(),
 		CreatedAt:  time.Now(),
+		Source:     domain.SourceUpload,
 	}
 
 	t.Run("SuccessfulSave", func(t *testing.T) {
 		mock.ExpectExec("INSERT INTO documents").
-			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt).
+			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt, doc.Tenant, doc.Source, doc.Origin, doc.Sealed, domain.DefaultHashAlgo, doc.FileName, doc.Size, doc.ContentType, "", doc.Threat, "{}", "", "").
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.Save(context.Background(), doc)
@@ -108,16 +118,16 @@ func TestSave(t *testing.T) {
 
 	t.Run("SaveWithAlreadyExistingDocument", func(t *testing.T) {
 		mock.ExpectExec("INSERT INTO documents").
-			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt).
-			WillReturnError(sql.ErrNoRows) // Simulating a unique constraint violation
+			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt, doc.Tenant, doc.Source, doc.Origin, doc.Sealed, domain.DefaultHashAlgo, doc.FileName, doc.Size, doc.ContentType, "", doc.Threat, "{}", "", "").
+			WillReturnError(&pq.Error{Code: "23505", Message: "duplicate key value violates unique constraint"})
 
 		err := repo.Save(context.Background(), doc)
-		assert.Error(t, err)
+		assert.ErrorIs(t, err, port.ErrDocumentAlreadyExists)
 	})
 
 	t.Run("DatabaseErrorOnSave", func(t *testing.T) {
 		mock.ExpectExec("INSERT INTO documents").
-			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt).
+			WithArgs(doc.ID, doc.Hash, doc.Tag, doc.Status, doc.AnalyzedAt, doc.CreatedAt, doc.Tenant, doc.Source, doc.Origin, doc.Sealed, domain.DefaultHashAlgo, doc.FileName, doc.Size, doc.ContentType, "", doc.Threat, "{}", "", "").
 			WillReturnError(sql.ErrConnDone) // Simulating a database connection error
 
 		err := repo.Save(context.Background(), doc)
@@ -140,23 +150,32 @@ func TestGet(t *testing.T) {
 
 	t.Run("DocumentFound", func(t *testing.T) {
 		docID := "123"
-		rows := sqlmock.NewRows([]string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at"}).
-			AddRow(docID, "hash123", "tag1", 1, time.Now(), time.Now())
+		rows := sqlmock.NewRows([]string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at", "tenant", "source", "origin", "sealed", "hash_algo", "file_name", "file_size", "content_type", "archive_report", "deleted_at", "threat_name", "labels", "engines", "binary_version"}).
+			AddRow(docID, "hash123", "tag1", 1, time.Now(), time.Now(), "", domain.SourceUpload, "", "", "SHA-512", "report.pdf", 1024, "application/pdf", `{"status":"clean","files":1,"entries":[{"path":"a.txt","status":"clean"}]}`, nil, "", `{"team":"payments"}`, "clamav,yara", "\"9b2cf535f27731c974343645a3985328\"")
 
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE document_id =").
-			WithArgs(docID).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE document_id =").
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnRows(rows)
 
 		doc, err := repo.Get(context.Background(), docID)
 		assert.NoError(t, err)
 		assert.NotNil(t, doc)
 		assert.Equal(t, docID, doc.ID)
+		assert.Equal(t, "SHA-512", doc.HashAlgo)
+		assert.Equal(t, "report.pdf", doc.FileName)
+		assert.Equal(t, int64(1024), doc.Size)
+		assert.Equal(t, domain.Labels{"team": "payments"}, doc.Labels)
+		assert.Equal(t, []string{"clamav", "yara"}, doc.Engines)
+		assert.Equal(t, `"9b2cf535f27731c974343645a3985328"`, doc.BinaryVersion)
+		if assert.NotNil(t, doc.Archive) {
+			assert.Equal(t, []domain.ArchiveEntry{{Path: "a.txt", Status: "clean"}}, doc.Archive.Entries)
+		}
 	})
 
 	t.Run("DocumentNotFound", func(t *testing.T) {
 		docID := "unknown"
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE document_id =").
-			WithArgs(docID).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE document_id =").
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnError(sql.ErrNoRows)
 
 		doc, err := repo.Get(context.Background(), docID)
@@ -164,10 +183,22 @@ func TestGet(t *testing.T) {
 		assert.Nil(t, doc)
 	})
 
+	t.Run("DocumentOfAnotherTenant", func(t *testing.T) {
+		docID := "123"
+		ctx := domain.ContextWithTenant(context.Background(), "bu-a")
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE document_id = .+ AND tenant = .+").
+			WithArgs(docID, "bu-a").
+			WillReturnError(sql.ErrNoRows)
+
+		doc, err := repo.Get(ctx, docID)
+		assert.ErrorIs(t, err, port.ErrDocumentNotFound)
+		assert.Nil(t, doc)
+	})
+
 	t.Run("DatabaseError", func(t *testing.T) {
 		docID := "error"
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE document_id =").
-			WithArgs(docID).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE document_id =").
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnError(sql.ErrConnDone)
 
 		doc, err := repo.Get(context.Background(), docID)
@@ -191,23 +222,24 @@ func TestGetByHash(t *testing.T) {
 
 	t.Run("DocumentFound", func(t *testing.T) {
 		docHash := "hash123"
-		rows := sqlmock.NewRows([]string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at"}).
-			AddRow("123", docHash, "tag1", 1, time.Now(), time.Now())
+		rows := sqlmock.NewRows([]string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at", "tenant", "source", "origin", "sealed", "hash_algo", "file_name", "file_size", "content_type", "archive_report", "deleted_at", "threat_name", "labels", "engines", "binary_version"}).
+			AddRow("123", docHash, "tag1", 1, time.Now(), time.Now(), "", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "Win.Test.EICAR_HDB-1", "{}", "", "")
 
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE hash =").
-			WithArgs(docHash).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE hash =").
+			WithArgs(docHash, domain.DefaultTenant).
 			WillReturnRows(rows)
 
 		doc, err := repo.GetByHash(context.Background(), docHash)
 		assert.NoError(t, err)
 		assert.NotNil(t, doc)
 		assert.Equal(t, docHash, doc.Hash)
+		assert.Equal(t, "Win.Test.EICAR_HDB-1", doc.Threat)
 	})
 
 	t.Run("DocumentNotFound", func(t *testing.T) {
 		docHash := "unknownhash"
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE hash =").
-			WithArgs(docHash).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE hash =").
+			WithArgs(docHash, domain.DefaultTenant).
 			WillReturnError(sql.ErrNoRows)
 
 		doc, err := repo.GetByHash(context.Background(), docHash)
@@ -217,8 +249,8 @@ func TestGetByHash(t *testing.T) {
 
 	t.Run("DatabaseError", func(t *testing.T) {
 		docHash := "errorhash"
-		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at FROM documents WHERE hash =").
-			WithArgs(docHash).
+		mock.ExpectQuery("SELECT document_id, hash, tag, status, analyzed_at, created_at, tenant, source, origin, sealed, hash_algo, file_name, file_size, content_type, archive_report, deleted_at, threat_name, labels, engines, binary_version FROM documents WHERE hash =").
+			WithArgs(docHash, domain.DefaultTenant).
 			WillReturnError(sql.ErrConnDone)
 
 		doc, err := repo.GetByHash(context.Background(), docHash)
@@ -244,7 +276,7 @@ func TestDelete(t *testing.T) {
 	t.Run("SuccessfulDeletion", func(t *testing.T) {
 		docID := "123"
 		mock.ExpectExec("DELETE FROM documents WHERE document_id =").
-			WithArgs(docID).
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
 		err := repo.Delete(context.Background(), docID)
@@ -255,7 +287,7 @@ func TestDelete(t *testing.T) {
 	t.Run("NonExistentDocument", func(t *testing.T) {
 		docID := "nonexistent"
 		mock.ExpectExec("DELETE FROM documents WHERE document_id =").
-			WithArgs(docID).
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnResult(sqlmock.NewResult(0, 0)) // No rows affected
 
 		err := repo.Delete(context.Background(), docID)
@@ -267,7 +299,7 @@ func TestDelete(t *testing.T) {
 	t.Run("DatabaseError", func(t *testing.T) {
 		docID := "dbError"
 		mock.ExpectExec("DELETE FROM documents WHERE document_id =").
-			WithArgs(docID).
+			WithArgs(docID, domain.DefaultTenant).
 			WillReturnError(errors.New("database error"))
 
 		err := repo.Delete(context.Background(), docID)
@@ -294,11 +326,11 @@ func TestUpdateStatus(t *testing.T) {
 		newStatus := domain.StatusClean
 		analyzedAt := time.Now()
 
-		mock.ExpectExec("UPDATE documents SET status = .+, analyzed_at = .+ WHERE document_id = .+").
-			WithArgs(newStatus, sqlmock.AnyArg(), docID).
+		mock.ExpectExec("UPDATE documents SET status = .+, threat_name = .+, analyzed_at = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs(newStatus, "", sqlmock.AnyArg(), docID, domain.DefaultTenant).
 			WillReturnResult(sqlmock.NewResult(1, 1))
 
-		err := repo.UpdateStatus(context.Background(), docID, newStatus, analyzedAt)
+		err := repo.UpdateStatus(context.Background(), docID, newStatus, "", analyzedAt)
 		assert.NoError(t, err)
 	})
 
@@ -308,11 +340,11 @@ func TestUpdateStatus(t *testing.T) {
 		newStatus := domain.StatusClean
 		analyzedAt := time.Now()
 
-		mock.ExpectExec("UPDATE documents SET status = .+, analyzed_at = .+ WHERE document_id = .+").
-			WithArgs(newStatus, sqlmock.AnyArg(), docID).
+		mock.ExpectExec("UPDATE documents SET status = .+, threat_name = .+, analyzed_at = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs(newStatus, "", sqlmock.AnyArg(), docID, domain.DefaultTenant).
 			WillReturnResult(sqlmock.NewResult(0, 0)) // No rows affected
 
-		err := repo.UpdateStatus(context.Background(), docID, newStatus, analyzedAt)
+		err := repo.UpdateStatus(context.Background(), docID, newStatus, "", analyzedAt)
 		assert.Error(t, err)
 	})
 
@@ -322,11 +354,11 @@ func TestUpdateStatus(t *testing.T) {
 		newStatus := domain.AnalysisStatus(2)
 		analyzedAt := time.Now()
 
-		mock.ExpectExec("UPDATE documents SET status = .+, analyzed_at = .+ WHERE document_id = .+").
-			WithArgs(newStatus, sqlmock.AnyArg(), docID).
+		mock.ExpectExec("UPDATE documents SET status = .+, threat_name = .+, analyzed_at = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs(newStatus, "", sqlmock.AnyArg(), docID, domain.DefaultTenant).
 			WillReturnError(sql.ErrConnDone) // Simulating a database error
 
-		err := repo.UpdateStatus(context.Background(), docID, newStatus, analyzedAt)
+		err := repo.UpdateStatus(context.Background(), docID, newStatus, "", analyzedAt)
 		assert.Error(t, err)
 	})
 
@@ -336,6 +368,159 @@ func TestUpdateStatus(t *testing.T) {
 	}
 }
 
+func TestSoftDelete(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	deletedAt := time.Now()
+
+	// Scenario: Successfully soft-deleting a document
+	t.Run("Deleted", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET deleted_at = \\$1 WHERE document_id = \\$2 AND tenant = \\$3 AND deleted_at IS NULL").
+			WithArgs(deletedAt, "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.SoftDelete(context.Background(), "123", deletedAt))
+	})
+
+	// Scenario: Soft-deleting a document which does not exist or is deleted already
+	t.Run("DocumentNotFound", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET deleted_at").
+			WithArgs(deletedAt, "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SoftDelete(context.Background(), "123", deletedAt)
+		assert.ErrorIs(t, err, port.ErrDocumentNotFound)
+	})
+
+	// Scenario: Successfully restoring a document
+	t.Run("Restored", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET deleted_at = NULL WHERE document_id = \\$1 AND tenant = \\$2 AND deleted_at IS NOT NULL").
+			WithArgs("123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Restore(context.Background(), "123"))
+	})
+
+	// Scenario: Restoring a document which is not deleted
+	t.Run("NotDeleted", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET deleted_at = NULL").
+			WithArgs("123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Restore(context.Background(), "123")
+		assert.ErrorIs(t, err, port.ErrDocumentNotFound)
+	})
+
+	// Ensure all expectations were met
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestSaveArchiveReport(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	report := &domain.ArchiveReport{Status: "infected", Files: 1, Entries: []domain.ArchiveEntry{{Path: "eicar.com", Status: "infected"}}}
+
+	t.Run("ReportSaved", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET archive_report = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs(`{"status":"infected","files":1,"entries":[{"path":"eicar.com","status":"infected"}]}`, "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.SaveArchiveReport(context.Background(), "123", report)
+		assert.NoError(t, err)
+	})
+
+	t.Run("DocumentNotFound", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET archive_report = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs(sqlmock.AnyArg(), "nonexistent", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.SaveArchiveReport(context.Background(), "nonexistent", report)
+		assert.ErrorIs(t, err, port.ErrSaveArchiveReportFailed)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateContent(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	doc := &domain.Document{ID: "123", Hash: "abc", HashAlgo: "SHA-256", Size: 42, ContentType: "application/pdf"}
+
+	t.Run("ContentUpdated", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET hash = .+, hash_algo = .+, file_size = .+, content_type = .+ WHERE document_id = .+ AND tenant = .+ AND hash = ''").
+			WithArgs("abc", "SHA-256", int64(42), "application/pdf", "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.UpdateContent(context.Background(), doc)
+		assert.NoError(t, err)
+	})
+
+	t.Run("ContentAlreadyRecorded", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET hash = .+ WHERE document_id = .+ AND tenant = .+ AND hash = ''").
+			WithArgs("abc", "SHA-256", int64(42), "application/pdf", "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateContent(context.Background(), doc)
+		assert.ErrorIs(t, err, port.ErrUpdateContentFailed)
+		assert.ErrorIs(t, err, port.ErrDocumentContentRecorded)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestUpdateBinaryVersion(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+
+	t.Run("VersionUpdated", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET binary_version = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs("etag", "123", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+
+		err := repo.UpdateBinaryVersion(context.Background(), "123", "etag")
+		assert.NoError(t, err)
+	})
+
+	t.Run("DocumentNotFound", func(t *testing.T) {
+		mock.ExpectExec("UPDATE documents SET binary_version = .+ WHERE document_id = .+ AND tenant = .+").
+			WithArgs("etag", "nonexistent", domain.DefaultTenant).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.UpdateBinaryVersion(context.Background(), "nonexistent", "etag")
+		assert.ErrorIs(t, err, port.ErrUpdateBinaryVersionFailed)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestPurge(t *testing.T) {
 	db, mock, err := sqlmock.New()
 	if err != nil {
@@ -346,14 +531,44 @@ func TestPurge(t *testing.T) {
 	repo := &PostgresDocumentRepository{db: db}
 	purgeTime := time.Now().Add(-24 * time.Hour) // Purging documents older than 24 hours
 
-	// Scenario: Successfully purging the documents
+	// Scenario: Successfully purging the documents, the expired and the soft-deleted ones
 	t.Run("SuccessfulPurge", func(t *testing.T) {
 		mock.ExpectExec("DELETE FROM documents WHERE created_at < \\$1 AND status != \\$2").
 			WithArgs(purgeTime, domain.StatusPending).
 			WillReturnResult(sqlmock.NewResult(0, 1)) // Simulating one row affected
+		mock.ExpectExec("DELETE FROM documents WHERE deleted_at < \\$1").
+			WithArgs(purgeTime).
+			WillReturnResult(sqlmock.NewResult(0, 3))
+		mock.ExpectExec("DELETE FROM document_events WHERE occurred_at < \\$1 AND NOT EXISTS").
+			WithArgs(purgeTime).
+			WillReturnResult(sqlmock.NewResult(0, 7)) // the events do not count
+		mock.ExpectExec("DELETE FROM callback_outbox WHERE created_at < \\$1 AND NOT EXISTS").
+			WithArgs(purgeTime).
+			WillReturnResult(sqlmock.NewResult(0, 1)) // nor do the notifications
+
+		n, err := repo.Purge(purgeTime)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), n)
+	})
 
-		err := repo.Purge(purgeTime)
+	// Scenario: Successfully purging the documents having some statuses
+	t.Run("SuccessfulPurgeByStatus", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM documents WHERE created_at < \\$1 AND status != \\$2 AND status = ANY\\(\\$3\\)").
+			WithArgs(purgeTime, domain.StatusPending, pq.Array([]int64{int64(domain.StatusInfected)})).
+			WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec("DELETE FROM documents WHERE deleted_at < \\$1 AND status = ANY\\(\\$2\\)").
+			WithArgs(purgeTime, pq.Array([]int64{int64(domain.StatusInfected)})).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM document_events WHERE occurred_at < \\$1").
+			WithArgs(purgeTime).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM callback_outbox WHERE created_at < \\$1").
+			WithArgs(purgeTime).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		n, err := repo.Purge(purgeTime, domain.StatusInfected)
 		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
 	})
 
 	// Scenario: Encountering a database error during purge
@@ -362,8 +577,41 @@ func TestPurge(t *testing.T) {
 			WithArgs(purgeTime, domain.StatusPending).
 			WillReturnError(sql.ErrConnDone) // Simulating a database error
 
-		err := repo.Purge(purgeTime)
+		_, err := repo.Purge(purgeTime)
+		assert.Error(t, err)
+	})
+
+	// Scenario: Purging the documents by batches until a batch is not full
+	t.Run("SuccessfulPurgeByBatches", func(t *testing.T) {
+		repo := &PostgresDocumentRepository{db: db}
+		WithPurgeBatches(2, time.Millisecond)(repo)
+		q := "DELETE FROM documents WHERE id IN \\(SELECT id FROM documents WHERE created_at < \\$1 AND status != \\$2 LIMIT 2\\)"
+		mock.ExpectExec(q).WithArgs(purgeTime, domain.StatusPending).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(q).WithArgs(purgeTime, domain.StatusPending).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(q).WithArgs(purgeTime, domain.StatusPending).WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM documents WHERE id IN \\(SELECT id FROM documents WHERE deleted_at < \\$1 LIMIT 2\\)").
+			WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM document_events WHERE id IN \\(SELECT id FROM document_events WHERE occurred_at < \\$1 AND NOT EXISTS .* LIMIT 2\\)").
+			WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("DELETE FROM callback_outbox WHERE id IN \\(SELECT id FROM callback_outbox WHERE created_at < \\$1 AND NOT EXISTS .* LIMIT 2\\)").
+			WithArgs(purgeTime).WillReturnResult(sqlmock.NewResult(0, 0))
+
+		n, err := repo.Purge(purgeTime)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(5), n)
+	})
+
+	// Scenario: Reporting the documents deleted by the batches committed before an error
+	t.Run("BatchError", func(t *testing.T) {
+		repo := &PostgresDocumentRepository{db: db}
+		WithPurgeBatches(2, 0)(repo)
+		q := "DELETE FROM documents WHERE id IN"
+		mock.ExpectExec(q).WithArgs(purgeTime, domain.StatusPending).WillReturnResult(sqlmock.NewResult(0, 2))
+		mock.ExpectExec(q).WithArgs(purgeTime, domain.StatusPending).WillReturnError(sql.ErrConnDone)
+
+		n, err := repo.Purge(purgeTime)
 		assert.Error(t, err)
+		assert.Equal(t, int64(2), n)
 	})
 
 	// Ensure all expectations were met
@@ -372,6 +620,460 @@ func TestPurge(t *testing.T) {
 	}
 }
 
+func TestEvents(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	ctx := domain.ContextWithTenant(context.Background(), "bu-a")
+	columns := []string{"id", "document_id", "tenant", "type", "status", "threat_name", "hash", "occurred_at"}
+	now := time.Now()
+
+	// Scenario: Successfully retrieving the events of a document of the tenant, the oldest first
+	t.Run("Success", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow(3, "ID1", "bu-a", "created", domain.StatusPending, "", "hash1", now).
+			AddRow(8, "ID1", "bu-a", "status_changed", domain.StatusInfected, "Win.Test.EICAR_HDB-1", "hash1", now)
+		mock.ExpectQuery("SELECT (.+) FROM document_events WHERE tenant = \\$1 AND document_id = \\$2 ORDER BY id").
+			WithArgs("bu-a", "ID1").
+			WillReturnRows(rows)
+
+		events, err := repo.Events(ctx, "ID1")
+		assert.NoError(t, err)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, domain.EventCreated, events[0].Type)
+			assert.Equal(t, int64(8), events[1].Seq)
+			assert.Equal(t, domain.EventStatusChanged, events[1].Type)
+			assert.Equal(t, domain.StatusInfected, events[1].Status)
+			assert.Equal(t, "Win.Test.EICAR_HDB-1", events[1].Threat)
+		}
+	})
+
+	// Scenario: Encountering a database error
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM document_events").
+			WithArgs("bu-a", "ID1").
+			WillReturnError(sql.ErrConnDone)
+
+		events, err := repo.Events(ctx, "ID1")
+		assert.ErrorIs(t, err, port.ErrDocumentEventsFailed)
+		assert.Nil(t, events)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestCallbackOutbox(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	ctx := domain.ContextWithTenant(context.Background(), "bu-a")
+
+	// Scenario: Adding a notification of a pending document, made due later by the trigger
+	t.Run("AddPending", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM documents WHERE document_id = \\$1 AND tenant = \\$2 FOR SHARE").
+			WithArgs("ID1", "bu-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.StatusPending))
+		mock.ExpectExec("INSERT INTO callback_outbox").
+			WithArgs("ID1", "bu-a", "https://example.com/hook", false).
+			WillReturnResult(sqlmock.NewResult(1, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.AddCallback(ctx, "ID1", "https://example.com/hook"))
+	})
+
+	// Scenario: Adding a notification of an analyzed document, due at once
+	t.Run("AddAnalyzed", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM documents").
+			WithArgs("ID2", "bu-a").
+			WillReturnRows(sqlmock.NewRows([]string{"status"}).AddRow(domain.StatusClean))
+		mock.ExpectExec("INSERT INTO callback_outbox").
+			WithArgs("ID2", "bu-a", "https://example.com/hook", true).
+			WillReturnResult(sqlmock.NewResult(2, 1))
+		mock.ExpectCommit()
+
+		assert.NoError(t, repo.AddCallback(ctx, "ID2", "https://example.com/hook"))
+	})
+
+	// Scenario: Adding a notification of an unknown document
+	t.Run("AddNotFound", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectQuery("SELECT status FROM documents").
+			WithArgs("ID3", "bu-a").
+			WillReturnError(sql.ErrNoRows)
+		mock.ExpectRollback()
+
+		err := repo.AddCallback(ctx, "ID3", "https://example.com/hook")
+		assert.ErrorIs(t, err, port.ErrCallbackOutboxFailed)
+		assert.ErrorIs(t, err, port.ErrDocumentNotFound)
+	})
+
+	// Scenario: Claiming the due notifications, sorted by id
+	t.Run("Claim", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"id", "document_id", "tenant", "callback_url", "attempts"}).
+			AddRow(7, "ID2", "bu-a", "https://example.com/hook", 2).
+			AddRow(4, "ID1", "bu-b", "https://example.com/other", 1)
+		mock.ExpectQuery("UPDATE callback_outbox SET attempts = attempts \\+ 1").
+			WithArgs(10, float64(30)).
+			WillReturnRows(rows)
+
+		deliveries, err := repo.ClaimCallbacks(context.Background(), 10, 30*time.Second)
+		assert.NoError(t, err)
+		if assert.Len(t, deliveries, 2) {
+			assert.Equal(t, domain.CallbackDelivery{ID: 4, DocumentID: "ID1", Tenant: "bu-b", CallbackURL: "https://example.com/other", Attempts: 1}, deliveries[0])
+			assert.Equal(t, int64(7), deliveries[1].ID)
+		}
+	})
+
+	// Scenario: Removing a delivered notification
+	t.Run("Remove", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM callback_outbox WHERE id = \\$1").
+			WithArgs(4).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.RemoveCallback(context.Background(), 4))
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestWebhooks(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	columns := []string{"webhook_id", "url", "events", "secret", "active", "created_at", "updated_at"}
+	now := time.Now().UTC()
+	w := &domain.Webhook{
+		ID:        "WH1",
+		URL:       "https://example.com/hook",
+		Events:    []domain.WebhookEvent{domain.WebhookEventAlert, domain.WebhookEventReport},
+		Active:    true,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	// Scenario: Saving a new webhook
+	t.Run("Save", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO webhooks").
+			WithArgs("WH1", "https://example.com/hook", pq.Array([]string{"alert", "report"}), "", true, now, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.SaveWebhook(context.Background(), w))
+	})
+
+	// Scenario: Retrieving a webhook
+	t.Run("Get", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE webhook_id = \\$1").
+			WithArgs("WH1").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("WH1", "https://example.com/hook", "{alert,report}", "", true, now, now))
+
+		got, err := repo.GetWebhook(context.Background(), "WH1")
+		assert.NoError(t, err)
+		assert.Equal(t, w, got)
+	})
+
+	// Scenario: Retrieving an unknown webhook
+	t.Run("GetNotFound", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM webhooks WHERE webhook_id = \\$1").
+			WithArgs("WH2").
+			WillReturnError(sql.ErrNoRows)
+
+		_, err := repo.GetWebhook(context.Background(), "WH2")
+		assert.ErrorIs(t, err, port.ErrWebhookNotFound)
+	})
+
+	// Scenario: Listing the webhooks, the oldest first
+	t.Run("List", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM webhooks ORDER BY created_at, webhook_id").
+			WillReturnRows(sqlmock.NewRows(columns).
+				AddRow("WH1", "https://example.com/hook", "{alert,report}", "", true, now, now).
+				AddRow("WH3", "https://example.com/report", "{report}", "secret", false, now, now))
+
+		webhooks, err := repo.ListWebhooks(context.Background())
+		assert.NoError(t, err)
+		if assert.Len(t, webhooks, 2) {
+			assert.Equal(t, []domain.WebhookEvent{domain.WebhookEventReport}, webhooks[1].Events)
+			assert.False(t, webhooks[1].Active)
+		}
+	})
+
+	// Scenario: Updating a webhook, and an unknown one
+	t.Run("Update", func(t *testing.T) {
+		mock.ExpectExec("UPDATE webhooks SET url = \\$2, events = \\$3, secret = \\$4, active = \\$5, updated_at = \\$6 WHERE webhook_id = \\$1").
+			WithArgs("WH1", "https://example.com/hook", pq.Array([]string{"alert", "report"}), "", true, now).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("UPDATE webhooks").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, repo.UpdateWebhook(context.Background(), w))
+		assert.ErrorIs(t, repo.UpdateWebhook(context.Background(), w), port.ErrWebhookNotFound)
+	})
+
+	// Scenario: Deleting a webhook, and an unknown one
+	t.Run("Delete", func(t *testing.T) {
+		mock.ExpectExec("DELETE FROM webhooks WHERE webhook_id = \\$1").
+			WithArgs("WH1").
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("DELETE FROM webhooks WHERE webhook_id = \\$1").
+			WithArgs("WH1").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		assert.NoError(t, repo.DeleteWebhook(context.Background(), "WH1"))
+		assert.ErrorIs(t, repo.DeleteWebhook(context.Background(), "WH1"), port.ErrWebhookNotFound)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestFindByStatus(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	columns := []string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at", "tenant", "source", "origin", "sealed", "hash_algo", "file_name", "file_size", "content_type", "archive_report", "deleted_at", "threat_name", "labels", "engines", "binary_version"}
+	now := time.Now()
+
+	// Scenario: Successfully retrieving the pending documents of all the tenants
+	t.Run("Success", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow("ID1", "hash1", "tag1", domain.StatusPending, time.Time{}, now, "", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "", "{}", "", "").
+			AddRow("ID2", "hash2", "tag2", domain.StatusPending, time.Time{}, now, "bu-a", domain.SourceOnAccess, "web-01:/srv/a.php", "", "SHA-256", "a.php", 0, "", "", now, "", "{}", "", "")
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE status = \\$1").
+			WithArgs(domain.StatusPending).
+			WillReturnRows(rows)
+
+		docs, err := repo.FindByStatus(context.Background(), domain.StatusPending)
+		assert.NoError(t, err)
+		if assert.Len(t, docs, 2) {
+			assert.Equal(t, "ID1", docs[0].ID)
+			assert.Equal(t, "bu-a", docs[1].Tenant)
+		}
+	})
+
+	// Scenario: Encountering a database error
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE status = \\$1").
+			WithArgs(domain.StatusPending).
+			WillReturnError(sql.ErrConnDone)
+
+		docs, err := repo.FindByStatus(context.Background(), domain.StatusPending)
+		assert.ErrorIs(t, err, port.ErrFindDocumentsFailed)
+		assert.Nil(t, docs)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestList(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	columns := []string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at", "tenant", "source", "origin", "sealed", "hash_algo", "file_name", "file_size", "content_type", "archive_report", "deleted_at", "threat_name", "labels", "engines", "binary_version"}
+	ctx := domain.ContextWithTenant(context.Background(), "bu-a")
+	now := time.Now()
+
+	// Scenario: Listing the documents of a tenant carrying some labels
+	t.Run("ByLabels", func(t *testing.T) {
+		rows := sqlmock.NewRows(columns).
+			AddRow("ID1", "hash1", "tag1", domain.StatusClean, now, now, "bu-a", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "", `{"env":"prod","team":"payments"}`, "", "")
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL AND labels @> \\$2::jsonb ORDER BY created_at DESC, document_id DESC LIMIT 10").
+			WithArgs("bu-a", `{"team":"payments"}`).
+			WillReturnRows(rows)
+
+		docs, err := repo.List(ctx, domain.DocumentFilter{Labels: domain.Labels{"team": "payments"}, Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, docs, 1) {
+			assert.Equal(t, domain.Labels{"env": "prod", "team": "payments"}, docs[0].Labels)
+		}
+	})
+
+	// Scenario: Listing the documents of a tenant by status, dates and tag prefix
+	t.Run("ByStatusDatesAndTag", func(t *testing.T) {
+		since := now.Add(-24 * time.Hour)
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL AND status = ANY\\(\\$2\\) AND created_at >= \\$3 AND created_at < \\$4 "+
+			"AND status != \\$5 AND analyzed_at >= \\$6 AND tag LIKE \\$7 ORDER BY created_at DESC, document_id DESC$").
+			WithArgs("bu-a", pq.Array([]int64{int64(domain.StatusInfected), int64(domain.StatusClean)}), since, now, domain.StatusPending, since, `inv\_2024\%%`).
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		docs, err := repo.List(ctx, domain.DocumentFilter{
+			Statuses:      []domain.AnalysisStatus{domain.StatusInfected, domain.StatusClean},
+			CreatedAfter:  since,
+			CreatedBefore: now,
+			AnalyzedAfter: since,
+			TagPrefix:     "inv_2024%",
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, docs)
+	})
+
+	// Scenario: Listing all the documents of a tenant
+	t.Run("Unfiltered", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL ORDER BY created_at DESC, document_id DESC$").
+			WithArgs("bu-a").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		docs, err := repo.List(ctx, domain.DocumentFilter{})
+		assert.NoError(t, err)
+		assert.Empty(t, docs)
+	})
+
+	// Scenario: Listing the page of the documents of a tenant following a cursor
+	t.Run("AfterCursor", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL AND status = ANY\\(\\$2\\) "+
+			"AND \\(created_at, document_id\\) < \\(\\$3, \\$4\\) ORDER BY created_at DESC, document_id DESC LIMIT 10").
+			WithArgs("bu-a", pq.Array([]int64{int64(domain.StatusClean)}), now, "ID1").
+			WillReturnRows(sqlmock.NewRows(columns))
+
+		docs, err := repo.List(ctx, domain.DocumentFilter{
+			Statuses: []domain.AnalysisStatus{domain.StatusClean},
+			Limit:    10,
+			After:    domain.DocumentCursor{CreatedAt: now, ID: "ID1"},
+		})
+		assert.NoError(t, err)
+		assert.Empty(t, docs)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestIterate(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	columns := []string{"document_id", "hash", "tag", "status", "analyzed_at", "created_at", "tenant", "source", "origin", "sealed", "hash_algo", "file_name", "file_size", "content_type", "archive_report", "deleted_at", "threat_name", "labels", "engines", "binary_version"}
+	ctx := domain.ContextWithTenant(context.Background(), "bu-a")
+	now := time.Now()
+	since := now.Add(-24 * time.Hour)
+
+	// Scenario: Iterating over the documents of a tenant fetched by batches from a cursor
+	t.Run("Success", func(t *testing.T) {
+		full := sqlmock.NewRows(columns)
+		for i := 0; i < exportBatchSize; i++ {
+			full.AddRow(fmt.Sprintf("ID%d", i), "hash", "tag", domain.StatusClean, now, now, "bu-a", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "", "{}", "", "")
+		}
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE export_cursor NO SCROLL CURSOR FOR SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL AND created_at >= \\$2 ORDER BY created_at$").
+			WithArgs("bu-a", since).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FETCH FORWARD 1000 FROM export_cursor").WillReturnRows(full)
+		mock.ExpectQuery("FETCH FORWARD 1000 FROM export_cursor").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("last", "hash", "tag", domain.StatusPending, now, now, "bu-a", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "", "{}", "", ""))
+		mock.ExpectRollback()
+
+		var n int
+		err := repo.Iterate(ctx, domain.DocumentFilter{CreatedAfter: since, Limit: 10}, func(doc *domain.Document) error {
+			n++
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, exportBatchSize+1, n, "the limit must be ignored")
+	})
+
+	// Scenario: Stopping at the first error returned by the callback
+	t.Run("CallbackError", func(t *testing.T) {
+		errStop := errors.New("stop")
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE export_cursor").WithArgs("bu-a").WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectQuery("FETCH FORWARD 1000 FROM export_cursor").
+			WillReturnRows(sqlmock.NewRows(columns).AddRow("ID1", "hash", "tag", domain.StatusClean, now, now, "bu-a", domain.SourceUpload, "", "", "SHA-256", "", 0, "", "", nil, "", "{}", "", ""))
+		mock.ExpectRollback()
+
+		err := repo.Iterate(ctx, domain.DocumentFilter{}, func(*domain.Document) error { return errStop })
+		assert.ErrorIs(t, err, errStop)
+	})
+
+	// Scenario: Encountering a database error
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectBegin()
+		mock.ExpectExec("DECLARE export_cursor").WillReturnError(sql.ErrConnDone)
+		mock.ExpectRollback()
+
+		err := repo.Iterate(ctx, domain.DocumentFilter{}, func(*domain.Document) error { return nil })
+		assert.ErrorIs(t, err, port.ErrFindDocumentsFailed)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestStats(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	since := time.Now().Add(-24 * time.Hour)
+
+	// Scenario: Successfully computing the statistics of a tenant
+	t.Run("Success", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"pending", "infected", "clean", "failed", "uploaded", "latency"}).AddRow(1, 2, 3, 5, 4, 1.5)
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1 AND deleted_at IS NULL").
+			WithArgs("bu-a", domain.StatusPending, domain.StatusInfected, domain.StatusClean, since, domain.StatusTimeout, domain.StatusError, domain.StatusTooLarge).
+			WillReturnRows(rows)
+
+		stats, err := repo.Stats(domain.ContextWithTenant(context.Background(), "bu-a"), since)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.DocumentStats{
+			Pending:            1,
+			Infected:           2,
+			Clean:              3,
+			Failed:             5,
+			UploadedSince:      4,
+			AverageScanLatency: 1500 * time.Millisecond,
+		}, stats)
+	})
+
+	// Scenario: Encountering a database error
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE tenant = \\$1").WillReturnError(sql.ErrConnDone)
+
+		stats, err := repo.Stats(context.Background(), since)
+		assert.ErrorIs(t, err, port.ErrDocumentStatsFailed)
+		assert.Nil(t, stats)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
 func TestPing(t *testing.T) {
 	db, mock, err := sqlmock.New(sqlmock.MonitorPingsOption(true))
 	if err != nil {
@@ -402,3 +1104,154 @@ func TestPing(t *testing.T) {
 		t.Errorf("there were unfulfilled expectations: %s", err)
 	}
 }
+
+func TestMetrics(t *testing.T) {
+	db, _, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+	db.SetMaxOpenConns(7)
+
+	repo := &PostgresDocumentRepository{db: db}
+	metrics := make(map[string]domain.Metric)
+	for _, m := range repo.Metrics() {
+		metrics[m.Name] = m
+	}
+	assert.Equal(t, float64(7), metrics["goyav_db_max_open_connections"].Value)
+	assert.Equal(t, domain.MetricGauge, metrics["goyav_db_in_use_connections"].Kind)
+	assert.Equal(t, domain.MetricCounter, metrics["goyav_db_wait_count_total"].Kind)
+}
+
+func TestSummarize(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+	to := time.Now()
+	from := to.Add(-24 * time.Hour)
+
+	// Scenario: Successfully summarizing the documents of all the tenants
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE created_at >= \\$1 AND created_at < \\$2$").
+			WithArgs(from, to, domain.StatusPending, domain.StatusInfected, domain.StatusClean, domain.StatusTimeout, domain.StatusError, domain.StatusTooLarge).
+			WillReturnRows(sqlmock.NewRows([]string{"total", "pending", "infected", "clean", "failed"}).AddRow(10, 1, 2, 6, 1))
+		mock.ExpectQuery("SELECT threat_name, COUNT\\(\\*\\) FROM documents (.+) GROUP BY threat_name ORDER BY 2 DESC, 1 LIMIT \\$4").
+			WithArgs(from, to, domain.StatusInfected, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"threat_name", "count"}).AddRow("Win.Test.EICAR_HDB-1", 2))
+		mock.ExpectQuery("SELECT tenant, COUNT\\(\\*\\) FROM documents (.+) GROUP BY tenant ORDER BY 2 DESC, 1 LIMIT \\$3").
+			WithArgs(from, to, 3).
+			WillReturnRows(sqlmock.NewRows([]string{"tenant", "count"}).AddRow("bu-a", 7).AddRow("bu-b", 3))
+
+		report, err := repo.Summarize(context.Background(), from, to, 3)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.SummaryReport{
+			From:           from,
+			To:             to,
+			Total:          10,
+			Pending:        1,
+			Infected:       2,
+			Clean:          6,
+			Failed:         1,
+			TopThreats:     []domain.ThreatCount{{Name: "Win.Test.EICAR_HDB-1", Count: 2}},
+			BusiestTenants: []domain.TenantCount{{Tenant: "bu-a", Uploads: 7}, {Tenant: "bu-b", Uploads: 3}},
+		}, report)
+	})
+
+	// Scenario: Encountering a database error
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectQuery("SELECT (.+) FROM documents WHERE created_at >= \\$1").WillReturnError(sql.ErrConnDone)
+
+		report, err := repo.Summarize(context.Background(), from, to, 3)
+		assert.ErrorIs(t, err, port.ErrDocumentSummaryFailed)
+		assert.Nil(t, report)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestRunExclusive(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("an error '%s' was not expected when opening a stub database connection", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresDocumentRepository{db: db}
+
+	// Scenario: Running the job holding its lock
+	t.Run("Locked", func(t *testing.T) {
+		mock.ExpectQuery("SELECT pg_try_advisory_lock\\(\\$1, hashtext\\(\\$2\\)\\)").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO job_runs").
+			WithArgs("purge", float64(3600)).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+		mock.ExpectExec("SELECT pg_advisory_unlock\\(\\$1, hashtext\\(\\$2\\)\\)").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		var run bool
+		ok, err := repo.RunExclusive(context.Background(), "purge", time.Hour, func(ctx context.Context) error {
+			run = true
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.True(t, ok)
+		assert.True(t, run)
+	})
+
+	// Scenario: Skipping the job run by another replica within the interval
+	t.Run("RecentlyRun", func(t *testing.T) {
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(true))
+		mock.ExpectExec("INSERT INTO job_runs").
+			WithArgs("purge", float64(3600)).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+		mock.ExpectExec("SELECT pg_advisory_unlock").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		ok, err := repo.RunExclusive(context.Background(), "purge", time.Hour, func(ctx context.Context) error {
+			t.Error("the job should not be run")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	// Scenario: Skipping the job locked by another replica
+	t.Run("Skipped", func(t *testing.T) {
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnRows(sqlmock.NewRows([]string{"pg_try_advisory_lock"}).AddRow(false))
+
+		ok, err := repo.RunExclusive(context.Background(), "purge", time.Hour, func(ctx context.Context) error {
+			t.Error("the job should not be run")
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.False(t, ok)
+	})
+
+	// Scenario: Failing to take the lock
+	t.Run("Failed", func(t *testing.T) {
+		mock.ExpectQuery("SELECT pg_try_advisory_lock").
+			WithArgs(advisoryLockClass, "purge").
+			WillReturnError(errors.New("connection reset"))
+
+		ok, err := repo.RunExclusive(context.Background(), "purge", time.Hour, func(ctx context.Context) error { return nil })
+		assert.ErrorIs(t, err, port.ErrJobLockFailed)
+		assert.False(t, ok)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
@@ -0,0 +1,47 @@
+package docrepo
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/port"
+	"time"
+)
+
+// advisoryLockClass is the first key of the advisory locks taken by GoyAV, "GoyA", so that they do not collide with
+// the advisory locks of the other applications sharing the database. The second key is the hash of the name of the
+// lock.
+const advisoryLockClass = 0x476f7941
+
+// RunExclusive runs fn holding the session-level advisory lock of job on a connection of its own, unless another
+// replica holds it already or ran the job less than every ago, and reports whether fn was run. The lock only keeps
+// the replicas from running the job at once, the start of its last run is recorded in the job_runs table under the
+// lock so that a replica does not run it again right after another one. The lock is released along with the
+// connection should the replica stop while running fn.
+func (r PostgresDocumentRepository) RunExclusive(ctx context.Context, job string, every time.Duration, fn func(ctx context.Context) error) (bool, error) {
+	conn, err := r.db.Conn(ctx)
+	if err != nil {
+		return false, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrJobLockFailed, err)
+	}
+	defer conn.Close()
+
+	var locked bool
+	if err = conn.QueryRowContext(ctx, "SELECT pg_try_advisory_lock($1, hashtext($2))", advisoryLockClass, job).Scan(&locked); err != nil {
+		return false, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrJobLockFailed, err)
+	}
+	if !locked {
+		return false, nil
+	}
+	defer conn.ExecContext(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1, hashtext($2))", advisoryLockClass, job)
+
+	res, err := conn.ExecContext(ctx, `INSERT INTO job_runs (job, last_run_at) VALUES ($1, now() AT TIME ZONE 'UTC')
+ON CONFLICT (job) DO UPDATE SET last_run_at = EXCLUDED.last_run_at
+WHERE job_runs.last_run_at <= EXCLUDED.last_run_at - make_interval(secs => $2)`, job, every.Seconds())
+	if err != nil {
+		return false, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrJobLockFailed, err)
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		// the job was run by another replica less than every ago
+		return false, err
+	}
+	return true, fn(ctx)
+}
@@ -0,0 +1,21 @@
+package docrepo
+
+import (
+	"goyav/internal/core/domain"
+)
+
+// Metrics reports the statistics of the connection pool of the database, see sql.DBStats.
+func (r PostgresDocumentRepository) Metrics() []domain.Metric {
+	s := r.db.Stats()
+	return []domain.Metric{
+		{Name: "goyav_db_max_open_connections", Help: "Maximum number of open connections to the database.", Kind: domain.MetricGauge, Value: float64(s.MaxOpenConnections)},
+		{Name: "goyav_db_open_connections", Help: "Number of established connections to the database, in use or idle.", Kind: domain.MetricGauge, Value: float64(s.OpenConnections)},
+		{Name: "goyav_db_in_use_connections", Help: "Number of connections to the database currently in use.", Kind: domain.MetricGauge, Value: float64(s.InUse)},
+		{Name: "goyav_db_idle_connections", Help: "Number of idle connections to the database.", Kind: domain.MetricGauge, Value: float64(s.Idle)},
+		{Name: "goyav_db_wait_count_total", Help: "Total number of connections waited for.", Kind: domain.MetricCounter, Value: float64(s.WaitCount)},
+		{Name: "goyav_db_wait_duration_seconds_total", Help: "Total time blocked waiting for a new connection.", Kind: domain.MetricCounter, Value: s.WaitDuration.Seconds()},
+		{Name: "goyav_db_max_idle_closed_total", Help: "Total number of connections closed due to the maximum number of idle connections.", Kind: domain.MetricCounter, Value: float64(s.MaxIdleClosed)},
+		{Name: "goyav_db_max_idle_time_closed_total", Help: "Total number of connections closed due to the maximum idle time.", Kind: domain.MetricCounter, Value: float64(s.MaxIdleTimeClosed)},
+		{Name: "goyav_db_max_lifetime_closed_total", Help: "Total number of connections closed due to the maximum lifetime.", Kind: domain.MetricCounter, Value: float64(s.MaxLifetimeClosed)},
+	}
+}
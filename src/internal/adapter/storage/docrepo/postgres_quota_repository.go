@@ -0,0 +1,107 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+)
+
+// PostgresQuotaRepository persists the quota usage of tenants in the tenant_quotas table, one row per tenant.
+type PostgresQuotaRepository struct {
+	db *sql.DB
+}
+
+var ErrPostgresQuotaRepository = errors.New("PostgresQuotaRepository")
+
+func NewPostgresQuota(db *sql.DB) (*PostgresQuotaRepository, error) {
+	if db == nil {
+		return nil, fmt.Errorf("%w : required sql.DB, got nil", ErrPostgresQuotaRepository)
+	}
+	if err := db.Ping(); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrPostgresQuotaRepository, err)
+	}
+	if err := CheckSchemaVersion(context.Background(), db); err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrPostgresQuotaRepository, err)
+	}
+	slog.Info("quota repository created")
+	return &PostgresQuotaRepository{db: db}, nil
+}
+
+// reserveQuery records an upload in a single statement, so that concurrent uploads cannot exceed the quota:
+// the row is left untouched, and no row is affected, when the upload does not fit.
+// The uploads counter starts over when the day changes.
+const reserveQuery = `INSERT INTO tenant_quotas AS q (tenant, day, uploads, stored_bytes) VALUES ($1, $2, 1, $3)
+ON CONFLICT (tenant) DO UPDATE SET
+    uploads = CASE WHEN q.day = EXCLUDED.day THEN q.uploads + 1 ELSE 1 END,
+    day = EXCLUDED.day,
+    stored_bytes = q.stored_bytes + EXCLUDED.stored_bytes
+WHERE ($4 = 0 OR q.day <> EXCLUDED.day OR q.uploads < $4)
+    AND ($5 = 0 OR q.stored_bytes + EXCLUDED.stored_bytes <= $5)`
+
+// Reserve records an upload of size bytes during the given day, provided that it fits in quota.
+func (r PostgresQuotaRepository) Reserve(ctx context.Context, day time.Time, size int64, quota domain.Quota) error {
+	// the first upload of a tenant inserts its row without checking the limits
+	if quota.MaxStoredBytes > 0 && size > quota.MaxStoredBytes {
+		return fmt.Errorf("%w: %w: %d bytes do not fit in %d bytes", ErrPostgresQuotaRepository, port.ErrQuotaExceeded, size, quota.MaxStoredBytes)
+	}
+
+	tenant := domain.TenantFromContext(ctx)
+	res, err := r.db.ExecContext(ctx, reserveQuery, tenant, domain.QuotaDay(day), size, quota.MaxUploadsPerDay, quota.MaxStoredBytes)
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresQuotaRepository, port.ErrQuotaReserveFailed, err)
+	}
+
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresQuotaRepository, port.ErrQuotaReserveFailed, err)
+	}
+
+	if n == 0 {
+		return fmt.Errorf("%w: %w: tenant=%q", ErrPostgresQuotaRepository, port.ErrQuotaExceeded, tenant)
+	}
+	return nil
+}
+
+// Release gives back size bytes of stored data.
+func (r PostgresQuotaRepository) Release(ctx context.Context, size int64) error {
+	q := "UPDATE tenant_quotas SET stored_bytes = GREATEST(stored_bytes - $1, 0) WHERE tenant = $2"
+	if _, err := r.db.ExecContext(ctx, q, size, domain.TenantFromContext(ctx)); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresQuotaRepository, port.ErrQuotaReleaseFailed, err)
+	}
+	return nil
+}
+
+// Usage returns the usage of the quota during the given day. A tenant without any upload has a zero usage.
+func (r PostgresQuotaRepository) Usage(ctx context.Context, day time.Time) (*domain.QuotaUsage, error) {
+	var (
+		lastDay time.Time
+		usage   = &domain.QuotaUsage{Day: domain.QuotaDay(day)}
+	)
+
+	q := "SELECT day, uploads, stored_bytes FROM tenant_quotas WHERE tenant = $1"
+	err := r.db.QueryRowContext(ctx, q, domain.TenantFromContext(ctx)).Scan(&lastDay, &usage.Uploads, &usage.StoredBytes)
+	if errors.Is(err, sql.ErrNoRows) {
+		return usage, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresQuotaRepository, port.ErrQuotaUsageFailed, err)
+	}
+
+	if !domain.QuotaDay(lastDay).Equal(usage.Day) {
+		usage.Uploads = 0
+	}
+	return usage, nil
+}
+
+// Ping checks the repository's availability or health status.
+func (r PostgresQuotaRepository) Ping() error {
+	if err := r.db.Ping(); err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresQuotaRepository, port.ErrQuotaRepositoryUnavailable, err)
+	}
+	return nil
+}
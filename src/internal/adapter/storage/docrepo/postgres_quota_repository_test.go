@@ -0,0 +1,102 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"testing"
+	"time"
+
+	"github.com/DATA-DOG/go-sqlmock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPostgresQuotaReserve(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresQuotaRepository{db: db}
+	ctx := domain.ContextWithTenant(context.Background(), "finance")
+	now := time.Date(2024, 3, 18, 13, 30, 0, 0, time.UTC)
+	quota := domain.Quota{MaxUploadsPerDay: 10, MaxStoredBytes: 1 << 20}
+
+	t.Run("Success", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO tenant_quotas").
+			WithArgs("finance", domain.QuotaDay(now), int64(512), quota.MaxUploadsPerDay, quota.MaxStoredBytes).
+			WillReturnResult(sqlmock.NewResult(0, 1))
+
+		assert.NoError(t, repo.Reserve(ctx, now, 512, quota))
+	})
+
+	t.Run("Exceeded", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO tenant_quotas").
+			WithArgs("finance", domain.QuotaDay(now), int64(512), quota.MaxUploadsPerDay, quota.MaxStoredBytes).
+			WillReturnResult(sqlmock.NewResult(0, 0))
+
+		err := repo.Reserve(ctx, now, 512, quota)
+		assert.ErrorIs(t, err, port.ErrQuotaExceeded)
+	})
+
+	t.Run("LargerThanStoredBytes", func(t *testing.T) {
+		err := repo.Reserve(ctx, now, quota.MaxStoredBytes+1, quota)
+		assert.ErrorIs(t, err, port.ErrQuotaExceeded)
+	})
+
+	t.Run("DatabaseError", func(t *testing.T) {
+		mock.ExpectExec("INSERT INTO tenant_quotas").WillReturnError(errors.New("database error"))
+
+		err := repo.Reserve(ctx, now, 512, quota)
+		assert.ErrorIs(t, err, port.ErrQuotaReserveFailed)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
+
+func TestPostgresQuotaUsage(t *testing.T) {
+	db, mock, err := sqlmock.New()
+	if err != nil {
+		t.Fatalf("error creating sqlmock: %v", err)
+	}
+	defer db.Close()
+
+	repo := &PostgresQuotaRepository{db: db}
+	ctx := domain.ContextWithTenant(context.Background(), "finance")
+	now := time.Date(2024, 3, 18, 13, 30, 0, 0, time.UTC)
+
+	t.Run("SameDay", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"day", "uploads", "stored_bytes"}).AddRow(domain.QuotaDay(now), 3, 2048)
+		mock.ExpectQuery("SELECT day, uploads, stored_bytes FROM tenant_quotas").WithArgs("finance").WillReturnRows(rows)
+
+		usage, err := repo.Usage(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.QuotaUsage{Day: domain.QuotaDay(now), Uploads: 3, StoredBytes: 2048}, usage)
+	})
+
+	t.Run("PreviousDay", func(t *testing.T) {
+		rows := sqlmock.NewRows([]string{"day", "uploads", "stored_bytes"}).AddRow(domain.QuotaDay(now).AddDate(0, 0, -1), 3, 2048)
+		mock.ExpectQuery("SELECT day, uploads, stored_bytes FROM tenant_quotas").WithArgs("finance").WillReturnRows(rows)
+
+		usage, err := repo.Usage(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.QuotaUsage{Day: domain.QuotaDay(now), Uploads: 0, StoredBytes: 2048}, usage)
+	})
+
+	t.Run("NoUpload", func(t *testing.T) {
+		mock.ExpectQuery("SELECT day, uploads, stored_bytes FROM tenant_quotas").WithArgs("finance").WillReturnError(sql.ErrNoRows)
+
+		usage, err := repo.Usage(ctx, now)
+		assert.NoError(t, err)
+		assert.Equal(t, &domain.QuotaUsage{Day: domain.QuotaDay(now)}, usage)
+	})
+
+	if err := mock.ExpectationsWereMet(); err != nil {
+		t.Errorf("there were unfulfilled expectations: %s", err)
+	}
+}
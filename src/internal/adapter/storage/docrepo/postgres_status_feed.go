@@ -0,0 +1,102 @@
+package docrepo
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// StatusChannel is the channel the changes of the status of the documents are notified on, by the trigger
+// installed by the migrations.
+const StatusChannel = "goyav_document_status"
+
+// statusFeedPingInterval is the interval between the checks of the connection of an idle listener.
+const statusFeedPingInterval = time.Minute
+
+// PostgresStatusFeed implements port.StatusFeed by listening to the notifications of the status changes made by
+// any replica, on a connection of its own outside of the pool of the repositories.
+type PostgresStatusFeed struct {
+	connInfo string
+}
+
+// NewPostgresStatusFeed creates a feed of the status changes listening to the database of connInfo, the connection
+// string given to sql.Open.
+func NewPostgresStatusFeed(connInfo string) *PostgresStatusFeed {
+	return &PostgresStatusFeed{connInfo: connInfo}
+}
+
+// Listen calls handle with each status change notified on StatusChannel until ctx is done. The connection is
+// reestablished when lost, handle is then called with a change without ID since notifications may have been missed.
+func (f *PostgresStatusFeed) Listen(ctx context.Context, handle func(domain.StatusChange)) error {
+	listener := pq.NewListener(f.connInfo, time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		switch ev {
+		case pq.ListenerEventDisconnected:
+			slog.Warn("status feed disconnected", "error", err)
+		case pq.ListenerEventConnectionAttemptFailed:
+			slog.Warn("status feed connection failed", "error", err)
+		}
+	})
+	defer listener.Close()
+	if err := listener.Listen(StatusChannel); err != nil {
+		return fmt.Errorf("%w: %v", port.ErrStatusFeedFailed, err)
+	}
+	slog.Info("status feed listening", "channel", StatusChannel)
+
+	ping := time.NewTicker(statusFeedPingInterval)
+	defer ping.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case n := <-listener.Notify:
+			if n == nil {
+				handle(domain.StatusChange{})
+				continue
+			}
+			change, err := parseStatusChange(n.Extra)
+			if err != nil {
+				slog.Error("status feed - invalid notification", "error", err, "payload", n.Extra)
+				continue
+			}
+			handle(change)
+		case <-ping.C:
+			go listener.Ping()
+		}
+	}
+}
+
+// statusNotification is the payload of a notification on StatusChannel.
+type statusNotification struct {
+	ID         string `json:"document_id"`
+	Tenant     string `json:"tenant"`
+	Status     int    `json:"status"`
+	AnalyzedAt string `json:"analyzed_at"`
+	Threat     string `json:"threat_name"`
+}
+
+// parseStatusChange returns the status change notified with payload.
+func parseStatusChange(payload string) (domain.StatusChange, error) {
+	var n statusNotification
+	if err := json.Unmarshal([]byte(payload), &n); err != nil {
+		return domain.StatusChange{}, err
+	}
+	if n.ID == "" {
+		return domain.StatusChange{}, fmt.Errorf("missing document ID")
+	}
+	change := domain.StatusChange{ID: n.ID, Tenant: n.Tenant, Status: domain.AnalysisStatus(n.Status), Threat: n.Threat}
+	if n.AnalyzedAt != "" {
+		// timestamp without time zone, as stored
+		t, err := time.Parse("2006-01-02T15:04:05.999999", n.AnalyzedAt)
+		if err != nil {
+			return domain.StatusChange{}, err
+		}
+		change.AnalyzedAt = t
+	}
+	return change, nil
+}
@@ -0,0 +1,43 @@
+package docrepo
+
+import (
+	"goyav/internal/core/domain"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseStatusChange(t *testing.T) {
+	t.Run("Analyzed", func(t *testing.T) {
+		change, err := parseStatusChange(`{"document_id":"RNiGEv6oqPNt6C4SeKuwLw","tenant":"finance","status":2,"analyzed_at":"2024-01-31T12:30:05.123456"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusChange{
+			ID:         "RNiGEv6oqPNt6C4SeKuwLw",
+			Tenant:     "finance",
+			Status:     domain.StatusClean,
+			AnalyzedAt: time.Date(2024, time.January, 31, 12, 30, 5, 123456000, time.UTC),
+		}, change)
+	})
+
+	t.Run("Infected", func(t *testing.T) {
+		change, err := parseStatusChange(`{"document_id":"RNiGEv6oqPNt6C4SeKuwLw","tenant":"","status":1,"analyzed_at":"2024-01-31T12:30:05","threat_name":"Win.Test.EICAR_HDB-1"}`)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusInfected, change.Status)
+		assert.Equal(t, "Win.Test.EICAR_HDB-1", change.Threat)
+	})
+
+	t.Run("NotAnalyzed", func(t *testing.T) {
+		change, err := parseStatusChange(`{"document_id":"RNiGEv6oqPNt6C4SeKuwLw","tenant":"","status":0,"analyzed_at":null}`)
+		assert.NoError(t, err)
+		assert.Equal(t, domain.StatusPending, change.Status)
+		assert.True(t, change.AnalyzedAt.IsZero())
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for _, payload := range []string{"", "{}", `{"document_id":"RNiGEv6oqPNt6C4SeKuwLw","analyzed_at":"yesterday"}`} {
+			_, err := parseStatusChange(payload)
+			assert.Error(t, err, payload)
+		}
+	})
+}
@@ -0,0 +1,114 @@
+package docrepo
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+
+	"github.com/lib/pq"
+)
+
+// webhookColumns are the columns of the webhooks table scanned by scanWebhook.
+const webhookColumns = "webhook_id, url, events, secret, active, created_at, updated_at"
+
+// SaveWebhook records the new webhook w.
+func (r PostgresDocumentRepository) SaveWebhook(ctx context.Context, w *domain.Webhook) error {
+	q := "INSERT INTO webhooks (" + webhookColumns + ") VALUES ($1, $2, $3, $4, $5, $6, $7)"
+	_, err := r.db.ExecContext(ctx, q, w.ID, w.URL, pq.Array(webhookEvents(w)), w.Secret, w.Active, w.CreatedAt.UTC(), w.UpdatedAt.UTC())
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	return nil
+}
+
+// GetWebhook retrieves the webhook identified by ID.
+func (r PostgresDocumentRepository) GetWebhook(ctx context.Context, ID string) (*domain.Webhook, error) {
+	row := r.db.QueryRowContext(ctx, "SELECT "+webhookColumns+" FROM webhooks WHERE webhook_id = $1", ID)
+	w, err := scanWebhook(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("%w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrWebhookNotFound, ID)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	return w, nil
+}
+
+// ListWebhooks retrieves all the webhooks, the oldest first.
+func (r PostgresDocumentRepository) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	rows, err := r.db.QueryContext(ctx, "SELECT "+webhookColumns+" FROM webhooks ORDER BY created_at, webhook_id")
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	defer rows.Close()
+
+	var webhooks []*domain.Webhook
+	for rows.Next() {
+		w, err := scanWebhook(rows)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+		}
+		webhooks = append(webhooks, w)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	return webhooks, nil
+}
+
+// UpdateWebhook replaces the URL, events, secret, active flag and update date of the webhook identified by the ID of w.
+func (r PostgresDocumentRepository) UpdateWebhook(ctx context.Context, w *domain.Webhook) error {
+	q := "UPDATE webhooks SET url = $2, events = $3, secret = $4, active = $5, updated_at = $6 WHERE webhook_id = $1"
+	res, err := r.db.ExecContext(ctx, q, w.ID, w.URL, pq.Array(webhookEvents(w)), w.Secret, w.Active, w.UpdatedAt.UTC())
+	return checkWebhookAffected(res, err, w.ID)
+}
+
+// DeleteWebhook removes the webhook identified by ID.
+func (r PostgresDocumentRepository) DeleteWebhook(ctx context.Context, ID string) error {
+	res, err := r.db.ExecContext(ctx, "DELETE FROM webhooks WHERE webhook_id = $1", ID)
+	return checkWebhookAffected(res, err, ID)
+}
+
+// checkWebhookAffected returns the error of a statement changing the webhook identified by ID, wrapping
+// port.ErrWebhookNotFound if it changed no row.
+func checkWebhookAffected(res sql.Result, err error, ID string) error {
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return fmt.Errorf("%w: %w: %v", ErrPostgresDocumentRepository, port.ErrWebhookRepositoryFailed, err)
+	}
+	if n == 0 {
+		return fmt.Errorf("%w: %w: ID %v", ErrPostgresDocumentRepository, port.ErrWebhookNotFound, ID)
+	}
+	return nil
+}
+
+// scanWebhook scans the webhookColumns of a row.
+func scanWebhook(row rowScanner) (*domain.Webhook, error) {
+	var (
+		w      domain.Webhook
+		events []string
+	)
+	if err := row.Scan(&w.ID, &w.URL, pq.Array(&events), &w.Secret, &w.Active, &w.CreatedAt, &w.UpdatedAt); err != nil {
+		return nil, err
+	}
+	w.Events = make([]domain.WebhookEvent, len(events))
+	for i, e := range events {
+		w.Events[i] = domain.WebhookEvent(e)
+	}
+	return &w, nil
+}
+
+// webhookEvents returns the names of the events of w.
+func webhookEvents(w *domain.Webhook) []string {
+	events := make([]string, len(w.Events))
+	for i, e := range w.Events {
+		events[i] = string(e)
+	}
+	return events
+}
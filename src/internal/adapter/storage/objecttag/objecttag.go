@@ -0,0 +1,55 @@
+// Package objecttag records the verdicts of the documents as tags of the S3 objects holding their files, so that
+// the systems consuming a bucket can filter its objects on their verdict without calling GOYAV.
+package objecttag
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/tags"
+)
+
+// Tags recording the verdict of an object.
+const (
+	ScanStatus = "scan-status"
+	ScanTime   = "scan-time"
+	ThreatName = "threat-name"
+)
+
+// Verdict returns the tags recording the verdict of doc, along with the name of the threat found in the object,
+// if known.
+func Verdict(doc *domain.Document) map[string]string {
+	values := map[string]string{
+		ScanStatus: doc.Status.String(),
+		ScanTime:   doc.AnalyzedAt.UTC().Format(time.RFC3339),
+	}
+	if doc.Threat != "" {
+		values[ThreatName] = doc.Threat
+	}
+	return values
+}
+
+// Put adds values to the tags of the object key of bucket, in its version versionID if not empty, without losing the
+// other tags of the object.
+func Put(ctx context.Context, client *minio.Client, bucket, key, versionID string, values map[string]string) error {
+	current, err := client.GetObjectTagging(ctx, bucket, key, minio.GetObjectTaggingOptions{VersionID: versionID})
+	if err != nil {
+		return fmt.Errorf("failed to get the tags of object %s/%s: %v", bucket, key, err)
+	}
+	merged := current.ToMap()
+	for k, v := range values {
+		merged[k] = v
+	}
+
+	t, err := tags.MapToObjectTags(merged)
+	if err != nil {
+		return fmt.Errorf("failed to tag object %s/%s: %v", bucket, key, err)
+	}
+	if err = client.PutObjectTagging(ctx, bucket, key, t, minio.PutObjectTaggingOptions{VersionID: versionID}); err != nil {
+		return fmt.Errorf("failed to tag object %s/%s: %v", bucket, key, err)
+	}
+	return nil
+}
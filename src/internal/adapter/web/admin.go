@@ -0,0 +1,154 @@
+package web
+
+import (
+	"crypto/subtle"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultReconcileMinAge is the default age under which documents and binary data are skipped by a reconciliation.
+const DefaultReconcileMinAge = 15 * time.Minute
+
+// DefaultRequeueMinAge is the default time a document must have been pending for to be requeued.
+const DefaultRequeueMinAge = time.Hour
+
+// WithAdminKey enables the /admin routes, which require the given API key in the X-API-Key header.
+// The routes are only available when the service implements port.AdminService.
+func WithAdminKey(key string) Option {
+	return func(d *DocumentMux) {
+		d.adminKey = hashAPIKey(key)
+	}
+}
+
+// withAdmin passes the request to the next handler if it carries the admin API key, it answers 401 otherwise.
+func (d *DocumentMux) withAdmin(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(HeaderAPIKey)
+		if key == "" || subtle.ConstantTimeCompare([]byte(hashAPIKey(key)), []byte(d.adminKey)) != 1 {
+			writeError(w, http.StatusUnauthorized, "missing or invalid credentials", nil)
+			return
+		}
+		next(w, r)
+	}
+}
+
+// reconcileHandler reports the mismatches between the documents and their binary data.
+// GET only reports them, POST fixes them as well.
+func (d *DocumentMux) reconcileHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	opts := domain.ReconcileOptions{
+		Fix:    r.Method == http.MethodPost,
+		MinAge: DefaultReconcileMinAge,
+	}
+	if v := r.URL.Query().Get("min_age"); v != "" {
+		minAge, err := time.ParseDuration(v)
+		if err != nil || minAge < 0 {
+			writeError(w, http.StatusBadRequest, "min_age must be a positive duration, e.g. 15m", om)
+			return
+		}
+		opts.MinAge = minAge
+	}
+
+	report, err := d.admin.Reconcile(r.Context(), opts)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.reconcileHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+	om.Message = strconv.Itoa(len(report.Mismatches)) + " mismatch(es) found"
+	om.Reconciliation = report
+	writeJson(w, http.StatusOK, om)
+}
+
+// purgeHandler immediately purges the documents created before the date of the before query parameter,
+// restricted to the statuses of the comma-separated status query parameter if it is set.
+func (d *DocumentMux) purgeHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	q := r.URL.Query()
+
+	before, err := time.Parse(time.RFC3339, q.Get("before"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "before must be a RFC 3339 date, e.g. 2024-01-31T00:00:00Z", om)
+		return
+	}
+	opts := domain.PurgeOptions{Before: before}
+	if v := q.Get("status"); v != "" {
+		for _, name := range strings.Split(v, ",") {
+			status, ok := domain.ParseAnalysisStatus(strings.TrimSpace(name))
+			if !ok {
+				writeError(w, http.StatusBadRequest, "status must be a comma-separated list of pending, clean or infected", om)
+				return
+			}
+			if !slices.Contains(opts.Statuses, status) {
+				opts.Statuses = append(opts.Statuses, status)
+			}
+		}
+	}
+
+	report, err := d.admin.Purge(r.Context(), opts)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.purgeHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+	om.Message = strconv.FormatInt(report.Documents, 10) + " document(s) purged"
+	om.Purge = report
+	writeJson(w, http.StatusOK, om)
+}
+
+// requeueHandler triggers again the analysis of the documents pending for longer than the min_age query parameter
+// whose binary data still exists.
+func (d *DocumentMux) requeueHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	opts := domain.RequeueOptions{MinAge: DefaultRequeueMinAge}
+	if v := r.URL.Query().Get("min_age"); v != "" {
+		minAge, err := time.ParseDuration(v)
+		if err != nil || minAge < 0 {
+			writeError(w, http.StatusBadRequest, "min_age must be a positive duration, e.g. 1h", om)
+			return
+		}
+		opts.MinAge = minAge
+	}
+
+	report, err := d.admin.Requeue(r.Context(), opts)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.requeueHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+	om.Message = strconv.Itoa(report.Requeued) + " document(s) requeued"
+	om.Requeue = report
+	writeJson(w, http.StatusOK, om)
+}
+
+// concurrencyHandler reports the limit on the number of analyses run at once. PUT changes it to the value of the
+// limit query parameter as well, until the service restarts.
+func (d *DocumentMux) concurrencyHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	if r.Method != http.MethodPut {
+		om.Message = "concurrency limit"
+		om.Concurrency = d.admin.Concurrency()
+		writeJson(w, http.StatusOK, om)
+		return
+	}
+
+	limit, err := strconv.Atoi(r.URL.Query().Get("limit"))
+	if err != nil || limit < 1 {
+		writeError(w, http.StatusBadRequest, "limit must be a strictly positive integer", om)
+		return
+	}
+	c, err := d.admin.SetConcurrency(limit)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.concurrencyHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+	om.Message = "concurrency limit set to " + strconv.Itoa(c.Limit)
+	om.Concurrency = c
+	writeJson(w, http.StatusOK, om)
+}
@@ -0,0 +1,112 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/pkg/helper"
+	"log/slog"
+	"net/http"
+)
+
+// HeaderAPIKey is the request header carrying the API key of a client.
+const HeaderAPIKey = "X-API-Key"
+
+// WithAPIKeys makes the document routes require an API key, sent in the X-API-Key header.
+// keys maps each accepted API key to the tenant it belongs to.
+func WithAPIKeys(keys map[string]string) Option {
+	return func(d *DocumentMux) {
+		d.apiKeys = make(map[string]string, len(keys))
+		for key, tenant := range keys {
+			d.apiKeys[hashAPIKey(key)] = tenant
+		}
+	}
+}
+
+// WithTenantHeader makes the document routes read the tenant from the given request header.
+// The header must be set by a trusted gateway, it is ignored when API keys are configured.
+func WithTenantHeader(name string) Option {
+	return func(d *DocumentMux) {
+		d.tenantHeader = name
+	}
+}
+
+// withTenant resolves the tenant of a request and passes it to the next handler through the request's context.
+// It answers 401 when the tenant cannot be resolved. Requests carrying a bearer token issued by the admin API
+// get the token's tenant, provided that the token grants scope; they are answered 403 otherwise. Requests signed
+// by a signing client get the client's tenant, provided that they match their signature, see WithSignedRequests.
+func (d *DocumentMux) withTenant(scope string, next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get(HeaderSignatureClient) != "" && d.signingClients != nil {
+			tenant, body, err := d.verifySignature(w, r)
+			var tooLarge *http.MaxBytesError
+			switch {
+			case errors.Is(err, errInvalidSignature) || errors.Is(err, errStaleSignature):
+				slog.InfoContext(r.Context(), "signature rejected", "error", err.Error())
+				writeError(w, http.StatusUnauthorized, "missing, invalid or stale signature", nil)
+				return
+			case errors.As(err, &tooLarge):
+				writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the request body exceeds the maximum allowed size : %v Bytes.", tooLarge.Limit), nil)
+				return
+			case err != nil:
+				slog.InfoContext(r.Context(), "handler.withTenant", "error", err.Error())
+				writeError(w, http.StatusBadRequest, "the request body could not be read", nil)
+				return
+			}
+			defer body.Close()
+			r = r.WithContext(domain.ContextWithTenant(r.Context(), tenant))
+			r.Body = body
+			next(w, r)
+			return
+		}
+
+		if token, ok := bearerToken(r); ok && d.tokenSecret != nil {
+			claims, code, err := d.verifyToken(token, scope)
+			if err != nil {
+				slog.InfoContext(r.Context(), "token rejected", "error", err.Error())
+				writeError(w, code, "missing, invalid or insufficient credentials", nil)
+				return
+			}
+			next(w, r.WithContext(domain.ContextWithTenant(r.Context(), claims.Tenant)))
+			return
+		}
+
+		tenant, ok := d.resolveTenant(r)
+		if !ok {
+			writeError(w, http.StatusUnauthorized, "missing or invalid credentials", nil)
+			return
+		}
+		next(w, r.WithContext(domain.ContextWithTenant(r.Context(), tenant)))
+	}
+}
+
+// resolveTenant returns the tenant of a request, derived from its API key if API keys are configured,
+// from the tenant header if it is configured, or the default tenant otherwise. When clients sign their requests, the
+// default tenant is only given to the requests of a client authenticated by its certificate.
+func (d *DocumentMux) resolveTenant(r *http.Request) (string, bool) {
+	switch {
+	case len(d.apiKeys) > 0:
+		key := r.Header.Get(HeaderAPIKey)
+		if key == "" {
+			return "", false
+		}
+		tenant, ok := d.apiKeys[hashAPIKey(key)]
+		return tenant, ok
+	case d.tenantHeader != "":
+		tenant := r.Header.Get(d.tenantHeader)
+		return tenant, helper.IsValidTenant(tenant)
+	case d.signingClients != nil:
+		return domain.DefaultTenant, clientCertificate(r) != nil
+	default:
+		return domain.DefaultTenant, true
+	}
+}
+
+// hashAPIKey returns the SHA-256 digest of an API key. Keys are looked up by digest so that
+// the lookup time does not depend on how much of a guessed key is right.
+func hashAPIKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,73 @@
+package web
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// WithUploadDeadline gives the upload requests their own time limit to be read, instead of the ReadTimeout of the
+// server, which then only has to fit the other requests: timeout, plus the time to read their body at minRate bytes
+// per second if it is strictly positive. The size of a body of unknown length is taken to be the maximum upload size.
+func WithUploadDeadline(timeout time.Duration, minRate uint64) Option {
+	return func(d *DocumentMux) {
+		d.uploadTimeout = timeout
+		d.uploadMinRate = minRate
+	}
+}
+
+// WithWriteTimeout tells the handlers the WriteTimeout of the server, counted from the end of the read of the request
+// headers, so that the responses held on purpose, such as the long polls, are given more time to be written. The
+// streamed responses, the exports and the events, and the analyses of images, whose duration cannot be bounded, are
+// not limited by it.
+func WithWriteTimeout(timeout time.Duration) Option {
+	return func(d *DocumentMux) {
+		d.writeTimeout = timeout
+	}
+}
+
+// extendWriteDeadline gives the response extra time to be written on top of the write timeout, counted from now,
+// or lifts its write deadline if extra is negative. It has no effect without a write timeout.
+func (d *DocumentMux) extendWriteDeadline(w http.ResponseWriter, r *http.Request, extra time.Duration) {
+	if d.writeTimeout <= 0 {
+		return
+	}
+	var deadline time.Time
+	if extra >= 0 {
+		deadline = time.Now().Add(extra + d.writeTimeout)
+	}
+	if err := http.NewResponseController(w).SetWriteDeadline(deadline); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		slog.WarnContext(r.Context(), "handler.extendWriteDeadline", "error", err.Error())
+	}
+}
+
+// uploadDeadline returns the time limit to read an upload of size bytes.
+func (d *DocumentMux) uploadDeadline(size int64) time.Duration {
+	timeout := d.uploadTimeout
+	if d.uploadMinRate > 0 {
+		timeout += time.Duration(float64(size) / float64(d.uploadMinRate) * float64(time.Second))
+	}
+	return timeout
+}
+
+// withUploadDeadline sets the read deadline of the upload request from its Content-Length, or from limit, the maximum
+// size of the upload, if it is unknown, before passing it to the next handler. The write deadline is postponed as
+// much, the response being written once the upload is read. It has no effect without an upload timeout.
+func (d *DocumentMux) withUploadDeadline(limit uint64, next http.HandlerFunc) http.HandlerFunc {
+	if d.uploadTimeout <= 0 {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		size := r.ContentLength
+		if size < 0 {
+			size = int64(limit)
+		}
+		timeout := d.uploadDeadline(size)
+		d.extendWriteDeadline(w, r, timeout)
+		if err := http.NewResponseController(w).SetReadDeadline(time.Now().Add(timeout)); err != nil && !errors.Is(err, http.ErrNotSupported) {
+			slog.WarnContext(r.Context(), "handler.withUploadDeadline", "error", err.Error())
+		}
+		next(w, r)
+	}
+}
@@ -0,0 +1,46 @@
+package web
+
+import (
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// errUnsupportedEncoding is returned by decodeBody for a request body compressed with an encoding other than gzip.
+var errUnsupportedEncoding = errors.New("unsupported content encoding")
+
+// decodedBody is the body of a request decompressed according to its Content-Encoding.
+type decodedBody struct {
+	io.Reader
+	gz, body io.Closer
+}
+
+func (b *decodedBody) Close() error {
+	return errors.Join(b.gz.Close(), b.body.Close())
+}
+
+// decodeBody limits the body of r to limit bytes and, when r carries a gzip Content-Encoding, decompresses it within
+// the same limit, so that a small compressed body cannot expand to an unbounded one. The decompressed body replaces
+// the body of r, without its Content-Encoding, and true is returned. It fails with errUnsupportedEncoding for the
+// encodings other than gzip and identity, and with an error of the gzip package for an invalid gzip header.
+func decodeBody(w http.ResponseWriter, r *http.Request, limit int64) (bool, error) {
+	r.Body = http.MaxBytesReader(w, r.Body, limit)
+	switch encoding := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Encoding"))); encoding {
+	case "", "identity":
+		return false, nil
+	case "gzip", "x-gzip":
+		gz, err := gzip.NewReader(r.Body)
+		if err != nil {
+			return false, err
+		}
+		r.Body = http.MaxBytesReader(w, &decodedBody{Reader: gz, gz: gz, body: r.Body}, limit)
+		r.Header.Del("Content-Encoding")
+		r.ContentLength = -1
+		return true, nil
+	default:
+		return false, fmt.Errorf("%w: %q", errUnsupportedEncoding, encoding)
+	}
+}
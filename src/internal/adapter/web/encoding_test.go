@@ -0,0 +1,73 @@
+package web
+
+import (
+	"bytes"
+	"compress/gzip"
+	"goyav/internal/core/port"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// gzipped returns data compressed with gzip.
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	_, err := zw.Write(data)
+	require.NoError(t, err)
+	require.NoError(t, zw.Close())
+	return buf.Bytes()
+}
+
+func TestDecodeBody(t *testing.T) {
+	const limit = 1 << 10
+	d := newTestMux(t, limit)
+
+	tests := []struct {
+		name     string
+		encoding string
+		body     []byte
+		status   int
+		contains string
+	}{
+		{name: "uncompressed body", body: port.EICAR, status: http.StatusOK, contains: "infected"},
+		{name: "valid gzip body", encoding: "gzip", body: gzipped(t, port.EICAR), status: http.StatusOK, contains: "infected"},
+		{name: "gzip bomb", encoding: "gzip", body: gzipped(t, make([]byte, 64*limit)), status: http.StatusRequestEntityTooLarge},
+		{name: "corrupt gzip header", encoding: "gzip", body: []byte("not gzip data"), status: http.StatusBadRequest},
+		{name: "corrupt gzip data", encoding: "gzip", body: gzipped(t, port.EICAR)[:20], status: http.StatusBadRequest},
+		{name: "unknown encoding", encoding: "br", body: port.EICAR, status: http.StatusUnsupportedMediaType},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/scan", bytes.NewReader(tt.body))
+			if tt.encoding != "" {
+				r.Header.Set("Content-Encoding", tt.encoding)
+			}
+			w := httptest.NewRecorder()
+			d.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+			assert.Contains(t, w.Body.String(), tt.contains)
+		})
+	}
+
+	t.Run("round trip", func(t *testing.T) {
+		data := []byte(strings.Repeat("GoyAV ", 100))
+		r := httptest.NewRequest(http.MethodPost, "/scan", bytes.NewReader(gzipped(t, data)))
+		r.Header.Set("Content-Encoding", "x-gzip")
+		compressed, err := decodeBody(httptest.NewRecorder(), r, limit)
+		require.NoError(t, err)
+		assert.True(t, compressed)
+		assert.Empty(t, r.Header.Get("Content-Encoding"))
+
+		decoded, err := io.ReadAll(r.Body)
+		assert.NoError(t, err)
+		assert.Equal(t, data, decoded)
+		assert.NoError(t, r.Body.Close())
+	})
+}
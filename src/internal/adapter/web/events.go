@@ -0,0 +1,174 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+	"sync"
+	"time"
+)
+
+const (
+	// eventsKeepAlive is the interval between the comments keeping an idle event stream open through the proxies.
+	eventsKeepAlive = 30 * time.Second
+
+	// statusFeedRetryDelay is the delay before listening again to a status feed which failed.
+	statusFeedRetryDelay = 5 * time.Second
+)
+
+// WithStatusFeed enables the GET /documents/{id}/events route, which pushes the status of a document to the client
+// as server-sent events, each time feed reports a change of its status.
+func WithStatusFeed(feed port.StatusFeed) Option {
+	return func(d *DocumentMux) {
+		d.statusFeed = feed
+	}
+}
+
+// statusHub dispatches the status changes reported by a status feed to the requests awaiting them, by document ID.
+type statusHub struct {
+	mu          sync.Mutex
+	subscribers map[string]map[chan struct{}]struct{}
+}
+
+func newStatusHub() *statusHub {
+	return &statusHub{subscribers: make(map[string]map[chan struct{}]struct{})}
+}
+
+// subscribe returns a channel signaled when the status of the document identified by ID may have changed, and the
+// function ending the subscription.
+func (h *statusHub) subscribe(ID string) (<-chan struct{}, func()) {
+	ch := make(chan struct{}, 1)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.subscribers[ID] == nil {
+		h.subscribers[ID] = make(map[chan struct{}]struct{})
+	}
+	h.subscribers[ID][ch] = struct{}{}
+	return ch, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+		delete(h.subscribers[ID], ch)
+		if len(h.subscribers[ID]) == 0 {
+			delete(h.subscribers, ID)
+		}
+	}
+}
+
+// publish signals the subscribers of the document of change, or all of them when change has no ID.
+func (h *statusHub) publish(change domain.StatusChange) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for ID, subscribers := range h.subscribers {
+		if change.ID != "" && change.ID != ID {
+			continue
+		}
+		for ch := range subscribers {
+			// a pending signal covers this one
+			select {
+			case ch <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+// listenStatus dispatches the changes reported by the status feed until ctx is done, listening again when it fails.
+func (d *DocumentMux) listenStatus(ctx context.Context) {
+	for {
+		err := d.statusFeed.Listen(ctx, d.events.publish)
+		if ctx.Err() != nil {
+			return
+		}
+		slog.Error("status feed failed, listening again", "error", err, "delay", statusFeedRetryDelay)
+		select {
+		case <-time.After(statusFeedRetryDelay):
+		case <-ctx.Done():
+			return
+		}
+		// the changes made meanwhile are missed
+		d.events.publish(domain.StatusChange{})
+	}
+}
+
+// getDocumentEventsHandler streams the status of a document as server-sent events: a status event with the
+// document as soon as the stream opens, then each time its status changes, until its analysis completes.
+func (d *DocumentMux) getDocumentEventsHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	ctx := r.Context()
+
+	// Subscribe first, so that no change is missed between the retrieval of the document and the subscription.
+	changed, unsubscribe := d.events.subscribe(om.ID)
+	defer unsubscribe()
+
+	doc, err := d.service.GetDocument(ctx, om.ID)
+	switch {
+	case err == nil:
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+		return
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found", om)
+		return
+	case errors.Is(err, port.ErrServiceDocumentDeleted):
+		writeError(w, http.StatusGone, "the document was deleted.", om)
+		return
+	default:
+		slog.ErrorContext(ctx, "handler.getDocumentEventsHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+
+	d.extendWriteDeadline(w, r, -1)
+	rc := http.NewResponseController(w)
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+
+	keepAlive := time.NewTicker(eventsKeepAlive)
+	defer keepAlive.Stop()
+	status := doc.Status
+	if err = writeStatusEvent(w, rc, doc); err != nil {
+		return
+	}
+	for status == domain.StatusPending {
+		select {
+		case <-ctx.Done():
+			return
+		case <-keepAlive.C:
+			if _, err = fmt.Fprint(w, ": keep-alive\n\n"); err != nil || rc.Flush() != nil {
+				return
+			}
+		case <-changed:
+			if doc, err = d.service.GetDocument(ctx, om.ID); err != nil {
+				// the document was deleted or purged meanwhile, the client gets a 410 or a 404 when it reconnects
+				slog.DebugContext(ctx, "handler.getDocumentEventsHandler", "error", err.Error())
+				return
+			}
+			if doc.Status == status {
+				continue
+			}
+			status = doc.Status
+			if err = writeStatusEvent(w, rc, doc); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// writeStatusEvent writes a status event carrying doc, then flushes it to the client.
+func writeStatusEvent(w http.ResponseWriter, rc *http.ResponseController, doc *domain.Document) error {
+	b, err := json.Marshal(&ObjectMessage{ID: doc.ID, Message: "document status", Document: domain.NewDocumentDTO(doc)})
+	if err != nil {
+		return err
+	}
+	if _, err = fmt.Fprintf(w, "event: status\ndata: %s\n\n", b); err != nil {
+		return err
+	}
+	return rc.Flush()
+}
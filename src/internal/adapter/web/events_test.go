@@ -0,0 +1,31 @@
+package web
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"testing"
+	"time"
+)
+
+// blockingFeed is a status feed reporting no change until its context is done, which it signals on stopped.
+type blockingFeed struct {
+	stopped chan struct{}
+}
+
+func (f *blockingFeed) Listen(ctx context.Context, _ func(domain.StatusChange)) error {
+	<-ctx.Done()
+	close(f.stopped)
+	return ctx.Err()
+}
+
+func TestCloseStopsStatusFeed(t *testing.T) {
+	feed := &blockingFeed{stopped: make(chan struct{})}
+	d := newTestMux(t, 1<<10, WithStatusFeed(feed))
+
+	d.Close()
+	select {
+	case <-feed.stopped:
+	case <-time.After(time.Second):
+		t.Fatal("the status feed is still listened to once the mux is closed")
+	}
+}
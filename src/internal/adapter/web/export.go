@@ -0,0 +1,194 @@
+package web
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// paramFormat is the name of the query parameter of the export giving its format, csv or jsonl.
+	paramFormat = "format"
+
+	// paramFrom and paramTo are the names of the query parameters of the export bounding the creation date of the
+	// documents, in RFC 3339 format, as created_after and created_before do.
+	paramFrom = "from"
+	paramTo   = "to"
+
+	// exportFlushInterval is the number of documents written between two flushes of the export to the client.
+	exportFlushInterval = 500
+)
+
+// exportColumns are the columns of the CSV export, holding the fields of the document DTO of the same names.
+var exportColumns = []string{
+	"id", "tenant", "hash", "hash_algo", "tag", "analyse_status", "analyzed_at", "threat", "created_at", "source",
+	"origin", "file_name", "size", "content_type", "labels",
+}
+
+// exportDocumentsHandler streams the documents of the tenant matching the filter given by the query parameters, the
+// oldest first, as CSV or JSON Lines. The filter takes the parameters of the listing, but the limit and the cursor,
+// along with from and to. Since the response is written as the documents are read, an error occurring once it
+// started can only be reported by cutting it short.
+func (d *DocumentMux) exportDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	query := r.URL.Query()
+	query.Del(paramLimit)
+	query.Del(paramCursor)
+	filter, errs := parseDocumentFilter(query)
+	errs = append(errs, parseDates(query, map[string]*time.Time{paramFrom: &filter.CreatedAfter, paramTo: &filter.CreatedBefore})...)
+	format := query.Get(paramFormat)
+	if format == "" {
+		format = "csv"
+	}
+	if format != "csv" && format != "jsonl" {
+		errs = append(errs, FieldError{Field: paramFormat, Code: codeInvalid, Message: "must be csv or jsonl"})
+	}
+	if errs != nil {
+		sortFieldErrors(errs)
+		om.Errors = errs
+		writeError(w, http.StatusBadRequest, "the export request is invalid", om)
+		return
+	}
+
+	d.extendWriteDeadline(w, r, -1)
+	e := &exporter{w: w, rc: http.NewResponseController(w), format: format}
+	err := d.service.ExportDocuments(r.Context(), filter, e.write)
+	switch {
+	case errors.Is(err, port.ErrServiceInvalidFilter):
+		om.Errors = []FieldError{{Field: paramTagPrefix, Code: codeInvalid, Message: "the documents cannot be exported by this tag prefix"}}
+		writeError(w, http.StatusBadRequest, "the export request is invalid", om)
+		return
+	case err != nil && !e.started:
+		slog.ErrorContext(r.Context(), "handler.exportDocumentsHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while exporting the documents", om)
+		return
+	case err != nil:
+		slog.ErrorContext(r.Context(), "handler.exportDocumentsHandler", "error", err.Error(), "documents", e.count)
+		return
+	}
+	if err = e.close(); err != nil {
+		slog.ErrorContext(r.Context(), "handler.exportDocumentsHandler", "error", err.Error(), "documents", e.count)
+	}
+}
+
+// exporter writes the exported documents to the response, starting it with the first document, so that an error
+// occurring before can still be answered with an error response.
+type exporter struct {
+	w       http.ResponseWriter
+	rc      *http.ResponseController
+	format  string
+	started bool
+	count   int
+
+	bw  *bufio.Writer
+	csv *csv.Writer
+	enc *json.Encoder
+}
+
+// start writes the headers of the response and, in CSV, its header row.
+func (e *exporter) start() error {
+	e.started = true
+	name := "goyav-export-" + time.Now().UTC().Format("20060102T150405Z")
+	if e.format == "csv" {
+		e.w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		e.w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.csv"`)
+	} else {
+		e.w.Header().Set("Content-Type", "application/x-ndjson")
+		e.w.Header().Set("Content-Disposition", `attachment; filename="`+name+`.jsonl"`)
+	}
+	e.w.Header().Set("X-Accel-Buffering", "no")
+	e.w.WriteHeader(http.StatusOK)
+
+	e.bw = bufio.NewWriter(e.w)
+	if e.format == "csv" {
+		e.csv = csv.NewWriter(e.bw)
+		return e.csv.Write(exportColumns)
+	}
+	e.enc = json.NewEncoder(e.bw)
+	return nil
+}
+
+// write writes doc to the response, flushing it every exportFlushInterval documents.
+func (e *exporter) write(doc *domain.Document) error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	dto := domain.NewDocumentDTO(doc)
+	if e.csv != nil {
+		labels := ""
+		if len(dto.Labels) > 0 {
+			b, err := json.Marshal(dto.Labels)
+			if err != nil {
+				return err
+			}
+			labels = string(b)
+		}
+		size := ""
+		if dto.Size > 0 {
+			size = strconv.FormatInt(dto.Size, 10)
+		}
+		record := []string{
+			dto.ID, dto.Tenant, dto.Hash, dto.HashAlgo, dto.Tag, dto.Status, dto.AnalyzedAt, dto.Threat, dto.CreatedAt,
+			dto.Source, dto.Origin, dto.FileName, size, dto.ContentType, labels,
+		}
+		for i, v := range record {
+			record[i] = csvSafe(v)
+		}
+		if err := e.csv.Write(record); err != nil {
+			return err
+		}
+	} else if err := e.enc.Encode(dto); err != nil {
+		return err
+	}
+	e.count++
+	if e.count%exportFlushInterval == 0 {
+		return e.flush()
+	}
+	return nil
+}
+
+// close ends the response, starting it first if no document was exported.
+func (e *exporter) close() error {
+	if !e.started {
+		if err := e.start(); err != nil {
+			return err
+		}
+	}
+	return e.flush()
+}
+
+// flush writes the buffered documents and flushes them to the client.
+func (e *exporter) flush() error {
+	if e.csv != nil {
+		e.csv.Flush()
+		if err := e.csv.Error(); err != nil {
+			return err
+		}
+	}
+	if err := e.bw.Flush(); err != nil {
+		return err
+	}
+	if err := e.rc.Flush(); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		return err
+	}
+	return nil
+}
+
+// csvSafe prefixes v with a quote if it starts with a character a spreadsheet would take for the start of a formula,
+// so that an uploaded file name or tag cannot run a formula when the export is opened.
+func csvSafe(v string) string {
+	if v != "" && strings.ContainsRune("=+-@\t\r", rune(v[0])) {
+		return "'" + v
+	}
+	return v
+}
@@ -0,0 +1,51 @@
+package web
+
+import (
+	"strings"
+)
+
+// extensionPolicy restricts the extensions of the names of the uploaded files. An extension, such as ".pdf"
+// or ".tar.gz", matches the file names ending with it, ignoring case.
+type extensionPolicy struct {
+	allow []string // allow lists the accepted extensions, any file name is accepted if empty.
+	deny  []string // deny lists the rejected extensions, it prevails over allow.
+}
+
+// WithExtensionPolicy makes the upload handler reject the files whose name does not end with one of the allowed
+// extensions, if any, or ends with one of the denied extensions, e.g. "exe" or ".tar.gz".
+// The rejected uploads are answered 415 without being passed to the service.
+func WithExtensionPolicy(allow, deny []string) Option {
+	return func(d *DocumentMux) {
+		d.extensions = extensionPolicy{allow: normalizeExtensions(allow), deny: normalizeExtensions(deny)}
+	}
+}
+
+// allows reports whether the policy accepts the file name.
+func (p extensionPolicy) allows(name string) bool {
+	name = strings.ToLower(name)
+	if hasExtension(name, p.deny) {
+		return false
+	}
+	return len(p.allow) == 0 || hasExtension(name, p.allow)
+}
+
+// hasExtension reports whether the lowercase file name ends with one of the extensions.
+func hasExtension(name string, extensions []string) bool {
+	for _, ext := range extensions {
+		if strings.HasSuffix(name, ext) {
+			return true
+		}
+	}
+	return false
+}
+
+// normalizeExtensions returns the extensions in lowercase, starting with a dot.
+func normalizeExtensions(extensions []string) []string {
+	normalized := make([]string, 0, len(extensions))
+	for _, ext := range extensions {
+		if ext = strings.ToLower(strings.TrimPrefix(ext, ".")); ext != "" {
+			normalized = append(normalized, "."+ext)
+		}
+	}
+	return normalized
+}
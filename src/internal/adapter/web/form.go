@@ -0,0 +1,116 @@
+package web
+
+import (
+	"fmt"
+	"goyav/internal/core/domain"
+	"io"
+	"mime/multipart"
+	"net/url"
+	"slices"
+	"strings"
+)
+
+const (
+	// fieldFile is the name of the form part carrying the document to upload.
+	fieldFile = "file"
+
+	// fieldTag is the name of the optional form field carrying the document's tag.
+	fieldTag = "tag"
+
+	// fieldPriority is the name of the optional form field carrying the priority of the analysis,
+	// "interactive" (default) or "batch".
+	fieldPriority = "priority"
+
+	// fieldCallbackURL is the name of the optional form field carrying the URL notified of the result of the analysis.
+	fieldCallbackURL = "callback_url"
+
+	// fieldLabels is the name of the optional form field carrying the labels of the document, as a JSON object
+	// of strings.
+	fieldLabels = "labels"
+
+	// fieldEngine is the name of the optional form field, or query parameter, carrying the comma-separated names of
+	// the engines analyzing the document, "all" (default) for all of them.
+	fieldEngine = "engine"
+)
+
+// Codes reported in FieldError.Code.
+const (
+	codeMissing       = "missing"
+	codeDuplicatePart = "duplicate_part"
+	codeMisplacedPart = "misplaced_part"
+	codeUnknownField  = "unknown_field"
+	codeTooLong       = "too_long"
+	codeEmpty         = "empty"
+	codeInvalid       = "invalid"
+
+	codeUnsupportedMediaType = "unsupported_media_type"
+	codeUnsupportedExtension = "unsupported_extension"
+)
+
+// MaxUploadFiles is the maximum number of file parts of an upload request.
+const MaxUploadFiles = 32
+
+// uploadValueFields lists the non-file form fields accepted by the upload handler.
+var uploadValueFields = []string{fieldTag, fieldPriority, fieldCallbackURL, fieldLabels, fieldEngine}
+
+// fieldValueMaxLength is the maximum size in bytes of a form field value of an upload request.
+const fieldValueMaxLength = 64 << 10
+
+// readFieldValue reads the value of a form field part, of at most fieldValueMaxLength bytes.
+func readFieldValue(part *multipart.Part) (string, *FieldError, error) {
+	b, err := io.ReadAll(io.LimitReader(part, fieldValueMaxLength+1))
+	if err != nil {
+		return "", nil, err
+	}
+	if len(b) > fieldValueMaxLength {
+		return "", &FieldError{Field: part.FormName(), Code: codeTooLong, Message: fmt.Sprintf("must not exceed %d bytes", fieldValueMaxLength)}, nil
+	}
+	return string(b), nil, nil
+}
+
+// validateUploadFields checks the form fields of an upload request, read before its file parts.
+// It requires at most one value per known field and field values within their size limits, tagMaxLength bytes for
+// the tag. If rejectUnknown is true, any other field is reported as well. The returned errors are sorted by field
+// name, nil means the fields are valid.
+func validateUploadFields(fields url.Values, rejectUnknown bool, tagMaxLength int) []FieldError {
+	var errs []FieldError
+
+	for name, values := range fields {
+		if !slices.Contains(uploadValueFields, name) {
+			if rejectUnknown {
+				errs = append(errs, FieldError{Field: name, Code: codeUnknownField, Message: "unexpected form field"})
+			}
+			continue
+		}
+		if len(values) > 1 {
+			errs = append(errs, FieldError{Field: name, Code: codeDuplicatePart, Message: fmt.Sprintf("at most one value is expected, got %d", len(values))})
+			continue
+		}
+		if name == fieldTag && len(values[0]) > tagMaxLength {
+			errs = append(errs, FieldError{Field: name, Code: codeTooLong, Message: fmt.Sprintf("must not exceed %d bytes", tagMaxLength)})
+		}
+		if name == fieldCallbackURL && len(values[0]) > domain.CallbackURLMaxLength {
+			errs = append(errs, FieldError{Field: name, Code: codeTooLong, Message: fmt.Sprintf("must not exceed %d bytes", domain.CallbackURLMaxLength)})
+		}
+		if name == fieldLabels {
+			if _, err := domain.ParseLabels(values[0]); err != nil {
+				errs = append(errs, FieldError{Field: name, Code: codeInvalid, Message: err.Error()})
+			}
+		}
+		if name == fieldEngine {
+			if _, err := domain.ParseEngines(values[0]); err != nil {
+				errs = append(errs, FieldError{Field: name, Code: codeInvalid, Message: err.Error()})
+			}
+		}
+		if name == fieldPriority {
+			if _, ok := domain.ParsePriority(values[0]); !ok {
+				errs = append(errs, FieldError{Field: name, Code: codeInvalid, Message: "must be interactive or batch"})
+			}
+		}
+	}
+
+	slices.SortStableFunc(errs, func(a, b FieldError) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+	return errs
+}
@@ -1,13 +1,21 @@
 package web
 
 import (
+	"bufio"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"goyav/internal/core/domain"
 	"goyav/internal/core/port"
-	"log"
+	"goyav/pkg/helper"
+	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
+	"net/url"
+	"sync"
+	"time"
 )
 
 func (d *DocumentMux) root(w http.ResponseWriter, r *http.Request) {
@@ -25,7 +33,15 @@ func (d *DocumentMux) getDocumentByIDHandler(w http.ResponseWriter, r *http.Requ
 		writeError(w, http.StatusBadRequest, "please provide a document ID", om)
 		return
 	}
-	doc, err := d.service.GetDocument(r.Context(), id)
+	// The response is held until the analysis completes, for wait at most.
+	wait, err := parseWait(r.URL.Query().Get("wait"))
+	if err != nil {
+		om.ID = id
+		writeError(w, http.StatusBadRequest, "the wait parameter must be a duration, such as 30s, or a number of seconds", om)
+		return
+	}
+	d.extendWriteDeadline(w, r, wait)
+	doc, err := d.awaitDocument(r.Context(), id, wait)
 	if err != nil {
 		switch {
 		case errors.Is(err, port.ErrServiceGetDocumentFailed):
@@ -36,71 +52,440 @@ func (d *DocumentMux) getDocumentByIDHandler(w http.ResponseWriter, r *http.Requ
 			om.ID = id
 			writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
 			return
+		case errors.Is(err, port.ErrServiceDocumentDeleted):
+			om.ID = id
+			writeError(w, http.StatusGone, "the document was deleted.", om)
+			return
 		default:
-			log.Printf("getDocumentHandler: %v", err.Error())
+			slog.ErrorContext(r.Context(), "handler.getDocumentByIDHandler", "error", err.Error())
 			writeError(w, http.StatusInternalServerError, "an error occured", om)
 			return
 		}
 	}
 	om.Message = "document found"
 	om.Document = domain.NewDocumentDTO(doc)
+	d.advisePoll(w, om, doc)
 	writeJson(w, http.StatusOK, om)
 }
 
+// getDocumentHistoryHandler retrieves the events of a document, the changes it went through from its upload on.
+func (d *DocumentMux) getDocumentHistoryHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	events, err := d.service.DocumentHistory(r.Context(), om.ID)
+	switch {
+	case err == nil:
+		om.Message = "document history"
+		om.Events = make([]domain.DocumentEventDTO, len(events))
+		for i, e := range events {
+			om.Events[i] = domain.NewDocumentEventDTO(e)
+		}
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found", om)
+	case errors.Is(err, port.ErrServiceHistoryUnavailable):
+		writeError(w, http.StatusNotImplemented, "the history of the documents is not recorded.", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.getDocumentHistoryHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+	}
+}
+
+// getHashHandler retrieves the document holding the content with the hash of the path, so that a client can check
+// whether a file was analyzed already before uploading it.
+func (d *DocumentMux) getHashHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	doc, err := d.service.LookupHash(r.Context(), r.PathValue("hash"))
+	switch {
+	case err == nil:
+		om.ID = doc.ID
+		om.Message = "document found"
+		om.Document = domain.NewDocumentDTO(doc)
+		d.advisePoll(w, om, doc)
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceInvalidHash):
+		writeError(w, http.StatusBadRequest, "the provided hash is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "no document holds this content, it has to be uploaded", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.getHashHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+	}
+}
+
+// deleteDocumentHandler soft-deletes a document, which can be restored with POST /documents/{id}/restore
+// until it is purged.
+func (d *DocumentMux) deleteDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	doc, err := d.service.DeleteDocument(r.Context(), om.ID)
+	switch {
+	case err == nil:
+		om.Message = "document deleted, it can be restored until it is purged."
+		om.Document = domain.NewDocumentDTO(doc)
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found", om)
+	case errors.Is(err, port.ErrServiceDocumentDeleted):
+		writeError(w, http.StatusGone, "the document was deleted already.", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.deleteDocumentHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while deleting the document", om)
+	}
+}
+
+// restoreDocumentHandler restores a soft-deleted document which has not been purged yet.
+func (d *DocumentMux) restoreDocumentHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	doc, err := d.service.RestoreDocument(r.Context(), om.ID)
+	switch {
+	case err == nil:
+		om.Message = "document restored."
+		om.Document = domain.NewDocumentDTO(doc)
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found, it may have been purged", om)
+	case errors.Is(err, port.ErrServiceDocumentNotDeleted):
+		writeError(w, http.StatusConflict, "the document is not deleted.", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.restoreDocumentHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while restoring the document", om)
+	}
+}
+
 func (d *DocumentMux) postDocumentHandler(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodPost {
 		methodNotAllowed(w, r)
 		return
 	}
 
+	// The tenant may have a maximum upload size of its own.
+	maxUploadSize := int64(d.maxUploadSize)
+	if n := d.service.MaxUploadSize(r.Context()); n > 0 {
+		maxUploadSize = n
+	}
 	var (
 		om               = &ObjectMessage{}
-		reqSizeLim int64 = int64(d.maxUploadSize) + (1 << 10)
+		reqSizeLim int64 = maxUploadSize + (1 << 10)
 	)
 
-	r.Body = http.MaxBytesReader(w, r.Body, reqSizeLim)
+	// A gzip-compressed body is decompressed within the same limit.
+	compressed, err := decodeBody(w, r, reqSizeLim)
+	switch {
+	case errors.Is(err, errUnsupportedEncoding):
+		writeError(w, http.StatusUnsupportedMediaType, "the content encoding of the request is not supported, use gzip or none.", om)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "the request body is not valid gzip data.", om)
+		return
+	}
 	defer r.Body.Close()
-	if err := r.ParseMultipartForm(reqSizeLim); err != nil {
-		slog.Debug(fmt.Sprintf("handler.postDocumentHandler: %v", om.Message), "error", err.Error())
-		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("uploaded data exceeds the maximum allowed size : %v Bytes.", d.maxUploadSize), om)
+	mr, err := r.MultipartReader()
+	if err != nil {
+		writeError(w, http.StatusBadRequest, "the request body is not a multipart form.", om)
 		return
 	}
 
-	tag := r.FormValue("tag")
+	// The parts are read as they come: the form fields first, then the file parts, each of them streamed to the
+	// service as soon as it is reached. A request failing once some of its files are uploaded has them deleted.
+	var (
+		fields  = url.Values{}
+		ctx     context.Context
+		results []UploadResult
+	)
+	reject := func(code int, message string, errs ...FieldError) {
+		d.deleteUploaded(r.Context(), results)
+		om.Errors = errs
+		writeError(w, code, message, om)
+	}
+	rejectBody := func(err error) {
+		slog.DebugContext(r.Context(), "handler.postDocumentHandler: failed to read the request body", "error", err.Error())
+		var tooLarge *http.MaxBytesError
+		switch {
+		case errors.As(err, &tooLarge):
+			reject(http.StatusRequestEntityTooLarge, fmt.Sprintf("uploaded data exceeds the maximum allowed size : %v Bytes.", maxUploadSize))
+		case compressed:
+			reject(http.StatusBadRequest, "the compressed request body is invalid.")
+		default:
+			reject(http.StatusBadRequest, "the request body is not a valid multipart form.")
+		}
+	}
+	for {
+		part, err := mr.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			rejectBody(err)
+			return
+		}
 
-	file, header, err := r.FormFile("file")
-	if err != nil {
-		slog.Error("handler.postDocumentHandler: "+om.Message, "msg", err.Error())
-		writeError(w, http.StatusBadRequest, "failed to upload file", om)
-		return
+		name := part.FormName()
+		if part.FileName() == "" {
+			if ctx != nil {
+				reject(http.StatusBadRequest, "the upload request is invalid", FieldError{Field: name, Code: codeMisplacedPart, Message: "the form fields must precede the file parts"})
+				return
+			}
+			v, ferr, err := readFieldValue(part)
+			switch {
+			case err != nil:
+				rejectBody(err)
+				return
+			case ferr != nil:
+				reject(http.StatusBadRequest, "the upload request is invalid", *ferr)
+				return
+			}
+			fields.Add(name, v)
+			continue
+		}
+		if name != fieldFile {
+			if d.rejectUnknownFields {
+				reject(http.StatusBadRequest, "the upload request is invalid", FieldError{Field: name, Code: codeUnknownField, Message: "unexpected file part"})
+				return
+			}
+			continue
+		}
+
+		if ctx == nil {
+			var errs []FieldError
+			if ctx, errs = d.uploadContext(r, fields); errs != nil {
+				reject(http.StatusBadRequest, "the upload request is invalid", errs...)
+				return
+			}
+		}
+		switch {
+		case len(results) == MaxUploadFiles:
+			reject(http.StatusBadRequest, "the upload request is invalid", FieldError{Field: fieldFile, Code: codeDuplicatePart, Message: fmt.Sprintf("at most %d files are expected", MaxUploadFiles)})
+			return
+		case len(results) == 1 && fields.Get(fieldTag) != "":
+			reject(http.StatusBadRequest, "the upload request is invalid", FieldError{Field: fieldTag, Code: codeInvalid, Message: "a tag can only be given to a single file, the files of a request are tagged with their name"})
+			return
+		}
+
+		code, fom, err := d.uploadFile(ctx, part, fields.Get(fieldTag))
+		if err != nil {
+			rejectBody(err)
+			return
+		}
+		results = append(results, UploadResult{FileName: part.FileName(), Status: code, ObjectMessage: fom})
 	}
-	defer file.Close()
 
-	if header.Size == 0 {
-		writeError(w, http.StatusBadRequest, "the file to upload is empty", om)
-		return
+	switch len(results) {
+	case 0:
+		errs := validateUploadFields(fields, d.rejectUnknownFields, d.service.TagMaxLength())
+		errs = append(errs, FieldError{Field: fieldFile, Code: codeMissing, Message: "a file part is required"})
+		reject(http.StatusBadRequest, "the upload request is invalid", errs...)
+	case 1:
+		if results[0].Status == http.StatusServiceUnavailable {
+			d.writeOverloaded(w, results[0].ObjectMessage)
+			return
+		}
+		writeJson(w, results[0].Status, results[0].ObjectMessage)
+	default:
+		// Each file of a request uploading several is uploaded on its own, and given a result of its own.
+		om.Results = results
+		om.Message = fmt.Sprintf("%d files processed.", len(results))
+		writeJson(w, http.StatusMultiStatus, om)
+	}
+}
+
+// uploadContext validates the form fields of the upload request r, completes them with its query parameters, and
+// returns the context of r carrying them: the analysis of the documents is scheduled with the priority of the
+// upload, interactive unless stated otherwise, by the engines it selects, all of them unless stated otherwise, then
+// its result sent to the callback URL, if any.
+func (d *DocumentMux) uploadContext(r *http.Request, fields url.Values) (context.Context, []FieldError) {
+	if errs := validateUploadFields(fields, d.rejectUnknownFields, d.service.TagMaxLength()); errs != nil {
+		return nil, errs
+	}
+	for name, values := range r.URL.Query() {
+		if !fields.Has(name) {
+			fields[name] = values
+		}
+	}
+
+	ctx := r.Context()
+	if p, ok := domain.ParsePriority(fields.Get(fieldPriority)); ok {
+		ctx = domain.ContextWithPriority(ctx, p)
+	}
+	if u := fields.Get(fieldCallbackURL); u != "" {
+		ctx = domain.ContextWithCallbackURL(ctx, u)
+	}
+	if v := fields.Get(fieldLabels); v != "" {
+		// validated with the fields
+		labels, _ := domain.ParseLabels(v)
+		ctx = domain.ContextWithLabels(ctx, labels)
+	}
+	if v := fields.Get(fieldEngine); v != "" {
+		// the form field is validated with the fields, but not the query parameter
+		engines, err := domain.ParseEngines(v)
+		if err != nil {
+			return nil, []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: err.Error()}}
+		}
+		ctx = domain.ContextWithEngines(ctx, engines)
+	}
+	return ctx, nil
+}
+
+// deleteUploaded deletes the documents created by the uploads of results, those of a request failing after them.
+func (d *DocumentMux) deleteUploaded(ctx context.Context, results []UploadResult) {
+	for _, result := range results {
+		if result.Status != http.StatusCreated {
+			continue
+		}
+		if _, err := d.service.DeleteDocument(ctx, result.ID); err != nil {
+			slog.ErrorContext(ctx, "handler.postDocumentHandler: failed to delete an uploaded document", "ID", result.ID, "error", err.Error())
+		}
+	}
+}
+
+// UploadResult is the outcome of the upload of one of the files of a request uploading several: the status code and
+// the message which would answer a request uploading it alone.
+type UploadResult struct {
+	FileName string `json:"file_name"`
+	Status   int    `json:"status"`
+	*ObjectMessage
+}
+
+// partReaders are the buffered readers of the file parts of the uploads, reused once the file of a part is uploaded.
+var partReaders = sync.Pool{New: func() any { return bufio.NewReaderSize(nil, helper.CopyBufferSize) }}
+
+// uploadFile streams the file part of a multipart form to the service, tagged with tag, or with its name if tag is
+// empty, and returns the status code and the message answering its upload. The error reading the request body, if
+// any, fails the whole request instead.
+func (d *DocumentMux) uploadFile(ctx context.Context, part *multipart.Part, tag string) (int, *ObjectMessage, error) {
+	om := &ObjectMessage{}
+	if !d.extensions.allows(helper.SanitizeFileName(part.FileName())) {
+		om.Errors = []FieldError{{Field: fieldFile, Code: codeUnsupportedExtension, Message: "the extension of the file name is not allowed"}}
+		om.Message = "the extension of the uploaded file is not allowed."
+		return http.StatusUnsupportedMediaType, om, nil
+	}
+
+	buf := partReaders.Get().(*bufio.Reader)
+	buf.Reset(part)
+	defer func() {
+		buf.Reset(nil)
+		partReaders.Put(buf)
+	}()
+	if _, err := buf.Peek(1); err != nil {
+		if !errors.Is(err, io.EOF) {
+			return 0, nil, err
+		}
+		om.Errors = []FieldError{{Field: fieldFile, Code: codeEmpty, Message: fmt.Sprintf("the file to upload %q is empty", part.FileName())}}
+		om.Message = "the upload request is invalid"
+		return http.StatusBadRequest, om, nil
 	}
 
 	if tag == "" {
-		tag = header.Filename
+		tag = part.FileName()
+	}
+	file := &recordingReader{r: buf}
+	ID, err := d.service.Upload(domain.ContextWithFileName(ctx, part.FileName()), file, -1, tag)
+	if file.err != nil {
+		return 0, nil, file.err
 	}
-	ID, err := d.service.Upload(r.Context(), file, header.Size, tag)
 	switch {
 	case err == nil:
 		om.ID = ID
 		om.Message = "document uploaded successfully."
-		writeJson(w, http.StatusCreated, om)
-		return
+		if d.completionEstimates {
+			eta := d.service.EstimateCompletion(file.n).UTC().Round(time.Second)
+			om.EstimatedCompletionAt = &eta
+		}
+		return http.StatusCreated, om, nil
 	case errors.Is(err, port.ErrDocumentAlreadyExists):
 		om.ID = ID
 		om.Message = "document already exists."
-		writeJson(w, http.StatusOK, om)
-		return
+		return http.StatusOK, om, nil
+	case errors.Is(err, port.ErrServiceUnsupportedMediaType):
+		om.Errors = []FieldError{{Field: fieldFile, Code: codeUnsupportedMediaType, Message: "the media type of the file is not allowed"}}
+		om.Message = "the media type of the uploaded file is not allowed."
+		return http.StatusUnsupportedMediaType, om, nil
+	case errors.Is(err, port.ErrServiceFileTooLarge):
+		om.Message = "uploaded data exceeds the maximum file size of the tenant."
+		return http.StatusRequestEntityTooLarge, om, nil
+	case errors.Is(err, port.ErrServiceCallbacksDisabled):
+		om.Errors = []FieldError{{Field: fieldCallbackURL, Code: codeInvalid, Message: "callbacks are not enabled"}}
+		om.Message = "the upload request is invalid"
+		return http.StatusBadRequest, om, nil
+	case errors.Is(err, port.ErrServiceInvalidCallbackURL):
+		om.Errors = []FieldError{{Field: fieldCallbackURL, Code: codeInvalid, Message: "must be an http or https URL of a public host"}}
+		om.Message = "the upload request is invalid"
+		return http.StatusBadRequest, om, nil
+	case errors.Is(err, port.ErrServiceUnknownEngine):
+		om.Errors = []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: "unknown engine"}}
+		om.Message = "the upload request is invalid"
+		return http.StatusBadRequest, om, nil
+	case errors.Is(err, port.ErrServiceQuotaExceeded):
+		om.Message = "quota exceeded."
+		return http.StatusTooManyRequests, om, nil
+	case errors.Is(err, port.ErrServiceOverloaded):
+		om.Message = "too many analyses in progress, retry later."
+		return http.StatusServiceUnavailable, om, nil
 	default:
-		writeError(w, http.StatusInternalServerError, "an error occured while uploading", om)
-		slog.Error("handler.postDocumentHandler: "+om.Message, "msg", err.Error())
+		om.Message = "an error occured while uploading"
+		slog.ErrorContext(ctx, "handler.postDocumentHandler: "+om.Message, "msg", err.Error())
+		return http.StatusInternalServerError, om, nil
+	}
+}
+
+// recordingReader reads a request body, or a part of it, counting the bytes read and recording the error reading it.
+type recordingReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (p *recordingReader) Read(b []byte) (int, error) {
+	n, err := p.r.Read(b)
+	p.n += int64(n)
+	if err != nil && !errors.Is(err, io.EOF) {
+		p.err = err
+	}
+	return n, err
+}
+
+func (d *DocumentMux) getStatsHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	om := &ObjectMessage{}
+	stats, err := d.service.Stats(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.getStatsHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
 		return
 	}
+	om.Message = "statistics computed"
+	om.Stats = domain.NewStatsDTO(stats)
+	writeJson(w, http.StatusOK, om)
+}
+
+func (d *DocumentMux) getQuotaHandler(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		methodNotAllowed(w, r)
+		return
+	}
+	om := &ObjectMessage{}
+	status, err := d.service.QuotaStatus(r.Context())
+	if err != nil {
+		switch {
+		case errors.Is(err, port.ErrServiceQuotasDisabled):
+			writeError(w, http.StatusNotFound, "quotas are not enabled", om)
+			return
+		default:
+			slog.ErrorContext(r.Context(), "handler.getQuotaHandler", "error", err.Error())
+			writeError(w, http.StatusInternalServerError, "an error occured", om)
+			return
+		}
+	}
+	om.Message = "quota found"
+	om.Quota = domain.NewQuotaDTO(status)
+	writeJson(w, http.StatusOK, om)
 }
 
 func (d *DocumentMux) ping(w http.ResponseWriter, r *http.Request) {
@@ -108,14 +493,56 @@ func (d *DocumentMux) ping(w http.ResponseWriter, r *http.Request) {
 		methodNotAllowed(w, r)
 		return
 	}
+	health := d.service.Health(r.Context())
+	if code, body, ok := d.pings.get(health); ok {
+		writeJsonBody(w, code, body)
+		return
+	}
+
 	om := &ObjectMessage{
+		Message:     "PONG : everything is good",
 		Information: d.service.Information(),
 		Version:     d.service.Version(),
+		Health:      health,
 	}
-	if err := d.service.Ping(); err != nil {
-		writeError(w, http.StatusServiceUnavailable, "service unavailable", om)
+	code := http.StatusOK
+	if health.Status != domain.HealthUp {
+		om.Message = "service unavailable"
+		code = http.StatusServiceUnavailable
+	}
+	body, err := json.Marshal(om)
+	if err != nil {
+		writeJson(w, code, om)
 		return
 	}
-	om.Message = "PONG : everything is good"
-	writeJson(w, http.StatusOK, om)
+	body = append(body, '\n') // as encoded by writeJson
+	d.pings.set(health, code, body)
+	writeJsonBody(w, code, body)
+}
+
+// pingCache holds the body of the last answer to GET /ping, precomputed for the health report it was computed from,
+// so that it is not encoded again for each probe while the service reuses its health report, see
+// service.WithHealthCache.
+type pingCache struct {
+	mux       sync.Mutex
+	checkedAt time.Time // checkedAt identifies the health report of the body.
+	code      int
+	body      []byte
+}
+
+// get returns the status code and the body of the answer to GET /ping for health, if they are cached.
+func (c *pingCache) get(health *domain.Health) (int, []byte, bool) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	if c.body == nil || !c.checkedAt.Equal(health.CheckedAt) {
+		return 0, nil, false
+	}
+	return c.code, c.body, true
+}
+
+// set caches the status code and the body of the answer to GET /ping for health.
+func (c *pingCache) set(health *domain.Health, code int, body []byte) {
+	c.mux.Lock()
+	defer c.mux.Unlock()
+	c.checkedAt, c.code, c.body = health.CheckedAt, code, body
 }
@@ -1,8 +1,11 @@
 package web
 
 import (
+	"context"
 	"goyav/internal/core/port"
+	"log/slog"
 	"net/http"
+	"time"
 )
 
 // Default upload size limit in bytes : 1 Mib
@@ -13,14 +16,132 @@ type DocumentMux struct {
 	*http.ServeMux
 	service       port.DocumentService
 	maxUploadSize uint64 // Maximum upload size for documents, in bytes.
+
+	// rejectUnknownFields makes the upload handler reject form fields it does not know.
+	rejectUnknownFields bool
+
+	// completionEstimates makes the upload handler answer with the estimated completion date of the analysis.
+	completionEstimates bool
+
+	// extensions restricts the extensions of the names of the uploaded files.
+	extensions extensionPolicy
+
+	// apiKeys maps the SHA-256 digests of the accepted API keys to their tenant.
+	apiKeys map[string]string
+
+	// tenantHeader is the name of the request header carrying the tenant, if any.
+	tenantHeader string
+
+	// admin serves the /admin routes, which are enabled when it is not nil.
+	admin port.AdminService
+
+	// adminKey is the SHA-256 digest of the API key required by the /admin routes.
+	adminKey string
+
+	// webhooks serves the /admin/webhooks routes, which are enabled along with the /admin routes when the service
+	// manages the webhooks.
+	webhooks port.WebhookService
+
+	// maxImageSize is the maximum size of an image tarball, in bytes.
+	maxImageSize uint64
+
+	// uploadTimeout and uploadMinRate give the time limit to read an upload, which is left to the server when
+	// uploadTimeout is zero, see WithUploadDeadline.
+	uploadTimeout time.Duration
+	uploadMinRate uint64
+
+	// uploadSlots holds a value for each upload request processed, up to its capacity, see WithMaxConcurrentUploads.
+	uploadSlots chan struct{}
+
+	// writeTimeout is the WriteTimeout of the server, extended or lifted by the handlers needing it, see WithWriteTimeout.
+	writeTimeout time.Duration
+
+	// tokenSecret signs the tokens issued by the admin API, which are accepted when it is set.
+	tokenSecret []byte
+
+	// signingClients maps the IDs of the clients signing their requests to their tenant and secret, and
+	// signatureMaxSkew bounds the age of their signatures, see WithSignedRequests.
+	signingClients   map[string]SigningClient
+	signatureMaxSkew time.Duration
+
+	// statusFeed reports the status changes pushed by GET /documents/{id}/events, which is enabled when it is set,
+	// and events dispatches them to the requests.
+	statusFeed port.StatusFeed
+	events     *statusHub
+	stopStatus context.CancelFunc // stopStatus stops listening to the status feed, see Close.
+
+	// openAPISpec is the OpenAPI specification of the API in JSON, served on GET /openapi.json when it is set,
+	// and swaggerUI enables the GET /docs page exploring it.
+	openAPISpec []byte
+	swaggerUI   bool
+
+	// analyzerOnly restricts the routes to the scan of files and the health checks, see WithAnalyzerOnly.
+	analyzerOnly bool
+
+	// pings holds the last answer to GET /ping, see pingCache.
+	pings pingCache
+}
+
+// Option configures optional behaviours of a DocumentMux.
+type Option func(*DocumentMux)
+
+// WithUnknownFieldsRejected makes the upload handler reject requests carrying form fields
+// other than the documented ones, instead of silently ignoring them.
+func WithUnknownFieldsRejected(b bool) Option {
+	return func(d *DocumentMux) {
+		d.rejectUnknownFields = b
+	}
 }
 
-func NewDocumentMux(s port.DocumentService, n uint64) *DocumentMux {
+// WithAnalyzerOnly restricts the routes to POST /scan and the health checks, GET /ping and GET /readyz, for a service
+// without repositories, see service.NewAnalyzer. The other routes are answered 404.
+func WithAnalyzerOnly() Option {
+	return func(d *DocumentMux) {
+		d.analyzerOnly = true
+	}
+}
+
+// WithCompletionEstimates makes the upload handler include in its responses the date at which the analysis
+// of the uploaded document is expected to complete, for the clients to schedule their first poll.
+func WithCompletionEstimates(b bool) Option {
+	return func(d *DocumentMux) {
+		d.completionEstimates = b
+	}
+}
+
+func NewDocumentMux(s port.DocumentService, n uint64, opts ...Option) *DocumentMux {
 	d := &DocumentMux{
 		ServeMux:      http.NewServeMux(),
 		maxUploadSize: n,
+		maxImageSize:  DefaultMaxImageSize,
 		service:       s,
 	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	if d.adminKey != "" {
+		a, ok := s.(port.AdminService)
+		if !ok {
+			slog.Warn("admin routes disabled: the service does not implement the admin operations")
+		}
+		d.admin = a
+		d.webhooks, _ = s.(port.WebhookService)
+	}
+	if d.statusFeed != nil {
+		d.events = newStatusHub()
+		var ctx context.Context
+		ctx, d.stopStatus = context.WithCancel(context.Background())
+		go d.listenStatus(ctx)
+	}
 	d.setup()
 	return d
 }
+
+// Close stops listening to the status feed, if any, once the server is shut down. The event streams still open are
+// no longer pushed the status changes.
+func (d *DocumentMux) Close() error {
+	if d.stopStatus != nil {
+		d.stopStatus()
+	}
+	return nil
+}
@@ -0,0 +1,59 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+)
+
+// DefaultMaxImageSize is the default size limit of an image tarball, in bytes: 1 GiB.
+const DefaultMaxImageSize uint64 = 1 << 30
+
+// WithMaxImageSize sets the maximum size of the image tarballs accepted by POST /images, in bytes.
+func WithMaxImageSize(n uint64) Option {
+	return func(d *DocumentMux) {
+		if n > 0 {
+			d.maxImageSize = n
+		}
+	}
+}
+
+// postImageHandler analyzes the container image tarball sent as the body of the request, as written by docker save
+// or as an OCI image layout, possibly compressed with gzip or zstd, and answers with the findings of each layer.
+func (d *DocumentMux) postImageHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	if r.ContentLength > int64(d.maxImageSize) {
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the image exceeds the maximum allowed size : %v Bytes.", d.maxImageSize), om)
+		return
+	}
+	size := r.ContentLength
+	if size < 0 {
+		// reading one byte more than allowed makes the body reader fail
+		size = int64(d.maxImageSize) + 1
+	}
+	r.Body = http.MaxBytesReader(w, r.Body, int64(d.maxImageSize))
+	defer r.Body.Close()
+	d.extendWriteDeadline(w, r, -1)
+
+	report, err := d.service.AnalyzeImage(r.Context(), r.Body, size)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		om.Message = "image analyzed"
+		om.Image = report
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceImagesDisabled):
+		writeError(w, http.StatusNotFound, "image analysis is not enabled", om)
+	case errors.As(err, &tooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the image exceeds the maximum allowed size : %v Bytes.", d.maxImageSize), om)
+	case errors.Is(err, port.ErrInvalidImage):
+		writeError(w, http.StatusBadRequest, "the body is not a valid image tarball", om)
+	case errors.Is(err, port.ErrImageLimitExceeded):
+		writeError(w, http.StatusUnprocessableEntity, "the image exceeds the unpacking limits", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.postImageHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while analyzing the image", om)
+	}
+}
@@ -0,0 +1,34 @@
+package web
+
+import (
+	"net/http"
+)
+
+// WithMaxConcurrentUploads limits the number of upload requests, POST /documents and POST /images, processed at once,
+// answering 429 to the ones above the limit, so that the memory taken by the parse of their forms stays bounded.
+// The uploads are not limited when n is zero.
+func WithMaxConcurrentUploads(n uint64) Option {
+	return func(d *DocumentMux) {
+		if n > 0 {
+			d.uploadSlots = make(chan struct{}, n)
+		}
+	}
+}
+
+// withUploadLimit passes the request to the next handler if a slot of the upload limiter is free, holding it until
+// the handler returns, it answers 429 otherwise. It has no effect without upload limiter.
+func (d *DocumentMux) withUploadLimit(next http.HandlerFunc) http.HandlerFunc {
+	if d.uploadSlots == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		select {
+		case d.uploadSlots <- struct{}{}:
+			defer func() { <-d.uploadSlots }()
+			next(w, r)
+		default:
+			w.Header().Set("Retry-After", "1")
+			writeError(w, http.StatusTooManyRequests, "too many uploads in progress, retry later.", nil)
+		}
+	}
+}
@@ -0,0 +1,159 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// paramLabel is the name of the repeatable query parameter of the listing selecting the documents carrying
+	// a label, as key:value.
+	paramLabel = "label"
+
+	// paramStatus is the name of the repeatable query parameter of the listing selecting the documents of an
+	// analysis status, or of a comma-separated list of statuses.
+	paramStatus = "status"
+
+	// paramCreatedAfter and paramCreatedBefore are the names of the query parameters of the listing bounding the
+	// creation date of the documents, and paramAnalyzedAfter and paramAnalyzedBefore their analysis date, in RFC 3339
+	// format. The after bounds are included, the before ones excluded.
+	paramCreatedAfter   = "created_after"
+	paramCreatedBefore  = "created_before"
+	paramAnalyzedAfter  = "analyzed_after"
+	paramAnalyzedBefore = "analyzed_before"
+
+	// paramTagPrefix is the name of the query parameter of the listing selecting the documents by prefix of their tag.
+	paramTagPrefix = "tag_prefix"
+
+	// paramLimit is the name of the query parameter of the listing bounding the number of documents listed.
+	paramLimit = "limit"
+
+	// paramCursor is the name of the query parameter of the listing giving the next_cursor of the previous page.
+	paramCursor = "cursor"
+)
+
+// listDocumentsHandler lists the documents of the tenant, the most recent first, matching the filter given by the
+// query parameters, up to the limit parameter. A full page carries the cursor of its last document, from which the
+// next page is listed.
+func (d *DocumentMux) listDocumentsHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	filter, errs := parseDocumentFilter(r.URL.Query())
+	if errs != nil {
+		om.Errors = errs
+		writeError(w, http.StatusBadRequest, "the listing request is invalid", om)
+		return
+	}
+	docs, err := d.service.ListDocuments(r.Context(), filter)
+	if errors.Is(err, port.ErrServiceInvalidFilter) {
+		om.Errors = []FieldError{{Field: paramTagPrefix, Code: codeInvalid, Message: "the documents cannot be listed by this tag prefix"}}
+		writeError(w, http.StatusBadRequest, "the listing request is invalid", om)
+		return
+	}
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.listDocumentsHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while listing the documents", om)
+		return
+	}
+	om.Message = fmt.Sprintf("%d documents found", len(docs))
+	om.Documents = make([]*domain.DocumentDTO, len(docs))
+	for i, doc := range docs {
+		om.Documents[i] = domain.NewDocumentDTO(doc)
+	}
+	limit := filter.Limit
+	if limit <= 0 {
+		limit = domain.DefaultListLimit
+	}
+	if len(docs) > 0 && len(docs) >= limit {
+		om.NextCursor = domain.CursorOf(docs[len(docs)-1]).String()
+	}
+	writeJson(w, http.StatusOK, om)
+}
+
+// parseDocumentFilter returns the filter of the documents listed, given by the query parameters of a listing
+// request, or the errors of the invalid parameters.
+func parseDocumentFilter(query url.Values) (domain.DocumentFilter, []FieldError) {
+	var (
+		filter domain.DocumentFilter
+		errs   []FieldError
+	)
+	for _, v := range query[paramLabel] {
+		key, value, found := strings.Cut(v, ":")
+		if !found {
+			errs = append(errs, FieldError{Field: paramLabel, Code: codeInvalid, Message: "expected key:value"})
+			continue
+		}
+		if filter.Labels == nil {
+			filter.Labels = make(domain.Labels)
+		}
+		filter.Labels[key] = value
+	}
+	if err := filter.Labels.Validate(); err != nil {
+		errs = append(errs, FieldError{Field: paramLabel, Code: codeInvalid, Message: err.Error()})
+	}
+	for _, v := range query[paramStatus] {
+		for _, name := range strings.Split(v, ",") {
+			status, ok := domain.ParseAnalysisStatus(strings.TrimSpace(name))
+			if !ok {
+				errs = append(errs, FieldError{Field: paramStatus, Code: codeInvalid, Message: "must be pending, infected, clean, timeout or error"})
+				continue
+			}
+			filter.Statuses = append(filter.Statuses, status)
+		}
+	}
+	errs = append(errs, parseDates(query, map[string]*time.Time{
+		paramCreatedAfter:   &filter.CreatedAfter,
+		paramCreatedBefore:  &filter.CreatedBefore,
+		paramAnalyzedAfter:  &filter.AnalyzedAfter,
+		paramAnalyzedBefore: &filter.AnalyzedBefore,
+	})...)
+	filter.TagPrefix = query.Get(paramTagPrefix)
+	if v := query.Get(paramLimit); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > domain.MaxListLimit {
+			errs = append(errs, FieldError{Field: paramLimit, Code: codeInvalid, Message: fmt.Sprintf("must be an integer between 1 and %d", domain.MaxListLimit)})
+		}
+		filter.Limit = n
+	}
+	if v := query.Get(paramCursor); v != "" {
+		cursor, err := domain.ParseDocumentCursor(v)
+		if err != nil {
+			errs = append(errs, FieldError{Field: paramCursor, Code: codeInvalid, Message: "must be the next_cursor of a previous page"})
+		}
+		filter.After = cursor
+	}
+	sortFieldErrors(errs)
+	return filter, errs
+}
+
+// parseDates sets the dates given by the query parameters in RFC 3339 format to the bounds of the same names, and
+// returns the errors of the invalid ones.
+func parseDates(query url.Values, bounds map[string]*time.Time) []FieldError {
+	var errs []FieldError
+	for param, bound := range bounds {
+		if v := query.Get(param); v != "" {
+			t, err := time.Parse(time.RFC3339, v)
+			if err != nil {
+				errs = append(errs, FieldError{Field: param, Code: codeInvalid, Message: "must be a date in RFC 3339 format, e.g. 2024-03-18T00:00:00Z"})
+				continue
+			}
+			*bound = t
+		}
+	}
+	return errs
+}
+
+// sortFieldErrors sorts errs by field, so that the errors of a request are reported in a stable order.
+func sortFieldErrors(errs []FieldError) {
+	slices.SortStableFunc(errs, func(a, b FieldError) int {
+		return strings.Compare(a.Field, b.Field)
+	})
+}
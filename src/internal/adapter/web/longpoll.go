@@ -0,0 +1,85 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"strconv"
+	"time"
+)
+
+const (
+	// MaxDocumentWait is the longest a GET /documents/{id} request waits for the analysis of a pending document,
+	// longer waits are shortened to it.
+	MaxDocumentWait = 60 * time.Second
+
+	// documentWaitPollInterval is the interval between two checks of the status of an awaited document, when the
+	// status changes are not pushed by a status feed or may have been missed.
+	documentWaitPollInterval = time.Second
+)
+
+// errInvalidWait is returned when the wait query parameter is neither a duration nor a number of seconds.
+var errInvalidWait = errors.New("invalid wait")
+
+// parseWait parses the wait query parameter of GET /documents/{id}, a duration such as "30s" or a number of seconds,
+// shortened to MaxDocumentWait. An empty value means no wait.
+func parseWait(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	wait, err := time.ParseDuration(v)
+	if err != nil {
+		seconds, serr := strconv.ParseUint(v, 10, 32)
+		if serr != nil {
+			return 0, errInvalidWait
+		}
+		wait = time.Duration(seconds) * time.Second
+	}
+	if wait < 0 {
+		return 0, errInvalidWait
+	}
+	return min(wait, MaxDocumentWait), nil
+}
+
+// awaitDocument retrieves the document identified by ID, waiting up to wait for its status to leave pending. The
+// status is checked again each time the status feed reports a change of it, if enabled, and at least every
+// documentWaitPollInterval. The document is returned pending when the wait expires, and the wait ends with ctx.
+func (d *DocumentMux) awaitDocument(ctx context.Context, ID string, wait time.Duration) (*domain.Document, error) {
+	// Subscribe first, so that no change is missed between the retrieval of the document and the subscription.
+	var changed <-chan struct{}
+	if d.events != nil {
+		var unsubscribe func()
+		changed, unsubscribe = d.events.subscribe(ID)
+		defer unsubscribe()
+	}
+
+	doc, err := d.service.GetDocument(ctx, ID)
+	if err != nil || doc.Status != domain.StatusPending || wait <= 0 {
+		return doc, err
+	}
+
+	deadline := time.NewTimer(wait)
+	defer deadline.Stop()
+	poll := time.NewTicker(documentWaitPollInterval)
+	defer poll.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return doc, nil
+		case <-deadline.C:
+			return doc, nil
+		case <-changed:
+		case <-poll.C:
+		}
+		next, err := d.service.GetDocument(ctx, ID)
+		if err != nil {
+			if ctx.Err() != nil {
+				return doc, nil
+			}
+			return nil, err
+		}
+		if doc = next; doc.Status != domain.StatusPending {
+			return doc, nil
+		}
+	}
+}
@@ -0,0 +1,23 @@
+package web
+
+import (
+	"bufio"
+	"net/http"
+	"strconv"
+)
+
+// metricsContentType is the content type of the Prometheus text exposition format.
+const metricsContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// metricsHandler writes the measures of the service in the Prometheus text exposition format.
+func (d *DocumentMux) metricsHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", metricsContentType)
+	w.WriteHeader(http.StatusOK)
+	bw := bufio.NewWriter(w)
+	for _, m := range d.admin.Metrics(r.Context()) {
+		bw.WriteString("# HELP " + m.Name + " " + m.Help + "\n")
+		bw.WriteString("# TYPE " + m.Name + " " + string(m.Kind) + "\n")
+		bw.WriteString(m.Name + " " + strconv.FormatFloat(m.Value, 'g', -1, 64) + "\n")
+	}
+	bw.Flush()
+}
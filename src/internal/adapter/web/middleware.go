@@ -0,0 +1,104 @@
+package web
+
+import (
+	"context"
+	"crypto/x509"
+	"goyav/pkg/helper"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// HeaderRequestID is the header carrying the ID of a request, sent back with its response.
+const HeaderRequestID = "X-Request-ID"
+
+// HeaderTraceparent is the W3C Trace Context header carrying the trace a request is part of.
+const HeaderTraceparent = "traceparent"
+
+// ServeHTTP serves a request with the routes of the DocumentMux, after tagging it with a request ID, and logs it.
+// The request ID is taken from the X-Request-ID header when it is valid, generated otherwise, sent back in the
+// X-Request-ID header of the response and carried by the context of the request, for downstream log lines. The trace
+// context of a valid traceparent header is carried by the context as well, correlating the log lines to the trace.
+// The X-Request-Timeout header bounds the time the handler and the service work on the request: its context is
+// cancelled once it is over, and the server errors it causes are answered with 504 Gateway Timeout. The analysis of an
+// upload is not bounded by it. The subject of the certificate the client authenticated with over TLS, if any, is
+// logged with the request.
+func (d *DocumentMux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	start := time.Now()
+
+	ID := r.Header.Get(HeaderRequestID)
+	if !helper.IsValidRequestID(ID) {
+		ID = helper.NewRequestID()
+	}
+	w.Header().Set(HeaderRequestID, ID)
+	r = r.WithContext(helper.ContextWithRequestID(r.Context(), ID))
+	if tc, ok := helper.ParseTraceparent(r.Header.Get(HeaderTraceparent)); ok {
+		r = r.WithContext(helper.ContextWithTraceContext(r.Context(), tc))
+	}
+
+	rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+	if timeout, err := parseRequestTimeout(r.Header.Get(HeaderRequestTimeout)); err != nil {
+		writeError(rec, http.StatusBadRequest, "Invalid X-Request-Timeout header, expected a duration such as 2.5s or a number of seconds.", nil)
+	} else {
+		var cancel context.CancelFunc
+		r, cancel = withRequestTimeout(r, timeout)
+		defer cancel()
+		rec.ctx = r.Context()
+		d.ServeMux.ServeHTTP(rec, r)
+	}
+
+	args := []any{
+		"method", r.Method,
+		"path", r.URL.Path,
+		"status", rec.status,
+		"duration", time.Since(start),
+		"request_size", r.ContentLength,
+		"response_size", rec.size,
+		"remote_addr", r.RemoteAddr,
+	}
+	if cert := clientCertificate(r); cert != nil {
+		args = append(args, "client_certificate", cert.Subject.String(), "client_certificate_serial", cert.SerialNumber.String())
+	}
+	slog.InfoContext(r.Context(), "http request", args...)
+}
+
+// clientCertificate returns the certificate the client of r authenticated with over TLS, nil if none.
+func clientCertificate(r *http.Request) *x509.Certificate {
+	if r.TLS == nil || len(r.TLS.PeerCertificates) == 0 {
+		return nil
+	}
+	return r.TLS.PeerCertificates[0]
+}
+
+// responseRecorder records the status code and the size of the body of a response. The server errors of a request
+// whose X-Request-Timeout is over, the context ctx of the request, are turned into 504 Gateway Timeout.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	size        int64
+	wroteHeader bool
+	ctx         context.Context
+}
+
+func (rr *responseRecorder) WriteHeader(code int) {
+	if !rr.wroteHeader {
+		if code >= http.StatusInternalServerError && rr.ctx != nil && requestTimedOut(rr.ctx) {
+			code = http.StatusGatewayTimeout
+		}
+		rr.status = code
+		rr.wroteHeader = true
+	}
+	rr.ResponseWriter.WriteHeader(code)
+}
+
+func (rr *responseRecorder) Write(b []byte) (int, error) {
+	rr.wroteHeader = true
+	n, err := rr.ResponseWriter.Write(b)
+	rr.size += int64(n)
+	return n, err
+}
+
+// Unwrap returns the wrapped ResponseWriter, for http.ResponseController.
+func (rr *responseRecorder) Unwrap() http.ResponseWriter {
+	return rr.ResponseWriter
+}
@@ -0,0 +1,57 @@
+package web
+
+import (
+	"log/slog"
+	"net/http"
+)
+
+// swaggerUIVersion is the major version of the Swagger UI distribution loaded by GET /docs.
+const swaggerUIVersion = "5"
+
+// swaggerUIPage renders /openapi.json with Swagger UI, whose assets are loaded from the unpkg CDN.
+const swaggerUIPage = `<!DOCTYPE html>
+<html lang="en">
+<head>
+  <meta charset="utf-8">
+  <meta name="viewport" content="width=device-width, initial-scale=1">
+  <title>GoyAV API</title>
+  <link rel="stylesheet" href="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui.css">
+</head>
+<body>
+  <div id="swagger-ui"></div>
+  <script src="https://unpkg.com/swagger-ui-dist@` + swaggerUIVersion + `/swagger-ui-bundle.js" crossorigin></script>
+  <script>
+    window.onload = () => {
+      window.ui = SwaggerUIBundle({url: "/openapi.json", dom_id: "#swagger-ui"});
+    };
+  </script>
+</body>
+</html>
+`
+
+// WithOpenAPISpec enables the GET /openapi.json route serving spec, the OpenAPI specification of the API in JSON,
+// along with the GET /docs page exploring it with Swagger UI when ui is true.
+func WithOpenAPISpec(spec []byte, ui bool) Option {
+	return func(d *DocumentMux) {
+		d.openAPISpec = spec
+		d.swaggerUI = ui
+	}
+}
+
+// getOpenAPIHandler writes the OpenAPI specification of the API.
+func (d *DocumentMux) getOpenAPIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write(d.openAPISpec); err != nil {
+		slog.DebugContext(r.Context(), "handler.getOpenAPIHandler", "error", err.Error())
+	}
+}
+
+// getSwaggerUIHandler writes the Swagger UI page exploring the OpenAPI specification of the API.
+func (d *DocumentMux) getSwaggerUIHandler(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	if _, err := w.Write([]byte(swaggerUIPage)); err != nil {
+		slog.DebugContext(r.Context(), "handler.getSwaggerUIHandler", "error", err.Error())
+	}
+}
@@ -0,0 +1,56 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// HeaderRequestTimeout is the header with which a client bounds the time GoyAV works on its request.
+const HeaderRequestTimeout = "X-Request-Timeout"
+
+var (
+	// errInvalidRequestTimeout is returned when the X-Request-Timeout header is neither a strictly positive duration
+	// nor a strictly positive number of seconds.
+	errInvalidRequestTimeout = errors.New("invalid " + HeaderRequestTimeout)
+
+	// errRequestTimeout is the cause of the cancellation of the context of a request once its X-Request-Timeout is over.
+	errRequestTimeout = errors.New(HeaderRequestTimeout + " exceeded")
+)
+
+// parseRequestTimeout parses the X-Request-Timeout header, a duration such as "2.5s" or a number of seconds. An empty
+// value means no timeout.
+func parseRequestTimeout(v string) (time.Duration, error) {
+	if v == "" {
+		return 0, nil
+	}
+	timeout, err := time.ParseDuration(v)
+	if err != nil {
+		seconds, serr := strconv.ParseUint(v, 10, 32)
+		if serr != nil {
+			return 0, errInvalidRequestTimeout
+		}
+		timeout = time.Duration(seconds) * time.Second
+	}
+	if timeout <= 0 {
+		return 0, errInvalidRequestTimeout
+	}
+	return timeout, nil
+}
+
+// withRequestTimeout returns r with a context cancelled once timeout is over, with errRequestTimeout as cause, and
+// the function releasing it. r is returned as is if timeout is zero.
+func withRequestTimeout(r *http.Request, timeout time.Duration) (*http.Request, context.CancelFunc) {
+	if timeout <= 0 {
+		return r, func() {}
+	}
+	ctx, cancel := context.WithTimeoutCause(r.Context(), timeout, errRequestTimeout)
+	return r.WithContext(ctx), cancel
+}
+
+// requestTimedOut reports whether the X-Request-Timeout of the request of ctx is over.
+func requestTimedOut(ctx context.Context) bool {
+	return errors.Is(context.Cause(ctx), errRequestTimeout)
+}
@@ -5,11 +5,81 @@ func (d *DocumentMux) setup() {
 	// root
 	d.HandleFunc("GET /{$}", d.root)
 
+	// a service without repositories only scans files
+	if d.analyzerOnly {
+		d.HandleFunc("POST /scan", d.withUploadLimit(d.withUploadDeadline(d.maxUploadSize, d.withBodyLimit(d.uploadBodyLimit, d.withTenant(ScopeUpload, d.postScanHandler)))))
+		d.HandleFunc("GET /ping/", d.ping)
+		d.HandleFunc("GET /readyz", d.readyHandler)
+		return
+	}
+
 	// /documents
-	d.HandleFunc("GET /documents", methodNotAllowed)
-	d.HandleFunc("POST /documents", d.postDocumentHandler)
-	d.HandleFunc("GET /documents/{id}", d.getDocumentByIDHandler)
+	d.HandleFunc("GET /documents", d.withTenant(ScopeRead, d.listDocumentsHandler))
+	d.HandleFunc("POST /documents", d.withUploadLimit(d.withUploadDeadline(d.maxUploadSize, d.withBodyLimit(d.uploadBodyLimit, d.withTenant(ScopeUpload, d.postDocumentHandler)))))
+	d.HandleFunc("GET /documents/export", d.withTenant(ScopeRead, d.exportDocumentsHandler))
+	d.HandleFunc("GET /documents/{id}", d.withTenant(ScopeRead, d.getDocumentByIDHandler))
+	d.HandleFunc("DELETE /documents/{id}", d.withTenant(ScopeUpload, d.deleteDocumentHandler))
+	d.HandleFunc("POST /documents/{id}/restore", d.withTenant(ScopeUpload, d.restoreDocumentHandler))
+	d.HandleFunc("GET /documents/{id}/download", d.withTenant(ScopeRead, d.getDownloadHandler))
+	d.HandleFunc("GET /documents/{id}/history", d.withTenant(ScopeRead, d.getDocumentHistoryHandler))
+	if d.events != nil {
+		d.HandleFunc("GET /documents/{id}/events", d.withTenant(ScopeRead, d.getDocumentEventsHandler))
+	}
+
+	// /hash
+	d.HandleFunc("GET /hash/{hash}", d.withTenant(ScopeRead, d.getHashHandler))
+
+	// /uploads
+	d.HandleFunc("POST /uploads", d.withTenant(ScopeUpload, d.postUploadHandler))
+	d.HandleFunc("POST /uploads/{id}/confirm", d.withTenant(ScopeUpload, d.confirmUploadHandler))
+
+	// /images
+	d.HandleFunc("POST /images", d.withUploadLimit(d.withUploadDeadline(d.maxImageSize, d.withBodyLimit(d.imageBodyLimit, d.withTenant(ScopeUpload, d.postImageHandler)))))
+
+	// /scan
+	d.HandleFunc("POST /scan", d.withUploadLimit(d.withUploadDeadline(d.maxUploadSize, d.withBodyLimit(d.uploadBodyLimit, d.withTenant(ScopeUpload, d.postScanHandler)))))
+
+	// /verdicts
+	d.HandleFunc("POST /verdicts", d.withTenant(ScopeReport, d.postVerdictHandler))
+
+	// /stats
+	d.HandleFunc("GET /stats", d.withTenant(ScopeRead, d.getStatsHandler))
+
+	// /quota
+	d.HandleFunc("GET /quota", d.withTenant(ScopeRead, d.getQuotaHandler))
 
 	// /ping
 	d.HandleFunc("GET /ping/", d.ping)
+
+	// /readyz
+	d.HandleFunc("GET /readyz", d.readyHandler)
+
+	// /openapi.json
+	if d.openAPISpec != nil {
+		d.HandleFunc("GET /openapi.json", d.getOpenAPIHandler)
+		if d.swaggerUI {
+			d.HandleFunc("GET /docs", d.getSwaggerUIHandler)
+		}
+	}
+
+	// /admin
+	if d.admin != nil {
+		d.HandleFunc("GET /admin/reconcile", d.withAdmin(d.reconcileHandler))
+		d.HandleFunc("POST /admin/reconcile", d.withAdmin(d.reconcileHandler))
+		d.HandleFunc("POST /admin/purge", d.withAdmin(d.purgeHandler))
+		d.HandleFunc("POST /admin/requeue", d.withAdmin(d.requeueHandler))
+		d.HandleFunc("GET /admin/metrics", d.withAdmin(d.metricsHandler))
+		d.HandleFunc("GET /admin/concurrency", d.withAdmin(d.concurrencyHandler))
+		d.HandleFunc("PUT /admin/concurrency", d.withAdmin(d.concurrencyHandler))
+	}
+	if d.admin != nil && d.webhooks != nil {
+		d.HandleFunc("GET /admin/webhooks", d.withAdmin(d.listWebhooksHandler))
+		d.HandleFunc("POST /admin/webhooks", d.withAdmin(d.createWebhookHandler))
+		d.HandleFunc("GET /admin/webhooks/{id}", d.withAdmin(d.getWebhookHandler))
+		d.HandleFunc("PATCH /admin/webhooks/{id}", d.withAdmin(d.updateWebhookHandler))
+		d.HandleFunc("DELETE /admin/webhooks/{id}", d.withAdmin(d.deleteWebhookHandler))
+	}
+	if d.adminKey != "" && d.tokenSecret != nil {
+		d.HandleFunc("POST /admin/tokens", d.withAdmin(d.issueTokenHandler))
+	}
 }
@@ -0,0 +1,80 @@
+package web
+
+import (
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+)
+
+// postScanHandler analyzes the file sent as the body of the request, possibly compressed with gzip, and answers with
+// its verdict, without storing it nor recording a document. The engine and priority query parameters select the
+// engines analyzing the file and the priority of its analysis, as the form fields of an upload do.
+func (d *DocumentMux) postScanHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	maxUploadSize := int64(d.maxUploadSize)
+	if n := d.service.MaxUploadSize(r.Context()); n > 0 {
+		maxUploadSize = n
+	}
+	compressed, err := decodeBody(w, r, maxUploadSize)
+	switch {
+	case errors.Is(err, errUnsupportedEncoding):
+		writeError(w, http.StatusUnsupportedMediaType, "the content encoding of the request is not supported, use gzip or none.", om)
+		return
+	case err != nil:
+		writeError(w, http.StatusBadRequest, "the request body is not valid gzip data.", om)
+		return
+	}
+	defer r.Body.Close()
+	d.extendWriteDeadline(w, r, -1)
+
+	ctx := r.Context()
+	query := r.URL.Query()
+	if v := query.Get(fieldPriority); v != "" {
+		p, ok := domain.ParsePriority(v)
+		if !ok {
+			om.Errors = []FieldError{{Field: fieldPriority, Code: codeInvalid, Message: "must be interactive or batch"}}
+			writeError(w, http.StatusBadRequest, "the scan request is invalid", om)
+			return
+		}
+		ctx = domain.ContextWithPriority(ctx, p)
+	}
+	if v := query.Get(fieldEngine); v != "" {
+		engines, err := domain.ParseEngines(v)
+		if err != nil {
+			om.Errors = []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: err.Error()}}
+			writeError(w, http.StatusBadRequest, "the scan request is invalid", om)
+			return
+		}
+		ctx = domain.ContextWithEngines(ctx, engines)
+	}
+
+	body := &recordingReader{r: r.Body}
+	report, err := d.service.Scan(ctx, body, -1)
+	var tooLarge *http.MaxBytesError
+	switch {
+	case err == nil:
+		om.Message = "file scanned"
+		om.Scan = report
+		writeJson(w, http.StatusOK, om)
+	case errors.As(body.err, &tooLarge), errors.Is(err, port.ErrServiceFileTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, fmt.Sprintf("the file exceeds the maximum allowed size : %v Bytes.", maxUploadSize), om)
+	case body.err != nil:
+		slog.DebugContext(r.Context(), "handler.postScanHandler: failed to read the request body", "error", body.err.Error())
+		if compressed {
+			writeError(w, http.StatusBadRequest, "the compressed request body is invalid.", om)
+			return
+		}
+		writeError(w, http.StatusBadRequest, "failed to read the request body.", om)
+	case errors.Is(err, port.ErrServiceUnknownEngine):
+		om.Errors = []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: "unknown engine"}}
+		writeError(w, http.StatusBadRequest, "the scan request is invalid", om)
+	case errors.Is(err, port.ErrServiceOverloaded):
+		d.writeOverloaded(w, om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.postScanHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while scanning the file", om)
+	}
+}
@@ -0,0 +1,182 @@
+package web
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/pkg/helper"
+	"io"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+)
+
+// Request headers of the requests signed with the shared secret of a client, see WithSignedRequests.
+const (
+	// HeaderSignatureClient carries the ID of the client signing the request.
+	HeaderSignatureClient = "X-Signature-Client"
+
+	// HeaderSignatureTimestamp carries the date the request was signed at, in seconds since the Unix epoch.
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+
+	// HeaderSignature carries the hex-encoded HMAC-SHA256 signature of the request, see helper.NewRequestMAC.
+	HeaderSignature = "X-Signature"
+)
+
+const (
+	// DefaultSignatureMaxSkew is the default largest difference between the timestamp of a signed request and the
+	// date it is received at.
+	DefaultSignatureMaxSkew = 5 * time.Minute
+
+	// SigningSecretMinLength is the minimum length in bytes of the secret shared with a signing client.
+	SigningSecretMinLength = 32
+
+	// signedBodyMemory is the size of the body of a signed request held in memory while its signature is verified,
+	// the larger bodies are spilled to a temporary file.
+	signedBodyMemory = 1 << 20
+
+	// maxSignedRequestSize is the maximum size of the body of a signed request on the routes without a limit of their
+	// own, see withBodyLimit.
+	maxSignedRequestSize = 64 << 10
+)
+
+var (
+	// errInvalidSignature is returned when the signature of a request is missing, malformed or does not match it.
+	errInvalidSignature = errors.New("invalid signature")
+
+	// errStaleSignature is returned when the timestamp of a signed request is too far from the current date.
+	errStaleSignature = errors.New("stale signature")
+)
+
+// SigningClient is a client signing its requests with a shared secret.
+type SigningClient struct {
+	Tenant string // Tenant is the tenant of the documents of the client.
+	Secret []byte // Secret is the secret signing the requests of the client.
+}
+
+// WithSignedRequests makes the document routes accept the requests signed with HMAC-SHA256 by the given clients,
+// mapped by their ID. A signed request carries the ID of its client in the X-Signature-Client header, the date it
+// was signed at in the X-Signature-Timestamp header, which must be within maxSkew of the current date, and its
+// signature in the X-Signature header, see helper.NewRequestMAC. It is given the tenant of its client. The unsigned
+// requests are then refused unless they carry an API key, a tenant header, a bearer token or a client certificate.
+func WithSignedRequests(clients map[string]SigningClient, maxSkew time.Duration) Option {
+	return func(d *DocumentMux) {
+		d.signingClients = clients
+		d.signatureMaxSkew = maxSkew
+	}
+}
+
+// bodyLimitKey is the key of the context of a request holding the limit of the size of its body, see withBodyLimit.
+type bodyLimitKey struct{}
+
+// withBodyLimit bounds the size of the body of the signed requests of the route by the limit returned for the tenant
+// of their client, before their body is read to verify their signature. The signed requests of the routes without
+// limit are bounded by maxSignedRequestSize. It has no effect without signing clients.
+func (d *DocumentMux) withBodyLimit(limit func(ctx context.Context) int64, next http.HandlerFunc) http.HandlerFunc {
+	if d.signingClients == nil {
+		return next
+	}
+	return func(w http.ResponseWriter, r *http.Request) {
+		next(w, r.WithContext(context.WithValue(r.Context(), bodyLimitKey{}, limit)))
+	}
+}
+
+// uploadBodyLimit is the limit of the size of the body of an upload of the tenant of ctx, the form fields included.
+func (d *DocumentMux) uploadBodyLimit(ctx context.Context) int64 {
+	if n := d.service.MaxUploadSize(ctx); n > 0 {
+		return n + (1 << 10)
+	}
+	return int64(d.maxUploadSize) + (1 << 10)
+}
+
+// imageBodyLimit is the limit of the size of the body of an image analysis.
+func (d *DocumentMux) imageBodyLimit(context.Context) int64 {
+	return int64(d.maxImageSize)
+}
+
+// verifySignature checks the signature of the signed request r and returns the tenant of its client, along with the
+// body of r, read to be verified, to be served instead of it and closed once served. The body is read within the
+// limit of the route, see withBodyLimit, failing with a *http.MaxBytesError beyond it.
+func (d *DocumentMux) verifySignature(w http.ResponseWriter, r *http.Request) (string, io.ReadCloser, error) {
+	ID := r.Header.Get(HeaderSignatureClient)
+	client, ok := d.signingClients[ID]
+	if !ok {
+		return "", nil, fmt.Errorf("%w: unknown client %q", errInvalidSignature, ID)
+	}
+	timestamp := r.Header.Get(HeaderSignatureTimestamp)
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: invalid timestamp %q of client %q", errInvalidSignature, timestamp, ID)
+	}
+	if skew := time.Since(time.Unix(seconds, 0)).Abs(); skew > d.signatureMaxSkew {
+		return "", nil, fmt.Errorf("%w: signed %s away by client %q", errStaleSignature, skew.Round(time.Second), ID)
+	}
+	signature, err := hex.DecodeString(r.Header.Get(HeaderSignature))
+	if err != nil {
+		return "", nil, fmt.Errorf("%w: malformed signature of client %q", errInvalidSignature, ID)
+	}
+
+	limit := int64(maxSignedRequestSize)
+	if f, ok := r.Context().Value(bodyLimitKey{}).(func(context.Context) int64); ok {
+		limit = f(domain.ContextWithTenant(r.Context(), client.Tenant))
+	}
+	if r.ContentLength > limit {
+		return "", nil, fmt.Errorf("the request of client %q exceeds %d bytes: %w", ID, limit, &http.MaxBytesError{Limit: limit})
+	}
+
+	mac := helper.NewRequestMAC(client.Secret, timestamp, r.Method, r.URL.RequestURI())
+	body, err := spoolBody(http.MaxBytesReader(w, r.Body, limit), mac)
+	if err != nil {
+		return "", nil, fmt.Errorf("failed to read the body of the request of client %q: %w", ID, err)
+	}
+	if !hmac.Equal(signature, mac.Sum(nil)) {
+		body.Close()
+		return "", nil, fmt.Errorf("%w: the request of client %q does not match its signature", errInvalidSignature, ID)
+	}
+	return client.Tenant, body, nil
+}
+
+// spoolBody reads src, writing it to w as well, and returns a copy of it, held in memory up to signedBodyMemory
+// bytes, spilled to a temporary file removed once it is closed otherwise.
+func spoolBody(src io.Reader, w io.Writer) (io.ReadCloser, error) {
+	var buf bytes.Buffer
+	_, err := io.CopyN(io.MultiWriter(&buf, w), src, signedBodyMemory)
+	switch {
+	case errors.Is(err, io.EOF):
+		return io.NopCloser(&buf), nil
+	case err != nil:
+		return nil, err
+	}
+
+	f, err := os.CreateTemp("", "goyav-signed-*")
+	if err != nil {
+		return nil, err
+	}
+	body := &tempFile{File: f}
+	if _, err = f.Write(buf.Bytes()); err == nil {
+		if _, err = helper.Copy(io.MultiWriter(f, w), src); err == nil {
+			_, err = f.Seek(0, io.SeekStart)
+		}
+	}
+	if err != nil {
+		body.Close()
+		return nil, err
+	}
+	return body, nil
+}
+
+// tempFile is a temporary file removed once it is closed.
+type tempFile struct {
+	*os.File
+}
+
+func (f *tempFile) Close() error {
+	err := f.File.Close()
+	os.Remove(f.Name())
+	return err
+}
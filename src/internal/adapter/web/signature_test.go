@@ -0,0 +1,103 @@
+package web
+
+import (
+	"encoding/hex"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/service"
+	"goyav/pkg/helper"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestMux returns a mux serving a service with mock repositories, files of at most maxUploadSize bytes, configured
+// by opts.
+func newTestMux(t *testing.T, maxUploadSize uint64, opts ...Option) *DocumentMux {
+	t.Helper()
+	s, err := service.New(binaryrepo.NewMock(), docrepo.NewMock(), antivirus.NewMock(), "1.0", "information", time.Second, 16)
+	require.NoError(t, err)
+	return NewDocumentMux(s, maxUploadSize, opts...)
+}
+
+// signRequest signs r, whose body is body, as the client ID with secret.
+func signRequest(r *http.Request, ID string, secret []byte, body string) {
+	ts := strconv.FormatInt(time.Now().Unix(), 10)
+	mac := helper.NewRequestMAC(secret, ts, r.Method, r.URL.RequestURI())
+	mac.Write([]byte(body))
+	r.Header.Set(HeaderSignatureClient, ID)
+	r.Header.Set(HeaderSignatureTimestamp, ts)
+	r.Header.Set(HeaderSignature, hex.EncodeToString(mac.Sum(nil)))
+}
+
+func TestSignedRequests(t *testing.T) {
+	secret := []byte(strings.Repeat("s", SigningSecretMinLength))
+	d := newTestMux(t, 1<<10, WithSignedRequests(map[string]SigningClient{"gateway": {Tenant: "finance", Secret: secret}}, time.Minute))
+
+	tests := []struct {
+		name   string
+		req    func() *http.Request
+		status int
+	}{
+		{
+			name: "signed request",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/stats", nil)
+				signRequest(r, "gateway", secret, "")
+				return r
+			},
+			status: http.StatusOK,
+		},
+		{
+			name: "unsigned request",
+			req: func() *http.Request {
+				return httptest.NewRequest(http.MethodGet, "/stats", nil)
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "wrong signature",
+			req: func() *http.Request {
+				r := httptest.NewRequest(http.MethodGet, "/stats", nil)
+				signRequest(r, "gateway", []byte(strings.Repeat("x", SigningSecretMinLength)), "")
+				return r
+			},
+			status: http.StatusUnauthorized,
+		},
+		{
+			name: "body beyond the upload limit",
+			req: func() *http.Request {
+				body := strings.Repeat("a", 4<<10)
+				r := httptest.NewRequest(http.MethodPost, "/documents", strings.NewReader(body))
+				signRequest(r, "gateway", secret, body)
+				return r
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+		{
+			name: "body of unknown length beyond the route limit",
+			req: func() *http.Request {
+				body := strings.Repeat("a", maxSignedRequestSize+1)
+				r := httptest.NewRequest(http.MethodPost, "/verdicts", strings.NewReader(body))
+				r.ContentLength = -1
+				signRequest(r, "gateway", secret, body)
+				return r
+			},
+			status: http.StatusRequestEntityTooLarge,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			d.ServeHTTP(w, tt.req())
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+}
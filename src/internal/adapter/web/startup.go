@@ -0,0 +1,87 @@
+package web
+
+import (
+	"errors"
+	"goyav/internal/core/domain"
+	"io"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// startupRetryAfter is the delay, in seconds, advised to the clients of a service still starting.
+const startupRetryAfter = "5"
+
+// Startup is the handler of the requests received while the service connects to its dependencies: it answers them
+// 503 until it is given the handler of the API by Ready, then passes them to it.
+type Startup struct {
+	handler atomic.Pointer[http.Handler]
+
+	mu     sync.Mutex
+	closed bool
+	deps   io.Closer // deps releases the dependencies of the handler, see Ready.
+}
+
+// NewStartup creates a Startup answering 503 until Ready is called.
+func NewStartup() *Startup {
+	return &Startup{}
+}
+
+// Ready makes the startup pass the requests to h from now on, deps releasing the dependencies assembled for h, such
+// as its database connection, when the startup is closed. Both are closed at once if the startup is closed already.
+func (s *Startup) Ready(h http.Handler, deps io.Closer) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		closeHandler(h, deps)
+		return
+	}
+	s.deps = deps
+	s.handler.Store(&h)
+}
+
+// Close closes the handler of the API once the server is shut down, if it is ready and implements io.Closer, then
+// its dependencies, or when it gets ready otherwise.
+func (s *Startup) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	if h := s.handler.Load(); h != nil {
+		return closeHandler(*h, s.deps)
+	}
+	return nil
+}
+
+// closeHandler closes h if it implements io.Closer, then deps unless it is nil.
+func closeHandler(h http.Handler, deps io.Closer) error {
+	var errs []error
+	if c, ok := h.(io.Closer); ok {
+		errs = append(errs, c.Close())
+	}
+	if deps != nil {
+		errs = append(errs, deps.Close())
+	}
+	return errors.Join(errs...)
+}
+
+// ServeHTTP passes the request to the handler of the API once it is ready, it answers 503 with a Retry-After header
+// otherwise.
+func (s *Startup) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if h := s.handler.Load(); h != nil {
+		(*h).ServeHTTP(w, r)
+		return
+	}
+	w.Header().Set("Retry-After", startupRetryAfter)
+	writeError(w, http.StatusServiceUnavailable, "service starting: its dependencies are not reachable yet", nil)
+}
+
+// readyHandler reports whether the service is ready to serve requests, which it is when all its dependencies are up.
+// Unlike the ping, it does not detail their health.
+func (d *DocumentMux) readyHandler(w http.ResponseWriter, r *http.Request) {
+	if d.service.Health(r.Context()).Status != domain.HealthUp {
+		w.Header().Set("Retry-After", startupRetryAfter)
+		writeError(w, http.StatusServiceUnavailable, "service not ready", nil)
+		return
+	}
+	writeJson(w, http.StatusOK, &ObjectMessage{Message: "service ready"})
+}
@@ -0,0 +1,44 @@
+package web
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// closeCounter counts the times it is closed.
+type closeCounter int
+
+func (c *closeCounter) Close() error {
+	*c++
+	return nil
+}
+
+func TestStartup(t *testing.T) {
+	t.Run("closed once ready", func(t *testing.T) {
+		s := NewStartup()
+		w := httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+		var deps closeCounter
+		s.Ready(http.NotFoundHandler(), &deps)
+		w = httptest.NewRecorder()
+		s.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping", nil))
+		assert.Equal(t, http.StatusNotFound, w.Code)
+
+		assert.NoError(t, s.Close())
+		assert.Equal(t, closeCounter(1), deps)
+	})
+
+	t.Run("ready once closed", func(t *testing.T) {
+		s := NewStartup()
+		assert.NoError(t, s.Close())
+
+		var deps closeCounter
+		s.Ready(http.NotFoundHandler(), &deps)
+		assert.Equal(t, closeCounter(1), deps)
+	})
+}
@@ -0,0 +1,185 @@
+package web
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/pkg/helper"
+	"log/slog"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Scopes granted by the tokens issued by the admin API.
+const (
+	// ScopeUpload grants the upload of documents.
+	ScopeUpload = "upload"
+
+	// ScopeRead grants the retrieval of documents, statistics and quotas.
+	ScopeRead = "read"
+
+	// ScopeReport grants the report of verdicts by on-access scanning agents.
+	ScopeReport = "report"
+)
+
+const (
+	// DefaultTokenTTL is the lifetime of an issued token when none is requested.
+	DefaultTokenTTL = time.Hour
+
+	// MaxTokenTTL is the longest lifetime of an issued token.
+	MaxTokenTTL = 24 * time.Hour
+
+	// TokenSecretMinLength is the minimum length in bytes of the secret signing the tokens.
+	TokenSecretMinLength = 32
+)
+
+// tokenClaims are the claims of a token issued by the admin API.
+type tokenClaims struct {
+	ID        string   `json:"jti"`
+	Subject   string   `json:"sub,omitempty"`
+	Tenant    string   `json:"tnt"`
+	Scopes    []string `json:"scp"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// tokenRequest is the body of a request for a token.
+type tokenRequest struct {
+	Name   string   `json:"name"`
+	Tenant string   `json:"tenant"`
+	Scopes []string `json:"scopes"`
+	TTL    string   `json:"ttl"`
+}
+
+// IssuedToken describes a token issued by the admin API.
+type IssuedToken struct {
+	Token     string   `json:"token"`
+	ID        string   `json:"id"`
+	Name      string   `json:"name,omitempty"`
+	Tenant    string   `json:"tenant,omitempty"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt string   `json:"expires_at"`
+}
+
+// WithTokenSecret makes the document routes accept the tokens issued by POST /admin/tokens, sent as
+// bearer tokens in the Authorization header, and signs them with secret.
+func WithTokenSecret(secret []byte) Option {
+	return func(d *DocumentMux) {
+		d.tokenSecret = secret
+	}
+}
+
+// multiTenant reports whether the tenant of the requests is resolved from their credentials or from a header, rather
+// than being the default tenant.
+func (d *DocumentMux) multiTenant() bool {
+	return len(d.apiKeys) > 0 || d.tenantHeader != "" || d.signingClients != nil
+}
+
+// bearerToken returns the bearer token of the Authorization header of a request, if any.
+func bearerToken(r *http.Request) (string, bool) {
+	scheme, token, found := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !found || !strings.EqualFold(scheme, "Bearer") || token == "" {
+		return "", false
+	}
+	return token, true
+}
+
+// verifyToken checks a token and returns its claims, unless it is expired or does not grant scope.
+func (d *DocumentMux) verifyToken(token, scope string) (*tokenClaims, int, error) {
+	var c tokenClaims
+	if err := helper.VerifyToken(d.tokenSecret, token, &c); err != nil {
+		return nil, http.StatusUnauthorized, err
+	}
+	if time.Now().Unix() >= c.ExpiresAt {
+		return nil, http.StatusUnauthorized, fmt.Errorf("token %s expired", c.ID)
+	}
+	if c.Tenant == domain.DefaultTenant && d.multiTenant() {
+		return nil, http.StatusUnauthorized, fmt.Errorf("token %s has no tenant", c.ID)
+	}
+	if !slices.Contains(c.Scopes, scope) {
+		return nil, http.StatusForbidden, fmt.Errorf("token %s does not grant the %q scope", c.ID, scope)
+	}
+	return &c, http.StatusOK, nil
+}
+
+// issueTokenHandler issues a token granting scopes on the documents of a tenant until it expires.
+func (d *DocumentMux) issueTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		om  = &ObjectMessage{}
+		req tokenRequest
+	)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 1<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "the request body must be a JSON object", om)
+		return
+	}
+
+	var errs []FieldError
+	switch {
+	case req.Tenant == domain.DefaultTenant:
+		// the default tenant owns the documents only when multi-tenancy is not configured
+		if d.multiTenant() {
+			errs = append(errs, FieldError{Field: "tenant", Code: codeMissing, Message: "the tenant of the token is required"})
+		}
+	case !helper.IsValidTenant(req.Tenant):
+		errs = append(errs, FieldError{Field: "tenant", Code: codeInvalid, Message: "tenant names are made of up to 64 letters, digits, '-' or '_'"})
+	}
+	if len(req.Scopes) == 0 {
+		errs = append(errs, FieldError{Field: "scopes", Code: codeMissing, Message: "at least one scope is required"})
+	}
+	for _, scope := range req.Scopes {
+		if scope != ScopeUpload && scope != ScopeRead && scope != ScopeReport {
+			errs = append(errs, FieldError{Field: "scopes", Code: codeInvalid, Message: fmt.Sprintf("unknown scope %q", scope)})
+		}
+	}
+	ttl := DefaultTokenTTL
+	if req.TTL != "" {
+		v, err := time.ParseDuration(req.TTL)
+		if err != nil || v <= 0 || v > MaxTokenTTL {
+			errs = append(errs, FieldError{Field: "ttl", Code: codeInvalid, Message: "ttl must be a positive duration of at most " + MaxTokenTTL.String()})
+		}
+		ttl = v
+	}
+	if errs != nil {
+		om.Errors = errs
+		writeError(w, http.StatusBadRequest, "the token request is invalid", om)
+		return
+	}
+
+	id := make([]byte, 12)
+	if _, err := rand.Read(id); err != nil {
+		slog.ErrorContext(r.Context(), "handler.issueTokenHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+	scopes := slices.Clone(req.Scopes)
+	slices.Sort(scopes)
+	claims := tokenClaims{
+		ID:        base64.RawURLEncoding.EncodeToString(id),
+		Subject:   req.Name,
+		Tenant:    req.Tenant,
+		Scopes:    slices.Compact(scopes),
+		ExpiresAt: time.Now().Add(ttl).Unix(),
+	}
+	token, err := helper.SignToken(d.tokenSecret, claims)
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.issueTokenHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+		return
+	}
+
+	expiresAt := time.Unix(claims.ExpiresAt, 0).UTC().Format(time.RFC3339)
+	slog.InfoContext(r.Context(), "token issued", "id", claims.ID, "name", claims.Subject, "tenant", claims.Tenant, "scopes", claims.Scopes, "expires_at", expiresAt)
+	om.Message = "token issued"
+	om.Token = &IssuedToken{
+		Token:     token,
+		ID:        claims.ID,
+		Name:      claims.Subject,
+		Tenant:    claims.Tenant,
+		Scopes:    claims.Scopes,
+		ExpiresAt: expiresAt,
+	}
+	writeJson(w, http.StatusCreated, om)
+}
@@ -0,0 +1,63 @@
+package web
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIssueToken(t *testing.T) {
+	secret := WithTokenSecret([]byte(strings.Repeat("s", TokenSecretMinLength)))
+	single := newTestMux(t, 1<<10, WithAdminKey("admin"), secret)
+	multi := newTestMux(t, 1<<10, WithAdminKey("admin"), secret, WithAPIKeys(map[string]string{"key": "finance"}))
+
+	tests := []struct {
+		name   string
+		mux    *DocumentMux
+		body   string
+		status int
+	}{
+		{name: "token of a tenant", mux: multi, body: `{"tenant":"finance","scopes":["upload"]}`, status: http.StatusCreated},
+		{name: "missing tenant", mux: multi, body: `{"scopes":["upload"]}`, status: http.StatusBadRequest},
+		{name: "empty tenant", mux: multi, body: `{"tenant":"","scopes":["read"]}`, status: http.StatusBadRequest},
+		{name: "default tenant of a single-tenant deployment", mux: single, body: `{"scopes":["read"]}`, status: http.StatusCreated},
+		{name: "invalid tenant", mux: single, body: `{"tenant":"fin/ance","scopes":["read"]}`, status: http.StatusBadRequest},
+		{name: "missing scopes", mux: multi, body: `{"tenant":"finance"}`, status: http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/tokens", strings.NewReader(tt.body))
+			r.Header.Set(HeaderAPIKey, "admin")
+			w := httptest.NewRecorder()
+			tt.mux.ServeHTTP(w, r)
+			assert.Equal(t, tt.status, w.Code, w.Body.String())
+		})
+	}
+
+	t.Run("token of the default tenant", func(t *testing.T) {
+		r := httptest.NewRequest(http.MethodPost, "/admin/tokens", strings.NewReader(`{"scopes":["read"]}`))
+		r.Header.Set(HeaderAPIKey, "admin")
+		w := httptest.NewRecorder()
+		single.ServeHTTP(w, r)
+		require.Equal(t, http.StatusCreated, w.Code, w.Body.String())
+		var om ObjectMessage
+		require.NoError(t, json.Unmarshal(w.Body.Bytes(), &om))
+		require.NotNil(t, om.Token)
+
+		stats := func(d *DocumentMux) int {
+			r := httptest.NewRequest(http.MethodGet, "/stats", nil)
+			r.Header.Set("Authorization", "Bearer "+om.Token.Token)
+			w := httptest.NewRecorder()
+			d.ServeHTTP(w, r)
+			return w.Code
+		}
+		assert.Equal(t, http.StatusOK, stats(single))
+		// the same token is refused once multi-tenancy is configured
+		assert.Equal(t, http.StatusUnauthorized, stats(multi))
+	})
+}
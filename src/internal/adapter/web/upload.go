@@ -0,0 +1,160 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// uploadRequest is the body of a request of a presigned upload, all its fields are optional.
+type uploadRequest struct {
+	Tag      string        `json:"tag"`
+	FileName string        `json:"file_name"`
+	Labels   domain.Labels `json:"labels"`
+	Engine   string        `json:"engine"`
+}
+
+// postUploadHandler creates a document awaiting its binary data and answers with the presigned URL the client
+// uploads the data to directly, before confirming the upload with POST /uploads/{id}/confirm.
+func (d *DocumentMux) postUploadHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		om  = &ObjectMessage{}
+		req uploadRequest
+	)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 16<<10)).Decode(&req); err != nil && !errors.Is(err, io.EOF) {
+		writeError(w, http.StatusBadRequest, "the request body must be a JSON object", om)
+		return
+	}
+	if !d.extensions.allows(helper.SanitizeFileName(req.FileName)) {
+		om.Errors = []FieldError{{Field: "file_name", Code: codeUnsupportedExtension, Message: "the extension of the file name is not allowed"}}
+		writeError(w, http.StatusUnsupportedMediaType, "the extension of the file to upload is not allowed.", om)
+		return
+	}
+
+	if err := req.Labels.Validate(); err != nil {
+		om.Errors = []FieldError{{Field: fieldLabels, Code: codeInvalid, Message: err.Error()}}
+		writeError(w, http.StatusBadRequest, "the upload request is invalid", om)
+		return
+	}
+
+	var engines []string
+	if req.Engine != "" {
+		var err error
+		if engines, err = domain.ParseEngines(req.Engine); err != nil {
+			om.Errors = []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: err.Error()}}
+			writeError(w, http.StatusBadRequest, "the upload request is invalid", om)
+			return
+		}
+	}
+
+	tag := req.Tag
+	if tag == "" {
+		tag = req.FileName
+	}
+	ctx := domain.ContextWithFileName(r.Context(), req.FileName)
+	if len(req.Labels) > 0 {
+		ctx = domain.ContextWithLabels(ctx, req.Labels)
+	}
+	if engines != nil {
+		ctx = domain.ContextWithEngines(ctx, engines)
+	}
+	upload, err := d.service.CreateUpload(ctx, tag)
+	switch {
+	case err == nil:
+		om.ID = upload.ID
+		om.Message = "upload URL created."
+		om.Upload = upload
+		writeJson(w, http.StatusCreated, om)
+	case errors.Is(err, port.ErrServicePresignedUploadsDisabled):
+		writeError(w, http.StatusNotFound, "presigned uploads are not enabled", om)
+	case errors.Is(err, port.ErrServiceUnknownEngine):
+		om.Errors = []FieldError{{Field: fieldEngine, Code: codeInvalid, Message: "unknown engine"}}
+		writeError(w, http.StatusBadRequest, "the upload request is invalid", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.postUploadHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while creating the upload URL", om)
+	}
+}
+
+// getDownloadHandler answers with a presigned URL the retained file of a clean document can be downloaded from
+// directly, rather than through the service.
+func (d *DocumentMux) getDownloadHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	download, err := d.service.PresignDownload(r.Context(), om.ID)
+	switch {
+	case err == nil:
+		om.Message = "download URL created."
+		om.Download = download
+		writeJson(w, http.StatusOK, om)
+	case errors.Is(err, port.ErrServiceDownloadsDisabled):
+		writeError(w, http.StatusNotFound, "downloads are not enabled", om)
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found", om)
+	case errors.Is(err, port.ErrServiceDocumentDeleted):
+		writeError(w, http.StatusGone, "the document was deleted.", om)
+	case errors.Is(err, port.ErrServiceDocumentNotClean):
+		writeError(w, http.StatusConflict, "only clean documents can be downloaded.", om)
+	case errors.Is(err, port.ErrServiceContentNotRetained):
+		writeError(w, http.StatusGone, "the file of the document is not retained.", om)
+	case errors.Is(err, port.ErrServiceContentChanged):
+		writeError(w, http.StatusConflict, "the file of the document changed since it was analyzed.", om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.getDownloadHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while creating the download URL", om)
+	}
+}
+
+// confirmUploadHandler confirms that the binary data of a document was uploaded to its presigned URL, which triggers
+// its analysis with the priority given by the priority query parameter, interactive unless stated otherwise.
+func (d *DocumentMux) confirmUploadHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	ctx := r.Context()
+	if p, ok := domain.ParsePriority(r.URL.Query().Get(fieldPriority)); ok {
+		ctx = domain.ContextWithPriority(ctx, p)
+	}
+
+	doc, err := d.service.ConfirmUpload(ctx, om.ID)
+	switch {
+	case err == nil:
+		om.Message = "upload confirmed, the analysis is scheduled."
+		om.Document = domain.NewDocumentDTO(doc)
+		if d.completionEstimates {
+			eta := d.service.EstimateCompletion(doc.Size).UTC().Round(time.Second)
+			om.EstimatedCompletionAt = &eta
+		}
+		d.advisePoll(w, om, doc)
+		writeJson(w, http.StatusAccepted, om)
+	case errors.Is(err, port.ErrServiceInvalidID):
+		writeError(w, http.StatusBadRequest, "the provided ID is invalid", om)
+	case errors.Is(err, port.ErrServiceGetDocumentFailed):
+		writeError(w, http.StatusNotFound, "document not found", om)
+	case errors.Is(err, port.ErrServiceDocumentDeleted):
+		writeError(w, http.StatusGone, "the document was deleted.", om)
+	case errors.Is(err, port.ErrServiceUploadAlreadyConfirmed):
+		writeError(w, http.StatusConflict, "the upload is already confirmed.", om)
+	case errors.Is(err, port.ErrServiceUploadNotReceived):
+		writeError(w, http.StatusConflict, "the file was not uploaded to the upload URL yet.", om)
+	case errors.Is(err, port.ErrServiceUploadChanged):
+		writeError(w, http.StatusConflict, "the file was uploaded again while the upload was confirmed.", om)
+	case errors.Is(err, port.ErrServiceUnsupportedMediaType):
+		om.Errors = []FieldError{{Field: fieldFile, Code: codeUnsupportedMediaType, Message: "the media type of the file is not allowed"}}
+		writeError(w, http.StatusUnsupportedMediaType, "the media type of the uploaded file is not allowed.", om)
+	case errors.Is(err, port.ErrServiceFileTooLarge):
+		writeError(w, http.StatusRequestEntityTooLarge, "uploaded data exceeds the maximum file size of the tenant.", om)
+	case errors.Is(err, port.ErrServiceQuotaExceeded):
+		writeError(w, http.StatusTooManyRequests, "quota exceeded.", om)
+	case errors.Is(err, port.ErrServiceOverloaded):
+		d.writeOverloaded(w, om)
+	default:
+		slog.ErrorContext(r.Context(), "handler.confirmUploadHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while confirming the upload", om)
+	}
+}
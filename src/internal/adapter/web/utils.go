@@ -1,19 +1,53 @@
 package web
 
 import (
+	"bytes"
 	"encoding/json"
 	"fmt"
 	"goyav/internal/core/domain"
 	"log/slog"
 	"net/http"
+	"strconv"
+	"sync"
+	"time"
 )
 
 type ObjectMessage struct {
-	Message     string              `json:"message"`
-	ID          string              `json:"id,omitempty"`
-	Version     string              `json:"version,omitempty"`
-	Information string              `json:"information,omitempty"`
-	Document    *domain.DocumentDTO `json:"document,omitempty"`
+	Message     string                    `json:"message"`
+	ID          string                    `json:"id,omitempty"`
+	Version     string                    `json:"version,omitempty"`
+	Information string                    `json:"information,omitempty"`
+	Document    *domain.DocumentDTO       `json:"document,omitempty"`
+	Documents   []*domain.DocumentDTO     `json:"documents,omitempty"`
+	NextCursor  string                    `json:"next_cursor,omitempty"`
+	Events      []domain.DocumentEventDTO `json:"events,omitempty"`
+	Quota       *domain.QuotaDTO          `json:"quota,omitempty"`
+	Stats       *domain.StatsDTO          `json:"stats,omitempty"`
+	Health      *domain.Health            `json:"health,omitempty"`
+
+	EstimatedCompletionAt *time.Time `json:"estimated_completion_at,omitempty"`
+	ETASeconds            int        `json:"eta_seconds,omitempty"`
+
+	Reconciliation *domain.ReconcileReport   `json:"reconciliation,omitempty"`
+	Purge          *domain.PurgeReport       `json:"purge,omitempty"`
+	Requeue        *domain.RequeueReport     `json:"requeue,omitempty"`
+	Concurrency    *domain.Concurrency       `json:"concurrency,omitempty"`
+	Image          *domain.ImageReport       `json:"image,omitempty"`
+	Scan           *domain.ScanReport        `json:"scan,omitempty"`
+	Upload         *domain.PresignedUpload   `json:"upload,omitempty"`
+	Download       *domain.PresignedDownload `json:"download,omitempty"`
+	Token          *IssuedToken              `json:"token,omitempty"`
+	Webhook        *domain.WebhookDTO        `json:"webhook,omitempty"`
+	Webhooks       []*domain.WebhookDTO      `json:"webhooks,omitempty"`
+	Errors         []FieldError              `json:"errors,omitempty"`
+	Results        []UploadResult            `json:"results,omitempty"`
+}
+
+// FieldError describes why a single form field of a request was rejected.
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
 }
 
 // methodNotAllowed sends a method not allowed response.
@@ -22,16 +56,46 @@ func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
 	writeError(w, http.StatusMethodNotAllowed, msg, &ObjectMessage{})
 }
 
-// writeJson marshals and writes a JSON response
+// maxPooledJSONBuffer is the capacity above which the buffer of a JSON encoder is not pooled, so that the large
+// responses, such as the listings, do not keep their memory.
+const maxPooledJSONBuffer = 64 << 10
+
+// jsonEncoder is a JSON encoder writing to a buffer of its own, pooled by writeJson.
+type jsonEncoder struct {
+	buf bytes.Buffer
+	enc *json.Encoder
+}
+
+// jsonEncoders are the encoders of writeJson, reused from one response to the next.
+var jsonEncoders = sync.Pool{New: func() any {
+	e := &jsonEncoder{}
+	e.enc = json.NewEncoder(&e.buf)
+	return e
+}}
+
+// writeJson encodes v with a pooled encoder and writes it as a JSON response, along with its length.
 func writeJson(w http.ResponseWriter, code int, v any) {
-	b, err := json.Marshal(v)
-	if err != nil {
+	e := jsonEncoders.Get().(*jsonEncoder)
+	defer func() {
+		if e.buf.Cap() <= maxPooledJSONBuffer {
+			e.buf.Reset()
+			jsonEncoders.Put(e)
+		}
+	}()
+	if err := e.enc.Encode(v); err != nil {
 		http.Error(w, "inernal server error", http.StatusInternalServerError)
 		slog.Error("handler.printjson", "error", err.Error())
+		return
 	}
+	writeJsonBody(w, code, e.buf.Bytes())
+}
+
+// writeJsonBody writes body, encoded already, as a JSON response.
+func writeJsonBody(w http.ResponseWriter, code int, body []byte) {
 	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Content-Length", strconv.Itoa(len(body)))
 	w.WriteHeader(code)
-	w.Write(b)
+	w.Write(body)
 }
 
 // writeError shortcut for writing error responses in JSON format. Uses writeJson.
@@ -42,3 +106,28 @@ func writeError(w http.ResponseWriter, code int, msg string, obj *ObjectMessage)
 	obj.Message = msg
 	writeJson(w, code, obj)
 }
+
+// maxRetryAfter bounds the delay advised to the clients of an overloaded service.
+const maxRetryAfter = time.Minute
+
+// writeOverloaded answers 503 to an upload rejected while the service is overloaded, advising the client to retry
+// once the analyses ahead of it are expected to be done, within one second to maxRetryAfter.
+func (d *DocumentMux) writeOverloaded(w http.ResponseWriter, om *ObjectMessage) {
+	wait := time.Until(d.service.EstimateCompletion(0)).Round(time.Second)
+	wait = min(max(wait, time.Second), maxRetryAfter)
+	w.Header().Set("Retry-After", strconv.Itoa(int(wait.Seconds())))
+	writeError(w, http.StatusServiceUnavailable, "too many analyses in progress, retry later.", om)
+}
+
+// advisePoll advises the client of a pending document when to poll it again: the eta_seconds field holds the number of
+// seconds its analysis is expected to take still, computed from the pending analyses and the average duration of the
+// analyses, and the Retry-After header the same, within one second to maxRetryAfter. Nothing is advised for the
+// documents with a verdict.
+func (d *DocumentMux) advisePoll(w http.ResponseWriter, om *ObjectMessage, doc *domain.Document) {
+	if doc.Status != domain.StatusPending {
+		return
+	}
+	eta := max(time.Until(d.service.EstimateCompletion(doc.Size)), time.Second)
+	om.ETASeconds = int((eta + time.Second - 1) / time.Second)
+	w.Header().Set("Retry-After", strconv.Itoa(int(min(eta, maxRetryAfter).Round(time.Second)/time.Second)))
+}
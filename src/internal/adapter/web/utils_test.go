@@ -0,0 +1,78 @@
+package web
+
+import (
+	"encoding/json"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteJsonConcurrently(t *testing.T) {
+	var wg sync.WaitGroup
+	for i := range 64 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			// every other message exceeds the buffers kept by the pool
+			message := strconv.Itoa(i)
+			if i%2 == 0 {
+				message += strings.Repeat("x", maxPooledJSONBuffer)
+			}
+			w := httptest.NewRecorder()
+			writeJson(w, http.StatusOK, &ObjectMessage{Message: message})
+
+			var om ObjectMessage
+			assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &om))
+			assert.Equal(t, message, om.Message, "the body of a response must not be shared with another one")
+			assert.Equal(t, strconv.Itoa(w.Body.Len()), w.Header().Get("Content-Length"))
+		}()
+	}
+	wg.Wait()
+}
+
+func TestPingCache(t *testing.T) {
+	const ttl = 50 * time.Millisecond
+	docRepoMock := docrepo.NewMock()
+	// without retention, no purge checks the repository meanwhile
+	s, err := service.New(binaryrepo.NewMock(), docRepoMock, antivirus.NewMock(), "1.0", "information", 0, 16,
+		service.WithHealthCache(ttl))
+	require.NoError(t, err)
+	d := NewDocumentMux(s, 1<<10)
+
+	ping := func() (int, string) {
+		w := httptest.NewRecorder()
+		d.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/ping/", nil))
+		return w.Code, w.Body.String()
+	}
+
+	code, body := ping()
+	assert.Equal(t, http.StatusOK, code)
+
+	// the cached body is answered as long as the service reuses its health report
+	docRepoMock.IsOnline(false)
+	code, cached := ping()
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, body, cached)
+
+	// and no longer once the health report is checked again
+	time.Sleep(2 * ttl)
+	code, body = ping()
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, body, "service unavailable")
+
+	docRepoMock.IsOnline(true)
+	time.Sleep(2 * ttl)
+	code, _ = ping()
+	assert.Equal(t, http.StatusOK, code)
+}
@@ -0,0 +1,92 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// maxVerdictPathLength is the maximum length of the path of a file reported by an on-access scanning agent.
+const maxVerdictPathLength = 4096
+
+// verdictRequest is the body of a verdict reported by an on-access scanning agent.
+type verdictRequest struct {
+	SHA256    string `json:"sha256"`
+	Host      string `json:"host"`
+	Path      string `json:"path"`
+	Verdict   string `json:"verdict"`
+	Signature string `json:"signature"`
+	ScannedAt string `json:"scanned_at"`
+}
+
+// postVerdictHandler records the verdict reported by an on-access scanning agent, such as a clamd VirusEvent
+// or a clamonacc hook, on a file GoyAV never received.
+func (d *DocumentMux) postVerdictHandler(w http.ResponseWriter, r *http.Request) {
+	var (
+		om  = &ObjectMessage{}
+		req verdictRequest
+	)
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, 8<<10)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "the request body must be a JSON object", om)
+		return
+	}
+
+	v := domain.ExternalVerdict{
+		Hash:      strings.ToLower(req.SHA256),
+		Host:      req.Host,
+		Path:      req.Path,
+		Signature: req.Signature,
+	}
+	var errs []FieldError
+	if req.SHA256 == "" {
+		errs = append(errs, FieldError{Field: "sha256", Code: codeMissing, Message: "the SHA-256 hash of the file is required"})
+	}
+	if req.Host == "" {
+		errs = append(errs, FieldError{Field: "host", Code: codeMissing, Message: "the host holding the file is required"})
+	}
+	switch {
+	case req.Path == "":
+		errs = append(errs, FieldError{Field: "path", Code: codeMissing, Message: "the path of the file is required"})
+	case len(req.Path) > maxVerdictPathLength:
+		errs = append(errs, FieldError{Field: "path", Code: codeTooLong, Message: "the path of the file is too long"})
+	}
+	status, ok := domain.ParseAnalysisStatus(req.Verdict)
+	if !ok || !status.IsVerdict() {
+		errs = append(errs, FieldError{Field: "verdict", Code: codeInvalid, Message: "verdict must be clean or infected"})
+	}
+	v.Status = status
+	if req.ScannedAt != "" {
+		scannedAt, err := time.Parse(time.RFC3339, req.ScannedAt)
+		if err != nil {
+			errs = append(errs, FieldError{Field: "scanned_at", Code: codeInvalid, Message: "scanned_at must be a RFC 3339 date"})
+		}
+		v.ScannedAt = scannedAt
+	}
+	if errs != nil {
+		om.Errors = errs
+		writeError(w, http.StatusBadRequest, "the verdict is invalid", om)
+		return
+	}
+
+	ID, created, err := d.service.IngestVerdict(r.Context(), v)
+	switch {
+	case errors.Is(err, port.ErrServiceInvalidVerdict):
+		writeError(w, http.StatusBadRequest, "the verdict is invalid", om)
+	case err != nil:
+		slog.ErrorContext(r.Context(), "handler.postVerdictHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured", om)
+	case created:
+		om.Message = "verdict recorded"
+		om.ID = ID
+		writeJson(w, http.StatusCreated, om)
+	default:
+		om.Message = "verdict updated"
+		om.ID = ID
+		writeJson(w, http.StatusOK, om)
+	}
+}
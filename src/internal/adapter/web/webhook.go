@@ -0,0 +1,141 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"net/http"
+	"strconv"
+)
+
+// maxWebhookRequestSize is the maximum size of the body of a request creating or changing a webhook.
+const maxWebhookRequestSize = 8 << 10
+
+// webhookRequest is the body of a request creating a webhook, or changing the fields it gives.
+type webhookRequest struct {
+	URL    *string  `json:"url"`
+	Events []string `json:"events"`
+	Secret *string  `json:"secret"`
+	Active *bool    `json:"active"`
+}
+
+// update returns the change of a webhook given by req.
+func (req *webhookRequest) update() domain.WebhookUpdate {
+	u := domain.WebhookUpdate{URL: req.URL, Secret: req.Secret, Active: req.Active}
+	if req.Events != nil {
+		u.Events = make([]domain.WebhookEvent, len(req.Events))
+		for i, e := range req.Events {
+			u.Events[i] = domain.WebhookEvent(e)
+		}
+	}
+	return u
+}
+
+// listWebhooksHandler lists all the webhooks, the oldest first.
+func (d *DocumentMux) listWebhooksHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	webhooks, err := d.webhooks.ListWebhooks(r.Context())
+	if err != nil {
+		slog.ErrorContext(r.Context(), "handler.listWebhooksHandler", "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while listing the webhooks", om)
+		return
+	}
+	om.Message = strconv.Itoa(len(webhooks)) + " webhook(s) found"
+	om.Webhooks = make([]*domain.WebhookDTO, len(webhooks))
+	for i, wh := range webhooks {
+		om.Webhooks[i] = domain.NewWebhookDTO(wh)
+	}
+	writeJson(w, http.StatusOK, om)
+}
+
+// createWebhookHandler subscribes the URL of the request to its events, active unless the request says otherwise.
+func (d *DocumentMux) createWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{}
+	req, ok := decodeWebhookRequest(w, r, om)
+	if !ok {
+		return
+	}
+	var wh domain.Webhook
+	req.update().Apply(&wh)
+	wh.Active = req.Active == nil || *req.Active
+
+	created, err := d.webhooks.CreateWebhook(r.Context(), wh)
+	if err != nil {
+		writeWebhookError(w, r, err, om, "handler.createWebhookHandler")
+		return
+	}
+	om.Message = "webhook created"
+	om.ID = created.ID
+	om.Webhook = domain.NewWebhookDTO(created)
+	writeJson(w, http.StatusCreated, om)
+}
+
+// getWebhookHandler retrieves a webhook, without its secret.
+func (d *DocumentMux) getWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	wh, err := d.webhooks.GetWebhook(r.Context(), om.ID)
+	if err != nil {
+		writeWebhookError(w, r, err, om, "handler.getWebhookHandler")
+		return
+	}
+	om.Message = "webhook found"
+	om.Webhook = domain.NewWebhookDTO(wh)
+	writeJson(w, http.StatusOK, om)
+}
+
+// updateWebhookHandler changes the fields of a webhook given by the request, leaving the others unchanged.
+func (d *DocumentMux) updateWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	req, ok := decodeWebhookRequest(w, r, om)
+	if !ok {
+		return
+	}
+	wh, err := d.webhooks.UpdateWebhook(r.Context(), om.ID, req.update())
+	if err != nil {
+		writeWebhookError(w, r, err, om, "handler.updateWebhookHandler")
+		return
+	}
+	om.Message = "webhook updated"
+	om.Webhook = domain.NewWebhookDTO(wh)
+	writeJson(w, http.StatusOK, om)
+}
+
+// deleteWebhookHandler removes a webhook, which receives no event from then on.
+func (d *DocumentMux) deleteWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	om := &ObjectMessage{ID: r.PathValue("id")}
+	if err := d.webhooks.DeleteWebhook(r.Context(), om.ID); err != nil {
+		writeWebhookError(w, r, err, om, "handler.deleteWebhookHandler")
+		return
+	}
+	om.Message = "webhook deleted"
+	writeJson(w, http.StatusOK, om)
+}
+
+// decodeWebhookRequest decodes the body of a request creating or changing a webhook. It answers 400 and returns false
+// if the body is not a JSON object.
+func decodeWebhookRequest(w http.ResponseWriter, r *http.Request, om *ObjectMessage) (*webhookRequest, bool) {
+	var req webhookRequest
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, maxWebhookRequestSize)).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "the request body must be a JSON object", om)
+		return nil, false
+	}
+	return &req, true
+}
+
+// writeWebhookError answers the error of the management of a webhook: 400 with the invalid field, 404 for an unknown
+// webhook, 500 otherwise, logged with the name of the handler.
+func writeWebhookError(w http.ResponseWriter, r *http.Request, err error, om *ObjectMessage, handler string) {
+	var invalid *domain.WebhookError
+	switch {
+	case errors.As(err, &invalid):
+		om.Errors = []FieldError{{Field: invalid.Field, Code: codeInvalid, Message: invalid.Message}}
+		writeError(w, http.StatusBadRequest, "the webhook is invalid", om)
+	case errors.Is(err, port.ErrWebhookNotFound):
+		writeError(w, http.StatusNotFound, "webhook not found", om)
+	default:
+		slog.ErrorContext(r.Context(), handler, "error", err.Error())
+		writeError(w, http.StatusInternalServerError, "an error occured while managing the webhooks", om)
+	}
+}
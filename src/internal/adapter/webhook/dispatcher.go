@@ -0,0 +1,129 @@
+// Package webhook implements the delivery of the events of the service to the webhook subscriptions.
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"goyav/internal/adapter/alert"
+	"goyav/internal/adapter/report"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"time"
+)
+
+// Request headers of the deliveries of the events.
+const (
+	// HeaderEvent carries the kind of event delivered.
+	HeaderEvent = "X-Webhook-Event"
+
+	// HeaderWebhookID carries the ID of the webhook the event is delivered to.
+	HeaderWebhookID = "X-Webhook-ID"
+
+	// HeaderSignatureTimestamp carries the date the delivery was signed at, in seconds since the Unix epoch.
+	HeaderSignatureTimestamp = "X-Signature-Timestamp"
+
+	// HeaderSignature carries the hex-encoded HMAC-SHA256 signature of the delivery, see helper.NewRequestMAC.
+	HeaderSignature = "X-Signature"
+)
+
+// Dispatcher implements port.AlertNotifier and port.ReportSender by posting the alerts and the reports as JSON to
+// the active webhooks subscribing to them, in the bodies posted to the webhook URLs of the configuration. The
+// deliveries of the webhooks having a secret are signed with it as the clients sign their requests to the service.
+// Like the webhook URLs of the configuration, the webhooks are managed by the operators and may target a private
+// address.
+type Dispatcher struct {
+	webhooks port.WebhookRepository
+	client   *http.Client
+}
+
+// NewDispatcher creates a dispatcher posting the events to the webhooks of r with requests bounded by timeout, their
+// bodies of at least compression bytes being compressed if the webhook accepts it, see helper.CompressingTransport.
+// The signature of a delivery covers its uncompressed body.
+func NewDispatcher(r port.WebhookRepository, timeout time.Duration, compression int) *Dispatcher {
+	return &Dispatcher{webhooks: r, client: &http.Client{Timeout: timeout, Transport: helper.CompressingTransport(nil, compression)}}
+}
+
+// Notify posts a to the webhooks subscribing to the alerts.
+func (d *Dispatcher) Notify(ctx context.Context, a *domain.InfectedRateAlert) error {
+	if err := d.dispatch(ctx, domain.WebhookEventAlert, alert.NewWebhookMessage(a)); err != nil {
+		return fmt.Errorf("%w: %w", port.ErrAlertDeliveryFailed, err)
+	}
+	return nil
+}
+
+// Send posts r to the webhooks subscribing to the reports.
+func (d *Dispatcher) Send(ctx context.Context, r *domain.SummaryReport) error {
+	if err := d.dispatch(ctx, domain.WebhookEventReport, report.NewWebhookMessage(r)); err != nil {
+		return fmt.Errorf("%w: %w", port.ErrReportDeliveryFailed, err)
+	}
+	return nil
+}
+
+// dispatch posts message to each active webhook subscribing to event. A webhook failing to receive it does not keep
+// the others from receiving it, the errors of all of them are returned.
+func (d *Dispatcher) dispatch(ctx context.Context, event domain.WebhookEvent, message any) error {
+	webhooks, err := d.webhooks.ListWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	body, err := json.Marshal(message)
+	if err != nil {
+		return err
+	}
+	var errs []error
+	for _, w := range webhooks {
+		if !w.Subscribes(event) {
+			continue
+		}
+		if err = d.post(ctx, w, event, body); err != nil {
+			errs = append(errs, fmt.Errorf("webhook %s: %w", w.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// post posts body to the URL of w, signed with its secret unless it is empty, which must answer with a 2xx status
+// code.
+func (d *Dispatcher) post(ctx context.Context, w *domain.Webhook, event domain.WebhookEvent, body []byte) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "GoyAV")
+	req.Header.Set(HeaderEvent, string(event))
+	req.Header.Set(HeaderWebhookID, w.ID)
+	if w.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(HeaderSignatureTimestamp, timestamp)
+		req.Header.Set(HeaderSignature, Sign([]byte(w.Secret), timestamp, req.URL, body))
+	}
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return err
+	}
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+// Sign returns the hex-encoded signature with secret of a delivery of body posted to u at timestamp, see
+// helper.NewRequestMAC.
+func Sign(secret []byte, timestamp string, u *url.URL, body []byte) string {
+	mac := helper.NewRequestMAC(secret, timestamp, http.MethodPost, u.RequestURI())
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
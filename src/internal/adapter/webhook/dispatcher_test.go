@@ -0,0 +1,85 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDispatcher(t *testing.T) {
+	var (
+		mu         sync.Mutex
+		deliveries = make(map[string]*http.Request)
+		bodies     = make(map[string][]byte)
+	)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		mu.Lock()
+		deliveries[r.Header.Get(HeaderWebhookID)] = r
+		bodies[r.Header.Get(HeaderWebhookID)] = body
+		mu.Unlock()
+		if strings.HasSuffix(r.URL.Path, "/failing") {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	repo := docrepo.NewMock()
+	secret := strings.Repeat("s", domain.WebhookSecretMinLength)
+	for _, w := range []*domain.Webhook{
+		{ID: "signed", URL: srv.URL + "/alerts?team=sec", Events: []domain.WebhookEvent{domain.WebhookEventAlert}, Secret: secret, Active: true},
+		{ID: "all", URL: srv.URL + "/all", Events: domain.WebhookEvents, Active: true},
+		{ID: "inactive", URL: srv.URL + "/inactive", Events: domain.WebhookEvents},
+		{ID: "reports", URL: srv.URL + "/reports", Events: []domain.WebhookEvent{domain.WebhookEventReport}, Active: true},
+	} {
+		assert.NoError(t, repo.SaveWebhook(context.Background(), w))
+	}
+	d := NewDispatcher(repo, time.Second, 0)
+
+	// Scenario: An alert is posted to the active webhooks subscribing to the alerts, signed with their secret
+	t.Run("Alert", func(t *testing.T) {
+		alert := &domain.InfectedRateAlert{At: time.Now(), Window: 15 * time.Minute, Threshold: 0.1, Rate: 0.5, Infected: 10, Analyzed: 20}
+		assert.NoError(t, d.Notify(context.Background(), alert))
+
+		assert.Len(t, deliveries, 2)
+		if r, ok := deliveries["signed"]; assert.True(t, ok) {
+			assert.Equal(t, "alert", r.Header.Get(HeaderEvent))
+			timestamp := r.Header.Get(HeaderSignatureTimestamp)
+			assert.Equal(t, Sign([]byte(secret), timestamp, r.URL, bodies["signed"]), r.Header.Get(HeaderSignature))
+			var m struct {
+				Alert struct {
+					Name string `json:"name"`
+				} `json:"alert"`
+			}
+			assert.NoError(t, json.Unmarshal(bodies["signed"], &m))
+			assert.Equal(t, "infected_rate", m.Alert.Name)
+		}
+		if r, ok := deliveries["all"]; assert.True(t, ok) {
+			assert.Empty(t, r.Header.Get(HeaderSignature), "a webhook without a secret is not signed")
+		}
+	})
+
+	// Scenario: A webhook failing to receive a report does not keep the others from receiving it
+	t.Run("Report", func(t *testing.T) {
+		clear(deliveries)
+		failing := &domain.Webhook{ID: "all", URL: srv.URL + "/failing", Events: domain.WebhookEvents, Active: true}
+		assert.NoError(t, repo.UpdateWebhook(context.Background(), failing))
+
+		err := d.Send(context.Background(), &domain.SummaryReport{Period: domain.ReportDaily, Total: 3})
+		assert.ErrorIs(t, err, port.ErrReportDeliveryFailed)
+		assert.ErrorContains(t, err, "webhook all")
+		assert.Contains(t, deliveries, "reports")
+		assert.Contains(t, deliveries, "all")
+	})
+}
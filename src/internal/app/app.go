@@ -0,0 +1,184 @@
+// Package app assembles GoyAV from its configuration. Each component has a provider function taking
+// its configuration section and its dependencies, so that alternative mains can assemble the application,
+// or a part of it, without duplicating the setup logic; Build assembles all of them.
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/internal/adapter/web"
+	"goyav/internal/service"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"time"
+)
+
+// App is an assembled GoyAV.
+type App struct {
+	Service  *service.Service // Service is nil when the server was built with the startup retry, see Build.
+	Server   *http.Server     // Server is nil when only the service is assembled.
+	Listener net.Listener     // Listener is the listener Server serves on, nil when only the service is assembled.
+	DB       *sql.DB          // DB is the database connection shared by the Postgres repositories, nil with the startup retry.
+}
+
+// Close releases the resources of the app once its server is shut down: the handler of the server, which stops
+// listening to the status changes, then the database connection. The app assembled in the background by the startup
+// retry is closed along with the handler of the server, see buildLazily.
+func (a *App) Close() error {
+	var errs []error
+	if a.Server != nil {
+		if c, ok := a.Server.Handler.(io.Closer); ok {
+			errs = append(errs, c.Close())
+		}
+	}
+	if a.DB != nil {
+		errs = append(errs, a.DB.Close())
+	}
+	return errors.Join(errs...)
+}
+
+const (
+	// startupRetryMinDelay and startupRetryMaxDelay bound the delay between two attempts to assemble the service when
+	// its dependencies are not reachable at startup, doubled after each failed attempt.
+	startupRetryMinDelay = time.Second
+	startupRetryMaxDelay = 30 * time.Second
+)
+
+// Build assembles GoyAV from cfg with the default adapters: S3, PostgreSQL and ClamAV. When the startup retry is
+// enabled, the server and its listener are returned at once, without the service, see buildLazily.
+func Build(cfg *Config) (*App, error) {
+	if cfg.Server.StartupRetry {
+		return buildLazily(cfg)
+	}
+	goyav, err := buildService(cfg)
+	if err != nil {
+		return nil, err
+	}
+	feed := ProvideStatusFeed(cfg.Server, cfg.Postgres)
+	goyav.Server = ProvideHTTPServer(cfg.Server, cfg.Tenancy, cfg.Admin, goyav.Service, feed)
+	if goyav.Listener, err = ProvideListener(cfg.Server); err != nil {
+		return nil, fmt.Errorf("error while creating the listener: %w", err)
+	}
+	return goyav, nil
+}
+
+// buildLazily creates the HTTP server and its listener, answering 503 to the requests until the service is
+// assembled by connect in the background, so that GoyAV starts while its dependencies restart.
+func buildLazily(cfg *Config) (*App, error) {
+	startup := web.NewStartup()
+	goyav := &App{Server: newHTTPServer(cfg.Server, startup)}
+	var err error
+	if goyav.Listener, err = ProvideListener(cfg.Server); err != nil {
+		return nil, fmt.Errorf("error while creating the listener: %w", err)
+	}
+	go connect(cfg, startup)
+	return goyav, nil
+}
+
+// connect assembles the document service of GoyAV until its dependencies are reachable, retrying with an exponential
+// backoff, then makes startup pass the requests to the handler of the API. The assembled app is handed to startup as
+// well, so that its database connection is closed along with the server, see App.Close.
+func connect(cfg *Config, startup *web.Startup) {
+	delay := startupRetryMinDelay
+	for attempt := 1; ; attempt++ {
+		goyav, err := buildService(cfg)
+		if err == nil {
+			feed := ProvideStatusFeed(cfg.Server, cfg.Postgres)
+			// the app assembled without its server is closed along with the startup, releasing its database connection
+			startup.Ready(ProvideHandler(cfg.Server, cfg.Tenancy, cfg.Admin, goyav.Service, feed), goyav)
+			slog.Info("GoyAV is ready", "attempts", attempt)
+			return
+		}
+		slog.Warn("GoyAV failed to reach its dependencies", "error", err.Error(), "attempt", attempt, "retry in", delay.String())
+		time.Sleep(delay)
+		delay = min(2*delay, startupRetryMaxDelay)
+	}
+}
+
+// buildService assembles the service of GoyAV from cfg, without its repositories in analyzer-only mode, see
+// BuildAnalyzer.
+func buildService(cfg *Config) (*App, error) {
+	if cfg.Server.AnalyzerOnly {
+		return BuildAnalyzer(cfg)
+	}
+	return BuildService(cfg)
+}
+
+// BuildAnalyzer assembles the service of GoyAV from cfg with the ClamAV analyzers only, without the S3 bucket nor the
+// database, for the deployments only scanning files, see service.NewAnalyzer. The HTTP server is not assembled.
+func BuildAnalyzer(cfg *Config) (*App, error) {
+	a, err := ProvideAnalyzer(cfg.ClamAV)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating antivirus analyzer: %w", err)
+	}
+	engines, err := ProvideEngines(cfg.ClamAV)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the antivirus engines: %w", err)
+	}
+	svc, err := ProvideAnalyzerService(cfg.Service, a, engines)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the service: %w", err)
+	}
+	return &App{Service: svc}, nil
+}
+
+// BuildService assembles the document service of GoyAV from cfg with the default adapters, without the HTTP server.
+// The database connection is closed if the service cannot be assembled.
+func BuildService(cfg *Config) (goyav *App, err error) {
+	b, err := ProvideBinaryRepo(cfg.S3)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating binary repository: %w", err)
+	}
+
+	db, err := ProvideDB(cfg.Postgres)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating document repository: %w", err)
+	}
+	defer func() {
+		if err != nil {
+			db.Close()
+		}
+	}()
+	if cfg.Postgres.AutoMigrate {
+		if err = MigrateDB(context.Background(), cfg.Postgres, db); err != nil {
+			return nil, fmt.Errorf("error while migrating the database: %w", err)
+		}
+	}
+	d, err := ProvideDocRepo(cfg.Postgres, db)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating document repository: %w", err)
+	}
+	quotas, err := ProvideQuotaRepo(cfg.Service.Quotas, db)
+	if err != nil {
+		return nil, fmt.Errorf("error while configuring quotas: %w", err)
+	}
+
+	anon, err := ProvideAnonymizer(cfg.Service.Pseudonymization)
+	if err != nil {
+		return nil, fmt.Errorf("error while configuring pseudonymization: %w", err)
+	}
+
+	a, err := ProvideAnalyzer(cfg.ClamAV)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating antivirus analyzer: %w", err)
+	}
+	fallback, err := ProvideFallbackAnalyzer(cfg.ClamAV)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the fallback antivirus analyzer: %w", err)
+	}
+	engines, err := ProvideEngines(cfg.ClamAV)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the antivirus engines: %w", err)
+	}
+
+	svc, err := ProvideService(cfg.Service, b, d, a, fallback, engines, quotas, anon)
+	if err != nil {
+		return nil, fmt.Errorf("error while creating the service: %w", err)
+	}
+
+	return &App{Service: svc, DB: db}, nil
+}
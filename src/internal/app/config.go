@@ -0,0 +1,1794 @@
+package app
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goyav/internal/adapter/alert"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/blocklist"
+	"goyav/internal/adapter/cache"
+	"goyav/internal/adapter/callback"
+	"goyav/internal/adapter/limiter"
+	"goyav/internal/adapter/report"
+	"goyav/internal/adapter/reputation"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/adapter/web"
+	"goyav/internal/core/domain"
+	"goyav/internal/service"
+	"goyav/pkg/helper"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+const (
+	// Default upload size limit in bytes : 1 Mib
+	DefaultMaxUploadSize    uint64        = 1 << 20
+	DefaultUploadTimeout    time.Duration = 10 * time.Second
+	DefaultReadTimeout      time.Duration = 10 * time.Second
+	DefaultWriteTimeout     time.Duration = 60 * time.Second
+	DefaultIdleTimeout      time.Duration = 120 * time.Second
+	DefaultHeaderTimeout    time.Duration = 5 * time.Second // DefaultHeaderTimeout is the default read timeout of the request headers.
+	DefaultResultTimeToLive time.Duration = time.Hour
+
+	// Default image size limit in bytes : 1 GiB
+	DefaultMaxImageSize uint64 = 1 << 30
+
+	// Default multipart uploads to S3: files of 64 MiB or more, in parts of 16 MiB, 4 at once
+	DefaultMultipartThreshold uint64 = 64 << 20
+	DefaultPartSize           uint64 = 16 << 20
+	DefaultPartParallelism    uint   = 4
+)
+
+// Config holds the configuration of GoyAV, in one section per component.
+type Config struct {
+	Server   ServerConfig
+	Tenancy  TenancyConfig
+	Admin    AdminConfig
+	Service  ServiceConfig
+	S3       S3Config
+	Postgres PostgresConfig
+	ClamAV   ClamAVConfig
+	Lambda   LambdaConfig
+}
+
+// ServerConfig configures the HTTP server.
+type ServerConfig struct {
+	Host                 string
+	Port                 int64
+	ListenNetwork        string        // ListenNetwork is the network the server listens on, tcp or unix.
+	ListenAddress        string        // ListenAddress is the host:port, or the path of the Unix socket, the server listens on.
+	SocketMode           os.FileMode   // SocketMode is the mode of the Unix socket the server listens on.
+	MaxUploadSize        uint64        // MaxUploadSize is the maximum size of an upload, in bytes.
+	ReadTimeout          time.Duration // ReadTimeout is the maximum duration of the read of a request, but the uploads.
+	UploadTimeout        time.Duration // UploadTimeout is the maximum duration of the read of an upload, along with UploadMinRate.
+	UploadMinRate        uint64        // UploadMinRate is the slowest upload rate, in bytes per second, given time to read the upload.
+	WriteTimeout         time.Duration // WriteTimeout is the maximum duration of the write of a response, unlimited when it is zero.
+	IdleTimeout          time.Duration // IdleTimeout is the maximum duration a keep-alive connection waits for the next request.
+	ReadHeaderTimeout    time.Duration // ReadHeaderTimeout is the maximum duration of the read of the headers of a request.
+	MaxHeaderBytes       int           // MaxHeaderBytes is the maximum size of the headers of a request, in bytes.
+	MaxConcurrentUploads uint64        // MaxConcurrentUploads is the number of uploads processed at once, unlimited when it is zero.
+	StartupRetry         bool          // StartupRetry makes the server start while the dependencies are not reachable, see Build.
+	RejectUnknownFields  bool          // RejectUnknownFields makes uploads with unknown form fields rejected.
+	MaxImageSize         uint64        // MaxImageSize is the maximum size of an image tarball, in bytes.
+	CompletionEstimates  bool          // CompletionEstimates makes uploads answered with the estimated completion date of their analysis.
+	AllowedExtensions    []string      // AllowedExtensions lists the accepted extensions of the uploaded file names, all of them if empty.
+	DeniedExtensions     []string      // DeniedExtensions lists the rejected extensions of the uploaded file names.
+	StatusEvents         bool          // StatusEvents enables the push of the status changes of the documents, notified by the database.
+	SwaggerUI            bool          // SwaggerUI enables the Swagger UI page exploring the OpenAPI specification of the API.
+	AnalyzerOnly         bool          // AnalyzerOnly assembles GoyAV without its repositories, only scanning files, see BuildAnalyzer.
+
+	// TLSCertFile and TLSKeyFile are the PEM files of the certificate and the key the connections are served with over
+	// TLS, which is disabled when TLSCertFile is empty. TLSClientCAFile is the PEM bundle of the CAs verifying the
+	// certificates the clients must then present, which are not requested when it is empty, and TLSClientNames lists
+	// the common names or subject alternative names of the accepted client certificates, all of them if empty.
+	TLSCertFile     string
+	TLSKeyFile      string
+	TLSClientCAFile string
+	TLSClientNames  []string
+}
+
+// TenancyConfig configures how the tenant of a request is resolved: from its API key if APIKeys is not empty,
+// from the TenantHeader request header if it is set, otherwise all documents belong to the default tenant.
+type TenancyConfig struct {
+	APIKeys      map[string]string // APIKeys maps each accepted API key to its tenant.
+	TenantHeader string
+
+	// SigningClients maps the IDs of the clients signing their requests to their tenant and shared secret, whatever
+	// the tenant resolution, and SignatureMaxSkew bounds the age of their signatures, see web.WithSignedRequests.
+	SigningClients   map[string]web.SigningClient
+	SignatureMaxSkew time.Duration
+}
+
+// AdminConfig configures the administration API, which is disabled when APIKey is empty.
+type AdminConfig struct {
+	APIKey      string
+	TokenSecret string // TokenSecret signs the scoped tokens, which are disabled when it is empty.
+}
+
+// ServiceConfig configures the document service.
+type ServiceConfig struct {
+	Version           string
+	Information       string
+	ResultTTL         time.Duration       // ResultTTL is the retention of the analysis results, the auto-purge is disabled when it is not strictly positive.
+	PurgeSchedule     domain.CronSchedule // PurgeSchedule schedules the auto-purge, which runs every shortest retention when it is zero.
+	SemaphoreCapacity uint64
+
+	// StatusRetentions overrides ResultTTL for the documents of some statuses, which are never purged automatically
+	// when their retention is zero.
+	StatusRetentions map[domain.AnalysisStatus]time.Duration
+
+	// SharedConcurrency limits the number of analyses run at once by all the replicas, on top of SemaphoreCapacity.
+	SharedConcurrency SharedConcurrencyConfig
+
+	// MaxQueuedAnalyses is the number of analyses waiting for a slot above which the uploads are rejected, they are
+	// never rejected when it is zero.
+	MaxQueuedAnalyses int
+
+	// HealthCacheTTL is the time a health report is reused, the dependencies are checked on every call when it is zero.
+	HealthCacheTTL time.Duration
+
+	// AdmissionControlInterval is the polling interval of the analyzer's load, admission control is disabled when it is zero.
+	AdmissionControlInterval time.Duration
+
+	Quotas           QuotaConfig
+	Pseudonymization PseudonymizationConfig
+	Images           ImageConfig
+	Archives         ArchiveConfig
+	PresignedUploads PresignedUploadConfig
+	Retention        RetentionConfig
+	Callbacks        CallbackConfig
+	VerdictCache     VerdictCacheConfig
+	Blocklist        BlocklistConfig
+	Reputation       ReputationConfig
+	Reports          ReportConfig
+	Alerts           AlertConfig
+	Retry            service.RetryPolicy // Retry is the schedule of the attempts of the analyses.
+
+	// IDScheme is the scheme of the IDs of the uploaded documents.
+	IDScheme helper.IDScheme
+
+	// HashAlgorithm hashes the content of the uploaded documents.
+	HashAlgorithm helper.HashAlgorithm
+
+	// MaxUploadSizes maps tenants to the maximum size of their uploads in bytes, overriding ServerConfig.MaxUploadSize.
+	MaxUploadSizes map[string]int64
+
+	// SpoolMemory is the size in bytes of the uploads held in memory while they are hashed and stored, the larger ones
+	// are spilled to a temporary file in SpoolDir, the default directory of the system if empty.
+	SpoolMemory int64
+	SpoolDir    string
+
+	// MediaTypes restricts the media types of the uploaded documents.
+	MediaTypes domain.MediaTypePolicy
+
+	// TagPolicy sanitizes the tags of the uploaded documents.
+	TagPolicy helper.TagPolicy
+
+	// DedupePolicy is the deduplication policy of the tenants without a policy of their own in DedupePolicies.
+	DedupePolicy   domain.DedupePolicy
+	DedupePolicies map[string]domain.DedupePolicy
+
+	// AnalysisDeadline is the maximum duration of an analysis, retries included, analyses are unbounded when it is zero.
+	AnalysisDeadline time.Duration
+
+	// StuckPendingThreshold is the time a document may be pending for before it is reported as stuck, the pending
+	// documents are not watched when it is zero.
+	StuckPendingThreshold time.Duration
+}
+
+// QuotaConfig configures the quotas of the tenants, which are not enforced unless Enabled is set.
+type QuotaConfig struct {
+	Enabled bool
+	Default domain.Quota            // Default is the quota of the tenants without an entry in Tenants.
+	Tenants map[string]domain.Quota // Tenants holds the quotas of specific tenants.
+}
+
+// PseudonymizationConfig configures the pseudonymization of tags and file names, which is disabled when Key is empty.
+type PseudonymizationConfig struct {
+	Key     []byte
+	SealKey []byte // SealKey keeps the original values encrypted, they are not kept when it is empty.
+}
+
+// ImageConfig configures the analysis of container images, which is disabled unless Enabled is set.
+type ImageConfig struct {
+	Enabled    bool
+	Limits     domain.ImageLimits
+	CheckLinks bool // CheckLinks makes the links escaping the filesystem of a layer and the Windows shortcuts reported.
+}
+
+// ArchiveConfig configures the extraction of the uploaded archives, which are analyzed as a whole unless Enabled is set.
+type ArchiveConfig struct {
+	Enabled bool
+	Limits  domain.ArchiveLimits
+}
+
+// PresignedUploadConfig configures the uploads made directly to the S3 bucket with presigned URLs,
+// which are disabled unless Enabled is set.
+type PresignedUploadConfig struct {
+	Enabled bool
+	Expiry  time.Duration // Expiry is the validity of the presigned URLs.
+	MaxSize int64         // MaxSize is the maximum size of a presigned upload in bytes, overridden by the tenants' own maximum.
+}
+
+// RetentionConfig configures the retention of the files of the analyzed documents, which are deleted once analyzed
+// unless RetainClean, for the clean ones, or QuarantineInfected, for the infected ones, is set.
+type RetentionConfig struct {
+	RetainClean        bool
+	DownloadExpiry     time.Duration // DownloadExpiry is the validity of the presigned URLs the retained files are downloaded from.
+	QuarantineInfected bool
+	TagVerdicts        bool // TagVerdicts records the verdicts in the tags of the retained and quarantined files.
+}
+
+// CallbackConfig configures the notification of the callback URLs given with the uploads, which are refused unless
+// Enabled is set.
+type CallbackConfig struct {
+	Enabled      bool
+	Timeout      time.Duration // Timeout bounds each request to a callback URL.
+	Attempts     int           // Attempts is the maximum number of requests made to notify a callback URL.
+	AllowPrivate bool          // AllowPrivate lets the callback URLs target loopback and private addresses.
+	Compression  int           // Compression is the size of the bodies compressed for the hosts accepting it, 0 to never.
+
+	// Outbox records the callback URLs in the database along with the uploads, their notifications being claimed
+	// every OutboxInterval for OutboxLease, doubled after each claim, and given up after OutboxClaims claims.
+	Outbox         bool
+	OutboxInterval time.Duration
+	OutboxLease    time.Duration
+	OutboxClaims   int
+}
+
+// SharedConcurrencyConfig configures the concurrency limiter shared by the replicas through Redis, which is disabled
+// when Capacity is zero.
+type SharedConcurrencyConfig struct {
+	RedisURL string        // RedisURL is the URL of the Redis server holding the slots, e.g. redis://redis:6379/0.
+	Capacity int           // Capacity is the number of analyses run at once by all the replicas sharing Key.
+	Key      string        // Key is the key of the slots in Redis, distinct for the deployments sharing a server.
+	Lease    time.Duration // Lease is the time the slots of a replica stopped without giving them back are held for.
+}
+
+// VerdictCacheConfig configures the in-memory cache of the verdicts by hash of the analyzed content, which is disabled
+// when TTL is zero.
+type VerdictCacheConfig struct {
+	TTL        time.Duration // TTL is the time a verdict is cached for, independently of the retention of the documents.
+	MaxEntries int           // MaxEntries is the maximum number of cached verdicts, the least recently used are evicted.
+}
+
+// BlocklistConfig configures the blocklist of the hashes of known malicious content, which is disabled when Path is
+// empty.
+type BlocklistConfig struct {
+	Path           string        // Path is the path of the file listing the blocklisted hashes.
+	Threat         string        // Threat is the name of the threat of the hashes listed without one.
+	ReloadInterval time.Duration // ReloadInterval is the interval between two checks for changes of the file.
+}
+
+// ReputationConfig configures the external reputation source looked up before the analyses, which is disabled when
+// Source is empty.
+type ReputationConfig struct {
+	Source  string        // Source is the name of the reputation source, malwarebazaar or virustotal.
+	URL     string        // URL is the URL of the API of the source, its public API when empty.
+	APIKey  string        // APIKey authenticates the lookups.
+	Timeout time.Duration // Timeout bounds each lookup.
+
+	// MinMalicious and MinClean are the numbers of VirusTotal engines detecting content for it to be infected, and
+	// analyzing it without result for it to be clean, never when MinClean is zero.
+	MinMalicious int
+	MinClean     int
+}
+
+// ReportConfig configures the scheduled summary reports, which are disabled when the period of Schedule is empty.
+// The reports are posted to WebhookURL unless it is empty, and mailed to EmailTo through the SMTP server listening on
+// SMTPAddress unless it is empty.
+type ReportConfig struct {
+	Schedule domain.ReportSchedule
+	Top      int           // Top is the number of threats and tenants listed by the reports.
+	Timeout  time.Duration // Timeout bounds the requests to the webhook URL.
+
+	// Compression is the size of the bodies compressed for the webhooks accepting it, 0 to never.
+	Compression int
+
+	WebhookURL string
+
+	SMTPAddress  string
+	SMTPUsername string
+	SMTPPassword string
+	EmailFrom    string
+	EmailTo      []string
+}
+
+// AlertConfig configures the alert raised when the infected rate of the analyses over Window rises above InfectedRate,
+// which is disabled when InfectedRate is zero. The alert is notified by each of Notifiers, log, webhook or slack, the
+// latter two posting to WebhookURL and SlackWebhookURL.
+type AlertConfig struct {
+	InfectedRate float64
+	Window       time.Duration
+	MinAnalyzed  int64         // MinAnalyzed is the number of verdicts over Window below which no alert is raised.
+	Timeout      time.Duration // Timeout bounds the requests to the webhook URLs.
+	Compression  int           // Compression is the size of the bodies compressed for the webhooks accepting it, 0 to never.
+
+	Notifiers       []string
+	WebhookURL      string
+	SlackWebhookURL string
+}
+
+// S3Config configures the S3 bucket holding the binary data of documents.
+type S3Config struct {
+	Endpoint    string // Endpoint is the host and port of the S3 service, without protocol.
+	AccessKeyID string
+	SecretKey   string
+	Bucket      string
+	UseSSL      bool
+
+	// LifecycleExpiry is the age of the objects expired by the lifecycle of the bucket, which is left as is when it is zero.
+	LifecycleExpiry time.Duration
+
+	// QuarantineRetention and QuarantineLegalHold protect the files of the quarantined documents from deletion, with
+	// a retention for the given duration and a legal hold, which require object locking.
+	QuarantineRetention time.Duration
+	QuarantineLegalHold bool
+
+	// SSEMode is the server-side encryption mode of the saved files, see binaryrepo.WithServerSideEncryption,
+	// and SSEKMSKeyID the key of the key management service in SSE-KMS mode.
+	SSEMode     string
+	SSEKMSKeyID string
+
+	// ShardLevels is the number of levels of key prefixes the files are spread across, see binaryrepo.WithKeySharding.
+	ShardLevels int
+
+	// MultipartThreshold is the size from which the files are saved with a multipart upload of parts of PartSize
+	// bytes, PartParallelism at once, see binaryrepo.WithMultipartUpload.
+	MultipartThreshold uint64
+	PartSize           uint64
+	PartParallelism    uint
+
+	// SlowThreshold is the duration from which an operation on the files is logged as slow, none if zero, see
+	// binaryrepo.WithSlowOperations.
+	SlowThreshold time.Duration
+}
+
+// PostgresConfig configures the PostgreSQL database holding the documents.
+type PostgresConfig struct {
+	Host     string
+	Port     uint64
+	User     string
+	Password string
+	Database string
+	Schema   string
+	SSLMode  string
+
+	// Settings of the connection pool shared by the Postgres repositories, see sql.DB. Zero means unlimited.
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+
+	// AutoMigrate applies the pending migrations of the database at startup, otherwise they are applied by
+	// "goyav migrate" and the startup fails until then.
+	AutoMigrate bool
+
+	// Partitions partitions the documents table by creation date with the given interval, none by default, and
+	// PartitionsAhead is the number of partitions created in advance of the current one.
+	Partitions      docrepo.PartitionInterval
+	PartitionsAhead int
+
+	// PurgeBatchSize is the maximum number of documents deleted at once by a purge, all of them if zero, and
+	// PurgeBatchPause the pause between two batches.
+	PurgeBatchSize  int
+	PurgeBatchPause time.Duration
+
+	// SlowThreshold is the duration from which a query on the documents is logged as slow, none if zero, see
+	// docrepo.WithSlowOperations.
+	SlowThreshold time.Duration
+}
+
+// ClamAVConfig configures the ClamAV antivirus analyzer.
+type ClamAVConfig struct {
+	Host    string
+	Port    uint64
+	Timeout uint64 // Timeout is the timeout of an analysis, in seconds.
+
+	// ChunkSize is the size of the chunks of data streamed to clamd, see antivirus.WithChunkSize.
+	ChunkSize uint64
+
+	// TimeoutPerMB is the time added to Timeout for each MiB of data analyzed, up to MaxTimeout, see
+	// antivirus.WithSizeTimeout.
+	TimeoutPerMB time.Duration
+	MaxTimeout   time.Duration
+
+	// FallbackHost and FallbackPort locate the clamd analyzing the files while the primary one fails, none when
+	// FallbackHost is empty.
+	FallbackHost string
+	FallbackPort uint64
+
+	// Engines maps the names of the engines an upload can select besides clamav to the "host:port" address of their
+	// clamd, see service.WithEngines.
+	Engines map[string]string
+
+	// SlowThreshold is the duration from which an analysis is logged as slow, none if zero, see
+	// antivirus.WithSlowAnalyses.
+	SlowThreshold time.Duration
+}
+
+// LambdaConfig configures the Lambda mode, in which the objects notified by S3 events are analyzed.
+type LambdaConfig struct {
+	S3Endpoint   string        // S3Endpoint is the host of the S3 service holding the notified objects.
+	Region       string        // Region is the region of the S3 service.
+	Tenant       string        // Tenant owns the documents of the objects, the default tenant when it is empty.
+	PollInterval time.Duration // PollInterval is the interval between two checks of a pending verdict.
+}
+
+// LoadConfig reads the configuration of GoyAV from the GOYAV_* environment variables.
+func LoadConfig() (*Config, error) {
+	cfg := new(Config)
+	loaders := []func(*Config) error{
+		loadServerConfig,
+		loadTenancyConfig,
+		loadAdminConfig,
+		loadServiceConfig,
+		loadS3Config,
+		loadPostgresConfig,
+		loadClamAVConfig,
+		loadLambdaConfig,
+	}
+	for _, load := range loaders {
+		if err := load(cfg); err != nil {
+			return nil, err
+		}
+	}
+	return cfg, nil
+}
+
+func loadServerConfig(cfg *Config) error {
+	var err error
+	c := &cfg.Server
+
+	// Configure host (default: localhost) and port (default : 80)
+	c.Host = helper.GetEnvWithDefault("GOYAV_HOST", "localhost")
+	c.Port, err = strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_PORT", "80"), 10, 64)
+	if err != nil {
+		return errors.New("GOYAV_PORT must be a valid port number")
+	}
+	slog.Info("server configuration", "host", c.Host, "port", c.Port)
+
+	// Configure the listener (default: TCP on the host and port)
+	if c.ListenNetwork, c.ListenAddress, err = parseListen(helper.GetEnvWithDefault("GOYAV_LISTEN", ""), c.Host, c.Port); err != nil {
+		return fmt.Errorf("GOYAV_LISTEN: %w", err)
+	}
+	mode, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_SOCKET_MODE", "0660"), 8, 32)
+	if err != nil || mode > 0o777 {
+		return errors.New("GOYAV_SOCKET_MODE must be an octal file mode, e.g. 0660")
+	}
+	c.SocketMode = os.FileMode(mode)
+	slog.Info("listener set", "network", c.ListenNetwork, "address", c.ListenAddress)
+
+	// Configure maximum upload size (default: 1 MiB)
+	c.MaxUploadSize, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_MAX_UPLOAD_SIZE", ""), 10, 64)
+	if err != nil || c.MaxUploadSize == 0 {
+		c.MaxUploadSize = DefaultMaxUploadSize
+		slog.Warn("setting maximum upload size set to default", "default (bytes)", c.MaxUploadSize)
+	}
+	slog.Info("maximum upload size set", "size (bytes)", c.MaxUploadSize)
+
+	// Configure upload timeout in seconds (default: 10 seconds)
+	uploadTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_UPLOAD_TIMEOUT", ""), 10, 64)
+	c.UploadTimeout = time.Duration(uploadTimeout) * time.Second
+	if err != nil || uploadTimeout == 0 {
+		c.UploadTimeout = DefaultUploadTimeout
+		slog.Warn("setting upload timeout to default", "default", DefaultUploadTimeout.String())
+	}
+	slog.Info("upload timeout set", "timeout", c.UploadTimeout.String())
+
+	// Configure the minimum upload rate, extending the upload timeout by the size of the upload (default: 0, disabled)
+	c.UploadMinRate, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_UPLOAD_MIN_RATE", "0"), 10, 64)
+	if err != nil {
+		return errors.New("GOYAV_UPLOAD_MIN_RATE must be a positive number of bytes per second")
+	}
+	slog.Info("upload minimum rate set", "enabled ?", c.UploadMinRate > 0, "rate (bytes/s)", c.UploadMinRate)
+
+	// Configure read timeout of the other requests in seconds (default: 10 seconds)
+	readTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_READ_TIMEOUT", "10"), 10, 64)
+	if err != nil || readTimeout == 0 {
+		return errors.New("GOYAV_READ_TIMEOUT must be a strictly positive number of seconds")
+	}
+	c.ReadTimeout = time.Duration(readTimeout) * time.Second
+	slog.Info("read timeout set", "timeout", c.ReadTimeout.String())
+
+	// Configure write timeout in seconds (default: 60 seconds, 0 disables it)
+	writeTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_WRITE_TIMEOUT", "60"), 10, 64)
+	if err != nil {
+		return errors.New("GOYAV_WRITE_TIMEOUT must be a positive number of seconds")
+	}
+	c.WriteTimeout = time.Duration(writeTimeout) * time.Second
+	slog.Info("write timeout set", "enabled ?", c.WriteTimeout > 0, "timeout", c.WriteTimeout.String())
+
+	// Configure idle timeout of the keep-alive connections in seconds (default: 120 seconds)
+	idleTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_IDLE_TIMEOUT", "120"), 10, 64)
+	if err != nil || idleTimeout == 0 {
+		return errors.New("GOYAV_IDLE_TIMEOUT must be a strictly positive number of seconds")
+	}
+	c.IdleTimeout = time.Duration(idleTimeout) * time.Second
+	slog.Info("idle timeout set", "timeout", c.IdleTimeout.String())
+
+	// Configure read timeout of the request headers in seconds (default: 5 seconds)
+	headerTimeout, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_READ_HEADER_TIMEOUT", "5"), 10, 64)
+	if err != nil || headerTimeout == 0 {
+		return errors.New("GOYAV_READ_HEADER_TIMEOUT must be a strictly positive number of seconds")
+	}
+	c.ReadHeaderTimeout = time.Duration(headerTimeout) * time.Second
+	slog.Info("read header timeout set", "timeout", c.ReadHeaderTimeout.String())
+
+	// Configure maximum size of the request headers (default: 1 MiB)
+	c.MaxHeaderBytes, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_MAX_HEADER_BYTES", strconv.Itoa(http.DefaultMaxHeaderBytes)))
+	if err != nil || c.MaxHeaderBytes <= 0 {
+		return errors.New("GOYAV_MAX_HEADER_BYTES must be a strictly positive number of bytes")
+	}
+	slog.Info("maximum header size set", "size (bytes)", c.MaxHeaderBytes)
+
+	// Configure the startup retry (default: false)
+	c.StartupRetry, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_STARTUP_RETRY", "false"))
+	if err != nil {
+		return errors.New("GOYAV_STARTUP_RETRY must be true or false")
+	}
+	slog.Info("startup retry set", "enabled ?", c.StartupRetry)
+
+	// Configure the number of uploads processed at once (default: 0, unlimited)
+	c.MaxConcurrentUploads, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_MAX_CONCURRENT_UPLOADS", "0"), 10, 64)
+	if err != nil {
+		return errors.New("GOYAV_MAX_CONCURRENT_UPLOADS must be a positive integer")
+	}
+	slog.Info("upload limiter set", "enabled ?", c.MaxConcurrentUploads > 0, "max concurrent uploads", c.MaxConcurrentUploads)
+
+	// Configure the rejection of unknown upload form fields (default: false)
+	c.RejectUnknownFields, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_REJECT_UNKNOWN_FIELDS", "false"))
+	if err != nil {
+		return errors.New("GOYAV_REJECT_UNKNOWN_FIELDS must be true or false")
+	}
+	slog.Info("upload form validation set", "reject unknown fields ?", c.RejectUnknownFields)
+
+	// Configure the completion estimates of the upload responses (default: false)
+	c.CompletionEstimates, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_COMPLETION_ESTIMATES", "false"))
+	if err != nil {
+		return errors.New("GOYAV_COMPLETION_ESTIMATES must be true or false")
+	}
+	slog.Info("upload completion estimates set", "enabled ?", c.CompletionEstimates)
+
+	// Configure the push of the status changes as server-sent events (default: false)
+	c.StatusEvents, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_STATUS_EVENTS", "false"))
+	if err != nil {
+		return errors.New("GOYAV_STATUS_EVENTS must be true or false")
+	}
+	slog.Info("status events set", "enabled ?", c.StatusEvents)
+
+	// Configure the analyzer-only mode, without the S3 bucket nor the database (default: false)
+	c.AnalyzerOnly, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_ANALYZER_ONLY", "false"))
+	if err != nil {
+		return errors.New("GOYAV_ANALYZER_ONLY must be true or false")
+	}
+	if c.AnalyzerOnly && c.StatusEvents {
+		return errors.New("GOYAV_STATUS_EVENTS cannot be enabled along with GOYAV_ANALYZER_ONLY, without the database")
+	}
+	slog.Info("analyzer-only mode set", "enabled ?", c.AnalyzerOnly)
+
+	// Configure the Swagger UI page (default: false)
+	c.SwaggerUI, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_SWAGGER_UI", "false"))
+	if err != nil {
+		return errors.New("GOYAV_SWAGGER_UI must be true or false")
+	}
+	slog.Info("swagger UI set", "enabled ?", c.SwaggerUI)
+
+	// Configure the extensions of the uploaded file names (default: all of them)
+	if c.AllowedExtensions, err = parseExtensions(helper.GetEnvWithDefault("GOYAV_ALLOWED_EXTENSIONS", "")); err != nil {
+		return fmt.Errorf("GOYAV_ALLOWED_EXTENSIONS is not valid: %w", err)
+	}
+	if c.DeniedExtensions, err = parseExtensions(helper.GetEnvWithDefault("GOYAV_DENIED_EXTENSIONS", "")); err != nil {
+		return fmt.Errorf("GOYAV_DENIED_EXTENSIONS is not valid: %w", err)
+	}
+	slog.Info("file extension policy set", "allowed", c.AllowedExtensions, "denied", c.DeniedExtensions)
+
+	// Configure maximum image size (default: 1 GiB)
+	if c.MaxImageSize, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_MAX_IMAGE_SIZE", strconv.FormatUint(DefaultMaxImageSize, 10)), 10, 64); err != nil || c.MaxImageSize == 0 {
+		return errors.New("GOYAV_MAX_IMAGE_SIZE must be a strictly positive number of bytes")
+	}
+
+	// Configure TLS and the client certificates (default: none)
+	c.TLSCertFile = helper.GetEnvWithDefault("GOYAV_TLS_CERT_FILE", "")
+	c.TLSKeyFile = helper.GetEnvWithDefault("GOYAV_TLS_KEY_FILE", "")
+	c.TLSClientCAFile = helper.GetEnvWithDefault("GOYAV_TLS_CLIENT_CA_FILE", "")
+	c.TLSClientNames = parseNames(helper.GetEnvWithDefault("GOYAV_TLS_CLIENT_NAMES", ""))
+	switch {
+	case (c.TLSCertFile == "") != (c.TLSKeyFile == ""):
+		return errors.New("GOYAV_TLS_CERT_FILE and GOYAV_TLS_KEY_FILE must be set together")
+	case c.TLSClientCAFile != "" && c.TLSCertFile == "":
+		return errors.New("GOYAV_TLS_CLIENT_CA_FILE requires GOYAV_TLS_CERT_FILE")
+	case len(c.TLSClientNames) > 0 && c.TLSClientCAFile == "":
+		return errors.New("GOYAV_TLS_CLIENT_NAMES requires GOYAV_TLS_CLIENT_CA_FILE")
+	}
+	slog.Info("TLS set", "enabled ?", c.TLSCertFile != "", "client certificates ?", c.TLSClientCAFile != "")
+	return nil
+}
+
+func loadTenancyConfig(cfg *Config) error {
+	var err error
+	c := &cfg.Tenancy
+
+	// Configure the clients signing their requests (default: none)
+	if v := helper.GetEnvWithDefault("GOYAV_SIGNING_CLIENTS", ""); v != "" {
+		if c.SigningClients, err = parseSigningClients(v); err != nil {
+			return fmt.Errorf("GOYAV_SIGNING_CLIENTS is not valid: %w", err)
+		}
+	}
+	if c.SignatureMaxSkew, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_SIGNATURE_MAX_SKEW", web.DefaultSignatureMaxSkew.String())); err != nil || c.SignatureMaxSkew <= 0 {
+		return errors.New("GOYAV_SIGNATURE_MAX_SKEW must be a strictly positive duration")
+	}
+	slog.Info("signed requests set", "signing clients", len(c.SigningClients), "max skew", c.SignatureMaxSkew.String())
+
+	if v := helper.GetEnvWithDefault("GOYAV_API_KEYS", ""); v != "" {
+		keys, err := parseAPIKeys(v)
+		if err != nil {
+			return fmt.Errorf("GOYAV_API_KEYS is not valid: %w", err)
+		}
+		c.APIKeys = keys
+		slog.Info("multi-tenancy set", "tenant resolution", "api key", "api keys", len(keys))
+		return nil
+	}
+
+	if header := helper.GetEnvWithDefault("GOYAV_TENANT_HEADER", ""); header != "" {
+		c.TenantHeader = header
+		slog.Info("multi-tenancy set", "tenant resolution", "header", "header", header)
+		return nil
+	}
+
+	slog.Info("multi-tenancy disabled")
+	return nil
+}
+
+func loadAdminConfig(cfg *Config) error {
+	c := &cfg.Admin
+
+	// Configure the admin API, disabled when no admin API key is set
+	c.APIKey = helper.GetEnvWithDefault("GOYAV_ADMIN_API_KEY", "")
+	slog.Info("admin API set", "enabled ?", c.APIKey != "")
+
+	// Configure the scoped tokens, disabled when no token secret is set
+	c.TokenSecret = helper.GetEnvWithDefault("GOYAV_TOKEN_SECRET", "")
+	if c.TokenSecret != "" && len(c.TokenSecret) < web.TokenSecretMinLength {
+		return fmt.Errorf("GOYAV_TOKEN_SECRET must be at least %d bytes long", web.TokenSecretMinLength)
+	}
+	slog.Info("scoped tokens set", "enabled ?", c.TokenSecret != "")
+	return nil
+}
+
+func loadServiceConfig(cfg *Config) error {
+	var err error
+	c := &cfg.Service
+
+	// Configure version
+	c.Version, err = helper.GetEnvWithError("GOYAV_VERSION")
+	if err != nil {
+		return errors.New("GOYAV_VERSION must be set")
+	}
+	slog.Info("application version set", "version", c.Version)
+
+	// Configure information (default: GoyAV)
+	c.Information = helper.GetEnvWithDefault("GOYAV_INFORMATION", "GoyAV")
+	slog.Info("application information set", "information", c.Information)
+
+	// Configure result time to live (default: 1 hour)
+	c.ResultTTL, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_RESULT_TTL", "1h"))
+	if err != nil {
+		c.ResultTTL = DefaultResultTimeToLive
+		slog.Warn("setting result time to live to default", "default", c.ResultTTL.String())
+	}
+	slog.Info("result time to live set", "duration", c.ResultTTL.String())
+	if v := helper.GetEnvWithDefault("GOYAV_PURGE_SCHEDULE", ""); v != "" {
+		if c.PurgeSchedule, err = domain.ParseCronSchedule(v); err != nil {
+			return fmt.Errorf("GOYAV_PURGE_SCHEDULE is not valid: %w", err)
+		}
+	}
+	if v := helper.GetEnvWithDefault("GOYAV_STATUS_RETENTION", ""); v != "" {
+		if c.StatusRetentions, err = parseStatusRetentions(v); err != nil {
+			return fmt.Errorf("GOYAV_STATUS_RETENTION is not valid: %w", err)
+		}
+		slog.Info("status retentions set", "retentions", c.StatusRetentions)
+	}
+	slog.Info("document repository auto-purge set", "auto-purge ?", c.ResultTTL > 0 || len(c.StatusRetentions) > 0, "schedule", c.PurgeSchedule.String())
+
+	// Configure semaphore capacity (default: 128 goroutines)
+	c.SemaphoreCapacity, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_SEMAPHORE_CAPACITY", "128"), 10, 64)
+	if err != nil {
+		c.SemaphoreCapacity = service.DefaultSemaphoreCapacity
+		slog.Warn("setting semaphore capacity to default", "default", "128 goroutines")
+	}
+	slog.Info("semaphore capacity set", "capacity (goroutines)", c.SemaphoreCapacity)
+
+	// Configure the concurrency limiter shared by the replicas (default: 0, disabled)
+	if err = loadSharedConcurrency(&c.SharedConcurrency); err != nil {
+		return err
+	}
+
+	// Configure the backpressure on the uploads (default: 0, disabled)
+	c.MaxQueuedAnalyses, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_MAX_QUEUED_ANALYSES", "0"))
+	if err != nil || c.MaxQueuedAnalyses < 0 {
+		return errors.New("GOYAV_MAX_QUEUED_ANALYSES must be a positive integer")
+	}
+	slog.Info("upload backpressure set", "enabled ?", c.MaxQueuedAnalyses > 0, "max queued analyses", c.MaxQueuedAnalyses)
+
+	// Configure the cache of the health checks (default: 5 seconds)
+	c.HealthCacheTTL, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_HEALTH_CACHE_TTL", "5s"))
+	if err != nil || c.HealthCacheTTL < 0 {
+		return errors.New("GOYAV_HEALTH_CACHE_TTL must be a positive duration")
+	}
+	slog.Info("health check cache set", "enabled ?", c.HealthCacheTTL > 0, "ttl", c.HealthCacheTTL.String())
+
+	// Configure the polling interval of the analyzer's load (default: 0, admission control disabled)
+	c.AdmissionControlInterval, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CLAMAV_STATS_INTERVAL", "0s"))
+	if err != nil {
+		return errors.New("GOYAV_CLAMAV_STATS_INTERVAL must be a valid duration")
+	}
+	slog.Info("analyzer admission control set", "enabled ?", c.AdmissionControlInterval > 0, "interval", c.AdmissionControlInterval.String())
+
+	// Configure per-tenant quotas
+	if v := helper.GetEnvWithDefault("GOYAV_TENANT_QUOTAS", ""); v != "" {
+		c.Quotas.Default, c.Quotas.Tenants, err = parseQuotas(v)
+		if err != nil {
+			return fmt.Errorf("GOYAV_TENANT_QUOTAS is not valid: %w", err)
+		}
+		c.Quotas.Enabled = true
+		slog.Info("tenant quotas set", "tenants", len(c.Quotas.Tenants), "default", fmt.Sprintf("%+v", c.Quotas.Default))
+	} else {
+		slog.Info("tenant quotas disabled")
+	}
+
+	// Configure per-tenant maximum upload sizes
+	if v := helper.GetEnvWithDefault("GOYAV_TENANT_MAX_UPLOAD_SIZES", ""); v != "" {
+		if c.MaxUploadSizes, err = parseTenantSizes(v); err != nil {
+			return fmt.Errorf("GOYAV_TENANT_MAX_UPLOAD_SIZES is not valid: %w", err)
+		}
+		slog.Info("tenant maximum upload sizes set", "sizes (bytes)", c.MaxUploadSizes)
+	}
+
+	// Configure the spool of the uploads (default: spilled to the default temporary directory)
+	c.SpoolMemory, err = strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_SPOOL_MEMORY", "0"), 10, 64)
+	if err != nil || c.SpoolMemory < 0 {
+		return errors.New("GOYAV_SPOOL_MEMORY must be a positive number of bytes")
+	}
+	if c.SpoolDir = helper.GetEnvWithDefault("GOYAV_SPOOL_DIR", ""); c.SpoolDir != "" {
+		if info, err := os.Stat(c.SpoolDir); err != nil || !info.IsDir() {
+			return fmt.Errorf("GOYAV_SPOOL_DIR must be an existing directory: %s", c.SpoolDir)
+		}
+	}
+	slog.Info("upload spool set", "memory (bytes)", c.SpoolMemory, "directory", c.SpoolDir)
+
+	// Configure the pseudonymization of tags and file names
+	c.Pseudonymization.Key = []byte(helper.GetEnvWithDefault("GOYAV_PSEUDONYMIZATION_KEY", ""))
+	if v := helper.GetEnvWithDefault("GOYAV_PSEUDONYMIZATION_SEAL_KEY", ""); v != "" {
+		if c.Pseudonymization.SealKey, err = hex.DecodeString(v); err != nil {
+			return errors.New("GOYAV_PSEUDONYMIZATION_SEAL_KEY must be hex-encoded")
+		}
+	}
+	slog.Info("pseudonymization set", "enabled ?", len(c.Pseudonymization.Key) > 0, "originals kept ?", len(c.Pseudonymization.SealKey) > 0)
+
+	// Configure the retry policy of the analyses
+	if err = loadRetryPolicy(&c.Retry); err != nil {
+		return err
+	}
+
+	// Configure the scheme of the document IDs (default: md5)
+	if c.IDScheme, err = helper.ParseIDScheme(helper.GetEnvWithDefault("GOYAV_ID_SCHEME", string(helper.DefaultIDScheme))); err != nil {
+		return fmt.Errorf("GOYAV_ID_SCHEME is not valid: %w", err)
+	}
+	slog.Info("document ID scheme set", "scheme", c.IDScheme)
+
+	// Configure the algorithm hashing the content of the documents (default: SHA-256)
+	if c.HashAlgorithm, err = helper.ParseHashAlgorithm(helper.GetEnvWithDefault("GOYAV_HASH_ALGORITHM", string(helper.DefaultHashAlgorithm))); err != nil {
+		return fmt.Errorf("GOYAV_HASH_ALGORITHM is not valid: %w", err)
+	}
+	slog.Info("hash algorithm set", "algorithm", c.HashAlgorithm)
+
+	// Configure the blocklist of the hashes of known malicious content (default: disabled)
+	c.Blocklist.Path = helper.GetEnvWithDefault("GOYAV_HASH_BLOCKLIST", "")
+	c.Blocklist.Threat = helper.GetEnvWithDefault("GOYAV_HASH_BLOCKLIST_THREAT", blocklist.DefaultThreat)
+	if c.Blocklist.ReloadInterval, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_HASH_BLOCKLIST_RELOAD_INTERVAL", "30s")); err != nil || c.Blocklist.ReloadInterval < 0 {
+		return errors.New("GOYAV_HASH_BLOCKLIST_RELOAD_INTERVAL must be a positive duration")
+	}
+	slog.Info("hash blocklist set", "enabled ?", c.Blocklist.Path != "", "path", c.Blocklist.Path, "reload interval", c.Blocklist.ReloadInterval.String())
+
+	// Configure the media types accepted for upload (default: all of them)
+	if c.MediaTypes.Allow, err = parseMediaTypes(helper.GetEnvWithDefault("GOYAV_ALLOWED_MEDIA_TYPES", "")); err != nil {
+		return fmt.Errorf("GOYAV_ALLOWED_MEDIA_TYPES is not valid: %w", err)
+	}
+	if c.MediaTypes.Deny, err = parseMediaTypes(helper.GetEnvWithDefault("GOYAV_DENIED_MEDIA_TYPES", "")); err != nil {
+		return fmt.Errorf("GOYAV_DENIED_MEDIA_TYPES is not valid: %w", err)
+	}
+	slog.Info("media type policy set", "enabled ?", !c.MediaTypes.IsZero(), "allowed", c.MediaTypes.Allow, "denied", c.MediaTypes.Deny)
+
+	// Configure the sanitization of the tags (default: letters, digits, '-', '_' and '.', within 128 bytes)
+	c.TagPolicy = helper.DefaultTagPolicy
+	if c.TagPolicy.MaxLength, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_TAG_MAX_LENGTH", strconv.Itoa(helper.TagMaxLength))); err != nil || c.TagPolicy.MaxLength < 1 {
+		return errors.New("GOYAV_TAG_MAX_LENGTH must be a strictly positive number of bytes")
+	}
+	if v := helper.GetEnvWithDefault("GOYAV_TAG_CHARACTERS", ""); v != "" {
+		if c.TagPolicy.Classes, err = helper.ParseCharClasses(v); err != nil {
+			return fmt.Errorf("GOYAV_TAG_CHARACTERS is not valid: %w", err)
+		}
+	}
+	c.TagPolicy.Extra = helper.GetEnvWithDefault("GOYAV_TAG_EXTRA_CHARACTERS", c.TagPolicy.Extra)
+	if c.TagPolicy.Raw, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_TAG_RAW", "false")); err != nil {
+		return errors.New("GOYAV_TAG_RAW must be a boolean")
+	}
+	if err = c.TagPolicy.Validate(); err != nil {
+		return fmt.Errorf("GOYAV_TAG_MAX_LENGTH is not valid: %w", err)
+	}
+	slog.Info("tag policy set", "max length", c.TagPolicy.MaxLength, "classes", c.TagPolicy.Classes, "extra characters", c.TagPolicy.Extra, "raw ?", c.TagPolicy.Raw)
+
+	// Configure the deduplication policy of the uploads (default: strict)
+	if c.DedupePolicy, err = domain.ParseDedupePolicy(helper.GetEnvWithDefault("GOYAV_DEDUPE_POLICY", string(domain.DedupeStrict))); err != nil {
+		return fmt.Errorf("GOYAV_DEDUPE_POLICY is not valid: %w", err)
+	}
+	if v := helper.GetEnvWithDefault("GOYAV_TENANT_DEDUPE_POLICIES", ""); v != "" {
+		if c.DedupePolicies, err = parseTenantDedupePolicies(v); err != nil {
+			return fmt.Errorf("GOYAV_TENANT_DEDUPE_POLICIES is not valid: %w", err)
+		}
+	}
+	slog.Info("deduplication policy set", "policy", c.DedupePolicy, "tenant policies", c.DedupePolicies)
+
+	// Configure the deadline of the analyses (default: 15 minutes)
+	c.AnalysisDeadline, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_ANALYSIS_DEADLINE", service.DefaultAnalysisDeadline.String()))
+	if err != nil || c.AnalysisDeadline < 0 {
+		return errors.New("GOYAV_ANALYSIS_DEADLINE must be a positive duration")
+	}
+	if c.AnalysisDeadline > 0 && c.Retry.MaxDuration > c.AnalysisDeadline {
+		slog.Warn("the analysis deadline is shorter than the maximum duration of the retries", "deadline", c.AnalysisDeadline.String(), "max retry duration", c.Retry.MaxDuration.String())
+	}
+	slog.Info("analysis deadline set", "enabled ?", c.AnalysisDeadline > 0, "deadline", c.AnalysisDeadline.String())
+
+	// Configure the detection of the stuck pending documents (default: 0, disabled)
+	c.StuckPendingThreshold, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_STUCK_PENDING_THRESHOLD", "0"))
+	if err != nil || c.StuckPendingThreshold < 0 {
+		return errors.New("GOYAV_STUCK_PENDING_THRESHOLD must be a positive duration")
+	}
+	slog.Info("stuck pending detection set", "enabled ?", c.StuckPendingThreshold > 0, "threshold", c.StuckPendingThreshold.String())
+
+	// Configure the analysis of container images (default: disabled)
+	if err = loadImageConfig(&c.Images); err != nil {
+		return err
+	}
+
+	// Configure the extraction of archives (default: disabled)
+	if err = loadArchiveConfig(&c.Archives); err != nil {
+		return err
+	}
+
+	// Configure the presigned uploads (default: disabled)
+	if err = loadPresignedUploadConfig(&c.PresignedUploads); err != nil {
+		return err
+	}
+
+	// Configure the callbacks of the uploads (default: disabled)
+	if err = loadCallbackConfig(&c.Callbacks); err != nil {
+		return err
+	}
+
+	// Configure the reputation source looked up before the analyses (default: disabled)
+	if err = loadReputationConfig(&c.Reputation); err != nil {
+		return err
+	}
+	if c.Reputation.Source != "" && c.HashAlgorithm != helper.HashSHA256 {
+		return errors.New("GOYAV_REPUTATION_SOURCE requires GOYAV_HASH_ALGORITHM to be SHA-256")
+	}
+
+	// Configure the verdict cache (default: disabled)
+	if c.VerdictCache.TTL, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_VERDICT_CACHE_TTL", "0s")); err != nil || c.VerdictCache.TTL < 0 {
+		return errors.New("GOYAV_VERDICT_CACHE_TTL must be a positive duration")
+	}
+	if c.VerdictCache.MaxEntries, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_VERDICT_CACHE_SIZE", strconv.Itoa(cache.DefaultMaxEntries))); err != nil || c.VerdictCache.MaxEntries < 1 {
+		return errors.New("GOYAV_VERDICT_CACHE_SIZE must be a strictly positive number")
+	}
+	slog.Info("verdict cache set", "enabled ?", c.VerdictCache.TTL > 0, "ttl", c.VerdictCache.TTL.String(), "max entries", c.VerdictCache.MaxEntries)
+
+	// Configure the scheduled summary reports (default: disabled)
+	if err = loadReportConfig(&c.Reports); err != nil {
+		return err
+	}
+
+	// Configure the alert on the infected rate (default: disabled)
+	if err = loadAlertConfig(&c.Alerts); err != nil {
+		return err
+	}
+
+	// Configure the retention of the files of the clean documents (default: disabled)
+	return loadRetentionConfig(&c.Retention)
+}
+
+func loadReportConfig(c *ReportConfig) error {
+	v := helper.GetEnvWithDefault("GOYAV_REPORT_SCHEDULE", "")
+	if v == "" {
+		slog.Info("summary reports set", "enabled ?", false)
+		return nil
+	}
+	var err error
+	if c.Schedule.Period, err = domain.ParseReportPeriod(v); err != nil {
+		return fmt.Errorf("GOYAV_REPORT_SCHEDULE is not valid: %w", err)
+	}
+	at, err := time.Parse("15:04", helper.GetEnvWithDefault("GOYAV_REPORT_TIME", "06:00"))
+	if err != nil {
+		return errors.New("GOYAV_REPORT_TIME must be a time of day in UTC, as HH:MM")
+	}
+	c.Schedule.At = time.Duration(at.Hour())*time.Hour + time.Duration(at.Minute())*time.Minute
+	if c.Schedule.Weekday, err = parseWeekday(helper.GetEnvWithDefault("GOYAV_REPORT_WEEKDAY", "monday")); err != nil {
+		return fmt.Errorf("GOYAV_REPORT_WEEKDAY is not valid: %w", err)
+	}
+	if c.Top, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_REPORT_TOP", strconv.Itoa(service.DefaultReportTop))); err != nil || c.Top < 1 {
+		return errors.New("GOYAV_REPORT_TOP must be a strictly positive number")
+	}
+	if c.Timeout, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_REPORT_TIMEOUT", report.DefaultTimeout.String())); err != nil || c.Timeout <= 0 {
+		return errors.New("GOYAV_REPORT_TIMEOUT must be a strictly positive duration")
+	}
+	if c.Compression, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_REPORT_COMPRESSION_THRESHOLD", strconv.Itoa(helper.DefaultCompressionThreshold))); err != nil || c.Compression < 0 {
+		return errors.New("GOYAV_REPORT_COMPRESSION_THRESHOLD must be a positive number of bytes")
+	}
+
+	if c.WebhookURL = helper.GetEnvWithDefault("GOYAV_REPORT_WEBHOOK_URL", ""); c.WebhookURL != "" {
+		if u, err := url.Parse(c.WebhookURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return errors.New("GOYAV_REPORT_WEBHOOK_URL must be an http or https URL")
+		}
+	}
+	if c.SMTPAddress = helper.GetEnvWithDefault("GOYAV_REPORT_SMTP_ADDRESS", ""); c.SMTPAddress != "" {
+		if _, _, err = net.SplitHostPort(c.SMTPAddress); err != nil {
+			return errors.New("GOYAV_REPORT_SMTP_ADDRESS must be given as host:port")
+		}
+		c.SMTPUsername = helper.GetEnvWithDefault("GOYAV_REPORT_SMTP_USERNAME", "")
+		c.SMTPPassword = helper.GetEnvWithDefault("GOYAV_REPORT_SMTP_PASSWORD", "")
+		if c.EmailFrom, err = parseEmailAddress(helper.GetEnvWithDefault("GOYAV_REPORT_EMAIL_FROM", "")); err != nil {
+			return fmt.Errorf("GOYAV_REPORT_EMAIL_FROM is not valid: %w", err)
+		}
+		for _, addr := range strings.Split(helper.GetEnvWithDefault("GOYAV_REPORT_EMAIL_TO", ""), ",") {
+			to, err := parseEmailAddress(addr)
+			if err != nil {
+				return fmt.Errorf("GOYAV_REPORT_EMAIL_TO is not valid: %w", err)
+			}
+			c.EmailTo = append(c.EmailTo, to)
+		}
+	}
+	// without a webhook URL nor an SMTP server, the reports are only posted to the webhooks subscribing to them
+	slog.Info("summary reports set", "enabled ?", true, "period", c.Schedule.Period, "time", at.Format("15:04"), "weekday", c.Schedule.Weekday,
+		"top", c.Top, "webhook ?", c.WebhookURL != "", "email recipients", len(c.EmailTo))
+	return nil
+}
+
+func loadAlertConfig(c *AlertConfig) error {
+	var err error
+	if c.InfectedRate, err = strconv.ParseFloat(helper.GetEnvWithDefault("GOYAV_ALERT_INFECTED_RATE", "0"), 64); err != nil || c.InfectedRate < 0 || c.InfectedRate >= 1 {
+		return errors.New("GOYAV_ALERT_INFECTED_RATE must be a ratio from 0 to 1, excluded")
+	}
+	if c.InfectedRate == 0 {
+		slog.Info("infected rate alert set", "enabled ?", false)
+		return nil
+	}
+	if c.Window, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_ALERT_WINDOW", service.DefaultAlertWindow.String())); err != nil || c.Window < time.Minute {
+		return errors.New("GOYAV_ALERT_WINDOW must be a duration of at least a minute")
+	}
+	if c.MinAnalyzed, err = strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_ALERT_MIN_ANALYSES", strconv.Itoa(service.DefaultAlertMinAnalyzed)), 10, 64); err != nil || c.MinAnalyzed < 1 {
+		return errors.New("GOYAV_ALERT_MIN_ANALYSES must be a strictly positive number")
+	}
+	if c.Timeout, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_ALERT_TIMEOUT", alert.DefaultTimeout.String())); err != nil || c.Timeout <= 0 {
+		return errors.New("GOYAV_ALERT_TIMEOUT must be a strictly positive duration")
+	}
+	if c.Compression, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_ALERT_COMPRESSION_THRESHOLD", strconv.Itoa(helper.DefaultCompressionThreshold))); err != nil || c.Compression < 0 {
+		return errors.New("GOYAV_ALERT_COMPRESSION_THRESHOLD must be a positive number of bytes")
+	}
+
+	for _, n := range strings.Split(helper.GetEnvWithDefault("GOYAV_ALERT_NOTIFIERS", "log"), ",") {
+		n = strings.ToLower(strings.TrimSpace(n))
+		var env string
+		var target *string
+		switch n {
+		case "log":
+		case "webhook":
+			env, target = "GOYAV_ALERT_WEBHOOK_URL", &c.WebhookURL
+		case "slack":
+			env, target = "GOYAV_ALERT_SLACK_WEBHOOK_URL", &c.SlackWebhookURL
+		default:
+			return fmt.Errorf("GOYAV_ALERT_NOTIFIERS is not valid: unknown notifier %q, expected log, webhook or slack", n)
+		}
+		if target != nil {
+			*target = helper.GetEnvWithDefault(env, "")
+			if u, err := url.Parse(*target); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("%s must be an http or https URL", env)
+			}
+		}
+		if !slices.Contains(c.Notifiers, n) {
+			c.Notifiers = append(c.Notifiers, n)
+		}
+	}
+	slog.Info("infected rate alert set", "enabled ?", true, "threshold", c.InfectedRate, "window", c.Window.String(),
+		"min analyses", c.MinAnalyzed, "notifiers", c.Notifiers)
+	return nil
+}
+
+// parseWeekday returns the day of the week named s in English, e.g. monday.
+func parseWeekday(s string) (time.Weekday, error) {
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		if strings.EqualFold(strings.TrimSpace(s), d.String()) {
+			return d, nil
+		}
+	}
+	return 0, fmt.Errorf("unknown day of the week %q", s)
+}
+
+// parseEmailAddress returns the bare email address of s, e.g. secops@example.com.
+func parseEmailAddress(s string) (string, error) {
+	addr, err := mail.ParseAddress(strings.TrimSpace(s))
+	if err != nil {
+		return "", fmt.Errorf("invalid email address %q: %v", s, err)
+	}
+	return addr.Address, nil
+}
+
+func loadRetryPolicy(p *service.RetryPolicy) error {
+	var err error
+	d := service.DefaultRetryPolicy
+	if p.MaxAttempts, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_RETRY_MAX_ATTEMPTS", strconv.Itoa(d.MaxAttempts))); err != nil {
+		return errors.New("GOYAV_RETRY_MAX_ATTEMPTS must be a number")
+	}
+	if p.BaseDelay, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_RETRY_BASE_DELAY", d.BaseDelay.String())); err != nil {
+		return errors.New("GOYAV_RETRY_BASE_DELAY must be a valid duration")
+	}
+	if p.Factor, err = strconv.ParseFloat(helper.GetEnvWithDefault("GOYAV_RETRY_FACTOR", strconv.FormatFloat(d.Factor, 'g', -1, 64)), 64); err != nil {
+		return errors.New("GOYAV_RETRY_FACTOR must be a number")
+	}
+	if p.Jitter, err = strconv.ParseFloat(helper.GetEnvWithDefault("GOYAV_RETRY_JITTER", strconv.FormatFloat(d.Jitter, 'g', -1, 64)), 64); err != nil {
+		return errors.New("GOYAV_RETRY_JITTER must be a number")
+	}
+	if p.MaxDuration, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_RETRY_MAX_DURATION", d.MaxDuration.String())); err != nil {
+		return errors.New("GOYAV_RETRY_MAX_DURATION must be a valid duration")
+	}
+	if err = p.Validate(); err != nil {
+		return fmt.Errorf("GOYAV_RETRY_* are not valid: %w", err)
+	}
+	slog.Info("analysis retry policy set", "max attempts", p.MaxAttempts, "base delay", p.BaseDelay.String(), "factor", p.Factor, "jitter", p.Jitter, "max duration", p.MaxDuration.String())
+	return nil
+}
+
+func loadSharedConcurrency(c *SharedConcurrencyConfig) error {
+	var err error
+	if c.Capacity, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_SHARED_SEMAPHORE_CAPACITY", "0")); err != nil || c.Capacity < 0 {
+		return errors.New("GOYAV_SHARED_SEMAPHORE_CAPACITY must be a positive integer")
+	}
+	c.Key = helper.GetEnvWithDefault("GOYAV_SHARED_SEMAPHORE_KEY", limiter.DefaultKey)
+	if c.Lease, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_SHARED_SEMAPHORE_LEASE", limiter.DefaultLease.String())); err != nil || c.Lease < time.Second {
+		return errors.New("GOYAV_SHARED_SEMAPHORE_LEASE must be a duration of at least 1s")
+	}
+	c.RedisURL = helper.GetEnvWithDefault("GOYAV_REDIS_URL", "")
+	if c.Capacity > 0 {
+		if _, err = redis.ParseURL(c.RedisURL); err != nil {
+			return fmt.Errorf("GOYAV_REDIS_URL is not valid, it is required by GOYAV_SHARED_SEMAPHORE_CAPACITY: %w", err)
+		}
+	}
+	slog.Info("shared semaphore set", "enabled ?", c.Capacity > 0, "capacity", c.Capacity, "key", c.Key, "lease", c.Lease.String())
+	return nil
+}
+
+func loadImageConfig(c *ImageConfig) error {
+	var err error
+	if c.Enabled, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_IMAGE_ANALYSIS", "false")); err != nil {
+		return errors.New("GOYAV_IMAGE_ANALYSIS must be true or false")
+	}
+	if c.CheckLinks, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_IMAGE_CHECK_LINKS", "true")); err != nil {
+		return errors.New("GOYAV_IMAGE_CHECK_LINKS must be true or false")
+	}
+
+	// Parse the unpacking limits, 0 means unlimited
+	limits := []struct {
+		env   string
+		value *int64
+		def   string
+	}{
+		{"GOYAV_IMAGE_MAX_FILE_SIZE", &c.Limits.MaxFileSize, "268435456"},
+		{"GOYAV_IMAGE_MAX_UNPACKED_SIZE", &c.Limits.MaxUnpackedSize, "4294967296"},
+	}
+	for _, l := range limits {
+		if *l.value, err = strconv.ParseInt(helper.GetEnvWithDefault(l.env, l.def), 10, 64); err != nil || *l.value < 0 {
+			return fmt.Errorf("%s must be a positive number of bytes", l.env)
+		}
+	}
+	if c.Limits.MaxLayers, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_IMAGE_MAX_LAYERS", "128")); err != nil || c.Limits.MaxLayers < 0 {
+		return errors.New("GOYAV_IMAGE_MAX_LAYERS must be a positive number")
+	}
+	if c.Limits.MaxFiles, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_IMAGE_MAX_FILES", "100000")); err != nil || c.Limits.MaxFiles < 0 {
+		return errors.New("GOYAV_IMAGE_MAX_FILES must be a positive number")
+	}
+	slog.Info("image analysis set", "enabled ?", c.Enabled, "check links ?", c.CheckLinks, "limits", fmt.Sprintf("%+v", c.Limits))
+	return nil
+}
+
+func loadArchiveConfig(c *ArchiveConfig) error {
+	var err error
+	if c.Enabled, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_ARCHIVE_ANALYSIS", "false")); err != nil {
+		return errors.New("GOYAV_ARCHIVE_ANALYSIS must be true or false")
+	}
+
+	// Parse the extraction limits, 0 means unlimited
+	limits := []struct {
+		env   string
+		value *int64
+		def   string
+	}{
+		{"GOYAV_ARCHIVE_MAX_FILE_SIZE", &c.Limits.MaxFileSize, "268435456"},
+		{"GOYAV_ARCHIVE_MAX_UNPACKED_SIZE", &c.Limits.MaxUnpackedSize, "1073741824"},
+	}
+	for _, l := range limits {
+		if *l.value, err = strconv.ParseInt(helper.GetEnvWithDefault(l.env, l.def), 10, 64); err != nil || *l.value < 0 {
+			return fmt.Errorf("%s must be a positive number of bytes", l.env)
+		}
+	}
+	if c.Limits.MaxDepth, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_ARCHIVE_MAX_DEPTH", "3")); err != nil || c.Limits.MaxDepth < 0 {
+		return errors.New("GOYAV_ARCHIVE_MAX_DEPTH must be a positive number")
+	}
+	if c.Limits.MaxFiles, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_ARCHIVE_MAX_FILES", "10000")); err != nil || c.Limits.MaxFiles < 0 {
+		return errors.New("GOYAV_ARCHIVE_MAX_FILES must be a positive number")
+	}
+	slog.Info("archive analysis set", "enabled ?", c.Enabled, "limits", fmt.Sprintf("%+v", c.Limits))
+	return nil
+}
+
+func loadPresignedUploadConfig(c *PresignedUploadConfig) error {
+	var err error
+	if c.Enabled, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_PRESIGNED_UPLOADS", "false")); err != nil {
+		return errors.New("GOYAV_PRESIGNED_UPLOADS must be true or false")
+	}
+	if c.Expiry, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_PRESIGNED_UPLOAD_EXPIRY", "15m")); err != nil || c.Expiry <= 0 {
+		return errors.New("GOYAV_PRESIGNED_UPLOAD_EXPIRY must be a strictly positive duration")
+	}
+	if c.MaxSize, err = strconv.ParseInt(helper.GetEnvWithDefault("GOYAV_PRESIGNED_UPLOAD_MAX_SIZE", "5368709120"), 10, 64); err != nil || c.MaxSize < 0 {
+		return errors.New("GOYAV_PRESIGNED_UPLOAD_MAX_SIZE must be a positive number of bytes")
+	}
+	slog.Info("presigned uploads set", "enabled ?", c.Enabled, "expiry", c.Expiry.String(), "max size (bytes)", c.MaxSize)
+	return nil
+}
+
+func loadCallbackConfig(c *CallbackConfig) error {
+	var err error
+	if c.Enabled, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_CALLBACKS", "false")); err != nil {
+		return errors.New("GOYAV_CALLBACKS must be true or false")
+	}
+	if c.Timeout, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CALLBACK_TIMEOUT", callback.DefaultTimeout.String())); err != nil || c.Timeout <= 0 {
+		return errors.New("GOYAV_CALLBACK_TIMEOUT must be a strictly positive duration")
+	}
+	if c.Attempts, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_CALLBACK_ATTEMPTS", strconv.Itoa(callback.DefaultAttempts))); err != nil || c.Attempts < 1 {
+		return errors.New("GOYAV_CALLBACK_ATTEMPTS must be a strictly positive number")
+	}
+	if c.AllowPrivate, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_CALLBACK_ALLOW_PRIVATE_NETWORKS", "false")); err != nil {
+		return errors.New("GOYAV_CALLBACK_ALLOW_PRIVATE_NETWORKS must be true or false")
+	}
+	if c.Compression, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_CALLBACK_COMPRESSION_THRESHOLD", strconv.Itoa(helper.DefaultCompressionThreshold))); err != nil || c.Compression < 0 {
+		return errors.New("GOYAV_CALLBACK_COMPRESSION_THRESHOLD must be a positive number of bytes")
+	}
+	if c.Outbox, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_CALLBACK_OUTBOX", "false")); err != nil {
+		return errors.New("GOYAV_CALLBACK_OUTBOX must be true or false")
+	}
+	if c.OutboxInterval, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CALLBACK_OUTBOX_INTERVAL", "5s")); err != nil || c.OutboxInterval <= 0 {
+		return errors.New("GOYAV_CALLBACK_OUTBOX_INTERVAL must be a strictly positive duration")
+	}
+	if c.OutboxLease, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CALLBACK_OUTBOX_LEASE", "2m")); err != nil || c.OutboxLease <= 0 {
+		return errors.New("GOYAV_CALLBACK_OUTBOX_LEASE must be a strictly positive duration")
+	}
+	if c.OutboxClaims, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_CALLBACK_OUTBOX_CLAIMS", "10")); err != nil || c.OutboxClaims < 1 {
+		return errors.New("GOYAV_CALLBACK_OUTBOX_CLAIMS must be a strictly positive number")
+	}
+	slog.Info("callbacks set", "enabled ?", c.Enabled, "timeout", c.Timeout.String(), "attempts", c.Attempts, "private networks allowed ?", c.AllowPrivate,
+		"compression threshold", c.Compression,
+		"outbox ?", c.Outbox, "outbox interval", c.OutboxInterval.String(), "outbox lease", c.OutboxLease.String(), "outbox claims", c.OutboxClaims)
+	return nil
+}
+
+func loadReputationConfig(c *ReputationConfig) error {
+	var err error
+	switch c.Source = strings.ToLower(helper.GetEnvWithDefault("GOYAV_REPUTATION_SOURCE", "")); c.Source {
+	case "", "malwarebazaar", "virustotal":
+	default:
+		return errors.New("GOYAV_REPUTATION_SOURCE must be malwarebazaar or virustotal")
+	}
+	c.URL = helper.GetEnvWithDefault("GOYAV_REPUTATION_URL", "")
+	c.APIKey = helper.GetEnvWithDefault("GOYAV_REPUTATION_API_KEY", "")
+	if c.Source != "" && c.APIKey == "" {
+		return errors.New("GOYAV_REPUTATION_API_KEY is required by GOYAV_REPUTATION_SOURCE")
+	}
+	if c.Timeout, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_REPUTATION_TIMEOUT", reputation.DefaultTimeout.String())); err != nil || c.Timeout <= 0 {
+		return errors.New("GOYAV_REPUTATION_TIMEOUT must be a strictly positive duration")
+	}
+	if c.MinMalicious, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_REPUTATION_MIN_MALICIOUS", strconv.Itoa(reputation.DefaultMinMalicious))); err != nil || c.MinMalicious < 1 {
+		return errors.New("GOYAV_REPUTATION_MIN_MALICIOUS must be a strictly positive number")
+	}
+	if c.MinClean, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_REPUTATION_MIN_CLEAN", "0")); err != nil || c.MinClean < 0 {
+		return errors.New("GOYAV_REPUTATION_MIN_CLEAN must be a positive number")
+	}
+	slog.Info("reputation source set", "source", c.Source, "timeout", c.Timeout.String(), "min malicious", c.MinMalicious, "min clean", c.MinClean)
+	return nil
+}
+
+func loadRetentionConfig(c *RetentionConfig) error {
+	var err error
+	if c.RetainClean, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_RETAIN_CLEAN_FILES", "false")); err != nil {
+		return errors.New("GOYAV_RETAIN_CLEAN_FILES must be true or false")
+	}
+	if c.DownloadExpiry, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_PRESIGNED_DOWNLOAD_EXPIRY", "5m")); err != nil || c.DownloadExpiry <= 0 {
+		return errors.New("GOYAV_PRESIGNED_DOWNLOAD_EXPIRY must be a strictly positive duration")
+	}
+	slog.Info("clean files retention set", "enabled ?", c.RetainClean, "download expiry", c.DownloadExpiry.String())
+	if c.QuarantineInfected, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_QUARANTINE_INFECTED_FILES", "false")); err != nil {
+		return errors.New("GOYAV_QUARANTINE_INFECTED_FILES must be true or false")
+	}
+	slog.Info("infected files quarantine set", "enabled ?", c.QuarantineInfected)
+	if c.TagVerdicts, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_S3_VERDICT_TAGS", "false")); err != nil {
+		return errors.New("GOYAV_S3_VERDICT_TAGS must be true or false")
+	}
+	slog.Info("verdict tags set", "enabled ?", c.TagVerdicts)
+	return nil
+}
+
+func loadS3Config(cfg *Config) error {
+	var err error
+	c := &cfg.S3
+
+	// Retrieve the s3 endpoint endpoint : host and port without protocol
+	if c.Endpoint, err = helper.GetEnvWithError("GOYAV_S3_ENDPOINT_URL"); err != nil {
+		return err
+	}
+	slog.Info("configuring s3 bucket", "endpoint URL", c.Endpoint)
+
+	// Retrieve s3 access key ID with error check
+	if c.AccessKeyID, err = helper.GetEnvWithError("GOYAV_S3_ACCESS_KEY"); err != nil {
+		return err
+	}
+	slog.Info("configuring s3 bucket", "access key ID", c.AccessKeyID)
+
+	// Retrieve s3 secret key with error check
+	if c.SecretKey, err = helper.GetEnvWithError("GOYAV_S3_SECRET_KEY"); err != nil {
+		return err
+	}
+	slog.Debug("configuring s3 bucket", "secret key", c.SecretKey)
+
+	// Retrieve s3 bucket name configuration
+	c.Bucket = helper.GetEnvWithDefault("GOYAV_S3_BUCKET_NAME", "goyav")
+	slog.Info("configuring s3 bucket", "bucket name", c.Bucket)
+
+	// Parse and validate s3 SSL usage
+	if c.UseSSL, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_S3_USE_SSL", "false")); err != nil {
+		return errors.New("GOYAV_S3_USE_SSL must be true or false")
+	}
+	slog.Info("configuring s3 bucket", "use ssl ?", c.UseSSL)
+
+	// Configure the lifecycle of the bucket, expiring the objects as the analysis results (default: disabled)
+	lifecycle, err := strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_S3_LIFECYCLE_EXPIRY", "false"))
+	if err != nil {
+		return errors.New("GOYAV_S3_LIFECYCLE_EXPIRY must be true or false")
+	}
+	if lifecycle {
+		// the objects must outlive the longest retention, e.g. of the infected documents kept as evidence
+		expiry := max(cfg.Service.ResultTTL, 0)
+		for _, ttl := range cfg.Service.StatusRetentions {
+			if ttl <= 0 {
+				expiry = 0
+				break
+			}
+			expiry = max(expiry, ttl)
+		}
+		if expiry == 0 {
+			slog.Warn("the lifecycle of the bucket is not set without a result time to live, or with a status retained forever")
+		}
+		c.LifecycleExpiry = expiry
+	}
+	slog.Info("configuring s3 bucket", "lifecycle expiry", c.LifecycleExpiry.String())
+
+	// Configure the protection of the quarantined files (default: none)
+	if c.QuarantineRetention, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_S3_QUARANTINE_RETENTION", "0s")); err != nil || c.QuarantineRetention < 0 {
+		return errors.New("GOYAV_S3_QUARANTINE_RETENTION must be a positive duration")
+	}
+	if c.QuarantineLegalHold, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_S3_QUARANTINE_LEGAL_HOLD", "false")); err != nil {
+		return errors.New("GOYAV_S3_QUARANTINE_LEGAL_HOLD must be true or false")
+	}
+	slog.Info("configuring s3 bucket", "quarantine retention", c.QuarantineRetention.String(), "quarantine legal hold ?", c.QuarantineLegalHold)
+
+	// Configure the server-side encryption of the files (default: none)
+	c.SSEMode = strings.ToUpper(helper.GetEnvWithDefault("GOYAV_S3_SSE", binaryrepo.SSENone))
+	c.SSEKMSKeyID = helper.GetEnvWithDefault("GOYAV_S3_SSE_KMS_KEY_ID", "")
+	switch {
+	case c.SSEMode != binaryrepo.SSENone && c.SSEMode != binaryrepo.SSES3 && c.SSEMode != binaryrepo.SSEKMS:
+		return fmt.Errorf("GOYAV_S3_SSE must be %s or %s", binaryrepo.SSES3, binaryrepo.SSEKMS)
+	case c.SSEMode == binaryrepo.SSEKMS && c.SSEKMSKeyID == "":
+		return fmt.Errorf("GOYAV_S3_SSE_KMS_KEY_ID must be set in %s mode", binaryrepo.SSEKMS)
+	}
+	slog.Info("configuring s3 bucket", "server-side encryption", c.SSEMode, "kms key ID", c.SSEKMSKeyID)
+
+	// Configure the sharding of the object keys (default: 0, not sharded)
+	c.ShardLevels, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_S3_SHARD_LEVELS", "0"))
+	if err != nil || c.ShardLevels < 0 || c.ShardLevels > binaryrepo.MaxShardLevels {
+		return fmt.Errorf("GOYAV_S3_SHARD_LEVELS must be between 0 and %d", binaryrepo.MaxShardLevels)
+	}
+	slog.Info("configuring s3 bucket", "shard levels", c.ShardLevels)
+
+	// Configure the multipart uploads (default: from 64 MiB, in parts of 16 MiB, 4 at once)
+	c.MultipartThreshold, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_S3_MULTIPART_THRESHOLD", strconv.FormatUint(DefaultMultipartThreshold, 10)), 10, 64)
+	if err != nil {
+		return errors.New("GOYAV_S3_MULTIPART_THRESHOLD must be a positive number of bytes")
+	}
+	c.PartSize, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_S3_PART_SIZE", strconv.FormatUint(DefaultPartSize, 10)), 10, 64)
+	if err != nil || c.PartSize < binaryrepo.MinPartSize || c.PartSize > binaryrepo.MaxPartSize {
+		return fmt.Errorf("GOYAV_S3_PART_SIZE must be between %d and %d bytes", binaryrepo.MinPartSize, binaryrepo.MaxPartSize)
+	}
+	parallelism, err := strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_S3_PART_PARALLELISM", strconv.FormatUint(uint64(DefaultPartParallelism), 10)), 10, 32)
+	if err != nil || parallelism == 0 {
+		return errors.New("GOYAV_S3_PART_PARALLELISM must be a strictly positive number")
+	}
+	c.PartParallelism = uint(parallelism)
+	slog.Info("configuring s3 bucket", "multipart threshold", c.MultipartThreshold, "part size", c.PartSize, "part parallelism", c.PartParallelism)
+
+	// Duration from which the operations on the files are logged as slow (default: none)
+	if c.SlowThreshold, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_S3_SLOW_THRESHOLD", "0s")); err != nil || c.SlowThreshold < 0 {
+		return errors.New("GOYAV_S3_SLOW_THRESHOLD must be a positive duration or zero")
+	}
+	if c.SlowThreshold > 0 {
+		slog.Info("configuring s3 bucket", "slow threshold", c.SlowThreshold.String())
+	}
+	return nil
+}
+
+func loadPostgresConfig(cfg *Config) error {
+	var err error
+	c := &cfg.Postgres
+
+	// Retrieve PostgreSQL host configuration
+	c.Host = helper.GetEnvWithDefault("GOYAV_POSTGRES_HOST", "127.0.0.1")
+	slog.Info("configuring postgres", "host", c.Host)
+
+	// Parse and validate PostgreSQL port
+	if c.Port, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_POSTGRES_PORT", "5432"), 10, 64); err != nil {
+		return errors.New("GOYAV_POSTGRES_PORT must be a valid port number")
+	}
+	slog.Info("configuring postgres", "port", c.Port)
+
+	// Retrieve PostgreSQL user
+	if c.User, err = helper.GetEnvWithError("GOYAV_POSTGRES_USER"); err != nil {
+		return fmt.Errorf("GOYAV_POSTGRES_USER must be a valid user name: %w", err)
+	}
+	slog.Info("configuring postgres", "user", c.User)
+
+	// Retrieve PostgreSQL user passwd
+	if c.Password, err = helper.GetEnvWithError("GOYAV_POSTGRES_USER_PASSWORD"); err != nil {
+		return fmt.Errorf("GOYAV_POSTGRES_USER_PASSWORD is not valid: %w", err)
+	}
+	slog.Debug("configuring postgres", "password", c.Password)
+
+	// Retrieve PostgreSQL database name
+	if c.Database, err = helper.GetEnvWithError("GOYAV_POSTGRES_DB"); err != nil {
+		return fmt.Errorf("GOYAV_POSTGRES_DB is not valid : %w", err)
+	}
+	slog.Info("configuring postgres", "database name", c.Database)
+
+	// Retrieve PostgreSQL schema
+	if c.Schema, err = helper.GetEnvWithError("GOYAV_POSTGRES_SCHEMA"); err != nil {
+		return fmt.Errorf("GOYAV_POSTGRES_SCHEMA is not valid: %w", err)
+	}
+	slog.Info("configuring postgres", "postgres schema name", c.Schema)
+
+	// Retrieve PostgreSQL SSL usage
+	c.SSLMode = helper.GetEnvWithDefault("GOYAV_POSTGRES_SSL_MODE", "require")
+	slog.Info("configuring postgres", "postgres ssl mode", c.SSLMode)
+
+	// Configure the connection pool (default: 20 connections, 10 idle ones, renewed every 30 minutes or after
+	// 5 idle minutes)
+	if c.MaxOpenConns, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_POSTGRES_MAX_OPEN_CONNS", "20")); err != nil || c.MaxOpenConns < 0 {
+		return errors.New("GOYAV_POSTGRES_MAX_OPEN_CONNS must be a positive integer")
+	}
+	if c.MaxIdleConns, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_POSTGRES_MAX_IDLE_CONNS", "10")); err != nil || c.MaxIdleConns < 0 {
+		return errors.New("GOYAV_POSTGRES_MAX_IDLE_CONNS must be a positive integer")
+	}
+	if c.MaxOpenConns > 0 && c.MaxIdleConns > c.MaxOpenConns {
+		return errors.New("GOYAV_POSTGRES_MAX_IDLE_CONNS must not exceed GOYAV_POSTGRES_MAX_OPEN_CONNS")
+	}
+	if c.ConnMaxLifetime, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_POSTGRES_CONN_MAX_LIFETIME", "30m")); err != nil || c.ConnMaxLifetime < 0 {
+		return errors.New("GOYAV_POSTGRES_CONN_MAX_LIFETIME must be a positive duration")
+	}
+	if c.ConnMaxIdleTime, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_POSTGRES_CONN_MAX_IDLE_TIME", "5m")); err != nil || c.ConnMaxIdleTime < 0 {
+		return errors.New("GOYAV_POSTGRES_CONN_MAX_IDLE_TIME must be a positive duration")
+	}
+	slog.Info("configuring postgres", "max open connections", c.MaxOpenConns, "max idle connections", c.MaxIdleConns,
+		"connection max lifetime", c.ConnMaxLifetime.String(), "connection max idle time", c.ConnMaxIdleTime.String())
+
+	// Apply the migrations of the database at startup (default: true)
+	if c.AutoMigrate, err = strconv.ParseBool(helper.GetEnvWithDefault("GOYAV_POSTGRES_AUTO_MIGRATE", "true")); err != nil {
+		return errors.New("GOYAV_POSTGRES_AUTO_MIGRATE must be true or false")
+	}
+	slog.Info("configuring postgres", "auto migrate ?", c.AutoMigrate)
+
+	// Partitioning of the documents table by creation date: daily, monthly or none (default: none)
+	if c.Partitions, err = docrepo.ParsePartitionInterval(strings.ToLower(helper.GetEnvWithDefault("GOYAV_POSTGRES_PARTITIONS", ""))); err != nil {
+		return errors.New("GOYAV_POSTGRES_PARTITIONS must be daily, monthly or empty")
+	}
+	if c.PartitionsAhead, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_POSTGRES_PARTITIONS_AHEAD", "3")); err != nil || c.PartitionsAhead < 0 {
+		return errors.New("GOYAV_POSTGRES_PARTITIONS_AHEAD must be a positive integer or zero")
+	}
+	if c.Partitions != docrepo.PartitionNone {
+		slog.Info("configuring postgres", "partitions", c.Partitions, "partitions ahead", c.PartitionsAhead)
+	}
+
+	// Batches of the deletions of the purges (default: 5000 documents, 100ms apart)
+	if c.PurgeBatchSize, err = strconv.Atoi(helper.GetEnvWithDefault("GOYAV_POSTGRES_PURGE_BATCH_SIZE", strconv.Itoa(docrepo.DefaultPurgeBatchSize))); err != nil || c.PurgeBatchSize < 0 {
+		return errors.New("GOYAV_POSTGRES_PURGE_BATCH_SIZE must be a positive integer or zero")
+	}
+	if c.PurgeBatchPause, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_POSTGRES_PURGE_BATCH_PAUSE", docrepo.DefaultPurgeBatchPause.String())); err != nil || c.PurgeBatchPause < 0 {
+		return errors.New("GOYAV_POSTGRES_PURGE_BATCH_PAUSE must be a positive duration or zero")
+	}
+	slog.Info("configuring postgres", "purge batch size", c.PurgeBatchSize, "purge batch pause", c.PurgeBatchPause.String())
+
+	// Duration from which the queries on the documents are logged as slow (default: none)
+	if c.SlowThreshold, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_POSTGRES_SLOW_THRESHOLD", "0s")); err != nil || c.SlowThreshold < 0 {
+		return errors.New("GOYAV_POSTGRES_SLOW_THRESHOLD must be a positive duration or zero")
+	}
+	if c.SlowThreshold > 0 {
+		slog.Info("configuring postgres", "slow threshold", c.SlowThreshold.String())
+	}
+	return nil
+}
+
+// LoadPostgresConfig loads the configuration of the PostgreSQL database only, see LoadConfig.
+func LoadPostgresConfig() (*PostgresConfig, error) {
+	cfg := &Config{}
+	if err := loadPostgresConfig(cfg); err != nil {
+		return nil, err
+	}
+	return &cfg.Postgres, nil
+}
+
+func loadClamAVConfig(cfg *Config) error {
+	var err error
+	c := &cfg.ClamAV
+
+	// Retrieve ClamAV host configuration
+	c.Host = helper.GetEnvWithDefault("GOYAV_CLAMAV_HOST", "127.0.0.1")
+	slog.Info("configuring clamav", "host", c.Host)
+
+	// Parse and validate ClamAV port
+	if c.Port, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_PORT", "3310"), 10, 64); err != nil {
+		return errors.New("GOYAV_CLAMAV_PORT must be a valid port number")
+	}
+	slog.Info("configuring clamav", "port", c.Port)
+
+	// Parse and validate ClamAV timeout
+	if c.Timeout, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_TIMEOUT", "30"), 10, 64); err != nil {
+		return errors.New("GOYAV_CLAMAV_TIMEOUT must be a strictly positive number")
+	}
+	slog.Info("configuring clamav", "timeout", c.Timeout)
+
+	// Parse and validate the size of the chunks streamed to clamd
+	c.ChunkSize, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_CHUNK_SIZE", strconv.Itoa(antivirus.DefaultChunkSize)), 10, 64)
+	if err != nil || c.ChunkSize == 0 || c.ChunkSize > math.MaxUint32 {
+		return errors.New("GOYAV_CLAMAV_CHUNK_SIZE must be a strictly positive number of bytes")
+	}
+	slog.Info("configuring clamav", "chunk size", c.ChunkSize)
+
+	// Parse and validate the scaling of the timeout with the size of the data (default: none, up to 10m)
+	if c.TimeoutPerMB, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CLAMAV_TIMEOUT_PER_MB", "0s")); err != nil || c.TimeoutPerMB < 0 {
+		return errors.New("GOYAV_CLAMAV_TIMEOUT_PER_MB must be a positive duration")
+	}
+	timeout := time.Duration(c.Timeout) * time.Second
+	if c.MaxTimeout, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CLAMAV_MAX_TIMEOUT", max(10*time.Minute, timeout).String())); err != nil || c.MaxTimeout < timeout {
+		return errors.New("GOYAV_CLAMAV_MAX_TIMEOUT must be a duration no shorter than GOYAV_CLAMAV_TIMEOUT")
+	}
+	slog.Info("configuring clamav", "timeout per MiB", c.TimeoutPerMB.String(), "max timeout", c.MaxTimeout.String())
+
+	// Duration from which the analyses are logged as slow (default: none)
+	if c.SlowThreshold, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_CLAMAV_SLOW_THRESHOLD", "0s")); err != nil || c.SlowThreshold < 0 {
+		return errors.New("GOYAV_CLAMAV_SLOW_THRESHOLD must be a positive duration or zero")
+	}
+	if c.SlowThreshold > 0 {
+		slog.Info("configuring clamav", "slow threshold", c.SlowThreshold.String())
+	}
+
+	// Retrieve the fallback clamd, if any
+	c.FallbackHost = helper.GetEnvWithDefault("GOYAV_CLAMAV_FALLBACK_HOST", "")
+	if c.FallbackPort, err = strconv.ParseUint(helper.GetEnvWithDefault("GOYAV_CLAMAV_FALLBACK_PORT", "3310"), 10, 64); err != nil {
+		return errors.New("GOYAV_CLAMAV_FALLBACK_PORT must be a valid port number")
+	}
+	slog.Info("configuring clamav", "fallback host", c.FallbackHost, "fallback port", c.FallbackPort)
+
+	// Retrieve the other engines the uploads can select, if any
+	if v := helper.GetEnvWithDefault("GOYAV_CLAMAV_ENGINES", ""); v != "" {
+		if c.Engines, err = parseEngines(v); err != nil {
+			return fmt.Errorf("GOYAV_CLAMAV_ENGINES: %w", err)
+		}
+	}
+	slog.Info("configuring clamav", "engines", len(c.Engines)+1)
+	return nil
+}
+
+func loadLambdaConfig(cfg *Config) error {
+	var err error
+	c := &cfg.Lambda
+
+	// Retrieve the S3 service of the notified objects, its credentials are those of the Lambda function
+	c.S3Endpoint = helper.GetEnvWithDefault("GOYAV_LAMBDA_S3_ENDPOINT", "s3.amazonaws.com")
+	c.Region = helper.GetEnvWithDefault("AWS_REGION", "")
+
+	// Retrieve the tenant owning the documents of the objects (default: the default tenant)
+	c.Tenant = helper.GetEnvWithDefault("GOYAV_LAMBDA_TENANT", "")
+	if c.Tenant != "" && !helper.IsValidTenant(c.Tenant) {
+		return errors.New("GOYAV_LAMBDA_TENANT must be a valid tenant name")
+	}
+
+	// Parse the polling interval of the verdicts (default: 1 second)
+	if c.PollInterval, err = time.ParseDuration(helper.GetEnvWithDefault("GOYAV_LAMBDA_POLL_INTERVAL", "1s")); err != nil || c.PollInterval <= 0 {
+		return errors.New("GOYAV_LAMBDA_POLL_INTERVAL must be a strictly positive duration")
+	}
+	slog.Debug("configuring lambda mode", "s3 endpoint", c.S3Endpoint, "region", c.Region, "tenant", c.Tenant, "poll interval", c.PollInterval.String())
+	return nil
+}
+
+// parseListen parses a listen address, "unix:///path/to/socket" or "tcp://host:port", into its network and address.
+// The empty address is host:port over TCP.
+func parseListen(v, host string, port int64) (network, address string, err error) {
+	if v == "" {
+		return "tcp", net.JoinHostPort(host, strconv.FormatInt(port, 10)), nil
+	}
+	network, address, found := strings.Cut(v, "://")
+	switch {
+	case !found || address == "":
+		return "", "", fmt.Errorf(`invalid listen address %q, expected e.g. "unix:///var/run/goyav.sock" or "tcp://localhost:80"`, v)
+	case network == "unix":
+		if !filepath.IsAbs(address) {
+			return "", "", fmt.Errorf("the path of the Unix socket %q must be absolute", address)
+		}
+	case network == "tcp":
+		if _, _, err = net.SplitHostPort(address); err != nil {
+			return "", "", fmt.Errorf("invalid TCP address %q: %v", address, err)
+		}
+	default:
+		return "", "", fmt.Errorf("unsupported network %q, expected unix or tcp", network)
+	}
+	return network, address, nil
+}
+
+// parseMediaTypes parses a comma-separated list of media types or "type/*" patterns, e.g. "application/pdf,image/*".
+func parseMediaTypes(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	var patterns []string
+	for _, pattern := range strings.Split(v, ",") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		typ, subtype, found := strings.Cut(pattern, "/")
+		if !found || typ == "" || typ == "*" || subtype == "" || strings.ContainsAny(subtype, "/;") {
+			return nil, fmt.Errorf(`invalid media type %q, expected e.g. "application/pdf" or "image/*"`, pattern)
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, nil
+}
+
+// parseExtensions parses a comma-separated list of file extensions, with or without their leading dot, e.g. "pdf,.tar.gz".
+func parseExtensions(v string) ([]string, error) {
+	if strings.TrimSpace(v) == "" {
+		return nil, nil
+	}
+	var extensions []string
+	for _, ext := range strings.Split(v, ",") {
+		ext = strings.TrimPrefix(strings.ToLower(strings.TrimSpace(ext)), ".")
+		if ext == "" || strings.ContainsAny(ext, `/\*`) {
+			return nil, fmt.Errorf(`invalid file extension %q, expected e.g. "pdf" or ".tar.gz"`, ext)
+		}
+		extensions = append(extensions, "."+ext)
+	}
+	return extensions, nil
+}
+
+// parseNames parses a comma-separated list of names, e.g. "scanner.internal,spiffe://prod/uploader".
+func parseNames(v string) []string {
+	var names []string
+	for _, name := range strings.Split(v, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// parseEngines parses the value of GOYAV_CLAMAV_ENGINES, e.g. "strict=clamd-strict:3310,yara=clamd-yara:3310".
+func parseEngines(v string) (map[string]string, error) {
+	engines := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		name, address, found := strings.Cut(strings.TrimSpace(pair), "=")
+		if !found {
+			return nil, errors.New(`expected a comma-separated list of "name=host:port" pairs`)
+		}
+		if !domain.ValidEngineName(name) || name == domain.EngineClamAV {
+			return nil, fmt.Errorf("invalid engine name %q", name)
+		}
+		host, port, err := net.SplitHostPort(address)
+		if err == nil {
+			_, err = strconv.ParseUint(port, 10, 16)
+		}
+		if err != nil || host == "" {
+			return nil, fmt.Errorf("the address of engine %q must be a host:port address", name)
+		}
+		if _, exists := engines[name]; exists {
+			return nil, fmt.Errorf("duplicated engine %q", name)
+		}
+		engines[name] = address
+	}
+	return engines, nil
+}
+
+// parseAPIKeys parses a comma-separated list of "key:tenant" pairs.
+func parseAPIKeys(v string) (map[string]string, error) {
+	keys := make(map[string]string)
+	for _, pair := range strings.Split(v, ",") {
+		key, tenant, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found || key == "" {
+			return nil, errors.New(`expected a comma-separated list of "key:tenant" pairs`)
+		}
+		if !helper.IsValidTenant(tenant) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant)
+		}
+		if _, exists := keys[key]; exists {
+			return nil, fmt.Errorf("duplicated API key for tenant %q", tenant)
+		}
+		keys[key] = tenant
+	}
+	return keys, nil
+}
+
+// parseSigningClients parses a comma-separated list of "client:tenant:secret" triples, e.g.
+// "mail-gateway:finance:<secret of at least 32 bytes>".
+func parseSigningClients(v string) (map[string]web.SigningClient, error) {
+	clients := make(map[string]web.SigningClient)
+	for _, triple := range strings.Split(v, ",") {
+		ID, rest, found := strings.Cut(strings.TrimSpace(triple), ":")
+		tenant, secret, found2 := strings.Cut(rest, ":")
+		if !found || !found2 || ID == "" {
+			return nil, errors.New(`expected a comma-separated list of "client:tenant:secret" triples`)
+		}
+		if !helper.IsValidTenant(tenant) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant)
+		}
+		if len(secret) < web.SigningSecretMinLength {
+			return nil, fmt.Errorf("the secret of client %q must be at least %d bytes long", ID, web.SigningSecretMinLength)
+		}
+		if _, exists := clients[ID]; exists {
+			return nil, fmt.Errorf("duplicated client %q", ID)
+		}
+		clients[ID] = web.SigningClient{Tenant: tenant, Secret: []byte(secret)}
+	}
+	return clients, nil
+}
+
+// parseTenantSizes parses a comma-separated list of "tenant:bytes" pairs, e.g. "premium:524288000,trial:1048576".
+func parseTenantSizes(v string) (map[string]int64, error) {
+	sizes := make(map[string]int64)
+	for _, pair := range strings.Split(v, ",") {
+		tenant, size, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, errors.New(`expected a comma-separated list of "tenant:bytes" pairs`)
+		}
+		if !helper.IsValidTenant(tenant) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant)
+		}
+		n, err := strconv.ParseInt(size, 10, 64)
+		if err != nil || n <= 0 {
+			return nil, fmt.Errorf("the size of tenant %q must be a strictly positive number of bytes", tenant)
+		}
+		if _, exists := sizes[tenant]; exists {
+			return nil, fmt.Errorf("duplicated size for tenant %q", tenant)
+		}
+		sizes[tenant] = n
+	}
+	return sizes, nil
+}
+
+// parseTenantDedupePolicies parses the value of GOYAV_TENANT_DEDUPE_POLICIES, e.g. "finance:rescan,hr:new-record".
+func parseTenantDedupePolicies(v string) (map[string]domain.DedupePolicy, error) {
+	policies := make(map[string]domain.DedupePolicy)
+	for _, pair := range strings.Split(v, ",") {
+		tenant, name, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, errors.New(`expected a comma-separated list of "tenant:policy" pairs`)
+		}
+		if !helper.IsValidTenant(tenant) {
+			return nil, fmt.Errorf("invalid tenant name %q", tenant)
+		}
+		p, err := domain.ParseDedupePolicy(name)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", tenant, err)
+		}
+		if _, exists := policies[tenant]; exists {
+			return nil, fmt.Errorf("duplicated policy for tenant %q", tenant)
+		}
+		policies[tenant] = p
+	}
+	return policies, nil
+}
+
+// parseStatusRetentions parses the value of GOYAV_STATUS_RETENTION, e.g. "clean:1h,infected:2160h,error:168h".
+// A retention of zero keeps the documents of the status forever. The pending documents are not purged automatically and
+// cannot be given a retention.
+func parseStatusRetentions(v string) (map[domain.AnalysisStatus]time.Duration, error) {
+	retentions := make(map[domain.AnalysisStatus]time.Duration)
+	for _, pair := range strings.Split(v, ",") {
+		name, duration, found := strings.Cut(strings.TrimSpace(pair), ":")
+		if !found {
+			return nil, errors.New(`expected a comma-separated list of "status:duration" pairs`)
+		}
+		status, ok := domain.ParseAnalysisStatus(name)
+		if !ok || status == domain.StatusPending {
+			return nil, fmt.Errorf("invalid analysis status %q", name)
+		}
+		ttl, err := time.ParseDuration(duration)
+		if err != nil || ttl < 0 {
+			return nil, fmt.Errorf("status %q: invalid retention %q", name, duration)
+		}
+		if _, exists := retentions[status]; exists {
+			return nil, fmt.Errorf("duplicated retention for status %q", name)
+		}
+		retentions[status] = ttl
+	}
+	return retentions, nil
+}
+
+// parseQuotas parses the value of GOYAV_TENANT_QUOTAS, e.g. "*:uploads_per_day=100;finance:stored_bytes=1073741824,file_size=10485760".
+// The limits are uploads_per_day, stored_bytes and file_size; an omitted limit is unlimited.
+func parseQuotas(v string) (domain.Quota, map[string]domain.Quota, error) {
+	var (
+		defaultQuota domain.Quota
+		quotas       = make(map[string]domain.Quota)
+	)
+	for _, entry := range strings.Split(v, ";") {
+		tenant, limits, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found {
+			return defaultQuota, nil, errors.New(`expected a semicolon-separated list of "tenant:limit=value,..." entries`)
+		}
+		if tenant != "*" && !helper.IsValidTenant(tenant) {
+			return defaultQuota, nil, fmt.Errorf("invalid tenant name %q", tenant)
+		}
+
+		var q domain.Quota
+		for _, limit := range strings.Split(limits, ",") {
+			name, value, _ := strings.Cut(strings.TrimSpace(limit), "=")
+			n, err := strconv.ParseInt(value, 10, 64)
+			if err != nil || n < 0 {
+				return defaultQuota, nil, fmt.Errorf("invalid value %q of limit %q for tenant %q", value, name, tenant)
+			}
+			switch name {
+			case "uploads_per_day":
+				q.MaxUploadsPerDay = n
+			case "stored_bytes":
+				q.MaxStoredBytes = n
+			case "file_size":
+				q.MaxFileSize = n
+			default:
+				return defaultQuota, nil, fmt.Errorf("unknown limit %q for tenant %q", name, tenant)
+			}
+		}
+
+		if tenant == "*" {
+			defaultQuota = q
+			continue
+		}
+		if _, exists := quotas[tenant]; exists {
+			return defaultQuota, nil, fmt.Errorf("duplicated quota for tenant %q", tenant)
+		}
+		quotas[tenant] = q
+	}
+	return defaultQuota, quotas, nil
+}
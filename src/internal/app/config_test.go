@@ -0,0 +1,320 @@
+package app
+
+import (
+	"goyav/internal/adapter/alert"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/limiter"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/adapter/web"
+	"goyav/internal/core/domain"
+	"goyav/internal/service"
+	"goyav/pkg/helper"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setRequiredEnv sets the environment variables without a default value.
+func setRequiredEnv(t *testing.T) {
+	for name, value := range map[string]string{
+		"GOYAV_VERSION":                "1.0",
+		"GOYAV_S3_ENDPOINT_URL":        "localhost:9000",
+		"GOYAV_S3_ACCESS_KEY":          "access",
+		"GOYAV_S3_SECRET_KEY":          "secret",
+		"GOYAV_POSTGRES_USER":          "goyav",
+		"GOYAV_POSTGRES_USER_PASSWORD": "password",
+		"GOYAV_POSTGRES_DB":            "goyav",
+		"GOYAV_POSTGRES_SCHEMA":        "public",
+	} {
+		t.Setenv(name, value)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Run("Defaults", func(t *testing.T) {
+		setRequiredEnv(t)
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, "localhost", cfg.Server.Host)
+		assert.Equal(t, int64(80), cfg.Server.Port)
+		assert.Equal(t, "tcp", cfg.Server.ListenNetwork)
+		assert.Equal(t, "localhost:80", cfg.Server.ListenAddress)
+		assert.Equal(t, os.FileMode(0o660), cfg.Server.SocketMode)
+		assert.Equal(t, DefaultMaxUploadSize, cfg.Server.MaxUploadSize)
+		assert.Equal(t, DefaultUploadTimeout, cfg.Server.UploadTimeout)
+		assert.Equal(t, DefaultReadTimeout, cfg.Server.ReadTimeout)
+		assert.Zero(t, cfg.Server.UploadMinRate)
+		assert.Equal(t, DefaultWriteTimeout, cfg.Server.WriteTimeout)
+		assert.Equal(t, DefaultIdleTimeout, cfg.Server.IdleTimeout)
+		assert.Equal(t, DefaultHeaderTimeout, cfg.Server.ReadHeaderTimeout)
+		assert.Equal(t, 1<<20, cfg.Server.MaxHeaderBytes)
+		assert.Equal(t, time.Hour, cfg.Service.ResultTTL)
+		assert.Equal(t, 5*time.Second, cfg.Service.HealthCacheTTL)
+		assert.False(t, cfg.Service.Quotas.Enabled)
+		assert.Empty(t, cfg.Tenancy.APIKeys)
+		assert.Empty(t, cfg.Tenancy.SigningClients)
+		assert.Zero(t, cfg.Service.SpoolMemory)
+		assert.Empty(t, cfg.Service.SpoolDir)
+		assert.Equal(t, SharedConcurrencyConfig{Key: limiter.DefaultKey, Lease: limiter.DefaultLease}, cfg.Service.SharedConcurrency)
+		assert.Equal(t, web.DefaultSignatureMaxSkew, cfg.Tenancy.SignatureMaxSkew)
+		assert.Equal(t, "goyav", cfg.S3.Bucket)
+		assert.Equal(t, uint64(5432), cfg.Postgres.Port)
+		assert.Equal(t, "require", cfg.Postgres.SSLMode)
+		assert.Equal(t, uint64(3310), cfg.ClamAV.Port)
+		assert.Equal(t, uint64(antivirus.DefaultChunkSize), cfg.ClamAV.ChunkSize)
+		assert.Zero(t, cfg.ClamAV.TimeoutPerMB)
+		assert.Equal(t, 10*time.Minute, cfg.ClamAV.MaxTimeout)
+		assert.Empty(t, cfg.ClamAV.FallbackHost)
+		assert.Empty(t, cfg.ClamAV.Engines)
+		assert.Zero(t, cfg.ClamAV.SlowThreshold)
+		assert.Zero(t, cfg.Postgres.SlowThreshold)
+		assert.Zero(t, cfg.S3.SlowThreshold)
+		assert.Empty(t, cfg.Server.TLSCertFile)
+		assert.Empty(t, cfg.Server.TLSClientNames)
+		assert.False(t, cfg.Service.Images.Enabled)
+		assert.Equal(t, 128, cfg.Service.Images.Limits.MaxLayers)
+		assert.False(t, cfg.Service.Archives.Enabled)
+		assert.Equal(t, 3, cfg.Service.Archives.Limits.MaxDepth)
+		assert.False(t, cfg.Service.PresignedUploads.Enabled)
+		assert.Equal(t, 15*time.Minute, cfg.Service.PresignedUploads.Expiry)
+		assert.False(t, cfg.Service.Retention.RetainClean)
+		assert.Equal(t, 5*time.Minute, cfg.Service.Retention.DownloadExpiry)
+		assert.False(t, cfg.Service.Retention.QuarantineInfected)
+		assert.False(t, cfg.Service.Retention.TagVerdicts)
+		assert.False(t, cfg.Service.Callbacks.Enabled)
+		assert.Zero(t, cfg.Service.VerdictCache.TTL)
+		assert.Empty(t, cfg.Service.Blocklist.Path)
+		assert.Empty(t, cfg.Service.Reputation.Source)
+		assert.Equal(t, 5, cfg.Service.Reputation.MinMalicious)
+		assert.Equal(t, 30*time.Second, cfg.Service.Blocklist.ReloadInterval)
+		assert.Equal(t, helper.DefaultTagPolicy, cfg.Service.TagPolicy)
+		assert.Equal(t, domain.DedupeStrict, cfg.Service.DedupePolicy)
+		assert.Empty(t, cfg.Service.DedupePolicies)
+		assert.Empty(t, cfg.Service.Reports.Schedule.Period)
+		assert.Equal(t, 100000, cfg.Service.VerdictCache.MaxEntries)
+		assert.Equal(t, 5, cfg.Service.Callbacks.Attempts)
+		assert.Equal(t, 10*time.Second, cfg.Service.Callbacks.Timeout)
+		assert.Zero(t, cfg.S3.LifecycleExpiry)
+		assert.Zero(t, cfg.S3.QuarantineRetention)
+		assert.Empty(t, cfg.S3.SSEMode)
+		assert.True(t, cfg.Postgres.AutoMigrate)
+		assert.Equal(t, 20, cfg.Postgres.MaxOpenConns)
+		assert.Equal(t, 10, cfg.Postgres.MaxIdleConns)
+		assert.Equal(t, 30*time.Minute, cfg.Postgres.ConnMaxLifetime)
+		assert.Equal(t, service.DefaultRetryPolicy, cfg.Service.Retry)
+		assert.Equal(t, service.DefaultAnalysisDeadline, cfg.Service.AnalysisDeadline)
+		assert.Zero(t, cfg.Service.StuckPendingThreshold)
+		assert.True(t, cfg.Service.PurgeSchedule.IsZero())
+		assert.Empty(t, cfg.Service.StatusRetentions)
+		assert.Zero(t, cfg.Service.Alerts.InfectedRate)
+		assert.Equal(t, helper.IDSchemeMD5, cfg.Service.IDScheme)
+		assert.Equal(t, helper.HashSHA256, cfg.Service.HashAlgorithm)
+		assert.True(t, cfg.Service.MediaTypes.IsZero())
+		assert.Empty(t, cfg.Server.AllowedExtensions)
+		assert.Equal(t, "s3.amazonaws.com", cfg.Lambda.S3Endpoint)
+		assert.Equal(t, time.Second, cfg.Lambda.PollInterval)
+	})
+
+	t.Run("Sections", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("GOYAV_UPLOAD_TIMEOUT", "30")
+		t.Setenv("GOYAV_UPLOAD_MIN_RATE", "65536")
+		t.Setenv("GOYAV_READ_TIMEOUT", "5")
+		t.Setenv("GOYAV_WRITE_TIMEOUT", "0")
+		t.Setenv("GOYAV_MAX_HEADER_BYTES", "8192")
+		t.Setenv("GOYAV_MAX_CONCURRENT_UPLOADS", "32")
+		t.Setenv("GOYAV_STARTUP_RETRY", "true")
+		t.Setenv("GOYAV_API_KEYS", "k1:finance,k2:hr")
+		t.Setenv("GOYAV_SIGNING_CLIENTS", "mail-gateway:finance:0123456789abcdef:0123456789abcdef")
+		t.Setenv("GOYAV_TENANT_QUOTAS", "*:uploads_per_day=100;finance:file_size=10")
+		t.Setenv("GOYAV_PSEUDONYMIZATION_SEAL_KEY", "00ff")
+		t.Setenv("GOYAV_ALLOWED_MEDIA_TYPES", "application/pdf, Image/*")
+		t.Setenv("GOYAV_DENIED_EXTENSIONS", "exe, .TAR.GZ")
+		t.Setenv("GOYAV_TENANT_MAX_UPLOAD_SIZES", "premium:524288000, trial:1024")
+		spoolDir := t.TempDir()
+		t.Setenv("GOYAV_SPOOL_MEMORY", "8388608")
+		t.Setenv("GOYAV_SPOOL_DIR", spoolDir)
+		t.Setenv("GOYAV_SHARED_SEMAPHORE_CAPACITY", "32")
+		t.Setenv("GOYAV_REDIS_URL", "redis://redis:6379/1")
+		t.Setenv("GOYAV_RESULT_TTL", "48h")
+		t.Setenv("GOYAV_S3_LIFECYCLE_EXPIRY", "true")
+		t.Setenv("GOYAV_STATUS_RETENTION", "clean:1h, infected:2160h")
+		t.Setenv("GOYAV_ALERT_INFECTED_RATE", "0.2")
+		t.Setenv("GOYAV_ALERT_NOTIFIERS", "log, slack")
+		t.Setenv("GOYAV_ALERT_SLACK_WEBHOOK_URL", "https://hooks.slack.com/services/T0/B0/x")
+		t.Setenv("GOYAV_S3_QUARANTINE_RETENTION", "2160h")
+		t.Setenv("GOYAV_S3_SSE", "sse-kms")
+		t.Setenv("GOYAV_S3_SSE_KMS_KEY_ID", "goyav-key")
+		t.Setenv("GOYAV_S3_SHARD_LEVELS", "2")
+		t.Setenv("GOYAV_S3_PART_PARALLELISM", "8")
+		t.Setenv("GOYAV_CLAMAV_ENGINES", "strict=clamd-strict:3310, yara=clamd-yara:3311")
+		t.Setenv("GOYAV_POSTGRES_MAX_OPEN_CONNS", "5")
+		t.Setenv("GOYAV_POSTGRES_MAX_IDLE_CONNS", "5")
+		t.Setenv("GOYAV_POSTGRES_PARTITIONS", "Daily")
+		t.Setenv("GOYAV_LISTEN", "unix:///var/run/goyav.sock")
+		t.Setenv("GOYAV_SOCKET_MODE", "600")
+		t.Setenv("GOYAV_TAG_MAX_LENGTH", "255")
+		t.Setenv("GOYAV_TAG_CHARACTERS", "letters,marks,digits,punctuation,symbols,spaces")
+		t.Setenv("GOYAV_TAG_RAW", "true")
+		t.Setenv("GOYAV_DEDUPE_POLICY", "new-record")
+		t.Setenv("GOYAV_TENANT_DEDUPE_POLICIES", "finance:rescan")
+		t.Setenv("GOYAV_REPORT_SCHEDULE", "weekly")
+		t.Setenv("GOYAV_REPORT_TIME", "07:30")
+		t.Setenv("GOYAV_REPORT_WEEKDAY", "Friday")
+		t.Setenv("GOYAV_REPORT_SMTP_ADDRESS", "smtp.example.com:587")
+		t.Setenv("GOYAV_REPORT_EMAIL_FROM", "GoyAV <goyav@example.com>")
+		t.Setenv("GOYAV_REPORT_EMAIL_TO", "secops@example.com, ops@example.com")
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, 30*time.Second, cfg.Server.UploadTimeout)
+		assert.Equal(t, uint64(65536), cfg.Server.UploadMinRate)
+		assert.Equal(t, 5*time.Second, cfg.Server.ReadTimeout)
+		assert.Zero(t, cfg.Server.WriteTimeout)
+		assert.Equal(t, 8192, cfg.Server.MaxHeaderBytes)
+		assert.Equal(t, uint64(32), cfg.Server.MaxConcurrentUploads)
+		assert.True(t, cfg.Server.StartupRetry)
+		assert.Equal(t, "unix", cfg.Server.ListenNetwork)
+		assert.Equal(t, "/var/run/goyav.sock", cfg.Server.ListenAddress)
+		assert.Equal(t, os.FileMode(0o600), cfg.Server.SocketMode)
+		assert.Equal(t, helper.TagPolicy{MaxLength: 255, Classes: helper.CharLetters | helper.CharMarks | helper.CharDigits | helper.CharPunctuation | helper.CharSymbols | helper.CharSpaces, Extra: "-_.", Raw: true}, cfg.Service.TagPolicy)
+		assert.Equal(t, domain.DedupeNewRecord, cfg.Service.DedupePolicy)
+		assert.Equal(t, map[string]domain.DedupePolicy{"finance": domain.DedupeRescan}, cfg.Service.DedupePolicies)
+		assert.Equal(t, domain.ReportSchedule{Period: domain.ReportWeekly, At: 7*time.Hour + 30*time.Minute, Weekday: time.Friday}, cfg.Service.Reports.Schedule)
+		assert.Equal(t, service.DefaultReportTop, cfg.Service.Reports.Top)
+		assert.Equal(t, "goyav@example.com", cfg.Service.Reports.EmailFrom)
+		assert.Equal(t, []string{"secops@example.com", "ops@example.com"}, cfg.Service.Reports.EmailTo)
+		assert.Equal(t, map[string]string{"k1": "finance", "k2": "hr"}, cfg.Tenancy.APIKeys)
+		assert.Equal(t, map[string]web.SigningClient{"mail-gateway": {Tenant: "finance", Secret: []byte("0123456789abcdef:0123456789abcdef")}}, cfg.Tenancy.SigningClients)
+		assert.True(t, cfg.Service.Quotas.Enabled)
+		assert.Equal(t, domain.Quota{MaxUploadsPerDay: 100}, cfg.Service.Quotas.Default)
+		assert.Equal(t, domain.Quota{MaxFileSize: 10}, cfg.Service.Quotas.Tenants["finance"])
+		assert.Equal(t, []byte{0x00, 0xff}, cfg.Service.Pseudonymization.SealKey)
+		assert.Equal(t, []string{"application/pdf", "image/*"}, cfg.Service.MediaTypes.Allow)
+		assert.Equal(t, []string{".exe", ".tar.gz"}, cfg.Server.DeniedExtensions)
+		assert.Equal(t, map[string]int64{"premium": 524288000, "trial": 1024}, cfg.Service.MaxUploadSizes)
+		assert.Equal(t, int64(8<<20), cfg.Service.SpoolMemory)
+		assert.Equal(t, spoolDir, cfg.Service.SpoolDir)
+		assert.Equal(t, SharedConcurrencyConfig{RedisURL: "redis://redis:6379/1", Capacity: 32, Key: limiter.DefaultKey, Lease: limiter.DefaultLease}, cfg.Service.SharedConcurrency)
+		assert.Equal(t, map[domain.AnalysisStatus]time.Duration{domain.StatusClean: time.Hour, domain.StatusInfected: 2160 * time.Hour}, cfg.Service.StatusRetentions)
+		assert.Equal(t, 2160*time.Hour, cfg.S3.LifecycleExpiry)
+		assert.Equal(t, AlertConfig{InfectedRate: 0.2, Window: service.DefaultAlertWindow, MinAnalyzed: service.DefaultAlertMinAnalyzed, Timeout: alert.DefaultTimeout, Compression: helper.DefaultCompressionThreshold,
+			Notifiers: []string{"log", "slack"}, SlackWebhookURL: "https://hooks.slack.com/services/T0/B0/x"}, cfg.Service.Alerts)
+		assert.Equal(t, 90*24*time.Hour, cfg.S3.QuarantineRetention)
+		assert.Equal(t, "SSE-KMS", cfg.S3.SSEMode)
+		assert.Equal(t, "goyav-key", cfg.S3.SSEKMSKeyID)
+		assert.Equal(t, 2, cfg.S3.ShardLevels)
+		assert.Equal(t, DefaultMultipartThreshold, cfg.S3.MultipartThreshold)
+		assert.Equal(t, DefaultPartSize, cfg.S3.PartSize)
+		assert.Equal(t, uint(8), cfg.S3.PartParallelism)
+		assert.Equal(t, map[string]string{"strict": "clamd-strict:3310", "yara": "clamd-yara:3311"}, cfg.ClamAV.Engines)
+		assert.Equal(t, 5, cfg.Postgres.MaxOpenConns)
+		assert.Equal(t, 5, cfg.Postgres.MaxIdleConns)
+		assert.Equal(t, docrepo.PartitionDaily, cfg.Postgres.Partitions)
+		assert.Equal(t, 3, cfg.Postgres.PartitionsAhead)
+		assert.Equal(t, 5000, cfg.Postgres.PurgeBatchSize)
+		assert.Equal(t, 100*time.Millisecond, cfg.Postgres.PurgeBatchPause)
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		for name, value := range map[string]string{
+			"GOYAV_PORT":                           "http",
+			"GOYAV_LISTEN":                         "unix://goyav.sock",
+			"GOYAV_SOCKET_MODE":                    "rw-rw----",
+			"GOYAV_API_KEYS":                       "k1:not a tenant",
+			"GOYAV_SIGNING_CLIENTS":                "mail-gateway:finance:short",
+			"GOYAV_SIGNATURE_MAX_SKEW":             "0s",
+			"GOYAV_TOKEN_SECRET":                   "short",
+			"GOYAV_TENANT_QUOTAS":                  "finance:unknown=1",
+			"GOYAV_TENANT_MAX_UPLOAD_SIZES":        "premium:0",
+			"GOYAV_SPOOL_MEMORY":                   "-1",
+			"GOYAV_SPOOL_DIR":                      "/nonexistent/goyav",
+			"GOYAV_SHARED_SEMAPHORE_CAPACITY":      "8",
+			"GOYAV_SHARED_SEMAPHORE_LEASE":         "10ms",
+			"GOYAV_PSEUDONYMIZATION_SEAL_KEY":      "not hex",
+			"GOYAV_IMAGE_MAX_LAYERS":               "-1",
+			"GOYAV_ARCHIVE_MAX_DEPTH":              "-1",
+			"GOYAV_ARCHIVE_MAX_UNPACKED_SIZE":      "1GiB",
+			"GOYAV_PRESIGNED_UPLOAD_EXPIRY":        "0s",
+			"GOYAV_RETAIN_CLEAN_FILES":             "maybe",
+			"GOYAV_STATUS_EVENTS":                  "maybe",
+			"GOYAV_ANALYZER_ONLY":                  "maybe",
+			"GOYAV_S3_VERDICT_TAGS":                "maybe",
+			"GOYAV_POSTGRES_AUTO_MIGRATE":          "maybe",
+			"GOYAV_POSTGRES_MAX_OPEN_CONNS":        "-1",
+			"GOYAV_POSTGRES_CONN_MAX_LIFETIME":     "1 hour",
+			"GOYAV_POSTGRES_PARTITIONS":            "weekly",
+			"GOYAV_POSTGRES_PARTITIONS_AHEAD":      "-1",
+			"GOYAV_POSTGRES_PURGE_BATCH_SIZE":      "all",
+			"GOYAV_POSTGRES_PURGE_BATCH_PAUSE":     "100",
+			"GOYAV_POSTGRES_SLOW_THRESHOLD":        "-1s",
+			"GOYAV_S3_SLOW_THRESHOLD":              "1 second",
+			"GOYAV_CLAMAV_SLOW_THRESHOLD":          "10",
+			"GOYAV_TLS_CERT_FILE":                  "goyav.pem",
+			"GOYAV_TLS_CLIENT_NAMES":               "scanner.internal",
+			"GOYAV_S3_QUARANTINE_RETENTION":        "90d",
+			"GOYAV_S3_SSE":                         "SSE-C",
+			"GOYAV_CLAMAV_ENGINES":                 "clamav=clamd:3310",
+			"GOYAV_RETRY_MAX_ATTEMPTS":             "0",
+			"GOYAV_RETRY_FACTOR":                   "0.5",
+			"GOYAV_RETRY_JITTER":                   "2",
+			"GOYAV_ANALYSIS_DEADLINE":              "-1m",
+			"GOYAV_ID_SCHEME":                      "sha1",
+			"GOYAV_HASH_ALGORITHM":                 "md5",
+			"GOYAV_HASH_BLOCKLIST_RELOAD_INTERVAL": "-1s",
+			"GOYAV_REPUTATION_SOURCE":              "urlhaus",
+			"GOYAV_REPUTATION_TIMEOUT":             "0s",
+			"GOYAV_REPUTATION_MIN_CLEAN":           "-1",
+			"GOYAV_ALLOWED_MEDIA_TYPES":            "pdf",
+			"GOYAV_DENIED_MEDIA_TYPES":             "*/*",
+			"GOYAV_ALLOWED_EXTENSIONS":             "pdf,,doc",
+			"GOYAV_TAG_MAX_LENGTH":                 "256",
+			"GOYAV_TAG_CHARACTERS":                 "letters,emoji",
+			"GOYAV_DEDUPE_POLICY":                  "always",
+			"GOYAV_TENANT_DEDUPE_POLICIES":         "finance",
+			"GOYAV_STATUS_RETENTION":               "pending:1h",
+			"GOYAV_ALERT_INFECTED_RATE":            "20%",
+			"GOYAV_REPORT_SCHEDULE":                "monthly",
+			"GOYAV_LAMBDA_TENANT":                  "not a tenant",
+			"GOYAV_LAMBDA_POLL_INTERVAL":           "0s",
+		} {
+			t.Run(name, func(t *testing.T) {
+				setRequiredEnv(t)
+				t.Setenv(name, value)
+				_, err := LoadConfig()
+				assert.Error(t, err)
+			})
+		}
+	})
+
+	t.Run("Reputation", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("GOYAV_REPUTATION_SOURCE", "VirusTotal")
+		_, err := LoadConfig()
+		assert.Error(t, err, "the API key should be required")
+
+		t.Setenv("GOYAV_REPUTATION_API_KEY", "key")
+		cfg, err := LoadConfig()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, "virustotal", cfg.Service.Reputation.Source)
+
+		t.Setenv("GOYAV_HASH_ALGORITHM", "SHA-512")
+		_, err = LoadConfig()
+		assert.Error(t, err, "the lookups should require SHA-256 hashes")
+	})
+
+	t.Run("MissingVersion", func(t *testing.T) {
+		setRequiredEnv(t)
+		t.Setenv("GOYAV_VERSION", "")
+		os.Unsetenv("GOYAV_VERSION")
+		_, err := LoadConfig()
+		assert.Error(t, err)
+	})
+}
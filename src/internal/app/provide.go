@@ -0,0 +1,482 @@
+package app
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"goyav/api"
+	"goyav/internal/adapter/alert"
+	"goyav/internal/adapter/anonymizer"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/blocklist"
+	"goyav/internal/adapter/cache"
+	"goyav/internal/adapter/callback"
+	"goyav/internal/adapter/lambda"
+	"goyav/internal/adapter/limiter"
+	"goyav/internal/adapter/report"
+	"goyav/internal/adapter/reputation"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/adapter/web"
+	"goyav/internal/adapter/webhook"
+	"goyav/internal/core/port"
+	"goyav/internal/service"
+	"log/slog"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"github.com/redis/go-redis/v9"
+)
+
+// ProvideBinaryRepo creates the S3 binary repository storing the binary data of files.
+func ProvideBinaryRepo(cfg S3Config) (port.BinaryRepository, error) {
+	cli, err := minio.New(cfg.Endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(cfg.AccessKeyID, cfg.SecretKey, ""),
+		Secure: cfg.UseSSL,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := binaryrepo.NewMinio(cli, cfg.Bucket,
+		binaryrepo.WithLifecycleExpiry(cfg.LifecycleExpiry),
+		binaryrepo.WithQuarantine(cfg.QuarantineRetention, cfg.QuarantineLegalHold),
+		binaryrepo.WithServerSideEncryption(cfg.SSEMode, cfg.SSEKMSKeyID),
+		binaryrepo.WithKeySharding(cfg.ShardLevels),
+		binaryrepo.WithMultipartUpload(cfg.MultipartThreshold, cfg.PartSize, cfg.PartParallelism),
+		binaryrepo.WithSlowOperations(cfg.SlowThreshold))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("minio repository setup complete")
+	return repo, nil
+}
+
+// ProvideDB opens the PostgreSQL database shared by the Postgres repositories, with the settings of its connection pool.
+func ProvideDB(cfg PostgresConfig) (*sql.DB, error) {
+	db, err := sql.Open("postgres", postgresConnInfo(cfg))
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(cfg.MaxOpenConns)
+	db.SetMaxIdleConns(cfg.MaxIdleConns)
+	db.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	db.SetConnMaxIdleTime(cfg.ConnMaxIdleTime)
+	return db, nil
+}
+
+// postgresConnInfo returns the connection string of the PostgreSQL database.
+func postgresConnInfo(cfg PostgresConfig) string {
+	return fmt.Sprintf("host=%v port=%v dbname=%v search_path=%v sslmode=%v user=%v password=%v",
+		cfg.Host, cfg.Port, cfg.Database, cfg.Schema, cfg.SSLMode, cfg.User, cfg.Password)
+}
+
+// ProvideStatusFeed creates the feed of the status changes of the documents, notified by the PostgreSQL database.
+// It returns a nil feed when the status events are not enabled.
+func ProvideStatusFeed(cfg ServerConfig, db PostgresConfig) port.StatusFeed {
+	if !cfg.StatusEvents {
+		return nil
+	}
+	return docrepo.NewPostgresStatusFeed(postgresConnInfo(db))
+}
+
+// MigrateDB applies the pending migrations of the database, see docrepo.Migrate, then partitions the documents table
+// if cfg requires it, see docrepo.Partition.
+func MigrateDB(ctx context.Context, cfg PostgresConfig, db *sql.DB) error {
+	from, to, err := docrepo.Migrate(ctx, db)
+	if err != nil {
+		return err
+	}
+	slog.Info("database schema up to date", "from version", from, "version", to)
+	return docrepo.Partition(ctx, db, cfg.Partitions)
+}
+
+// ProvideDocRepo creates the Postgres document repository.
+func ProvideDocRepo(cfg PostgresConfig, db *sql.DB) (port.DocumentRepository, error) {
+	repo, err := docrepo.NewPotgres(db,
+		docrepo.WithPartitions(cfg.Partitions, cfg.PartitionsAhead),
+		docrepo.WithPurgeBatches(cfg.PurgeBatchSize, cfg.PurgeBatchPause),
+		docrepo.WithSlowOperations(cfg.SlowThreshold))
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("postgres repository setup complete")
+	return repo, nil
+}
+
+// ProvideQuotaRepo creates the Postgres repository of the quota usage of tenants.
+// It returns a nil repository when quotas are not enabled.
+func ProvideQuotaRepo(cfg QuotaConfig, db *sql.DB) (port.QuotaRepository, error) {
+	if !cfg.Enabled {
+		return nil, nil
+	}
+	return docrepo.NewPostgresQuota(db)
+}
+
+// ProvideAnonymizer creates the anonymizer pseudonymizing tags and file names.
+// It returns a nil anonymizer when pseudonymization is not enabled.
+func ProvideAnonymizer(cfg PseudonymizationConfig) (port.Anonymizer, error) {
+	if len(cfg.Key) == 0 {
+		return nil, nil
+	}
+	return anonymizer.NewHMAC(cfg.Key, cfg.SealKey)
+}
+
+// ProvideAnalyzer creates the ClamAV antivirus analyzer.
+func ProvideAnalyzer(cfg ClamAVConfig) (port.AntivirusAnalyzer, error) {
+	a, err := newClamav(cfg, cfg.Host, cfg.Port)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("clamav analyzer setup complete")
+	return a, nil
+}
+
+// ProvideFallbackAnalyzer creates the ClamAV antivirus analyzer of the fallback clamd, used while the primary one
+// fails. It returns a nil analyzer when no fallback is configured.
+func ProvideFallbackAnalyzer(cfg ClamAVConfig) (port.AntivirusAnalyzer, error) {
+	if cfg.FallbackHost == "" {
+		return nil, nil
+	}
+	a, err := newClamav(cfg, cfg.FallbackHost, cfg.FallbackPort)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("fallback clamav analyzer setup complete")
+	return a, nil
+}
+
+// ProvideConcurrencyLimiter creates the concurrency limiter shared by the replicas through Redis. It returns a nil
+// limiter when cfg disables it.
+func ProvideConcurrencyLimiter(cfg SharedConcurrencyConfig) (port.ConcurrencyLimiter, error) {
+	if cfg.Capacity == 0 {
+		return nil, nil
+	}
+	opts, err := redis.ParseURL(cfg.RedisURL)
+	if err != nil {
+		return nil, err
+	}
+	slog.Info("shared concurrency limiter setup complete", "capacity", cfg.Capacity, "key", cfg.Key)
+	return limiter.NewRedis(redis.NewClient(opts), cfg.Key, cfg.Capacity, cfg.Lease), nil
+}
+
+// ProvideEngines creates the ClamAV antivirus analyzers of the engines the uploads can select besides the analyzer of
+// the service, by name, none if cfg has none.
+func ProvideEngines(cfg ClamAVConfig) (map[string]port.AntivirusAnalyzer, error) {
+	engines := make(map[string]port.AntivirusAnalyzer, len(cfg.Engines))
+	for name, address := range cfg.Engines {
+		host, p, err := net.SplitHostPort(address)
+		if err != nil {
+			return nil, fmt.Errorf("engine %s: %w", name, err)
+		}
+		portNumber, err := strconv.ParseUint(p, 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("engine %s: %w", name, err)
+		}
+		if engines[name], err = newClamav(cfg, host, portNumber); err != nil {
+			return nil, fmt.Errorf("engine %s: %w", name, err)
+		}
+	}
+	if len(engines) > 0 {
+		slog.Info("clamav engines setup complete", "engines", len(engines))
+	}
+	return engines, nil
+}
+
+// newClamav creates a ClamAV antivirus analyzer of the clamd listening on host and port, configured by cfg.
+func newClamav(cfg ClamAVConfig, host string, port uint64) (*antivirus.ClamavAnalyser, error) {
+	return antivirus.NewClamav(host, port, cfg.Timeout,
+		antivirus.WithChunkSize(cfg.ChunkSize),
+		antivirus.WithSizeTimeout(cfg.TimeoutPerMB, cfg.MaxTimeout),
+		antivirus.WithSlowAnalyses(cfg.SlowThreshold))
+}
+
+// ProvideService creates the document service. The fallback analyzer, the other engines, the quota repository and the
+// anonymizer are optional: the analyses failing with a are not handed over when fallback is nil, the uploads can only
+// select a when engines is empty, quotas are not enforced when quotas is nil, and tags and file names are stored as is
+// when anon is nil. The files of container images are analyzed with
+// a when image analysis is enabled.
+func ProvideService(cfg ServiceConfig, b port.BinaryRepository, d port.DocumentRepository, a, fallback port.AntivirusAnalyzer, engines map[string]port.AntivirusAnalyzer, quotas port.QuotaRepository, anon port.Anonymizer) (*service.Service, error) {
+	opts := []service.Option{
+		service.WithAdmissionControl(cfg.AdmissionControlInterval),
+		service.WithMaxQueuedAnalyses(cfg.MaxQueuedAnalyses),
+		service.WithHealthCache(cfg.HealthCacheTTL),
+		service.WithRetryPolicy(cfg.Retry),
+		service.WithAnalysisDeadline(cfg.AnalysisDeadline),
+		service.WithStuckPendingThreshold(cfg.StuckPendingThreshold),
+		service.WithPurgeSchedule(cfg.PurgeSchedule),
+		service.WithStatusRetentions(cfg.StatusRetentions),
+		service.WithIDScheme(cfg.IDScheme),
+		service.WithHashAlgorithm(cfg.HashAlgorithm),
+		service.WithMaxUploadSizes(cfg.MaxUploadSizes),
+		service.WithSpool(cfg.SpoolMemory, cfg.SpoolDir),
+		service.WithMediaTypePolicy(cfg.MediaTypes),
+		service.WithTagPolicy(cfg.TagPolicy),
+		service.WithDedupePolicies(cfg.DedupePolicy, cfg.DedupePolicies),
+	}
+	l, err := ProvideConcurrencyLimiter(cfg.SharedConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		opts = append(opts, service.WithConcurrencyLimiter(l))
+	}
+	if quotas != nil {
+		opts = append(opts, service.WithQuotas(quotas, cfg.Quotas.Default, cfg.Quotas.Tenants))
+	}
+	if anon != nil {
+		opts = append(opts, service.WithAnonymizer(anon))
+	}
+	if fallback != nil {
+		opts = append(opts, service.WithFallbackAnalyzer(fallback))
+	}
+	if len(engines) > 0 {
+		opts = append(opts, service.WithEngines(engines))
+	}
+	if cfg.Images.Enabled {
+		opts = append(opts, service.WithImageAnalyzer(antivirus.NewImage(a, cfg.Images.Limits, cfg.Images.CheckLinks)))
+	}
+	if cfg.Archives.Enabled {
+		opts = append(opts, service.WithArchiveAnalyzer(antivirus.NewArchive(a, cfg.Archives.Limits)))
+	}
+	if cfg.Retention.RetainClean {
+		opts = append(opts, service.WithRetainedBinaries(cfg.Retention.DownloadExpiry))
+	}
+	if cfg.Retention.QuarantineInfected {
+		opts = append(opts, service.WithQuarantine())
+	}
+	if cfg.Retention.TagVerdicts {
+		opts = append(opts, service.WithVerdictTags())
+	}
+	if cfg.PresignedUploads.Enabled {
+		opts = append(opts, service.WithPresignedUploads(cfg.PresignedUploads.Expiry, cfg.PresignedUploads.MaxSize))
+	}
+	if cfg.Blocklist.Path != "" {
+		bl, err := blocklist.NewFile(cfg.Blocklist.Path, cfg.HashAlgorithm, cfg.Blocklist.Threat, cfg.Blocklist.ReloadInterval)
+		if err != nil {
+			return nil, err
+		}
+		slog.Info("hash blocklist setup complete", "hashes", bl.Len())
+		opts = append(opts, service.WithHashBlocklist(bl))
+	}
+	switch r := cfg.Reputation; r.Source {
+	case "malwarebazaar":
+		opts = append(opts, service.WithReputationSource(reputation.NewMalwareBazaar(r.URL, r.APIKey, r.Timeout)))
+	case "virustotal":
+		opts = append(opts, service.WithReputationSource(reputation.NewVirusTotal(r.URL, r.APIKey, r.Timeout, r.MinMalicious, r.MinClean)))
+	}
+	if cfg.VerdictCache.TTL > 0 {
+		opts = append(opts, service.WithVerdictCache(cache.NewMemory(cfg.VerdictCache.TTL, cfg.VerdictCache.MaxEntries)))
+	}
+	if cfg.Callbacks.Enabled {
+		opts = append(opts, service.WithCallbacks(callback.NewHTTP(cfg.Callbacks.Timeout, cfg.Callbacks.Attempts, cfg.Callbacks.AllowPrivate, cfg.Callbacks.Compression)))
+		if cfg.Callbacks.Outbox {
+			opts = append(opts, service.WithCallbackOutbox(cfg.Callbacks.OutboxInterval, cfg.Callbacks.OutboxLease, cfg.Callbacks.OutboxClaims))
+		}
+	}
+	if r := cfg.Reports; r.Schedule.Period != "" {
+		var senders []port.ReportSender
+		if r.WebhookURL != "" {
+			senders = append(senders, report.NewWebhook(r.WebhookURL, r.Timeout, r.Compression))
+		}
+		if r.SMTPAddress != "" {
+			senders = append(senders, report.NewEmail(r.SMTPAddress, r.SMTPUsername, r.SMTPPassword, r.EmailFrom, r.EmailTo))
+		}
+		if webhooks, ok := d.(port.WebhookRepository); ok {
+			senders = append(senders, webhook.NewDispatcher(webhooks, r.Timeout, r.Compression))
+		}
+		opts = append(opts, service.WithReports(r.Schedule, r.Top, senders...))
+	}
+	if r := cfg.Alerts; r.InfectedRate > 0 {
+		var notifiers []port.AlertNotifier
+		for _, n := range r.Notifiers {
+			switch n {
+			case "log":
+				notifiers = append(notifiers, alert.NewLog())
+			case "webhook":
+				notifiers = append(notifiers, alert.NewWebhook(r.WebhookURL, r.Timeout, r.Compression))
+			case "slack":
+				notifiers = append(notifiers, alert.NewSlack(r.SlackWebhookURL, r.Timeout))
+			}
+		}
+		if webhooks, ok := d.(port.WebhookRepository); ok {
+			notifiers = append(notifiers, webhook.NewDispatcher(webhooks, r.Timeout, r.Compression))
+		}
+		opts = append(opts, service.WithInfectedRateAlert(r.Window, r.InfectedRate, r.MinAnalyzed, notifiers...))
+	}
+	return service.New(b, d, a, cfg.Version, cfg.Information, cfg.ResultTTL, cfg.SemaphoreCapacity, opts...)
+}
+
+// ProvideAnalyzerService creates the service without repositories, only scanning files with a, or with the other
+// engines, which are optional. The options of cfg about the documents are ignored.
+func ProvideAnalyzerService(cfg ServiceConfig, a port.AntivirusAnalyzer, engines map[string]port.AntivirusAnalyzer) (*service.Service, error) {
+	opts := []service.Option{
+		service.WithAdmissionControl(cfg.AdmissionControlInterval),
+		service.WithMaxQueuedAnalyses(cfg.MaxQueuedAnalyses),
+		service.WithHealthCache(cfg.HealthCacheTTL),
+		service.WithHashAlgorithm(cfg.HashAlgorithm),
+		service.WithMaxUploadSizes(cfg.MaxUploadSizes),
+		service.WithSpool(cfg.SpoolMemory, cfg.SpoolDir),
+	}
+	l, err := ProvideConcurrencyLimiter(cfg.SharedConcurrency)
+	if err != nil {
+		return nil, err
+	}
+	if l != nil {
+		opts = append(opts, service.WithConcurrencyLimiter(l))
+	}
+	if len(engines) > 0 {
+		opts = append(opts, service.WithEngines(engines))
+	}
+	if cfg.Archives.Enabled {
+		opts = append(opts, service.WithArchiveAnalyzer(antivirus.NewArchive(a, cfg.Archives.Limits)))
+	}
+	slog.Info("analyzer-only service: the documents are neither stored nor recorded")
+	return service.NewAnalyzer(a, cfg.Version, cfg.Information, cfg.SemaphoreCapacity, opts...)
+}
+
+// ProvideHTTPServer creates the HTTP server exposing the document service, pushing the status changes reported by
+// feed unless it is nil.
+func ProvideHTTPServer(cfg ServerConfig, tenancy TenancyConfig, admin AdminConfig, svc port.DocumentService, feed port.StatusFeed) *http.Server {
+	return newHTTPServer(cfg, ProvideHandler(cfg, tenancy, admin, svc, feed))
+}
+
+// ProvideHandler creates the handler of the routes of the API exposing the document service, pushing the status
+// changes reported by feed unless it is nil.
+func ProvideHandler(cfg ServerConfig, tenancy TenancyConfig, admin AdminConfig, svc port.DocumentService, feed port.StatusFeed) http.Handler {
+	opts := []web.Option{
+		web.WithUnknownFieldsRejected(cfg.RejectUnknownFields),
+		web.WithMaxImageSize(cfg.MaxImageSize),
+		web.WithCompletionEstimates(cfg.CompletionEstimates),
+		web.WithExtensionPolicy(cfg.AllowedExtensions, cfg.DeniedExtensions),
+		web.WithUploadDeadline(cfg.UploadTimeout, cfg.UploadMinRate),
+		web.WithWriteTimeout(cfg.WriteTimeout),
+		web.WithMaxConcurrentUploads(cfg.MaxConcurrentUploads),
+	}
+	if cfg.AnalyzerOnly {
+		opts = append(opts, web.WithAnalyzerOnly())
+	}
+	switch {
+	case len(tenancy.APIKeys) > 0:
+		opts = append(opts, web.WithAPIKeys(tenancy.APIKeys))
+	case tenancy.TenantHeader != "":
+		opts = append(opts, web.WithTenantHeader(tenancy.TenantHeader))
+	}
+	if len(tenancy.SigningClients) > 0 {
+		opts = append(opts, web.WithSignedRequests(tenancy.SigningClients, tenancy.SignatureMaxSkew))
+	}
+	if admin.APIKey != "" {
+		opts = append(opts, web.WithAdminKey(admin.APIKey))
+	}
+	if admin.TokenSecret != "" {
+		opts = append(opts, web.WithTokenSecret([]byte(admin.TokenSecret)))
+	}
+	if feed != nil {
+		opts = append(opts, web.WithStatusFeed(feed))
+	}
+	if spec, err := api.JSON(); err != nil {
+		slog.Error("the OpenAPI specification is not served", "error", err)
+	} else {
+		opts = append(opts, web.WithOpenAPISpec(spec, cfg.SwaggerUI))
+	}
+
+	return web.NewDocumentMux(svc, cfg.MaxUploadSize, opts...)
+}
+
+// newHTTPServer creates the HTTP server serving h with the limits of cfg.
+func newHTTPServer(cfg ServerConfig, h http.Handler) *http.Server {
+	server := &http.Server{
+		ReadTimeout:       cfg.ReadTimeout,
+		ReadHeaderTimeout: cfg.ReadHeaderTimeout,
+		WriteTimeout:      cfg.WriteTimeout,
+		IdleTimeout:       cfg.IdleTimeout,
+		MaxHeaderBytes:    cfg.MaxHeaderBytes,
+		Handler:           h,
+	}
+	if cfg.ListenNetwork == "tcp" {
+		server.Addr = cfg.ListenAddress
+	}
+	return server
+}
+
+// ProvideListener creates the listener the HTTP server serves on. The socket passed by systemd socket activation
+// prevails over the configured address. A Unix socket is given the configured mode, and the socket file left by a
+// previous run is replaced, unless a server still accepts connections on it. The connections are served over TLS
+// when a certificate is configured, see ProvideTLSConfig.
+func ProvideListener(cfg ServerConfig) (net.Listener, error) {
+	config, err := ProvideTLSConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	ln, err := systemdListener()
+	if err != nil {
+		return nil, fmt.Errorf("socket activation failed: %w", err)
+	}
+	if ln != nil {
+		slog.Info("serving the socket passed by systemd", "network", ln.Addr().Network(), "address", ln.Addr().String())
+		return tlsListener(ln, config), nil
+	}
+
+	if cfg.ListenNetwork == "unix" {
+		if err = removeStaleSocket(cfg.ListenAddress); err != nil {
+			return nil, err
+		}
+	}
+	ln, err = net.Listen(cfg.ListenNetwork, cfg.ListenAddress)
+	if err != nil {
+		return nil, fmt.Errorf("failed to listen on %s://%s: %w", cfg.ListenNetwork, cfg.ListenAddress, err)
+	}
+	if cfg.ListenNetwork == "unix" {
+		if err = os.Chmod(cfg.ListenAddress, cfg.SocketMode); err != nil {
+			ln.Close()
+			return nil, fmt.Errorf("failed to set the mode of the socket %s: %w", cfg.ListenAddress, err)
+		}
+	}
+	return tlsListener(ln, config), nil
+}
+
+// removeStaleSocket removes the Unix socket file at path if no server accepts connections on it anymore.
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil
+	case err != nil:
+		return err
+	case fi.Mode()&os.ModeSocket == 0:
+		return fmt.Errorf("%s exists and is not a socket", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		conn.Close()
+		return fmt.Errorf("the socket %s is in use", path)
+	}
+	return os.Remove(path)
+}
+
+// ProvideLambdaHandler creates the handler of the S3 events of the Lambda mode, uploading the objects of at most
+// maxObjectSize bytes to svc. The objects are read and tagged with the credentials of the Lambda function.
+func ProvideLambdaHandler(cfg LambdaConfig, maxObjectSize uint64, svc port.DocumentService) (*lambda.Handler, error) {
+	cli, err := minio.New(cfg.S3Endpoint, &minio.Options{
+		Creds: credentials.NewChainCredentials([]credentials.Provider{
+			&credentials.EnvAWS{},
+			&credentials.IAM{},
+		}),
+		Secure: true,
+		Region: cfg.Region,
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	opts := []lambda.Option{lambda.WithPollInterval(cfg.PollInterval)}
+	if cfg.Tenant != "" {
+		opts = append(opts, lambda.WithTenant(cfg.Tenant))
+	}
+	return lambda.NewHandler(svc, lambda.NewMinioObjectStore(cli), maxObjectSize, opts...), nil
+}
@@ -0,0 +1,60 @@
+package app
+
+import (
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProvideListener(t *testing.T) {
+	cfg := ServerConfig{ListenNetwork: "unix", ListenAddress: filepath.Join(t.TempDir(), "goyav.sock"), SocketMode: 0o600}
+
+	ln, err := ProvideListener(cfg)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	fi, err := os.Stat(cfg.ListenAddress)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	assert.Equal(t, os.FileMode(0o600), fi.Mode().Perm())
+
+	_, err = ProvideListener(cfg)
+	assert.Error(t, err, "a socket in use must not be replaced")
+
+	// a socket left by a previous run is replaced
+	ln.(*net.UnixListener).SetUnlinkOnClose(false)
+	ln.Close()
+	ln, err = ProvideListener(cfg)
+	if assert.NoError(t, err) {
+		ln.Close()
+	}
+}
+
+func TestSystemdListener(t *testing.T) {
+	t.Run("NotActivated", func(t *testing.T) {
+		ln, err := systemdListener()
+		assert.NoError(t, err)
+		assert.Nil(t, ln)
+	})
+
+	t.Run("OtherProcess", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", "1")
+		t.Setenv("LISTEN_FDS", "1")
+		ln, err := systemdListener()
+		assert.NoError(t, err)
+		assert.Nil(t, ln)
+		assert.Empty(t, os.Getenv("LISTEN_FDS"), "the variables of the activation must be unset")
+	})
+
+	t.Run("Invalid", func(t *testing.T) {
+		t.Setenv("LISTEN_PID", strconv.Itoa(os.Getpid()))
+		t.Setenv("LISTEN_FDS", "0")
+		_, err := systemdListener()
+		assert.Error(t, err)
+	})
+}
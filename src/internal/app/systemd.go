@@ -0,0 +1,47 @@
+package app
+
+import (
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"strconv"
+)
+
+// systemdListenFDsStart is the first file descriptor passed by systemd socket activation, see sd_listen_fds(3).
+const systemdListenFDsStart = 3
+
+// systemdListener returns the listener passed by systemd socket activation, or nil when the process was not started
+// by a socket unit. The variables of the activation are unset, so that the child processes do not take the socket
+// for theirs.
+func systemdListener() (net.Listener, error) {
+	pid, fds := os.Getenv("LISTEN_PID"), os.Getenv("LISTEN_FDS")
+	if fds == "" {
+		return nil, nil
+	}
+	defer func() {
+		os.Unsetenv("LISTEN_PID")
+		os.Unsetenv("LISTEN_FDS")
+		os.Unsetenv("LISTEN_FDNAMES")
+	}()
+	if pid != strconv.Itoa(os.Getpid()) {
+		// the sockets were passed to another process
+		return nil, nil
+	}
+	n, err := strconv.Atoi(fds)
+	if err != nil || n < 1 {
+		return nil, fmt.Errorf("invalid LISTEN_FDS %q", fds)
+	}
+	if n > 1 {
+		slog.Warn("only the first socket passed by systemd is served", "sockets", n)
+	}
+
+	// net.FileListener works on a duplicate of the file descriptor
+	f := os.NewFile(systemdListenFDsStart, "systemd socket")
+	defer f.Close()
+	ln, err := net.FileListener(f)
+	if err != nil {
+		return nil, fmt.Errorf("the file descriptor passed by systemd is not a listening socket: %w", err)
+	}
+	return ln, nil
+}
@@ -0,0 +1,91 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"slices"
+)
+
+// errClientNotAllowed is returned by the handshake of a client whose certificate names none of the allowed clients.
+var errClientNotAllowed = errors.New("client certificate not allowed")
+
+// ProvideTLSConfig creates the TLS configuration of the listener, nil when TLS is disabled. When a client CA bundle is
+// configured, the clients must present a certificate signed by one of its CAs and, if client names are configured,
+// naming one of them by its common name or one of its subject alternative names.
+func ProvideTLSConfig(cfg ServerConfig) (*tls.Config, error) {
+	if cfg.TLSCertFile == "" {
+		return nil, nil
+	}
+	cert, err := tls.LoadX509KeyPair(cfg.TLSCertFile, cfg.TLSKeyFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the TLS certificate: %w", err)
+	}
+	config := &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+	}
+	if cfg.TLSClientCAFile == "" {
+		return config, nil
+	}
+
+	pem, err := os.ReadFile(cfg.TLSClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the client CA bundle: %w", err)
+	}
+	config.ClientCAs = x509.NewCertPool()
+	if !config.ClientCAs.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no certificate found in the client CA bundle %s", cfg.TLSClientCAFile)
+	}
+	config.ClientAuth = tls.RequireAndVerifyClientCert
+	if len(cfg.TLSClientNames) > 0 {
+		config.VerifyConnection = verifyClientNames(cfg.TLSClientNames)
+	}
+	slog.Info("client certificates required", "allowed names", cfg.TLSClientNames)
+	return config, nil
+}
+
+// verifyClientNames returns the verification of the connections of the clients whose certificate, verified by the
+// client CAs, names one of names.
+func verifyClientNames(names []string) func(tls.ConnectionState) error {
+	return func(cs tls.ConnectionState) error {
+		if len(cs.PeerCertificates) == 0 {
+			return errClientNotAllowed
+		}
+		cert := cs.PeerCertificates[0]
+		if slices.ContainsFunc(certificateNames(cert), func(name string) bool { return slices.Contains(names, name) }) {
+			return nil
+		}
+		return fmt.Errorf("%w: %q", errClientNotAllowed, cert.Subject.CommonName)
+	}
+}
+
+// certificateNames returns the names of the subject of cert: its common name, then its DNS names, email addresses,
+// IP addresses and URIs.
+func certificateNames(cert *x509.Certificate) []string {
+	var names []string
+	if cert.Subject.CommonName != "" {
+		names = append(names, cert.Subject.CommonName)
+	}
+	names = append(names, cert.DNSNames...)
+	names = append(names, cert.EmailAddresses...)
+	for _, ip := range cert.IPAddresses {
+		names = append(names, ip.String())
+	}
+	for _, uri := range cert.URIs {
+		names = append(names, uri.String())
+	}
+	return names
+}
+
+// tlsListener wraps ln with config, returning ln as is when config is nil.
+func tlsListener(ln net.Listener, config *tls.Config) net.Listener {
+	if config == nil {
+		return ln
+	}
+	return tls.NewListener(ln, config)
+}
@@ -0,0 +1,42 @@
+package app
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"net/url"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestVerifyClientNames(t *testing.T) {
+	spiffe, _ := url.Parse("spiffe://prod/uploader")
+	verify := verifyClientNames([]string{"scanner.internal", "spiffe://prod/uploader"})
+
+	for cert, want := range map[*x509.Certificate]bool{
+		{Subject: pkix.Name{CommonName: "scanner.internal"}}:                             true,
+		{Subject: pkix.Name{CommonName: "mail"}, DNSNames: []string{"scanner.internal"}}: true,
+		{Subject: pkix.Name{CommonName: "uploader"}, URIs: []*url.URL{spiffe}}:           true,
+		{Subject: pkix.Name{CommonName: "mail"}, DNSNames: []string{"mail.internal"}}:    false,
+	} {
+		err := verify(tls.ConnectionState{PeerCertificates: []*x509.Certificate{cert}})
+		if want {
+			assert.NoError(t, err, cert.Subject.CommonName)
+		} else {
+			assert.ErrorIs(t, err, errClientNotAllowed, cert.Subject.CommonName)
+		}
+	}
+	assert.ErrorIs(t, verify(tls.ConnectionState{}), errClientNotAllowed)
+}
+
+func TestProvideTLSConfig(t *testing.T) {
+	config, err := ProvideTLSConfig(ServerConfig{})
+	assert.NoError(t, err)
+	assert.Nil(t, config, "TLS must be disabled without certificate")
+
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	_, err = ProvideTLSConfig(ServerConfig{TLSCertFile: missing, TLSKeyFile: missing})
+	assert.Error(t, err)
+}
@@ -0,0 +1,22 @@
+package domain
+
+import (
+	"fmt"
+	"time"
+)
+
+// InfectedRateAlert reports that the share of the analyzed documents found infected over the last Window exceeded
+// Threshold, which usually means an active campaign against the users of the service.
+type InfectedRateAlert struct {
+	At        time.Time
+	Window    time.Duration
+	Threshold float64
+	Rate      float64 // Rate is Infected out of Analyzed.
+	Infected  int64   // Infected is the number of documents found infected over Window.
+	Analyzed  int64   // Analyzed is the number of documents found clean or infected over Window.
+}
+
+// String returns a one-line summary of a, e.g. "infected rate 42.0% above 10.0% over 15m0s: 21 infected out of 50".
+func (a *InfectedRateAlert) String() string {
+	return fmt.Sprintf("infected rate %.1f%% above %.1f%% over %s: %d infected out of %d", a.Rate*100, a.Threshold*100, a.Window, a.Infected, a.Analyzed)
+}
@@ -0,0 +1,33 @@
+package domain
+
+// ArchiveLimits bounds the extraction of an archive, a limit is not enforced when it is zero.
+type ArchiveLimits struct {
+	MaxDepth        int   // MaxDepth is the maximum nesting depth of the archives extracted, the uploaded archive is at depth 1.
+	MaxFiles        int   // MaxFiles is the maximum number of files of an archive, nested archives included.
+	MaxFileSize     int64 // MaxFileSize is the maximum size of a file, in bytes.
+	MaxUnpackedSize int64 // MaxUnpackedSize is the maximum size of all the files of an archive, in bytes.
+}
+
+// ArchiveEntry is a file of an archive along with its verdict.
+type ArchiveEntry struct {
+	Path   string `json:"path"` // Path is the path of the file in the archive, prefixed by the path of its nested archive if any.
+	Status string `json:"status"`
+	Threat string `json:"threat,omitempty"` // Threat is the name of the threat found in the file, if infected and known.
+}
+
+// ArchiveReport is the outcome of the analysis of an archive file by file, its entries are in the order of the archive.
+type ArchiveReport struct {
+	Status  string         `json:"status"` // Status is infected if an entry is, clean otherwise.
+	Files   int            `json:"files"`
+	Entries []ArchiveEntry `json:"entries"`
+}
+
+// Threat returns the name of the threat found in the first infected file of the archive, if known.
+func (r *ArchiveReport) Threat() string {
+	for _, e := range r.Entries {
+		if e.Status == StatusInfected.String() && e.Threat != "" {
+			return e.Threat
+		}
+	}
+	return ""
+}
@@ -0,0 +1,29 @@
+package domain
+
+import "context"
+
+// CallbackURLMaxLength is the maximum length of a callback URL.
+const CallbackURLMaxLength = 2048
+
+type callbackURLKey struct{}
+
+// ContextWithCallbackURL returns a copy of ctx carrying the URL notified of the result of the analysis of an upload.
+func ContextWithCallbackURL(ctx context.Context, callbackURL string) context.Context {
+	return context.WithValue(ctx, callbackURLKey{}, callbackURL)
+}
+
+// CallbackURLFromContext returns the callback URL carried by ctx, or an empty string if there is none.
+func CallbackURLFromContext(ctx context.Context) string {
+	u, _ := ctx.Value(callbackURLKey{}).(string)
+	return u
+}
+
+// CallbackDelivery is a notification of a callback URL held by a transactional outbox, due once the document it
+// notifies has a final status.
+type CallbackDelivery struct {
+	ID          int64 // ID identifies the notification in the outbox.
+	DocumentID  string
+	Tenant      string
+	CallbackURL string
+	Attempts    int // Attempts is the number of times the notification was claimed, this time included.
+}
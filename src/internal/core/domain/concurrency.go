@@ -0,0 +1,8 @@
+package domain
+
+// Concurrency is the state of the limit on the number of analyses run at once.
+type Concurrency struct {
+	Limit   int `json:"limit"`   // Limit is the number of analyses run at once.
+	Running int `json:"running"` // Running is the number of analyses running, above Limit while it is being lowered.
+	Queued  int `json:"queued"`  // Queued is the number of analyses waiting for a slot.
+}
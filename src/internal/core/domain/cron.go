@@ -0,0 +1,160 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// cronMacros are the shorthands of the common cron expressions.
+var cronMacros = map[string]string{
+	"@yearly":   "0 0 1 1 *",
+	"@annually": "0 0 1 1 *",
+	"@monthly":  "0 0 1 * *",
+	"@weekly":   "0 0 * * 0",
+	"@daily":    "0 0 * * *",
+	"@midnight": "0 0 * * *",
+	"@hourly":   "0 * * * *",
+}
+
+// CronSchedule is a schedule given by a cron expression of five fields, minute, hour, day of month, month and day of
+// week, evaluated in UTC. Each field is a *, a value, a range a-b, or a comma-separated list of them, each optionally
+// followed by a step /n. The days of week go from 0, Sunday, to 7, Sunday again. As with cron, when both the day of
+// month and the day of week are restricted, a day matching either matches. The zero CronSchedule is not set.
+type CronSchedule struct {
+	expr                                  string
+	minutes, hours, days, months, weekday uint64 // the bits of the values matched by each field
+	anyDay, anyWeekday                    bool   // anyDay and anyWeekday report whether the fields start with *
+}
+
+// ErrInvalidCronExpression is returned when a cron expression cannot be parsed.
+var ErrInvalidCronExpression = errors.New("invalid cron expression")
+
+// ParseCronSchedule parses a cron expression of five fields, e.g. "0 3 * * *" every day at 03:00 UTC, or one of the
+// macros @yearly, @monthly, @weekly, @daily and @hourly. The expressions never matching, e.g. "0 0 30 2 *", are
+// refused.
+func ParseCronSchedule(expr string) (CronSchedule, error) {
+	s := CronSchedule{expr: strings.TrimSpace(expr)}
+	fields := strings.Fields(s.expr)
+	if m, ok := cronMacros[strings.ToLower(s.expr)]; ok {
+		fields = strings.Fields(m)
+	}
+	if len(fields) != 5 {
+		return CronSchedule{}, fmt.Errorf("%w: %q must have five fields, minute, hour, day of month, month and day of week", ErrInvalidCronExpression, expr)
+	}
+	s.anyDay, s.anyWeekday = strings.HasPrefix(fields[2], "*"), strings.HasPrefix(fields[4], "*")
+
+	var err error
+	for _, f := range []struct {
+		bits        *uint64
+		first, last int
+		name        string
+	}{
+		{&s.minutes, 0, 59, "minute"},
+		{&s.hours, 0, 23, "hour"},
+		{&s.days, 1, 31, "day of month"},
+		{&s.months, 1, 12, "month"},
+		{&s.weekday, 0, 7, "day of week"},
+	} {
+		if *f.bits, err = parseCronField(fields[0], f.first, f.last); err != nil {
+			return CronSchedule{}, fmt.Errorf("%w: %s %q: %v", ErrInvalidCronExpression, f.name, fields[0], err)
+		}
+		fields = fields[1:]
+	}
+	// 7 is Sunday as well
+	if s.weekday&(1<<7) != 0 {
+		s.weekday |= 1
+	}
+	if s.Next(time.Now()).IsZero() {
+		return CronSchedule{}, fmt.Errorf("%w: %q never matches", ErrInvalidCronExpression, expr)
+	}
+	return s, nil
+}
+
+// parseCronField returns the bits of the values from first to last matched by the field f.
+func parseCronField(f string, first, last int) (uint64, error) {
+	var bits uint64
+	for _, part := range strings.Split(f, ",") {
+		rng, step := part, 1
+		if i := strings.IndexByte(part, '/'); i >= 0 {
+			n, err := strconv.Atoi(part[i+1:])
+			if err != nil || n < 1 {
+				return 0, errors.New("the step must be a strictly positive number")
+			}
+			rng, step = part[:i], n
+		}
+
+		lo, hi := first, last
+		switch i := strings.IndexByte(rng, '-'); {
+		case rng == "*":
+		case i >= 0:
+			var err1, err2 error
+			lo, err1 = strconv.Atoi(rng[:i])
+			hi, err2 = strconv.Atoi(rng[i+1:])
+			if err1 != nil || err2 != nil {
+				return 0, fmt.Errorf("%q is not a range of numbers", rng)
+			}
+		default:
+			n, err := strconv.Atoi(rng)
+			if err != nil {
+				return 0, fmt.Errorf("%q is not a number", rng)
+			}
+			lo, hi = n, n
+			if step > 1 {
+				// as with cron, n/step stands for n-last/step
+				hi = last
+			}
+		}
+		if lo < first || hi > last || lo > hi {
+			return 0, fmt.Errorf("%q is out of the range %d-%d", rng, first, last)
+		}
+		for v := lo; v <= hi; v += step {
+			bits |= 1 << v
+		}
+	}
+	return bits, nil
+}
+
+// IsZero reports whether s is not set.
+func (s CronSchedule) IsZero() bool {
+	return s.expr == ""
+}
+
+// String returns the cron expression of s.
+func (s CronSchedule) String() string {
+	return s.expr
+}
+
+// Next returns the first time strictly after now matched by s, in UTC, or the zero time if there is none within five
+// years, e.g. for February 30th.
+func (s CronSchedule) Next(now time.Time) time.Time {
+	t := now.UTC().Truncate(time.Minute).Add(time.Minute)
+	limit := t.AddDate(5, 0, 0)
+	for t.Before(limit) {
+		switch {
+		case s.months&(1<<uint(t.Month())) == 0:
+			t = time.Date(t.Year(), t.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+		case !s.matchesDay(t):
+			t = time.Date(t.Year(), t.Month(), t.Day()+1, 0, 0, 0, 0, time.UTC)
+		case s.hours&(1<<uint(t.Hour())) == 0:
+			t = t.Truncate(time.Hour).Add(time.Hour)
+		case s.minutes&(1<<uint(t.Minute())) == 0:
+			t = t.Add(time.Minute)
+		default:
+			return t
+		}
+	}
+	return time.Time{}
+}
+
+// matchesDay reports whether the day of t is matched by the day of month and day of week fields of s.
+func (s CronSchedule) matchesDay(t time.Time) bool {
+	day := s.days&(1<<uint(t.Day())) != 0
+	weekday := s.weekday&(1<<uint(t.Weekday())) != 0
+	if s.anyDay || s.anyWeekday {
+		return day && weekday
+	}
+	return day || weekday
+}
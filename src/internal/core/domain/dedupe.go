@@ -0,0 +1,30 @@
+package domain
+
+import "fmt"
+
+// DedupePolicy decides what an upload of content held by an existing document of the same tenant does.
+type DedupePolicy string
+
+const (
+	// DedupeStrict returns the existing document holding the same content under the same tag, and creates a
+	// document with the known verdict, without analyzing it again, for the same content under another tag.
+	// It is the default policy.
+	DedupeStrict DedupePolicy = "strict"
+
+	// DedupeNewRecord always creates a new document, with the known verdict on the same content, if any, without
+	// analyzing it again.
+	DedupeNewRecord DedupePolicy = "new-record"
+
+	// DedupeRescan always creates a new document and analyzes its content, whatever was known about it.
+	DedupeRescan DedupePolicy = "rescan"
+)
+
+// ParseDedupePolicy returns the deduplication policy named name.
+func ParseDedupePolicy(name string) (DedupePolicy, error) {
+	switch p := DedupePolicy(name); p {
+	case DedupeStrict, DedupeNewRecord, DedupeRescan:
+		return p, nil
+	default:
+		return "", fmt.Errorf("unknown deduplication policy %q, expected strict, new-record or rescan", name)
+	}
+}
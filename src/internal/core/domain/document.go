@@ -1,6 +1,7 @@
 package domain
 
 import (
+	"context"
 	"time"
 )
 
@@ -16,16 +17,121 @@ const (
 
 	// StatusClean indicates that the document is clean (not infected).
 	StatusClean
+
+	// StatusTimeout indicates that the analysis of the document did not complete before its deadline,
+	// it will not be analyzed.
+	StatusTimeout
+
+	// StatusError indicates that the analysis of the document failed for good, e.g. because its data is missing or
+	// the analyzer kept failing, it will not be analyzed.
+	StatusError
+
+	// StatusTooLarge indicates that the document is larger than the antivirus accepts to analyze, it will not be
+	// analyzed.
+	StatusTooLarge
 )
 
+// AnalysisStatuses are all the analysis statuses, pending first.
+var AnalysisStatuses = []AnalysisStatus{StatusPending, StatusInfected, StatusClean, StatusTimeout, StatusError, StatusTooLarge}
+
+// String returns the name of an analysis status, as exposed by the API.
+func (s AnalysisStatus) String() string {
+	switch s {
+	case StatusClean:
+		return "clean"
+	case StatusInfected:
+		return "infected"
+	case StatusTimeout:
+		return "timeout"
+	case StatusError:
+		return "error"
+	case StatusTooLarge:
+		return "too_large"
+	default:
+		return "pending"
+	}
+}
+
+// ParseAnalysisStatus returns the analysis status named name, see AnalysisStatus.String.
+func ParseAnalysisStatus(name string) (AnalysisStatus, bool) {
+	switch name {
+	case "pending":
+		return StatusPending, true
+	case "infected":
+		return StatusInfected, true
+	case "clean":
+		return StatusClean, true
+	case "timeout":
+		return StatusTimeout, true
+	case "error":
+		return StatusError, true
+	case "too_large":
+		return StatusTooLarge, true
+	default:
+		return StatusPending, false
+	}
+}
+
+// IsVerdict reports whether s is the verdict of an antivirus, clean or infected.
+func (s AnalysisStatus) IsVerdict() bool {
+	return s == StatusClean || s == StatusInfected
+}
+
+// IsFailure reports whether s is the final status of a document whose analysis failed, timeout, error or too large.
+func (s AnalysisStatus) IsFailure() bool {
+	return s == StatusTimeout || s == StatusError || s == StatusTooLarge
+}
+
+// Source tells where the verdict of a document comes from.
+type Source string
+
+const (
+	// SourceUpload is the source of the documents uploaded to, and analyzed by, the service.
+	SourceUpload Source = "upload"
+
+	// SourceOnAccess is the source of the documents whose verdict was reported by an on-access
+	// scanning agent, such as clamonacc, for a file the service never received.
+	SourceOnAccess Source = "on_access"
+)
+
+// DefaultHashAlgo is the algorithm of the hash of the documents saved without one.
+const DefaultHashAlgo = "SHA-256"
+
 // Document represents a document with its attributes.
 type Document struct {
-	ID         string         `json:"id"`
-	Hash       string         `json:"hash"`
-	Tag        string         `json:"tag"`
-	Status     AnalysisStatus `json:"status"`
-	AnalyzedAt time.Time      `json:"analyzed_at"`
-	CreatedAt  time.Time      `json:"created_at"`
+	ID          string         `json:"id"`
+	Tenant      string         `json:"tenant"`
+	Hash        string         `json:"hash"`
+	HashAlgo    string         `json:"hash_algo"` // HashAlgo is the algorithm of Hash, DefaultHashAlgo when empty.
+	Tag         string         `json:"tag"`
+	Status      AnalysisStatus `json:"status"`
+	AnalyzedAt  time.Time      `json:"analyzed_at"`
+	Threat      string         `json:"threat"` // Threat is the name of the threat found in an infected document, if known.
+	CreatedAt   time.Time      `json:"created_at"`
+	Source      Source         `json:"source"`
+	Origin      string         `json:"origin"`       // Origin locates the file of an externally-sourced document, as host:path.
+	Sealed      string         `json:"sealed"`       // Sealed holds the encrypted original tag, origin and file name of a pseudonymized document.
+	FileName    string         `json:"file_name"`    // FileName is the original name of the uploaded file, if known.
+	Size        int64          `json:"size"`         // Size is the size of the file in bytes, zero if unknown.
+	ContentType string         `json:"content_type"` // ContentType is the media type detected from the content of the file.
+	Archive     *ArchiveReport `json:"archive"`      // Archive is the verdict on each file of an archive, nil unless it was extracted.
+	DeletedAt   time.Time      `json:"deleted_at"`   // DeletedAt is the date of the soft deletion of the document, zero unless deleted.
+	Labels      Labels         `json:"labels"`       // Labels are the key/value pairs set on the document at upload, if any.
+	Engines     []string       `json:"engines"`      // Engines are the names of the engines selected to analyze the document, if known.
+	// BinaryVersion is the version of the binary data of the document when it was saved, see port.BinaryPresigner,
+	// empty unless known.
+	BinaryVersion string `json:"binary_version"`
+}
+
+// IsDeleted reports whether d is soft-deleted: it is kept until purged, so that it can be restored meanwhile.
+func (d *Document) IsDeleted() bool {
+	return !d.DeletedAt.IsZero()
+}
+
+// AwaitsUpload reports whether d is a pending document whose binary data was not uploaded yet, see PresignedUpload.
+// Such a document has no hash until its upload is confirmed.
+func (d *Document) AwaitsUpload() bool {
+	return d.Status == StatusPending && d.Source == SourceUpload && d.Hash == ""
 }
 
 // NewDocument creates a new Document instance with the provided ID, hash and tag.
@@ -33,8 +139,23 @@ func NewDocument(id, hash, tag string) *Document {
 	return &Document{
 		ID:        id,
 		Hash:      hash,
+		HashAlgo:  DefaultHashAlgo,
 		Tag:       tag,
 		CreatedAt: time.Now(),
 		Status:    StatusPending,
+		Source:    SourceUpload,
 	}
 }
+
+type fileNameKey struct{}
+
+// ContextWithFileName returns a copy of ctx carrying the original name of the file being uploaded.
+func ContextWithFileName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, fileNameKey{}, name)
+}
+
+// FileNameFromContext returns the original file name carried by ctx, or an empty string if there is none.
+func FileNameFromContext(ctx context.Context) string {
+	name, _ := ctx.Value(fileNameKey{}).(string)
+	return name
+}
@@ -6,30 +6,44 @@ import (
 )
 
 type DocumentDTO struct {
-	ID         string `json:"id"`
-	Hash       string `json:"hash"`
-	HashAlgo   string `json:"hash_algo"`
-	Tag        string `json:"tag"`
-	Status     string `json:"analyse_status"`
-	AnalyzedAt string `json:"analyzed_at,omitempty"`
-	CreatedAt  string `json:"created_at"`
+	ID          string `json:"id"`
+	Tenant      string `json:"tenant,omitempty"`
+	Hash        string `json:"hash"`
+	HashAlgo    string `json:"hash_algo"`
+	Tag         string `json:"tag"`
+	Status      string `json:"analyse_status"`
+	AnalyzedAt  string `json:"analyzed_at,omitempty"`
+	Threat      string `json:"threat,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	Source      string `json:"source"`
+	Origin      string `json:"origin,omitempty"`
+	FileName    string `json:"file_name,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+	DeletedAt   string `json:"deleted_at,omitempty"`
+
+	Labels  map[string]string `json:"labels,omitempty"`
+	Engines []string          `json:"engines,omitempty"`
+
+	Archive *ArchiveReport `json:"archive,omitempty"`
 }
 
 func NewDocumentDTO(d *Document) *DocumentDTO {
 	var (
-		status     string
+		status     = d.Status.String()
 		analyzedAt string
 		createdAt  string
 		tag        string
+		source     = d.Source
+		hashAlgo   = d.HashAlgo
 	)
 
-	switch d.Status {
-	case StatusClean:
-		status = "clean"
-	case StatusInfected:
-		status = "infected"
-	default:
-		status = "pending"
+	if hashAlgo == "" {
+		hashAlgo = DefaultHashAlgo
+	}
+
+	if source == "" {
+		source = SourceUpload
 	}
 
 	if d.Status != StatusPending {
@@ -39,13 +53,92 @@ func NewDocumentDTO(d *Document) *DocumentDTO {
 	createdAt = d.CreatedAt.Format(time.RFC3339)
 	tag = html.EscapeString(d.Tag)
 
+	var deletedAt string
+	if d.IsDeleted() {
+		deletedAt = d.DeletedAt.Format(time.RFC3339)
+	}
+
+	var labels map[string]string
+	if len(d.Labels) > 0 {
+		labels = make(map[string]string, len(d.Labels))
+		for k, v := range d.Labels {
+			labels[k] = html.EscapeString(v)
+		}
+	}
+
 	return &DocumentDTO{
-		ID:         d.ID,
-		Hash:       d.Hash,
-		HashAlgo:   "SHA-256",
-		Tag:        tag,
-		Status:     status,
-		CreatedAt:  createdAt,
-		AnalyzedAt: analyzedAt,
+		ID:          d.ID,
+		Tenant:      d.Tenant,
+		Hash:        d.Hash,
+		HashAlgo:    hashAlgo,
+		Tag:         tag,
+		Status:      status,
+		CreatedAt:   createdAt,
+		AnalyzedAt:  analyzedAt,
+		Threat:      d.Threat,
+		Source:      string(source),
+		Origin:      html.EscapeString(d.Origin),
+		FileName:    html.EscapeString(d.FileName),
+		Size:        d.Size,
+		ContentType: d.ContentType,
+		DeletedAt:   deletedAt,
+		Labels:      labels,
+		Engines:     d.Engines,
+		Archive:     d.Archive,
+	}
+}
+
+type QuotaDTO struct {
+	Tenant           string `json:"tenant,omitempty"`
+	Day              string `json:"day"`
+	UploadsToday     int64  `json:"uploads_today"`
+	MaxUploadsPerDay int64  `json:"max_uploads_per_day,omitempty"`
+	StoredBytes      int64  `json:"stored_bytes"`
+	MaxStoredBytes   int64  `json:"max_stored_bytes,omitempty"`
+	MaxFileSize      int64  `json:"max_file_size,omitempty"`
+}
+
+func NewQuotaDTO(s *QuotaStatus) *QuotaDTO {
+	return &QuotaDTO{
+		Tenant:           s.Tenant,
+		Day:              s.Usage.Day.Format(time.DateOnly),
+		UploadsToday:     s.Usage.Uploads,
+		MaxUploadsPerDay: s.Quota.MaxUploadsPerDay,
+		StoredBytes:      s.Usage.StoredBytes,
+		MaxStoredBytes:   s.Quota.MaxStoredBytes,
+		MaxFileSize:      s.Quota.MaxFileSize,
+	}
+}
+
+type StatsDTO struct {
+	Documents struct {
+		Pending  int64 `json:"pending"`
+		Infected int64 `json:"infected"`
+		Clean    int64 `json:"clean"`
+		Failed   int64 `json:"failed"`
+	} `json:"documents"`
+	UploadsLast24h            int64   `json:"uploads_last_24h"`
+	AverageScanLatencySeconds float64 `json:"average_scan_latency_seconds"`
+	Purge                     struct {
+		Runs        int64  `json:"runs"`
+		Documents   int64  `json:"documents"`
+		LastPurgeAt string `json:"last_purge_at,omitempty"`
+	} `json:"purge"`
+}
+
+func NewStatsDTO(s *Stats) *StatsDTO {
+	dto := &StatsDTO{
+		UploadsLast24h:            s.Documents.UploadedSince,
+		AverageScanLatencySeconds: s.Documents.AverageScanLatency.Seconds(),
+	}
+	dto.Documents.Pending = s.Documents.Pending
+	dto.Documents.Infected = s.Documents.Infected
+	dto.Documents.Clean = s.Documents.Clean
+	dto.Documents.Failed = s.Documents.Failed
+	dto.Purge.Runs = s.Purge.Runs
+	dto.Purge.Documents = s.Purge.Documents
+	if !s.Purge.LastPurgeAt.IsZero() {
+		dto.Purge.LastPurgeAt = s.Purge.LastPurgeAt.Format(time.RFC3339)
 	}
+	return dto
 }
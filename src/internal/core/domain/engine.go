@@ -0,0 +1,57 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+const (
+	// EngineClamAV is the name of the antivirus engine of the service, clamd.
+	EngineClamAV = "clamav"
+
+	// EngineAll selects all the engines of the service, the default.
+	EngineAll = "all"
+)
+
+// engineNamePattern matches the names of the engines.
+var engineNamePattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,31}$`)
+
+// ValidEngineName reports whether name can name an engine: up to 32 lowercase letters, digits, '_' and '-', other
+// than EngineAll.
+func ValidEngineName(name string) bool {
+	return name != EngineAll && engineNamePattern.MatchString(name)
+}
+
+// ParseEngines returns the names of the engines of a comma-separated list such as "clamav,yara", without duplicates,
+// or nil for EngineAll.
+func ParseEngines(s string) ([]string, error) {
+	var engines []string
+	for _, name := range strings.Split(s, ",") {
+		name = strings.ToLower(strings.TrimSpace(name))
+		switch {
+		case name == EngineAll:
+			return nil, nil
+		case !ValidEngineName(name):
+			return nil, fmt.Errorf("invalid engine name %q", name)
+		case !slices.Contains(engines, name):
+			engines = append(engines, name)
+		}
+	}
+	return engines, nil
+}
+
+type enginesKey struct{}
+
+// ContextWithEngines returns a copy of ctx carrying the names of the engines selected to analyze a document.
+func ContextWithEngines(ctx context.Context, engines []string) context.Context {
+	return context.WithValue(ctx, enginesKey{}, engines)
+}
+
+// EnginesFromContext returns the names of the engines carried by ctx, or nil, all the engines, if there are none.
+func EnginesFromContext(ctx context.Context) []string {
+	engines, _ := ctx.Value(enginesKey{}).([]string)
+	return engines
+}
@@ -0,0 +1,48 @@
+package domain
+
+import "time"
+
+// DocumentEventType is the kind of change of a document recorded by a DocumentEvent.
+type DocumentEventType string
+
+const (
+	EventCreated         DocumentEventType = "created"          // EventCreated records the saving of a new document.
+	EventStatusChanged   DocumentEventType = "status_changed"   // EventStatusChanged records a new analysis status or threat.
+	EventContentUploaded DocumentEventType = "content_uploaded" // EventContentUploaded records the content of a presigned upload.
+	EventDeleted         DocumentEventType = "deleted"          // EventDeleted records the soft deletion of a document.
+	EventRestored        DocumentEventType = "restored"         // EventRestored records the restoration of a deleted document.
+	EventUpdated         DocumentEventType = "updated"          // EventUpdated records any other change, such as an archive report.
+)
+
+// DocumentEvent is a change of a document, as recorded by the append-only log of the changes of the documents, along
+// with the state of the document right after it. The events of a document are ordered by their sequence number.
+type DocumentEvent struct {
+	Seq        int64 // Seq is the position of the event in the log, increasing with the time of the change.
+	DocumentID string
+	Tenant     string
+	Type       DocumentEventType
+	Status     AnalysisStatus
+	Threat     string
+	Hash       string
+	OccurredAt time.Time
+}
+
+type DocumentEventDTO struct {
+	Seq        int64  `json:"seq"`
+	Type       string `json:"type"`
+	Status     string `json:"analyse_status"`
+	Threat     string `json:"threat,omitempty"`
+	Hash       string `json:"hash,omitempty"`
+	OccurredAt string `json:"occurred_at"`
+}
+
+func NewDocumentEventDTO(e DocumentEvent) DocumentEventDTO {
+	return DocumentEventDTO{
+		Seq:        e.Seq,
+		Type:       string(e.Type),
+		Status:     e.Status.String(),
+		Threat:     e.Threat,
+		Hash:       e.Hash,
+		OccurredAt: e.OccurredAt.Format(time.RFC3339),
+	}
+}
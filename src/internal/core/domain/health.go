@@ -0,0 +1,32 @@
+package domain
+
+import "time"
+
+// Health statuses of the service and of its dependencies.
+const (
+	HealthUp   = "up"
+	HealthDown = "down"
+)
+
+// Names of the dependencies of the service reported by its health.
+const (
+	DependencyBinaryRepository   = "binary_repository"
+	DependencyDocumentRepository = "document_repository"
+	DependencyAntivirusAnalyzer  = "antivirus_analyzer"
+	DependencyQuotaRepository    = "quota_repository"
+)
+
+// DependencyHealth is the outcome of the check of a dependency of the service.
+type DependencyHealth struct {
+	Name      string  `json:"name"`
+	Status    string  `json:"status"`
+	LatencyMS float64 `json:"latency_ms"` // LatencyMS is the duration of the check, in milliseconds.
+	Error     string  `json:"error,omitempty"`
+}
+
+// Health is the health of the service: it is up when all its dependencies are.
+type Health struct {
+	Status       string             `json:"status"`
+	CheckedAt    time.Time          `json:"checked_at"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+}
@@ -0,0 +1,39 @@
+package domain
+
+// ImageLimits bounds the unpacking of the layers of a container image, a limit is not enforced when it is zero.
+type ImageLimits struct {
+	MaxLayers       int   // MaxLayers is the maximum number of layers of an image.
+	MaxFiles        int   // MaxFiles is the maximum number of files of all the layers of an image.
+	MaxFileSize     int64 // MaxFileSize is the maximum size of a file, in bytes.
+	MaxUnpackedSize int64 // MaxUnpackedSize is the maximum size of all the files of an image, in bytes.
+}
+
+// Kinds of the findings of the analysis of a container image.
+const (
+	FindingInfected     = "infected"      // the file is infected
+	FindingUnsafePath   = "unsafe_path"   // the path of the entry escapes the root of the filesystem
+	FindingEscapingLink = "escaping_link" // the target of the link escapes the root of the filesystem
+	FindingShortcut     = "shortcut"      // the file is a Windows shortcut
+)
+
+// ImageFinding is a file of a layer worth reporting.
+type ImageFinding struct {
+	Path   string `json:"path"`
+	Kind   string `json:"kind"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// LayerReport is the outcome of the analysis of a layer of a container image.
+type LayerReport struct {
+	Digest   string         `json:"digest"`
+	Status   string         `json:"status"` // Status is infected if a file of the layer is, clean otherwise.
+	Files    int            `json:"files"`  // Files is the number of files analyzed.
+	Findings []ImageFinding `json:"findings,omitempty"`
+}
+
+// ImageReport is the outcome of the analysis of a container image, its layers are in the order of the manifests.
+type ImageReport struct {
+	Status string        `json:"status"` // Status is infected if a layer is, clean otherwise.
+	Files  int           `json:"files"`
+	Layers []LayerReport `json:"layers"`
+}
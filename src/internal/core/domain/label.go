@@ -0,0 +1,89 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"unicode"
+	"unicode/utf8"
+)
+
+const (
+	// LabelsMaxCount is the maximum number of labels of a document.
+	LabelsMaxCount = 32
+
+	// LabelKeyMaxLength is the maximum length of the key of a label.
+	LabelKeyMaxLength = 63
+
+	// LabelValueMaxLength is the maximum length in bytes of the value of a label.
+	LabelValueMaxLength = 255
+)
+
+// labelKeyPattern matches the valid label keys: lowercase letters, digits, '.', '_' and '-', starting with a letter
+// or a digit.
+var labelKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9._-]*$`)
+
+// Labels are the key/value pairs set on a document at upload, so that its verdict can be routed downstream and the
+// documents listed by label. Unlike the tag, they are stored as given, once validated, and escaped on output.
+type Labels map[string]string
+
+// Validate checks the number of labels, their keys against labelKeyPattern and their values, valid UTF-8 text
+// without control characters, against their maximum lengths.
+func (l Labels) Validate() error {
+	if len(l) > LabelsMaxCount {
+		return fmt.Errorf("at most %d labels are allowed, got %d", LabelsMaxCount, len(l))
+	}
+	for k, v := range l {
+		if len(k) > LabelKeyMaxLength || !labelKeyPattern.MatchString(k) {
+			return fmt.Errorf("invalid label key %q, expected at most %d lowercase letters, digits, '.', '_' or '-'", k, LabelKeyMaxLength)
+		}
+		if len(v) > LabelValueMaxLength {
+			return fmt.Errorf("the value of label %q exceeds %d bytes", k, LabelValueMaxLength)
+		}
+		if !utf8.ValidString(v) {
+			return fmt.Errorf("the value of label %q is not valid UTF-8", k)
+		}
+		for _, r := range v {
+			if unicode.IsControl(r) {
+				return fmt.Errorf("the value of label %q contains control characters", k)
+			}
+		}
+	}
+	return nil
+}
+
+// Contains reports whether l holds every label of subset with the same value.
+func (l Labels) Contains(subset Labels) bool {
+	for k, v := range subset {
+		if w, ok := l[k]; !ok || w != v {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseLabels returns the labels of the JSON object s, whose values are strings, once validated.
+func ParseLabels(s string) (Labels, error) {
+	var l Labels
+	if err := json.Unmarshal([]byte(s), &l); err != nil {
+		return nil, fmt.Errorf("expected a JSON object of strings: %v", err)
+	}
+	if err := l.Validate(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+type labelsKey struct{}
+
+// ContextWithLabels returns a copy of ctx carrying the labels of the document being uploaded.
+func ContextWithLabels(ctx context.Context, labels Labels) context.Context {
+	return context.WithValue(ctx, labelsKey{}, labels)
+}
+
+// LabelsFromContext returns the labels carried by ctx, or nil if there are none.
+func LabelsFromContext(ctx context.Context) Labels {
+	l, _ := ctx.Value(labelsKey{}).(Labels)
+	return l
+}
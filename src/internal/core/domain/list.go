@@ -0,0 +1,119 @@
+package domain
+
+import (
+	"encoding/base64"
+	"errors"
+	"slices"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultListLimit is the number of documents listed when no limit is given.
+	DefaultListLimit = 100
+
+	// MaxListLimit is the maximum number of documents listed at once.
+	MaxListLimit = 1000
+)
+
+// DocumentFilter selects the documents of a tenant to list, the soft-deleted ones are never listed.
+// Its zero value selects every document.
+type DocumentFilter struct {
+	// Labels are the labels the documents must all carry, with the same values.
+	Labels Labels
+
+	// Statuses are the analysis statuses of the documents, any status if empty.
+	Statuses []AnalysisStatus
+
+	// CreatedAfter and CreatedBefore bound the creation date of the documents, included and excluded respectively,
+	// unless zero.
+	CreatedAfter  time.Time
+	CreatedBefore time.Time
+
+	// AnalyzedAfter and AnalyzedBefore bound the analysis date of the documents, as CreatedAfter and CreatedBefore.
+	// The pending documents are left out when either is set.
+	AnalyzedAfter  time.Time
+	AnalyzedBefore time.Time
+
+	// TagPrefix is the prefix of the tag of the documents, as stored.
+	TagPrefix string
+
+	// Limit is the maximum number of documents listed, the most recent first.
+	Limit int
+
+	// After is the position of the last document of the previous page of the listing, whose next page lists the
+	// documents following it, the first page if it is zero.
+	After DocumentCursor
+}
+
+// Matches reports whether doc is selected by f, regardless of its tenant and its deletion.
+func (f DocumentFilter) Matches(doc *Document) bool {
+	analyzed := !f.AnalyzedAfter.IsZero() || !f.AnalyzedBefore.IsZero()
+	switch {
+	case !doc.Labels.Contains(f.Labels),
+		len(f.Statuses) > 0 && !slices.Contains(f.Statuses, doc.Status),
+		!inRange(doc.CreatedAt, f.CreatedAfter, f.CreatedBefore),
+		analyzed && (doc.Status == StatusPending || !inRange(doc.AnalyzedAt, f.AnalyzedAfter, f.AnalyzedBefore)),
+		!strings.HasPrefix(doc.Tag, f.TagPrefix):
+		return false
+	}
+	return true
+}
+
+// inRange reports whether t is within [after, before), each bound ignored when zero.
+func inRange(t, after, before time.Time) bool {
+	return (after.IsZero() || !t.Before(after)) && (before.IsZero() || t.Before(before))
+}
+
+// DocumentCursor is the position of a document in a listing of the documents, the most recent first, those created at
+// the same time being sorted by descending ID, so that the next pages of the listing are read from where the previous
+// one stopped, rather than by skipping the documents listed already, and are not shifted by the documents uploaded
+// meanwhile.
+type DocumentCursor struct {
+	CreatedAt time.Time
+	ID        string
+}
+
+// ErrInvalidCursor is returned when a cursor cannot be parsed.
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// CursorOf returns the position of doc in a listing.
+func CursorOf(doc *Document) DocumentCursor {
+	return DocumentCursor{CreatedAt: doc.CreatedAt, ID: doc.ID}
+}
+
+// ParseDocumentCursor parses a cursor encoded by DocumentCursor.String.
+func ParseDocumentCursor(s string) (DocumentCursor, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return DocumentCursor{}, ErrInvalidCursor
+	}
+	date, ID, found := strings.Cut(string(b), " ")
+	createdAt, err := time.Parse(time.RFC3339Nano, date)
+	if !found || err != nil || ID == "" {
+		return DocumentCursor{}, ErrInvalidCursor
+	}
+	return DocumentCursor{CreatedAt: createdAt, ID: ID}, nil
+}
+
+// IsZero reports whether c is the zero cursor, preceding the first document of a listing.
+func (c DocumentCursor) IsZero() bool {
+	return c.CreatedAt.IsZero() && c.ID == ""
+}
+
+// Precedes reports whether doc follows c in a listing: it is older, or was created at the same time with a smaller ID.
+// Every document follows the zero cursor.
+func (c DocumentCursor) Precedes(doc *Document) bool {
+	if c.IsZero() {
+		return true
+	}
+	if d := doc.CreatedAt.Compare(c.CreatedAt); d != 0 {
+		return d < 0
+	}
+	return doc.ID < c.ID
+}
+
+// String encodes c as an opaque token, in base64url.
+func (c DocumentCursor) String() string {
+	return base64.RawURLEncoding.EncodeToString([]byte(c.CreatedAt.UTC().Format(time.RFC3339Nano) + " " + c.ID))
+}
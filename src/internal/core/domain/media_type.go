@@ -0,0 +1,40 @@
+package domain
+
+import "strings"
+
+// MediaTypePolicy restricts the media types of the documents that can be uploaded, as detected from their content.
+// A media type is matched by a pattern equal to it, e.g. "application/pdf", or to its type followed by "/*",
+// e.g. "image/*", ignoring case and parameters.
+type MediaTypePolicy struct {
+	Allow []string // Allow lists the patterns of the accepted media types, any media type is accepted if empty.
+	Deny  []string // Deny lists the patterns of the rejected media types, it prevails over Allow.
+}
+
+// IsZero reports whether the policy accepts every media type.
+func (p MediaTypePolicy) IsZero() bool {
+	return len(p.Allow) == 0 && len(p.Deny) == 0
+}
+
+// Allows reports whether the policy accepts the media type contentType.
+func (p MediaTypePolicy) Allows(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.ToLower(strings.TrimSpace(mediaType))
+	if matchesMediaType(p.Deny, mediaType) {
+		return false
+	}
+	return len(p.Allow) == 0 || matchesMediaType(p.Allow, mediaType)
+}
+
+// matchesMediaType reports whether mediaType, in lowercase, is matched by one of the patterns.
+func matchesMediaType(patterns []string, mediaType string) bool {
+	for _, pattern := range patterns {
+		pattern = strings.ToLower(pattern)
+		if pattern == mediaType {
+			return true
+		}
+		if prefix, ok := strings.CutSuffix(pattern, "/*"); ok && strings.HasPrefix(mediaType, prefix+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,20 @@
+package domain
+
+// MetricKind is the kind of a metric, named as in the Prometheus exposition format.
+type MetricKind string
+
+const (
+	// MetricGauge is a metric whose value goes up and down.
+	MetricGauge MetricKind = "gauge"
+
+	// MetricCounter is a metric whose value only goes up, until the service restarts.
+	MetricCounter MetricKind = "counter"
+)
+
+// Metric is a measure of the service or of one of its dependencies, at the time it is reported.
+type Metric struct {
+	Name  string
+	Help  string
+	Kind  MetricKind
+	Value float64
+}
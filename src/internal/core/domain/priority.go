@@ -0,0 +1,52 @@
+package domain
+
+import "context"
+
+// Priority is the priority of the analysis of a document: the pending analyses of a higher priority are run first.
+type Priority int
+
+const (
+	// PriorityInteractive is the priority of the uploads of users waiting for their verdict, and the default one.
+	PriorityInteractive Priority = iota
+
+	// PriorityBatch is the priority of bulk uploads, such as imports, analyzed when no interactive upload is waiting.
+	PriorityBatch
+
+	// PriorityLevels is the number of priorities.
+	PriorityLevels = int(PriorityBatch) + 1
+)
+
+// String returns the name of a priority, as exposed by the API.
+func (p Priority) String() string {
+	if p == PriorityBatch {
+		return "batch"
+	}
+	return "interactive"
+}
+
+// ParsePriority returns the priority named name, see Priority.String.
+func ParsePriority(name string) (Priority, bool) {
+	switch name {
+	case "interactive":
+		return PriorityInteractive, true
+	case "batch":
+		return PriorityBatch, true
+	default:
+		return PriorityInteractive, false
+	}
+}
+
+type priorityKey struct{}
+
+// ContextWithPriority returns a copy of ctx carrying the given priority.
+func ContextWithPriority(ctx context.Context, p Priority) context.Context {
+	return context.WithValue(ctx, priorityKey{}, p)
+}
+
+// PriorityFromContext returns the priority carried by ctx, or PriorityInteractive if there is none.
+func PriorityFromContext(ctx context.Context) Priority {
+	if p, ok := ctx.Value(priorityKey{}).(Priority); ok {
+		return p
+	}
+	return PriorityInteractive
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "time"
+
+// PurgeOptions are the options of an on-demand purge.
+type PurgeOptions struct {
+	// Before is the cutoff date: only the documents created before it are purged.
+	Before time.Time
+
+	// Statuses restricts the purge to the documents having one of these statuses, all the analyzed
+	// documents are purged when it is empty. Pending documents are only purged when requested.
+	Statuses []AnalysisStatus
+}
+
+// PurgeReport is the outcome of an on-demand purge.
+type PurgeReport struct {
+	Before    time.Time `json:"before"`
+	Statuses  []string  `json:"statuses,omitempty"`
+	Documents int64     `json:"documents"` // Documents is the number of documents removed.
+	Binaries  int64     `json:"binaries"`  // Binaries is the number of binary data removed along with their documents.
+}
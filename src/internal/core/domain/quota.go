@@ -0,0 +1,30 @@
+package domain
+
+import "time"
+
+// Quota holds the limits applied to the uploads of a tenant. A zero limit means unlimited.
+type Quota struct {
+	MaxUploadsPerDay int64 // MaxUploadsPerDay is the number of uploads accepted per day (UTC).
+	MaxStoredBytes   int64 // MaxStoredBytes is the number of bytes of binary data the tenant can hold at once.
+	MaxFileSize      int64 // MaxFileSize is the size in bytes of the largest file the tenant can upload.
+}
+
+// QuotaUsage is the consumption of a tenant's quota.
+type QuotaUsage struct {
+	Day         time.Time // Day is the day (UTC) Uploads is counted for.
+	Uploads     int64     // Uploads is the number of uploads accepted during Day.
+	StoredBytes int64     // StoredBytes is the number of bytes of binary data currently held for the tenant.
+}
+
+// QuotaStatus is the quota of a tenant along with its usage.
+type QuotaStatus struct {
+	Tenant string
+	Quota  Quota
+	Usage  QuotaUsage
+}
+
+// QuotaDay returns the day, in UTC, t is counted for by daily quotas.
+func QuotaDay(t time.Time) time.Time {
+	y, m, d := t.UTC().Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+}
@@ -0,0 +1,50 @@
+package domain
+
+import "time"
+
+// MismatchKind is the kind of inconsistency found between the documents and their binary data.
+type MismatchKind string
+
+const (
+	// MismatchDocumentWithoutBinary is a pending document whose binary data is missing: it can never be analyzed.
+	MismatchDocumentWithoutBinary MismatchKind = "document_without_binary"
+
+	// MismatchBinaryWithoutDocument is binary data that no document refers to.
+	MismatchBinaryWithoutDocument MismatchKind = "binary_without_document"
+
+	// MismatchBinaryOfAnalyzedDocument is binary data kept after the analysis of its document.
+	MismatchBinaryOfAnalyzedDocument MismatchKind = "binary_of_analyzed_document"
+
+	// MismatchHash is binary data whose hash differs from the hash of its document.
+	MismatchHash MismatchKind = "hash_mismatch"
+)
+
+// Mismatch is an inconsistency found by a reconciliation.
+type Mismatch struct {
+	Kind     MismatchKind `json:"kind"`
+	Tenant   string       `json:"tenant,omitempty"`
+	ID       string       `json:"id"`
+	Detail   string       `json:"detail,omitempty"`
+	Action   string       `json:"action,omitempty"`    // Action is the fix applied, if any.
+	FixError string       `json:"fix_error,omitempty"` // FixError reports why the fix failed.
+}
+
+// ReconcileOptions are the options of a reconciliation.
+type ReconcileOptions struct {
+	// Fix applies the fixes of the mismatches found, instead of only reporting them.
+	Fix bool
+
+	// MinAge is the age under which documents and binary data are skipped, as an upload in progress
+	// may have saved its binary data but not its document yet.
+	MinAge time.Duration
+}
+
+// ReconcileReport is the outcome of a reconciliation of the documents with their binary data.
+type ReconcileReport struct {
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt time.Time  `json:"finished_at"`
+	Fix        bool       `json:"fix"`
+	Documents  int        `json:"documents"` // Documents is the number of pending documents checked.
+	Binaries   int        `json:"binaries"`  // Binaries is the number of binary data checked.
+	Mismatches []Mismatch `json:"mismatches"`
+}
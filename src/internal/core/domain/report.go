@@ -0,0 +1,86 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// ReportPeriod is the period covered by a summary report, and the interval between two scheduled reports.
+type ReportPeriod string
+
+const (
+	// ReportDaily reports on the last 24 hours every day.
+	ReportDaily ReportPeriod = "daily"
+
+	// ReportWeekly reports on the last 7 days every week.
+	ReportWeekly ReportPeriod = "weekly"
+)
+
+// ParseReportPeriod returns the report period named s, daily or weekly.
+func ParseReportPeriod(s string) (ReportPeriod, error) {
+	switch p := ReportPeriod(strings.ToLower(strings.TrimSpace(s))); p {
+	case ReportDaily, ReportWeekly:
+		return p, nil
+	}
+	return "", fmt.Errorf("unknown report period %q, expected daily or weekly", s)
+}
+
+// Duration returns the duration of the period.
+func (p ReportPeriod) Duration() time.Duration {
+	if p == ReportWeekly {
+		return 7 * 24 * time.Hour
+	}
+	return 24 * time.Hour
+}
+
+// ReportSchedule is the schedule of the summary reports: every day, or every week on Weekday, at the time of day At,
+// in UTC.
+type ReportSchedule struct {
+	Period  ReportPeriod
+	At      time.Duration // At is the time of day of the reports, as the duration since midnight.
+	Weekday time.Weekday  // Weekday is the day of the weekly reports.
+}
+
+// Next returns the time of the first report strictly after now.
+func (s ReportSchedule) Next(now time.Time) time.Time {
+	now = now.UTC()
+	next := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC).Add(s.At)
+	if s.Period == ReportWeekly {
+		next = next.AddDate(0, 0, (int(s.Weekday)-int(next.Weekday())+7)%7)
+	}
+	for !next.After(now) {
+		next = next.Add(s.Period.Duration())
+	}
+	return next
+}
+
+// ThreatCount is the number of documents found infected by a threat.
+type ThreatCount struct {
+	Name  string `json:"name"`
+	Count int64  `json:"count"`
+}
+
+// TenantCount is the number of documents uploaded by a tenant.
+type TenantCount struct {
+	Tenant  string `json:"tenant"`
+	Uploads int64  `json:"uploads"`
+}
+
+// SummaryReport summarizes the documents of all the tenants uploaded between From, included, and To, excluded.
+type SummaryReport struct {
+	Period   ReportPeriod `json:"period,omitempty"`
+	From     time.Time    `json:"from"`
+	To       time.Time    `json:"to"`
+	Total    int64        `json:"total"`
+	Pending  int64        `json:"pending"`
+	Infected int64        `json:"infected"`
+	Clean    int64        `json:"clean"`
+	Failed   int64        `json:"failed"` // Failed is the number of documents whose analysis failed for good.
+
+	// InfectedRate is the share of the infected documents among the documents with a verdict, zero if there is none.
+	InfectedRate float64 `json:"infected_rate"`
+
+	TopThreats     []ThreatCount `json:"top_threats"`     // TopThreats are the most frequent threats, the most frequent first.
+	BusiestTenants []TenantCount `json:"busiest_tenants"` // BusiestTenants are the tenants uploading the most, the busiest first.
+}
@@ -0,0 +1,18 @@
+package domain
+
+import "time"
+
+// RequeueOptions are the options of a requeue of the pending documents.
+type RequeueOptions struct {
+	// MinAge is the time a document must have been pending for to be requeued, as its analysis may still be waiting
+	// for a slot or running meanwhile.
+	MinAge time.Duration
+}
+
+// RequeueReport is the outcome of a requeue of the pending documents.
+type RequeueReport struct {
+	Before   time.Time `json:"before"`   // Before is the date the requeued documents were created before.
+	Pending  int       `json:"pending"`  // Pending is the number of documents pending since before Before.
+	Requeued int       `json:"requeued"` // Requeued is the number of documents whose analysis was triggered again.
+	Missing  int       `json:"missing"`  // Missing is the number of documents left pending without binary data.
+}
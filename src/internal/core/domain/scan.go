@@ -0,0 +1,11 @@
+package domain
+
+// ScanReport is the outcome of the analysis of a file scanned on the fly, neither stored nor recorded as a document.
+type ScanReport struct {
+	Status  string         `json:"status"` // Status is infected if an engine found a threat, clean otherwise.
+	Threat  string         `json:"threat,omitempty"`
+	Hash    string         `json:"hash"` // Hash is the hash of the file, with the hash algorithm of the service.
+	Size    int64          `json:"size"`
+	Engines []string       `json:"engines"` // Engines lists the engines selected to analyze the file.
+	Archive *ArchiveReport `json:"archive,omitempty"`
+}
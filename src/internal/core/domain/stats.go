@@ -0,0 +1,26 @@
+package domain
+
+import "time"
+
+// DocumentStats are aggregate statistics on the documents of a tenant.
+type DocumentStats struct {
+	Pending            int64         // Pending is the number of documents waiting for their analysis.
+	Infected           int64         // Infected is the number of documents found infected.
+	Clean              int64         // Clean is the number of documents found clean.
+	Failed             int64         // Failed is the number of documents whose analysis failed for good, see AnalysisStatus.IsFailure.
+	UploadedSince      int64         // UploadedSince is the number of documents uploaded since the requested date.
+	AverageScanLatency time.Duration // AverageScanLatency is the average time between the upload and the analysis of a document.
+}
+
+// PurgeStats are the totals of the purges run by the service since it started.
+type PurgeStats struct {
+	Runs        int64     // Runs is the number of purges run.
+	Documents   int64     // Documents is the number of documents removed.
+	LastPurgeAt time.Time // LastPurgeAt is the time of the last purge, zero if none ran yet.
+}
+
+// Stats are the statistics reported by the service.
+type Stats struct {
+	Documents DocumentStats
+	Purge     PurgeStats
+}
@@ -0,0 +1,14 @@
+package domain
+
+import "time"
+
+// StatusChange is a change of the analysis status of a document, pushed to the clients awaiting its verdict.
+// A change without ID tells that changes may have been missed, the clients must then check the status of
+// the documents they await.
+type StatusChange struct {
+	ID         string
+	Tenant     string
+	Status     AnalysisStatus
+	AnalyzedAt time.Time
+	Threat     string // Threat is the name of the threat found in an infected document, if known.
+}
@@ -0,0 +1,21 @@
+package domain
+
+import "context"
+
+// DefaultTenant is the tenant owning the documents when multi-tenancy is not configured.
+const DefaultTenant = ""
+
+type tenantKey struct{}
+
+// ContextWithTenant returns a copy of ctx carrying the given tenant.
+func ContextWithTenant(ctx context.Context, tenant string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenant)
+}
+
+// TenantFromContext returns the tenant carried by ctx, or DefaultTenant if there is none.
+func TenantFromContext(ctx context.Context) string {
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		return tenant
+	}
+	return DefaultTenant
+}
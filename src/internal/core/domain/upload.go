@@ -0,0 +1,20 @@
+package domain
+
+import "time"
+
+// PresignedUpload is a document awaiting its binary data, which the client uploads directly to the binary repository
+// with a request of the given method to URL, before ExpiresAt, then confirms.
+type PresignedUpload struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	Method    string    `json:"method"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// PresignedDownload is a URL the retained binary data of a clean document can be downloaded from directly,
+// with a GET request, before ExpiresAt.
+type PresignedDownload struct {
+	ID        string    `json:"id"`
+	URL       string    `json:"url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
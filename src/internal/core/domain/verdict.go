@@ -0,0 +1,37 @@
+package domain
+
+import "time"
+
+// ExternalVerdict is the verdict on a file reported by an on-access scanning agent.
+type ExternalVerdict struct {
+	Hash      string         // Hash is the SHA-256 hash of the file.
+	Host      string         // Host is the name of the host holding the file.
+	Path      string         // Path is the path of the file on its host.
+	Status    AnalysisStatus // Status is the verdict, clean or infected.
+	Signature string         // Signature is the name of the threat found, if any.
+	ScannedAt time.Time      // ScannedAt is the time of the scan.
+}
+
+// Origin returns the origin of the documents holding the verdict, see Document.Origin.
+func (v ExternalVerdict) Origin() string {
+	return v.Host + ":" + v.Path
+}
+
+// CachedVerdict is the verdict of an analysis kept in a verdict cache, given to the uploads of the same content.
+type CachedVerdict struct {
+	Status     AnalysisStatus // Status is the verdict, clean or infected.
+	Threat     string         // Threat is the name of the threat found, if any.
+	AnalyzedAt time.Time      // AnalyzedAt is the time of the analysis.
+}
+
+// Reputation is the verdict of a reputation source on content it knows by hash.
+type Reputation struct {
+	Status AnalysisStatus // Status is the verdict, clean or infected, when the source is confident, pending otherwise.
+	Threat string         // Threat is the name of the threat known to the source, if any.
+	Source string         // Source is the name of the reputation source.
+}
+
+// IsConfident reports whether the source is confident enough in its verdict for the content not to be analyzed.
+func (r Reputation) IsConfident() bool {
+	return r.Status == StatusClean || r.Status == StatusInfected
+}
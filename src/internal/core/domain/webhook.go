@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+const (
+	// WebhookURLMaxLength is the maximum length of the URL of a webhook.
+	WebhookURLMaxLength = 2048
+
+	// WebhookSecretMinLength and WebhookSecretMaxLength bound the length in bytes of the secret signing the deliveries
+	// of a webhook.
+	WebhookSecretMinLength = 32
+	WebhookSecretMaxLength = 255
+)
+
+// WebhookEvent is a kind of event delivered to the webhooks subscribing to it.
+type WebhookEvent string
+
+const (
+	WebhookEventAlert  WebhookEvent = "alert"  // WebhookEventAlert delivers the alerts raised on the infected rate.
+	WebhookEventReport WebhookEvent = "report" // WebhookEventReport delivers the scheduled summary reports.
+)
+
+// WebhookEvents are the kinds of events a webhook may subscribe to.
+var WebhookEvents = []WebhookEvent{WebhookEventAlert, WebhookEventReport}
+
+// Webhook is a subscription of a URL to some kinds of events, managed by the operators through the admin API rather
+// than configured by the environment. The events are posted to its URL while it is active, and signed with its secret
+// unless it is empty.
+type Webhook struct {
+	ID        string
+	URL       string
+	Events    []WebhookEvent
+	Secret    string
+	Active    bool
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Subscribes reports whether the events of kind e are posted to w.
+func (w *Webhook) Subscribes(e WebhookEvent) bool {
+	return w.Active && slices.Contains(w.Events, e)
+}
+
+// WebhookError reports the field of a webhook which is invalid, and why.
+type WebhookError struct {
+	Field   string // Field is the name of the field, url, events or secret.
+	Message string
+}
+
+func (e *WebhookError) Error() string {
+	return e.Field + ": " + e.Message
+}
+
+// Validate checks the URL of w, an absolute http or https URL without credentials, its events, at least one known kind
+// given once, and its secret, empty or within the bounds of its length. It returns a *WebhookError for the first
+// invalid field. Unlike the callback URLs, the URL of a webhook is chosen by an operator and may target a private
+// address.
+func (w *Webhook) Validate() error {
+	u, err := url.Parse(w.URL)
+	switch {
+	case len(w.URL) > WebhookURLMaxLength:
+		return &WebhookError{"url", fmt.Sprintf("the URL exceeds %d bytes", WebhookURLMaxLength)}
+	case err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "":
+		return &WebhookError{"url", "expected an absolute http or https URL"}
+	case u.User != nil:
+		return &WebhookError{"url", "the URL must not carry credentials"}
+	}
+	if len(w.Events) == 0 {
+		return &WebhookError{"events", "at least one event is required"}
+	}
+	for i, e := range w.Events {
+		if !slices.Contains(WebhookEvents, e) {
+			return &WebhookError{"events", fmt.Sprintf("unknown event %q, expected one of %v", e, WebhookEvents)}
+		}
+		if slices.Contains(w.Events[:i], e) {
+			return &WebhookError{"events", fmt.Sprintf("event %q is given more than once", e)}
+		}
+	}
+	if w.Secret != "" && (len(w.Secret) < WebhookSecretMinLength || len(w.Secret) > WebhookSecretMaxLength) {
+		return &WebhookError{"secret", fmt.Sprintf("the secret must be %d to %d bytes long", WebhookSecretMinLength, WebhookSecretMaxLength)}
+	}
+	return nil
+}
+
+// WebhookUpdate is a change of a webhook, leaving its fields which are nil unchanged.
+type WebhookUpdate struct {
+	URL    *string
+	Events []WebhookEvent
+	Secret *string // Secret replaces the secret of the webhook, removing it if empty.
+	Active *bool
+}
+
+// Apply changes w by u.
+func (u WebhookUpdate) Apply(w *Webhook) {
+	if u.URL != nil {
+		w.URL = *u.URL
+	}
+	if u.Events != nil {
+		w.Events = u.Events
+	}
+	if u.Secret != nil {
+		w.Secret = *u.Secret
+	}
+	if u.Active != nil {
+		w.Active = *u.Active
+	}
+}
+
+// WebhookDTO is a webhook as returned by the admin API, which never returns its secret.
+type WebhookDTO struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Events    []string `json:"events"`
+	Signed    bool     `json:"signed"`
+	Active    bool     `json:"active"`
+	CreatedAt string   `json:"created_at"`
+	UpdatedAt string   `json:"updated_at"`
+}
+
+func NewWebhookDTO(w *Webhook) *WebhookDTO {
+	events := make([]string, len(w.Events))
+	for i, e := range w.Events {
+		events[i] = string(e)
+	}
+	return &WebhookDTO{
+		ID:        w.ID,
+		URL:       w.URL,
+		Events:    events,
+		Signed:    w.Secret != "",
+		Active:    w.Active,
+		CreatedAt: w.CreatedAt.Format(time.RFC3339),
+		UpdatedAt: w.UpdatedAt.Format(time.RFC3339),
+	}
+}
@@ -0,0 +1,55 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// AdminService defines the operations reserved to the administrators of the service.
+// Unlike the DocumentService, its operations span all the tenants.
+type AdminService interface {
+	// Reconcile compares the documents with the binary data held in the binary repository
+	// and reports, and optionally fixes, the mismatches found.
+	Reconcile(ctx context.Context, opts domain.ReconcileOptions) (*domain.ReconcileReport, error)
+
+	// Purge immediately removes the documents created before a cutoff date, optionally restricted
+	// to some statuses, and reports how many documents and binary data were removed.
+	Purge(ctx context.Context, opts domain.PurgeOptions) (*domain.PurgeReport, error)
+
+	// Requeue triggers again the analysis of the documents pending for longer than a threshold whose binary data
+	// still exists, and reports how many were requeued.
+	Requeue(ctx context.Context, opts domain.RequeueOptions) (*domain.RequeueReport, error)
+
+	// Metrics returns the current measures of the service and of its dependencies.
+	Metrics(ctx context.Context) []domain.Metric
+
+	// Concurrency returns the current limit on the number of analyses run at once, along with the analyses running
+	// and waiting.
+	Concurrency() *domain.Concurrency
+
+	// SetConcurrency changes the limit on the number of analyses run at once, until the service restarts. When it is
+	// lowered, the analyses running finish but no other starts until they are under the new limit.
+	SetConcurrency(limit int) (*domain.Concurrency, error)
+}
+
+// MetricsReporter is implemented by the dependencies of the service able to report measures of their own,
+// such as the connection pool of a database.
+type MetricsReporter interface {
+	// Metrics returns the current measures of the dependency.
+	Metrics() []domain.Metric
+}
+
+var (
+	// ErrServiceReconcileFailed is returned when a reconciliation cannot be completed.
+	ErrServiceReconcileFailed = errors.New("failed to reconcile documents and binary data")
+
+	// ErrServicePurgeFailed is returned when an on-demand purge cannot be completed.
+	ErrServicePurgeFailed = errors.New("failed to purge documents")
+
+	// ErrServiceRequeueFailed is returned when a requeue of the pending documents cannot be completed.
+	ErrServiceRequeueFailed = errors.New("failed to requeue pending documents")
+
+	// ErrServiceInvalidConcurrency is returned when the limit on the analyses run at once is not strictly positive.
+	ErrServiceInvalidConcurrency = errors.New("invalid concurrency limit")
+)
@@ -0,0 +1,17 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// AlertNotifier is implemented by the adapters notifying the operators of the alerts raised by the service, through a
+// webhook, Slack or the logs.
+type AlertNotifier interface {
+	// Notify delivers alert, returning an error if it could not be delivered.
+	Notify(ctx context.Context, alert *domain.InfectedRateAlert) error
+}
+
+// ErrAlertDeliveryFailed is returned when an alert could not be delivered.
+var ErrAlertDeliveryFailed = errors.New("alert delivery failed")
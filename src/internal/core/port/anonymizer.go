@@ -0,0 +1,26 @@
+package port
+
+import "errors"
+
+// Anonymizer defines the operations protecting the tags and file names stored with documents,
+// for installations where these values are sensitive themselves.
+type Anonymizer interface {
+	// Pseudonym returns the pseudonym stored in place of a value. A value always has the same pseudonym,
+	// so that documents can still be looked up by the pseudonym of their tag.
+	Pseudonym(value string) string
+
+	// Seal encrypts the original values of a document so that they can be kept at rest.
+	// It returns an empty string when the original values are not kept at all.
+	Seal(values map[string]string) (string, error)
+
+	// Open decrypts the original values sealed by Seal.
+	Open(sealed string) (map[string]string, error)
+}
+
+var (
+	// ErrSealFailed is returned when the original values of a document cannot be encrypted.
+	ErrSealFailed = errors.New("failed to seal original values")
+
+	// ErrOpenFailed is returned when sealed original values cannot be decrypted.
+	ErrOpenFailed = errors.New("failed to open sealed values")
+)
@@ -24,12 +24,43 @@ type AntivirusAnalyzer interface {
 	Ping() error
 }
 
+// ThreatIdentifier is implemented by antivirus analyzers able to name the threat they detect.
+// The service uses it to record the name of the signature matched by an infected document.
+type ThreatIdentifier interface {
+	// AnalyzeThreat performs the analysis of Analyze, and returns along with the status the name of the threat
+	// detected, empty unless the data is infected.
+	AnalyzeThreat(ctx context.Context, data io.Reader) (domain.AnalysisStatus, string, error)
+}
+
+// LoadReporter is implemented by antivirus analyzers able to report how busy they are.
+// The service uses it to slow down the dispatch of analyses when the analyzer is saturated.
+type LoadReporter interface {
+	// Load returns the current load of the analyzer.
+	Load(ctx context.Context) (AnalyzerLoad, error)
+}
+
+// AnalyzerLoad describes the current workload of an antivirus analyzer.
+type AnalyzerLoad struct {
+	BusyWorkers int // BusyWorkers is the number of workers currently scanning.
+	MaxWorkers  int // MaxWorkers is the maximum number of workers of the analyzer.
+	QueuedJobs  int // QueuedJobs is the number of scans waiting for a free worker.
+}
+
+// Saturated reports whether the analyzer cannot take a new scan without queueing it.
+func (l AnalyzerLoad) Saturated() bool {
+	return l.QueuedJobs > 0 || (l.MaxWorkers > 0 && l.BusyWorkers >= l.MaxWorkers)
+}
+
 var (
 	EICAR = []byte(`X5O!P%@AP[4\PZX54(P^)7CC)7}$EICAR-STANDARD-ANTIVIRUS-TEST-FILE!$H+H*`)
 
 	// ErrAntivirusAnalysisFailed is returned when the analysis cannot be performed due to an error in the process.
 	ErrAntivirusAnalysisFailed = errors.New("antivirus analysis failed")
 
+	// ErrAntivirusSizeLimitExceeded is returned when the data is larger than the antivirus accepts to analyze.
+	// Retrying the analysis is pointless.
+	ErrAntivirusSizeLimitExceeded = errors.New("data exceeds the size limit of the antivirus")
+
 	// ErrAntivirusServiceUnavailable is returned when the antivirus service is not reachable or not ready.
 	ErrAntivirusAnalyserUnavailable = errors.New("antivirus service is unavailable")
 )
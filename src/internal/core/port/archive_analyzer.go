@@ -0,0 +1,26 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"io"
+)
+
+// ArchiveAnalyzer analyzes archives file by file.
+type ArchiveAnalyzer interface {
+	// AnalyzeArchive extracts the zip, tar or gzip archive of size bytes read from r, along with the archives
+	// it holds, analyzes each of their files and reports the verdict on each of them.
+	AnalyzeArchive(ctx context.Context, r io.ReaderAt, size int64) (*domain.ArchiveReport, error)
+}
+
+var (
+	// ErrNotAnArchive is returned when the data is not an archive of a supported format.
+	ErrNotAnArchive = errors.New("not an archive")
+
+	// ErrInvalidArchive is returned when an archive is corrupted.
+	ErrInvalidArchive = errors.New("invalid archive")
+
+	// ErrArchiveLimitExceeded is returned when the extraction of an archive exceeds a limit.
+	ErrArchiveLimitExceeded = errors.New("archive limit exceeded")
+)
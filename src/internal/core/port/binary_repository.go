@@ -3,12 +3,15 @@ package port
 import (
 	"context"
 	"errors"
+	"goyav/internal/core/domain"
 	"io"
+	"time"
 )
 
 // BinaryRepository defines the interface for operations related to managing the binary data of documents.
 // This interface abstracts the underlying storage mechanism, which could be a file system or
 // an object storage system like AWS S3, Azure Blob Storage, or MinIO.
+// The binary data of each tenant carried by the operations' context (see domain.TenantFromContext) is kept apart.
 type BinaryRepository interface {
 	// Save stores the binary data of a document, identified by a unique ID, into the storage system.
 	// The function takes a context to manage timeouts and cancellation, a reader for the data,
@@ -25,6 +28,62 @@ type BinaryRepository interface {
 	// Ping checks the availability or health of the storage system. It is used to verify
 	// if the storage system is accessible and functioning correctly.
 	Ping() error
+
+	// Walk calls fn for the binary data of every document, whatever its tenant. It stops at the first error returned by fn.
+	Walk(ctx context.Context, fn func(BinaryInfo) error) error
+}
+
+// BinaryPresigner is implemented by the binary repositories letting clients upload and download the binary data of
+// documents directly, with presigned URLs, so that large files do not go through the service.
+type BinaryPresigner interface {
+	// PresignUpload returns a URL the binary data of the document identified by ID can be uploaded to
+	// with a PUT request, until it expires. The data is staged apart from the binary data of the document, which
+	// the URL cannot change, until it is promoted with PromoteUpload.
+	PresignUpload(ctx context.Context, ID string, expiry time.Duration) (string, error)
+
+	// GetUpload retrieves the data staged for the document identified by ID, along with its version, which changes
+	// whenever the data is uploaded again. It fails with ErrBinaryNotFound if no data is staged.
+	GetUpload(ctx context.Context, ID string) (io.ReadCloser, string, error)
+
+	// PromoteUpload makes the data staged for the document identified by ID, in the given version, its binary data
+	// and removes it from the staging area, and returns the version of the binary data, see BinaryVersion. It fails
+	// with ErrUploadChanged if the staged data is no longer in this version, leaving the binary data of the document
+	// unchanged.
+	PromoteUpload(ctx context.Context, ID, version string) (string, error)
+
+	// DiscardUpload removes the data staged for the document identified by ID, if any.
+	DiscardUpload(ctx context.Context, ID string) error
+
+	// BinaryVersion returns the version of the binary data of the document identified by ID, which changes whenever
+	// the data is written, so that the data can be checked unchanged without reading it. It fails with
+	// ErrBinaryNotFound if the document has no binary data.
+	BinaryVersion(ctx context.Context, ID string) (string, error)
+
+	// PresignDownload returns a URL the binary data of the document identified by ID can be downloaded from
+	// with a GET request, until it expires. The data is downloaded as an attachment named fileName, if not empty.
+	PresignDownload(ctx context.Context, ID, fileName string, expiry time.Duration) (string, error)
+}
+
+// BinaryQuarantiner is implemented by the binary repositories able to protect the binary data of the infected
+// documents from deletion, to keep it as evidence.
+type BinaryQuarantiner interface {
+	// Quarantine protects the binary data of the document identified by ID from deletion.
+	Quarantine(ctx context.Context, ID string) error
+}
+
+// BinaryTagger is implemented by the binary repositories able to record the verdict of a document along with its
+// retained binary data, so that the systems consuming the storage can filter the data on its verdict.
+type BinaryTagger interface {
+	// TagVerdict records the verdict of doc along with its binary data.
+	TagVerdict(ctx context.Context, doc *domain.Document) error
+}
+
+// BinaryInfo describes the binary data of a document held in a BinaryRepository.
+type BinaryInfo struct {
+	Tenant     string
+	ID         string
+	Size       int64
+	ModifiedAt time.Time
 }
 
 var (
@@ -34,9 +93,32 @@ var (
 	// ErrGetDataFailed is returned when the Get operation fails.
 	ErrGetDataFailed = errors.New("failed to get the document's bytes data")
 
+	// ErrBinaryNotFound is returned along with ErrGetDataFailed or ErrDeleteDataFailed when there is no binary data
+	// for the document.
+	ErrBinaryNotFound = errors.New("document's bytes data not found")
+
 	// ErrDeleteDataFailed is returned when the Delete operation fails.
 	ErrDeleteDataFailed = errors.New("failed to delete the document's bytes data")
 
+	// ErrWalkDataFailed is returned when the Walk operation fails.
+	ErrWalkDataFailed = errors.New("failed to list the documents' bytes data")
+
+	// ErrPresignFailed is returned when the PresignUpload or PresignDownload operation fails.
+	ErrPresignFailed = errors.New("failed to presign the upload of the document's bytes data")
+
+	// ErrPromoteUploadFailed is returned when the PromoteUpload or DiscardUpload operation fails.
+	ErrPromoteUploadFailed = errors.New("failed to promote the uploaded data of the document")
+
+	// ErrUploadChanged is returned along with ErrPromoteUploadFailed when the staged data was uploaded again since
+	// it was read.
+	ErrUploadChanged = errors.New("uploaded data changed")
+
+	// ErrQuarantineFailed is returned when the Quarantine operation fails.
+	ErrQuarantineFailed = errors.New("failed to quarantine the document's bytes data")
+
+	// ErrTagVerdictFailed is returned when the TagVerdict operation fails.
+	ErrTagVerdictFailed = errors.New("failed to tag the document's bytes data with its verdict")
+
 	// ErrBinaryRepositoryUnavailable is returned when the Ping operation fails to reach the byte repository.
 	ErrBinaryRepositoryUnavailable = errors.New("binary repository is unavailable")
 )
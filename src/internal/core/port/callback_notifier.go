@@ -0,0 +1,25 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// CallbackNotifier is implemented by the adapters notifying the callback URL given with an upload of the result of
+// its analysis. The callback URLs are chosen by the clients, the notifier must not let them reach internal services.
+type CallbackNotifier interface {
+	// Validate checks that callbackURL may be notified, before the upload is accepted.
+	Validate(callbackURL string) error
+
+	// Notify sends doc, once analyzed, to callbackURL, retrying until it is acknowledged or ctx is done.
+	Notify(ctx context.Context, callbackURL string, doc *domain.Document) error
+}
+
+var (
+	// ErrInvalidCallbackURL is returned when a callback URL is malformed or targets a host which may not be notified.
+	ErrInvalidCallbackURL = errors.New("invalid callback URL")
+
+	// ErrCallbackFailed is returned when a callback URL could not be notified.
+	ErrCallbackFailed = errors.New("callback failed")
+)
@@ -0,0 +1,28 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"time"
+)
+
+// CallbackOutbox is implemented by the document repositories holding the notifications of the callback URLs in a
+// transactional outbox: a notification becomes due in the transaction recording the final status of its document, so
+// that it is delivered even if the replica which analyzed the document stops right after.
+type CallbackOutbox interface {
+	// AddCallback records that callbackURL is to be notified of the document of the tenant carried by ctx identified
+	// by ID once it has a final status, due at once if it has one already.
+	AddCallback(ctx context.Context, ID, callbackURL string) error
+
+	// ClaimCallbacks returns up to limit due notifications of all the tenants, the oldest first, and hides them from
+	// the next claims for lease times two to the power of their previous attempts, so that a single replica delivers
+	// each, and one which failed is retried with an exponential backoff unless it is removed.
+	ClaimCallbacks(ctx context.Context, limit int, lease time.Duration) ([]domain.CallbackDelivery, error)
+
+	// RemoveCallback removes a notification once delivered, or given up.
+	RemoveCallback(ctx context.Context, ID int64) error
+}
+
+// ErrCallbackOutboxFailed indicates a failure to record, claim or remove a notification of the callback outbox.
+var ErrCallbackOutboxFailed = errors.New("callback outbox failed")
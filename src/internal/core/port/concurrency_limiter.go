@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"errors"
+)
+
+// ConcurrencyLimiter is implemented by the adapters limiting the number of analyses run at once by all the replicas
+// of the service sharing them, on top of the scheduler of each replica, so that the replicas together do not
+// overload the analyzer.
+type ConcurrencyLimiter interface {
+	// Acquire blocks until a slot is available or ctx is done, and returns the function giving it back.
+	Acquire(ctx context.Context) (release func(), err error)
+}
+
+// ErrConcurrencyLimiterFailed is returned when a slot of a concurrency limiter cannot be acquired, other than because
+// the context is done.
+var ErrConcurrencyLimiterFailed = errors.New("concurrency limiter failed")
@@ -9,6 +9,8 @@ import (
 )
 
 // DocumentRepository defines operations for managing documents in a repository.
+// Except Purge, FindByStatus and Summarize, its operations are scoped to the tenant carried by their context (see domain.TenantFromContext):
+// a document owned by another tenant is reported as not found.
 type DocumentRepository interface {
 	// Save adds a new document to the repository and returns an error if the document already exists or
 	// if there is an issue during the save operation.
@@ -23,16 +25,65 @@ type DocumentRepository interface {
 	// Delete removes a document from the repository by its ID and returns an error if not found or during deletion.
 	Delete(ctx context.Context, id string) error
 
-	// UpdateStatus updates a document's analysis status and date, returning an error for nonexistent documents,
-	// invalid status, or update issues.
-	UpdateStatus(ctx context.Context, id string, status domain.AnalysisStatus, analyzedAt time.Time) error
+	// SoftDelete marks a document as deleted at the given date, keeping it until it is purged, and returns an error
+	// if it is not found or deleted already. Get still returns a soft-deleted document, GetByHash ignores it.
+	SoftDelete(ctx context.Context, id string, deletedAt time.Time) error
+
+	// Restore clears the soft deletion of a document, returning an error if it is not found or not deleted.
+	Restore(ctx context.Context, id string) error
+
+	// UpdateStatus updates a document's analysis status and date, along with the name of the threat found in it,
+	// empty unless known, returning an error for nonexistent documents, invalid status, or update issues.
+	UpdateStatus(ctx context.Context, id string, status domain.AnalysisStatus, threat string, analyzedAt time.Time) error
+
+	// SaveArchiveReport records the verdict on each file of the archive of a document, returning an error for
+	// nonexistent documents or update issues.
+	SaveArchiveReport(ctx context.Context, id string, report *domain.ArchiveReport) error
+
+	// UpdateContent records the hash, its algorithm, the size and the content type of a document whose binary data
+	// was uploaded after the document was saved, returning an error for nonexistent documents or update issues. The
+	// content is recorded once: it fails with ErrDocumentContentRecorded if the document has a hash already, so that
+	// concurrent confirmations of the same upload are told apart.
+	UpdateContent(ctx context.Context, doc *domain.Document) error
+
+	// UpdateBinaryVersion records the version of the binary data of a document, see domain.Document.BinaryVersion,
+	// returning an error for nonexistent documents or update issues.
+	UpdateBinaryVersion(ctx context.Context, id, version string) error
 
 	// Ping checks the repository's availability or health status.
 	Ping() error
 
 	// Purge removes documents from the repository that have a known antiviral analysis result
-	// and were created before the specified date.
-	Purge(date time.Time) error
+	// and were created before the specified date, as well as the documents soft-deleted before that date,
+	// restricted to the given statuses if any. It returns the number of documents removed.
+	Purge(date time.Time, statuses ...domain.AnalysisStatus) (int64, error)
+
+	// List retrieves the documents matching filter, the most recent first, those created at the same time by descending
+	// ID, leaving out the soft-deleted ones. Only the documents following the cursor of filter are listed, if any.
+	List(ctx context.Context, filter domain.DocumentFilter) ([]*domain.Document, error)
+
+	// Iterate calls fn with each document matching filter, the oldest first, leaving out the soft-deleted ones and
+	// ignoring the limit and the cursor of filter, without holding them all in memory. It stops at the first error
+	// returned by fn, and returns it.
+	Iterate(ctx context.Context, filter domain.DocumentFilter, fn func(*domain.Document) error) error
+
+	// FindByStatus retrieves the documents of all the tenants having the given analysis status.
+	FindByStatus(ctx context.Context, status domain.AnalysisStatus) ([]*domain.Document, error)
+
+	// Stats returns aggregate statistics on the documents, counting the documents uploaded since the given date.
+	Stats(ctx context.Context, since time.Time) (*domain.DocumentStats, error)
+
+	// Summarize counts the documents of all the tenants created between from, included, and to, excluded, by status,
+	// along with the top most frequent threats and the top tenants uploading the most.
+	Summarize(ctx context.Context, from, to time.Time, top int) (*domain.SummaryReport, error)
+}
+
+// DocumentEventLog is implemented by the document repositories recording each change of the documents in an
+// append-only log, along with the change itself, so that what happened to a document is never lost to the next change.
+type DocumentEventLog interface {
+	// Events retrieves the events of the document of the tenant carried by ctx identified by id, the oldest first.
+	// The events of a purged document are removed along with it.
+	Events(ctx context.Context, id string) ([]domain.DocumentEvent, error)
 }
 
 var (
@@ -50,6 +101,22 @@ var (
 	// possibly due to a nonexistent document or database issues.
 	ErrUpdateStatusFailed = errors.New("failed to update document status")
 
+	// ErrSaveArchiveReportFailed indicates a failure in recording the archive report of a document,
+	// possibly due to a nonexistent document or database issues.
+	ErrSaveArchiveReportFailed = errors.New("failed to save the archive report")
+
+	// ErrUpdateContentFailed indicates a failure in recording the hash, size and content type of a document,
+	// possibly because it does not exist.
+	ErrUpdateContentFailed = errors.New("failed to update document content")
+
+	// ErrDocumentContentRecorded is returned along with ErrUpdateContentFailed when the content of the document is
+	// recorded already, or the document no longer exists.
+	ErrDocumentContentRecorded = errors.New("document content already recorded")
+
+	// ErrUpdateBinaryVersionFailed indicates a failure in recording the version of the binary data of a document,
+	// possibly because it does not exist.
+	ErrUpdateBinaryVersionFailed = errors.New("failed to update the version of the document's binary data")
+
 	// ErrSaveDocumentFailed indicates a failure to save a new document to the repository,
 	// possibly due to database or connectivity issues.
 	ErrSaveDocumentFailed = errors.New("failed to save the document")
@@ -58,10 +125,30 @@ var (
 	// possibly due to the document not existing or database issues.
 	ErrDeleteDocumentFailed = errors.New("failed to delete the document")
 
+	// ErrSoftDeleteDocumentFailed indicates a failure to mark a document as deleted,
+	// possibly due to the document not existing, being deleted already, or database issues.
+	ErrSoftDeleteDocumentFailed = errors.New("failed to soft-delete the document")
+
+	// ErrRestoreDocumentFailed indicates a failure to restore a soft-deleted document,
+	// possibly due to the document not existing, not being deleted, or database issues.
+	ErrRestoreDocumentFailed = errors.New("failed to restore the document")
+
 	// ErrDocumentRepositoryUnavailable indicates that the document repository is not accessible,
 	// possibly due to database downtime or network issues.
 	ErrDocumentRepositoryUnavailable = errors.New("document repository is unavailable")
 
 	// ErrDocumentRepositoryPurgeFaild indicates a failure in the purge operation of the document repository.
 	ErrDocumentRepositoryPurgeFailed = errors.New("failed to purge the document repository")
+
+	// ErrFindDocumentsFailed indicates a failure to retrieve a set of documents from the repository.
+	ErrFindDocumentsFailed = errors.New("failed to find documents")
+
+	// ErrDocumentStatsFailed indicates a failure to compute statistics on the documents of the repository.
+	ErrDocumentStatsFailed = errors.New("failed to compute document statistics")
+
+	// ErrDocumentEventsFailed indicates a failure to retrieve the events of a document from the repository.
+	ErrDocumentEventsFailed = errors.New("failed to retrieve document events")
+
+	// ErrDocumentSummaryFailed indicates a failure to summarize the documents of the repository for a report.
+	ErrDocumentSummaryFailed = errors.New("failed to summarize documents")
 )
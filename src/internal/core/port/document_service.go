@@ -5,22 +5,88 @@ import (
 	"errors"
 	"goyav/internal/core/domain"
 	"io"
+	"time"
 )
 
 // DocumentService defines the operations for managing documents in the system.
 // It provides methods for uploading documents and retrieving their status.
 type DocumentService interface {
-	// Upload accepts a byte slice representing a document, along with a tag for the document.
+	// Upload accepts a byte slice representing a document, along with a tag for the document. The size of the data
+	// is -1 when it is not known in advance, such as for a streamed upload.
 	// It returns the ID of the newly uploaded document and any error encountered during the upload process.
 	Upload(ctx context.Context, data io.Reader, size int64, tag string) (ID string, err error)
 
+	// CreateUpload saves a pending document of the tenant carried by ctx, awaiting its binary data, and returns a
+	// presigned URL the client uploads the data to directly, before confirming the upload with ConfirmUpload.
+	CreateUpload(ctx context.Context, tag string) (*domain.PresignedUpload, error)
+
+	// ConfirmUpload checks the binary data uploaded to the presigned URL of a document and triggers its analysis.
+	// It returns the document, along with its hash, size and content type.
+	ConfirmUpload(ctx context.Context, ID string) (*domain.Document, error)
+
+	// PresignDownload returns a presigned URL the retained binary data of a clean document can be downloaded from.
+	PresignDownload(ctx context.Context, ID string) (*domain.PresignedDownload, error)
+
+	// MaxUploadSize returns the maximum size in bytes of an upload of the tenant carried by ctx, or zero if the tenant
+	// has no maximum of its own, in which case the maximum of the server applies.
+	MaxUploadSize(ctx context.Context) int64
+
+	// TagMaxLength returns the maximum length in bytes of the tag of an upload.
+	TagMaxLength() int
+
+	// EstimateCompletion estimates when the analysis of a document of size bytes, just uploaded, will complete.
+	EstimateCompletion(size int64) time.Time
+
 	// GetDocument retrieves the current status of a document identified by its ID.
 	// It returns the document information (if found) and any error encountered during the retrieval process.
 	GetDocument(ctx context.Context, ID string) (*domain.Document, error)
 
+	// DocumentHistory retrieves the events of a document identified by its ID, deleted or not, the oldest first.
+	DocumentHistory(ctx context.Context, ID string) ([]domain.DocumentEvent, error)
+
+	// LookupHash retrieves the document of the tenant carried by ctx holding the content with the given hash, so that
+	// a client can learn whether the content was analyzed already without uploading it.
+	LookupHash(ctx context.Context, hash string) (*domain.Document, error)
+
+	// ListDocuments retrieves the documents of the tenant carried by ctx matching filter, the most recent first.
+	ListDocuments(ctx context.Context, filter domain.DocumentFilter) ([]*domain.Document, error)
+
+	// ExportDocuments calls fn with each document of the tenant carried by ctx matching filter, the oldest first,
+	// whatever the limit of filter. It stops at the first error returned by fn.
+	ExportDocuments(ctx context.Context, filter domain.DocumentFilter, fn func(*domain.Document) error) error
+
+	// DeleteDocument soft-deletes a document, which can be restored with RestoreDocument until it is purged.
+	// It returns the deleted document.
+	DeleteDocument(ctx context.Context, ID string) (*domain.Document, error)
+
+	// RestoreDocument restores a soft-deleted document and returns it.
+	RestoreDocument(ctx context.Context, ID string) (*domain.Document, error)
+
+	// IngestVerdict records the verdict reported by an on-access scanning agent on a file the service never received,
+	// as an externally-sourced document of the tenant carried by ctx. It returns the ID of the document and whether
+	// it was created, rather than updated by a new verdict on the same file.
+	IngestVerdict(ctx context.Context, v domain.ExternalVerdict) (ID string, created bool, err error)
+
+	// AnalyzeImage analyzes the files of the layers of the container image tarball read from data.
+	// The analysis is synchronous and its report is not stored.
+	AnalyzeImage(ctx context.Context, data io.Reader, size int64) (*domain.ImageReport, error)
+
+	// Scan analyzes the data read from data, of size bytes or -1 if unknown, with the engines carried by ctx, all of
+	// them if there are none. The analysis is synchronous, and neither the data nor its report are stored.
+	Scan(ctx context.Context, data io.Reader, size int64) (*domain.ScanReport, error)
+
+	// Stats returns aggregate statistics on the documents of the tenant carried by ctx and on the purges of the service.
+	Stats(ctx context.Context) (*domain.Stats, error)
+
+	// QuotaStatus returns the quota of the tenant carried by ctx along with its current usage.
+	QuotaStatus(ctx context.Context) (*domain.QuotaStatus, error)
+
 	// Ping checks the connectivity or readiness of the service.
 	Ping() error
 
+	// Health checks each dependency of the service and reports its status, latency and error, if any.
+	Health(ctx context.Context) *domain.Health
+
 	// Version returns the current version of the DocumentService.
 	Version() string
 
@@ -39,6 +105,106 @@ var (
 	// ErrServiceGetDocumentFailed is returned when retrieving a document fails.
 	ErrServiceGetDocumentFailed = errors.New("failed to retrieve document")
 
+	// ErrServiceHistoryUnavailable is returned when the history of a document is retrieved while the document
+	// repository does not record the events of the documents.
+	ErrServiceHistoryUnavailable = errors.New("document history unavailable")
+
+	// ErrServiceListDocumentsFailed is returned when listing the documents fails.
+	ErrServiceListDocumentsFailed = errors.New("failed to list documents")
+
+	// ErrServiceInvalidFilter is returned when the documents are listed with a filter which cannot be applied.
+	ErrServiceInvalidFilter = errors.New("invalid document filter")
+
+	// ErrServiceDocumentDeleted is returned when a soft-deleted document is retrieved, or deleted again.
+	ErrServiceDocumentDeleted = errors.New("document deleted")
+
+	// ErrServiceDocumentNotDeleted is returned when a document which is not soft-deleted is restored.
+	ErrServiceDocumentNotDeleted = errors.New("document not deleted")
+
+	// ErrServiceDeleteDocumentFailed is returned when the soft deletion or the restoration of a document fails.
+	ErrServiceDeleteDocumentFailed = errors.New("failed to delete or restore document")
+
 	// ErrUserServiceInvalidID indicates that an invalid ID was provided.
 	ErrServiceInvalidID = errors.New("invalid ID provided")
+
+	// ErrServiceInvalidHash is returned when a hash which is not one of the hash algorithm of the service is looked up.
+	ErrServiceInvalidHash = errors.New("invalid hash provided")
+
+	// ErrServiceInvalidVerdict is returned when a reported verdict lacks a valid hash, origin or analysis result.
+	ErrServiceInvalidVerdict = errors.New("invalid verdict")
+
+	// ErrServiceIngestVerdictFailed is returned when recording a reported verdict fails.
+	ErrServiceIngestVerdictFailed = errors.New("failed to ingest verdict")
+
+	// ErrServiceImagesDisabled is returned when an image is analyzed while image analysis is not configured.
+	ErrServiceImagesDisabled = errors.New("image analysis is not enabled")
+
+	// ErrServiceAnalyzeImageFailed is returned when the analysis of an image fails.
+	ErrServiceAnalyzeImageFailed = errors.New("failed to analyze image")
+
+	// ErrServiceScanFailed is returned when the analysis of scanned data fails.
+	ErrServiceScanFailed = errors.New("failed to scan data")
+
+	// ErrServiceGetStatsFailed is returned when computing statistics fails.
+	ErrServiceGetStatsFailed = errors.New("failed to compute statistics")
+
+	// ErrServiceReportFailed is returned when summarizing the documents for a report fails.
+	ErrServiceReportFailed = errors.New("failed to build the report")
+
+	// ErrServiceOverloaded is returned when an upload is rejected because too many analyses are waiting for a slot
+	// already, so that the upload can be retried later rather than queued for an analysis that may never run.
+	ErrServiceOverloaded = errors.New("too many analyses waiting")
+
+	// ErrServiceQuotaExceeded is returned when an upload exceeds the daily uploads or the stored bytes of a tenant's quota.
+	ErrServiceQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrServiceUnsupportedMediaType is returned when the media type of an upload is rejected by the media type policy.
+	ErrServiceUnsupportedMediaType = errors.New("unsupported media type")
+
+	// ErrServiceFileTooLarge is returned when an upload exceeds the maximum file size of a tenant's quota.
+	ErrServiceFileTooLarge = errors.New("file too large")
+
+	// ErrServicePresignedUploadsDisabled is returned when a presigned upload is requested while they are not configured.
+	ErrServicePresignedUploadsDisabled = errors.New("presigned uploads are not enabled")
+
+	// ErrServiceUploadNotReceived is returned when an upload is confirmed before its data was uploaded to the presigned URL.
+	ErrServiceUploadNotReceived = errors.New("uploaded data not received")
+
+	// ErrServiceUploadChanged is returned when the data of an upload is uploaded again while the upload is confirmed.
+	ErrServiceUploadChanged = errors.New("uploaded data changed during the confirmation")
+
+	// ErrServiceUploadAlreadyConfirmed is returned when the upload of a document which is not awaiting its data is confirmed.
+	ErrServiceUploadAlreadyConfirmed = errors.New("upload already confirmed")
+
+	// ErrServiceDownloadsDisabled is returned when a download is requested while binary data is not retained.
+	ErrServiceDownloadsDisabled = errors.New("downloads are not enabled")
+
+	// ErrServiceDownloadFailed is returned when presigning the download of a document fails.
+	ErrServiceDownloadFailed = errors.New("failed to presign download")
+
+	// ErrServiceDocumentNotClean is returned when the download of a document which is not clean is requested.
+	ErrServiceDocumentNotClean = errors.New("document is not clean")
+
+	// ErrServiceContentNotRetained is returned when the download of a clean document whose binary data is not
+	// retained, e.g. a duplicate or a document analyzed before the retention was enabled, is requested.
+	ErrServiceContentNotRetained = errors.New("document content not retained")
+
+	// ErrServiceContentChanged is returned when the download of a clean document whose binary data no longer matches
+	// the hash of the data which was analyzed is requested.
+	ErrServiceContentChanged = errors.New("document content changed since its analysis")
+
+	// ErrServiceQuotasDisabled is returned when quotas are requested while they are not configured.
+	ErrServiceQuotasDisabled = errors.New("quotas are not enabled")
+
+	// ErrServiceGetQuotaFailed is returned when retrieving the quota status of a tenant fails.
+	ErrServiceGetQuotaFailed = errors.New("failed to retrieve quota status")
+
+	// ErrServiceCallbacksDisabled is returned when an upload carries a callback URL while callbacks are not configured.
+	ErrServiceCallbacksDisabled = errors.New("callbacks are not enabled")
+
+	// ErrServiceInvalidCallbackURL is returned when the callback URL of an upload is rejected by the callback notifier.
+	ErrServiceInvalidCallbackURL = errors.New("invalid callback URL")
+
+	// ErrServiceUnknownEngine is returned when an upload selects an engine the service does not have.
+	ErrServiceUnknownEngine = errors.New("unknown engine")
 )
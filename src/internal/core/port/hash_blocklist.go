@@ -0,0 +1,13 @@
+package port
+
+import (
+	"context"
+)
+
+// HashBlocklist is implemented by the adapters holding the hashes of content known to be malicious, so that its
+// uploads are given an infected verdict at once, without being stored nor analyzed, even before the signatures of
+// the analyzer detect it.
+type HashBlocklist interface {
+	// Lookup returns the name of the threat of the content with the given hash, and whether it is blocklisted.
+	Lookup(ctx context.Context, hash string) (threat string, blocked bool, err error)
+}
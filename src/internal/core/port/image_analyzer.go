@@ -0,0 +1,23 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"io"
+)
+
+// ImageAnalyzer analyzes container images file by file.
+type ImageAnalyzer interface {
+	// AnalyzeImage unpacks the layers of the image tarball of size bytes read from r, in the OCI image layout
+	// or in the format of docker save, analyzes each of their files and reports the findings of each layer.
+	AnalyzeImage(ctx context.Context, r io.ReaderAt, size int64) (*domain.ImageReport, error)
+}
+
+var (
+	// ErrInvalidImage is returned when the data is not an image tarball or when its manifests are inconsistent.
+	ErrInvalidImage = errors.New("invalid image")
+
+	// ErrImageLimitExceeded is returned when the unpacking of an image exceeds a limit.
+	ErrImageLimitExceeded = errors.New("image limit exceeded")
+)
@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// JobLocker is implemented by the document repositories shared by several replicas, which elect one of them to run
+// a periodic job, such as the purge, rather than all of them at once.
+type JobLocker interface {
+	// RunExclusive runs fn holding the lock of the job named job, unless another replica holds it already or ran
+	// the job less than every ago, and reports whether fn was run. The error of fn is returned as is.
+	RunExclusive(ctx context.Context, job string, every time.Duration, fn func(ctx context.Context) error) (bool, error)
+}
+
+// ErrJobLockFailed indicates a failure to take or release the lock of a job.
+var ErrJobLockFailed = errors.New("job lock failed")
@@ -0,0 +1,42 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"time"
+)
+
+// QuotaRepository defines the operations persisting the quota usage of tenants.
+// Its operations are scoped to the tenant carried by their context (see domain.TenantFromContext).
+type QuotaRepository interface {
+	// Reserve records an upload of size bytes during the given day, provided that it fits in quota.
+	// The check and the record are atomic; ErrQuotaExceeded is returned when the upload does not fit.
+	Reserve(ctx context.Context, day time.Time, size int64, quota domain.Quota) error
+
+	// Release gives back size bytes of stored data, once the binary data of a document is deleted.
+	Release(ctx context.Context, size int64) error
+
+	// Usage returns the usage of the quota during the given day.
+	Usage(ctx context.Context, day time.Time) (*domain.QuotaUsage, error)
+
+	// Ping checks the repository's availability or health status.
+	Ping() error
+}
+
+var (
+	// ErrQuotaExceeded is returned when an upload does not fit in the quota of a tenant.
+	ErrQuotaExceeded = errors.New("quota exceeded")
+
+	// ErrQuotaReserveFailed is returned when the Reserve operation fails.
+	ErrQuotaReserveFailed = errors.New("failed to reserve quota")
+
+	// ErrQuotaReleaseFailed is returned when the Release operation fails.
+	ErrQuotaReleaseFailed = errors.New("failed to release quota")
+
+	// ErrQuotaUsageFailed is returned when the Usage operation fails.
+	ErrQuotaUsageFailed = errors.New("failed to retrieve quota usage")
+
+	// ErrQuotaRepositoryUnavailable is returned when the Ping operation fails to reach the quota repository.
+	ErrQuotaRepositoryUnavailable = errors.New("quota repository is unavailable")
+)
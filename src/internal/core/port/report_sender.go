@@ -0,0 +1,16 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// ReportSender is implemented by the adapters delivering the scheduled summary reports, by email or to a webhook.
+type ReportSender interface {
+	// Send delivers report, returning an error if it could not be delivered.
+	Send(ctx context.Context, report *domain.SummaryReport) error
+}
+
+// ErrReportDeliveryFailed is returned when a summary report could not be delivered.
+var ErrReportDeliveryFailed = errors.New("report delivery failed")
@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// ReputationSource is implemented by the adapters querying an external reputation service, such as MalwareBazaar or
+// VirusTotal, for its verdict on content by SHA-256 hash, so that the content it is confident about is not analyzed.
+type ReputationSource interface {
+	// Lookup returns the reputation of the content with the given SHA-256 hash, pending if the source does not know it
+	// or is not confident about it.
+	Lookup(ctx context.Context, sha256 string) (domain.Reputation, error)
+}
+
+// ErrReputationLookupFailed is returned when a reputation source cannot be queried.
+var ErrReputationLookupFailed = errors.New("reputation lookup failed")
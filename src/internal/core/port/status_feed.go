@@ -0,0 +1,18 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// StatusFeed is implemented by the adapters pushing the changes of the analysis status of the documents, whichever
+// replica of the service made them.
+type StatusFeed interface {
+	// Listen calls handle with each status change until ctx is done or the feed fails for good.
+	// It calls handle with a change without ID when changes may have been missed, e.g. after a reconnection.
+	Listen(ctx context.Context, handle func(domain.StatusChange)) error
+}
+
+// ErrStatusFeedFailed indicates that the status changes can no longer be listened to.
+var ErrStatusFeedFailed = errors.New("status feed failed")
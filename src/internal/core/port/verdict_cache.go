@@ -0,0 +1,21 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// VerdictCache is implemented by the adapters keeping the verdicts of the analyses for a time of their own, keyed by
+// the hash of the analyzed content, so that the uploads of the same content are given their verdict without being
+// stored nor analyzed again.
+type VerdictCache interface {
+	// Get returns the verdict cached under key, and whether there is one which has not expired.
+	Get(ctx context.Context, key string) (domain.CachedVerdict, bool, error)
+
+	// Set caches v under key, replacing the verdict cached under key, if any.
+	Set(ctx context.Context, key string, v domain.CachedVerdict) error
+}
+
+// ErrVerdictCacheFailed is returned when a verdict cache cannot be read or written.
+var ErrVerdictCacheFailed = errors.New("verdict cache failed")
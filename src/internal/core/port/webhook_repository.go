@@ -0,0 +1,35 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// WebhookRepository is implemented by the document repositories holding the webhook subscriptions, shared by all the
+// replicas of the service.
+type WebhookRepository interface {
+	// SaveWebhook records the new webhook w.
+	SaveWebhook(ctx context.Context, w *domain.Webhook) error
+
+	// GetWebhook retrieves the webhook identified by ID.
+	GetWebhook(ctx context.Context, ID string) (*domain.Webhook, error)
+
+	// ListWebhooks retrieves all the webhooks, the oldest first.
+	ListWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+
+	// UpdateWebhook replaces the URL, events, secret, active flag and update date of the webhook identified by the ID
+	// of w.
+	UpdateWebhook(ctx context.Context, w *domain.Webhook) error
+
+	// DeleteWebhook removes the webhook identified by ID.
+	DeleteWebhook(ctx context.Context, ID string) error
+}
+
+var (
+	// ErrWebhookNotFound indicates that the specified webhook could not be found in the repository.
+	ErrWebhookNotFound = errors.New("webhook not found")
+
+	// ErrWebhookRepositoryFailed indicates a failure to save, retrieve, update or remove a webhook.
+	ErrWebhookRepositoryFailed = errors.New("webhook repository failed")
+)
@@ -0,0 +1,37 @@
+package port
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+)
+
+// WebhookService defines the management of the webhook subscriptions, reserved to the administrators of the service.
+type WebhookService interface {
+	// CreateWebhook validates and records a new webhook, and returns it with its ID and dates.
+	CreateWebhook(ctx context.Context, w domain.Webhook) (*domain.Webhook, error)
+
+	// ListWebhooks retrieves all the webhooks, the oldest first.
+	ListWebhooks(ctx context.Context) ([]*domain.Webhook, error)
+
+	// GetWebhook retrieves the webhook identified by ID.
+	GetWebhook(ctx context.Context, ID string) (*domain.Webhook, error)
+
+	// UpdateWebhook changes the webhook identified by ID by u, and returns it once validated and recorded.
+	UpdateWebhook(ctx context.Context, ID string, u domain.WebhookUpdate) (*domain.Webhook, error)
+
+	// DeleteWebhook removes the webhook identified by ID.
+	DeleteWebhook(ctx context.Context, ID string) error
+}
+
+var (
+	// ErrServiceWebhooksUnavailable is returned when the webhooks are managed while the document repository does not
+	// hold them.
+	ErrServiceWebhooksUnavailable = errors.New("webhooks unavailable")
+
+	// ErrServiceInvalidWebhook is returned when a webhook is created, or changed, with an invalid URL, events or secret.
+	ErrServiceInvalidWebhook = errors.New("invalid webhook")
+
+	// ErrServiceWebhookFailed is returned when a webhook cannot be created, retrieved, changed or removed.
+	ErrServiceWebhookFailed = errors.New("failed to manage webhook")
+)
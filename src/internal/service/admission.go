@@ -0,0 +1,60 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+)
+
+// watchAnalyzerLoad periodically queries the load of the analyzer and records whether it is saturated.
+// It runs indefinitely.
+func (s *Service) watchAnalyzerLoad(lr port.LoadReporter) {
+	ticker := time.NewTicker(s.loadPollInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		load, err := lr.Load(context.Background())
+		if err != nil {
+			// An unreachable analyzer is handled by the analysis retries.
+			slog.Debug("service - analyzer load unavailable", "error", err)
+			s.analyzerSaturated.Store(false)
+			continue
+		}
+		if saturated := load.Saturated(); saturated != s.analyzerSaturated.Swap(saturated) {
+			slog.Info("service - analyzer saturation changed", "saturated", saturated,
+				"busy workers", load.BusyWorkers, "max workers", load.MaxWorkers, "queued jobs", load.QueuedJobs)
+		}
+	}
+}
+
+// waitForAnalyzer blocks while the analyzer is reported as saturated, or until ctx is done.
+func (s *Service) waitForAnalyzer(ctx context.Context) error {
+	for s.analyzerSaturated.Load() {
+		select {
+		case <-time.After(s.loadPollInterval):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// admit holds back an analysis while the analyzer is saturated, see waitForAnalyzer, then takes a slot of the
+// concurrency limiter shared by the replicas, if any, and returns the function giving it back, or the error of ctx.
+// While the shared limiter fails, the analyses are only limited by the scheduler of each replica rather than failing.
+func (s *Service) admit(ctx context.Context) (func(), error) {
+	if err := s.waitForAnalyzer(ctx); err != nil {
+		return nil, err
+	}
+	if s.concurrencyLimiter == nil {
+		return func() {}, nil
+	}
+	release, err := s.concurrencyLimiter.Acquire(ctx)
+	if errors.Is(err, port.ErrConcurrencyLimiterFailed) {
+		slog.WarnContext(ctx, "service - shared concurrency limiter unavailable, the analysis is only limited by the replica", "error", err)
+		return func() {}, nil
+	}
+	return release, err
+}
@@ -0,0 +1,126 @@
+package service
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// DefaultAlertWindow is the default sliding window over which the infected rate is measured.
+const DefaultAlertWindow = 15 * time.Minute
+
+// DefaultAlertMinAnalyzed is the default number of verdicts over the window below which no alert is raised, so that a
+// single infected upload on a quiet instance does not raise one.
+const DefaultAlertMinAnalyzed = 20
+
+// alertTimeout bounds the delivery of an alert.
+const alertTimeout = time.Minute
+
+// alertBuckets is the number of buckets the sliding window of the infected rate is split into.
+const alertBuckets = 60
+
+// infectedRateDetector tracks the share of the analyzed documents found infected over a sliding window, and tells when
+// it rises above a threshold. The window is split into alertBuckets buckets, the oldest of which is dropped as the
+// window slides.
+type infectedRateDetector struct {
+	window      time.Duration
+	threshold   float64
+	minAnalyzed int64
+
+	mu      sync.Mutex
+	buckets [alertBuckets]rateBucket
+	firing  bool // firing reports whether the rate stayed above the threshold since the last alert
+}
+
+// rateBucket counts the verdicts of a slot of the window, the slots being numbered from the Unix epoch.
+type rateBucket struct {
+	slot               int64
+	infected, analyzed int64
+}
+
+// newInfectedRateDetector creates a detector of the infected rate rising above threshold over window, raising no alert
+// below minAnalyzed verdicts.
+func newInfectedRateDetector(window time.Duration, threshold float64, minAnalyzed int64) *infectedRateDetector {
+	return &infectedRateDetector{window: max(window, alertBuckets), threshold: threshold, minAnalyzed: minAnalyzed}
+}
+
+// slot returns the slot of the window holding t.
+func (d *infectedRateDetector) slot(t time.Time) int64 {
+	return t.UnixNano() / int64(d.window/alertBuckets)
+}
+
+// observe counts a verdict given at now, infected or clean, and returns an alert if the rate just rose above the
+// threshold, nil otherwise. Once raised, no alert is raised again until the rate falls back to the threshold.
+func (d *infectedRateDetector) observe(infected bool, now time.Time) *domain.InfectedRateAlert {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	slot := d.slot(now)
+	b := &d.buckets[slot%alertBuckets]
+	if b.slot != slot {
+		*b = rateBucket{slot: slot}
+	}
+	b.analyzed++
+	if infected {
+		b.infected++
+	}
+
+	inf, analyzed := d.count(slot)
+	rate := float64(inf) / float64(analyzed)
+	switch {
+	case rate <= d.threshold:
+		d.firing = false
+	case analyzed >= d.minAnalyzed && !d.firing:
+		d.firing = true
+		return &domain.InfectedRateAlert{At: now, Window: d.window, Threshold: d.threshold, Rate: rate, Infected: inf, Analyzed: analyzed}
+	}
+	return nil
+}
+
+// rate returns the infected rate over the window ending at now, zero without verdicts.
+func (d *infectedRateDetector) rate(now time.Time) float64 {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	inf, analyzed := d.count(d.slot(now))
+	if analyzed == 0 {
+		return 0
+	}
+	return float64(inf) / float64(analyzed)
+}
+
+// count returns the verdicts counted by the buckets of the window ending with slot. d.mu must be held.
+func (d *infectedRateDetector) count(slot int64) (infected, analyzed int64) {
+	for _, b := range d.buckets {
+		if b.slot <= slot && slot-b.slot < alertBuckets {
+			infected += b.infected
+			analyzed += b.analyzed
+		}
+	}
+	return infected, analyzed
+}
+
+// observeVerdict counts the verdict status in the infected rate, and notifies the alert notifiers when the rate rises
+// above the threshold. The statuses other than the verdicts of an antivirus are ignored.
+func (s *Service) observeVerdict(status domain.AnalysisStatus) {
+	if s.infectedRate == nil || !status.IsVerdict() {
+		return
+	}
+	if alert := s.infectedRate.observe(status == domain.StatusInfected, time.Now()); alert != nil {
+		go s.notifyAlert(alert)
+	}
+}
+
+// notifyAlert delivers alert with each alert notifier, a notifier failing to deliver it does not keep the others from
+// delivering it.
+func (s *Service) notifyAlert(alert *domain.InfectedRateAlert) {
+	ctx, cancel := context.WithTimeout(context.Background(), alertTimeout)
+	defer cancel()
+
+	for _, n := range s.alertNotifiers {
+		if err := n.Notify(ctx, alert); err != nil {
+			slog.Error("service - alert delivery failed", "error", err)
+		}
+	}
+}
@@ -0,0 +1,74 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"log/slog"
+)
+
+// Keys of the original values sealed along with a pseudonymized document.
+const (
+	sealedTag      = "tag"
+	sealedOrigin   = "origin"
+	sealedFileName = "file_name"
+)
+
+// pseudonym returns the value stored in place of a tag, its pseudonym if the service pseudonymizes tags.
+func (s *Service) pseudonym(value string) string {
+	if s.anonymizer == nil {
+		return value
+	}
+	return s.anonymizer.Pseudonym(value)
+}
+
+// protect replaces the tag, origin and file name of a document about to be saved by their pseudonyms,
+// and seals their original values if the anonymizer keeps them.
+func (s *Service) protect(doc *domain.Document) error {
+	if s.anonymizer == nil {
+		return nil
+	}
+	originals := make(map[string]string, 3)
+	if doc.Tag != "" {
+		originals[sealedTag] = doc.Tag
+	}
+	if doc.Origin != "" {
+		originals[sealedOrigin] = doc.Origin
+	}
+	if doc.FileName != "" {
+		originals[sealedFileName] = doc.FileName
+	}
+	sealed, err := s.anonymizer.Seal(originals)
+	if err != nil {
+		return fmt.Errorf("service: %w", err)
+	}
+	doc.Tag = s.anonymizer.Pseudonym(doc.Tag)
+	doc.Origin = s.anonymizer.Pseudonym(doc.Origin)
+	doc.FileName = s.anonymizer.Pseudonym(doc.FileName)
+	doc.Sealed = sealed
+	return nil
+}
+
+// reveal returns a copy of a retrieved document with its original tag, origin and file name when they were kept,
+// the document itself is returned otherwise.
+func (s *Service) reveal(ctx context.Context, stored *domain.Document) *domain.Document {
+	if s.anonymizer == nil || stored.Sealed == "" {
+		return stored
+	}
+	originals, err := s.anonymizer.Open(stored.Sealed)
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to reveal the original values of a document", "error", err, "tenant", stored.Tenant, "ID", stored.ID)
+		return stored
+	}
+	doc := *stored
+	if tag, ok := originals[sealedTag]; ok {
+		doc.Tag = tag
+	}
+	if origin, ok := originals[sealedOrigin]; ok {
+		doc.Origin = origin
+	}
+	if name, ok := originals[sealedFileName]; ok {
+		doc.FileName = name
+	}
+	return &doc
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"log/slog"
+)
+
+// verdict is the outcome of the analysis of the data of a document.
+type verdict struct {
+	status  domain.AnalysisStatus
+	threat  string                // threat is the name of the threat found in infected data, if known.
+	archive *domain.ArchiveReport // archive is the verdict on each file of an extracted archive, nil otherwise.
+}
+
+// analyze analyzes the data of a document of size bytes read from r. When the service has an archive analyzer,
+// an archive is extracted and analyzed file by file, and the verdict on each of them is returned. The data is
+// analyzed as a whole otherwise, as well as when it is not an archive, is corrupted or exceeds the extraction limits.
+func (s *Service) analyze(ctx context.Context, r io.Reader, size int64) (verdict, error) {
+	if s.archiveAnalyzer == nil {
+		return s.analyzeWhole(ctx, r)
+	}
+
+	// The data is read a first time to be extracted, then from its start again if it is analyzed as a whole.
+	sr, cleanup, err := s.spool.rewindable(r, size)
+	if err != nil {
+		return verdict{status: domain.StatusPending}, err
+	}
+	defer cleanup()
+
+	report, err := s.archiveAnalyzer.AnalyzeArchive(ctx, sr, sr.Size())
+	switch {
+	case err == nil:
+		v := verdict{status: domain.StatusClean, archive: report}
+		if report.Status == domain.StatusInfected.String() {
+			v.status = domain.StatusInfected
+			v.threat = report.Threat()
+		}
+		return v, nil
+	case errors.Is(err, port.ErrNotAnArchive):
+	case errors.Is(err, port.ErrInvalidArchive), errors.Is(err, port.ErrArchiveLimitExceeded):
+		slog.WarnContext(ctx, "service - archive analyzed as a whole", "error", err)
+	default:
+		return verdict{status: domain.StatusPending}, err
+	}
+	return s.analyzeWhole(ctx, io.NewSectionReader(sr, 0, sr.Size()))
+}
+
+// analyzeWhole analyzes data as a whole with the analyzer of the service, see analyzeWith.
+func (s *Service) analyzeWhole(ctx context.Context, data io.Reader) (verdict, error) {
+	return analyzeWith(ctx, s.AvAnalyzer, data)
+}
+
+// analyzeWith analyzes data as a whole with a, naming the threat found when a is a port.ThreatIdentifier.
+func analyzeWith(ctx context.Context, a port.AntivirusAnalyzer, data io.Reader) (verdict, error) {
+	if t, ok := a.(port.ThreatIdentifier); ok {
+		status, threat, err := t.AnalyzeThreat(ctx, data)
+		return verdict{status: status, threat: threat}, err
+	}
+	status, err := a.Analyze(ctx, data)
+	return verdict{status: status}, err
+}
@@ -0,0 +1,20 @@
+package service
+
+import (
+	"fmt"
+	"goyav/internal/core/port"
+)
+
+// checkBacklog returns port.ErrServiceOverloaded when maxQueuedAnalyses analyses or more are waiting for a slot of the
+// scheduler. The analyses counted in pendingAnalyses but not running are waiting, whether they reached the scheduler
+// yet or not.
+func (s *Service) checkBacklog() error {
+	if s.maxQueuedAnalyses <= 0 {
+		return nil
+	}
+	queued := s.pendingAnalyses.Load() - int64(s.scheduler.stats().running)
+	if queued >= s.maxQueuedAnalyses {
+		return fmt.Errorf("service: %w: %d analyses waiting for a slot, the maximum is %d", port.ErrServiceOverloaded, queued, s.maxQueuedAnalyses)
+	}
+	return nil
+}
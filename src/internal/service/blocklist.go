@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"time"
+)
+
+// blockedVerdict returns the infected verdict of the content with the given hash if it is blocklisted by the hash
+// blocklist of the service, if any. A failed lookup is only logged, the content is then analyzed.
+func (s *Service) blockedVerdict(ctx context.Context, hash string) (domain.CachedVerdict, bool) {
+	if s.hashBlocklist == nil {
+		return domain.CachedVerdict{}, false
+	}
+	threat, blocked, err := s.hashBlocklist.Lookup(ctx, hash)
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to look the hash up in the blocklist", "error", err, "hash", hash)
+		return domain.CachedVerdict{}, false
+	}
+	if !blocked {
+		return domain.CachedVerdict{}, false
+	}
+	slog.WarnContext(ctx, "service - blocklisted content uploaded", "hash", hash, "threat", threat)
+	return domain.CachedVerdict{Status: domain.StatusInfected, Threat: threat, AnalyzedAt: time.Now()}, true
+}
@@ -0,0 +1,113 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+)
+
+// callbackOutboxBatch is the maximum number of notifications claimed at once from the callback outbox.
+const callbackOutboxBatch = 32
+
+// validateCallback checks the callback URL carried by ctx, if any, with the callback notifier of the service.
+func (s *Service) validateCallback(ctx context.Context) error {
+	callbackURL := domain.CallbackURLFromContext(ctx)
+	if callbackURL == "" {
+		return nil
+	}
+	if s.callbacks == nil {
+		return fmt.Errorf("service: %w", port.ErrServiceCallbacksDisabled)
+	}
+	if err := s.callbacks.Validate(callbackURL); err != nil {
+		return fmt.Errorf("service: %w: %w", port.ErrServiceInvalidCallbackURL, err)
+	}
+	return nil
+}
+
+// notifyCallback sends the document identified by ID, once analyzed, to the callback URL carried by ctx, if any.
+// A failure is only logged, the client can still retrieve the document. The callback URL is not logged, since it
+// may hold a secret of the client.
+func (s *Service) notifyCallback(ctx context.Context, ID string) {
+	callbackURL := domain.CallbackURLFromContext(ctx)
+	if callbackURL == "" || s.callbacks == nil {
+		return
+	}
+	doc, err := s.DocumentRepository.Get(ctx, ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to retrieve the document to notify its callback", "error", err, "ID", ID)
+		return
+	}
+	if err = s.callbacks.Notify(ctx, callbackURL, s.reveal(ctx, doc)); err != nil {
+		slog.ErrorContext(ctx, "service - failed to notify the callback of the upload", "error", err, "ID", ID)
+		return
+	}
+	slog.DebugContext(ctx, "callback notified", "ID", ID)
+}
+
+// queueCallback records the callback URL carried by ctx, if any, in the callback outbox of the service, to be notified
+// of the document identified by ID once it has a final status, see port.CallbackOutbox. It returns ctx without the
+// callback URL once recorded, so that it is not notified directly as well. Without an outbox, or if it failed, ctx is
+// returned as is and the callback URL notified directly, see notifyCallback.
+func (s *Service) queueCallback(ctx context.Context, ID string) context.Context {
+	callbackURL := domain.CallbackURLFromContext(ctx)
+	if callbackURL == "" || s.callbacks == nil || s.callbackOutbox == nil {
+		return ctx
+	}
+	if err := s.callbackOutbox.AddCallback(ctx, ID, callbackURL); err != nil {
+		slog.ErrorContext(ctx, "service - failed to record the callback of the upload, notified directly", "error", err, "ID", ID)
+		return ctx
+	}
+	return domain.ContextWithCallbackURL(ctx, "")
+}
+
+// dispatchCallbacks periodically delivers the due notifications of the callback outbox of all the tenants. It runs
+// indefinitely.
+func (s *Service) dispatchCallbacks() {
+	ticker := time.NewTicker(s.outboxInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		for {
+			deliveries, err := s.callbackOutbox.ClaimCallbacks(context.Background(), callbackOutboxBatch, s.outboxLease)
+			if err != nil {
+				slog.Error("service - failed to claim the callbacks to notify", "error", err)
+				break
+			}
+			for _, d := range deliveries {
+				s.deliverCallback(d)
+			}
+			if len(deliveries) < callbackOutboxBatch {
+				break
+			}
+		}
+	}
+}
+
+// deliverCallback notifies the callback URL of d within the lease of its claim, then removes d from the outbox unless
+// it failed and may be retried: the notifications of the deleted documents, and those claimed outboxAttempts times,
+// are given up. The callback URL is not logged, since it may hold a secret of the client.
+func (s *Service) deliverCallback(d domain.CallbackDelivery) {
+	ctx, cancel := context.WithTimeout(domain.ContextWithTenant(context.Background(), d.Tenant), s.outboxLease)
+	defer cancel()
+
+	doc, err := s.DocumentRepository.Get(ctx, d.DocumentID)
+	if err == nil {
+		err = s.callbacks.Notify(ctx, d.CallbackURL, s.reveal(ctx, doc))
+	}
+	switch {
+	case err == nil:
+		slog.DebugContext(ctx, "callback notified", "ID", d.DocumentID, "attempts", d.Attempts)
+	case errors.Is(err, port.ErrDocumentNotFound) || d.Attempts >= s.outboxAttempts:
+		slog.ErrorContext(ctx, "service - callback of the upload given up", "error", err, "ID", d.DocumentID, "attempts", d.Attempts)
+	default:
+		slog.WarnContext(ctx, "service - failed to notify the callback of the upload, to be retried", "error", err, "ID", d.DocumentID, "attempts", d.Attempts)
+		return
+	}
+	if err = s.callbackOutbox.RemoveCallback(context.Background(), d.ID); err != nil {
+		slog.Error("service - failed to remove a notified callback", "error", err, "ID", d.DocumentID)
+	}
+}
@@ -0,0 +1,28 @@
+package service
+
+import (
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+)
+
+// Concurrency returns the current capacity of the scheduler of the analyses, along with the analyses holding a slot
+// and waiting for one.
+func (s *Service) Concurrency() *domain.Concurrency {
+	st := s.scheduler.stats()
+	return &domain.Concurrency{Limit: st.capacity, Running: st.running, Queued: st.queued}
+}
+
+// SetConcurrency changes the capacity of the scheduler of the analyses until the service restarts, so that the
+// analyses can be throttled while the analyzer or the binary repository is degraded. Unlike GOYAV_SEMAPHORE_CAPACITY,
+// the limit may be set under DefaultSemaphoreCapacity.
+func (s *Service) SetConcurrency(limit int) (*domain.Concurrency, error) {
+	if limit < 1 {
+		return nil, fmt.Errorf("%w: %d, expected at least 1", port.ErrServiceInvalidConcurrency, limit)
+	}
+	previous := s.scheduler.stats().capacity
+	s.scheduler.setCapacity(limit)
+	slog.Info("analysis concurrency changed", "previous", previous, "limit", limit)
+	return s.Concurrency(), nil
+}
@@ -0,0 +1,26 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"goyav/internal/core/domain"
+	"goyav/pkg/helper"
+)
+
+// tenantDedupePolicy returns the deduplication policy of the tenant carried by ctx.
+func (s *Service) tenantDedupePolicy(ctx context.Context) domain.DedupePolicy {
+	if p, ok := s.dedupePolicies[domain.TenantFromContext(ctx)]; ok {
+		return p
+	}
+	return s.dedupePolicy
+}
+
+// uniqueMD5ID returns an MD5 ID derived from md5ID, the ID derived from the data and the tag of an upload, and from
+// a random nonce, so that the uploads of the same data under the same tag get different IDs.
+func uniqueMD5ID(md5ID string) (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return helper.NewID(md5ID + string(nonce)), nil
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"time"
+)
+
+// DeleteDocument soft-deletes a document of the tenant carried by ctx: it is reported as deleted and no longer
+// deduplicates the uploads of the same data, but it is kept until the purge removes it, after the result TTL, so
+// that an accidental deletion can be undone with RestoreDocument meanwhile. Its binary data, if retained, is kept
+// as well. It returns the deleted document.
+func (s *Service) DeleteDocument(ctx context.Context, ID string) (*domain.Document, error) {
+	doc, err := s.GetDocument(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	deletedAt := time.Now()
+	if err = s.DocumentRepository.SoftDelete(ctx, ID, deletedAt); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceDeleteDocumentFailed, err)
+	}
+	doc.DeletedAt = deletedAt
+	return doc, nil
+}
+
+// RestoreDocument restores a soft-deleted document of the tenant carried by ctx, which has not been purged yet,
+// and returns it.
+func (s *Service) RestoreDocument(ctx context.Context, ID string) (*domain.Document, error) {
+	if !helper.IsValidID(ID) {
+		return nil, fmt.Errorf("service: %w: the provided ID is not valid", port.ErrServiceInvalidID)
+	}
+	doc, err := s.DocumentRepository.Get(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: id=%s", port.ErrServiceGetDocumentFailed, err, ID)
+	}
+	if !doc.IsDeleted() {
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceDocumentNotDeleted, ID)
+	}
+	if err = s.DocumentRepository.Restore(ctx, ID); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceDeleteDocumentFailed, err)
+	}
+	doc.DeletedAt = time.Time{}
+	return s.reveal(ctx, doc), nil
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"slices"
+)
+
+// engineNames returns the names of all the engines of the service, sorted.
+func (s *Service) engineNames() []string {
+	names := []string{domain.EngineClamAV}
+	for name := range s.engines {
+		if name != domain.EngineClamAV {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+	return names
+}
+
+// selectEngines returns the names of the engines selected by the upload of ctx, all the engines of the service when it
+// selects none, or port.ErrServiceUnknownEngine if the service does not have one of them.
+func (s *Service) selectEngines(ctx context.Context) ([]string, error) {
+	selected := domain.EnginesFromContext(ctx)
+	if selected == nil {
+		return s.engineNames(), nil
+	}
+	for _, name := range selected {
+		if _, ok := s.engines[name]; !ok && name != domain.EngineClamAV {
+			return nil, fmt.Errorf("service: %w: %s", port.ErrServiceUnknownEngine, name)
+		}
+	}
+	return selected, nil
+}
+
+// analyzeEngines analyzes the data of size bytes of a document with each engine carried by ctx, all of them if there
+// are none, one after the other, until one of them finds a threat. The engines the service no longer has are skipped,
+// and the data is analyzed by AvAnalyzer if none is left. The verdict of AvAnalyzer carries the report of the analysis
+// of an archive, see attemptAnalysis, while the other engines analyze the data as a whole.
+func (s *Service) analyzeEngines(ctx context.Context, ID string, size int64) (verdict, error) {
+	selected := domain.EnginesFromContext(ctx)
+	if selected == nil {
+		selected = s.engineNames()
+	}
+	var (
+		v   verdict
+		ran bool
+	)
+	for _, name := range selected {
+		var (
+			ev  verdict
+			err error
+		)
+		a, ok := s.engines[name]
+		switch {
+		case name == domain.EngineClamAV:
+			ev, err = s.attemptAnalysis(ctx, ID, size)
+		case ok:
+			if ev, err = s.attemptWith(ctx, ID, a); err != nil {
+				err = fmt.Errorf("analysis by %s %w", name, err)
+			}
+		default:
+			slog.WarnContext(ctx, "service - unknown engine skipped", "engine", name, "ID", ID)
+			continue
+		}
+		if err != nil {
+			return verdict{status: domain.StatusPending}, err
+		}
+		if !ran || ev.status == domain.StatusInfected {
+			v.status, v.threat = ev.status, ev.threat
+		}
+		if ev.archive != nil {
+			v.archive = ev.archive
+		}
+		ran = true
+		if v.status == domain.StatusInfected {
+			break
+		}
+	}
+	if !ran {
+		return s.attemptAnalysis(ctx, ID, size)
+	}
+	return v, nil
+}
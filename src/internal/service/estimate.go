@@ -0,0 +1,79 @@
+package service
+
+import (
+	"math/bits"
+	"sync"
+	"time"
+)
+
+const (
+	// DefaultAnalysisDuration is the duration assumed for an analysis until one has completed.
+	DefaultAnalysisDuration = 2 * time.Second
+
+	// durationWeight is the weight of the last analysis in the moving averages of the durations.
+	durationWeight = 0.2
+)
+
+// durationEstimator keeps exponential moving averages of the durations of the analyses, overall
+// and by size class, a size class holding the sizes of the same power of two.
+type durationEstimator struct {
+	mux     sync.Mutex
+	overall time.Duration
+	classes map[int]time.Duration
+}
+
+// record takes the duration of the analysis of a data of size bytes into account.
+func (e *durationEstimator) record(size int64, d time.Duration) {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if e.classes == nil {
+		e.classes = make(map[int]time.Duration)
+	}
+	e.overall = movingAverage(e.overall, d)
+	class := sizeClass(size)
+	e.classes[class] = movingAverage(e.classes[class], d)
+}
+
+// estimate returns the expected duration of the analysis of a data of size bytes: the average of its size class,
+// the overall average when no data of its size class was analyzed yet, or DefaultAnalysisDuration.
+func (e *durationEstimator) estimate(size int64) time.Duration {
+	e.mux.Lock()
+	defer e.mux.Unlock()
+	if d, ok := e.classes[sizeClass(size)]; ok {
+		return d
+	}
+	return e.average()
+}
+
+// average returns the overall average duration of the analyses, or DefaultAnalysisDuration. e must be locked.
+func (e *durationEstimator) average() time.Duration {
+	if e.overall == 0 {
+		return DefaultAnalysisDuration
+	}
+	return e.overall
+}
+
+func sizeClass(size int64) int {
+	return bits.Len64(uint64(max(size, 0)))
+}
+
+func movingAverage(avg, d time.Duration) time.Duration {
+	if avg == 0 {
+		return d
+	}
+	return time.Duration(durationWeight*float64(d) + (1-durationWeight)*float64(avg))
+}
+
+// EstimateCompletion estimates when the analysis of a document of size bytes, uploaded last, will complete. The
+// pending analyses ahead of it run by batches of the capacity of the scheduler, each batch taking the average
+// duration of an analysis, then the analysis of the document takes the average duration for its size.
+func (s *Service) EstimateCompletion(size int64) time.Time {
+	ahead := max(s.pendingAnalyses.Load()-1, 0)
+	batches := ahead / int64(s.scheduler.stats().capacity)
+
+	s.durations.mux.Lock()
+	wait := time.Duration(batches) * s.durations.average()
+	s.durations.mux.Unlock()
+
+	return time.Now().Add(wait + s.durations.estimate(size))
+}
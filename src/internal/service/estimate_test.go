@@ -0,0 +1,43 @@
+package service
+
+import (
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEstimateCompletion(t *testing.T) {
+	svc, err := New(binaryrepo.NewMock(), docrepo.NewMock(), antivirus.NewMock(), version, info, 0, semaphoreCapacity)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	within := func(t *testing.T, want time.Duration, got time.Time) {
+		t.Helper()
+		assert.WithinDuration(t, time.Now().Add(want), got, 100*time.Millisecond)
+	}
+
+	t.Run("NoHistory", func(t *testing.T) {
+		within(t, DefaultAnalysisDuration, svc.EstimateCompletion(1<<10))
+	})
+
+	t.Run("SizeClasses", func(t *testing.T) {
+		svc.durations.record(1<<10, time.Second)
+		svc.durations.record(1<<20, 10*time.Second)
+		within(t, time.Second, svc.EstimateCompletion(1<<10))
+		within(t, 10*time.Second, svc.EstimateCompletion(1<<20))
+
+		// no history for its size class: the overall average
+		within(t, svc.durations.average(), svc.EstimateCompletion(1<<30))
+	})
+
+	t.Run("Queue", func(t *testing.T) {
+		// a full batch of analyses ahead of the document
+		svc.pendingAnalyses.Store(int64(svc.scheduler.capacity) + 1)
+		defer svc.pendingAnalyses.Store(0)
+		within(t, svc.durations.average()+time.Second, svc.EstimateCompletion(1<<10))
+	})
+}
@@ -0,0 +1,40 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"goyav/internal/core/port"
+)
+
+// attemptFallback analyzes the data of a document as a whole with the fallback analyzer, retrying as the retry policy
+// of the service allows, see attemptAnalysis.
+func (s *Service) attemptFallback(ctx context.Context, ID string) (verdict, error) {
+	v, err := s.attemptWith(ctx, ID, s.fallbackAnalyzer)
+	if err != nil {
+		return v, err
+	}
+	s.fallbackAnalyses.Add(1)
+	return v, nil
+}
+
+// attemptWith analyzes the data of a document as a whole with a, retrying as the retry policy of the service allows.
+// Missing data is not retried, nor is data larger than a accepts.
+func (s *Service) attemptWith(ctx context.Context, ID string, a port.AntivirusAnalyzer) (verdict, error) {
+	var v verdict
+	err := s.retryPolicy.retry(ctx, func() error {
+		r, err := s.BinayRepository.Get(ctx, ID)
+		if errors.Is(err, port.ErrBinaryNotFound) {
+			return permanentError{err}
+		}
+		if err != nil {
+			return err
+		}
+		defer r.Close()
+		v, err = analyzeWith(ctx, a, r)
+		if errors.Is(err, port.ErrAntivirusSizeLimitExceeded) {
+			return permanentError{err}
+		}
+		return err
+	})
+	return v, err
+}
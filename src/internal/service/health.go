@@ -0,0 +1,111 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"slices"
+	"sync"
+	"time"
+)
+
+// HealthCheckTimeout is the time given to each dependency to answer a health check.
+const HealthCheckTimeout = 5 * time.Second
+
+// dependencyCheck is a health check of a dependency of the service.
+type dependencyCheck struct {
+	name string
+	ping func() error
+}
+
+// healthCache holds the last health report of the service, reused for ttl.
+type healthCache struct {
+	mux  sync.Mutex
+	ttl  time.Duration
+	last *domain.Health
+}
+
+// Health reports the health of the dependencies of the service, see checkHealth. When the health cache is enabled,
+// the last report is reused while it is younger than its time-to-live, and the concurrent calls wait for a single
+// check, so that frequent health probes do not reach the dependencies on every call.
+func (s *Service) Health(ctx context.Context) *domain.Health {
+	if s.health.ttl <= 0 {
+		return s.checkHealth(ctx)
+	}
+	s.health.mux.Lock()
+	defer s.health.mux.Unlock()
+	if s.health.last == nil || time.Since(s.health.last.CheckedAt) >= s.health.ttl {
+		// the report is shared by the calls to come, it must not depend on the cancellation of this one
+		s.health.last = s.checkHealth(context.WithoutCancel(ctx))
+	}
+	health := *s.health.last
+	health.Dependencies = slices.Clone(health.Dependencies)
+	return &health
+}
+
+// checkHealth checks the dependencies of the service concurrently, within HealthCheckTimeout, and reports the status,
+// latency and error of each of them. The repositories are not checked by a service without them, see NewAnalyzer, and
+// the quota repository is only checked when quotas are enabled.
+func (s *Service) checkHealth(ctx context.Context) *domain.Health {
+	var checks []dependencyCheck
+	if s.BinayRepository != nil {
+		checks = append(checks, dependencyCheck{domain.DependencyBinaryRepository, s.BinayRepository.Ping})
+	}
+	if s.DocumentRepository != nil {
+		checks = append(checks, dependencyCheck{domain.DependencyDocumentRepository, s.DocumentRepository.Ping})
+	}
+	checks = append(checks, dependencyCheck{domain.DependencyAntivirusAnalyzer, s.AvAnalyzer.Ping})
+	if s.quotaRepository != nil {
+		checks = append(checks, dependencyCheck{domain.DependencyQuotaRepository, s.quotaRepository.Ping})
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, HealthCheckTimeout)
+	defer cancel()
+
+	health := &domain.Health{
+		Status:       domain.HealthUp,
+		CheckedAt:    time.Now(),
+		Dependencies: make([]domain.DependencyHealth, len(checks)),
+	}
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			health.Dependencies[i] = checkDependency(ctx, c)
+		}()
+	}
+	wg.Wait()
+
+	for _, dep := range health.Dependencies {
+		if dep.Status != domain.HealthUp {
+			health.Status = domain.HealthDown
+			slog.WarnContext(ctx, "service - dependency down", "dependency", dep.Name, "error", dep.Error)
+		}
+	}
+	return health
+}
+
+// checkDependency runs a health check, giving up when ctx is done.
+func checkDependency(ctx context.Context, c dependencyCheck) domain.DependencyHealth {
+	start := time.Now()
+	done := make(chan error, 1)
+	go func() {
+		done <- c.ping()
+	}()
+
+	dep := domain.DependencyHealth{Name: c.name, Status: domain.HealthUp}
+	select {
+	case err := <-done:
+		if err != nil {
+			dep.Status = domain.HealthDown
+			dep.Error = err.Error()
+		}
+	case <-ctx.Done():
+		dep.Status = domain.HealthDown
+		dep.Error = fmt.Sprintf("no answer: %v", ctx.Err())
+	}
+	dep.LatencyMS = float64(time.Since(start).Microseconds()) / 1000
+	return dep
+}
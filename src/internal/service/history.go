@@ -0,0 +1,30 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+)
+
+// DocumentHistory retrieves the events of a document identified by its ID, deleted or not, the oldest first, from the
+// log of the changes of the documents kept by the document repository. It returns port.ErrServiceHistoryUnavailable
+// if the repository keeps none.
+func (s *Service) DocumentHistory(ctx context.Context, ID string) ([]domain.DocumentEvent, error) {
+	if !helper.IsValidID(ID) {
+		return nil, fmt.Errorf("service: %w: the provided ID is not valid", port.ErrServiceInvalidID)
+	}
+	log, ok := s.DocumentRepository.(port.DocumentEventLog)
+	if !ok {
+		return nil, fmt.Errorf("service: %w", port.ErrServiceHistoryUnavailable)
+	}
+	if _, err := s.DocumentRepository.Get(ctx, ID); err != nil {
+		return nil, fmt.Errorf("%w: %w: id=%s", port.ErrServiceGetDocumentFailed, err, ID)
+	}
+	events, err := log.Events(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: id=%s", err, ID)
+	}
+	return events, nil
+}
@@ -0,0 +1,43 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"io"
+	"log/slog"
+)
+
+// AnalyzeImage analyzes the files of the layers of the container image tarball read from data synchronously.
+// The analysis takes a slot of the scheduler of the service with the priority carried by ctx, like the analyses
+// of documents, and its report is not stored.
+func (s *Service) AnalyzeImage(ctx context.Context, data io.Reader, size int64) (*domain.ImageReport, error) {
+	if s.imageAnalyzer == nil {
+		return nil, fmt.Errorf("service: %w", port.ErrServiceImagesDisabled)
+	}
+
+	// The tarball is read once to resolve its layers, then to unpack them.
+	sr, cleanup, err := s.spool.rewindable(data, size)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceAnalyzeImageFailed, err)
+	}
+	defer cleanup()
+
+	s.scheduler.acquire(domain.PriorityFromContext(ctx))
+	defer s.scheduler.release()
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceAnalyzeImageFailed, err)
+	}
+	defer release()
+
+	report, err := s.imageAnalyzer.AnalyzeImage(ctx, sr, sr.Size())
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceAnalyzeImageFailed, err)
+	}
+	if report.Status == domain.StatusInfected.String() {
+		slog.WarnContext(ctx, "service - infected image", "tenant", domain.TenantFromContext(ctx), "layers", len(report.Layers), "files", report.Files)
+	}
+	return report, nil
+}
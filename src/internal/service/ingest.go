@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// IngestVerdict records the verdict reported by an on-access scanning agent on a file of the tenant carried by ctx,
+// as an externally-sourced document. The document of a file is identified by its hash and origin, so that a later
+// verdict on the same file updates it. It returns the ID of the document and whether it was created.
+func (s *Service) IngestVerdict(ctx context.Context, v domain.ExternalVerdict) (ID string, created bool, err error) {
+	v.Hash = strings.ToLower(v.Hash)
+	if !helper.IsValidHash(v.Hash) || v.Host == "" || v.Path == "" || !v.Status.IsVerdict() {
+		return "", false, fmt.Errorf("service: %w: hash=%q origin=%q status=%v", port.ErrServiceInvalidVerdict, v.Hash, v.Origin(), v.Status)
+	}
+	if v.ScannedAt.IsZero() || v.ScannedAt.After(time.Now()) {
+		v.ScannedAt = time.Now()
+	}
+
+	tenant := domain.TenantFromContext(ctx)
+	ID = helper.NewID(strings.Join([]string{tenant, string(domain.SourceOnAccess), v.Origin(), v.Hash}, "/"))
+	if v.Status == domain.StatusInfected {
+		slog.WarnContext(ctx, "service - on-access infection reported", "tenant", tenant, "ID", ID, "origin", s.pseudonym(v.Origin()), "signature", v.Signature)
+	}
+
+	_, err = s.DocumentRepository.Get(ctx, ID)
+	switch {
+	case err == nil:
+		if err = s.DocumentRepository.UpdateStatus(ctx, ID, v.Status, v.Signature, v.ScannedAt); err != nil {
+			return "", false, fmt.Errorf("%w: %w", port.ErrServiceIngestVerdictFailed, err)
+		}
+		return ID, false, nil
+	case !errors.Is(err, port.ErrDocumentNotFound):
+		return "", false, fmt.Errorf("%w: %w", port.ErrServiceIngestVerdictFailed, err)
+	}
+
+	doc := &domain.Document{
+		ID:         ID,
+		Tenant:     tenant,
+		Hash:       v.Hash,
+		HashAlgo:   domain.DefaultHashAlgo,
+		Status:     v.Status,
+		AnalyzedAt: v.ScannedAt,
+		Threat:     v.Signature,
+		CreatedAt:  time.Now(),
+		Source:     domain.SourceOnAccess,
+		Origin:     v.Origin(),
+		FileName:   helper.SanitizeFileName(v.Path),
+	}
+	if err = s.protect(doc); err != nil {
+		return "", false, fmt.Errorf("%w: %w", port.ErrServiceIngestVerdictFailed, err)
+	}
+	if err = s.DocumentRepository.Save(ctx, doc); err != nil {
+		return "", false, fmt.Errorf("%w: %w", port.ErrServiceIngestVerdictFailed, err)
+	}
+	return ID, true, nil
+}
@@ -0,0 +1,45 @@
+package service
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"time"
+)
+
+// Metrics returns the current measures of the scheduler of the analyses, the count of the stuck pending documents and
+// the infected rate, along with those of the repositories, of the analyzer, of the verdict cache and of the hash
+// blocklist of the service implementing port.MetricsReporter.
+func (s *Service) Metrics(ctx context.Context) []domain.Metric {
+	metrics := s.schedulerMetrics()
+	if s.stuckThreshold > 0 {
+		metrics = append(metrics, domain.Metric{Name: "goyav_stuck_pending_documents", Help: "Number of documents pending for longer than GOYAV_STUCK_PENDING_THRESHOLD at the last count.", Kind: domain.MetricGauge, Value: float64(s.stuckPending.Load())})
+	}
+	if s.infectedRate != nil {
+		metrics = append(metrics, domain.Metric{Name: "goyav_infected_rate", Help: "Share of the analyses found infected over the window of GOYAV_ALERT_INFECTED_RATE.", Kind: domain.MetricGauge, Value: s.infectedRate.rate(time.Now())})
+	}
+	for _, dep := range []any{s.DocumentRepository, s.BinayRepository, s.AvAnalyzer, s.verdictCache, s.hashBlocklist} {
+		if r, ok := dep.(port.MetricsReporter); ok {
+			metrics = append(metrics, r.Metrics()...)
+		}
+	}
+	return metrics
+}
+
+// schedulerMetrics returns the measures of the analyses: the slots of the scheduler taken and waited for, the time spent
+// waiting for them and the attempts retried, from which GOYAV_SEMAPHORE_CAPACITY can be tuned to the analyzer.
+func (s *Service) schedulerMetrics() []domain.Metric {
+	st := s.scheduler.stats()
+	return []domain.Metric{
+		{Name: "goyav_analyses_in_flight", Help: "Number of analyses holding a slot of the scheduler.", Kind: domain.MetricGauge, Value: float64(st.running)},
+		{Name: "goyav_analyses_queued", Help: "Number of analyses waiting for a slot of the scheduler.", Kind: domain.MetricGauge, Value: float64(st.queued)},
+		{Name: "goyav_scheduler_capacity", Help: "Number of analyses run at once, GOYAV_SEMAPHORE_CAPACITY unless changed with PUT /admin/concurrency.", Kind: domain.MetricGauge, Value: float64(st.capacity)},
+		{Name: "goyav_scheduler_utilization", Help: "Share of the slots of the scheduler taken, above 1 while the capacity is being lowered.", Kind: domain.MetricGauge, Value: float64(st.running) / float64(st.capacity)},
+		{Name: "goyav_analyses_started_total", Help: "Total number of analyses given a slot of the scheduler.", Kind: domain.MetricCounter, Value: float64(st.acquired)},
+		{Name: "goyav_analysis_queue_wait_seconds_total", Help: "Total time spent by the analyses waiting for a slot of the scheduler.", Kind: domain.MetricCounter, Value: st.waited.Seconds()},
+		{Name: "goyav_analysis_retries_total", Help: "Total number of attempts of analyses made after a failed attempt.", Kind: domain.MetricCounter, Value: float64(s.analysisRetries.Load())},
+		{Name: "goyav_fallback_analyses_total", Help: "Total number of analyses completed by the fallback analyzer.", Kind: domain.MetricCounter, Value: float64(s.fallbackAnalyses.Load())},
+		{Name: "goyav_reputation_verdicts_total", Help: "Total number of verdicts given by the reputation source instead of an analysis.", Kind: domain.MetricCounter, Value: float64(s.reputationVerdicts.Load())},
+		{Name: "goyav_reputation_errors_total", Help: "Total number of failed lookups of the reputation source.", Kind: domain.MetricCounter, Value: float64(s.reputationErrors.Load())},
+	}
+}
@@ -0,0 +1,302 @@
+package service
+
+import (
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"time"
+)
+
+// Option configures optional behaviours of a Service.
+type Option func(*Service)
+
+// WithAdmissionControl makes the service poll the load of its antivirus analyzer at the given
+// interval and hold back new analyses while the analyzer is saturated. It has no effect when
+// the interval is not strictly positive or when the analyzer does not implement port.LoadReporter.
+func WithAdmissionControl(interval time.Duration) Option {
+	return func(s *Service) {
+		s.loadPollInterval = interval
+	}
+}
+
+// WithHealthCache makes the service reuse its last health report for ttl, rather than checking its dependencies on
+// every call. It has no effect when ttl is not strictly positive.
+func WithHealthCache(ttl time.Duration) Option {
+	return func(s *Service) {
+		s.health.ttl = ttl
+	}
+}
+
+// WithMaxQueuedAnalyses makes the service reject the uploads with port.ErrServiceOverloaded while max analyses or
+// more are waiting for a slot of the scheduler. It has no effect when max is not strictly positive.
+func WithMaxQueuedAnalyses(max int) Option {
+	return func(s *Service) {
+		s.maxQueuedAnalyses = int64(max)
+	}
+}
+
+// WithConcurrencyLimiter makes the service take a slot of l for each analysis, once the analysis has a slot of the
+// scheduler, so that the replicas sharing l run at most as many analyses at once as l allows altogether.
+func WithConcurrencyLimiter(l port.ConcurrencyLimiter) Option {
+	return func(s *Service) {
+		s.concurrencyLimiter = l
+	}
+}
+
+// WithQuotas makes the service enforce quotas on uploads, recording their usage in repo.
+// quotas holds the quotas of specific tenants, the others are given defaultQuota.
+func WithQuotas(repo port.QuotaRepository, defaultQuota domain.Quota, quotas map[string]domain.Quota) Option {
+	return func(s *Service) {
+		s.quotaRepository = repo
+		s.defaultQuota = defaultQuota
+		s.quotas = quotas
+	}
+}
+
+// WithAnonymizer makes the service store the pseudonyms of the tags and origins of documents, given by a,
+// in place of their original values. The original values are only kept if a seals them.
+func WithAnonymizer(a port.Anonymizer) Option {
+	return func(s *Service) {
+		s.anonymizer = a
+	}
+}
+
+// WithImageAnalyzer makes the service analyze container images with a.
+func WithImageAnalyzer(a port.ImageAnalyzer) Option {
+	return func(s *Service) {
+		s.imageAnalyzer = a
+	}
+}
+
+// WithFallbackAnalyzer makes the service analyze the data of a document with a when the analyzer of the service still
+// fails after its retries, so that the documents do not stay pending while it is down. The fallback analyzes the
+// data as a whole, with the same retry policy.
+func WithFallbackAnalyzer(a port.AntivirusAnalyzer) Option {
+	return func(s *Service) {
+		s.fallbackAnalyzer = a
+	}
+}
+
+// WithEngines adds engines, by name, to the analyzer of the service, named domain.EngineClamAV, so that an upload can
+// select the engines analyzing its data, all of them by default. Each engine analyzes the data as a whole, with the
+// retry policy of the service, and the data is infected as soon as one of them finds a threat. The names must be
+// valid engine names, see domain.ValidEngineName, other than domain.EngineClamAV.
+func WithEngines(engines map[string]port.AntivirusAnalyzer) Option {
+	return func(s *Service) {
+		s.engines = engines
+	}
+}
+
+// WithArchiveAnalyzer makes the service extract the zip, tar and gzip archives uploaded with a, to analyze their
+// files one by one and keep the verdict on each of them along with the document.
+func WithArchiveAnalyzer(a port.ArchiveAnalyzer) Option {
+	return func(s *Service) {
+		s.archiveAnalyzer = a
+	}
+}
+
+// WithRetryPolicy sets the schedule of the attempts of the analyses, DefaultRetryPolicy by default.
+// It has no effect when p is not valid.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(s *Service) {
+		if p.Validate() == nil {
+			s.retryPolicy = p
+		}
+	}
+}
+
+// WithAnalysisDeadline sets the maximum duration of an analysis, retries included, DefaultAnalysisDeadline by default.
+// The documents whose analysis exceeds it get the StatusTimeout status. A zero deadline leaves the analyses unbounded.
+func WithAnalysisDeadline(d time.Duration) Option {
+	return func(s *Service) {
+		s.analysisDeadline = max(d, 0)
+	}
+}
+
+// WithIDScheme sets the scheme of the IDs of the uploaded documents, helper.DefaultIDScheme by default.
+func WithIDScheme(scheme helper.IDScheme) Option {
+	return func(s *Service) {
+		s.idScheme = scheme
+	}
+}
+
+// WithHashAlgorithm sets the algorithm hashing the content of the uploaded documents, helper.DefaultHashAlgorithm
+// by default. The documents keep the algorithm of their hash.
+func WithHashAlgorithm(algo helper.HashAlgorithm) Option {
+	return func(s *Service) {
+		s.hashAlgorithm = algo
+	}
+}
+
+// WithMaxUploadSizes sets the maximum size in bytes of the uploads of some tenants, overriding the maximum upload
+// size of the server, which applies to the other tenants.
+func WithMaxUploadSizes(sizes map[string]int64) Option {
+	return func(s *Service) {
+		s.maxUploadSizes = sizes
+	}
+}
+
+// WithSpool makes the service hold the data it reads more than once, such as the streamed uploads, in memory up to
+// memory bytes, and spill the larger data to temporary files in dir, the default directory of the system if empty.
+// Without it, the data is always spilled to the default directory.
+func WithSpool(memory int64, dir string) Option {
+	return func(s *Service) {
+		s.spool = spool{memory: memory, dir: dir}
+	}
+}
+
+// WithPresignedUploads lets the clients upload the binary data of documents directly to the binary repository, with
+// URLs valid for expiry, if it implements port.BinaryPresigner. The presigned uploads of the tenants without a maximum
+// upload size of their own are bounded by maxSize, unless it is zero.
+func WithPresignedUploads(expiry time.Duration, maxSize int64) Option {
+	return func(s *Service) {
+		s.presignExpiry = expiry
+		s.presignMaxSize = maxSize
+	}
+}
+
+// WithRetainedBinaries keeps the binary data of the clean documents after their analysis, rather than deleting it,
+// so that it can be downloaded directly from the binary repository with presigned URLs valid for downloadExpiry,
+// if it implements port.BinaryPresigner. The binary data of the other documents is deleted as usual.
+func WithRetainedBinaries(downloadExpiry time.Duration) Option {
+	return func(s *Service) {
+		s.retainClean = true
+		s.downloadExpiry = downloadExpiry
+	}
+}
+
+// WithQuarantine keeps the binary data of the infected documents after their analysis, rather than deleting it, as
+// evidence. It is protected from deletion if the binary repository implements port.BinaryQuarantiner.
+func WithQuarantine() Option {
+	return func(s *Service) {
+		s.quarantine = true
+	}
+}
+
+// WithVerdictTags records the verdicts of the documents along with their retained binary data, see
+// WithRetainedBinaries and WithQuarantine, if the binary repository implements port.BinaryTagger.
+func WithVerdictTags() Option {
+	return func(s *Service) {
+		s.tagVerdicts = true
+	}
+}
+
+// WithMediaTypePolicy restricts the media types of the uploaded documents, detected from their first 512 bytes.
+// The uploads of other media types are rejected with port.ErrServiceUnsupportedMediaType before being stored.
+func WithMediaTypePolicy(p domain.MediaTypePolicy) Option {
+	return func(s *Service) {
+		s.mediaTypePolicy = p
+	}
+}
+
+// WithTagPolicy sanitizes the tags of the uploaded documents with p rather than helper.DefaultTagPolicy. Since the
+// documents are deduplicated by tag, the re-uploads of the documents saved under another policy may not be recognized.
+func WithTagPolicy(p helper.TagPolicy) Option {
+	return func(s *Service) {
+		s.tagPolicy = p
+	}
+}
+
+// WithCallbacks makes the service accept the uploads carrying a callback URL, see domain.ContextWithCallbackURL,
+// and notify it with n once their analysis completes.
+func WithCallbacks(n port.CallbackNotifier) Option {
+	return func(s *Service) {
+		s.callbacks = n
+	}
+}
+
+// WithStatusRetentions makes the auto-purge keep the documents of the statuses of retentions, e.g. infected documents
+// kept as evidence, for their own retention rather than the result time-to-live, or forever if it is zero. The pending
+// documents are never purged automatically, whatever their retention.
+func WithStatusRetentions(retentions map[domain.AnalysisStatus]time.Duration) Option {
+	return func(s *Service) {
+		s.statusRetentions = retentions
+	}
+}
+
+// WithPurgeSchedule runs the auto-purge at the times of schedule, e.g. nightly, rather than every result time-to-live,
+// which still defines the retention of the documents. It has no effect when the auto-purge is disabled.
+func WithPurgeSchedule(schedule domain.CronSchedule) Option {
+	return func(s *Service) {
+		s.purgeSchedule = schedule
+	}
+}
+
+// WithStuckPendingThreshold makes the service count the documents pending for longer than threshold every minute, or
+// every threshold if shorter, report their number in its metrics and log a warning with their IDs. The documents are
+// not counted when threshold is not strictly positive.
+func WithStuckPendingThreshold(threshold time.Duration) Option {
+	return func(s *Service) {
+		s.stuckThreshold = threshold
+	}
+}
+
+// WithCallbackOutbox makes the service record the callback URLs in the document repository along with the uploads,
+// if it implements port.CallbackOutbox, so that a verdict recorded is notified even if the service stops right after.
+// The due notifications are claimed every interval and hidden from the other replicas for lease, doubled after each
+// claim, and given up after attempts claims. It has no effect without WithCallbacks.
+func WithCallbackOutbox(interval, lease time.Duration, attempts int) Option {
+	return func(s *Service) {
+		s.outboxInterval = interval
+		s.outboxLease = lease
+		s.outboxAttempts = max(attempts, 1)
+	}
+}
+
+// WithHashBlocklist makes the service give the uploads of the content blocklisted by b an infected verdict at once,
+// without storing nor analyzing them, whatever the verdict on the documents of the same content and the deduplication
+// policy.
+func WithHashBlocklist(b port.HashBlocklist) Option {
+	return func(s *Service) {
+		s.hashBlocklist = b
+	}
+}
+
+// WithReputationSource makes the service query r for its verdict on the content of each document before analyzing it,
+// and take it instead of analyzing the content when r is confident about it. Only the content hashed with SHA-256 is
+// looked up, see WithHashAlgorithm.
+func WithReputationSource(r port.ReputationSource) Option {
+	return func(s *Service) {
+		s.reputationSource = r
+	}
+}
+
+// WithVerdictCache makes the service give the uploads of content analyzed already the verdict cached in c, without
+// storing nor analyzing them, and cache the verdicts of its analyses in c.
+func WithVerdictCache(c port.VerdictCache) Option {
+	return func(s *Service) {
+		s.verdictCache = c
+	}
+}
+
+// WithDedupePolicies sets the deduplication policy of the uploads, domain.DedupeStrict by default. policies holds
+// the policies of specific tenants, the others are given defaultPolicy.
+func WithDedupePolicies(defaultPolicy domain.DedupePolicy, policies map[string]domain.DedupePolicy) Option {
+	return func(s *Service) {
+		s.dedupePolicy = defaultPolicy
+		s.dedupePolicies = policies
+	}
+}
+
+// WithInfectedRateAlert makes the service notify notifiers when the share of the analyses found infected over the
+// sliding window rises above threshold, a ratio from 0 to 1, once at least minAnalyzed verdicts were given over the
+// window. It has no effect without notifiers or if threshold is not strictly positive.
+func WithInfectedRateAlert(window time.Duration, threshold float64, minAnalyzed int64, notifiers ...port.AlertNotifier) Option {
+	return func(s *Service) {
+		if threshold <= 0 || len(notifiers) == 0 {
+			return
+		}
+		s.infectedRate = newInfectedRateDetector(window, threshold, minAnalyzed)
+		s.alertNotifiers = notifiers
+	}
+}
+
+// WithReports makes the service summarize the documents of all the tenants on schedule, reporting the top most
+// frequent threats and busiest tenants, and deliver the reports with senders. It has no effect without senders.
+func WithReports(schedule domain.ReportSchedule, top int, senders ...port.ReportSender) Option {
+	return func(s *Service) {
+		s.reportSchedule = schedule
+		s.reportTop = top
+		s.reportSenders = senders
+	}
+}
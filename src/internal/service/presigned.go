@@ -0,0 +1,274 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+	"log/slog"
+	"net/http"
+	"time"
+)
+
+// CreateUpload saves a pending document of the tenant carried by ctx, named after the file name it carries, and returns
+// a presigned URL its binary data can be uploaded to directly, so that large files do not go through the service.
+// The document has no hash until its upload is confirmed with ConfirmUpload, and records the engines carried by ctx,
+// which will analyze its data.
+func (s *Service) CreateUpload(ctx context.Context, tag string) (*domain.PresignedUpload, error) {
+	presigner, ok := s.BinayRepository.(port.BinaryPresigner)
+	if !ok || s.presignExpiry <= 0 {
+		return nil, fmt.Errorf("service: %w", port.ErrServicePresignedUploadsDisabled)
+	}
+	engines, err := s.selectEngines(ctx)
+	if err != nil {
+		return nil, err
+	}
+	tenant := domain.TenantFromContext(ctx)
+	tag = s.tagPolicy.Sanitize(tag)
+	fileName := helper.SanitizeFileName(domain.FileNameFromContext(ctx))
+
+	// The content is not known yet, the ID is derived from a random seed.
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, fmt.Errorf("service: %w: %v", port.ErrServiceUploadFailed, err)
+	}
+	ID, err := s.idScheme.NewID(helper.NewID(tenant + "/" + tag + "/" + hex.EncodeToString(nonce)))
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: failed to create a document ID: %v", port.ErrServiceUploadFailed, err)
+	}
+
+	url, err := presigner.PresignUpload(ctx, ID, s.presignExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w: id=%v", port.ErrServiceUploadFailed, err, ID)
+	}
+
+	doc := domain.NewDocument(ID, "", tag)
+	doc.Tenant = tenant
+	doc.HashAlgo = string(s.hashAlgorithm)
+	doc.FileName = fileName
+	doc.Labels = domain.LabelsFromContext(ctx)
+	doc.Engines = engines
+	if err = s.protect(doc); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	if err = s.DocumentRepository.Save(ctx, doc); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+
+	return &domain.PresignedUpload{
+		ID:        ID,
+		URL:       url,
+		Method:    http.MethodPut,
+		ExpiresAt: doc.CreatedAt.Add(s.presignExpiry),
+	}, nil
+}
+
+// ConfirmUpload reads the binary data uploaded to the presigned URL of a document of the tenant carried by ctx, to
+// compute its hash and detect its content type, checks it against the maximum upload size, the media type policy and
+// the quota of the tenant, then triggers its analysis with the priority carried by ctx. Rejected uploads are deleted,
+// the document along with its binary data. The data is analyzed even if a document with the same hash exists, since
+// it is stored already, but blocklisted content is given an infected verdict at once, see WithHashBlocklist.
+//
+// The content of the document is recorded before the uploaded data becomes its binary data, in the version which was
+// read, so that neither a concurrent confirmation nor a later upload to the presigned URL can replace the data which
+// is analyzed.
+func (s *Service) ConfirmUpload(ctx context.Context, ID string) (*domain.Document, error) {
+	presigner, ok := s.BinayRepository.(port.BinaryPresigner)
+	if !ok {
+		return nil, fmt.Errorf("service: %w", port.ErrServicePresignedUploadsDisabled)
+	}
+	if !helper.IsValidID(ID) {
+		return nil, fmt.Errorf("service: %w: the provided ID is not valid", port.ErrServiceInvalidID)
+	}
+	doc, err := s.DocumentRepository.Get(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: id=%s", port.ErrServiceGetDocumentFailed, err, ID)
+	}
+	if doc.IsDeleted() {
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceDocumentDeleted, ID)
+	}
+	if !doc.AwaitsUpload() {
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceUploadAlreadyConfirmed, ID)
+	}
+	// The uploaded data is kept while the service is overloaded, so that the upload can be confirmed later.
+	if err = s.checkBacklog(); err != nil {
+		return nil, err
+	}
+
+	size, hash, contentType, version, err := s.readUploaded(ctx, presigner, ID)
+	if err != nil {
+		return nil, err
+	}
+	// The document returned by the repository may be shared, its content is only recorded by UpdateContent.
+	confirmed := *doc
+	doc = &confirmed
+	doc.Hash = hash
+	doc.HashAlgo = string(s.hashAlgorithm)
+	doc.Size = size
+	doc.ContentType = contentType
+
+	// Blocklisted content is infected at once, without counting against the quota nor keeping its data.
+	if blocked, ok := s.blockedVerdict(ctx, hash); ok {
+		return s.blockUpload(ctx, presigner, doc, blocked)
+	}
+
+	// Check the upload as Upload does, the rejected data must not stay in the binary repository.
+	limit := s.MaxUploadSize(ctx)
+	if limit == 0 {
+		limit = s.presignMaxSize
+	}
+	switch {
+	case limit > 0 && size > limit:
+		err = fmt.Errorf("service: %w: %d bytes exceed the maximum upload size of %d bytes: tenant=%q", port.ErrServiceFileTooLarge, size, limit, doc.Tenant)
+	case !s.mediaTypePolicy.Allows(contentType):
+		err = fmt.Errorf("service: %w: %s", port.ErrServiceUnsupportedMediaType, contentType)
+	default:
+		err = s.reserveQuota(ctx, size)
+	}
+	if err != nil {
+		s.discardUpload(ctx, presigner, ID)
+		return nil, err
+	}
+
+	if err = s.DocumentRepository.UpdateContent(ctx, doc); err != nil {
+		s.releaseQuota(ctx, size)
+		if errors.Is(err, port.ErrDocumentContentRecorded) {
+			return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceUploadAlreadyConfirmed, ID)
+		}
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	binaryVersion, err := presigner.PromoteUpload(ctx, ID, version)
+	if err != nil {
+		s.releaseQuota(ctx, size)
+		s.discardUpload(ctx, presigner, ID)
+		if errors.Is(err, port.ErrUploadChanged) {
+			return nil, fmt.Errorf("service: %w: %w: id=%s", port.ErrServiceUploadChanged, err, ID)
+		}
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	if s.retainClean {
+		if err = s.DocumentRepository.UpdateBinaryVersion(ctx, ID, binaryVersion); err != nil {
+			slog.ErrorContext(ctx, "service - failed to record the version of the binary data", "error", err, "ID", ID)
+		} else {
+			doc.BinaryVersion = binaryVersion
+		}
+	}
+
+	// Trigger an asynchronous antivirus analysis by the engines selected when the upload was created.
+	s.pendingAnalyses.Add(1)
+	go s.asyncAnalyze(domain.ContextWithEngines(context.WithoutCancel(ctx), doc.Engines), ID, size)
+
+	return s.reveal(ctx, doc), nil
+}
+
+// blockUpload records the verdict of a confirmed upload of blocklisted content, then deletes its uploaded data.
+func (s *Service) blockUpload(ctx context.Context, presigner port.BinaryPresigner, doc *domain.Document, blocked domain.CachedVerdict) (*domain.Document, error) {
+	err := s.DocumentRepository.UpdateContent(ctx, doc)
+	if errors.Is(err, port.ErrDocumentContentRecorded) {
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceUploadAlreadyConfirmed, doc.ID)
+	}
+	if err == nil {
+		err = s.DocumentRepository.UpdateStatus(ctx, doc.ID, blocked.Status, blocked.Threat, blocked.AnalyzedAt)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	if err = presigner.DiscardUpload(ctx, doc.ID); err != nil {
+		slog.ErrorContext(ctx, "service - failed to delete blocklisted upload data", "error", err, "ID", doc.ID)
+	}
+	go s.notifyCallback(context.WithoutCancel(ctx), doc.ID)
+	doc.Status, doc.Threat, doc.AnalyzedAt = blocked.Status, blocked.Threat, blocked.AnalyzedAt
+	return s.reveal(ctx, doc), nil
+}
+
+// binaryVersion returns the version of the binary data of the document identified by ID, just saved, when the binary
+// data of the clean documents is retained to be downloaded, see PresignDownload, or else an empty string.
+func (s *Service) binaryVersion(ctx context.Context, ID string) string {
+	presigner, ok := s.BinayRepository.(port.BinaryPresigner)
+	if !ok || !s.retainClean {
+		return ""
+	}
+	version, err := presigner.BinaryVersion(ctx, ID)
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to get the version of the binary data", "error", err, "ID", ID)
+	}
+	return version
+}
+
+// readUploaded reads the data of a document uploaded to its presigned URL and returns its size, its hash, its content
+// type, detected from its first 512 bytes, and the version of the data which was read.
+func (s *Service) readUploaded(ctx context.Context, presigner port.BinaryPresigner, ID string) (size int64, hash, contentType, version string, err error) {
+	r, version, err := presigner.GetUpload(ctx, ID)
+	switch {
+	case errors.Is(err, port.ErrBinaryNotFound):
+		return 0, "", "", "", fmt.Errorf("service: %w: id=%s", port.ErrServiceUploadNotReceived, ID)
+	case err != nil:
+		return 0, "", "", "", fmt.Errorf("service: %w: %w: id=%s", port.ErrServiceUploadFailed, err, ID)
+	}
+	defer r.Close()
+
+	head := make([]byte, 512)
+	n, err := io.ReadFull(r, head)
+	if err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, io.ErrUnexpectedEOF) {
+		return 0, "", "", "", fmt.Errorf("service: %w: failed to read data: %v", port.ErrServiceUploadFailed, err)
+	}
+	cw := helper.NewCryptoWriterWithAlgorithm(s.hashAlgorithm)
+	cw.Write(head[:n])
+	size, err = helper.Copy(cw, r)
+	if err != nil {
+		return 0, "", "", "", fmt.Errorf("service: %w: failed to read data: %v", port.ErrServiceUploadFailed, err)
+	}
+	hash, _, _ = cw.GenerateHashAndID("")
+	return size + int64(n), hash, http.DetectContentType(head[:n]), version, nil
+}
+
+// discardUpload deletes a document whose upload is rejected, along with its uploaded data.
+func (s *Service) discardUpload(ctx context.Context, presigner port.BinaryPresigner, ID string) {
+	if err := presigner.DiscardUpload(ctx, ID); err != nil {
+		slog.ErrorContext(ctx, "service - failed to delete rejected upload data", "error", err, "ID", ID)
+	}
+	if err := s.DocumentRepository.Delete(ctx, ID); err != nil {
+		slog.ErrorContext(ctx, "service - failed to delete rejected upload document", "error", err, "ID", ID)
+	}
+}
+
+// PresignDownload returns a presigned URL the binary data of a clean document of the tenant carried by ctx can be
+// downloaded from directly, as an attachment named after its original file name. The binary data of the clean
+// documents is only retained with WithRetainedBinaries. The version of the binary data is checked to be the version
+// recorded when it was saved, so that no other data than the data found clean is handed out.
+func (s *Service) PresignDownload(ctx context.Context, ID string) (*domain.PresignedDownload, error) {
+	presigner, ok := s.BinayRepository.(port.BinaryPresigner)
+	if !ok || !s.retainClean || s.downloadExpiry <= 0 {
+		return nil, fmt.Errorf("service: %w", port.ErrServiceDownloadsDisabled)
+	}
+	doc, err := s.GetDocument(ctx, ID)
+	if err != nil {
+		return nil, err
+	}
+	if doc.Status != domain.StatusClean {
+		return nil, fmt.Errorf("service: %w: status=%s: id=%s", port.ErrServiceDocumentNotClean, doc.Status, ID)
+	}
+
+	// The duplicates of a clean document have no binary data of their own.
+	version, err := presigner.BinaryVersion(ctx, ID)
+	switch {
+	case errors.Is(err, port.ErrBinaryNotFound):
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceContentNotRetained, ID)
+	case err != nil:
+		return nil, fmt.Errorf("service: %w: %w: id=%s", port.ErrServiceDownloadFailed, err, ID)
+	case version != doc.BinaryVersion:
+		slog.WarnContext(ctx, "service - binary data changed since its analysis", "ID", ID, "version", doc.BinaryVersion, "binary_version", version)
+		return nil, fmt.Errorf("service: %w: id=%s", port.ErrServiceContentChanged, ID)
+	}
+
+	url, err := presigner.PresignDownload(ctx, ID, doc.FileName, s.downloadExpiry)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w: id=%s", port.ErrServiceDownloadFailed, err, ID)
+	}
+	return &domain.PresignedDownload{ID: ID, URL: url, ExpiresAt: time.Now().Add(s.downloadExpiry)}, nil
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// purgeJob is the name of the job of the periodic purge, run by a single replica at once, see runPurge.
+const purgeJob = "purge"
+
+// Purge immediately removes the documents of all the tenants created before opts.Before, restricted to opts.Statuses
+// if it is not empty. Analyzed documents are purged by the document repository, once their surviving binary data is
+// deleted, see purgeBinaries. Pending documents are only purged when requested: their binary data is deleted, and its
+// bytes given back to the tenant's quota, before the documents are.
+// The purge is counted in the purge totals of the service.
+func (s *Service) Purge(ctx context.Context, opts domain.PurgeOptions) (*domain.PurgeReport, error) {
+	report := &domain.PurgeReport{Before: opts.Before}
+	var analyzed []domain.AnalysisStatus
+	for _, status := range opts.Statuses {
+		report.Statuses = append(report.Statuses, status.String())
+		if status != domain.StatusPending {
+			analyzed = append(analyzed, status)
+		}
+	}
+
+	if len(opts.Statuses) == 0 || len(analyzed) > 0 {
+		b, err := s.purgeBinaries(ctx, []purgeRule{{date: opts.Before, statuses: analyzed}})
+		report.Binaries += b
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", port.ErrServicePurgeFailed, err)
+		}
+		n, err := s.DocumentRepository.Purge(opts.Before, analyzed...)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %w", port.ErrServicePurgeFailed, err)
+		}
+		report.Documents += n
+	}
+
+	if slices.Contains(opts.Statuses, domain.StatusPending) {
+		if err := s.purgePending(ctx, opts, report); err != nil {
+			s.recordPurge(report.Documents)
+			return nil, fmt.Errorf("%w: %w", port.ErrServicePurgeFailed, err)
+		}
+	}
+
+	s.recordPurge(report.Documents)
+	slog.InfoContext(ctx, "service - purge done", "before", opts.Before, "statuses", report.Statuses, "documents", report.Documents, "binaries", report.Binaries)
+	return report, nil
+}
+
+// purgeRule selects the documents the document repository purges before date, restricted to statuses if it is not
+// empty, see port.DocumentRepository.Purge.
+type purgeRule struct {
+	date     time.Time
+	statuses []domain.AnalysisStatus
+}
+
+// latestPurgeDate returns the latest date of rules, the zero time if there is none.
+func latestPurgeDate(rules []purgeRule) time.Time {
+	var latest time.Time
+	for _, r := range rules {
+		if r.date.After(latest) {
+			latest = r.date
+		}
+	}
+	return latest
+}
+
+// purgeBinaries deletes the binary data surviving the analysis of the documents the document repository purges with
+// any of rules: the retained and quarantined binary data, and the binary data which could not be deleted after an
+// analysis. The binary repository is walked once whatever the number of rules, and only the documents of the binary
+// data older than the latest date of rules are looked up. Its bytes are given back to the tenant's quota. A binary
+// data which cannot be deleted, such as quarantined data still protected from deletion, is logged and left to
+// Reconcile, its document is purged anyway. It returns the number of binary data deleted.
+func (s *Service) purgeBinaries(ctx context.Context, rules []purgeRule) (int64, error) {
+	var n int64
+	latest := latestPurgeDate(rules)
+	err := s.BinayRepository.Walk(ctx, func(b port.BinaryInfo) error {
+		if !b.ModifiedAt.Before(latest) {
+			return nil
+		}
+		tctx := domain.ContextWithTenant(ctx, b.Tenant)
+		doc, err := s.DocumentRepository.Get(tctx, b.ID)
+		switch {
+		case errors.Is(err, port.ErrDocumentNotFound):
+			// binary data without a document, left to Reconcile
+			return nil
+		case err != nil:
+			return err
+		case !slices.ContainsFunc(rules, func(r purgeRule) bool { return b.ModifiedAt.Before(r.date) && r.selects(doc) }):
+			return nil
+		}
+		if err := s.BinayRepository.Delete(tctx, b.ID); err != nil {
+			slog.WarnContext(ctx, "service - failed to delete the binary data of a purged document", "error", err, "tenant", b.Tenant, "ID", b.ID)
+			return nil
+		}
+		n++
+		s.releaseQuota(tctx, b.Size)
+		return nil
+	})
+	return n, err
+}
+
+// selects reports whether doc is purged by the document repository with the date and the statuses of r.
+func (r purgeRule) selects(doc *domain.Document) bool {
+	expired := doc.CreatedAt.Before(r.date) && doc.Status != domain.StatusPending || doc.IsDeleted() && doc.DeletedAt.Before(r.date)
+	return expired && (len(r.statuses) == 0 || slices.Contains(r.statuses, doc.Status))
+}
+
+// purgePending removes the pending documents created before opts.Before along with their binary data,
+// and counts them in report.
+func (s *Service) purgePending(ctx context.Context, opts domain.PurgeOptions, report *domain.PurgeReport) error {
+	pending, err := s.DocumentRepository.FindByStatus(ctx, domain.StatusPending)
+	if err != nil {
+		return err
+	}
+	docs := make(map[docKey]*domain.Document)
+	for _, doc := range pending {
+		if doc.CreatedAt.Before(opts.Before) {
+			docs[docKey{doc.Tenant, doc.ID}] = doc
+		}
+	}
+	if len(docs) == 0 {
+		return nil
+	}
+
+	// The binary data is walked for its size, given back to the tenant's quota.
+	err = s.BinayRepository.Walk(ctx, func(b port.BinaryInfo) error {
+		if _, ok := docs[docKey{b.Tenant, b.ID}]; !ok {
+			return nil
+		}
+		tctx := domain.ContextWithTenant(ctx, b.Tenant)
+		if err := s.BinayRepository.Delete(tctx, b.ID); err != nil {
+			return err
+		}
+		report.Binaries++
+		s.releaseQuota(tctx, b.Size)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for k := range docs {
+		err := s.DocumentRepository.Delete(domain.ContextWithTenant(ctx, k.tenant), k.ID)
+		if errors.Is(err, port.ErrDocumentNotFound) {
+			// deleted since the pending documents were listed
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		report.Documents++
+	}
+	return nil
+}
@@ -0,0 +1,82 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"log/slog"
+	"time"
+)
+
+// quotaOf returns the quota of a tenant: its own quota if one is configured, the default quota otherwise.
+func (s *Service) quotaOf(tenant string) domain.Quota {
+	if q, ok := s.quotas[tenant]; ok {
+		return q
+	}
+	return s.defaultQuota
+}
+
+// reserveQuota checks that an upload of size bytes fits in the quota of the tenant carried by ctx and records it.
+// It does nothing when quotas are not enabled.
+func (s *Service) reserveQuota(ctx context.Context, size int64) error {
+	if s.quotaRepository == nil {
+		return nil
+	}
+	tenant := domain.TenantFromContext(ctx)
+	quota := s.quotaOf(tenant)
+	if quota.MaxFileSize > 0 && size > quota.MaxFileSize {
+		return fmt.Errorf("service: %w: %d bytes exceed the limit of %d bytes: tenant=%q", port.ErrServiceFileTooLarge, size, quota.MaxFileSize, tenant)
+	}
+	if err := s.quotaRepository.Reserve(ctx, time.Now(), size, quota); err != nil {
+		if errors.Is(err, port.ErrQuotaExceeded) {
+			return fmt.Errorf("service: %w: %w", port.ErrServiceQuotaExceeded, err)
+		}
+		return fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	return nil
+}
+
+// MaxUploadSize returns the maximum size in bytes of an upload of the tenant carried by ctx, or zero if it has
+// no maximum of its own.
+func (s *Service) MaxUploadSize(ctx context.Context) int64 {
+	return s.maxUploadSizes[domain.TenantFromContext(ctx)]
+}
+
+// TagMaxLength returns the maximum length in bytes of the tags kept by the tag policy, see WithTagPolicy.
+func (s *Service) TagMaxLength() int {
+	if s.tagPolicy.MaxLength <= 0 {
+		return helper.TagMaxLength
+	}
+	return s.tagPolicy.MaxLength
+}
+
+// releaseQuota gives back the bytes of a binary data that is not stored anymore. It does nothing
+// when quotas are not enabled.
+func (s *Service) releaseQuota(ctx context.Context, size int64) {
+	if s.quotaRepository == nil {
+		return
+	}
+	if err := s.quotaRepository.Release(ctx, size); err != nil {
+		slog.ErrorContext(ctx, "service - failed to release quota", "error", err, "tenant", domain.TenantFromContext(ctx))
+	}
+}
+
+// QuotaStatus returns the quota of the tenant carried by ctx along with its current usage.
+func (s *Service) QuotaStatus(ctx context.Context) (*domain.QuotaStatus, error) {
+	if s.quotaRepository == nil {
+		return nil, fmt.Errorf("service: %w", port.ErrServiceQuotasDisabled)
+	}
+	tenant := domain.TenantFromContext(ctx)
+	usage, err := s.quotaRepository.Usage(ctx, time.Now())
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: tenant=%q", port.ErrServiceGetQuotaFailed, err, tenant)
+	}
+	return &domain.QuotaStatus{
+		Tenant: tenant,
+		Quota:  s.quotaOf(tenant),
+		Usage:  *usage,
+	}, nil
+}
@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"log/slog"
+	"time"
+)
+
+// docKey identifies a document, or its binary data, across tenants.
+type docKey struct {
+	tenant string
+	ID     string
+}
+
+// Reconcile compares the documents with the binary data held in the binary repository and reports the mismatches found.
+// Pending documents must have binary data with the same hash, other documents must not have binary data anymore,
+// unless it is retained, see Service.retains.
+// Documents and binary data younger than opts.MinAge are skipped. With opts.Fix, the following fixes are applied
+// and logged: binary data without a document or of an analyzed document is deleted, pending documents without binary
+// data are deleted so that they can be uploaded again, presigned uploads never confirmed included, along with their
+// uploaded data. Hash mismatches are only reported.
+func (s *Service) Reconcile(ctx context.Context, opts domain.ReconcileOptions) (*domain.ReconcileReport, error) {
+	report := &domain.ReconcileReport{
+		StartedAt:  time.Now(),
+		Fix:        opts.Fix,
+		Mismatches: []domain.Mismatch{},
+	}
+	cutoff := report.StartedAt.Add(-opts.MinAge)
+
+	pending, err := s.DocumentRepository.FindByStatus(ctx, domain.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceReconcileFailed, err)
+	}
+	docs := make(map[docKey]*domain.Document, len(pending))
+	for _, doc := range pending {
+		docs[docKey{doc.Tenant, doc.ID}] = doc
+	}
+
+	// Check every binary data against its document.
+	withBinary := make(map[docKey]bool)
+	err = s.BinayRepository.Walk(ctx, func(b port.BinaryInfo) error {
+		k := docKey{b.Tenant, b.ID}
+		withBinary[k] = true
+		if b.ModifiedAt.After(cutoff) {
+			return nil
+		}
+		report.Binaries++
+		tctx := domain.ContextWithTenant(ctx, b.Tenant)
+
+		if doc, ok := docs[k]; ok {
+			if m := s.checkBinaryHash(tctx, doc); m != nil {
+				report.Mismatches = append(report.Mismatches, *m)
+			}
+			return nil
+		}
+
+		m := domain.Mismatch{Tenant: b.Tenant, ID: b.ID}
+		doc, err := s.DocumentRepository.Get(tctx, b.ID)
+		switch {
+		case errors.Is(err, port.ErrDocumentNotFound):
+			m.Kind = domain.MismatchBinaryWithoutDocument
+		case err != nil:
+			return err
+		case doc.Status == domain.StatusPending || doc.AnalyzedAt.After(cutoff):
+			// uploaded or analyzed since the pending documents were listed
+			return nil
+		case s.retains(doc.Status):
+			// retained to be downloaded, or quarantined
+			return nil
+		default:
+			m.Kind = domain.MismatchBinaryOfAnalyzedDocument
+			m.Detail = fmt.Sprintf("analyzed at %s", doc.AnalyzedAt.Format(time.RFC3339))
+		}
+
+		if opts.Fix {
+			m.Action = "delete_binary"
+			if err := s.BinayRepository.Delete(tctx, b.ID); err != nil {
+				m.FixError = err.Error()
+			} else if m.Kind == domain.MismatchBinaryOfAnalyzedDocument {
+				// the bytes of binary data without a document were given back when its upload failed
+				s.releaseQuota(tctx, b.Size)
+			}
+		}
+		report.Mismatches = append(report.Mismatches, logMismatch(ctx, m))
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceReconcileFailed, err)
+	}
+
+	// Check that every pending document has binary data.
+	for k, doc := range docs {
+		if doc.CreatedAt.After(cutoff) {
+			continue
+		}
+		report.Documents++
+		if withBinary[k] {
+			continue
+		}
+
+		tctx := domain.ContextWithTenant(ctx, doc.Tenant)
+		if current, err := s.DocumentRepository.Get(tctx, doc.ID); err != nil || current.Status != domain.StatusPending {
+			// analyzed, and its binary data deleted, since the pending documents were listed
+			continue
+		}
+
+		m := domain.Mismatch{Kind: domain.MismatchDocumentWithoutBinary, Tenant: doc.Tenant, ID: doc.ID}
+		if opts.Fix {
+			m.Action = "delete_document"
+			if err := s.DocumentRepository.Delete(tctx, doc.ID); err != nil {
+				m.FixError = err.Error()
+			} else if presigner, ok := s.BinayRepository.(port.BinaryPresigner); ok && doc.AwaitsUpload() {
+				if err := presigner.DiscardUpload(tctx, doc.ID); err != nil {
+					m.FixError = err.Error()
+				}
+			}
+		}
+		report.Mismatches = append(report.Mismatches, logMismatch(ctx, m))
+	}
+
+	report.FinishedAt = time.Now()
+	slog.InfoContext(ctx, "service - reconciliation done", "fix", opts.Fix, "documents", report.Documents, "binaries", report.Binaries, "mismatches", len(report.Mismatches))
+	return report, nil
+}
+
+// checkBinaryHash compares the hash of a pending document with the hash of its binary data.
+// It returns the mismatch found, if any. The documents awaiting the confirmation of their upload have no hash yet.
+func (s *Service) checkBinaryHash(ctx context.Context, doc *domain.Document) *domain.Mismatch {
+	if doc.AwaitsUpload() {
+		return nil
+	}
+	r, err := s.BinayRepository.Get(ctx, doc.ID)
+	if err != nil {
+		// deleted since it was listed
+		return nil
+	}
+	defer r.Close()
+
+	algo, err := helper.ParseHashAlgorithm(doc.HashAlgo)
+	if err != nil {
+		algo = helper.HashSHA256
+	}
+	cw := helper.NewCryptoWriterWithAlgorithm(algo)
+	if _, err := helper.Copy(cw, r); err != nil {
+		slog.ErrorContext(ctx, "service - reconcile: failed to read binary data", "error", err, "tenant", doc.Tenant, "ID", doc.ID)
+		return nil
+	}
+	hash, _, _ := cw.GenerateHashAndID("")
+	if hash == doc.Hash {
+		return nil
+	}
+	m := logMismatch(ctx, domain.Mismatch{
+		Kind:   domain.MismatchHash,
+		Tenant: doc.Tenant,
+		ID:     doc.ID,
+		Detail: fmt.Sprintf("document hash %s, binary data hash %s", doc.Hash, hash),
+	})
+	return &m
+}
+
+// logMismatch logs a mismatch found by a reconciliation, along with its fix, and returns it.
+func logMismatch(ctx context.Context, m domain.Mismatch) domain.Mismatch {
+	slog.WarnContext(ctx, "service - reconcile mismatch", "kind", m.Kind, "tenant", m.Tenant, "ID", m.ID, "detail", m.Detail, "action", m.Action, "fix_error", m.FixError)
+	return m
+}
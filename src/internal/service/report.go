@@ -0,0 +1,61 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+)
+
+// DefaultReportTop is the default number of threats and tenants listed by the summary reports.
+const DefaultReportTop = 10
+
+// reportTimeout bounds the summary of the documents and the delivery of a scheduled report.
+const reportTimeout = 5 * time.Minute
+
+// Report summarizes the documents of all the tenants uploaded during the period ending at to, listing the top most
+// frequent threats and busiest tenants, DefaultReportTop of them if top is not strictly positive.
+func (s *Service) Report(ctx context.Context, period domain.ReportPeriod, to time.Time, top int) (*domain.SummaryReport, error) {
+	if top <= 0 {
+		top = DefaultReportTop
+	}
+	report, err := s.DocumentRepository.Summarize(ctx, to.Add(-period.Duration()), to, top)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceReportFailed, err)
+	}
+	report.Period = period
+	if analyzed := report.Infected + report.Clean; analyzed > 0 {
+		report.InfectedRate = float64(report.Infected) / float64(analyzed)
+	}
+	return report, nil
+}
+
+// autoReport delivers the summary reports on schedule. It runs indefinitely; a report which cannot be built is skipped,
+// and a sender failing to deliver it does not keep the others from delivering it.
+func (s *Service) autoReport() {
+	for {
+		next := s.reportSchedule.Next(time.Now())
+		time.Sleep(time.Until(next))
+		s.sendReport(next)
+	}
+}
+
+// sendReport builds the report of the period ending at to and delivers it with each sender.
+func (s *Service) sendReport(to time.Time) {
+	ctx, cancel := context.WithTimeout(context.Background(), reportTimeout)
+	defer cancel()
+
+	report, err := s.Report(ctx, s.reportSchedule.Period, to, s.reportTop)
+	if err != nil {
+		slog.Error("service - report failed", "error", err)
+		return
+	}
+	for _, sender := range s.reportSenders {
+		if err = sender.Send(ctx, report); err != nil {
+			slog.Error("service - report delivery failed", "error", err)
+		}
+	}
+	slog.Info("service - report sent", "period", report.Period, "from", report.From, "to", report.To, "documents", report.Total)
+}
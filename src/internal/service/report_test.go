@@ -0,0 +1,109 @@
+package service
+
+import (
+	"bytes"
+	"context"
+	"goyav/internal/adapter/antivirus"
+	"goyav/internal/adapter/report"
+	"goyav/internal/adapter/storage/binaryrepo"
+	"goyav/internal/adapter/storage/docrepo"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestReport(t *testing.T) {
+	sender := report.NewMock()
+	schedule := domain.ReportSchedule{Period: domain.ReportDaily, At: 6 * time.Hour}
+	svc, err := New(binaryrepo.NewMock(), docrepo.NewMock(), antivirus.NewMock(), version, info, 0, semaphoreCapacity, WithReports(schedule, 1, sender))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := domain.ContextWithTenant(context.Background(), "bu-a")
+	b := domain.ContextWithTenant(context.Background(), "bu-b")
+	for ctx, contents := range map[context.Context][]string{a: {"one", "two", "three"}, b: {"four"}} {
+		for _, c := range contents {
+			if _, err = svc.Upload(ctx, strings.NewReader(c), int64(len(c)), c); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+		}
+	}
+	if _, err = svc.Upload(b, bytes.NewReader(port.EICAR), int64(len(port.EICAR)), "EICAR"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	time.Sleep(time.Millisecond * 1500)
+
+	r, err := svc.Report(context.Background(), domain.ReportDaily, time.Now(), 0)
+	if assert.NoError(t, err) {
+		assert.Equal(t, domain.ReportDaily, r.Period)
+		assert.Equal(t, int64(5), r.Total)
+		assert.Equal(t, int64(1), r.Infected)
+		assert.Equal(t, int64(4), r.Clean)
+		assert.InDelta(t, 0.2, r.InfectedRate, 1e-9)
+		assert.Equal(t, []domain.ThreatCount{{Name: antivirus.EICARSignature, Count: 1}}, r.TopThreats)
+		assert.Equal(t, []domain.TenantCount{{Tenant: "bu-a", Uploads: 3}, {Tenant: "bu-b", Uploads: 2}}, r.BusiestTenants)
+	}
+
+	r, err = svc.Report(context.Background(), domain.ReportWeekly, time.Now().Add(-7*24*time.Hour), 0)
+	if assert.NoError(t, err) {
+		assert.Zero(t, r.Total)
+		assert.Zero(t, r.InfectedRate)
+	}
+
+	// the scheduled report lists the top tenant only
+	svc.sendReport(time.Now())
+	if reports := sender.Reports(); assert.Len(t, reports, 1) {
+		assert.Equal(t, []domain.TenantCount{{Tenant: "bu-a", Uploads: 3}}, reports[0].BusiestTenants)
+	}
+}
+
+func TestReportScheduleNext(t *testing.T) {
+	// Monday 18 March 2024
+	now := time.Date(2024, 3, 18, 10, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		schedule domain.ReportSchedule
+		next     time.Time
+	}{
+		{domain.ReportSchedule{Period: domain.ReportDaily, At: 6 * time.Hour}, time.Date(2024, 3, 19, 6, 0, 0, 0, time.UTC)},
+		{domain.ReportSchedule{Period: domain.ReportDaily, At: 18 * time.Hour}, time.Date(2024, 3, 18, 18, 0, 0, 0, time.UTC)},
+		{domain.ReportSchedule{Period: domain.ReportWeekly, At: 6 * time.Hour, Weekday: time.Monday}, time.Date(2024, 3, 25, 6, 0, 0, 0, time.UTC)},
+		{domain.ReportSchedule{Period: domain.ReportWeekly, At: 12 * time.Hour, Weekday: time.Monday}, time.Date(2024, 3, 18, 12, 0, 0, 0, time.UTC)},
+		{domain.ReportSchedule{Period: domain.ReportWeekly, Weekday: time.Sunday}, time.Date(2024, 3, 24, 0, 0, 0, 0, time.UTC)},
+	} {
+		assert.Equal(t, tc.next, tc.schedule.Next(now), "%+v", tc.schedule)
+	}
+}
+
+func TestCronScheduleNext(t *testing.T) {
+	// Monday 18 March 2024
+	now := time.Date(2024, 3, 18, 10, 30, 20, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		next time.Time
+	}{
+		{"0 3 * * *", time.Date(2024, 3, 19, 3, 0, 0, 0, time.UTC)},
+		{"*/15 * * * *", time.Date(2024, 3, 18, 10, 45, 0, 0, time.UTC)},
+		{"30 10 * * *", time.Date(2024, 3, 19, 10, 30, 0, 0, time.UTC)},
+		{"0 9-17/4 * * 1-5", time.Date(2024, 3, 18, 13, 0, 0, 0, time.UTC)},
+		{"0 0 * * 7", time.Date(2024, 3, 24, 0, 0, 0, 0, time.UTC)},
+		{"0 0 1 * 0", time.Date(2024, 3, 24, 0, 0, 0, 0, time.UTC)}, // the 1st or a Sunday
+		{"0 0 29 2 *", time.Date(2028, 2, 29, 0, 0, 0, 0, time.UTC)},
+		{"@monthly", time.Date(2024, 4, 1, 0, 0, 0, 0, time.UTC)},
+	} {
+		s, err := domain.ParseCronSchedule(tc.expr)
+		if assert.NoError(t, err, tc.expr) {
+			assert.Equal(t, tc.next, s.Next(now), tc.expr)
+			assert.Equal(t, tc.expr, s.String())
+		}
+	}
+
+	for _, expr := range []string{"", "0 3 * *", "60 * * * *", "* * 0 * *", "*/0 * * * *", "5-1 * * * *", "0 0 30 2 *", "@often"} {
+		_, err := domain.ParseCronSchedule(expr)
+		assert.ErrorIs(t, err, domain.ErrInvalidCronExpression, expr)
+	}
+}
@@ -0,0 +1,32 @@
+package service
+
+import (
+	"context"
+	"goyav/pkg/helper"
+	"log/slog"
+)
+
+// reputationVerdict returns the verdict of the reputation source of the service, if any, on the content of a document,
+// when the source is confident about it. The documents whose content is not hashed with SHA-256 are not looked up, and
+// a failed lookup is only logged, the content is then analyzed.
+func (s *Service) reputationVerdict(ctx context.Context, ID string) (verdict, bool) {
+	if s.reputationSource == nil {
+		return verdict{}, false
+	}
+	doc, err := s.DocumentRepository.Get(ctx, ID)
+	if err != nil || doc.Hash == "" || (doc.HashAlgo != "" && doc.HashAlgo != string(helper.HashSHA256)) {
+		return verdict{}, false
+	}
+	rep, err := s.reputationSource.Lookup(ctx, doc.Hash)
+	if err != nil {
+		s.reputationErrors.Add(1)
+		slog.WarnContext(ctx, "service - reputation lookup failed, the content is analyzed", "error", err, "ID", ID)
+		return verdict{}, false
+	}
+	if !rep.IsConfident() {
+		return verdict{}, false
+	}
+	s.reputationVerdicts.Add(1)
+	slog.DebugContext(ctx, "verdict given by the reputation source", "ID", ID, "source", rep.Source, "status", rep.Status.String())
+	return verdict{status: rep.Status, threat: rep.Threat}, true
+}
@@ -0,0 +1,64 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"log/slog"
+	"time"
+)
+
+// Requeue triggers again the analysis of the documents of all the tenants pending since before opts.MinAge ago whose
+// binary data still exists, such as those whose analysis was lost when a replica stopped. The documents awaiting a
+// presigned upload, and those analyzed by this replica already, are skipped; the documents without binary data are
+// counted as missing and left to Reconcile. Each requeued document is logged.
+func (s *Service) Requeue(ctx context.Context, opts domain.RequeueOptions) (*domain.RequeueReport, error) {
+	report := &domain.RequeueReport{Before: time.Now().Add(-opts.MinAge)}
+
+	pending, err := s.DocumentRepository.FindByStatus(ctx, domain.StatusPending)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceRequeueFailed, err)
+	}
+	docs := make(map[docKey]*domain.Document)
+	for _, doc := range pending {
+		k := docKey{doc.Tenant, doc.ID}
+		if doc.CreatedAt.After(report.Before) || doc.AwaitsUpload() || s.isAnalyzing(k) {
+			continue
+		}
+		docs[k] = doc
+	}
+	report.Pending = len(docs)
+	if len(docs) == 0 {
+		return report, nil
+	}
+
+	// The binary data is walked for its size, given back to the tenant's quota once analyzed.
+	err = s.BinayRepository.Walk(ctx, func(b port.BinaryInfo) error {
+		doc, ok := docs[docKey{b.Tenant, b.ID}]
+		if !ok {
+			return nil
+		}
+		delete(docs, docKey{b.Tenant, b.ID})
+		actx := domain.ContextWithEngines(domain.ContextWithTenant(context.WithoutCancel(ctx), b.Tenant), doc.Engines)
+		s.pendingAnalyses.Add(1)
+		go s.asyncAnalyze(actx, b.ID, b.Size)
+		report.Requeued++
+		slog.InfoContext(ctx, "service - pending document requeued", "tenant", b.Tenant, "ID", b.ID, "created at", doc.CreatedAt)
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceRequeueFailed, err)
+	}
+	report.Missing = len(docs)
+
+	slog.InfoContext(ctx, "service - requeue done", "before", report.Before, "pending", report.Pending, "requeued", report.Requeued, "missing", report.Missing)
+	return report, nil
+}
+
+// isAnalyzing reports whether the analysis of the document identified by k is waiting for a slot or running in this
+// replica.
+func (s *Service) isAnalyzing(k docKey) bool {
+	_, ok := s.analyzing.Load(k)
+	return ok
+}
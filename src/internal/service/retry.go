@@ -0,0 +1,92 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy is the schedule of the attempts of an analysis: after the n-th failed attempt, the next one is made
+// after BaseDelay * Factor^(n-1), give or take Jitter of it, unless MaxAttempts were made or the next attempt would
+// start after MaxDuration.
+type RetryPolicy struct {
+	MaxAttempts int           // MaxAttempts is the maximum number of attempts, at least 1.
+	BaseDelay   time.Duration // BaseDelay is the delay before the second attempt.
+	Factor      float64       // Factor multiplies the delay after each attempt, at least 1.
+	Jitter      float64       // Jitter is the fraction of the delay drawn at random, between 0 and 1.
+	MaxDuration time.Duration // MaxDuration bounds the time spent retrying, unbounded when it is zero.
+}
+
+// DefaultRetryPolicy retries an analysis 5 times over about 2 minutes and a half.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 6,
+	BaseDelay:   5 * time.Second,
+	Factor:      1.8,
+	Jitter:      0.1,
+	MaxDuration: 5 * time.Minute,
+}
+
+// ErrInvalidRetryPolicy is returned when a retry policy is not valid.
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// Validate checks that the fields of the policy are within their bounds.
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts < 1:
+		return fmt.Errorf("%w: at least 1 attempt is required", ErrInvalidRetryPolicy)
+	case p.BaseDelay < 0:
+		return fmt.Errorf("%w: negative base delay", ErrInvalidRetryPolicy)
+	case p.Factor < 1:
+		return fmt.Errorf("%w: the factor must be at least 1", ErrInvalidRetryPolicy)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("%w: the jitter must be between 0 and 1", ErrInvalidRetryPolicy)
+	case p.MaxDuration < 0:
+		return fmt.Errorf("%w: negative maximum duration", ErrInvalidRetryPolicy)
+	}
+	return nil
+}
+
+// Delay returns the delay before the attempt following the n-th failed attempt.
+func (p RetryPolicy) Delay(n int) time.Duration {
+	d := float64(p.BaseDelay) * math.Pow(p.Factor, float64(n-1))
+	if p.Jitter > 0 {
+		d += d * p.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(min(d, math.MaxInt64))
+}
+
+// permanentError marks the error of an attempt that retrying cannot fix.
+type permanentError struct {
+	error
+}
+
+func (e permanentError) Unwrap() error {
+	return e.error
+}
+
+// retry calls attempt until it succeeds or the policy gives up, and returns the error of the last attempt.
+// It gives up as soon as ctx is done or attempt returns a permanentError.
+func (p RetryPolicy) retry(ctx context.Context, attempt func() error) error {
+	start := time.Now()
+	for n := 1; ; n++ {
+		err := attempt()
+		if err == nil {
+			return nil
+		}
+		if errors.As(err, new(permanentError)) || n >= p.MaxAttempts {
+			return fmt.Errorf("failed after %d attempt(s): %w", n, err)
+		}
+		delay := p.Delay(n)
+		if p.MaxDuration > 0 && time.Since(start)+delay > p.MaxDuration {
+			return fmt.Errorf("failed after %d attempt(s) within %v: %w", n, p.MaxDuration, err)
+		}
+		select {
+		case <-time.After(delay):
+		case <-ctx.Done():
+			return fmt.Errorf("failed after %d attempt(s): %w: %w", n, ctx.Err(), err)
+		}
+	}
+}
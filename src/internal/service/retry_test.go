@@ -0,0 +1,71 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRetryPolicy(t *testing.T) {
+	t.Run("Delay", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, Factor: 2}
+		assert.Equal(t, time.Second, p.Delay(1))
+		assert.Equal(t, 2*time.Second, p.Delay(2))
+		assert.Equal(t, 4*time.Second, p.Delay(3))
+	})
+
+	t.Run("Jitter", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 4, BaseDelay: time.Second, Factor: 2, Jitter: 0.5}
+		for range 100 {
+			d := p.Delay(2)
+			assert.GreaterOrEqual(t, d, time.Second)
+			assert.LessOrEqual(t, d, 3*time.Second)
+		}
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, DefaultRetryPolicy.Validate())
+		for _, p := range []RetryPolicy{
+			{MaxAttempts: 0, Factor: 1},
+			{MaxAttempts: 1, Factor: 0.5},
+			{MaxAttempts: 1, Factor: 1, Jitter: 1.5},
+			{MaxAttempts: 1, Factor: 1, BaseDelay: -time.Second},
+		} {
+			assert.ErrorIs(t, p.Validate(), ErrInvalidRetryPolicy)
+		}
+	})
+
+	errAttempt := errors.New("attempt failed")
+
+	t.Run("MaxAttempts", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Factor: 1}
+		n := 0
+		err := p.retry(context.Background(), func() error { n++; return errAttempt })
+		assert.ErrorIs(t, err, errAttempt)
+		assert.Equal(t, 3, n)
+	})
+
+	t.Run("Success", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 3, BaseDelay: time.Millisecond, Factor: 1}
+		n := 0
+		err := p.retry(context.Background(), func() error {
+			if n++; n < 2 {
+				return errAttempt
+			}
+			return nil
+		})
+		assert.NoError(t, err)
+		assert.Equal(t, 2, n)
+	})
+
+	t.Run("MaxDuration", func(t *testing.T) {
+		p := RetryPolicy{MaxAttempts: 10, BaseDelay: 20 * time.Millisecond, Factor: 2, MaxDuration: 50 * time.Millisecond}
+		n := 0
+		err := p.retry(context.Background(), func() error { n++; return errAttempt })
+		assert.ErrorIs(t, err, errAttempt)
+		assert.Equal(t, 2, n)
+	})
+}
@@ -0,0 +1,75 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"io"
+)
+
+// Scan analyzes the data of size bytes, -1 if unknown, read from data synchronously, with the engines carried by ctx,
+// all the engines of the service if there are none, one after the other, until one of them finds a threat, as the
+// data of a document would be. The analysis takes a slot of the scheduler of the service with the priority carried
+// by ctx, and neither the data nor its report are stored: the repositories of the service are not used.
+func (s *Service) Scan(ctx context.Context, data io.Reader, size int64) (*domain.ScanReport, error) {
+	if err := s.checkBacklog(); err != nil {
+		return nil, err
+	}
+	engines, err := s.selectEngines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// The data is read a first time to calculate its hash, then once by each engine.
+	sr, cleanup, err := s.spool.rewindable(data, size)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceScanFailed, err)
+	}
+	defer cleanup()
+	if limit := s.MaxUploadSize(ctx); limit > 0 && sr.Size() > limit {
+		return nil, fmt.Errorf("service: %w: %d bytes exceed the maximum upload size of %d bytes", port.ErrServiceFileTooLarge, sr.Size(), limit)
+	}
+	cw := helper.NewCryptoWriterWithAlgorithm(s.hashAlgorithm)
+	if _, err = helper.Copy(cw, io.NewSectionReader(sr, 0, sr.Size())); err != nil {
+		return nil, fmt.Errorf("service: %w: failed to read data: %v", port.ErrServiceScanFailed, err)
+	}
+	hash, _, err := cw.GenerateHashAndID("")
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceScanFailed, err)
+	}
+
+	s.scheduler.acquire(domain.PriorityFromContext(ctx))
+	defer s.scheduler.release()
+	release, err := s.admit(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceScanFailed, err)
+	}
+	defer release()
+
+	report := &domain.ScanReport{Status: domain.StatusClean.String(), Hash: hash, Size: sr.Size(), Engines: engines}
+	for _, name := range engines {
+		var v verdict
+		if name == domain.EngineClamAV {
+			v, err = s.analyze(ctx, io.NewSectionReader(sr, 0, sr.Size()), sr.Size())
+		} else {
+			v, err = analyzeWith(ctx, s.engines[name], io.NewSectionReader(sr, 0, sr.Size()))
+		}
+		switch {
+		case errors.Is(err, port.ErrAntivirusSizeLimitExceeded):
+			return nil, fmt.Errorf("service: %w: %w", port.ErrServiceFileTooLarge, err)
+		case err != nil:
+			return nil, fmt.Errorf("service: %w: analysis by %s: %w", port.ErrServiceScanFailed, name, err)
+		}
+		if v.archive != nil {
+			report.Archive = v.archive
+		}
+		if v.status == domain.StatusInfected {
+			report.Status, report.Threat = v.status.String(), v.threat
+			break
+		}
+	}
+	return report, nil
+}
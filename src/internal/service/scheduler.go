@@ -0,0 +1,102 @@
+package service
+
+import (
+	"goyav/internal/core/domain"
+	"sync"
+	"time"
+)
+
+// scheduler limits the number of analyses running at once. When all its slots are taken, the analyses waiting
+// for one are served by priority, then in their order of arrival, so that bulk uploads do not hold back the
+// uploads of users.
+type scheduler struct {
+	mux      sync.Mutex
+	capacity int
+	running  int
+	waiting  [domain.PriorityLevels][]chan struct{}
+
+	// acquired counts the slots given since the scheduler was created, and waited sums the time spent waiting for them.
+	acquired uint64
+	waited   time.Duration
+}
+
+// schedulerStats are the measures of a scheduler.
+type schedulerStats struct {
+	capacity int
+	running  int
+	queued   int
+	acquired uint64
+	waited   time.Duration
+}
+
+func newScheduler(capacity int) *scheduler {
+	return &scheduler{capacity: capacity}
+}
+
+// acquire blocks until a slot is available to an analysis of priority p.
+func (sc *scheduler) acquire(p domain.Priority) {
+	sc.mux.Lock()
+	if sc.running < sc.capacity {
+		sc.running++
+		sc.acquired++
+		sc.mux.Unlock()
+		return
+	}
+	start := time.Now()
+	ready := make(chan struct{})
+	sc.waiting[p] = append(sc.waiting[p], ready)
+	sc.mux.Unlock()
+	<-ready
+
+	sc.mux.Lock()
+	sc.acquired++
+	sc.waited += time.Since(start)
+	sc.mux.Unlock()
+}
+
+// release frees the slot taken by acquire, handing it over to the first waiting analysis of the highest priority,
+// unless the capacity was lowered under the number of analyses running.
+func (sc *scheduler) release() {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	if sc.running <= sc.capacity && sc.wakeNext() {
+		return
+	}
+	sc.running--
+}
+
+// setCapacity changes the number of analyses run at once. Raising it gives the new slots to the waiting analyses
+// right away, lowering it lets the analyses running finish, their slots being given back until they are under it.
+func (sc *scheduler) setCapacity(capacity int) {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	sc.capacity = capacity
+	for sc.running < sc.capacity && sc.wakeNext() {
+		sc.running++
+	}
+}
+
+// wakeNext gives a slot to the first waiting analysis of the highest priority, reporting false if none is waiting.
+// The caller must hold the lock.
+func (sc *scheduler) wakeNext() bool {
+	for p, queue := range sc.waiting {
+		if len(queue) > 0 {
+			close(queue[0])
+			queue[0] = nil
+			sc.waiting[p] = queue[1:]
+			return true
+		}
+	}
+	return false
+}
+
+// stats returns the current measures of the scheduler.
+func (sc *scheduler) stats() schedulerStats {
+	sc.mux.Lock()
+	defer sc.mux.Unlock()
+	st := schedulerStats{capacity: sc.capacity, running: sc.running, acquired: sc.acquired, waited: sc.waited}
+	for _, queue := range sc.waiting {
+		st.queued += len(queue)
+	}
+	return st
+}
@@ -0,0 +1,96 @@
+package service
+
+import (
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestScheduler(t *testing.T) {
+	sc := newScheduler(1)
+	sc.acquire(domain.PriorityBatch)
+
+	// queue a batch analysis, then an interactive one
+	served := make(chan domain.Priority, 2)
+	wait := func(p domain.Priority) {
+		sc.acquire(p)
+		served <- p
+		sc.release()
+	}
+	go wait(domain.PriorityBatch)
+	assert.Eventually(t, func() bool {
+		sc.mux.Lock()
+		defer sc.mux.Unlock()
+		return len(sc.waiting[domain.PriorityBatch]) == 1
+	}, time.Second, time.Millisecond)
+	go wait(domain.PriorityInteractive)
+	assert.Eventually(t, func() bool {
+		sc.mux.Lock()
+		defer sc.mux.Unlock()
+		return len(sc.waiting[domain.PriorityInteractive]) == 1
+	}, time.Second, time.Millisecond)
+
+	st := sc.stats()
+	assert.Equal(t, schedulerStats{capacity: 1, running: 1, queued: 2, acquired: 1}, st)
+
+	// the interactive analysis is served first although it arrived last
+	sc.release()
+	assert.Equal(t, domain.PriorityInteractive, <-served)
+	assert.Equal(t, domain.PriorityBatch, <-served)
+
+	st = sc.stats()
+	assert.Zero(t, st.running)
+	assert.Zero(t, st.queued)
+	assert.Equal(t, uint64(3), st.acquired)
+	assert.Positive(t, st.waited, "the time spent waiting for a slot must be measured")
+}
+
+func TestSchedulerSetCapacity(t *testing.T) {
+	sc := newScheduler(2)
+	sc.acquire(domain.PriorityBatch)
+	sc.acquire(domain.PriorityBatch)
+
+	served := make(chan struct{}, 2)
+	for range 2 {
+		go func() {
+			sc.acquire(domain.PriorityBatch)
+			served <- struct{}{}
+		}()
+	}
+	assert.Eventually(t, func() bool { return sc.stats().queued == 2 }, time.Second, time.Millisecond)
+
+	// raising the capacity serves a waiting analysis right away
+	sc.setCapacity(3)
+	<-served
+	assert.Equal(t, schedulerStats{capacity: 3, running: 3, queued: 1, acquired: 3}, withoutWait(sc.stats()))
+
+	// lowering it lets the analyses running finish without serving the waiting one until they are under it
+	sc.setCapacity(1)
+	sc.release()
+	sc.release()
+	assert.Equal(t, schedulerStats{capacity: 1, running: 1, queued: 1, acquired: 3}, withoutWait(sc.stats()))
+	sc.release()
+	<-served
+	assert.Equal(t, schedulerStats{capacity: 1, running: 1, acquired: 4}, withoutWait(sc.stats()))
+}
+
+// withoutWait returns st without the time spent waiting, which cannot be predicted.
+func withoutWait(st schedulerStats) schedulerStats {
+	st.waited = 0
+	return st
+}
+
+func TestSetConcurrency(t *testing.T) {
+	s := &Service{scheduler: newScheduler(4)}
+
+	_, err := s.SetConcurrency(0)
+	assert.ErrorIs(t, err, port.ErrServiceInvalidConcurrency)
+
+	c, err := s.SetConcurrency(2)
+	assert.NoError(t, err)
+	assert.Equal(t, &domain.Concurrency{Limit: 2}, c)
+	assert.Equal(t, c, s.Concurrency())
+}
@@ -9,6 +9,10 @@ import (
 	"goyav/pkg/helper"
 	"io"
 	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -18,8 +22,12 @@ type Service struct {
 	DocumentRepository port.DocumentRepository
 	AvAnalyzer         port.AntivirusAnalyzer
 
-	// semaphore is used to control concurrent access to resources.
-	semaphore chan struct{}
+	// scheduler limits the number of concurrent analyses and runs them by priority.
+	scheduler *scheduler
+
+	// concurrencyLimiter limits the number of concurrent analyses of all the replicas sharing it, on top of scheduler;
+	// each replica is only limited by its own scheduler when it is nil.
+	concurrencyLimiter port.ConcurrencyLimiter
 
 	// version is the current version of the service
 	version string
@@ -29,19 +37,173 @@ type Service struct {
 
 	// resultTimeToLive specifies the duration for which analysis results are retained.
 	resultTimeToLive time.Duration
+
+	// statusRetentions overrides resultTimeToLive for the documents of some statuses, which are never purged
+	// automatically when their retention is zero.
+	statusRetentions map[domain.AnalysisStatus]time.Duration
+
+	// purgeSchedule schedules the auto-purge, which runs every shortest retention when it is zero.
+	purgeSchedule domain.CronSchedule
+
+	// loadPollInterval is the interval between two queries of the analyzer's load; admission control
+	// is disabled when it is not strictly positive.
+	loadPollInterval time.Duration
+
+	// health caches the health reports, see WithHealthCache.
+	health healthCache
+
+	// analyzerSaturated reports whether the last known load of the analyzer was saturated.
+	analyzerSaturated atomic.Bool
+
+	// quotaRepository records the quota usage of tenants; quotas are not enforced when it is nil.
+	quotaRepository port.QuotaRepository
+
+	// defaultQuota is the quota of the tenants without a quota of their own in quotas.
+	defaultQuota domain.Quota
+
+	// quotas holds the quotas of specific tenants.
+	quotas map[string]domain.Quota
+
+	// anonymizer pseudonymizes the tags and origins of documents at rest, they are stored as is when it is nil.
+	anonymizer port.Anonymizer
+
+	// pendingAnalyses is the number of analyses waiting for a slot of the scheduler or running.
+	pendingAnalyses atomic.Int64
+
+	// analyzing holds the docKey of the documents counted in pendingAnalyses, so that they are not requeued.
+	analyzing sync.Map
+
+	// stuckPending is the number of documents pending for longer than stuckThreshold at the last count, they are not
+	// counted when stuckThreshold is not strictly positive.
+	stuckThreshold time.Duration
+	stuckPending   atomic.Int64
+
+	// maxQueuedAnalyses is the number of analyses waiting for a slot above which the uploads are rejected, they are
+	// never rejected when it is not strictly positive.
+	maxQueuedAnalyses int64
+
+	// analysisRetries counts the attempts of analyses made after a failed attempt.
+	analysisRetries atomic.Uint64
+
+	// fallbackAnalyzer analyzes the data when AvAnalyzer keeps failing, if not nil, and fallbackAnalyses counts the
+	// analyses it completed.
+	fallbackAnalyzer port.AntivirusAnalyzer
+	fallbackAnalyses atomic.Uint64
+
+	// engines are the engines an upload can select besides AvAnalyzer, by name.
+	engines map[string]port.AntivirusAnalyzer
+
+	// durations averages the durations of the analyses, to estimate when an analysis will complete.
+	durations durationEstimator
+
+	// retryPolicy schedules the attempts of the analyses.
+	retryPolicy RetryPolicy
+
+	// idScheme is the scheme of the IDs of the uploaded documents.
+	idScheme helper.IDScheme
+
+	// hashAlgorithm hashes the content of the uploaded documents.
+	hashAlgorithm helper.HashAlgorithm
+
+	// maxUploadSizes maps tenants to the maximum size of their uploads, in bytes.
+	maxUploadSizes map[string]int64
+
+	// spool holds the data read more than once, such as the uploads of unknown size.
+	spool spool
+
+	// presignExpiry is the validity of the presigned upload URLs, presigned uploads are disabled when it is not
+	// strictly positive or when the binary repository does not implement port.BinaryPresigner.
+	presignExpiry time.Duration
+
+	// presignMaxSize is the maximum size in bytes of a presigned upload of the tenants without a maximum upload
+	// size of their own, presigned uploads are not bounded when it is not strictly positive.
+	presignMaxSize int64
+
+	// retainClean keeps the binary data of the clean documents after their analysis, to be downloaded with presigned
+	// URLs valid for downloadExpiry.
+	retainClean    bool
+	downloadExpiry time.Duration
+
+	// quarantine keeps the binary data of the infected documents after their analysis, protected from deletion
+	// if the binary repository implements port.BinaryQuarantiner.
+	quarantine bool
+
+	// tagVerdicts records the verdicts of the documents along with their retained binary data, see port.BinaryTagger.
+	tagVerdicts bool
+
+	// mediaTypePolicy restricts the media types of the uploaded documents.
+	mediaTypePolicy domain.MediaTypePolicy
+
+	// tagPolicy sanitizes the tags of the uploaded documents.
+	tagPolicy helper.TagPolicy
+
+	// analysisDeadline bounds the duration of an analysis, from the moment it gets a slot of the scheduler;
+	// analyses are not bounded when it is not strictly positive.
+	analysisDeadline time.Duration
+
+	// imageAnalyzer analyzes container images, which are not accepted when it is nil.
+	imageAnalyzer port.ImageAnalyzer
+
+	// archiveAnalyzer extracts the archives to analyze their files one by one, they are analyzed as a whole when it is nil.
+	archiveAnalyzer port.ArchiveAnalyzer
+
+	// dedupePolicy is the deduplication policy of the tenants without a policy of their own in dedupePolicies.
+	dedupePolicy   domain.DedupePolicy
+	dedupePolicies map[string]domain.DedupePolicy
+
+	// verdictCache keeps the verdicts of the analyses by hash of the analyzed content, verdicts are not cached when
+	// it is nil.
+	verdictCache port.VerdictCache
+
+	// hashBlocklist gives the uploads of blocklisted content an infected verdict without an analysis, if not nil.
+	hashBlocklist port.HashBlocklist
+
+	// reputationSource gives its verdict on the content it is confident about before it is analyzed, if not nil.
+	// reputationVerdicts counts the verdicts it gave, and reputationErrors its failed lookups.
+	reputationSource   port.ReputationSource
+	reputationVerdicts atomic.Uint64
+	reputationErrors   atomic.Uint64
+
+	// callbacks notifies the callback URLs given with the uploads, which are refused when it is nil.
+	callbacks port.CallbackNotifier
+
+	// callbackOutbox holds the callback URLs until their documents have a final status, their notifications being
+	// claimed every outboxInterval for outboxLease and given up after outboxAttempts claims. The callback URLs are
+	// notified directly when it is nil.
+	callbackOutbox port.CallbackOutbox
+	outboxInterval time.Duration
+	outboxLease    time.Duration
+	outboxAttempts int
+
+	// infectedRate measures the infected rate of the analyses, whose rise above its threshold is notified by
+	// alertNotifiers. It is nil when the alerts are disabled.
+	infectedRate   *infectedRateDetector
+	alertNotifiers []port.AlertNotifier
+
+	// reportSchedule schedules the summary reports, delivered by reportSenders, reporting the reportTop most frequent
+	// threats and busiest tenants. No report is scheduled when reportSenders is empty.
+	reportSchedule domain.ReportSchedule
+	reportTop      int
+	reportSenders  []port.ReportSender
+
+	// webhooks holds the webhook subscriptions, when the document repository implements port.WebhookRepository.
+	webhooks port.WebhookRepository
+
+	// purgeStats holds the totals of the purges run since the service started.
+	purgeStats    domain.PurgeStats
+	purgeStatsMux sync.Mutex
 }
 
 const (
 	// DefaultSemaphoreCapacity represents the default number of parallel goroutines
 	// that the server can run
 	DefaultSemaphoreCapacity = uint64(128)
+
+	// DefaultAnalysisDeadline is the default maximum duration of an analysis, retries included.
+	DefaultAnalysisDeadline = 15 * time.Minute
 )
 
 var (
-	// AntivirusRetryWaitTimes represents the time intervals in seconds before retrying
-	// an antivirus analysis after a connection failure.
-	AntivirusRetryWaitTimes = []int64{5, 10, 15, 25, 40, 65}
-
 	// ErrNilDependency is an error that occurs when a required dependency is nil
 	ErrNilDependency = errors.New("Service: nil dependency")
 )
@@ -49,10 +211,10 @@ var (
 // New creates a new Service instance with the specified dependencies, including binary repository,
 // document repository, antivirus analyzer, and additional service information like version, info,
 // result time-to-live, auto-purge flag, and semaphore capacity. It validates the dependencies and initializes
-// the Service with default or specified settings. If result time-to-if is strcitly positive, it starts
-// the purge process as a separate goroutine. Returns an error if dependencies are missing or if initial pinging of
-// repositories and analyzer fails.
-func New(binaryRepo port.BinaryRepository, docRepo port.DocumentRepository, avAnalyzer port.AntivirusAnalyzer, version, info string, resTTL time.Duration, semaphoreCapacity uint64) (*Service, error) {
+// the Service with default or specified settings. If result time-to-if, or a retention of WithStatusRetentions, is
+// strcitly positive, it starts the purge process as a separate goroutine. Returns an error if dependencies are missing or if initial pinging of
+// repositories and analyzer fails. Optional behaviours are enabled with opts.
+func New(binaryRepo port.BinaryRepository, docRepo port.DocumentRepository, avAnalyzer port.AntivirusAnalyzer, version, info string, resTTL time.Duration, semaphoreCapacity uint64, opts ...Option) (*Service, error) {
 	if binaryRepo == nil || docRepo == nil || avAnalyzer == nil {
 		return nil, fmt.Errorf("%w: missing repositories or analyzer", ErrNilDependency)
 	}
@@ -61,27 +223,79 @@ func New(binaryRepo port.BinaryRepository, docRepo port.DocumentRepository, avAn
 		return nil, fmt.Errorf("service: unable to create: %w", err)
 	}
 
-	autoPurge := (resTTL > 0)
+	service := newService(avAnalyzer, version, info, semaphoreCapacity)
+	service.BinayRepository = binaryRepo
+	service.DocumentRepository = docRepo
+	service.resultTimeToLive = resTTL
+	service.webhooks, _ = docRepo.(port.WebhookRepository)
 
-	capacity := max(semaphoreCapacity, DefaultSemaphoreCapacity)
-
-	service := &Service{
-		BinayRepository:    binaryRepo,
-		DocumentRepository: docRepo,
-		AvAnalyzer:         avAnalyzer,
-		semaphore:          make(chan struct{}, capacity),
-		version:            version,
-		information:        info,
-		resultTimeToLive:   resTTL,
+	for _, opt := range opts {
+		opt(service)
 	}
 
-	if autoPurge {
+	if service.purgeInterval() > 0 {
 		go service.autoPurge()
 	}
 
+	if len(service.reportSenders) > 0 {
+		go service.autoReport()
+	}
+
+	if o, ok := docRepo.(port.CallbackOutbox); ok && service.callbacks != nil && service.outboxInterval > 0 {
+		service.callbackOutbox = o
+		go service.dispatchCallbacks()
+	}
+
+	if service.stuckThreshold > 0 {
+		go service.watchStuckPending()
+	}
+
+	if lr, ok := avAnalyzer.(port.LoadReporter); ok && service.loadPollInterval > 0 {
+		go service.watchAnalyzerLoad(lr)
+	}
+
 	return service, nil
 }
 
+// NewAnalyzer creates a Service without repositories, which only scans data with avAnalyzer, see Scan, for the
+// deployments analyzing files without storing them: its operations on documents must not be used. It pings
+// avAnalyzer, and polls its load when configured to, but runs none of the background tasks of the documents, such as
+// the purge. Optional behaviours are enabled with opts.
+func NewAnalyzer(avAnalyzer port.AntivirusAnalyzer, version, info string, semaphoreCapacity uint64, opts ...Option) (*Service, error) {
+	if avAnalyzer == nil {
+		return nil, fmt.Errorf("%w: missing analyzer", ErrNilDependency)
+	}
+	if err := avAnalyzer.Ping(); err != nil {
+		return nil, fmt.Errorf("service: unable to create: %w", err)
+	}
+
+	service := newService(avAnalyzer, version, info, semaphoreCapacity)
+	for _, opt := range opts {
+		opt(service)
+	}
+	if lr, ok := avAnalyzer.(port.LoadReporter); ok && service.loadPollInterval > 0 {
+		go service.watchAnalyzerLoad(lr)
+	}
+	return service, nil
+}
+
+// newService creates a Service analyzing data with avAnalyzer, with the default settings and without repositories.
+func newService(avAnalyzer port.AntivirusAnalyzer, version, info string, semaphoreCapacity uint64) *Service {
+	capacity := max(semaphoreCapacity, DefaultSemaphoreCapacity)
+	return &Service{
+		AvAnalyzer:       avAnalyzer,
+		scheduler:        newScheduler(int(capacity)),
+		version:          version,
+		information:      info,
+		retryPolicy:      DefaultRetryPolicy,
+		analysisDeadline: DefaultAnalysisDeadline,
+		idScheme:         helper.DefaultIDScheme,
+		dedupePolicy:     domain.DedupeStrict,
+		tagPolicy:        helper.DefaultTagPolicy,
+		hashAlgorithm:    helper.DefaultHashAlgorithm,
+	}
+}
+
 // Version returns the current version of the service.
 func (s *Service) Version() string {
 	return s.version
@@ -92,66 +306,216 @@ func (s *Service) Information() string {
 	return s.information
 }
 
-// Upload handles the uploading of a document to the service. It computes a hash of the document,
-// sanitizes the provided tag, checks for the existence of a document with the same hash,
-// and either returns the ID of the existing document or saves a new one and triggers antivirus analysis.
+// Upload handles the uploading of a document of the tenant carried by ctx to the service. It checks the upload against
+// the tenant's quota, computes a hash of the document, sanitizes the provided tag with the tag policy, checks for the existence of a document
+// with the same hash, and either returns the ID of the existing document or saves a new one and triggers antivirus analysis.
+// The analysis is scheduled with the priority carried by ctx, and its result sent to the callback URL carried by ctx, if any.
+// The data is analyzed by the engines carried by ctx, all the engines of the service if there are none, and recorded
+// along with the document.
+// Whether an existing document is returned, and whether a known verdict is reused, depends on the deduplication policy
+// of the tenant, see domain.DedupePolicy.
 func (s *Service) Upload(ctx context.Context, data io.Reader, size int64, tag string) (ID string, err error) {
-	// Sanitize the tag.
-	tag = helper.Sanitize(tag)
+	// Documents are owned by the tenant of the request.
+	tenant := domain.TenantFromContext(ctx)
+
+	// The callback URL and the backlog of analyses are checked before anything is stored.
+	if err = s.validateCallback(ctx); err != nil {
+		return "", err
+	}
+	if err = s.checkBacklog(); err != nil {
+		return "", err
+	}
+	engines, err := s.selectEngines(ctx)
+	if err != nil {
+		return "", err
+	}
+	ctx = domain.ContextWithEngines(ctx, engines)
+
+	// The data streamed without a known size is spooled first, for its size to be checked.
+	if size < 0 {
+		sr, cleanup, err := s.spool.rewindable(data, size)
+		if err != nil {
+			return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+		}
+		defer cleanup()
+		data, size = sr, sr.Size()
+	}
+
+	// Check the upload against the tenant's maximum upload size, then against its quota. The reserved bytes
+	// are given back unless the binary data ends up stored.
+	if limit := s.MaxUploadSize(ctx); limit > 0 && size > limit {
+		return "", fmt.Errorf("service: %w: %d bytes exceed the maximum upload size of %d bytes: tenant=%q", port.ErrServiceFileTooLarge, size, limit, tenant)
+	}
+	if err = s.reserveQuota(ctx, size); err != nil {
+		return "", err
+	}
+	stored := false
+	defer func() {
+		if !stored {
+			s.releaseQuota(ctx, size)
+		}
+	}()
+
+	// Sanitize the tag and the original file name.
+	tag = s.tagPolicy.Sanitize(tag)
+	fileName := helper.SanitizeFileName(domain.FileNameFromContext(ctx))
+
+	// The data is read a first time to calculate its hash, then from its start again to be saved.
+	sr, cleanup, err := s.spool.rewindable(data, size)
+	if err != nil {
+		return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
+	defer cleanup()
 
 	// new CryptoWriter for generating hash and ID
-	cw := helper.NewCryptoWriter()
-	data = io.TeeReader(io.LimitReader(data, size), cw)
+	cw := helper.NewCryptoWriterWithAlgorithm(s.hashAlgorithm)
+	if _, err = helper.Copy(cw, sr); err != nil {
+		return "", fmt.Errorf("service: %w: failed to read data: %v", port.ErrServiceUploadFailed, err)
+	}
+	if _, err = sr.Seek(0, io.SeekStart); err != nil {
+		return "", fmt.Errorf("service: %w: failed to rewind data: %v", port.ErrServiceUploadFailed, err)
+	}
+	contentType := detectContentType(sr)
+	if !s.mediaTypePolicy.Allows(contentType) {
+		return "", fmt.Errorf("service: %w: %s", port.ErrServiceUnsupportedMediaType, contentType)
+	}
 
-	// Calculate the hash of the document and Generate its ID
-	hash, ID, err := cw.GenerateHashAndID(tag)
+	// Calculate the hash of the document and Generate its ID, the tenant is part of the ID's seed
+	// so that identical uploads of different tenants do not collide. Unless IDs are derived from MD5
+	// hashes, the ID is drawn at random.
+	seed := tag
+	if tenant != domain.DefaultTenant {
+		seed = tenant + "/" + tag
+	}
+	hash, ID, err := cw.GenerateHashAndID(seed)
+	policy := s.tenantDedupePolicy(ctx)
+	if err == nil && s.idScheme == helper.IDSchemeMD5 && policy != domain.DedupeStrict {
+		// every upload gets a document of its own, whose ID cannot be derived from the data and the tag only
+		ID, err = uniqueMD5ID(ID)
+	}
+	if err == nil {
+		ID, err = s.idScheme.NewID(ID)
+	}
 	if err != nil {
 		return "", fmt.Errorf("service: failed to calculate the hash or creating a document ID : %w", err)
 	}
 
-	// Check if a document with the same hash already exists.
-	existingDoc, _ := s.DocumentRepository.GetByHash(ctx, hash)
-	if existingDoc != nil {
-		// Return existing document's ID if it has the same tag.
-		if existingDoc.Tag == tag {
-			return existingDoc.ID, port.ErrDocumentAlreadyExists
-		}
-
-		// Otherwise save the document with a new ID if it's not pending analysis.
-		if existingDoc.Status != domain.StatusPending {
-			err = s.DocumentRepository.Save(ctx, &domain.Document{
-				ID:         ID,
-				Hash:       hash,
-				Tag:        tag,
-				Status:     existingDoc.Status,
-				AnalyzedAt: existingDoc.AnalyzedAt,
-				CreatedAt:  time.Now(),
-			})
-			if err != nil {
+	// MD5 IDs are derived from the data and the tag, the same upload as a soft-deleted document restores it.
+	if s.idScheme == helper.IDSchemeMD5 && policy == domain.DedupeStrict {
+		if deleted, _ := s.DocumentRepository.Get(ctx, ID); deleted != nil && deleted.IsDeleted() {
+			if err = s.DocumentRepository.Restore(ctx, ID); err != nil {
 				return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
 			}
 			return ID, port.ErrDocumentAlreadyExists
 		}
 	}
 
+	// Blocklisted content is infected whatever the verdict on the documents of the same content.
+	blocked, isBlocked := s.blockedVerdict(ctx, hash)
+
+	// Check if a document with the same hash already exists, the hashes of other algorithms do not match, unless
+	// the content is analyzed again anyway. Return existing document's ID if it has the same tag and the policy is
+	// strict, infected first if its content is blocklisted, its callback is notified at once if it has a verdict, or
+	// once it has one through the callback outbox, if any.
+	var existingDoc *domain.Document
+	if policy != domain.DedupeRescan {
+		existingDoc, _ = s.DocumentRepository.GetByHash(ctx, hash)
+	}
+	if existingDoc != nil && existingDoc.Tag == s.pseudonym(tag) && policy == domain.DedupeStrict {
+		if isBlocked && existingDoc.Status != domain.StatusInfected {
+			if err = s.DocumentRepository.UpdateStatus(ctx, existingDoc.ID, blocked.Status, blocked.Threat, blocked.AnalyzedAt); err != nil {
+				return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+			}
+			existingDoc.Status = blocked.Status
+		}
+		if ctx = s.queueCallback(ctx, existingDoc.ID); existingDoc.Status.IsVerdict() {
+			go s.notifyCallback(context.WithoutCancel(ctx), existingDoc.ID)
+		}
+		return existingDoc.ID, port.ErrDocumentAlreadyExists
+	}
+
+	// Otherwise save the document with a new ID if the verdict on the same content is known, from the blocklist, from
+	// an existing document or from the verdict cache, without storing nor analyzing its data.
+	known, ok := blocked, isBlocked
+	if !ok {
+		known, ok = s.knownVerdict(ctx, policy, existingDoc, hash)
+	}
+	if ok {
+		doc := &domain.Document{
+			ID:          ID,
+			Tenant:      tenant,
+			Hash:        hash,
+			HashAlgo:    string(s.hashAlgorithm),
+			Tag:         tag,
+			Status:      known.Status,
+			Threat:      known.Threat,
+			AnalyzedAt:  known.AnalyzedAt,
+			CreatedAt:   time.Now(),
+			Source:      domain.SourceUpload,
+			FileName:    fileName,
+			Size:        sr.Size(),
+			ContentType: contentType,
+			Labels:      domain.LabelsFromContext(ctx),
+		}
+		if err = s.protect(doc); err != nil {
+			return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+		}
+		if err = s.DocumentRepository.Save(ctx, doc); err != nil {
+			return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+		}
+		ctx = s.queueCallback(ctx, ID)
+		go s.notifyCallback(context.WithoutCancel(ctx), ID)
+		return ID, port.ErrDocumentAlreadyExists
+	}
+
 	// Save the binary data.
-	if err = s.BinayRepository.Save(ctx, data, size, ID); err != nil {
+	if err = s.BinayRepository.Save(ctx, sr, sr.Size(), ID); err != nil {
 		return "", fmt.Errorf("service: %w: %w: id=%v", port.ErrServiceUploadFailed, err, ID)
 	}
 
 	// Create and save a new document.
 	newDoc := domain.NewDocument(ID, hash, tag)
+	newDoc.Tenant = tenant
+	newDoc.HashAlgo = string(s.hashAlgorithm)
+	newDoc.FileName = fileName
+	newDoc.Size = sr.Size()
+	newDoc.ContentType = contentType
+	newDoc.Labels = domain.LabelsFromContext(ctx)
+	newDoc.Engines = engines
+	newDoc.BinaryVersion = s.binaryVersion(ctx, ID)
+	if err = s.protect(newDoc); err != nil {
+		return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
+	}
 	if err = s.DocumentRepository.Save(ctx, newDoc); err != nil {
+		// the same upload saved concurrently is analyzed already
+		if errors.Is(err, port.ErrDocumentAlreadyExists) {
+			return ID, port.ErrDocumentAlreadyExists
+		}
 		return "", fmt.Errorf("service: %w: %w", port.ErrServiceUploadFailed, err)
 	}
 
-	// Trigger an asynchronous antivirus analysis.
-	go s.asyncAnalyze(ID)
+	// Trigger an asynchronous antivirus analysis, whose result is notified through the callback outbox, if any.
+	ctx = s.queueCallback(ctx, ID)
+	stored = true
+	s.pendingAnalyses.Add(1)
+	go s.asyncAnalyze(context.WithoutCancel(ctx), ID, size)
 
 	return ID, nil
 }
 
+// detectContentType returns the media type of the data, detected from its first bytes
+// rather than trusted from the client, see http.DetectContentType.
+func detectContentType(data io.ReaderAt) string {
+	head := make([]byte, 512)
+	n, err := data.ReadAt(head, 0)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return ""
+	}
+	return http.DetectContentType(head[:n])
+}
+
 // GetDocument retrieves the current status of a document by its ID.
+// Only the documents of the tenant carried by ctx can be retrieved, the soft-deleted ones are reported as deleted.
 func (s *Service) GetDocument(ctx context.Context, ID string) (*domain.Document, error) {
 	if !helper.IsValidID(ID) {
 		return nil, fmt.Errorf("service: %w: the provided ID is not valid", port.ErrServiceInvalidID)
@@ -160,7 +524,77 @@ func (s *Service) GetDocument(ctx context.Context, ID string) (*domain.Document,
 	if err != nil {
 		return nil, fmt.Errorf("%w: %w: id=%s", port.ErrServiceGetDocumentFailed, err, ID)
 	}
-	return document, nil
+	if document.IsDeleted() {
+		return nil, fmt.Errorf("service: %w: deleted at %s: id=%s", port.ErrServiceDocumentDeleted, document.DeletedAt.Format(time.RFC3339), ID)
+	}
+	return s.reveal(ctx, document), nil
+}
+
+// LookupHash retrieves the document of the tenant carried by ctx holding the content with the given hash, in the
+// hash algorithm of the service, pending or not. The soft-deleted documents are ignored.
+func (s *Service) LookupHash(ctx context.Context, hash string) (*domain.Document, error) {
+	hash = strings.ToLower(hash)
+	if !s.hashAlgorithm.IsValid(hash) {
+		return nil, fmt.Errorf("service: %w: %q is not a %s hash", port.ErrServiceInvalidHash, hash, s.hashAlgorithm)
+	}
+	document, err := s.DocumentRepository.GetByHash(ctx, hash)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w: hash=%s", port.ErrServiceGetDocumentFailed, err, hash)
+	}
+	return s.reveal(ctx, document), nil
+}
+
+// ListDocuments retrieves the documents of the tenant carried by ctx matching filter, the most recent first. The limit
+// of filter defaults to domain.DefaultListLimit and is bounded by domain.MaxListLimit. Its tag prefix is sanitized as
+// the tags are, and refused while the tags are pseudonymized since the stored tags are not theirs.
+func (s *Service) ListDocuments(ctx context.Context, filter domain.DocumentFilter) ([]*domain.Document, error) {
+	if err := s.checkFilter(&filter); err != nil {
+		return nil, err
+	}
+	if filter.Limit <= 0 {
+		filter.Limit = domain.DefaultListLimit
+	}
+	filter.Limit = min(filter.Limit, domain.MaxListLimit)
+	docs, err := s.DocumentRepository.List(ctx, filter)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceListDocumentsFailed, err)
+	}
+	for i, doc := range docs {
+		docs[i] = s.reveal(ctx, doc)
+	}
+	return docs, nil
+}
+
+// ExportDocuments calls fn with each document of the tenant carried by ctx matching filter, the oldest first, whatever
+// the limit and the cursor of filter, without holding them all in memory. The filter is checked as by ListDocuments, before fn is
+// first called. It stops at the first error returned by fn.
+func (s *Service) ExportDocuments(ctx context.Context, filter domain.DocumentFilter, fn func(*domain.Document) error) error {
+	if err := s.checkFilter(&filter); err != nil {
+		return err
+	}
+	filter.Limit, filter.After = 0, domain.DocumentCursor{}
+	err := s.DocumentRepository.Iterate(ctx, filter, func(doc *domain.Document) error {
+		return fn(s.reveal(ctx, doc))
+	})
+	if err != nil {
+		return fmt.Errorf("service: %w: %w", port.ErrServiceListDocumentsFailed, err)
+	}
+	return nil
+}
+
+// checkFilter sanitizes the tag prefix of filter as the tags are, and refuses it while the tags are pseudonymized
+// since the stored tags are not theirs.
+func (s *Service) checkFilter(filter *domain.DocumentFilter) error {
+	if filter.TagPrefix == "" {
+		return nil
+	}
+	if s.anonymizer != nil {
+		return fmt.Errorf("service: %w: the tags are pseudonymized", port.ErrServiceInvalidFilter)
+	}
+	if filter.TagPrefix = s.tagPolicy.Sanitize(filter.TagPrefix); filter.TagPrefix == "" {
+		return fmt.Errorf("service: %w: the tag prefix has no character kept by the tag policy", port.ErrServiceInvalidFilter)
+	}
+	return nil
 }
 
 func (s *Service) Ping() error {
@@ -173,64 +607,298 @@ func (s *Service) Ping() error {
 
 const asyncAnalyseErrorMsg = "service - async analysis error"
 
-// asyncAnalyze performs the analysis of the data asynchronously with retry attempts
-func (s *Service) asyncAnalyze(ID string) {
-	s.semaphore <- struct{}{}
+// asyncAnalyze performs the analysis of the data of a document of the tenant carried by ctx asynchronously with retry
+// attempts, by the engines carried by ctx, once the scheduler gives it a slot for the priority carried by ctx. ctx
+// must not be canceled with the upload request, it only carries its values. size is the size of the data, given back
+// to the tenant's quota once the data is deleted. The caller counts the analysis in pendingAnalyses before starting
+// asyncAnalyze, so that it is counted as soon as the upload returns.
+func (s *Service) asyncAnalyze(ctx context.Context, ID string, size int64) {
+	k := docKey{domain.TenantFromContext(ctx), ID}
+	s.analyzing.Store(k, struct{}{})
+	s.scheduler.acquire(domain.PriorityFromContext(ctx))
 	go func() {
 		defer func() {
-			<-s.semaphore
+			s.scheduler.release()
+			s.analyzing.Delete(k)
+			s.pendingAnalyses.Add(-1)
 		}()
 
-		ctx := context.Background()
+		// The analysis, the wait for the analyzer included, is bounded by its deadline.
+		actx := ctx
+		if s.analysisDeadline > 0 {
+			var cancel context.CancelFunc
+			actx, cancel = context.WithTimeout(ctx, s.analysisDeadline)
+			defer cancel()
+		}
 
-		// Retrieve and defer close the data stream
-		r, err := s.BinayRepository.Get(ctx, ID)
-		if err != nil {
-			slog.Error(asyncAnalyseErrorMsg, "error", err, "ID", ID)
+		// Take the verdict of the reputation source if it is confident, otherwise hold back the analysis while the
+		// analyzer is saturated or the replicas run as many analyses as they share, then attempt to analyze with retries
+		start := time.Now()
+		var err error
+		v, reputed := s.reputationVerdict(actx, ID)
+		if !reputed {
+			var release func()
+			if release, err = s.admit(actx); err == nil {
+				v, err = s.analyzeEngines(actx, ID, size)
+				release()
+			}
+		}
+		switch {
+		case err != nil && errors.Is(actx.Err(), context.DeadlineExceeded):
+			s.failAnalysis(ctx, ID, size, domain.StatusTimeout, err)
+			return
+		case errors.Is(err, port.ErrAntivirusSizeLimitExceeded):
+			s.failAnalysis(ctx, ID, size, domain.StatusTooLarge, err)
+			return
+		case err != nil:
+			s.failAnalysis(ctx, ID, size, domain.StatusError, err)
 			return
 		}
-		defer r.Close()
 
-		// Attempt to analyze with retries
-		if err := s.attemptAnalysis(ctx, r, ID); err != nil {
-			slog.Error(asyncAnalyseErrorMsg, "error", err, "ID", ID)
+		// Record the verdict, along with the verdict on each file of an archive, and delete the analyzed data
+		// unless it is retained
+		retained := s.retains(v.status)
+		if v.archive != nil {
+			err = s.DocumentRepository.SaveArchiveReport(ctx, ID, v.archive)
+		}
+		analyzedAt := time.Now()
+		if err == nil {
+			err = s.DocumentRepository.UpdateStatus(ctx, ID, v.status, v.threat, analyzedAt)
+		}
+		if err == nil {
+			s.cacheVerdict(ctx, ID, v, analyzedAt)
+			s.observeVerdict(v.status)
+			go s.notifyCallback(ctx, ID)
+		}
+		if err == nil && !retained {
+			err = s.BinayRepository.Delete(ctx, ID)
+		}
+		if err == nil && retained {
+			s.tagVerdict(ctx, &domain.Document{ID: ID, Status: v.status, Threat: v.threat, AnalyzedAt: analyzedAt})
+		}
+		if q, ok := s.BinayRepository.(port.BinaryQuarantiner); ok && err == nil && retained && v.status == domain.StatusInfected {
+			err = q.Quarantine(ctx, ID)
+		}
+		if err != nil {
+			slog.ErrorContext(ctx, asyncAnalyseErrorMsg, "error", err, "ID", ID)
+			return
+		}
+		if !reputed {
+			s.durations.record(size, time.Since(start))
+		}
+		if !retained {
+			s.releaseQuota(ctx, size)
 		}
-		slog.Debug("analyse completed", "ID", ID)
+		slog.DebugContext(ctx, "analyse completed", "ID", ID)
 
 	}()
 }
 
-// attemptAnalysis tries to analyze the data with retries.
-func (s *Service) attemptAnalysis(ctx context.Context, r io.Reader, ID string) error {
-	var status domain.AnalysisStatus
-	for _, v := range AntivirusRetryWaitTimes {
-		var err error
-		if status, err = s.AvAnalyzer.Analyze(ctx, r); err == nil {
-			if err = s.DocumentRepository.UpdateStatus(ctx, ID, status, time.Now()); err == nil {
-				return s.BinayRepository.Delete(ctx, ID)
-			}
+// retains reports whether the binary data of the documents analyzed with the given status is kept after their
+// analysis: the clean ones are retained to be downloaded, and the infected ones quarantined, when configured to.
+func (s *Service) retains(status domain.AnalysisStatus) bool {
+	return (s.retainClean && status == domain.StatusClean) || (s.quarantine && status == domain.StatusInfected)
+}
+
+// tagVerdict records the verdict of doc along with its retained binary data, when configured to and supported by the
+// binary repository. A failure is only logged, the verdict is recorded already.
+func (s *Service) tagVerdict(ctx context.Context, doc *domain.Document) {
+	t, ok := s.BinayRepository.(port.BinaryTagger)
+	if !ok || !s.tagVerdicts {
+		return
+	}
+	if err := t.TagVerdict(ctx, doc); err != nil {
+		slog.ErrorContext(ctx, "service - failed to tag the retained binary data with its verdict", "error", err, "ID", doc.ID)
+	}
+}
+
+// attemptAnalysis analyzes the data of size bytes of a document, retrying as the retry policy of the service allows.
+// The data is retrieved again for each attempt, since a failed attempt may have consumed it, and missing data is not
+// retried, nor is data larger than the analyzer accepts. Once the retries are exhausted, the data is analyzed by the
+// fallback analyzer, if any. The verdict carries the report of the analysis of an archive, see Service.analyze.
+func (s *Service) attemptAnalysis(ctx context.Context, ID string, size int64) (verdict, error) {
+	var (
+		v        verdict
+		attempts int
+	)
+	err := s.retryPolicy.retry(ctx, func() error {
+		if attempts++; attempts > 1 {
+			s.analysisRetries.Add(1)
+		}
+		r, err := s.BinayRepository.Get(ctx, ID)
+		if errors.Is(err, port.ErrBinaryNotFound) {
+			return permanentError{err}
+		}
+		if err != nil {
 			return err
 		}
-		time.Sleep(time.Second * time.Duration(v))
+		defer r.Close()
+		v, err = s.analyze(ctx, r, size)
+		if errors.Is(err, port.ErrAntivirusSizeLimitExceeded) {
+			return permanentError{err}
+		}
+		return err
+	})
+	if err != nil && s.fallbackAnalyzer != nil && !errors.As(err, new(permanentError)) && ctx.Err() == nil {
+		slog.WarnContext(ctx, "service - analysis handed over to the fallback analyzer", "error", err, "ID", ID)
+		var ferr error
+		if v, ferr = s.attemptFallback(ctx, ID); ferr != nil {
+			err = errors.Join(err, fmt.Errorf("fallback %w", ferr))
+		} else {
+			err = nil
+		}
+	}
+	if err != nil {
+		return verdict{status: domain.StatusPending}, fmt.Errorf("analysis %w", err)
 	}
-	return fmt.Errorf("analysis failed after %d attempts", len(AntivirusRetryWaitTimes))
+	return v, nil
 }
 
+// failAnalysis records the final status of a document whose analysis failed with err, StatusTimeout, StatusError or
+// StatusTooLarge, then deletes its data, if any, and gives its size back to the tenant's quota. ctx must not be the
+// expired context of the analysis.
+func (s *Service) failAnalysis(ctx context.Context, ID string, size int64, status domain.AnalysisStatus, err error) {
+	slog.ErrorContext(ctx, asyncAnalyseErrorMsg, "error", err, "ID", ID, "status", status.String())
+	if err := s.DocumentRepository.UpdateStatus(ctx, ID, status, "", time.Now()); err != nil {
+		slog.ErrorContext(ctx, asyncAnalyseErrorMsg, "error", err, "ID", ID)
+		return
+	}
+	go s.notifyCallback(ctx, ID)
+	if err := s.BinayRepository.Delete(ctx, ID); err != nil && !errors.Is(err, port.ErrBinaryNotFound) {
+		slog.ErrorContext(ctx, asyncAnalyseErrorMsg, "error", err, "ID", ID)
+		return
+	}
+	s.releaseQuota(ctx, size)
+}
+
+// ping pings the repositories, unless they are nil, and the analyzer.
 func ping(b port.BinaryRepository, d port.DocumentRepository, a port.AntivirusAnalyzer) error {
-	return errors.Join(b.Ping(), d.Ping(), a.Ping())
+	var errs []error
+	if b != nil {
+		errs = append(errs, b.Ping())
+	}
+	if d != nil {
+		errs = append(errs, d.Ping())
+	}
+	return errors.Join(append(errs, a.Ping())...)
 }
 
 // autoPurge periodically purges old documents from the document repository.
-// It runs indefinitely, triggering a purge operation at intervals defined by documentTimeToLive.
+// It runs indefinitely, triggering a purge operation at the times of the purge schedule if it is set, or else at
+// intervals defined by the shortest retention, see purgeInterval. The replicas sharing the document repository do not
+// purge at once, nor one right after the other, see runPurge.
 func (s *Service) autoPurge() {
-	ticker := time.NewTicker(s.resultTimeToLive)
+	if !s.purgeSchedule.IsZero() {
+		for previous := time.Now(); ; {
+			next, gap := nextScheduledPurge(s.purgeSchedule, previous, time.Now())
+			time.Sleep(time.Until(next))
+			s.runPurge(gap)
+			previous = next
+		}
+	}
+
+	interval := s.purgeInterval()
+	ticker := time.NewTicker(interval)
 	defer ticker.Stop()
 
 	for range ticker.C {
-		purgeTime := time.Now().Add(-s.resultTimeToLive)
-		if err := s.DocumentRepository.Purge(purgeTime); err != nil {
+		s.runPurge(purgeGap(interval))
+	}
+}
+
+// purgeGap returns the shortest time between two purges by any of the replicas for the purges planned period apart:
+// nine tenths of it, so that the replicas whose clocks or tickers run slightly early do not skip a period.
+func purgeGap(period time.Duration) time.Duration {
+	return period - period/10
+}
+
+// nextScheduledPurge returns the first time of schedule after after, and the shortest time between two purges by any
+// of the replicas for the purge run then: the gap of the period since previous, the time of the purge before it or of
+// the start of the schedule, see purgeGap. The period is not the one until the following purge, since the runs of an
+// irregular schedule, such as every weekday, are not equally spaced.
+func nextScheduledPurge(schedule domain.CronSchedule, previous, after time.Time) (time.Time, time.Duration) {
+	next := schedule.Next(after)
+	return next, purgeGap(next.Sub(previous))
+}
+
+// purgeInterval returns the shortest strictly positive retention, of resultTimeToLive and of statusRetentions, or zero
+// if there is none: the documents are not purged automatically.
+func (s *Service) purgeInterval() time.Duration {
+	interval := max(s.resultTimeToLive, 0)
+	for _, ttl := range s.statusRetentions {
+		if ttl > 0 && (interval == 0 || ttl < interval) {
+			interval = ttl
+		}
+	}
+	return interval
+}
+
+// runPurge purges the expired documents, see purgeExpired, unless another replica sharing the document repository
+// is purging them already, or purged them less than every ago, when the repository implements port.JobLocker.
+func (s *Service) runPurge(every time.Duration) {
+	l, ok := s.DocumentRepository.(port.JobLocker)
+	if !ok {
+		s.purgeExpired()
+		return
+	}
+	ran, err := l.RunExclusive(context.Background(), purgeJob, every, func(context.Context) error {
+		s.purgeExpired()
+		return nil
+	})
+	switch {
+	case err != nil:
+		slog.Error("service - auto_purge failed", "error", err)
+	case !ran:
+		slog.Debug("service - auto_purge skipped, run by another replica", "every", every.String())
+	}
+}
+
+// purgeExpired purges the documents created, or soft-deleted, more than their retention ago, along with their surviving
+// binary data: the retention of their status in statusRetentions if it has one, resultTimeToLive otherwise.
+func (s *Service) purgeExpired() {
+	rules := s.purgeRules(time.Now())
+	b, err := s.purgeBinaries(context.Background(), rules)
+	if err != nil {
+		slog.Error("service - auto_purge failed", "error", err)
+		return
+	}
+	var n int64
+	for _, r := range rules {
+		purged, err := s.DocumentRepository.Purge(r.date, r.statuses...)
+		n += purged
+		if err != nil {
+			s.recordPurge(n)
 			slog.Error("service - auto_purge failed", "error", err)
+			return
 		}
-		slog.Debug("service - auto-purge done")
 	}
+	s.recordPurge(n)
+	slog.Debug("service - auto-purge done", "rules", len(rules), "documents", n, "binaries", b)
+}
+
+// purgeRules returns the rules of the periodic purge at now: a rule for each status with a strictly positive retention
+// in statusRetentions, and a rule for the other statuses with resultTimeToLive, if it is strictly positive. Without
+// statusRetentions, a single rule purges the documents of any status with resultTimeToLive.
+func (s *Service) purgeRules(now time.Time) []purgeRule {
+	if len(s.statusRetentions) == 0 {
+		return []purgeRule{{date: now.Add(-s.resultTimeToLive)}}
+	}
+
+	var (
+		rules  []purgeRule
+		others []domain.AnalysisStatus
+	)
+	for _, status := range domain.AnalysisStatuses {
+		ttl, ok := s.statusRetentions[status]
+		switch {
+		case !ok:
+			others = append(others, status)
+		case ttl > 0:
+			rules = append(rules, purgeRule{date: now.Add(-ttl), statuses: []domain.AnalysisStatus{status}})
+		}
+	}
+	if len(others) > 0 && s.resultTimeToLive > 0 {
+		rules = append(rules, purgeRule{date: now.Add(-s.resultTimeToLive), statuses: others})
+	}
+	return rules
 }
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"goyav/pkg/helper"
+	"io"
+	"os"
+	"sync"
+)
+
+// spoolBuffers are the buffers of the data held in memory by the spools, reused once the data is no longer read.
+var spoolBuffers = sync.Pool{New: func() any { return new(bytes.Buffer) }}
+
+// spool holds the data read more than once by the service, see WithSpool.
+type spool struct {
+	memory int64  // memory is the size of the data held in memory, the larger data is spilled to a temporary file.
+	dir    string // dir is the directory of the temporary files, the default one of the system if empty.
+}
+
+// rewindable returns a reader of the first size bytes of data, all of them if size is negative, which can be read
+// again from its start. Data of a known size implementing io.ReaderAt, such as uploaded multipart files, is read in
+// place; other data is held in memory up to the memory of p, and spooled to a temporary file otherwise. The returned
+// cleanup function removes the file, or gives the memory back to be reused, once the reader is no longer read.
+func (p spool) rewindable(data io.Reader, size int64) (*io.SectionReader, func(), error) {
+	if ra, ok := data.(io.ReaderAt); ok && size >= 0 {
+		return io.NewSectionReader(ra, 0, size), func() {}, nil
+	}
+
+	if p.memory > 0 && size <= p.memory {
+		// A data of unknown size is read one byte beyond the memory to know whether it fits.
+		limit := size
+		if size < 0 {
+			limit = p.memory + 1
+		}
+		buf := spoolBuffers.Get().(*bytes.Buffer)
+		buf.Reset()
+		release := func() { spoolBuffers.Put(buf) }
+		n, err := io.CopyN(buf, data, limit)
+		if err != nil && !errors.Is(err, io.EOF) {
+			release()
+			return nil, nil, fmt.Errorf("failed to read data: %w", err)
+		}
+		if n <= p.memory {
+			return io.NewSectionReader(bytes.NewReader(buf.Bytes()), 0, n), release, nil
+		}
+		defer release()
+		data = io.MultiReader(buf, data)
+	}
+
+	f, err := os.CreateTemp(p.dir, "goyav-upload-*")
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create a temporary file: %w", err)
+	}
+	cleanup := func() {
+		f.Close()
+		os.Remove(f.Name())
+	}
+	if size >= 0 {
+		data = io.LimitReader(data, size)
+	}
+	n, err := helper.Copy(f, data)
+	if err != nil {
+		cleanup()
+		return nil, nil, fmt.Errorf("failed to spool data to %s: %w", f.Name(), err)
+	}
+	return io.NewSectionReader(f, 0, n), cleanup, nil
+}
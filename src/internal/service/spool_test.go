@@ -0,0 +1,44 @@
+package service
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSpoolRewindable(t *testing.T) {
+	dir := t.TempDir()
+	p := spool{memory: 8, dir: dir}
+	spooled := func() int {
+		entries, err := os.ReadDir(dir)
+		assert.NoError(t, err)
+		return len(entries)
+	}
+
+	for name, tc := range map[string]struct {
+		data    string
+		size    int64
+		spilled bool
+	}{
+		"KnownSizeInMemory":   {data: "goyav", size: 5},
+		"UnknownSizeInMemory": {data: "12345678", size: -1},
+		"KnownSizeSpilled":    {data: "123456789", size: 9, spilled: true},
+		"UnknownSizeSpilled":  {data: "123456789", size: -1, spilled: true},
+	} {
+		t.Run(name, func(t *testing.T) {
+			// the readers are wrapped not to be read in place
+			sr, cleanup, err := p.rewindable(io.MultiReader(bytes.NewReader([]byte(tc.data))), tc.size)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.spilled, spooled() == 1)
+
+			b, err := io.ReadAll(sr)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.data, string(b))
+			cleanup()
+			assert.Zero(t, spooled())
+		})
+	}
+}
@@ -0,0 +1,34 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"time"
+)
+
+// Stats returns aggregate statistics on the documents of the tenant carried by ctx, counting the uploads
+// of the last 24 hours, along with the totals of the purges run since the service started.
+func (s *Service) Stats(ctx context.Context) (*domain.Stats, error) {
+	docStats, err := s.DocumentRepository.Stats(ctx, time.Now().Add(-24*time.Hour))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", port.ErrServiceGetStatsFailed, err)
+	}
+
+	s.purgeStatsMux.Lock()
+	defer s.purgeStatsMux.Unlock()
+	return &domain.Stats{
+		Documents: *docStats,
+		Purge:     s.purgeStats,
+	}, nil
+}
+
+// recordPurge adds a purge having removed n documents to the purge totals.
+func (s *Service) recordPurge(n int64) {
+	s.purgeStatsMux.Lock()
+	defer s.purgeStatsMux.Unlock()
+	s.purgeStats.Runs++
+	s.purgeStats.Documents += n
+	s.purgeStats.LastPurgeAt = time.Now()
+}
@@ -0,0 +1,44 @@
+package service
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"time"
+)
+
+const (
+	// stuckPendingCheckInterval is the interval between two counts of the stuck pending documents.
+	stuckPendingCheckInterval = time.Minute
+
+	// stuckPendingLoggedIDs is the maximum number of IDs of stuck pending documents logged at once.
+	stuckPendingLoggedIDs = 100
+)
+
+// watchStuckPending periodically counts the documents of all the tenants pending for longer than stuckThreshold, and
+// logs a warning with their IDs, so that a silent failure of the analyses can be alerted on. The documents awaiting a
+// presigned upload are not counted. It runs indefinitely.
+func (s *Service) watchStuckPending() {
+	ticker := time.NewTicker(min(s.stuckThreshold, stuckPendingCheckInterval))
+	defer ticker.Stop()
+
+	for range ticker.C {
+		pending, err := s.DocumentRepository.FindByStatus(context.Background(), domain.StatusPending)
+		if err != nil {
+			slog.Error("service - failed to count the stuck pending documents", "error", err)
+			continue
+		}
+		cutoff := time.Now().Add(-s.stuckThreshold)
+		var stuck []string
+		for _, doc := range pending {
+			if doc.CreatedAt.Before(cutoff) && !doc.AwaitsUpload() {
+				stuck = append(stuck, doc.Tenant+"/"+doc.ID)
+			}
+		}
+		s.stuckPending.Store(int64(len(stuck)))
+		if len(stuck) > 0 {
+			slog.Warn("service - documents pending for too long, see POST /admin/requeue", "threshold", s.stuckThreshold.String(),
+				"documents", len(stuck), "IDs", stuck[:min(len(stuck), stuckPendingLoggedIDs)])
+		}
+	}
+}
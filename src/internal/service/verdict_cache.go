@@ -0,0 +1,52 @@
+package service
+
+import (
+	"context"
+	"goyav/internal/core/domain"
+	"log/slog"
+	"time"
+)
+
+// verdictCacheKey returns the key of the verdict on the content of the given hash uploaded by tenant. The verdicts
+// are not shared between the tenants, nor between the hash algorithms.
+func (s *Service) verdictCacheKey(tenant, hash string) string {
+	return tenant + "/" + string(s.hashAlgorithm) + ":" + hash
+}
+
+// knownVerdict returns the verdict known on the content of the given hash uploaded by the tenant carried by ctx:
+// the one of existing, a document holding the same content, if it has a verdict, or else the one of the verdict
+// cache, if any. No verdict is known under the domain.DedupeRescan policy. A failure of the cache is only logged,
+// the content is then analyzed.
+func (s *Service) knownVerdict(ctx context.Context, policy domain.DedupePolicy, existing *domain.Document, hash string) (domain.CachedVerdict, bool) {
+	if policy == domain.DedupeRescan {
+		return domain.CachedVerdict{}, false
+	}
+	if existing != nil && existing.Status.IsVerdict() {
+		return domain.CachedVerdict{Status: existing.Status, Threat: existing.Threat, AnalyzedAt: existing.AnalyzedAt}, true
+	}
+	if s.verdictCache == nil {
+		return domain.CachedVerdict{}, false
+	}
+	v, ok, err := s.verdictCache.Get(ctx, s.verdictCacheKey(domain.TenantFromContext(ctx), hash))
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to read the verdict cache", "error", err)
+		return domain.CachedVerdict{}, false
+	}
+	return v, ok
+}
+
+// cacheVerdict caches the verdict v of the analysis of the document identified by ID, completed at analyzedAt, if it
+// is clean or infected. A failure is only logged, the verdict is recorded already.
+func (s *Service) cacheVerdict(ctx context.Context, ID string, v verdict, analyzedAt time.Time) {
+	if s.verdictCache == nil || (v.status != domain.StatusClean && v.status != domain.StatusInfected) {
+		return
+	}
+	doc, err := s.DocumentRepository.Get(ctx, ID)
+	if err == nil && doc.Hash != "" {
+		cached := domain.CachedVerdict{Status: v.status, Threat: v.threat, AnalyzedAt: analyzedAt}
+		err = s.verdictCache.Set(ctx, s.verdictCacheKey(doc.Tenant, doc.Hash), cached)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "service - failed to cache the verdict", "error", err, "ID", ID)
+	}
+}
@@ -0,0 +1,101 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"goyav/internal/core/domain"
+	"goyav/internal/core/port"
+	"goyav/pkg/helper"
+	"time"
+)
+
+// CreateWebhook validates w and records it as a new webhook, identified by a UUIDv7. An invalid webhook is refused with
+// port.ErrServiceInvalidWebhook, wrapping the *domain.WebhookError of its field. It returns
+// port.ErrServiceWebhooksUnavailable if the document repository does not hold the webhooks.
+func (s *Service) CreateWebhook(ctx context.Context, w domain.Webhook) (*domain.Webhook, error) {
+	repo, err := s.webhookRepository()
+	if err != nil {
+		return nil, err
+	}
+	if err = w.Validate(); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceInvalidWebhook, err)
+	}
+	if w.ID, err = helper.IDSchemeUUIDv7.NewID(""); err != nil {
+		return nil, fmt.Errorf("service: %w: %v", port.ErrServiceWebhookFailed, err)
+	}
+	w.CreatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	w.UpdatedAt = w.CreatedAt
+	if err = repo.SaveWebhook(ctx, &w); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	return &w, nil
+}
+
+// ListWebhooks retrieves all the webhooks, the oldest first.
+func (s *Service) ListWebhooks(ctx context.Context) ([]*domain.Webhook, error) {
+	repo, err := s.webhookRepository()
+	if err != nil {
+		return nil, err
+	}
+	webhooks, err := repo.ListWebhooks(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	return webhooks, nil
+}
+
+// GetWebhook retrieves the webhook identified by ID, wrapping port.ErrWebhookNotFound if there is none.
+func (s *Service) GetWebhook(ctx context.Context, ID string) (*domain.Webhook, error) {
+	repo, err := s.webhookRepository()
+	if err != nil {
+		return nil, err
+	}
+	w, err := repo.GetWebhook(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	return w, nil
+}
+
+// UpdateWebhook changes the webhook identified by ID by u, and records it once validated. Two concurrent changes of
+// the same webhook are not merged, the last one recorded prevails.
+func (s *Service) UpdateWebhook(ctx context.Context, ID string, u domain.WebhookUpdate) (*domain.Webhook, error) {
+	repo, err := s.webhookRepository()
+	if err != nil {
+		return nil, err
+	}
+	w, err := repo.GetWebhook(ctx, ID)
+	if err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	u.Apply(w)
+	if err = w.Validate(); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceInvalidWebhook, err)
+	}
+	w.UpdatedAt = time.Now().UTC().Truncate(time.Microsecond)
+	if err = repo.UpdateWebhook(ctx, w); err != nil {
+		return nil, fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	return w, nil
+}
+
+// DeleteWebhook removes the webhook identified by ID, wrapping port.ErrWebhookNotFound if there is none.
+func (s *Service) DeleteWebhook(ctx context.Context, ID string) error {
+	repo, err := s.webhookRepository()
+	if err != nil {
+		return err
+	}
+	if err = repo.DeleteWebhook(ctx, ID); err != nil {
+		return fmt.Errorf("service: %w: %w", port.ErrServiceWebhookFailed, err)
+	}
+	return nil
+}
+
+// webhookRepository returns the document repository holding the webhooks, or port.ErrServiceWebhooksUnavailable if
+// it holds none.
+func (s *Service) webhookRepository() (port.WebhookRepository, error) {
+	if s.webhooks == nil {
+		return nil, fmt.Errorf("service: %w", port.ErrServiceWebhooksUnavailable)
+	}
+	return s.webhooks, nil
+}
@@ -0,0 +1,285 @@
+// Package client is a minimal HTTP client of the GoyAV API.
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// Analysis statuses reported by the API.
+const (
+	StatusPending  = "pending"
+	StatusClean    = "clean"
+	StatusInfected = "infected"
+	StatusTimeout  = "timeout"   // StatusTimeout is the final status of the documents whose analysis did not complete in time.
+	StatusError    = "error"     // StatusError is the final status of the documents whose analysis failed for good.
+	StatusTooLarge = "too_large" // StatusTooLarge is the final status of the documents larger than the antivirus accepts.
+)
+
+// Priorities of the analysis of an upload, see WithPriority.
+const (
+	PriorityInteractive = "interactive"
+	PriorityBatch       = "batch"
+)
+
+// ErrRequestFailed is returned when the API answers with an unexpected status code.
+// The returned error is an *APIError.
+var ErrRequestFailed = errors.New("request failed")
+
+// APIError describes a response of the API with an unexpected status code.
+type APIError struct {
+	StatusCode int
+	Message    string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("%v: %d %s", ErrRequestFailed, e.StatusCode, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return ErrRequestFailed
+}
+
+// Document is a document as returned by the API.
+type Document struct {
+	ID          string `json:"id"`
+	Tenant      string `json:"tenant,omitempty"`
+	Hash        string `json:"hash"`
+	HashAlgo    string `json:"hash_algo"`
+	Tag         string `json:"tag"`
+	Status      string `json:"analyse_status"`
+	AnalyzedAt  string `json:"analyzed_at,omitempty"`
+	Threat      string `json:"threat,omitempty"`
+	CreatedAt   string `json:"created_at"`
+	Source      string `json:"source"`
+	Origin      string `json:"origin,omitempty"`
+	FileName    string `json:"file_name,omitempty"`
+	Size        int64  `json:"size,omitempty"`
+	ContentType string `json:"content_type,omitempty"`
+
+	Labels  map[string]string `json:"labels,omitempty"`
+	Engines []string          `json:"engines,omitempty"`
+}
+
+// message is the envelope of the API's responses.
+type message struct {
+	Message  string    `json:"message"`
+	ID       string    `json:"id,omitempty"`
+	Document *Document `json:"document,omitempty"`
+}
+
+// Client sends requests to a GoyAV server.
+type Client struct {
+	baseURL    string
+	apiKey     string
+	priority   string
+	engine     string
+	httpClient *http.Client
+}
+
+// Option configures a Client.
+type Option func(*Client)
+
+// WithAPIKey sets the API key sent in the X-API-Key header of every request.
+func WithAPIKey(key string) Option {
+	return func(c *Client) {
+		c.apiKey = key
+	}
+}
+
+// WithPriority sets the priority of the analysis of every upload, PriorityInteractive or PriorityBatch.
+// The server's default, interactive, applies otherwise.
+func WithPriority(priority string) Option {
+	return func(c *Client) {
+		c.priority = priority
+	}
+}
+
+// WithEngine sets the comma-separated names of the engines analyzing every upload, e.g. "clamav".
+// The server's default, all of its engines, applies otherwise.
+func WithEngine(engine string) Option {
+	return func(c *Client) {
+		c.engine = engine
+	}
+}
+
+// WithHTTPClient sets the HTTP client used to send requests, http.DefaultClient is used otherwise.
+func WithHTTPClient(hc *http.Client) Option {
+	return func(c *Client) {
+		c.httpClient = hc
+	}
+}
+
+// New returns a client of the GoyAV server listening at baseURL, e.g. "http://localhost:8080".
+func New(baseURL string, opts ...Option) (*Client, error) {
+	u, err := url.Parse(baseURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q", baseURL)
+	}
+	c := &Client{
+		baseURL:    strings.TrimSuffix(baseURL, "/"),
+		httpClient: http.DefaultClient,
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c, nil
+}
+
+// Upload sends the content of r for analysis under the given file name and tag, an empty tag
+// lets the server use the file name. It returns the ID of the document and whether the server
+// already knew it.
+func (c *Client) Upload(ctx context.Context, filename, tag string, r io.Reader) (string, bool, error) {
+	pr, pw := io.Pipe()
+	mw := multipart.NewWriter(pw)
+	go func() {
+		pw.CloseWithError(writeUploadForm(mw, filename, tag, c.priority, c.engine, r))
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, c.baseURL+"/documents", pr)
+	if err != nil {
+		pr.Close()
+		return "", false, err
+	}
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+
+	var m message
+	code, err := c.do(req, &m, http.StatusCreated, http.StatusOK)
+	if err != nil {
+		return "", false, err
+	}
+	return m.ID, code == http.StatusOK, nil
+}
+
+// Document retrieves the document with the given ID.
+func (c *Client) Document(ctx context.Context, ID string) (*Document, error) {
+	return c.document(ctx, ID, 0)
+}
+
+// document retrieves the document with the given ID, letting the server wait up to wait for its analysis to complete.
+func (c *Client) document(ctx context.Context, ID string, wait time.Duration) (*Document, error) {
+	u := c.baseURL + "/documents/" + url.PathEscape(ID)
+	if wait > 0 {
+		u += "?wait=" + url.QueryEscape(wait.String())
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	var m message
+	if _, err := c.do(req, &m, http.StatusOK); err != nil {
+		return nil, err
+	}
+	if m.Document == nil {
+		return nil, fmt.Errorf("%w: the response holds no document", ErrRequestFailed)
+	}
+	return m.Document, nil
+}
+
+// LookupHash retrieves the document holding the content with the given hash, in the hash algorithm of the server,
+// SHA-256 by default. It returns false if the server holds no such document, the content then has to be uploaded.
+func (c *Client) LookupHash(ctx context.Context, hash string) (*Document, bool, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, c.baseURL+"/hash/"+url.PathEscape(hash), nil)
+	if err != nil {
+		return nil, false, err
+	}
+	var m message
+	code, err := c.do(req, &m, http.StatusOK, http.StatusNotFound)
+	if err != nil {
+		return nil, false, err
+	}
+	if code == http.StatusNotFound {
+		return nil, false, nil
+	}
+	if m.Document == nil {
+		return nil, false, fmt.Errorf("%w: the response holds no document", ErrRequestFailed)
+	}
+	return m.Document, true, nil
+}
+
+// Await polls the document with the given ID every interval until its analysis completes, and returns it
+// with its final status. It returns the error of ctx if it is done first. Each request lets the server hold
+// the response for interval until the analysis completes, so that the verdict is returned as soon as it is known
+// by the servers supporting long polling.
+func (c *Client) Await(ctx context.Context, ID string, interval time.Duration) (*Document, error) {
+	for {
+		start := time.Now()
+		doc, err := c.document(ctx, ID, interval)
+		if err != nil {
+			return nil, err
+		}
+		if doc.Status != StatusPending {
+			return doc, nil
+		}
+		select {
+		case <-time.After(interval - time.Since(start)):
+		case <-ctx.Done():
+			return nil, fmt.Errorf("no verdict yet: %w", ctx.Err())
+		}
+	}
+}
+
+// do sends req and decodes the JSON body of the response into m. It returns an *APIError
+// if the status code of the response is not one of the expected ones.
+func (c *Client) do(req *http.Request, m *message, expected ...int) (int, error) {
+	if c.apiKey != "" {
+		req.Header.Set("X-API-Key", c.apiKey)
+	}
+	req.Header.Set("Accept", "application/json")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	decodeErr := json.NewDecoder(resp.Body).Decode(m)
+	for _, code := range expected {
+		if resp.StatusCode == code {
+			if decodeErr != nil {
+				return 0, fmt.Errorf("%w: failed to decode the response: %v", ErrRequestFailed, decodeErr)
+			}
+			return resp.StatusCode, nil
+		}
+	}
+	msg := m.Message
+	if decodeErr != nil {
+		msg = http.StatusText(resp.StatusCode)
+	}
+	return resp.StatusCode, &APIError{StatusCode: resp.StatusCode, Message: msg}
+}
+
+// writeUploadForm writes the multipart form of an upload, the tag, priority and engine parts being omitted when empty.
+func writeUploadForm(mw *multipart.Writer, filename, tag, priority, engine string, r io.Reader) error {
+	if tag != "" {
+		if err := mw.WriteField("tag", tag); err != nil {
+			return err
+		}
+	}
+	if priority != "" {
+		if err := mw.WriteField("priority", priority); err != nil {
+			return err
+		}
+	}
+	if engine != "" {
+		if err := mw.WriteField("engine", engine); err != nil {
+			return err
+		}
+	}
+	part, err := mw.CreateFormFile("file", filename)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(part, r); err != nil {
+		return err
+	}
+	return mw.Close()
+}
@@ -0,0 +1,183 @@
+package client
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestUpload(t *testing.T) {
+	var priority string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			io.WriteString(w, `{"message":"missing or invalid credentials"}`)
+			return
+		}
+		file, header, err := r.FormFile("file")
+		if err != nil {
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"failed to upload file"}`)
+			return
+		}
+		defer file.Close()
+		b, _ := io.ReadAll(file)
+		assert.Equal(t, "report.pdf", header.Filename)
+		assert.Equal(t, "archive/report.pdf", r.FormValue("tag"))
+		assert.Equal(t, "content", string(b))
+		priority = r.FormValue("priority")
+		w.WriteHeader(http.StatusCreated)
+		io.WriteString(w, `{"message":"document uploaded successfully.","id":"ITSzxj1mqz1gwFZ4iendeQ"}`)
+	}))
+	defer srv.Close()
+
+	t.Run("Success", func(t *testing.T) {
+		c, err := New(srv.URL, WithAPIKey("secret"))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		ID, existed, err := c.Upload(context.Background(), "report.pdf", "archive/report.pdf", strings.NewReader("content"))
+		assert.NoError(t, err)
+		assert.False(t, existed)
+		assert.Equal(t, "ITSzxj1mqz1gwFZ4iendeQ", ID)
+		assert.Empty(t, priority)
+	})
+
+	t.Run("Priority", func(t *testing.T) {
+		c, err := New(srv.URL, WithAPIKey("secret"), WithPriority(PriorityBatch))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _, err = c.Upload(context.Background(), "report.pdf", "archive/report.pdf", strings.NewReader("content"))
+		assert.NoError(t, err)
+		assert.Equal(t, PriorityBatch, priority)
+	})
+
+	t.Run("Unauthorized", func(t *testing.T) {
+		c, err := New(srv.URL)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		_, _, err = c.Upload(context.Background(), "report.pdf", "", strings.NewReader("content"))
+		var apiErr *APIError
+		if assert.True(t, errors.As(err, &apiErr)) {
+			assert.Equal(t, http.StatusUnauthorized, apiErr.StatusCode)
+			assert.Equal(t, "missing or invalid credentials", apiErr.Message)
+		}
+		assert.ErrorIs(t, err, ErrRequestFailed)
+	})
+}
+
+func TestDocument(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/documents/ITSzxj1mqz1gwFZ4iendeQ" {
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"document not found"}`)
+			return
+		}
+		io.WriteString(w, `{"message":"document found","document":{"id":"ITSzxj1mqz1gwFZ4iendeQ","analyse_status":"clean"}}`)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc, err := c.Document(context.Background(), "ITSzxj1mqz1gwFZ4iendeQ")
+	assert.NoError(t, err)
+	if assert.NotNil(t, doc) {
+		assert.Equal(t, StatusClean, doc.Status)
+	}
+
+	_, err = c.Document(context.Background(), "unknown")
+	assert.ErrorIs(t, err, ErrRequestFailed)
+}
+
+func TestLookupHash(t *testing.T) {
+	const hash = "2cf24dba5fb0a30e26e83b2ac5b9e29e1b161e5c1fa7425e73043362938b9824"
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/hash/" + hash:
+			io.WriteString(w, `{"message":"document found","id":"ITSzxj1mqz1gwFZ4iendeQ","document":{"id":"ITSzxj1mqz1gwFZ4iendeQ","hash":"`+hash+`","analyse_status":"clean"}}`)
+		case "/hash/invalid":
+			w.WriteHeader(http.StatusBadRequest)
+			io.WriteString(w, `{"message":"the provided hash is invalid"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			io.WriteString(w, `{"message":"no document holds this content, it has to be uploaded"}`)
+		}
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc, found, err := c.LookupHash(context.Background(), hash)
+	assert.NoError(t, err)
+	assert.True(t, found)
+	if assert.NotNil(t, doc) {
+		assert.Equal(t, "ITSzxj1mqz1gwFZ4iendeQ", doc.ID)
+		assert.Equal(t, StatusClean, doc.Status)
+	}
+
+	doc, found, err = c.LookupHash(context.Background(), strings.Repeat("0", 64))
+	assert.NoError(t, err)
+	assert.False(t, found)
+	assert.Nil(t, doc)
+
+	_, _, err = c.LookupHash(context.Background(), "invalid")
+	var apiErr *APIError
+	if assert.ErrorAs(t, err, &apiErr) {
+		assert.Equal(t, http.StatusBadRequest, apiErr.StatusCode)
+	}
+}
+
+func TestAwait(t *testing.T) {
+	var calls int
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		assert.NotEmpty(t, r.URL.Query().Get("wait"), "the server must be let wait for the verdict")
+		status := StatusPending
+		if calls == 3 {
+			status = StatusInfected
+		}
+		io.WriteString(w, `{"message":"document found","document":{"id":"ITSzxj1mqz1gwFZ4iendeQ","analyse_status":"`+status+`","threat":"Win.Test.EICAR_HDB-1"}}`)
+	}))
+	defer srv.Close()
+
+	c, err := New(srv.URL)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	doc, err := c.Await(context.Background(), "ITSzxj1mqz1gwFZ4iendeQ", time.Millisecond)
+	assert.NoError(t, err)
+	if assert.NotNil(t, doc) {
+		assert.Equal(t, StatusInfected, doc.Status)
+		assert.Equal(t, "Win.Test.EICAR_HDB-1", doc.Threat)
+	}
+	assert.Equal(t, 3, calls)
+
+	calls = 0
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	_, err = c.Await(ctx, "ITSzxj1mqz1gwFZ4iendeQ", time.Hour)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+}
+
+func TestNew(t *testing.T) {
+	for _, u := range []string{"", "localhost:8080", "://bad"} {
+		_, err := New(u)
+		assert.Error(t, err, u)
+	}
+}
@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"io"
+	"sync"
+)
+
+// CopyBufferSize is the size of the buffers of Copy, that of the buffers allocated by io.Copy.
+const CopyBufferSize = 32 << 10
+
+// BufferPool pools byte slices of a fixed size, so that the transient buffers of the uploads are reused rather than
+// allocated for each of them. The slices are pooled by pointer, so that putting one back does not allocate either.
+type BufferPool struct {
+	size int
+	pool sync.Pool
+}
+
+// NewBufferPool creates a pool of byte slices of size bytes.
+func NewBufferPool(size int) *BufferPool {
+	p := &BufferPool{size: size}
+	p.pool.New = func() any {
+		b := make([]byte, size)
+		return &b
+	}
+	return p
+}
+
+// Get returns a slice of the size of the pool, whose content is undefined, to be put back with Put once unused.
+func (p *BufferPool) Get() *[]byte {
+	return p.pool.Get().(*[]byte)
+}
+
+// Put puts b back into the pool, unless its length is not the size of the pool. b must not be used afterwards.
+func (p *BufferPool) Put(b *[]byte) {
+	if len(*b) == p.size {
+		p.pool.Put(b)
+	}
+}
+
+// copyBuffers are the buffers of Copy.
+var copyBuffers = NewBufferPool(CopyBufferSize)
+
+// Copy copies src to dst as io.Copy does, with a pooled buffer rather than one allocated for each copy. Unlike
+// io.Copy, it does not defer to the io.ReaderFrom of dst, as that of os.File allocates a buffer of its own for the
+// readers other than files.
+func Copy(dst io.Writer, src io.Reader) (int64, error) {
+	b := copyBuffers.Get()
+	defer copyBuffers.Put(b)
+	return io.CopyBuffer(writerOnly{dst}, src, *b)
+}
+
+// writerOnly hides the methods of an io.Writer other than Write.
+type writerOnly struct {
+	io.Writer
+}
@@ -0,0 +1,39 @@
+package helper
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+)
+
+func TestBufferPool(t *testing.T) {
+	p := NewBufferPool(16)
+	b := p.Get()
+	if len(*b) != 16 {
+		t.Fatalf("Get() returned %d bytes, want 16", len(*b))
+	}
+	p.Put(b)
+
+	// a slice of another size is not pooled
+	short := make([]byte, 8)
+	p.Put(&short)
+	for range 4 {
+		if b := p.Get(); len(*b) != 16 {
+			t.Errorf("Get() returned %d bytes, want 16", len(*b))
+		}
+	}
+}
+
+func TestCopy(t *testing.T) {
+	data := strings.Repeat("goyav", CopyBufferSize)
+	var dst bytes.Buffer
+	// hiding io.WriterTo and io.ReaderFrom, so that the pooled buffer is used
+	n, err := Copy(struct{ io.Writer }{&dst}, struct{ io.Reader }{strings.NewReader(data)})
+	if err != nil {
+		t.Fatalf("Copy() failed: %v", err)
+	}
+	if n != int64(len(data)) || dst.String() != data {
+		t.Errorf("Copy() copied %d bytes, want %d", n, len(data))
+	}
+}
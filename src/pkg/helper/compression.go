@@ -0,0 +1,207 @@
+package helper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+)
+
+// Content encodings supported for outgoing payloads, in order of preference.
+const (
+	EncodingBrotli   = "br"
+	EncodingZstd     = "zstd"
+	EncodingGzip     = "gzip"
+	EncodingIdentity = "identity"
+)
+
+// DefaultCompressionThreshold is the payload size in bytes under which compressing
+// is not worth the CPU time: 1 KiB.
+const DefaultCompressionThreshold = 1 << 10
+
+var supportedEncodings = []string{EncodingBrotli, EncodingZstd, EncodingGzip}
+
+// zstdEncoder returns the encoder shared by all callers, created on first use, EncodeAll is safe for concurrent use.
+var zstdEncoder = sync.OnceValues(func() (*zstd.Encoder, error) {
+	return zstd.NewWriter(nil)
+})
+
+// NegotiateEncoding picks the preferred supported encoding accepted by a subscriber,
+// given the value of an Accept-Encoding header (e.g. "zstd;q=0.9, gzip").
+// Encodings with a zero quality are refused and "*" matches any supported encoding.
+// It returns EncodingIdentity if no supported encoding is accepted.
+func NegotiateEncoding(acceptEncoding string) string {
+	var (
+		best        = EncodingIdentity
+		bestQuality float64
+	)
+	accepted := make(map[string]float64)
+	for _, part := range strings.Split(acceptEncoding, ",") {
+		name, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		name = strings.ToLower(strings.TrimSpace(name))
+		if name == "" {
+			continue
+		}
+		quality := 1.0
+		if v, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			q, err := strconv.ParseFloat(v, 64)
+			if err != nil {
+				continue
+			}
+			quality = q
+		}
+		accepted[name] = quality
+	}
+
+	for _, enc := range supportedEncodings {
+		quality, ok := accepted[enc]
+		if !ok {
+			quality, ok = accepted["*"]
+		}
+		if ok && quality > bestQuality {
+			best, bestQuality = enc, quality
+		}
+	}
+	return best
+}
+
+// CompressPayload encodes payload with the encoding negotiated from acceptEncoding.
+// Payloads smaller than threshold bytes are left untouched. It returns the body to send
+// along with the value of its Content-Encoding header, which is EncodingIdentity
+// when the payload was not compressed.
+func CompressPayload(payload []byte, acceptEncoding string, threshold int) ([]byte, string, error) {
+	if len(payload) < threshold {
+		return payload, EncodingIdentity, nil
+	}
+
+	enc := NegotiateEncoding(acceptEncoding)
+	switch enc {
+	case EncodingZstd:
+		e, err := zstdEncoder()
+		if err != nil {
+			return nil, "", fmt.Errorf("failed to compress payload with %s: %w", enc, err)
+		}
+		return e.EncodeAll(payload, make([]byte, 0, len(payload)/2)), enc, nil
+	case EncodingBrotli:
+		var buf bytes.Buffer
+		w := brotli.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, "", fmt.Errorf("failed to compress payload with %s: %w", enc, err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to compress payload with %s: %w", enc, err)
+		}
+		return buf.Bytes(), enc, nil
+	case EncodingGzip:
+		var buf bytes.Buffer
+		w := gzip.NewWriter(&buf)
+		if _, err := w.Write(payload); err != nil {
+			return nil, "", fmt.Errorf("failed to compress payload with %s: %w", enc, err)
+		}
+		if err := w.Close(); err != nil {
+			return nil, "", fmt.Errorf("failed to compress payload with %s: %w", enc, err)
+		}
+		return buf.Bytes(), enc, nil
+	default:
+		return payload, EncodingIdentity, nil
+	}
+}
+
+// maxReceivers bounds the number of receivers whose accepted encodings are remembered by a CompressingTransport, the
+// requests to the others are not compressed.
+const maxReceivers = 1024
+
+// compressingTransport implements http.RoundTripper, see CompressingTransport.
+type compressingTransport struct {
+	base      http.RoundTripper
+	threshold int
+
+	mu       sync.Mutex
+	accepted map[string]string // accepted holds the Accept-Encoding advertised by the origin of each receiver.
+}
+
+// CompressingTransport wraps base, http.DefaultTransport if nil, to compress the bodies of at least threshold bytes
+// with an encoding their receiver accepts, or returns base if threshold is not positive. The receivers advertise the
+// encodings they accept in the Accept-Encoding header of their responses, see RFC 7694: the requests to a receiver
+// are sent uncompressed until it does, and a compressed request refused with a 415 status code is sent again
+// uncompressed. The bodies must be replayable, see http.Request.GetBody.
+func CompressingTransport(base http.RoundTripper, threshold int) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if threshold <= 0 {
+		return base
+	}
+	return &compressingTransport{base: base, threshold: threshold, accepted: make(map[string]string)}
+}
+
+// RoundTrip implements http.RoundTripper.
+func (t *compressingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	origin := req.URL.Scheme + "://" + req.URL.Host
+	t.mu.Lock()
+	acceptEncoding, ok := t.accepted[origin]
+	t.mu.Unlock()
+	if !ok || req.GetBody == nil || req.ContentLength < int64(t.threshold) || req.Header.Get("Content-Encoding") != "" {
+		return t.send(origin, req)
+	}
+
+	payload, err := io.ReadAll(req.Body)
+	req.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	body, enc, err := CompressPayload(payload, acceptEncoding, t.threshold)
+	if err != nil {
+		return nil, err
+	}
+	if enc == EncodingIdentity {
+		return t.send(origin, withBody(req, payload))
+	}
+	compressed := withBody(req, body)
+	compressed.Header.Set("Content-Encoding", enc)
+	resp, err := t.send(origin, compressed)
+	if err != nil || resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, err
+	}
+	// the receiver no longer accepts the encoding, the request is sent again uncompressed
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 1<<16))
+	resp.Body.Close()
+	return t.send(origin, withBody(req, payload))
+}
+
+// send sends req with the base transport and remembers the encodings advertised by the receiver in the response: none
+// but the identity if it refuses the request with a 415 status code without advertising any.
+func (t *compressingTransport) send(origin string, req *http.Request) (*http.Response, error) {
+	resp, err := t.base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	values, advertised := resp.Header["Accept-Encoding"]
+	if !advertised && resp.StatusCode != http.StatusUnsupportedMediaType {
+		return resp, nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.accepted[origin]; ok || len(t.accepted) < maxReceivers {
+		t.accepted[origin] = strings.Join(values, ",")
+	}
+	return resp, nil
+}
+
+// withBody returns a shallow copy of req, with a deep copy of its headers, sending body.
+func withBody(req *http.Request, body []byte) *http.Request {
+	r := req.Clone(req.Context())
+	r.Body = io.NopCloser(bytes.NewReader(body))
+	r.ContentLength = int64(len(body))
+	r.GetBody = func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(body)), nil
+	}
+	return r
+}
@@ -0,0 +1,133 @@
+package helper
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+
+	"github.com/andybalholm/brotli"
+	"github.com/klauspost/compress/zstd"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNegotiateEncoding(t *testing.T) {
+	testCases := []struct {
+		desc   string
+		accept string
+		want   string
+	}{
+		{"empty header", "", EncodingIdentity},
+		{"unsupported encoding", "deflate", EncodingIdentity},
+		{"single encoding", "gzip", EncodingGzip},
+		{"server preference on equal quality", "gzip, zstd, br", EncodingBrotli},
+		{"highest quality wins", "br;q=0.5, zstd;q=0.8, gzip", EncodingGzip},
+		{"refused encoding", "br;q=0, zstd", EncodingZstd},
+		{"wildcard", "*", EncodingBrotli},
+		{"wildcard with refusal", "br;q=0, *;q=0.5", EncodingZstd},
+		{"case insensitive", "GZIP", EncodingGzip},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			assert.Equal(t, tc.want, NegotiateEncoding(tc.accept))
+		})
+	}
+}
+
+func TestCompressPayload(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"id":"ITSzxj1mqz1gwFZ4iendeQ","analyse_status":"clean"}`), 64)
+
+	t.Run("BelowThreshold", func(t *testing.T) {
+		body, enc, err := CompressPayload(payload, "gzip", len(payload)+1)
+		assert.NoError(t, err)
+		assert.Equal(t, EncodingIdentity, enc)
+		assert.Equal(t, payload, body)
+	})
+
+	decoders := map[string]func(io.Reader) (io.Reader, error){
+		EncodingGzip: func(r io.Reader) (io.Reader, error) { return gzip.NewReader(r) },
+		EncodingBrotli: func(r io.Reader) (io.Reader, error) {
+			return brotli.NewReader(r), nil
+		},
+		EncodingZstd: func(r io.Reader) (io.Reader, error) {
+			d, err := zstd.NewReader(r)
+			return d, err
+		},
+	}
+
+	for enc, decode := range decoders {
+		t.Run(enc, func(t *testing.T) {
+			body, got, err := CompressPayload(payload, enc, DefaultCompressionThreshold)
+			assert.NoError(t, err)
+			assert.Equal(t, enc, got)
+			assert.Less(t, len(body), len(payload), "the compressed body should be smaller than the payload")
+
+			r, err := decode(bytes.NewReader(body))
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			decoded, err := io.ReadAll(r)
+			assert.NoError(t, err)
+			assert.Equal(t, payload, decoded)
+		})
+	}
+}
+
+func TestCompressingTransport(t *testing.T) {
+	payload := bytes.Repeat([]byte(`{"id":"ITSzxj1mqz1gwFZ4iendeQ","analyse_status":"clean"}`), 64)
+	var refuse atomic.Bool
+	var encodings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		enc := r.Header.Get("Content-Encoding")
+		if enc != "" && refuse.Load() {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		body := io.Reader(r.Body)
+		if enc == EncodingGzip {
+			zr, err := gzip.NewReader(r.Body)
+			if err != nil {
+				w.WriteHeader(http.StatusBadRequest)
+				return
+			}
+			body = zr
+		}
+		decoded, _ := io.ReadAll(body)
+		if !bytes.Equal(payload, decoded) {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		encodings = append(encodings, enc)
+		if !refuse.Load() {
+			w.Header().Set("Accept-Encoding", "gzip")
+		}
+	}))
+	defer srv.Close()
+
+	client := &http.Client{Transport: CompressingTransport(nil, DefaultCompressionThreshold)}
+	post := func() {
+		t.Helper()
+		resp, err := client.Post(srv.URL, "application/json", bytes.NewReader(payload))
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		resp.Body.Close()
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	// the payload is compressed once the receiver advertises the encodings it accepts
+	post()
+	post()
+	// and sent again uncompressed once it refuses them, until it advertises them again
+	refuse.Store(true)
+	post()
+	post()
+	assert.Equal(t, []string{"", EncodingGzip, "", ""}, encodings)
+
+	// a transport without threshold does not compress
+	assert.Equal(t, http.DefaultTransport, CompressingTransport(nil, 0))
+}
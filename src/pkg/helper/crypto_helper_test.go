@@ -54,6 +54,9 @@ func TestIsValidHash(t *testing.T) {
 	if IsValidHash(invalidHash) {
 		t.Errorf("IsValidHash(%s) = true, want false", invalidHash)
 	}
+	if notHex := strings.Repeat("z", 64); IsValidHash(notHex) {
+		t.Errorf("IsValidHash(%s) = true, want false", notHex)
+	}
 }
 
 func TestIsValidID(t *testing.T) {
@@ -69,4 +72,7 @@ func TestIsValidID(t *testing.T) {
 	if IsValidID(invalidID) {
 		t.Errorf("IsValidID(%s) = true, want false", invalidID)
 	}
+	if ID := NewID("seed"); !IsValidID(ID) || ID != NewID("seed") {
+		t.Errorf("NewID(seed) = %s, want a valid ID, stable across calls", ID)
+	}
 }
@@ -0,0 +1,62 @@
+package helper
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+	"hash"
+	"strings"
+
+	"github.com/zeebo/blake3"
+)
+
+// HashAlgorithm is an algorithm hashing the content of documents, named as exposed by the API.
+type HashAlgorithm string
+
+const (
+	HashSHA256 HashAlgorithm = "SHA-256"
+	HashSHA512 HashAlgorithm = "SHA-512"
+	HashBLAKE3 HashAlgorithm = "BLAKE3" // HashBLAKE3 is BLAKE3 with its default 256-bit output.
+)
+
+// DefaultHashAlgorithm is the algorithm hashing the content of documents unless configured otherwise.
+const DefaultHashAlgorithm = HashSHA256
+
+// ParseHashAlgorithm returns the hash algorithm named name, ignoring case and dashes, e.g. "sha512" or "SHA-512".
+func ParseHashAlgorithm(name string) (HashAlgorithm, error) {
+	switch strings.ReplaceAll(strings.ToUpper(name), "-", "") {
+	case "SHA256":
+		return HashSHA256, nil
+	case "SHA512":
+		return HashSHA512, nil
+	case "BLAKE3":
+		return HashBLAKE3, nil
+	default:
+		return "", fmt.Errorf("unknown hash algorithm %q, expected SHA-256, SHA-512 or BLAKE3", name)
+	}
+}
+
+// New returns a new hash.Hash computing a digest of the algorithm, SHA-256 for an unknown algorithm.
+func (a HashAlgorithm) New() hash.Hash {
+	switch a {
+	case HashSHA512:
+		return sha512.New()
+	case HashBLAKE3:
+		return blake3.New()
+	default:
+		return sha256.New()
+	}
+}
+
+// IsValid checks if the provided hash string is a digest of the algorithm, in lowercase hexadecimal.
+func (a HashAlgorithm) IsValid(hash string) bool {
+	if len(hash) != 2*a.New().Size() {
+		return false
+	}
+	for _, c := range hash {
+		if (c < '0' || c > '9') && (c < 'a' || c > 'f') {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,43 @@
+package helper
+
+import (
+	"encoding/hex"
+	"testing"
+)
+
+func TestHashAlgorithm(t *testing.T) {
+	tests := []struct {
+		name string
+		want HashAlgorithm
+		size int
+	}{
+		{"sha256", HashSHA256, 64},
+		{"SHA-512", HashSHA512, 128},
+		{"blake3", HashBLAKE3, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			algo, err := ParseHashAlgorithm(tt.name)
+			if err != nil {
+				t.Fatalf("ParseHashAlgorithm(%s) failed: %v", tt.name, err)
+			}
+			if algo != tt.want {
+				t.Errorf("ParseHashAlgorithm(%s) = %s, want %s", tt.name, algo, tt.want)
+			}
+
+			h := algo.New()
+			h.Write([]byte("content"))
+			digest := hex.EncodeToString(h.Sum(nil))
+			if len(digest) != tt.size || !algo.IsValid(digest) {
+				t.Errorf("IsValid(%s) = false, want true", digest)
+			}
+		})
+	}
+
+	if HashSHA512.IsValid("275a021bbfb6489e54d471899f7db9d1663fc695ec2fe2a2c4538aabf651fd0f") {
+		t.Error("IsValid() should reject a SHA-256 hash for SHA-512")
+	}
+	if _, err := ParseHashAlgorithm("md5"); err == nil {
+		t.Error("ParseHashAlgorithm(md5) should fail")
+	}
+}
@@ -0,0 +1,106 @@
+package helper
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// IDScheme is a scheme of document IDs.
+type IDScheme string
+
+const (
+	// IDSchemeMD5 derives the ID of a document from the MD5 hash of its content and tag, encoded in base64 URL-safe.
+	// Identical uploads get identical IDs, which tells whether two documents share their content.
+	IDSchemeMD5 IDScheme = "md5"
+
+	// IDSchemeUUIDv7 draws the ID of a document at random, as a time-ordered UUID (RFC 9562).
+	IDSchemeUUIDv7 IDScheme = "uuidv7"
+
+	// IDSchemeULID draws the ID of a document at random, as a ULID.
+	IDSchemeULID IDScheme = "ulid"
+)
+
+// DefaultIDScheme is the scheme of the document IDs unless configured otherwise.
+const DefaultIDScheme = IDSchemeMD5
+
+// ParseIDScheme returns the ID scheme named name.
+func ParseIDScheme(name string) (IDScheme, error) {
+	switch s := IDScheme(strings.ToLower(name)); s {
+	case IDSchemeMD5, IDSchemeUUIDv7, IDSchemeULID:
+		return s, nil
+	default:
+		return "", fmt.Errorf("unknown ID scheme %q, expected md5, uuidv7 or ulid", name)
+	}
+}
+
+// NewID returns a new ID of the scheme. The MD5 scheme derives it from md5ID, the ID computed by
+// GenerateHashAndID, the other schemes ignore it.
+func (s IDScheme) NewID(md5ID string) (string, error) {
+	switch s {
+	case IDSchemeUUIDv7:
+		id, err := uuid.NewV7()
+		if err != nil {
+			return "", fmt.Errorf("failed to generate ID: %v", err)
+		}
+		return id.String(), nil
+	case IDSchemeULID:
+		return newULID(time.Now())
+	default:
+		return md5ID, nil
+	}
+}
+
+// IsValid checks if id is an ID of the scheme.
+func (s IDScheme) IsValid(id string) bool {
+	switch s {
+	case IDSchemeUUIDv7:
+		u, err := uuid.Parse(id)
+		return err == nil && len(id) == 36 && u.Version() == 7 && id == u.String()
+	case IDSchemeULID:
+		return isValidULID(id)
+	default:
+		decoded, err := base64.RawURLEncoding.DecodeString(id)
+		return err == nil && len(decoded) == 16 // MD5 hash is 16 bytes
+	}
+}
+
+// crockford is the alphabet of ULIDs, Crockford's base 32.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// newULID returns a ULID made of the timestamp t, in milliseconds, and 80 random bits.
+func newULID(t time.Time) (string, error) {
+	var b [16]byte
+	binary.BigEndian.PutUint64(b[:8], uint64(t.UnixMilli())<<16)
+	if _, err := rand.Read(b[6:]); err != nil {
+		return "", fmt.Errorf("failed to generate ID: %v", err)
+	}
+
+	// 128 bits are encoded in 26 characters of 5 bits, the first one holding the 3 most significant bits.
+	hi, lo := binary.BigEndian.Uint64(b[:8]), binary.BigEndian.Uint64(b[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:]), nil
+}
+
+// isValidULID checks if id is a ULID in canonical, uppercase, form.
+func isValidULID(id string) bool {
+	if len(id) != 26 || id[0] > '7' {
+		return false
+	}
+	for i := 0; i < len(id); i++ {
+		if !strings.ContainsRune(crockford, rune(id[i])) {
+			return false
+		}
+	}
+	return true
+}
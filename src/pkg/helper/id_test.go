@@ -0,0 +1,57 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIDScheme(t *testing.T) {
+	md5ID := NewID("seed")
+	for _, scheme := range []IDScheme{IDSchemeMD5, IDSchemeUUIDv7, IDSchemeULID} {
+		t.Run(string(scheme), func(t *testing.T) {
+			id, err := scheme.NewID(md5ID)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if !scheme.IsValid(id) || !IsValidID(id) {
+				t.Errorf("NewID() = %s, want a valid ID", id)
+			}
+			other, _ := scheme.NewID(md5ID)
+			if random := scheme != IDSchemeMD5; random == (id == other) {
+				t.Errorf("NewID() = %s then %s, random IDs are expected unless the scheme is md5", id, other)
+			}
+			if scheme != IDSchemeMD5 && scheme.IsValid(md5ID) {
+				t.Errorf("IsValid(%s) = true, want false", md5ID)
+			}
+		})
+	}
+
+	if _, err := ParseIDScheme("ULID"); err != nil {
+		t.Errorf("ParseIDScheme(ULID) failed: %v", err)
+	}
+	if _, err := ParseIDScheme("sha1"); err == nil {
+		t.Error("ParseIDScheme(sha1) should fail")
+	}
+}
+
+func TestULID(t *testing.T) {
+	// the timestamp is encoded in the first 10 characters, so that ULIDs sort by creation time
+	at := time.UnixMilli(1469918176385)
+	id, err := newULID(at)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !strings.HasPrefix(id, "01ARYZ6S41") {
+		t.Errorf("newULID() = %s, want the prefix 01ARYZ6S41", id)
+	}
+	later, _ := newULID(at.Add(time.Millisecond))
+	if later[:10] <= id[:10] {
+		t.Errorf("newULID() = %s then %s, want increasing timestamps", id, later)
+	}
+	for _, invalid := range []string{"", "01ARYZ6S41TSV4RRFFQ69G5FAVX", "81ARYZ6S41TSV4RRFFQ69G5FAV", "01ARYZ6S41TSV4RRFFQ69G5FAU", "01aryz6s41tsv4rrffq69g5fav"} {
+		if isValidULID(invalid) {
+			t.Errorf("isValidULID(%q) = true, want false", invalid)
+		}
+	}
+}
@@ -0,0 +1,126 @@
+package helper
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+)
+
+// logBackupTimeFormat is the format of the time of rotation in the names of the rotated log files, which sort them
+// from the oldest.
+const logBackupTimeFormat = "2006-01-02T15-04-05.000"
+
+// RotatingFile is an io.WriteCloser appending to a log file, which is rotated before a write would take it beyond
+// maxSize bytes, or once it was opened more than maxAge ago: it is renamed with the time of its rotation, e.g.
+// goyav-2024-03-18T06-00-00.000.log for goyav.log, and a new file is opened in its place. Only the maxBackups most
+// recent rotated files are kept. A zero maxSize, maxAge or maxBackups disables the matching limit.
+type RotatingFile struct {
+	path       string
+	maxSize    int64
+	maxAge     time.Duration
+	maxBackups int
+
+	mu       sync.Mutex
+	file     *os.File
+	size     int64
+	openedAt time.Time
+}
+
+// OpenRotatingFile opens the log file at path for appending, creating it and its directory if needed, see RotatingFile.
+func OpenRotatingFile(path string, maxSize int64, maxAge time.Duration, maxBackups int) (*RotatingFile, error) {
+	f := &RotatingFile{path: path, maxSize: maxSize, maxAge: maxAge, maxBackups: maxBackups}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create the directory of the log file: %w", err)
+	}
+	if err := f.open(); err != nil {
+		return nil, err
+	}
+	return f, nil
+}
+
+// open opens the log file for appending, counting the bytes it already holds.
+func (f *RotatingFile) open() error {
+	file, err := os.OpenFile(f.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+	if err != nil {
+		return fmt.Errorf("failed to open the log file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("failed to open the log file: %w", err)
+	}
+	f.file, f.size, f.openedAt = file, info.Size(), time.Now()
+	return nil
+}
+
+// Write appends p to the log file, rotating it first if p would take it beyond its maximum size or if it is older than
+// its maximum age. A log line larger than the maximum size is written to a file of its own.
+func (f *RotatingFile) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return 0, os.ErrClosed
+	}
+	full := f.maxSize > 0 && f.size > 0 && f.size+int64(len(p)) > f.maxSize
+	old := f.maxAge > 0 && time.Since(f.openedAt) >= f.maxAge
+	if full || old {
+		if err := f.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := f.file.Write(p)
+	f.size += int64(n)
+	return n, err
+}
+
+// Close closes the log file.
+func (f *RotatingFile) Close() error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.file == nil {
+		return nil
+	}
+	err := f.file.Close()
+	f.file = nil
+	return err
+}
+
+// rotate renames the log file with the current time, opens a new one and removes the oldest rotated files beyond the
+// maximum number of backups. f.mu must be held.
+func (f *RotatingFile) rotate() error {
+	err := f.file.Close()
+	f.file = nil
+	if err != nil {
+		return fmt.Errorf("failed to rotate the log file: %w", err)
+	}
+	ext := filepath.Ext(f.path)
+	prefix := strings.TrimSuffix(f.path, ext) + "-"
+	// the logs keep on being appended to the same file if it cannot be renamed, rather than being lost
+	renamed := os.Rename(f.path, prefix+time.Now().UTC().Format(logBackupTimeFormat)+ext) == nil
+	if err := f.open(); err != nil || !renamed {
+		return err
+	}
+
+	if f.maxBackups <= 0 {
+		return nil
+	}
+	backups, err := filepath.Glob(prefix + "*" + ext)
+	if err != nil {
+		return nil
+	}
+	backups = slices.DeleteFunc(backups, func(name string) bool {
+		_, err := time.Parse(logBackupTimeFormat, strings.TrimSuffix(strings.TrimPrefix(name, prefix), ext))
+		return err != nil
+	})
+	slices.Sort(backups)
+	for _, name := range backups[:max(len(backups)-f.maxBackups, 0)] {
+		os.Remove(name)
+	}
+	return nil
+}
@@ -0,0 +1,70 @@
+package helper
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRotatingFile(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "logs", "goyav.log")
+
+	t.Run("Size", func(t *testing.T) {
+		f, err := OpenRotatingFile(path, 10, 0, 2)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer f.Close()
+		for _, line := range []string{"line 1\n", "line 2\n", "line 3\n", "a line larger than the file\n", "line 5\n"} {
+			if _, err := f.Write([]byte(line)); err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			// the rotated files are named after the millisecond of their rotation
+			time.Sleep(2 * time.Millisecond)
+		}
+
+		data, err := os.ReadFile(path)
+		assert.NoError(t, err)
+		assert.Equal(t, "line 5\n", string(data))
+		backups, err := filepath.Glob(filepath.Join(dir, "logs", "goyav-*.log"))
+		assert.NoError(t, err)
+		if assert.Len(t, backups, 2, "only the most recent rotated files must be kept") {
+			data, _ = os.ReadFile(backups[0])
+			assert.Equal(t, "line 3\n", string(data))
+			data, _ = os.ReadFile(backups[1])
+			assert.Equal(t, "a line larger than the file\n", string(data))
+		}
+	})
+
+	t.Run("Age", func(t *testing.T) {
+		agePath := filepath.Join(dir, "age.log")
+		f, err := OpenRotatingFile(agePath, 0, 20*time.Millisecond, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		defer f.Close()
+		f.Write([]byte("old\n"))
+		time.Sleep(30 * time.Millisecond)
+		f.Write([]byte("new\n"))
+
+		data, err := os.ReadFile(agePath)
+		assert.NoError(t, err)
+		assert.Equal(t, "new\n", string(data))
+		backups, _ := filepath.Glob(filepath.Join(dir, "age-*.log"))
+		assert.Len(t, backups, 1)
+	})
+
+	t.Run("Closed", func(t *testing.T) {
+		f, err := OpenRotatingFile(filepath.Join(dir, "closed.log"), 0, 0, 0)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.NoError(t, f.Close())
+		_, err = f.Write([]byte("lost\n"))
+		assert.ErrorIs(t, err, os.ErrClosed)
+	})
+}
@@ -0,0 +1,96 @@
+package helper
+
+import (
+	"context"
+	"log/slog"
+	"runtime"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// packageLevel is the minimum level of the records logged by the package of path prefix and its sub-packages.
+type packageLevel struct {
+	prefix string
+	level  slog.Level
+}
+
+// packageLevelHandler is a slog.Handler filtering the records by the level of the package logging them.
+type packageLevelHandler struct {
+	slog.Handler
+	level  slog.Level     // level is the minimum level of the records of the packages without a level of their own.
+	levels []packageLevel // levels are the levels of the packages, the longest prefixes first.
+	min    slog.Level     // min is the lowest of all the levels.
+	cache  *sync.Map      // cache holds the level of the program counters already resolved.
+}
+
+// NewPackageLevelHandler returns a slog.Handler passing to h the records at or above the level of the package logging
+// them, found from the program counter of the records: the level in levels of the package path, e.g.
+// goyav/internal/adapter/web, or else of its closest parent, e.g. goyav/internal/adapter, or else level. h must let
+// through the records of the lowest level.
+func NewPackageLevelHandler(h slog.Handler, level slog.Level, levels map[string]slog.Level) slog.Handler {
+	ph := packageLevelHandler{Handler: h, level: level, min: level, cache: &sync.Map{}}
+	for prefix, l := range levels {
+		ph.levels = append(ph.levels, packageLevel{prefix, l})
+		ph.min = min(ph.min, l)
+	}
+	slices.SortFunc(ph.levels, func(a, b packageLevel) int {
+		return len(b.prefix) - len(a.prefix)
+	})
+	return ph
+}
+
+// Enabled reports whether a record of level may be handled by the level of one of the packages at least.
+func (h packageLevelHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	return level >= h.min && h.Handler.Enabled(ctx, level)
+}
+
+// Handle passes r to the wrapped handler if it is at or above the level of the package logging it.
+func (h packageLevelHandler) Handle(ctx context.Context, r slog.Record) error {
+	if r.Level < h.levelOf(r.PC) {
+		return nil
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// levelOf returns the level of the package of the function holding the program counter pc.
+func (h packageLevelHandler) levelOf(pc uintptr) slog.Level {
+	if len(h.levels) == 0 || pc == 0 {
+		return h.level
+	}
+	if l, ok := h.cache.Load(pc); ok {
+		return l.(slog.Level)
+	}
+	frame, _ := runtime.CallersFrames([]uintptr{pc}).Next()
+	level := h.level
+	for _, pl := range h.levels {
+		if pkg := packagePath(frame.Function); pkg == pl.prefix || strings.HasPrefix(pkg, pl.prefix+"/") {
+			level = pl.level
+			break
+		}
+	}
+	h.cache.Store(pc, level)
+	return level
+}
+
+// packagePath returns the path of the package of the function named fn, e.g. goyav/internal/service for
+// goyav/internal/service.(*Service).Upload.func1.
+func packagePath(fn string) string {
+	slash := strings.LastIndexByte(fn, '/')
+	if dot := strings.IndexByte(fn[slash+1:], '.'); dot >= 0 {
+		return fn[:slash+1+dot]
+	}
+	return fn
+}
+
+// WithAttrs returns a packageLevelHandler wrapping the wrapped handler with attrs.
+func (h packageLevelHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	h.Handler = h.Handler.WithAttrs(attrs)
+	return h
+}
+
+// WithGroup returns a packageLevelHandler wrapping the wrapped handler with the group name.
+func (h packageLevelHandler) WithGroup(name string) slog.Handler {
+	h.Handler = h.Handler.WithGroup(name)
+	return h
+}
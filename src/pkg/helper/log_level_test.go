@@ -0,0 +1,51 @@
+package helper
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestPackageLevelHandler(t *testing.T) {
+	for name, tc := range map[string]struct {
+		level  slog.Level
+		levels map[string]slog.Level
+		want   []string
+	}{
+		"Default":  {slog.LevelInfo, nil, []string{"info", "warn"}},
+		"Package":  {slog.LevelDebug, map[string]slog.Level{"goyav/pkg/helper": slog.LevelWarn, "goyav/internal": slog.LevelError}, []string{"warn"}},
+		"Parent":   {slog.LevelError, map[string]slog.Level{"goyav/pkg": slog.LevelDebug}, []string{"debug", "info", "warn"}},
+		"Other":    {slog.LevelWarn, map[string]slog.Level{"goyav/internal/service": slog.LevelDebug}, []string{"warn"}},
+		"NoPrefix": {slog.LevelWarn, map[string]slog.Level{"goyav/pkg/help": slog.LevelDebug}, []string{"warn"}},
+	} {
+		t.Run(name, func(t *testing.T) {
+			var buf bytes.Buffer
+			logger := slog.New(NewPackageLevelHandler(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}), tc.level, tc.levels))
+			logger.Debug("debug")
+			logger.Info("info")
+			logger.With("k", "v").Warn("warn")
+
+			var got []string
+			for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+				if _, msg, found := strings.Cut(line, "msg="); found {
+					got = append(got, strings.Fields(msg)[0])
+				}
+			}
+			assert.Equal(t, tc.want, got)
+		})
+	}
+}
+
+func TestPackagePath(t *testing.T) {
+	for fn, want := range map[string]string{
+		"goyav/internal/service.(*Service).Upload.func1": "goyav/internal/service",
+		"goyav/pkg/helper.TestPackagePath":               "goyav/pkg/helper",
+		"main.main":                                      "main",
+		"log/slog.(*Logger).log":                         "log/slog",
+	} {
+		assert.Equal(t, want, packagePath(fn), fn)
+	}
+}
@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+)
+
+// teeHandler is a slog.Handler passing the records to several handlers.
+type teeHandler []slog.Handler
+
+// NewTeeHandler returns a slog.Handler passing each record to each of handlers enabled for its level, e.g. to log
+// both to stdout and to an observability backend.
+func NewTeeHandler(handlers ...slog.Handler) slog.Handler {
+	return teeHandler(handlers)
+}
+
+// Enabled reports whether one of the handlers is enabled for level.
+func (t teeHandler) Enabled(ctx context.Context, level slog.Level) bool {
+	for _, h := range t {
+		if h.Enabled(ctx, level) {
+			return true
+		}
+	}
+	return false
+}
+
+// Handle passes a copy of r to each handler enabled for its level, and returns the errors of the handlers joined.
+func (t teeHandler) Handle(ctx context.Context, r slog.Record) error {
+	var errs []error
+	for _, h := range t {
+		if h.Enabled(ctx, r.Level) {
+			errs = append(errs, h.Handle(ctx, r.Clone()))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WithAttrs returns a teeHandler of the handlers with attrs.
+func (t teeHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := make(teeHandler, len(t))
+	for i, h := range t {
+		c[i] = h.WithAttrs(attrs)
+	}
+	return c
+}
+
+// WithGroup returns a teeHandler of the handlers with the group name.
+func (t teeHandler) WithGroup(name string) slog.Handler {
+	c := make(teeHandler, len(t))
+	for i, h := range t {
+		c[i] = h.WithGroup(name)
+	}
+	return c
+}
@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTeeHandler(t *testing.T) {
+	var debug, info bytes.Buffer
+	logger := slog.New(NewTeeHandler(
+		slog.NewTextHandler(&debug, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		slog.NewTextHandler(&info, &slog.HandlerOptions{Level: slog.LevelInfo}),
+	)).With("k", "v")
+
+	logger.Debug("debug")
+	logger.Info("info")
+	assert.Equal(t, 2, strings.Count(debug.String(), "k=v"))
+	assert.Equal(t, 1, strings.Count(info.String(), "k=v"))
+	assert.NotContains(t, info.String(), "debug")
+}
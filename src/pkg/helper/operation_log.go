@@ -0,0 +1,47 @@
+package helper
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// OperationLog logs the operations of an adapter with the context of the request they serve, so that they are
+// correlated to it through its request ID: the failed operations, and the operations lasting at least Slow when it is
+// strictly positive. The records are attributed to the function calling Observe, so that the level of its package
+// applies, see NewPackageLevelHandler.
+type OperationLog struct {
+	Component string        // Component names the adapter, e.g. minio, prefixing the messages.
+	Slow      time.Duration // Slow is the duration from which an operation is slow, never when it is zero.
+	Ignore    []error       // Ignore lists the errors which are expected outcomes rather than failures, e.g. not found.
+}
+
+// Observe logs the operation op started at start as a warning if it failed with err, or if it was slow. args are the
+// attributes of the operation, e.g. the ID of its document. It is meant to be deferred, with err pointing to the
+// error the operation returns, or nil if it cannot fail.
+func (l OperationLog) Observe(ctx context.Context, op string, start time.Time, err *error, args ...any) {
+	d := time.Since(start)
+	switch {
+	case err != nil && *err != nil && !slices.ContainsFunc(l.Ignore, func(e error) bool { return errors.Is(*err, e) }):
+		l.log(ctx, l.Component+" - "+op+" failed", append(args, "error", (*err).Error(), "duration", d)...)
+	case l.Slow > 0 && d >= l.Slow:
+		l.log(ctx, l.Component+" - slow "+op, append(args, "duration", d, "threshold", l.Slow)...)
+	}
+}
+
+// log logs a warning with the program counter of the caller of Observe.
+func (l OperationLog) log(ctx context.Context, msg string, args ...any) {
+	h := slog.Default().Handler()
+	if !h.Enabled(ctx, slog.LevelWarn) {
+		return
+	}
+	var pcs [1]uintptr
+	// skip runtime.Callers, log and Observe
+	runtime.Callers(3, pcs[:])
+	r := slog.NewRecord(time.Now(), slog.LevelWarn, msg, pcs[0])
+	r.Add(args...)
+	h.Handle(ctx, r)
+}
@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var errTestNotFound = errors.New("not found")
+
+// observedOperation is an operation of an adapter, observed by l.
+func observedOperation(ctx context.Context, l OperationLog, d time.Duration, result error) (err error) {
+	defer l.Observe(ctx, "get", time.Now(), &err, "ID", "doc")
+	time.Sleep(d)
+	return result
+}
+
+func TestOperationLog(t *testing.T) {
+	var buf bytes.Buffer
+	defer slog.SetDefault(slog.Default())
+	slog.SetDefault(slog.New(NewRequestIDHandler(NewPackageLevelHandler(
+		slog.NewJSONHandler(&buf, &slog.HandlerOptions{AddSource: true}), slog.LevelInfo, nil))))
+
+	l := OperationLog{Component: "minio", Slow: 20 * time.Millisecond, Ignore: []error{errTestNotFound}}
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	observedOperation(ctx, l, 0, nil)
+	observedOperation(ctx, l, 0, errTestNotFound)
+	observedOperation(ctx, l, 0, errors.New("connection refused"))
+	observedOperation(ctx, l, 30*time.Millisecond, nil)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if !assert.Len(t, lines, 2, "only the failed and the slow operations must be logged") {
+		return
+	}
+	for i, msg := range []string{"minio - get failed", "minio - slow get"} {
+		var record struct {
+			Msg       string
+			Level     string
+			ID        string
+			RequestID string `json:"request_id"`
+			Source    struct{ Function string }
+		}
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		assert.Equal(t, msg, record.Msg)
+		assert.Equal(t, "WARN", record.Level)
+		assert.Equal(t, "doc", record.ID)
+		assert.Equal(t, "req-1", record.RequestID)
+		assert.Equal(t, "goyav/pkg/helper.observedOperation", record.Source.Function, "the record must be attributed to the operation")
+	}
+}
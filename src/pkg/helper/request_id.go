@@ -0,0 +1,75 @@
+package helper
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"log/slog"
+)
+
+// RequestIDMaxLength is the maximum length of a request ID received from a client.
+const RequestIDMaxLength = 128
+
+// requestIDKey is the context key of the request ID.
+type requestIDKey struct{}
+
+// ContextWithRequestID returns a copy of ctx carrying the ID of the request being served.
+func ContextWithRequestID(ctx context.Context, ID string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, ID)
+}
+
+// RequestIDFromContext returns the request ID carried by ctx, or an empty string if none.
+func RequestIDFromContext(ctx context.Context) string {
+	ID, _ := ctx.Value(requestIDKey{}).(string)
+	return ID
+}
+
+// NewRequestID returns a random request ID of 32 hexadecimal characters.
+func NewRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// IsValidRequestID checks if a request ID received from a client is made of 1 to RequestIDMaxLength
+// ASCII letters, digits, '-', '_' or '.', which makes it safe to log and to send back.
+func IsValidRequestID(ID string) bool {
+	if len(ID) == 0 || len(ID) > RequestIDMaxLength {
+		return false
+	}
+	for _, r := range ID {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_' || r == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// requestIDHandler is a slog.Handler adding the request ID carried by the context of a record to the record.
+type requestIDHandler struct {
+	slog.Handler
+}
+
+// NewRequestIDHandler returns a slog.Handler adding a request_id attribute to the records logged with a context
+// carrying a request ID, e.g. with slog.ErrorContext, before passing them to h.
+func NewRequestIDHandler(h slog.Handler) slog.Handler {
+	return requestIDHandler{h}
+}
+
+// Handle adds the request ID carried by ctx to r, if any, and passes r to the wrapped handler.
+func (h requestIDHandler) Handle(ctx context.Context, r slog.Record) error {
+	if ID := RequestIDFromContext(ctx); ID != "" {
+		r.AddAttrs(slog.String("request_id", ID))
+	}
+	return h.Handler.Handle(ctx, r)
+}
+
+// WithAttrs returns a requestIDHandler wrapping the wrapped handler with attrs.
+func (h requestIDHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return requestIDHandler{h.Handler.WithAttrs(attrs)}
+}
+
+// WithGroup returns a requestIDHandler wrapping the wrapped handler with the group name.
+func (h requestIDHandler) WithGroup(name string) slog.Handler {
+	return requestIDHandler{h.Handler.WithGroup(name)}
+}
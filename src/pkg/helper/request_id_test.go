@@ -0,0 +1,55 @@
+package helper
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+func TestRequestIDHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(NewRequestIDHandler(slog.NewJSONHandler(&buf, nil))).With("component", "test")
+
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	logger.InfoContext(ctx, "with request ID")
+	logger.Info("without request ID")
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("got %d log lines, want 2", len(lines))
+	}
+	for i, want := range []string{"req-1", ""} {
+		var record map[string]any
+		if err := json.Unmarshal([]byte(lines[i]), &record); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if got, _ := record["request_id"].(string); got != want {
+			t.Errorf("line %d: request_id = %q, want %q", i, got, want)
+		}
+		if record["component"] != "test" {
+			t.Errorf("line %d: the attributes of the logger are lost", i)
+		}
+	}
+}
+
+func TestIsValidRequestID(t *testing.T) {
+	testCases := []struct {
+		ID    string
+		valid bool
+	}{
+		{NewRequestID(), true},
+		{"3f2c-1a.b_c", true},
+		{"", false},
+		{"with space", false},
+		{"line\nbreak", false},
+		{strings.Repeat("a", RequestIDMaxLength+1), false},
+	}
+	for _, tc := range testCases {
+		if got := IsValidRequestID(tc.ID); got != tc.valid {
+			t.Errorf("IsValidRequestID(%q) = %v, want %v", tc.ID, got, tc.valid)
+		}
+	}
+}
@@ -0,0 +1,19 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"hash"
+	"io"
+)
+
+// NewRequestMAC returns the HMAC-SHA256 with secret of a request sent at timestamp, a number of seconds since the
+// Unix epoch, with the given method and request URI, its path and query. The body of the request is written to it
+// as is, so that the signature covers the timestamp, the method, the request URI and the body, in this order:
+//
+//	HMAC-SHA256(secret, timestamp + "\n" + method + "\n" + uri + "\n" + body)
+func NewRequestMAC(secret []byte, timestamp, method, uri string) hash.Hash {
+	mac := hmac.New(sha256.New, secret)
+	io.WriteString(mac, timestamp+"\n"+method+"\n"+uri+"\n")
+	return mac
+}
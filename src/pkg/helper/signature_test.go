@@ -0,0 +1,24 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewRequestMAC(t *testing.T) {
+	secret := []byte("0123456789abcdef0123456789abcdef")
+	mac := NewRequestMAC(secret, "1700000000", "POST", "/documents?wait=1")
+	mac.Write([]byte("body"))
+
+	want := hmac.New(sha256.New, secret)
+	want.Write([]byte("1700000000\nPOST\n/documents?wait=1\nbody"))
+	assert.Equal(t, hex.EncodeToString(want.Sum(nil)), hex.EncodeToString(mac.Sum(nil)))
+
+	other := NewRequestMAC(secret, "1700000001", "POST", "/documents?wait=1")
+	other.Write([]byte("body"))
+	assert.NotEqual(t, mac.Sum(nil), other.Sum(nil), "the timestamp must be signed")
+}
@@ -1,25 +1,160 @@
 package helper
 
 import (
+	"fmt"
+	"slices"
 	"strings"
 	"unicode"
+	"unicode/utf8"
 )
 
+// TagMaxLength is the default maximum length in bytes of a tag.
 const TagMaxLength = 128
 
-func Sanitize(tag string) string {
-	// Tronquer la chaîne si elle dépasse la longueur maximale
-	if len(tag) > TagMaxLength {
-		tag = tag[:TagMaxLength]
+// TagStoredMaxLength is the maximum length of a stored tag, bounding the maximum length of a TagPolicy.
+const TagStoredMaxLength = 255
+
+// CharClasses is a set of classes of characters kept in the tags by a TagPolicy.
+type CharClasses uint8
+
+const (
+	CharLetters     CharClasses = 1 << iota // letters of any script, see unicode.IsLetter
+	CharMarks                               // combining marks, such as the vowel signs of the Indic scripts
+	CharDigits                              // decimal digits of any script, see unicode.IsDigit
+	CharPunctuation                         // punctuation, such as '(', ')', '!', '«' or '、'
+	CharSymbols                             // symbols, such as '+', '=', '~' or '€'
+	CharSpaces                              // spaces, otherwise replaced by '_'
+)
+
+// charClassNames are the names of the classes of characters, in the order of their bits.
+var charClassNames = []string{"letters", "marks", "digits", "punctuation", "symbols", "spaces"}
+
+// ParseCharClasses returns the classes of characters of the comma-separated list of names: letters, marks, digits,
+// punctuation, symbols and spaces.
+func ParseCharClasses(names string) (CharClasses, error) {
+	var classes CharClasses
+	for _, name := range strings.Split(names, ",") {
+		i := slices.Index(charClassNames, strings.ToLower(strings.TrimSpace(name)))
+		if i < 0 {
+			return 0, fmt.Errorf("unknown class of characters %q, expected letters, marks, digits, punctuation, symbols or spaces", name)
+		}
+		classes |= 1 << i
+	}
+	return classes, nil
+}
+
+// String returns the comma-separated names of the classes of c, as parsed by ParseCharClasses.
+func (c CharClasses) String() string {
+	var names []string
+	for i, name := range charClassNames {
+		if c&(1<<i) != 0 {
+			names = append(names, name)
+		}
+	}
+	return strings.Join(names, ",")
+}
+
+// contains reports whether r belongs to one of the classes of c.
+func (c CharClasses) contains(r rune) bool {
+	return c&CharLetters != 0 && unicode.IsLetter(r) ||
+		c&CharMarks != 0 && unicode.IsMark(r) ||
+		c&CharDigits != 0 && unicode.IsDigit(r) ||
+		c&CharPunctuation != 0 && unicode.IsPunct(r) ||
+		c&CharSymbols != 0 && unicode.IsSymbol(r) ||
+		c&CharSpaces != 0 && r == ' '
+}
+
+// TagPolicy decides how the tags sent by the clients are stored.
+type TagPolicy struct {
+	// MaxLength is the length in bytes the tags are truncated to, TagMaxLength if zero.
+	MaxLength int
+
+	// Classes are the classes of characters kept in the tags, along with the characters of Extra.
+	// The other characters are removed, but the spaces, replaced by '_'.
+	Classes CharClasses
+	Extra   string
+
+	// Raw keeps every character, but the control characters and invalid UTF-8, ignoring Classes and Extra.
+	// The tags are escaped when output, see domain.NewDocumentDTO.
+	Raw bool
+}
+
+// DefaultTagPolicy keeps the letters, the digits, '-', '_' and '.' of the tags, within TagMaxLength bytes.
+var DefaultTagPolicy = TagPolicy{MaxLength: TagMaxLength, Classes: CharLetters | CharDigits, Extra: "-_."}
+
+// Validate checks that the maximum length of p fits in the stored tags.
+func (p TagPolicy) Validate() error {
+	if p.MaxLength < 0 || p.MaxLength > TagStoredMaxLength {
+		return fmt.Errorf("the maximum length of the tags must not exceed %d bytes, got %d", TagStoredMaxLength, p.MaxLength)
+	}
+	return nil
+}
+
+// Sanitize returns tag truncated to the maximum length of p, without the characters p does not keep.
+func (p TagPolicy) Sanitize(tag string) string {
+	maxLength := p.MaxLength
+	if maxLength <= 0 {
+		maxLength = TagMaxLength
+	}
+	// a rune cut by the truncation is invalid, and removed
+	if len(tag) > maxLength {
+		tag = tag[:maxLength]
 	}
 
 	var sb strings.Builder
 	for _, r := range tag {
-		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '-' || r == '_' || r == '.' {
+		switch {
+		case r == utf8.RuneError || unicode.IsControl(r):
+		case p.Raw || p.Classes.contains(r) || strings.ContainsRune(p.Extra, r):
 			sb.WriteRune(r)
-		} else if r == ' ' {
+		case r == ' ':
 			sb.WriteRune('_')
 		}
 	}
 	return sb.String()
 }
+
+// Sanitize returns tag sanitized by DefaultTagPolicy.
+func Sanitize(tag string) string {
+	return DefaultTagPolicy.Sanitize(tag)
+}
+
+// FileNameMaxLength is the maximum length in bytes of a stored file name.
+const FileNameMaxLength = 255
+
+// SanitizeFileName returns the base name of a file name sent by a client, without its directories,
+// control characters or invalid UTF-8, truncated to FileNameMaxLength bytes.
+func SanitizeFileName(name string) string {
+	if i := strings.LastIndexAny(name, `/\`); i >= 0 {
+		name = name[i+1:]
+	}
+
+	var sb strings.Builder
+	for _, r := range name {
+		if r == utf8.RuneError || unicode.IsControl(r) {
+			continue
+		}
+		if sb.Len()+utf8.RuneLen(r) > FileNameMaxLength {
+			break
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
+
+// TenantMaxLength is the maximum length of a tenant name.
+const TenantMaxLength = 64
+
+// IsValidTenant checks if the provided tenant name is made of 1 to TenantMaxLength ASCII letters,
+// digits, '-' or '_', which makes it safe to use in object keys.
+func IsValidTenant(tenant string) bool {
+	if len(tenant) == 0 || len(tenant) > TenantMaxLength {
+		return false
+	}
+	for _, r := range tenant {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
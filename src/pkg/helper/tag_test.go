@@ -0,0 +1,46 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTagPolicy(t *testing.T) {
+	const tag = "Rapport (v2) + annexe «final»\t2024.pdf"
+
+	t.Run("Default", func(t *testing.T) {
+		assert.Equal(t, "Rapport_v2__annexe_final2024.pdf", Sanitize(tag))
+		assert.Len(t, Sanitize(strings.Repeat("a", 200)), TagMaxLength)
+		// a rune cut by the truncation is removed
+		assert.Equal(t, strings.Repeat("a", TagMaxLength-1), Sanitize(strings.Repeat("a", TagMaxLength-1)+"é"))
+	})
+
+	t.Run("Classes", func(t *testing.T) {
+		p := TagPolicy{Classes: CharLetters | CharDigits | CharPunctuation | CharSymbols | CharSpaces, Extra: "-_."}
+		assert.Equal(t, "Rapport (v2) + annexe «final»2024.pdf", p.Sanitize(tag))
+		p = TagPolicy{MaxLength: 7, Classes: CharLetters}
+		assert.Equal(t, "Rapport", p.Sanitize(tag))
+	})
+
+	t.Run("Raw", func(t *testing.T) {
+		p := TagPolicy{MaxLength: TagStoredMaxLength, Raw: true}
+		assert.Equal(t, "Rapport (v2) + annexe «final»2024.pdf", p.Sanitize(tag))
+		assert.Equal(t, "<script>", p.Sanitize("<script>\x00"))
+	})
+
+	t.Run("Validate", func(t *testing.T) {
+		assert.NoError(t, DefaultTagPolicy.Validate())
+		assert.Error(t, TagPolicy{MaxLength: TagStoredMaxLength + 1}.Validate())
+	})
+}
+
+func TestParseCharClasses(t *testing.T) {
+	classes, err := ParseCharClasses("letters, digits,Punctuation")
+	assert.NoError(t, err)
+	assert.Equal(t, CharLetters|CharDigits|CharPunctuation, classes)
+	assert.Equal(t, "letters,digits,punctuation", classes.String())
+	_, err = ParseCharClasses("letters,emoji")
+	assert.Error(t, err)
+}
@@ -0,0 +1,53 @@
+package helper
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidToken is returned when a token is malformed or its signature is not valid.
+var ErrInvalidToken = errors.New("invalid token")
+
+// SignToken returns a token made of the JSON encoding of claims and of its HMAC-SHA256 signature with secret,
+// both base64url encoded and joined by a dot.
+func SignToken(secret []byte, claims any) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode token claims: %w", err)
+	}
+	p := base64.RawURLEncoding.EncodeToString(payload)
+	return p + "." + base64.RawURLEncoding.EncodeToString(tokenSignature(secret, p)), nil
+}
+
+// VerifyToken checks the signature of a token made by SignToken and decodes its claims into claims.
+// It returns ErrInvalidToken if the token is malformed or not signed with secret.
+func VerifyToken(secret []byte, token string, claims any) error {
+	p, s, found := strings.Cut(token, ".")
+	if !found {
+		return fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+	sig, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil || !hmac.Equal(sig, tokenSignature(secret, p)) {
+		return fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(p)
+	if err != nil {
+		return fmt.Errorf("%w: malformed payload", ErrInvalidToken)
+	}
+	if err := json.Unmarshal(payload, claims); err != nil {
+		return fmt.Errorf("%w: malformed claims: %v", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+// tokenSignature returns the HMAC-SHA256 of the encoded payload of a token.
+func tokenSignature(secret []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
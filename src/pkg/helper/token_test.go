@@ -0,0 +1,44 @@
+package helper
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSignAndVerifyToken(t *testing.T) {
+	type claims struct {
+		Tenant string `json:"tnt"`
+		Exp    int64  `json:"exp"`
+	}
+	secret := []byte(strings.Repeat("s", 32))
+
+	token, err := SignToken(secret, claims{Tenant: "finance", Exp: 42})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	t.Run("Valid", func(t *testing.T) {
+		var got claims
+		assert.NoError(t, VerifyToken(secret, token, &got))
+		assert.Equal(t, claims{Tenant: "finance", Exp: 42}, got)
+	})
+
+	testCases := []struct {
+		desc   string
+		secret []byte
+		token  string
+	}{
+		{"other secret", []byte(strings.Repeat("o", 32)), token},
+		{"no signature", secret, strings.Split(token, ".")[0]},
+		{"tampered payload", secret, "eyJ0bnQiOiJociIsImV4cCI6NDJ9." + strings.Split(token, ".")[1]},
+		{"empty token", secret, ""},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.desc, func(t *testing.T) {
+			var got claims
+			assert.ErrorIs(t, VerifyToken(tc.secret, tc.token, &got), ErrInvalidToken)
+		})
+	}
+}
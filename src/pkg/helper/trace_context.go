@@ -0,0 +1,59 @@
+package helper
+
+import (
+	"context"
+	"encoding/hex"
+	"strings"
+)
+
+// TraceContext identifies the span of a distributed trace a request is part of, as propagated by the W3C Trace
+// Context traceparent header, e.g. 00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01.
+type TraceContext struct {
+	TraceID string // TraceID is the ID of the trace, as 32 lowercase hexadecimal characters.
+	SpanID  string // SpanID is the ID of the parent span, as 16 lowercase hexadecimal characters.
+	Sampled bool
+}
+
+// traceContextKey is the context key of the trace context.
+type traceContextKey struct{}
+
+// ParseTraceparent parses the value of a traceparent header. It reports false if it is not valid, or if its trace or
+// parent span ID is all zeros.
+func ParseTraceparent(v string) (TraceContext, bool) {
+	parts := strings.Split(strings.TrimSpace(v), "-")
+	// the future versions may add fields, version 00 has exactly four
+	if len(parts) < 4 || len(parts[0]) != 2 || parts[0] == "ff" || (parts[0] == "00" && len(parts) != 4) {
+		return TraceContext{}, false
+	}
+	version, traceID, spanID, flags := parts[0], parts[1], parts[2], parts[3]
+	if !isLowerHex(version) || len(traceID) != 32 || !isLowerHex(traceID) || len(spanID) != 16 || !isLowerHex(spanID) ||
+		len(flags) != 2 || !isLowerHex(flags) {
+		return TraceContext{}, false
+	}
+	if strings.Trim(traceID, "0") == "" || strings.Trim(spanID, "0") == "" {
+		return TraceContext{}, false
+	}
+	f, _ := hex.DecodeString(flags)
+	return TraceContext{TraceID: traceID, SpanID: spanID, Sampled: f[0]&1 == 1}, true
+}
+
+// isLowerHex reports whether s is made of lowercase hexadecimal characters only.
+func isLowerHex(s string) bool {
+	for _, r := range s {
+		if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+			return false
+		}
+	}
+	return true
+}
+
+// ContextWithTraceContext returns a copy of ctx carrying the trace context of the request being served.
+func ContextWithTraceContext(ctx context.Context, tc TraceContext) context.Context {
+	return context.WithValue(ctx, traceContextKey{}, tc)
+}
+
+// TraceContextFromContext returns the trace context carried by ctx, and reports whether it carries one.
+func TraceContextFromContext(ctx context.Context) (TraceContext, bool) {
+	tc, ok := ctx.Value(traceContextKey{}).(TraceContext)
+	return tc, ok
+}
@@ -0,0 +1,36 @@
+package helper
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseTraceparent(t *testing.T) {
+	for v, want := range map[string]*TraceContext{
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        {TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-00":        {TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7"},
+		"01-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-future": {TraceID: "4bf92f3577b34da6a3ce929d0e0e4736", SpanID: "00f067aa0ba902b7", Sampled: true},
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01-extra":  nil,
+		"ff-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01":        nil,
+		"00-4BF92F3577B34DA6A3CE929D0E0E4736-00f067aa0ba902b7-01":        nil,
+		"00-00000000000000000000000000000000-00f067aa0ba902b7-01":        nil,
+		"00-4bf92f3577b34da6a3ce929d0e0e4736-0000000000000000-01":        nil,
+		"00-4bf92f3577b34da6-00f067aa0ba902b7-01":                        nil,
+		"": nil,
+	} {
+		tc, ok := ParseTraceparent(v)
+		assert.Equal(t, want != nil, ok, v)
+		if want != nil {
+			assert.Equal(t, *want, tc, v)
+		}
+	}
+
+	ctx := ContextWithTraceContext(context.Background(), TraceContext{TraceID: "4bf92f3577b34da6a3ce929d0e0e4736"})
+	tc, ok := TraceContextFromContext(ctx)
+	assert.True(t, ok)
+	assert.Equal(t, "4bf92f3577b34da6a3ce929d0e0e4736", tc.TraceID)
+	_, ok = TraceContextFromContext(context.Background())
+	assert.False(t, ok)
+}